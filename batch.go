@@ -0,0 +1,133 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+const (
+	// BatchModeEnvKey is the environment variable that a consumer of a plugin sets to indicate
+	// that it will send a stream of CodeGeneratorRequests to the plugin.
+	//
+	// The only supported value is BatchModeDelimited. See WithBatchMode for more details.
+	BatchModeEnvKey = "PROTOPLUGIN_BATCH_MODE"
+	// BatchModeDelimited is the value of BatchModeEnvKey that indicates that the consumer will write
+	// length-delimited CodeGeneratorRequests to stdin, and expects length-delimited CodeGeneratorResponses
+	// on stdout.
+	BatchModeDelimited = "delimited"
+)
+
+// WithBatchMode returns a new RunOption that will result in the plugin handling multiple
+// CodeGeneratorRequests within a single process if the consumer of the plugin requests it.
+//
+// Consumers request batch mode by setting the environment variable PROTOPLUGIN_BATCH_MODE=delimited
+// (see BatchModeEnvKey) when invoking the plugin. In batch mode, stdin is read as a stream of
+// CodeGeneratorRequests, each prefixed with its size in bytes as a varint, as written by
+// protodelim.MarshalTo. For each CodeGeneratorRequest, the Handler is invoked and the CodeGeneratorResponse
+// is written to stdout, prefixed with its size in the same manner, before the next CodeGeneratorRequest is
+// read. The plugin exits once stdin is closed.
+//
+// This allows wrapper tools, such as Bazel persistent workers, to amortize the cost of process startup across
+// many generation units. If the environment variable is not set, the plugin behaves as if this option was not
+// specified, so plugins can safely specify this option and remain compatible with protoc and buf.
+//
+// If the Handler returns an error for any CodeGeneratorRequest, the plugin exits with the error without
+// reading further CodeGeneratorRequests. WithResponseCompression has no effect in batch mode.
+//
+// This option can be passed to Main or Run.
+//
+// The default is to read a single CodeGeneratorRequest from stdin.
+func WithBatchMode() RunOption {
+	return optsFunc(func(opts *opts) {
+		opts.batchMode = true
+	})
+}
+
+// *** PRIVATE ***
+
+// isBatchMode returns true if batch mode was enabled and the consumer requested batch mode via the environment.
+func isBatchMode(environ []string, batchMode bool) bool {
+	if !batchMode {
+		return false
+	}
+	value, _ := lookupEnv(environ, BatchModeEnvKey)
+	return value == BatchModeDelimited
+}
+
+// runBatch reads length-delimited CodeGeneratorRequests from stdin until EOF, invoking the
+// Handler for each and writing length-delimited CodeGeneratorResponses to stdout.
+func runBatch(
+	ctx context.Context,
+	env Env,
+	handler Handler,
+	opts *opts,
+) error {
+	unmarshalOptions := protodelim.UnmarshalOptions{
+		UnmarshalOptions: opts.unmarshalOptions,
+		// No limit unless WithMaxRequestBytes is specified, to match the behavior when not in batch mode.
+		MaxSize: -1,
+	}
+	if opts.maxRequestBytes > 0 {
+		unmarshalOptions.MaxSize = opts.maxRequestBytes
+	}
+	if unmarshalOptions.Resolver == nil {
+		unmarshalOptions.Resolver = opts.extensionTypeResolver
+	}
+	marshalOptions := protodelim.MarshalOptions{
+		MarshalOptions: proto.MarshalOptions{Deterministic: opts.deterministicMarshal},
+	}
+	reader := bufio.NewReader(env.Stdin)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		codeGeneratorRequest := &pluginpb.CodeGeneratorRequest{}
+		if err := unmarshalOptions.UnmarshalFrom(reader, codeGeneratorRequest); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			sizeTooLargeError := &protodelim.SizeTooLargeError{}
+			if errors.As(err, &sizeTooLargeError) {
+				return newRequestTooLargeError(opts.maxRequestBytes)
+			}
+			return err
+		}
+		codeGeneratorResponse, err := invoke(
+			ctx,
+			PluginEnv{
+				ProgramName: env.ProgramName,
+				Environ:     env.Environ,
+				Stderr:      env.Stderr,
+			},
+			handler,
+			codeGeneratorRequest,
+			opts,
+		)
+		if err != nil {
+			return err
+		}
+		if _, err := marshalOptions.MarshalTo(env.Stdout, codeGeneratorResponse); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestWithBatchModeOption(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	fileDescriptorProtos, err := compile(ctx, map[string][]byte{
+		"a.proto": []byte(`syntax = "proto3"; package foo; message A {}`),
+		"b.proto": []byte(`syntax = "proto3"; package foo; message B {}`),
+	})
+	require.NoError(t, err)
+	stdin := bytes.NewBuffer(nil)
+	for _, fileToGenerate := range []string{"a.proto", "b.proto", "a.proto"} {
+		_, err := protodelim.MarshalTo(
+			stdin,
+			&pluginpb.CodeGeneratorRequest{
+				FileToGenerate: []string{fileToGenerate},
+				ProtoFile:      fileDescriptorProtos,
+			},
+		)
+		require.NoError(t, err)
+	}
+	var numInvocations int
+	handler := HandlerFunc(
+		func(_ context.Context, _ PluginEnv, responseWriter ResponseWriter, request Request) error {
+			numInvocations++
+			for _, fileDescriptorProto := range request.FileDescriptorProtosToGenerate() {
+				responseWriter.AddFile(fileDescriptorProto.GetName()+".txt", fileDescriptorProto.GetMessageType()[0].GetName())
+			}
+			return nil
+		},
+	)
+
+	stdout := bytes.NewBuffer(nil)
+	err = Run(
+		ctx,
+		Env{
+			Environ: []string{BatchModeEnvKey + "=" + BatchModeDelimited},
+			Stdin:   bytes.NewReader(stdin.Bytes()),
+			Stdout:  stdout,
+			Stderr:  io.Discard,
+		},
+		handler,
+		WithBatchMode(),
+	)
+	require.NoError(t, err)
+	require.Equal(t, 3, numInvocations)
+	reader := bufio.NewReader(stdout)
+	for _, expected := range []string{"A", "B", "A"} {
+		codeGeneratorResponse := &pluginpb.CodeGeneratorResponse{}
+		require.NoError(t, protodelim.UnmarshalFrom(reader, codeGeneratorResponse))
+		require.Len(t, codeGeneratorResponse.GetFile(), 1)
+		require.Equal(t, expected, codeGeneratorResponse.GetFile()[0].GetContent())
+	}
+	require.ErrorIs(t, protodelim.UnmarshalFrom(reader, &pluginpb.CodeGeneratorResponse{}), io.EOF)
+
+	// Without the environment variable, a single non-delimited CodeGeneratorRequest is read.
+	numInvocations = 0
+	codeGeneratorRequestData, err := proto.Marshal(
+		&pluginpb.CodeGeneratorRequest{
+			FileToGenerate: []string{"b.proto"},
+			ProtoFile:      fileDescriptorProtos,
+		},
+	)
+	require.NoError(t, err)
+	stdout.Reset()
+	err = Run(
+		ctx,
+		Env{
+			Stdin:  bytes.NewReader(codeGeneratorRequestData),
+			Stdout: stdout,
+			Stderr: io.Discard,
+		},
+		handler,
+		WithBatchMode(),
+	)
+	require.NoError(t, err)
+	require.Equal(t, 1, numInvocations)
+	codeGeneratorResponse := &pluginpb.CodeGeneratorResponse{}
+	require.NoError(t, proto.Unmarshal(stdout.Bytes(), codeGeneratorResponse))
+	require.Equal(t, "B", codeGeneratorResponse.GetFile()[0].GetContent())
+
+	// A truncated stream results in an error.
+	err = Run(
+		ctx,
+		Env{
+			Environ: []string{BatchModeEnvKey + "=" + BatchModeDelimited},
+			Stdin:   bytes.NewReader(stdin.Bytes()[:stdin.Len()-1]),
+			Stdout:  io.Discard,
+			Stderr:  io.Discard,
+		},
+		handler,
+		WithBatchMode(),
+	)
+	require.Error(t, err)
+
+	// Each CodeGeneratorRequest is subject to WithMaxRequestBytes.
+	err = Run(
+		ctx,
+		Env{
+			Environ: []string{BatchModeEnvKey + "=" + BatchModeDelimited},
+			Stdin:   bytes.NewReader(stdin.Bytes()),
+			Stdout:  io.Discard,
+			Stderr:  io.Discard,
+		},
+		handler,
+		WithBatchMode(),
+		WithMaxRequestBytes(16),
+	)
+	requestTooLargeError := &RequestTooLargeError{}
+	require.ErrorAs(t, err, &requestTooLargeError)
+}
@@ -0,0 +1,51 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// *** PRIVATE ***
+
+// methodFunctionNamePrefix is the prefix of the function names of all methods on types within this package,
+// as given by runtime.Frame.Function.
+var methodFunctionNamePrefix = reflect.TypeOf(responseWriter{}).PkgPath() + ".(*"
+
+// callSite returns the "file:line" of the first caller that is not a method on a type within this package.
+//
+// This is used to identify the call within a Handler that resulted in an issue, skipping any
+// ResponseWriter wrappers within this package. Returns empty if the call site could not be determined.
+func callSite() string {
+	programCounters := make([]uintptr, 32)
+	// Skip runtime.Callers and callSite.
+	programCounters = programCounters[:runtime.Callers(2, programCounters)]
+	frames := runtime.CallersFrames(programCounters)
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, methodFunctionNamePrefix) {
+			if frame.File == "" {
+				return ""
+			}
+			return frame.File + ":" + strconv.Itoa(frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// CapabilityDeclarer is an optional interface that Handlers can implement to declare their capabilities.
+//
+// If the Handler given to Main, Run, or Invoke implements CapabilityDeclarer, the supported features
+// and editions on the response will be set automatically according to the declared Capabilities before
+// the Handler is called, and the Request will be validated against the declared Capabilities. If the
+// Request does not satisfy the declared Capabilities, the Handler will not be called, and an error
+// will be added to the response via AddError.
+type CapabilityDeclarer interface {
+	// Capabilities returns the Capabilities of the Handler.
+	Capabilities() Capabilities
+}
+
+// Capabilities are the capabilities of a Handler.
+type Capabilities struct {
+	// Proto3Optional says that the Handler supports proto3 optional fields.
+	//
+	// If true, FEATURE_PROTO3_OPTIONAL will be set on the response. If false, it is an error for
+	// any file to generate to contain proto3 optional fields.
+	Proto3Optional bool
+	// MinimumEdition is the minimum edition that the Handler supports.
+	//
+	// If both MinimumEdition and MaximumEdition are set, FEATURE_SUPPORTS_EDITIONS will be set on the
+	// response along with the editions, and it is an error for any file to generate to use an edition
+	// outside of this range. If either is not set, it is an error for any file to generate to use Editions.
+	MinimumEdition descriptorpb.Edition
+	// MaximumEdition is the maximum edition that the Handler supports.
+	//
+	// See MinimumEdition for more details.
+	MaximumEdition descriptorpb.Edition
+	// RequiredParameters are the parameter keys that must be present in the parameter of the request.
+	//
+	// The parameter is parsed as a comma-separated list of "key" or "key=value" elements, which is the
+	// convention for protoc plugins. It is an error for any of the keys to not be present.
+	RequiredParameters []string
+}
+
+// *** PRIVATE ***
+
+// applyCapabilities sets the supported features on the ResponseWriter according to the Capabilities of the Handler,
+// if the Handler is a CapabilityDeclarer, and validates the Request against the Capabilities.
+//
+// An error is returned if the Request does not satisfy the Capabilities.
+func applyCapabilities(handler Handler, responseWriter ResponseWriter, request Request) error {
+	capabilityDeclarer, ok := handler.(CapabilityDeclarer)
+	if !ok {
+		return nil
+	}
+	capabilities := capabilityDeclarer.Capabilities()
+	supportsEditions := capabilities.MinimumEdition != descriptorpb.Edition_EDITION_UNKNOWN &&
+		capabilities.MaximumEdition != descriptorpb.Edition_EDITION_UNKNOWN
+	if capabilities.Proto3Optional {
+		responseWriter.SetFeatureProto3Optional()
+	}
+	if supportsEditions {
+		responseWriter.SetFeatureSupportsEditions(capabilities.MinimumEdition, capabilities.MaximumEdition)
+	}
+	for _, fileDescriptorProto := range request.FileDescriptorProtosToGenerateUnsafe() {
+		if err := validateFileDescriptorProtoCapabilities(fileDescriptorProto, capabilities, supportsEditions); err != nil {
+			return fmt.Errorf("%s: %w", fileDescriptorProto.GetName(), err)
+		}
+	}
+	if len(capabilities.RequiredParameters) > 0 {
+		parameterKeys := make(map[string]struct{})
+		for _, element := range strings.Split(request.Parameter(), ",") {
+			key, _, _ := strings.Cut(element, "=")
+			parameterKeys[strings.TrimSpace(key)] = struct{}{}
+		}
+		for _, requiredParameter := range capabilities.RequiredParameters {
+			if _, ok := parameterKeys[requiredParameter]; !ok {
+				return fmt.Errorf("required parameter %q was not specified", requiredParameter)
+			}
+		}
+	}
+	return nil
+}
+
+func validateFileDescriptorProtoCapabilities(
+	fileDescriptorProto *descriptorpb.FileDescriptorProto,
+	capabilities Capabilities,
+	supportsEditions bool,
+) error {
+	if isEditionsFileDescriptorProto(fileDescriptorProto) {
+		if !supportsEditions {
+			return fmt.Errorf("plugin does not support Editions, but file uses edition %v", fileDescriptorProto.GetEdition())
+		}
+		if edition := fileDescriptorProto.GetEdition(); edition < capabilities.MinimumEdition || edition > capabilities.MaximumEdition {
+			return fmt.Errorf(
+				"plugin supports editions %v to %v, but file uses edition %v",
+				capabilities.MinimumEdition,
+				capabilities.MaximumEdition,
+				edition,
+			)
+		}
+	}
+	if !capabilities.Proto3Optional && fileDescriptorProtoHasProto3Optional(fileDescriptorProto) {
+		return errors.New("plugin does not support proto3 optional fields, but file contains proto3 optional fields")
+	}
+	return nil
+}
+
+func fileDescriptorProtoHasProto3Optional(fileDescriptorProto *descriptorpb.FileDescriptorProto) bool {
+	for _, extension := range fileDescriptorProto.GetExtension() {
+		if extension.GetProto3Optional() {
+			return true
+		}
+	}
+	return descriptorProtosHaveProto3Optional(fileDescriptorProto.GetMessageType())
+}
+
+func descriptorProtosHaveProto3Optional(descriptorProtos []*descriptorpb.DescriptorProto) bool {
+	for _, descriptorProto := range descriptorProtos {
+		for _, field := range descriptorProto.GetField() {
+			if field.GetProto3Optional() {
+				return true
+			}
+		}
+		for _, extension := range descriptorProto.GetExtension() {
+			if extension.GetProto3Optional() {
+				return true
+			}
+		}
+		if descriptorProtosHaveProto3Optional(descriptorProto.GetNestedType()) {
+			return true
+		}
+	}
+	return false
+}
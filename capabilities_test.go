@@ -0,0 +1,136 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestCapabilityDeclarer(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	fileDescriptorProtos, err := compile(ctx, map[string][]byte{
+		"proto3.proto":   []byte(`syntax = "proto3"; package foo; message A { optional int32 a = 1; }`),
+		"editions.proto": []byte(`edition = "2023"; package bar; message B {}`),
+	})
+	require.NoError(t, err)
+
+	invoke := func(capabilities Capabilities, parameter string, fileToGenerate string) (*pluginpb.CodeGeneratorResponse, bool) {
+		handler := &testCapabilityDeclarer{capabilities: capabilities}
+		codeGeneratorResponse, err := Invoke(
+			ctx,
+			handler,
+			&pluginpb.CodeGeneratorRequest{
+				FileToGenerate: []string{fileToGenerate},
+				Parameter:      proto.String(parameter),
+				ProtoFile:      fileDescriptorProtos,
+			},
+		)
+		require.NoError(t, err)
+		return codeGeneratorResponse, handler.handled
+	}
+
+	codeGeneratorResponse, handled := invoke(Capabilities{}, "", "proto3.proto")
+	require.False(t, handled)
+	require.Equal(
+		t,
+		"proto3.proto: plugin does not support proto3 optional fields, but file contains proto3 optional fields",
+		codeGeneratorResponse.GetError(),
+	)
+	codeGeneratorResponse, handled = invoke(Capabilities{Proto3Optional: true}, "", "proto3.proto")
+	require.True(t, handled)
+	require.Empty(t, codeGeneratorResponse.GetError())
+	require.Equal(t, uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL), codeGeneratorResponse.GetSupportedFeatures())
+
+	codeGeneratorResponse, handled = invoke(Capabilities{}, "", "editions.proto")
+	require.False(t, handled)
+	require.Equal(
+		t,
+		"editions.proto: plugin does not support Editions, but file uses edition EDITION_2023",
+		codeGeneratorResponse.GetError(),
+	)
+	codeGeneratorResponse, handled = invoke(
+		Capabilities{
+			MinimumEdition: descriptorpb.Edition_EDITION_PROTO2,
+			MaximumEdition: descriptorpb.Edition_EDITION_PROTO3,
+		},
+		"",
+		"editions.proto",
+	)
+	require.False(t, handled)
+	require.Equal(
+		t,
+		"editions.proto: plugin supports editions EDITION_PROTO2 to EDITION_PROTO3, but file uses edition EDITION_2023",
+		codeGeneratorResponse.GetError(),
+	)
+	codeGeneratorResponse, handled = invoke(
+		Capabilities{
+			MinimumEdition: descriptorpb.Edition_EDITION_PROTO2,
+			MaximumEdition: descriptorpb.Edition_EDITION_2023,
+		},
+		"",
+		"editions.proto",
+	)
+	require.True(t, handled)
+	require.Empty(t, codeGeneratorResponse.GetError())
+	require.Equal(t, int32(descriptorpb.Edition_EDITION_2023), codeGeneratorResponse.GetMaximumEdition())
+
+	codeGeneratorResponse, handled = invoke(
+		Capabilities{RequiredParameters: []string{"foo", "bar"}},
+		"foo=baz",
+		"editions.proto",
+	)
+	require.False(t, handled)
+	// Parameters are checked after files.
+	require.Equal(
+		t,
+		"editions.proto: plugin does not support Editions, but file uses edition EDITION_2023",
+		codeGeneratorResponse.GetError(),
+	)
+	codeGeneratorResponse, handled = invoke(
+		Capabilities{Proto3Optional: true, RequiredParameters: []string{"foo", "bar"}},
+		"foo=baz",
+		"proto3.proto",
+	)
+	require.False(t, handled)
+	require.Equal(t, `required parameter "bar" was not specified`, codeGeneratorResponse.GetError())
+	_, handled = invoke(
+		Capabilities{Proto3Optional: true, RequiredParameters: []string{"foo", "bar"}},
+		"foo=baz,bar",
+		"proto3.proto",
+	)
+	require.True(t, handled)
+}
+
+type testCapabilityDeclarer struct {
+	capabilities Capabilities
+	handled      bool
+}
+
+func (t *testCapabilityDeclarer) Handle(context.Context, PluginEnv, ResponseWriter, Request) error {
+	t.handled = true
+	return nil
+}
+
+func (t *testCapabilityDeclarer) Capabilities() Capabilities {
+	return t.capabilities
+}
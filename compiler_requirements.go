@@ -0,0 +1,90 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"context"
+)
+
+// CompilerRequirement is a minimum compiler version required by a feature of a plugin.
+//
+// See WithCompilerRequirements for more details.
+type CompilerRequirement struct {
+	// Feature describes the feature that requires the minimum compiler version, for example
+	// "source-retention options" or "Editions 2023".
+	//
+	// This is included in error messages. If empty, error messages only include the minimum version.
+	Feature string
+	// Minimum is the minimum compiler version required by the feature.
+	//
+	// The Suffix is not considered, see CompilerVersion.AtLeast.
+	Minimum CompilerVersion
+	// Applies returns true if the feature is used by the Request, and therefore the requirement must
+	// be satisfied.
+	//
+	// For example, a requirement for Editions 2023 may only apply if any file to generate uses Editions.
+	// If nil, the requirement always applies.
+	Applies func(Request) bool
+
+	// reason is the free-form reason given to WithMinimumCompilerVersion, used in error messages
+	// if Feature is empty.
+	reason string
+}
+
+// WithCompilerRequirements returns a new RunOption that will result in the plugin failing with an error
+// added to the CodeGeneratorResponse if the compiler that invoked the plugin does not satisfy all of the
+// given CompilerRequirements that apply to the Request.
+//
+// This allows a plugin to declare the minimum compiler versions of its features in one place. All unsatisfied
+// requirements are reported in a single error, so that users can upgrade their compiler once instead of
+// discovering requirements one at a time. See CheckCompilerRequirements for details on the error.
+//
+// This is implemented as a request interceptor, see WithRequestInterceptor for more details.
+//
+// This option can be passed to Main or Run.
+func WithCompilerRequirements(requirements ...CompilerRequirement) RunOption {
+	requirements = slicesClone(requirements)
+	return WithRequestInterceptor(
+		func(_ context.Context, request Request) error {
+			return CheckCompilerRequirements(request, requirements...)
+		},
+	)
+}
+
+// CheckCompilerRequirements checks that the compiler that invoked the plugin satisfies all of the given
+// CompilerRequirements that apply to the Request.
+//
+// If any requirements are not satisfied, a *CompilerRequirementsError is returned. If the compiler_version
+// field was not present on the CodeGeneratorRequest, all applicable requirements are unsatisfied, as the
+// compiler is assumed to be too old to provide it.
+//
+// This is useful for Handlers that want to check requirements themselves, for example within a HandlerMux.
+func CheckCompilerRequirements(request Request, requirements ...CompilerRequirement) error {
+	compilerVersion := request.CompilerVersion()
+	var unsatisfiedRequirements []CompilerRequirement
+	for _, requirement := range requirements {
+		if requirement.Applies != nil && !requirement.Applies(request) {
+			continue
+		}
+		minimum := requirement.Minimum
+		if !compilerVersion.AtLeast(minimum.Major, minimum.Minor, minimum.Patch) {
+			unsatisfiedRequirements = append(unsatisfiedRequirements, requirement)
+		}
+	}
+	if len(unsatisfiedRequirements) == 0 {
+		return nil
+	}
+	return newCompilerRequirementsError(compilerVersion, request.GeneratorName(), unsatisfiedRequirements)
+}
@@ -0,0 +1,121 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestWithCompilerRequirementsOption(t *testing.T) {
+	t.Parallel()
+
+	requirements := []CompilerRequirement{
+		{
+			Feature: "source-retention options",
+			Minimum: CompilerVersion{Major: 26},
+		},
+		{
+			Feature: "Editions 2023",
+			Minimum: CompilerVersion{Major: 27},
+			Applies: func(request Request) bool {
+				for _, fileDescriptorProto := range request.CodeGeneratorRequest().GetProtoFile() {
+					if fileDescriptorProto.GetSyntax() == "editions" {
+						return true
+					}
+				}
+				return false
+			},
+		},
+	}
+	invoke := func(version *pluginpb.Version, syntax string) (*pluginpb.CodeGeneratorResponse, bool) {
+		var handled bool
+		codeGeneratorResponse, err := Invoke(
+			context.Background(),
+			HandlerFunc(func(_ context.Context, _ PluginEnv, _ ResponseWriter, _ Request) error {
+				handled = true
+				return nil
+			}),
+			&pluginpb.CodeGeneratorRequest{
+				FileToGenerate: []string{"a.proto"},
+				ProtoFile: []*descriptorpb.FileDescriptorProto{
+					{
+						Name:   proto.String("a.proto"),
+						Syntax: proto.String(syntax),
+					},
+				},
+				CompilerVersion: version,
+			},
+			WithCompilerRequirements(requirements...),
+		)
+		require.NoError(t, err)
+		return codeGeneratorResponse, handled
+	}
+
+	codeGeneratorResponse, handled := invoke(&pluginpb.Version{Major: proto.Int32(27)}, "proto3")
+	require.True(t, handled)
+	require.Nil(t, codeGeneratorResponse.Error)
+
+	// The Editions requirement does not apply.
+	codeGeneratorResponse, handled = invoke(&pluginpb.Version{Major: proto.Int32(26), Minor: proto.Int32(1)}, "proto3")
+	require.True(t, handled)
+	require.Nil(t, codeGeneratorResponse.Error)
+
+	codeGeneratorResponse, handled = invoke(&pluginpb.Version{Major: proto.Int32(26), Minor: proto.Int32(1)}, "editions")
+	require.False(t, handled)
+	require.Equal(
+		t,
+		"this plugin requires a compiler version >= 27.0 for Editions 2023, but was invoked with protoc 26.1",
+		codeGeneratorResponse.GetError(),
+	)
+
+	codeGeneratorResponse, handled = invoke(&pluginpb.Version{Major: proto.Int32(25)}, "editions")
+	require.False(t, handled)
+	require.Equal(
+		t,
+		`this plugin requires a newer compiler, but was invoked with protoc 25.0:
+  - compiler version >= 26.0 for source-retention options
+  - compiler version >= 27.0 for Editions 2023`,
+		codeGeneratorResponse.GetError(),
+	)
+
+	request, err := NewRequest(
+		&pluginpb.CodeGeneratorRequest{
+			FileToGenerate: []string{"a.proto"},
+			ProtoFile: []*descriptorpb.FileDescriptorProto{
+				{
+					Name:   proto.String("a.proto"),
+					Syntax: proto.String("proto3"),
+				},
+			},
+		},
+	)
+	require.NoError(t, err)
+	err = CheckCompilerRequirements(request, requirements...)
+	compilerRequirementsError := &CompilerRequirementsError{}
+	require.ErrorAs(t, err, &compilerRequirementsError)
+	require.Nil(t, compilerRequirementsError.CompilerVersion)
+	require.Len(t, compilerRequirementsError.UnsatisfiedRequirements, 1)
+	require.Equal(
+		t,
+		"this plugin requires a compiler version >= 26.0 for source-retention options, but the compiler did not provide its version",
+		err.Error(),
+	)
+}
@@ -16,6 +16,8 @@ package protoplugin
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/pluginpb"
@@ -62,6 +64,38 @@ func NewCompilerVersion(version *pluginpb.Version) (*CompilerVersion, error) {
 	}, nil
 }
 
+// ParseCompilerVersion parses a CompilerVersion from its string representation.
+//
+// The accepted form is "[v]Major.Minor[.Patch][-Suffix]", for example "27.1", "v3.21.12", or "4.25.3-rc1".
+// If Patch is omitted, it is 0. Major, Minor, and Patch must be non-negative integers that fit within an int32.
+//
+// ParseCompilerVersion round-trips with String, that is ParseCompilerVersion(compilerVersion.String()) returns
+// a CompilerVersion equal to compilerVersion for all valid CompilerVersions.
+func ParseCompilerVersion(s string) (*CompilerVersion, error) {
+	value, suffix, hasSuffix := strings.Cut(strings.TrimPrefix(s, "v"), "-")
+	if hasSuffix && suffix == "" {
+		return nil, fmt.Errorf("invalid compiler version %q: suffix must not be empty", s)
+	}
+	split := strings.Split(value, ".")
+	if len(split) != 2 && len(split) != 3 {
+		return nil, fmt.Errorf("invalid compiler version %q: must be of the form Major.Minor[.Patch][-Suffix]", s)
+	}
+	components := make([]int, 3)
+	for i, element := range split {
+		component, err := strconv.ParseInt(element, 10, 32)
+		if err != nil || component < 0 || strings.HasPrefix(element, "+") {
+			return nil, fmt.Errorf("invalid compiler version %q: %q is not a non-negative integer", s, element)
+		}
+		components[i] = int(component)
+	}
+	return &CompilerVersion{
+		Major:  components[0],
+		Minor:  components[1],
+		Patch:  components[2],
+		Suffix: suffix,
+	}, nil
+}
+
 // String prints the string representation of the CompilerVersion.
 //
 // If the CompilerVersion is nil, this returns empty.
@@ -83,6 +117,47 @@ func (c *CompilerVersion) String() string {
 	return value
 }
 
+// AtLeast returns true if the CompilerVersion is greater than or equal to the given major, minor,
+// and patch version.
+//
+// The Suffix is not considered. If the CompilerVersion is nil, this returns false.
+func (c *CompilerVersion) AtLeast(major int, minor int, patch int) bool {
+	if c == nil {
+		return false
+	}
+	if c.Major != major {
+		return c.Major > major
+	}
+	if c.Minor != minor {
+		return c.Minor > minor
+	}
+	return c.Patch >= patch
+}
+
+// IsBuf returns true if the CompilerVersion was provided by buf.
+//
+// buf sets the suffix of the compiler version to "buf". If the CompilerVersion is nil, this returns false.
+func (c *CompilerVersion) IsBuf() bool {
+	if c == nil {
+		return false
+	}
+	return c.Suffix == compilerVersionSuffixBuf
+}
+
+// IsProtoc returns true if the CompilerVersion was likely provided by protoc.
+//
+// protoc releases have either an empty suffix, or a suffix for non-mainline releases such as "rc1" or "dev".
+// This is a heuristic, as other compilers may set the same suffixes. If the CompilerVersion is nil,
+// this returns false.
+func (c *CompilerVersion) IsProtoc() bool {
+	if c == nil {
+		return false
+	}
+	return c.Suffix == "" ||
+		c.Suffix == "dev" ||
+		strings.HasPrefix(c.Suffix, "rc")
+}
+
 // ToProto converts the CompilerVersion into a *pluginpb.Version.
 //
 // If the CompilerVersion is nil, this returns nil.
@@ -105,3 +180,7 @@ func (c *CompilerVersion) ToProto() *pluginpb.Version {
 	}
 	return version
 }
+
+// *** PRIVATE ***
+
+const compilerVersionSuffixBuf = "buf"
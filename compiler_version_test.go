@@ -0,0 +1,145 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestCompilerVersionAtLeast(t *testing.T) {
+	t.Parallel()
+
+	compilerVersion := &CompilerVersion{Major: 5, Minor: 27, Patch: 1}
+	require.True(t, compilerVersion.AtLeast(5, 27, 1))
+	require.True(t, compilerVersion.AtLeast(5, 27, 0))
+	require.True(t, compilerVersion.AtLeast(5, 26, 9))
+	require.True(t, compilerVersion.AtLeast(4, 30, 0))
+	require.False(t, compilerVersion.AtLeast(5, 27, 2))
+	require.False(t, compilerVersion.AtLeast(5, 28, 0))
+	require.False(t, compilerVersion.AtLeast(6, 0, 0))
+	require.False(t, (*CompilerVersion)(nil).AtLeast(0, 0, 0))
+}
+
+func TestParseCompilerVersion(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		value    string
+		expected *CompilerVersion
+		// Empty if the value round-trips.
+		expectedString string
+	}{
+		{
+			value:    "27.1",
+			expected: &CompilerVersion{Major: 27, Minor: 1},
+		},
+		{
+			value:          "v3.21.12",
+			expected:       &CompilerVersion{Major: 3, Minor: 21, Patch: 12},
+			expectedString: "3.21.12",
+		},
+		{
+			value:    "4.25.3-rc1",
+			expected: &CompilerVersion{Major: 4, Minor: 25, Patch: 3, Suffix: "rc1"},
+		},
+		{
+			value:    "3.0.0-buf",
+			expected: &CompilerVersion{Major: 3, Suffix: "buf"},
+		},
+		{
+			value:          "5.27.0",
+			expected:       &CompilerVersion{Major: 5, Minor: 27},
+			expectedString: "5.27",
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.value, func(t *testing.T) {
+			t.Parallel()
+			compilerVersion, err := ParseCompilerVersion(testCase.value)
+			require.NoError(t, err)
+			require.Equal(t, testCase.expected, compilerVersion)
+			expectedString := testCase.expectedString
+			if expectedString == "" {
+				expectedString = testCase.value
+			}
+			require.Equal(t, expectedString, compilerVersion.String())
+			fromProto, err := NewCompilerVersion(compilerVersion.ToProto())
+			require.NoError(t, err)
+			require.Equal(t, compilerVersion, fromProto)
+		})
+	}
+
+	for _, value := range []string{"", "27", "1.2.3.4", "1.-2", "1.+2", "a.b", "1.2-", "1.99999999999", "1..2"} {
+		_, err := ParseCompilerVersion(value)
+		require.Error(t, err, value)
+	}
+}
+
+func TestCompilerVersionGeneratorName(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		version          *pluginpb.Version
+		expectedIsBuf    bool
+		expectedIsProtoc bool
+		expectedName     string
+	}{
+		{
+			version: nil,
+		},
+		{
+			version:          &pluginpb.Version{Major: proto.Int32(5), Minor: proto.Int32(27)},
+			expectedIsProtoc: true,
+			expectedName:     "protoc",
+		},
+		{
+			version:          &pluginpb.Version{Major: proto.Int32(5), Minor: proto.Int32(27), Suffix: proto.String("rc2")},
+			expectedIsProtoc: true,
+			expectedName:     "protoc",
+		},
+		{
+			version:       &pluginpb.Version{Major: proto.Int32(5), Minor: proto.Int32(28), Suffix: proto.String("buf")},
+			expectedIsBuf: true,
+			expectedName:  "buf",
+		},
+		{
+			version: &pluginpb.Version{Major: proto.Int32(1), Suffix: proto.String("custom")},
+		},
+	}
+	for _, testCase := range testCases {
+		request, err := NewRequest(
+			&pluginpb.CodeGeneratorRequest{
+				FileToGenerate: []string{"a.proto"},
+				ProtoFile: []*descriptorpb.FileDescriptorProto{
+					{
+						Name:   proto.String("a.proto"),
+						Syntax: proto.String("proto3"),
+					},
+				},
+				CompilerVersion: testCase.version,
+			},
+		)
+		require.NoError(t, err)
+		require.Equal(t, testCase.expectedIsBuf, request.CompilerVersion().IsBuf(), testCase.version.String())
+		require.Equal(t, testCase.expectedIsProtoc, request.CompilerVersion().IsProtoc(), testCase.version.String())
+		require.Equal(t, testCase.expectedName, request.GeneratorName(), testCase.version.String())
+	}
+}
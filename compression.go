@@ -0,0 +1,77 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+const (
+	// ResponseCompressionEnvKey is the environment variable that a consumer of a plugin sets to indicate
+	// that it supports compressed CodeGeneratorResponses.
+	//
+	// The only supported value is ResponseCompressionGzip. See WithResponseCompression for more details.
+	ResponseCompressionEnvKey = "PROTOPLUGIN_RESPONSE_COMPRESSION"
+	// ResponseCompressionGzip is the value of ResponseCompressionEnvKey that indicates that the
+	// consumer supports gzip-compressed CodeGeneratorResponses.
+	ResponseCompressionGzip = "gzip"
+)
+
+// gzipMagic is the header of all gzip streams.
+//
+// This can never be the start of a valid serialized CodeGeneratorResponse, as 0x1f would
+// be the tag for field number 3 with wire type 7, which does not exist.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// ReadCodeGeneratorResponse reads a serialized CodeGeneratorResponse from the reader.
+//
+// The data may be compressed or uncompressed. If the data was compressed by a plugin that was given
+// WithResponseCompression, it is transparently decompressed. This is intended for proxies and hosts
+// that set ResponseCompressionEnvKey when invoking plugins.
+//
+// This is the same as UnmarshalResponse, except that the data is read from the reader.
+func ReadCodeGeneratorResponse(reader io.Reader) (*pluginpb.CodeGeneratorResponse, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	return UnmarshalResponse(data)
+}
+
+// *** PRIVATE ***
+
+// maybeCompressResponseData compresses the serialized CodeGeneratorResponse if compression
+// was enabled and the consumer indicated that it supports compression via the environment.
+func maybeCompressResponseData(data []byte, environ []string, responseCompression bool) ([]byte, error) {
+	if !responseCompression {
+		return data, nil
+	}
+	if value, _ := lookupEnv(environ, ResponseCompressionEnvKey); value != ResponseCompressionGzip {
+		return data, nil
+	}
+	buffer := bytes.NewBuffer(nil)
+	gzipWriter := gzip.NewWriter(buffer)
+	if _, err := gzipWriter.Write(data); err != nil {
+		return nil, err
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
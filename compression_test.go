@@ -0,0 +1,115 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestWithResponseCompressionOption(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	fileDescriptorProtos, err := compile(ctx, map[string][]byte{
+		"a.proto": []byte(`syntax = "proto3"; package foo; message A {}`),
+	})
+	require.NoError(t, err)
+	codeGeneratorRequestData, err := proto.Marshal(
+		&pluginpb.CodeGeneratorRequest{
+			FileToGenerate: []string{"a.proto"},
+			ProtoFile:      fileDescriptorProtos,
+		},
+	)
+	require.NoError(t, err)
+	content := strings.Repeat("compressible content\n", 1000)
+
+	run := func(environ []string, runOptions ...RunOption) []byte {
+		stdout := bytes.NewBuffer(nil)
+		err := Run(
+			ctx,
+			Env{
+				Environ: environ,
+				Stdin:   bytes.NewReader(codeGeneratorRequestData),
+				Stdout:  stdout,
+				Stderr:  io.Discard,
+			},
+			HandlerFunc(func(_ context.Context, _ PluginEnv, responseWriter ResponseWriter, _ Request) error {
+				responseWriter.AddFile("a.txt", content)
+				return nil
+			}),
+			runOptions...,
+		)
+		require.NoError(t, err)
+		return stdout.Bytes()
+	}
+
+	for _, testCase := range []struct {
+		name               string
+		environ            []string
+		runOptions         []RunOption
+		expectedCompressed bool
+	}{
+		{
+			name: "no_option",
+			environ: []string{
+				ResponseCompressionEnvKey + "=" + ResponseCompressionGzip,
+			},
+		},
+		{
+			name:       "no_env",
+			runOptions: []RunOption{WithResponseCompression()},
+		},
+		{
+			name: "unknown_env_value",
+			environ: []string{
+				ResponseCompressionEnvKey + "=zstd",
+			},
+			runOptions: []RunOption{WithResponseCompression()},
+		},
+		{
+			name: "compressed",
+			environ: []string{
+				"FOO=bar",
+				ResponseCompressionEnvKey + "=" + ResponseCompressionGzip,
+			},
+			runOptions:         []RunOption{WithResponseCompression()},
+			expectedCompressed: true,
+		},
+	} {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			data := run(testCase.environ, testCase.runOptions...)
+			if testCase.expectedCompressed {
+				require.Less(t, len(data), len(content))
+			} else {
+				require.Greater(t, len(data), len(content))
+			}
+			codeGeneratorResponse, err := ReadCodeGeneratorResponse(bytes.NewReader(data))
+			require.NoError(t, err)
+			require.Len(t, codeGeneratorResponse.GetFile(), 1)
+			require.Equal(t, content, codeGeneratorResponse.GetFile()[0].GetContent())
+		})
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// LineEnding is a line ending policy for generated file content.
+//
+// See ResponseWriterWithLineEndings for more details.
+type LineEnding int
+
+const (
+	// LineEndingPreserve says to not modify line endings.
+	LineEndingPreserve LineEnding = iota
+	// LineEndingLF says to normalize all line endings to "\n".
+	LineEndingLF
+	// LineEndingCRLF says to normalize all line endings to "\r\n".
+	LineEndingCRLF
+)
+
+// *** PRIVATE ***
+
+// contentNormalizer validates and normalizes the content of CodeGeneratorResponse.Files.
+type contentNormalizer struct {
+	validateUTF8          bool
+	defaultLineEnding     LineEnding
+	extensionToLineEnding map[string]LineEnding
+	// Binary files are never validated or normalized.
+	binaryFileNames          map[string]struct{}
+	lenientValidateErrorFunc func(error)
+}
+
+func (c *contentNormalizer) isEnabled() bool {
+	return c.validateUTF8 || c.defaultLineEnding != LineEndingPreserve || len(c.extensionToLineEnding) > 0
+}
+
+// normalize validates and normalizes the content of all files in the response.
+//
+// Must be called after validateAndNormalizeCodeGeneratorResponse.
+func (c *contentNormalizer) normalize(response *pluginpb.CodeGeneratorResponse) error {
+	if !c.isEnabled() {
+		return nil
+	}
+	for _, file := range response.GetFile() {
+		if file.Content == nil {
+			continue
+		}
+		if _, ok := c.binaryFileNames[file.GetName()]; ok {
+			continue
+		}
+		content := file.GetContent()
+		if c.validateUTF8 && !utf8.ValidString(content) {
+			if c.lenientValidateErrorFunc == nil {
+				return newInvalidUTF8ContentError(file.GetName(), false)
+			}
+			c.lenientValidateErrorFunc(newInvalidUTF8ContentError(file.GetName(), true))
+			content = strings.ToValidUTF8(content, string(utf8.RuneError))
+		}
+		content = normalizeLineEndings(content, c.lineEndingForName(file.GetName()))
+		if content != file.GetContent() {
+			file.Content = proto.String(content)
+		}
+	}
+	return nil
+}
+
+func (c *contentNormalizer) lineEndingForName(name string) LineEnding {
+	if lineEnding, ok := c.extensionToLineEnding[path.Ext(name)]; ok {
+		return lineEnding
+	}
+	return c.defaultLineEnding
+}
+
+func normalizeLineEndings(content string, lineEnding LineEnding) string {
+	switch lineEnding {
+	case LineEndingLF:
+		return strings.ReplaceAll(content, "\r\n", "\n")
+	case LineEndingCRLF:
+		if !strings.Contains(content, "\n") {
+			return content
+		}
+		return strings.ReplaceAll(strings.ReplaceAll(content, "\r\n", "\n"), "\n", "\r\n")
+	default:
+		return content
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestResponseWriterWithUTF8Validation(t *testing.T) {
+	t.Parallel()
+
+	responseWriter := NewResponseWriter(ResponseWriterWithUTF8Validation())
+	responseWriter.AddFile("a.txt", "foo")
+	responseWriter.AddFile("b.txt", "foo\xffbar")
+	_, err := responseWriter.ToCodeGeneratorResponse()
+	var responseValidationError *ResponseValidationError
+	require.ErrorAs(t, err, &responseValidationError)
+	require.Equal(t, "b.txt", responseValidationError.FileName)
+
+	var warnings []error
+	responseWriter = NewResponseWriter(
+		ResponseWriterWithUTF8Validation(),
+		ResponseWriterWithLenientValidation(func(err error) { warnings = append(warnings, err) }),
+	)
+	responseWriter.AddFile("b.txt", "foo\xffbar")
+	codeGeneratorResponse, err := responseWriter.ToCodeGeneratorResponse()
+	require.NoError(t, err)
+	require.Len(t, warnings, 1)
+	require.Equal(t, "foo�bar", codeGeneratorResponse.GetFile()[0].GetContent())
+}
+
+func TestResponseWriterWithLineEndings(t *testing.T) {
+	t.Parallel()
+
+	responseWriter := NewResponseWriter(
+		ResponseWriterWithLineEndings(
+			LineEndingLF,
+			map[string]LineEnding{
+				".bat": LineEndingCRLF,
+				".bin": LineEndingPreserve,
+			},
+		),
+	)
+	responseWriter.AddFile("a.txt", "foo\r\nbar\nbaz\r\n")
+	responseWriter.AddFile("a.bat", "foo\r\nbar\nbaz\r\n")
+	responseWriter.AddFile("a.bin", "foo\r\nbar\nbaz\r\n")
+	codeGeneratorResponse, err := responseWriter.ToCodeGeneratorResponse()
+	require.NoError(t, err)
+	require.Equal(t, "foo\nbar\nbaz\n", codeGeneratorResponse.GetFile()[0].GetContent())
+	require.Equal(t, "foo\r\nbar\r\nbaz\r\n", codeGeneratorResponse.GetFile()[1].GetContent())
+	require.Equal(t, "foo\r\nbar\nbaz\r\n", codeGeneratorResponse.GetFile()[2].GetContent())
+}
+
+func TestResponseWriterAddBinaryFile(t *testing.T) {
+	t.Parallel()
+
+	data := []byte{0x00, 0xff, '\r', '\n', 0xfe}
+	responseWriter := NewResponseWriter(
+		ResponseWriterWithUTF8Validation(),
+		ResponseWriterWithLineEndings(LineEndingLF, nil),
+	)
+	responseWriter.AddBinaryFile("a.bin", data)
+	codeGeneratorResponse, err := responseWriter.ToCodeGeneratorResponse()
+	require.NoError(t, err)
+	// Ensure that the bytes survive serialization.
+	codeGeneratorResponseData, err := proto.Marshal(codeGeneratorResponse)
+	require.NoError(t, err)
+	codeGeneratorResponse = &pluginpb.CodeGeneratorResponse{}
+	require.NoError(t, proto.Unmarshal(codeGeneratorResponseData, codeGeneratorResponse))
+	require.Equal(t, data, []byte(codeGeneratorResponse.GetFile()[0].GetContent()))
+
+	responseWriter = NewResponseWriter(ResponseWriterWithBase64BinaryFiles())
+	responseWriter.AddBinaryFile("a.bin", data)
+	codeGeneratorResponse, err = responseWriter.ToCodeGeneratorResponse()
+	require.NoError(t, err)
+	require.Equal(t, "a.bin"+BinaryFileBase64SidecarSuffix, codeGeneratorResponse.GetFile()[0].GetName())
+	decoded, err := base64.StdEncoding.DecodeString(codeGeneratorResponse.GetFile()[0].GetContent())
+	require.NoError(t, err)
+	require.Equal(t, data, decoded)
+}
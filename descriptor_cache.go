@@ -0,0 +1,193 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// DescriptorCache caches resolved FileDescriptors across CodeGeneratorRequests.
+//
+// Building FileDescriptors from FileDescriptorProtos requires linking every file on every request. For
+// long-running plugin servers and batch mode, successive CodeGeneratorRequests typically share most of their
+// dependencies, such as the Well-Known Types and shared company protos. A DescriptorCache allows these
+// FileDescriptors to be reused instead of re-linked.
+//
+// FileDescriptors are keyed by a hash of the FileDescriptorProto and the keys of its dependencies, so a
+// cached FileDescriptor is only reused if the file and all of its transitive dependencies are identical.
+//
+// A DescriptorCache is safe for concurrent use by multiple goroutines, and should be shared across
+// CodeGeneratorRequests. See WithDescriptorCache for more details.
+type DescriptorCache struct {
+	maxFiles int
+
+	lock sync.Mutex
+	// The front of the list is the most recently used entry.
+	entries   *list.List
+	keyToElem map[[sha256.Size]byte]*list.Element
+}
+
+// NewDescriptorCache returns a new DescriptorCache.
+//
+// The cache holds at most maxFiles FileDescriptors, evicting the least recently used FileDescriptors first.
+// If maxFiles is zero or negative, the cache is unbounded.
+func NewDescriptorCache(maxFiles int) *DescriptorCache {
+	return &DescriptorCache{
+		maxFiles:  maxFiles,
+		entries:   list.New(),
+		keyToElem: make(map[[sha256.Size]byte]*list.Element),
+	}
+}
+
+// WithDescriptorCache returns a new RunOption that says to use the DescriptorCache when building
+// FileDescriptors for the Request, for example within Request.AllFiles and Request.FileDescriptorsToGenerate.
+//
+// The same DescriptorCache should be passed to every invocation, for example to every call to Invoke
+// within a plugin server. When used with Main or Run, the DescriptorCache is shared across all
+// CodeGeneratorRequests in batch mode.
+//
+// This option can be passed to Main or Run.
+//
+// The default is to not cache FileDescriptors across CodeGeneratorRequests.
+func WithDescriptorCache(descriptorCache *DescriptorCache) RunOption {
+	return optsFunc(func(opts *opts) {
+		opts.descriptorCache = descriptorCache
+	})
+}
+
+// *** PRIVATE ***
+
+type descriptorCacheEntry struct {
+	key            [sha256.Size]byte
+	fileDescriptor protoreflect.FileDescriptor
+}
+
+func (c *DescriptorCache) get(key [sha256.Size]byte) (protoreflect.FileDescriptor, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	elem, ok := c.keyToElem[key]
+	if !ok {
+		return nil, false
+	}
+	c.entries.MoveToFront(elem)
+	entry, _ := elem.Value.(*descriptorCacheEntry)
+	return entry.fileDescriptor, true
+}
+
+func (c *DescriptorCache) put(key [sha256.Size]byte, fileDescriptor protoreflect.FileDescriptor) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if elem, ok := c.keyToElem[key]; ok {
+		c.entries.MoveToFront(elem)
+		return
+	}
+	c.keyToElem[key] = c.entries.PushFront(
+		&descriptorCacheEntry{
+			key:            key,
+			fileDescriptor: fileDescriptor,
+		},
+	)
+	for c.maxFiles > 0 && c.entries.Len() > c.maxFiles {
+		elem := c.entries.Back()
+		entry, _ := c.entries.Remove(elem).(*descriptorCacheEntry)
+		delete(c.keyToElem, entry.key)
+	}
+}
+
+// newFiles builds a new *protoregistry.Files for the FileDescriptorProtos, reusing cached FileDescriptors.
+//
+// This has the same semantics as protodesc.NewFiles.
+func (c *DescriptorCache) newFiles(fileDescriptorProtos []*descriptorpb.FileDescriptorProto) (*protoregistry.Files, error) {
+	builder := &descriptorCacheFilesBuilder{
+		cache:                     c,
+		nameToFileDescriptorProto: make(map[string]*descriptorpb.FileDescriptorProto, len(fileDescriptorProtos)),
+		nameToKey:                 make(map[string][sha256.Size]byte, len(fileDescriptorProtos)),
+		inProgress:                make(map[string]struct{}),
+		files:                     &protoregistry.Files{},
+	}
+	for _, fileDescriptorProto := range fileDescriptorProtos {
+		builder.nameToFileDescriptorProto[fileDescriptorProto.GetName()] = fileDescriptorProto
+	}
+	for _, fileDescriptorProto := range fileDescriptorProtos {
+		if err := builder.register(fileDescriptorProto); err != nil {
+			return nil, err
+		}
+	}
+	return builder.files, nil
+}
+
+type descriptorCacheFilesBuilder struct {
+	cache                     *DescriptorCache
+	nameToFileDescriptorProto map[string]*descriptorpb.FileDescriptorProto
+	// Populated for files that have been registered.
+	nameToKey map[string][sha256.Size]byte
+	// Files that are being registered, to guard against import cycles.
+	inProgress map[string]struct{}
+	files      *protoregistry.Files
+}
+
+// register registers the file and all of its dependencies, if they are not already registered.
+func (b *descriptorCacheFilesBuilder) register(fileDescriptorProto *descriptorpb.FileDescriptorProto) error {
+	if _, ok := b.nameToKey[fileDescriptorProto.GetName()]; ok {
+		return nil
+	}
+	b.inProgress[fileDescriptorProto.GetName()] = struct{}{}
+	defer delete(b.inProgress, fileDescriptorProto.GetName())
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(fileDescriptorProto)
+	if err != nil {
+		return err
+	}
+	hash := sha256.New()
+	_, _ = hash.Write(data)
+	for _, dependency := range fileDescriptorProto.GetDependency() {
+		dependencyFileDescriptorProto, ok := b.nameToFileDescriptorProto[dependency]
+		if !ok {
+			// Let protodesc.NewFile produce the error for the missing dependency.
+			continue
+		}
+		if _, ok := b.inProgress[dependency]; ok {
+			// Let protodesc.NewFile produce the error for the import cycle.
+			continue
+		}
+		if err := b.register(dependencyFileDescriptorProto); err != nil {
+			return err
+		}
+		dependencyKey := b.nameToKey[dependency]
+		_, _ = hash.Write(dependencyKey[:])
+	}
+	var key [sha256.Size]byte
+	copy(key[:], hash.Sum(nil))
+	fileDescriptor, ok := b.cache.get(key)
+	if !ok {
+		fileDescriptor, err = protodesc.NewFile(fileDescriptorProto, b.files)
+		if err != nil {
+			return err
+		}
+		b.cache.put(key, fileDescriptor)
+	}
+	if err := b.files.RegisterFile(fileDescriptor); err != nil {
+		return err
+	}
+	b.nameToKey[fileDescriptorProto.GetName()] = key
+	return nil
+}
@@ -0,0 +1,117 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestWithDescriptorCacheOption(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	descriptorCache := NewDescriptorCache(0)
+	invoke := func(files map[string]string) map[string]protoreflect.FileDescriptor {
+		fileNameToData := make(map[string][]byte, len(files))
+		for fileName, data := range files {
+			fileNameToData[fileName] = []byte(data)
+		}
+		fileDescriptorProtos, err := compile(ctx, fileNameToData)
+		require.NoError(t, err)
+		fileNameToFileDescriptor := make(map[string]protoreflect.FileDescriptor)
+		_, err = Invoke(
+			ctx,
+			HandlerFunc(func(_ context.Context, _ PluginEnv, _ ResponseWriter, request Request) error {
+				files, err := request.AllFiles()
+				if err != nil {
+					return err
+				}
+				files.RangeFiles(func(fileDescriptor protoreflect.FileDescriptor) bool {
+					fileNameToFileDescriptor[fileDescriptor.Path()] = fileDescriptor
+					return true
+				})
+				return nil
+			}),
+			&pluginpb.CodeGeneratorRequest{
+				FileToGenerate: []string{"a.proto"},
+				// c.proto must come before a.proto, which imports it.
+				ProtoFile: []*descriptorpb.FileDescriptorProto{fileDescriptorProtos[1], fileDescriptorProtos[0]},
+			},
+			WithDescriptorCache(descriptorCache),
+		)
+		require.NoError(t, err)
+		return fileNameToFileDescriptor
+	}
+
+	first := invoke(
+		map[string]string{
+			"a.proto": `syntax = "proto3"; package foo; import "c.proto"; message A { bar.C c = 1; }`,
+			"c.proto": `syntax = "proto3"; package bar; message C {}`,
+		},
+	)
+	second := invoke(
+		map[string]string{
+			"a.proto": `syntax = "proto3"; package foo; import "c.proto"; message A { bar.C c = 1; string s = 2; }`,
+			"c.proto": `syntax = "proto3"; package bar; message C {}`,
+		},
+	)
+	require.Same(t, first["c.proto"], second["c.proto"])
+	require.NotSame(t, first["a.proto"], second["a.proto"])
+	third := invoke(
+		map[string]string{
+			"a.proto": `syntax = "proto3"; package foo; import "c.proto"; message A { bar.C c = 1; string s = 2; }`,
+			"c.proto": `syntax = "proto3"; package bar; message C { string s = 1; }`,
+		},
+	)
+	// A dependency changed, so the file must be relinked.
+	require.NotSame(t, second["a.proto"], third["a.proto"])
+	require.NotSame(t, second["c.proto"], third["c.proto"])
+	require.Equal(t, 5, descriptorCache.entries.Len())
+}
+
+func TestDescriptorCacheEviction(t *testing.T) {
+	t.Parallel()
+
+	descriptorCache := NewDescriptorCache(2)
+	fileDescriptorProtos, err := compile(
+		context.Background(),
+		map[string][]byte{
+			"a.proto": []byte(`syntax = "proto3"; package foo; import "b.proto"; import "c.proto";`),
+			"b.proto": []byte(`syntax = "proto3"; package bar;`),
+			"c.proto": []byte(`syntax = "proto3"; package baz;`),
+		},
+	)
+	require.NoError(t, err)
+	files, err := descriptorCache.newFiles(fileDescriptorProtos)
+	require.NoError(t, err)
+	require.Equal(t, 3, files.NumFiles())
+	require.Equal(t, 2, descriptorCache.entries.Len())
+
+	_, err = descriptorCache.newFiles(
+		[]*descriptorpb.FileDescriptorProto{
+			{
+				Name:       fileDescriptorProtos[0].Name,
+				Dependency: []string{"missing.proto"},
+			},
+		},
+	)
+	require.Error(t, err)
+}
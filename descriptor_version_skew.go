@@ -0,0 +1,176 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+const descriptorProtoFileName = "google/protobuf/descriptor.proto"
+
+// *** PRIVATE ***
+
+// validateDescriptorVersionSkew validates that if google/protobuf/descriptor.proto is contained
+// within proto_file, it defines all fields and enum values that are used by the FileDescriptorProtos
+// in the CodeGeneratorRequest.
+//
+// An older descriptor.proto than the one the compiler used results in fields such as editions
+// features being treated as unknown by anything that interprets options using the descriptor.proto
+// in the CodeGeneratorRequest.
+func validateDescriptorVersionSkew(request *pluginpb.CodeGeneratorRequest) error {
+	var descriptorFileDescriptorProto *descriptorpb.FileDescriptorProto
+	for _, fileDescriptorProto := range request.GetProtoFile() {
+		if fileDescriptorProto.GetName() == descriptorProtoFileName {
+			descriptorFileDescriptorProto = fileDescriptorProto
+			break
+		}
+	}
+	if descriptorFileDescriptorProto == nil {
+		return nil
+	}
+	descriptorFileDescriptor, err := protodesc.NewFile(descriptorFileDescriptorProto, &protoregistry.Files{})
+	if err != nil {
+		return fmt.Errorf("invalid %s in CodeGeneratorRequest: %w", descriptorProtoFileName, err)
+	}
+	descriptorFiles := &protoregistry.Files{}
+	if err := descriptorFiles.RegisterFile(descriptorFileDescriptor); err != nil {
+		return err
+	}
+	checker := &descriptorVersionSkewChecker{
+		descriptorFiles: descriptorFiles,
+	}
+	for _, fileDescriptorProtos := range [][]*descriptorpb.FileDescriptorProto{
+		request.GetProtoFile(),
+		request.GetSourceFileDescriptors(),
+	} {
+		for _, fileDescriptorProto := range fileDescriptorProtos {
+			if fileDescriptorProto.GetName() == descriptorProtoFileName {
+				continue
+			}
+			if err := checker.checkMessage(fileDescriptorProto.GetName(), fileDescriptorProto.ProtoReflect()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+type descriptorVersionSkewChecker struct {
+	// descriptorFiles contains only the descriptor.proto in the CodeGeneratorRequest.
+	descriptorFiles *protoregistry.Files
+}
+
+// checkMessage checks that all fields set on the message, recursively, are defined by the
+// descriptor.proto in the CodeGeneratorRequest.
+//
+// Messages that are not defined within descriptor.proto, such as the values of custom options, are
+// not checked themselves, however messages within them are checked.
+func (c *descriptorVersionSkewChecker) checkMessage(fileName string, message protoreflect.Message) error {
+	var requestMessageDescriptor protoreflect.MessageDescriptor
+	if descriptor, err := c.descriptorFiles.FindDescriptorByName(message.Descriptor().FullName()); err == nil {
+		requestMessageDescriptor, _ = descriptor.(protoreflect.MessageDescriptor)
+	}
+	var err error
+	message.Range(func(fieldDescriptor protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		if requestMessageDescriptor != nil && !fieldDescriptor.IsExtension() {
+			if requestMessageDescriptor.Fields().ByNumber(fieldDescriptor.Number()) == nil {
+				err = newDescriptorVersionSkewError(
+					fileName,
+					fmt.Sprintf("field %s (number %d)", fieldDescriptor.FullName(), fieldDescriptor.Number()),
+				)
+				return false
+			}
+		}
+		err = c.checkValue(fileName, fieldDescriptor, value)
+		return err == nil
+	})
+	return err
+}
+
+func (c *descriptorVersionSkewChecker) checkValue(
+	fileName string,
+	fieldDescriptor protoreflect.FieldDescriptor,
+	value protoreflect.Value,
+) error {
+	switch {
+	case fieldDescriptor.IsMap():
+		if fieldDescriptor.MapValue().Message() == nil {
+			return nil
+		}
+		var err error
+		value.Map().Range(func(_ protoreflect.MapKey, mapValue protoreflect.Value) bool {
+			err = c.checkMessage(fileName, mapValue.Message())
+			return err == nil
+		})
+		return err
+	case fieldDescriptor.IsList():
+		list := value.List()
+		for i := 0; i < list.Len(); i++ {
+			if err := c.checkSingularValue(fileName, fieldDescriptor, list.Get(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return c.checkSingularValue(fileName, fieldDescriptor, value)
+	}
+}
+
+func (c *descriptorVersionSkewChecker) checkSingularValue(
+	fileName string,
+	fieldDescriptor protoreflect.FieldDescriptor,
+	value protoreflect.Value,
+) error {
+	switch fieldDescriptor.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return c.checkMessage(fileName, value.Message())
+	case protoreflect.EnumKind:
+		descriptor, err := c.descriptorFiles.FindDescriptorByName(fieldDescriptor.Enum().FullName())
+		if err != nil {
+			return nil
+		}
+		requestEnumDescriptor, ok := descriptor.(protoreflect.EnumDescriptor)
+		if !ok {
+			return nil
+		}
+		if requestEnumDescriptor.Values().ByNumber(value.Enum()) == nil {
+			return newDescriptorVersionSkewError(
+				fileName,
+				fmt.Sprintf("value %d of enum %s", value.Enum(), fieldDescriptor.Enum().FullName()),
+			)
+		}
+		return nil
+	default:
+		return nil
+	}
+}
+
+func newDescriptorVersionSkewError(fileName string, used string) error {
+	return fmt.Errorf(
+		"%s uses %s, which is not defined by the %s in the CodeGeneratorRequest - %s is likely older than "+
+			"the compiler that produced the CodeGeneratorRequest, ensure that %s is not being overridden with an outdated copy",
+		fileName,
+		used,
+		descriptorProtoFileName,
+		descriptorProtoFileName,
+		descriptorProtoFileName,
+	)
+}
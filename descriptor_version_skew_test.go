@@ -0,0 +1,145 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestWithDescriptorVersionSkewValidationOption(t *testing.T) {
+	t.Parallel()
+
+	currentDescriptorFileDescriptorProto := protodesc.ToFileDescriptorProto(descriptorpb.File_google_protobuf_descriptor_proto)
+	// An older descriptor.proto that does not have the features field on FileOptions.
+	noFeaturesDescriptorFileDescriptorProto := protodesc.ToFileDescriptorProto(descriptorpb.File_google_protobuf_descriptor_proto)
+	for _, descriptorProto := range noFeaturesDescriptorFileDescriptorProto.GetMessageType() {
+		if descriptorProto.GetName() != "FileOptions" {
+			continue
+		}
+		fields := make([]*descriptorpb.FieldDescriptorProto, 0, len(descriptorProto.GetField()))
+		for _, field := range descriptorProto.GetField() {
+			if field.GetName() != "features" {
+				fields = append(fields, field)
+			}
+		}
+		descriptorProto.Field = fields
+	}
+	// An older descriptor.proto that does not have EDITION_2023.
+	noEdition2023DescriptorFileDescriptorProto := protodesc.ToFileDescriptorProto(descriptorpb.File_google_protobuf_descriptor_proto)
+	for _, enumDescriptorProto := range noEdition2023DescriptorFileDescriptorProto.GetEnumType() {
+		if enumDescriptorProto.GetName() != "Edition" {
+			continue
+		}
+		values := make([]*descriptorpb.EnumValueDescriptorProto, 0, len(enumDescriptorProto.GetValue()))
+		for _, value := range enumDescriptorProto.GetValue() {
+			if value.GetName() != "EDITION_2023" {
+				values = append(values, value)
+			}
+		}
+		enumDescriptorProto.Value = values
+	}
+
+	proto3FileDescriptorProto := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("a.proto"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/protobuf/descriptor.proto"},
+		Options:    &descriptorpb.FileOptions{GoPackage: proto.String("foo/a")},
+	}
+	editionsFileDescriptorProto := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("b.proto"),
+		Syntax:     proto.String("editions"),
+		Edition:    descriptorpb.Edition_EDITION_2023.Enum(),
+		Dependency: []string{"google/protobuf/descriptor.proto"},
+	}
+	editionsFileWithFeaturesDescriptorProto := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("c.proto"),
+		Syntax:     proto.String("editions"),
+		Edition:    descriptorpb.Edition_EDITION_2023.Enum(),
+		Dependency: []string{"google/protobuf/descriptor.proto"},
+		Options: &descriptorpb.FileOptions{
+			Features: &descriptorpb.FeatureSet{
+				FieldPresence: descriptorpb.FeatureSet_IMPLICIT.Enum(),
+			},
+		},
+	}
+
+	testCases := []struct {
+		name                   string
+		descriptorProto        *descriptorpb.FileDescriptorProto
+		fileDescriptorProto    *descriptorpb.FileDescriptorProto
+		expectedErrorSubstring string
+	}{
+		{
+			name:                "no_descriptor_proto",
+			fileDescriptorProto: editionsFileWithFeaturesDescriptorProto,
+		},
+		{
+			name:                "current_descriptor_proto",
+			descriptorProto:     currentDescriptorFileDescriptorProto,
+			fileDescriptorProto: editionsFileWithFeaturesDescriptorProto,
+		},
+		{
+			name:                "old_descriptor_proto_unused_fields",
+			descriptorProto:     noFeaturesDescriptorFileDescriptorProto,
+			fileDescriptorProto: proto3FileDescriptorProto,
+		},
+		{
+			name:                   "old_descriptor_proto_edition",
+			descriptorProto:        noEdition2023DescriptorFileDescriptorProto,
+			fileDescriptorProto:    editionsFileDescriptorProto,
+			expectedErrorSubstring: "b.proto uses value 1000 of enum google.protobuf.Edition",
+		},
+		{
+			name:                   "old_descriptor_proto_features",
+			descriptorProto:        noFeaturesDescriptorFileDescriptorProto,
+			fileDescriptorProto:    editionsFileWithFeaturesDescriptorProto,
+			expectedErrorSubstring: "c.proto uses field google.protobuf.FileOptions.features (number 50)",
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			codeGeneratorRequest := &pluginpb.CodeGeneratorRequest{
+				FileToGenerate: []string{testCase.fileDescriptorProto.GetName()},
+				ProtoFile:      []*descriptorpb.FileDescriptorProto{testCase.fileDescriptorProto},
+			}
+			if testCase.descriptorProto != nil {
+				codeGeneratorRequest.ProtoFile = []*descriptorpb.FileDescriptorProto{
+					testCase.descriptorProto,
+					testCase.fileDescriptorProto,
+				}
+			}
+			handler := HandlerFunc(func(context.Context, PluginEnv, ResponseWriter, Request) error { return nil })
+			_, err := Invoke(context.Background(), handler, codeGeneratorRequest)
+			require.NoError(t, err)
+			_, err = Invoke(context.Background(), handler, codeGeneratorRequest, WithDescriptorVersionSkewValidation())
+			if testCase.expectedErrorSubstring == "" {
+				require.NoError(t, err)
+				return
+			}
+			var requestValidationError *RequestValidationError
+			require.ErrorAs(t, err, &requestValidationError)
+			require.ErrorContains(t, err, testCase.expectedErrorSubstring)
+		})
+	}
+}
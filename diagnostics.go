@@ -0,0 +1,168 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+const (
+	// DiagnosticsFormatEnvKey is the environment variable that an operator sets to change the format of
+	// messages written to stderr via PluginEnv by default.
+	//
+	// The only supported value is DiagnosticsFormatJSON. See WithJSONDiagnostics and WithEnvDefaultsDisabled
+	// for more details.
+	DiagnosticsFormatEnvKey = "PROTOPLUGIN_DIAGNOSTICS_FORMAT"
+	// DiagnosticsFormatJSON is the value of DiagnosticsFormatEnvKey that enables JSON diagnostics.
+	DiagnosticsFormatJSON = "json"
+)
+
+// DiagnosticSeverity is the severity of a Diagnostic.
+type DiagnosticSeverity int
+
+const (
+	// DiagnosticSeverityInfo is the severity of informational messages, such as those written with PluginEnv.Logf.
+	DiagnosticSeverityInfo DiagnosticSeverity = iota
+	// DiagnosticSeverityWarning is the severity of warnings, such as those written with PluginEnv.Warnf.
+	DiagnosticSeverityWarning
+	// DiagnosticSeverityError is the severity of errors, such as those written with PluginEnv.Errorf.
+	DiagnosticSeverityError
+)
+
+// String implements fmt.Stringer.
+func (d DiagnosticSeverity) String() string {
+	switch d {
+	case DiagnosticSeverityInfo:
+		return "info"
+	case DiagnosticSeverityWarning:
+		return "warning"
+	case DiagnosticSeverityError:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// Diagnostic is a structured message about the input or output of a plugin.
+//
+// Diagnostics are written to stderr with PluginEnv.Report.
+type Diagnostic struct {
+	// Severity is the severity of the Diagnostic.
+	Severity DiagnosticSeverity
+	// Code is an optional machine-readable identifier for the kind of Diagnostic, for example "FIELD_NAME_CASE".
+	Code string
+	// Message is the human-readable message.
+	Message string
+	// File is the optional path of the file the Diagnostic applies to, for example "foo/bar.proto".
+	File string
+	// Path is the optional path of the element within File that the Diagnostic applies to, as used by
+	// SourceCodeInfo.Location.path.
+	//
+	// This is only used if File is set.
+	Path []int32
+}
+
+// WithJSONDiagnostics returns a new RunOption that says to write all messages from PluginEnv.Logf, PluginEnv.Warnf,
+// PluginEnv.Errorf, and PluginEnv.Report to stderr as JSON lines, so that build systems and editors can parse
+// diagnostics instead of parsing free-form text.
+//
+// Each message is written as a single-line JSON object followed by a newline, with the following keys:
+//
+//   - "plugin": the name of the plugin as returned by PluginEnv.Name, omitted if empty.
+//   - "severity": one of "info", "warning", or "error".
+//   - "code": the code of the Diagnostic, omitted if empty.
+//   - "message": the message, without a trailing newline.
+//   - "file": the file of the Diagnostic, omitted if empty.
+//   - "path": the path of the Diagnostic, omitted if empty.
+//
+// If the plugin returns an error from Main, the error is also written as a JSON line. Colorization via
+// WithColorizedLogs is never applied to JSON lines.
+//
+// Operators can enable this behavior without this option by setting DiagnosticsFormatEnvKey to
+// DiagnosticsFormatJSON. See WithEnvDefaultsDisabled for more details.
+//
+// This option can be passed to Main or Run.
+//
+// The default is to write messages as free-form text.
+func WithJSONDiagnostics() RunOption {
+	return optsFunc(func(opts *opts) {
+		opts.jsonDiagnostics = true
+	})
+}
+
+// Report writes the Diagnostic to Stderr.
+//
+// If WithJSONDiagnostics was specified, the Diagnostic is written as a JSON line. Otherwise, the Diagnostic
+// is written in the same format as Logf, Warnf, or Errorf depending on the severity, with the message prefixed
+// with the file and suffixed with the code if present, for example "protoc-gen-foo: warning: foo.proto: bad (CODE)".
+// If Stderr is nil, this is a no-op.
+func (p PluginEnv) Report(diagnostic Diagnostic) {
+	if p.Stderr == nil {
+		return
+	}
+	if p.jsonDiagnostics {
+		writeJSONDiagnostic(p.Stderr, p.Name(), diagnostic)
+		return
+	}
+	message := strings.TrimSuffix(diagnostic.Message, "\n")
+	if diagnostic.File != "" {
+		message = diagnostic.File + ": " + message
+	}
+	if diagnostic.Code != "" {
+		message = message + " (" + diagnostic.Code + ")"
+	}
+	switch diagnostic.Severity {
+	case DiagnosticSeverityWarning:
+		p.Warnf("%s", message)
+	case DiagnosticSeverityError:
+		p.Errorf("%s", message)
+	default:
+		p.Logf("%s", message)
+	}
+}
+
+// *** PRIVATE ***
+
+type jsonDiagnostic struct {
+	Plugin   string  `json:"plugin,omitempty"`
+	Severity string  `json:"severity"`
+	Code     string  `json:"code,omitempty"`
+	Message  string  `json:"message"`
+	File     string  `json:"file,omitempty"`
+	Path     []int32 `json:"path,omitempty"`
+}
+
+// writeJSONDiagnostic writes the Diagnostic as a JSON line to the writer.
+//
+// Errors are ignored, as with all writes to stderr.
+func writeJSONDiagnostic(writer io.Writer, pluginName string, diagnostic Diagnostic) {
+	jsonDiagnostic := &jsonDiagnostic{
+		Plugin:   pluginName,
+		Severity: diagnostic.Severity.String(),
+		Code:     diagnostic.Code,
+		Message:  strings.TrimSuffix(diagnostic.Message, "\n"),
+		File:     diagnostic.File,
+	}
+	if diagnostic.File != "" {
+		jsonDiagnostic.Path = diagnostic.Path
+	}
+	data, err := json.Marshal(jsonDiagnostic)
+	if err != nil {
+		return
+	}
+	_, _ = writer.Write(append(data, '\n'))
+}
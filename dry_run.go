@@ -0,0 +1,138 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// DryRunReport is a report of the files a Handler would have generated.
+//
+// See WithDryRun for more details.
+type DryRunReport struct {
+	// Files are the files that were added to the response, in the order they were added.
+	Files []DryRunFile `json:"files,omitempty"`
+	// Duration is the total duration of the Handler invocation.
+	Duration time.Duration `json:"duration"`
+}
+
+// DryRunFile is a file that a Handler would have generated.
+type DryRunFile struct {
+	// Name is the name of the file.
+	Name string `json:"name"`
+	// InsertionPoint is the insertion point of the file, if any.
+	InsertionPoint string `json:"insertion_point,omitempty"`
+	// Size is the size of the content of the file in bytes.
+	Size int `json:"size"`
+	// Elapsed is the duration from the start of the Handler invocation until the file was added.
+	Elapsed time.Duration `json:"elapsed"`
+}
+
+// WithDryRun returns a new RunOption that will result in the Handler being invoked in dry-run mode.
+//
+// In dry-run mode, the Handler is invoked as normal, however the content of all files added to the
+// ResponseWriter is discarded as soon as it is added. The CodeGeneratorResponse will contain all files with
+// their names and insertion points, but with empty content. After the Handler has completed, the given
+// function is called with a DryRunReport listing the files, their sizes, and when they were added.
+//
+// This is useful for build planners that want to know what a plugin would generate without needing to
+// retain the generated content. If the function returns an error, the plugin fails with that error.
+//
+// This option can be passed to Main or Run.
+func WithDryRun(reportFunc func(*DryRunReport) error) RunOption {
+	return optsFunc(func(opts *opts) {
+		opts.dryRunReportFunc = reportFunc
+	})
+}
+
+// *** PRIVATE ***
+
+// dryRunResponseWriter is a ResponseWriter that records files and discards their contents.
+type dryRunResponseWriter struct {
+	ResponseWriter
+
+	start time.Time
+	files []DryRunFile
+	lock  sync.Mutex
+}
+
+func newDryRunResponseWriter(delegate ResponseWriter) *dryRunResponseWriter {
+	return &dryRunResponseWriter{
+		ResponseWriter: delegate,
+		start:          time.Now(),
+	}
+}
+
+func (d *dryRunResponseWriter) AddFile(name string, content string) {
+	d.AddCodeGeneratorResponseFiles(
+		&pluginpb.CodeGeneratorResponse_File{
+			Name:    proto.String(name),
+			Content: proto.String(content),
+		},
+	)
+}
+
+func (d *dryRunResponseWriter) AddBinaryFile(name string, data []byte) {
+	d.AddFile(name, string(data))
+}
+
+func (d *dryRunResponseWriter) AddCodeGeneratorResponseFiles(files ...*pluginpb.CodeGeneratorResponse_File) {
+	if d.Sealed() {
+		// Let the delegate record the write after sealing.
+		d.ResponseWriter.AddCodeGeneratorResponseFiles(files...)
+		return
+	}
+	elapsed := time.Since(d.start)
+	contentlessFiles := make([]*pluginpb.CodeGeneratorResponse_File, len(files))
+	d.lock.Lock()
+	for i, file := range files {
+		if file == nil {
+			// Leave the nil file in place so that the delegate handles it.
+			continue
+		}
+		d.files = append(
+			d.files,
+			DryRunFile{
+				Name:           file.GetName(),
+				InsertionPoint: file.GetInsertionPoint(),
+				Size:           len(file.GetContent()),
+				Elapsed:        elapsed,
+			},
+		)
+		contentlessFiles[i] = &pluginpb.CodeGeneratorResponse_File{
+			Name:           file.Name,
+			InsertionPoint: file.InsertionPoint,
+		}
+		if file.Content != nil {
+			contentlessFiles[i].Content = proto.String("")
+		}
+	}
+	d.lock.Unlock()
+	d.ResponseWriter.AddCodeGeneratorResponseFiles(contentlessFiles...)
+}
+
+func (d *dryRunResponseWriter) report() *DryRunReport {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return &DryRunReport{
+		Files:    slicesClone(d.files),
+		Duration: time.Since(d.start),
+	}
+}
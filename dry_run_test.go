@@ -0,0 +1,86 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestWithDryRunOption(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	fileDescriptorProtos, err := compile(ctx, map[string][]byte{
+		"a.proto": []byte(`syntax = "proto3"; package foo; message A {}`),
+	})
+	require.NoError(t, err)
+
+	var report *DryRunReport
+	codeGeneratorResponse, err := Invoke(
+		ctx,
+		HandlerFunc(func(_ context.Context, _ PluginEnv, responseWriter ResponseWriter, _ Request) error {
+			responseWriter.AddFile("a.txt", "foo")
+			responseWriter.AddCodeGeneratorResponseFiles(
+				&pluginpb.CodeGeneratorResponse_File{
+					Name:           proto.String("a.txt"),
+					InsertionPoint: proto.String("point"),
+					Content:        proto.String("foobar"),
+				},
+			)
+			return nil
+		}),
+		&pluginpb.CodeGeneratorRequest{
+			FileToGenerate: []string{"a.proto"},
+			ProtoFile:      fileDescriptorProtos,
+		},
+		WithDryRun(func(dryRunReport *DryRunReport) error {
+			report = dryRunReport
+			return nil
+		}),
+	)
+	require.NoError(t, err)
+	require.True(
+		t,
+		proto.Equal(
+			&pluginpb.CodeGeneratorResponse{
+				File: []*pluginpb.CodeGeneratorResponse_File{
+					{
+						Name:    proto.String("a.txt"),
+						Content: proto.String(""),
+					},
+					{
+						Name:           proto.String("a.txt"),
+						InsertionPoint: proto.String("point"),
+						Content:        proto.String(""),
+					},
+				},
+			},
+			codeGeneratorResponse,
+		),
+	)
+	require.NotNil(t, report)
+	require.Len(t, report.Files, 2)
+	require.Equal(t, "a.txt", report.Files[0].Name)
+	require.Equal(t, 3, report.Files[0].Size)
+	require.Equal(t, "point", report.Files[1].InsertionPoint)
+	require.Equal(t, 6, report.Files[1].Size)
+	require.LessOrEqual(t, report.Files[0].Elapsed, report.Files[1].Elapsed)
+	require.LessOrEqual(t, report.Files[1].Elapsed, report.Duration)
+}
@@ -0,0 +1,86 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// EmptyResponseDiagnosticCode is the code of the Diagnostic reported for empty responses with EmptyResponsePolicyWarn.
+const EmptyResponseDiagnosticCode = "EMPTY_RESPONSE"
+
+// EmptyResponsePolicy says how responses that contain no files are handled.
+//
+// A response is empty if the Handler did not add any files, and did not add an error. Handlers can
+// legitimately generate no files, for example if nothing matched a filter, however users often suspect
+// a misconfiguration when a plugin silently produces no output.
+//
+// See WithEmptyResponsePolicy for more details.
+type EmptyResponsePolicy int
+
+const (
+	// EmptyResponsePolicyAllow says to return empty responses as-is.
+	EmptyResponsePolicyAllow EmptyResponsePolicy = iota
+	// EmptyResponsePolicyWarn says to report a warning Diagnostic with the code EmptyResponseDiagnosticCode
+	// for empty responses.
+	EmptyResponsePolicyWarn
+	// EmptyResponsePolicyError says to add an error to empty responses.
+	EmptyResponsePolicyError
+)
+
+// WithEmptyResponsePolicy returns a new RunOption that says to handle responses that contain no files
+// according to the EmptyResponsePolicy.
+//
+// The policy is applied to the response produced by the Handler, before any response transforms. Handlers
+// that want to decide for themselves can use ResponseWriter.FileCount instead.
+//
+// This option can be passed to Main or Run.
+//
+// The default is EmptyResponsePolicyAllow.
+func WithEmptyResponsePolicy(emptyResponsePolicy EmptyResponsePolicy) RunOption {
+	return optsFunc(func(opts *opts) {
+		opts.emptyResponsePolicy = emptyResponsePolicy
+	})
+}
+
+// *** PRIVATE ***
+
+const emptyResponseMessage = "plugin generated no files"
+
+// checkEmptyResponse applies the EmptyResponsePolicy to the CodeGeneratorResponse.
+//
+// With EmptyResponsePolicyError, the error is set on the CodeGeneratorResponse.
+func checkEmptyResponse(
+	pluginEnv PluginEnv,
+	codeGeneratorResponse *pluginpb.CodeGeneratorResponse,
+	emptyResponsePolicy EmptyResponsePolicy,
+) {
+	if len(codeGeneratorResponse.GetFile()) > 0 || codeGeneratorResponse.Error != nil {
+		return
+	}
+	switch emptyResponsePolicy {
+	case EmptyResponsePolicyWarn:
+		pluginEnv.Report(
+			Diagnostic{
+				Severity: DiagnosticSeverityWarning,
+				Code:     EmptyResponseDiagnosticCode,
+				Message:  emptyResponseMessage,
+			},
+		)
+	case EmptyResponsePolicyError:
+		codeGeneratorResponse.Error = proto.String(emptyResponseMessage)
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestEmptyResponsePolicy(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	fileDescriptorProtos, err := compile(ctx, map[string][]byte{
+		"a.proto": []byte(`syntax = "proto3"; package foo; message A {}`),
+	})
+	require.NoError(t, err)
+	codeGeneratorRequest := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{"a.proto"},
+		ProtoFile:      fileDescriptorProtos,
+	}
+	codeGeneratorRequestData, err := proto.Marshal(codeGeneratorRequest)
+	require.NoError(t, err)
+	var fileCounts []int
+	newHandler := func(fileNames ...string) Handler {
+		return HandlerFunc(func(_ context.Context, _ PluginEnv, responseWriter ResponseWriter, _ Request) error {
+			for _, fileName := range fileNames {
+				responseWriter.AddFile(fileName, "")
+			}
+			fileCounts = append(fileCounts, responseWriter.FileCount())
+			return nil
+		})
+	}
+
+	codeGeneratorResponse, err := Invoke(ctx, newHandler(), codeGeneratorRequest)
+	require.NoError(t, err)
+	require.Empty(t, codeGeneratorResponse.GetError())
+
+	stderr := bytes.NewBuffer(nil)
+	err = Run(
+		ctx,
+		Env{
+			Stdin:  bytes.NewReader(codeGeneratorRequestData),
+			Stdout: io.Discard,
+			Stderr: stderr,
+		},
+		newHandler(),
+		WithEmptyResponsePolicy(EmptyResponsePolicyWarn),
+	)
+	require.NoError(t, err)
+	require.Equal(t, "warning: plugin generated no files (EMPTY_RESPONSE)\n", stderr.String())
+
+	codeGeneratorResponse, err = Invoke(ctx, newHandler(), codeGeneratorRequest, WithEmptyResponsePolicy(EmptyResponsePolicyError))
+	require.NoError(t, err)
+	require.Equal(t, "plugin generated no files", codeGeneratorResponse.GetError())
+
+	codeGeneratorResponse, err = Invoke(ctx, newHandler("a.txt", "b.txt"), codeGeneratorRequest, WithEmptyResponsePolicy(EmptyResponsePolicyError))
+	require.NoError(t, err)
+	require.Empty(t, codeGeneratorResponse.GetError())
+
+	// Errors added by the Handler are not replaced.
+	codeGeneratorResponse, err = Invoke(
+		ctx,
+		HandlerFunc(func(_ context.Context, _ PluginEnv, responseWriter ResponseWriter, _ Request) error {
+			responseWriter.AddError("bad input")
+			return nil
+		}),
+		codeGeneratorRequest,
+		WithEmptyResponsePolicy(EmptyResponsePolicyError),
+	)
+	require.NoError(t, err)
+	require.Equal(t, "bad input", codeGeneratorResponse.GetError())
+
+	require.Equal(t, []int{0, 0, 0, 2}, fileCounts)
+}
@@ -14,15 +14,32 @@
 
 package protoplugin
 
-import "io"
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// CacheDirEnvKey is the environment variable that overrides the base directory used by PluginEnv.CacheDir.
+const CacheDirEnvKey = "PROTOPLUGIN_CACHE_DIR"
 
 // Env represents an environment.
 //
 // This wraps items like args, environment variables, and stdio.
 //
 // When calling Main, this uses the values from the os package: os.Args[1:], os.Environ,
-// os.Stdin, os.Stdout, and os.Stderr.
+// os.Stdin, os.Stdout, and os.Stderr. The program name is the base name of os.Args[0],
+// without any ".exe" extension.
 type Env struct {
+	// ProgramName is the name that the program was invoked with, for example "protoc-gen-foo".
+	//
+	// This is optional, and is passed to the plugin via PluginEnv.ProgramName.
+	ProgramName string
 	// Args are the program arguments.
 	//
 	// Does not include the program name.
@@ -44,8 +61,260 @@ type Env struct {
 //
 // When calling Main, this uses the values os.Environ and os.Stderr.
 type PluginEnv struct {
+	// ProgramName is the name that the program was invoked with, for example "protoc-gen-foo".
+	//
+	// This may be empty, for example if the Handler was invoked with Invoke.
+	ProgramName string
 	// Environment are the environment variables.
 	Environ []string
 	// Stderr is the stderr for the plugin.
 	Stderr io.Writer
+
+	// pluginName is the name of the plugin, set by WithPluginName.
+	pluginName string
+	// colorize says to colorize Warnf and Errorf, set by WithColorizedLogs.
+	colorize bool
+	// jsonDiagnostics says to write all messages as JSON lines, set by WithJSONDiagnostics.
+	jsonDiagnostics bool
+	// nowFunc overrides the time returned by Now, set by WithNowFunc.
+	nowFunc func() time.Time
+	// randSource is the source for Rand, shared between all copies of the PluginEnv.
+	//
+	// Nil if the PluginEnv was not provided by this package.
+	randSource *lockedRandSource
+	// metrics are the Metrics for the current CodeGeneratorRequest.
+	//
+	// Nil if the PluginEnv was not provided by this package.
+	metrics *Metrics
+}
+
+// Name returns the name of the plugin.
+//
+// This is the plugin name set by WithPluginName if specified, and ProgramName otherwise. This allows
+// multi-tenant binaries and renamed plugin executables to report the name they were actually invoked with,
+// for example in headers of generated files such as "// Code generated by protoc-gen-foo. DO NOT EDIT.".
+//
+// This may be empty if neither WithPluginName was specified nor ProgramName was set.
+func (p PluginEnv) Name() string {
+	if p.pluginName != "" {
+		return p.pluginName
+	}
+	return p.ProgramName
+}
+
+// LookupEnv retrieves the value of the environment variable named by the key.
+//
+// If the variable is present in Environ, the value (which may be empty) is returned and
+// the boolean is true. Otherwise the returned value will be empty and the boolean will be false.
+// If the key is present multiple times, the last value wins, matching how exec.Cmd.Env
+// handles duplicate keys.
+func (p PluginEnv) LookupEnv(key string) (string, bool) {
+	return lookupEnv(p.Environ, key)
+}
+
+// EnvMap returns the environment variables in Environ as a map from key to value.
+//
+// If a key is present multiple times, the last value wins. Entries that are not of the form
+// "key=value" are ignored. The returned map is a new map on every call, and can be modified.
+func (p PluginEnv) EnvMap() map[string]string {
+	envMap := make(map[string]string, len(p.Environ))
+	for _, env := range p.Environ {
+		if key, value, ok := strings.Cut(env, "="); ok {
+			envMap[key] = value
+		}
+	}
+	return envMap
+}
+
+// Logf writes a formatted message to Stderr.
+//
+// If Name returns a non-empty value, the message is prefixed with the name, for example
+// "protoc-gen-foo: message". A trailing newline is added if not present. Errors writing to Stderr
+// are ignored. If Stderr is nil, this is a no-op.
+func (p PluginEnv) Logf(format string, args ...any) {
+	p.logf(DiagnosticSeverityInfo, "", format, args...)
+}
+
+// Warnf writes a formatted warning to Stderr.
+//
+// This is the same as Logf, except that the message is prefixed with "warning: ", which is
+// colorized if WithColorizedLogs was specified and Stderr is a terminal.
+func (p PluginEnv) Warnf(format string, args ...any) {
+	p.logf(DiagnosticSeverityWarning, ansiYellow, format, args...)
+}
+
+// Errorf writes a formatted error to Stderr.
+//
+// This is the same as Logf, except that the message is prefixed with "error: ", which is
+// colorized if WithColorizedLogs was specified and Stderr is a terminal.
+//
+// This does not result in the plugin failing. To report errors, use ResponseWriter.AddError,
+// or return an error from the Handler.
+func (p PluginEnv) Errorf(format string, args ...any) {
+	p.logf(DiagnosticSeverityError, ansiRed, format, args...)
+}
+
+// CacheDir returns a plugin-scoped directory that the plugin can use to store data between invocations,
+// such as fingerprints and intermediate artifacts for incremental generation. The directory is created
+// if it does not exist.
+//
+// The directory is "<base>/<name>", where the name is the result of Name. If Name is empty, or is not
+// a single path element, an error is returned. The base directory is determined from Environ:
+//
+//   - If CacheDirEnvKey is set to a non-empty value, the value is used as the base directory.
+//   - On Windows, "%LocalAppData%/protoplugin" is used.
+//   - On macOS, "$HOME/Library/Caches/protoplugin" is used.
+//   - Otherwise, "$XDG_CACHE_HOME/protoplugin" is used if XDG_CACHE_HOME is set, and "$HOME/.cache/protoplugin"
+//     is used otherwise.
+//
+// Data in the directory may be deleted at any time by the user, so plugins must be able to function
+// without it. If WithEnvAllowlist is specified, the environment variables above must be allowed.
+func (p PluginEnv) CacheDir() (string, error) {
+	name := p.Name()
+	if name == "" {
+		return "", errors.New("plugin name must be set with WithPluginName or ProgramName to use CacheDir")
+	}
+	if filepath.Base(name) != name || name == "." || name == ".." {
+		return "", fmt.Errorf("plugin name %q must be a single path element to use CacheDir", name)
+	}
+	baseDirPath, err := p.cacheBaseDirPath(runtime.GOOS)
+	if err != nil {
+		return "", err
+	}
+	dirPath := filepath.Join(baseDirPath, name)
+	if err := os.MkdirAll(dirPath, 0o755); err != nil {
+		return "", err
+	}
+	return dirPath, nil
+}
+
+// *** PRIVATE ***
+
+const cacheDirName = "protoplugin"
+
+func (p PluginEnv) cacheBaseDirPath(goos string) (string, error) {
+	if value, _ := p.LookupEnv(CacheDirEnvKey); value != "" {
+		return value, nil
+	}
+	var baseDirPath string
+	switch goos {
+	case "windows":
+		baseDirPath, _ = p.LookupEnv("LocalAppData")
+		if baseDirPath == "" {
+			return "", errors.New("%LocalAppData% is not defined")
+		}
+	case "darwin", "ios":
+		home, _ := p.LookupEnv("HOME")
+		if home == "" {
+			return "", errors.New("$HOME is not defined")
+		}
+		baseDirPath = filepath.Join(home, "Library", "Caches")
+	default:
+		baseDirPath, _ = p.LookupEnv("XDG_CACHE_HOME")
+		if baseDirPath == "" {
+			home, _ := p.LookupEnv("HOME")
+			if home == "" {
+				return "", errors.New("neither $XDG_CACHE_HOME nor $HOME are defined")
+			}
+			baseDirPath = filepath.Join(home, ".cache")
+		}
+	}
+	return filepath.Join(baseDirPath, cacheDirName), nil
+}
+
+const (
+	ansiRed    = "\x1b[31m"
+	ansiYellow = "\x1b[33m"
+	ansiReset  = "\x1b[0m"
+)
+
+func (p PluginEnv) logf(severity DiagnosticSeverity, color string, format string, args ...any) {
+	if p.Stderr == nil {
+		return
+	}
+	if p.jsonDiagnostics {
+		writeJSONDiagnostic(
+			p.Stderr,
+			p.Name(),
+			Diagnostic{
+				Severity: severity,
+				Message:  fmt.Sprintf(format, args...),
+			},
+		)
+		return
+	}
+	var level string
+	if severity != DiagnosticSeverityInfo {
+		level = severity.String()
+	}
+	var builder strings.Builder
+	if name := p.Name(); name != "" {
+		_, _ = builder.WriteString(name)
+		_, _ = builder.WriteString(": ")
+	}
+	if level != "" {
+		if p.colorize {
+			_, _ = builder.WriteString(color)
+			_, _ = builder.WriteString(level)
+			_, _ = builder.WriteString(":")
+			_, _ = builder.WriteString(ansiReset)
+			_, _ = builder.WriteString(" ")
+		} else {
+			_, _ = builder.WriteString(level)
+			_, _ = builder.WriteString(": ")
+		}
+	}
+	_, _ = fmt.Fprintf(&builder, format, args...)
+	if !strings.HasSuffix(builder.String(), "\n") {
+		_, _ = builder.WriteString("\n")
+	}
+	_, _ = io.WriteString(p.Stderr, builder.String())
+}
+
+// programNameFromArg returns the program name from the first argument of the program, as given by os.Args[0].
+func programNameFromArg(arg string) string {
+	if arg == "" {
+		return ""
+	}
+	return strings.TrimSuffix(filepath.Base(arg), ".exe")
+}
+
+// isTerminal returns true if the writer is a terminal.
+//
+// NO_COLOR (see https://no-color.org) is respected by the caller, not here.
+func isTerminal(writer io.Writer) bool {
+	file, ok := writer.(*os.File)
+	if !ok {
+		return false
+	}
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return fileInfo.Mode()&os.ModeCharDevice != 0
+}
+
+// filterEnviron returns the entries of environ whose keys are within allowlist.
+func filterEnviron(environ []string, allowlist map[string]struct{}) []string {
+	filteredEnviron := make([]string, 0, len(allowlist))
+	for _, env := range environ {
+		key, _, _ := strings.Cut(env, "=")
+		if _, ok := allowlist[key]; ok {
+			filteredEnviron = append(filteredEnviron, env)
+		}
+	}
+	return filteredEnviron
+}
+
+// lookupEnv looks up the value of the environment variable with the given key within environ.
+//
+// If the key is present multiple times, the last value wins, matching how exec.Cmd.Env
+// handles duplicate keys.
+func lookupEnv(environ []string, key string) (string, bool) {
+	for i := len(environ) - 1; i >= 0; i-- {
+		if envKey, value, ok := strings.Cut(environ[i], "="); ok && envKey == key {
+			return value, true
+		}
+	}
+	return "", false
 }
@@ -0,0 +1,140 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const (
+	// LenientValidationEnvKey is the environment variable that an operator sets to a true value, as parsed
+	// by strconv.ParseBool, to enable lenient validation by default, printing validation errors as warnings
+	// to stderr.
+	//
+	// See WithLenientValidation and WithEnvDefaultsDisabled for more details.
+	LenientValidationEnvKey = "PROTOPLUGIN_LENIENT"
+	// DeterministicMarshalEnvKey is the environment variable that an operator sets to a true value, as parsed
+	// by strconv.ParseBool, to enable deterministic marshaling by default.
+	//
+	// See WithDeterministicMarshal and WithEnvDefaultsDisabled for more details.
+	DeterministicMarshalEnvKey = "PROTOPLUGIN_DETERMINISTIC_MARSHAL"
+	// EagerValidationEnvKey is the environment variable that an operator sets to a true value, as parsed
+	// by strconv.ParseBool, to enable eager validation by default.
+	//
+	// See WithEagerValidation and WithEnvDefaultsDisabled for more details.
+	EagerValidationEnvKey = "PROTOPLUGIN_EAGER_VALIDATION"
+	// UTF8ValidationEnvKey is the environment variable that an operator sets to a true value, as parsed
+	// by strconv.ParseBool, to enable UTF-8 validation by default.
+	//
+	// See WithUTF8Validation and WithEnvDefaultsDisabled for more details.
+	UTF8ValidationEnvKey = "PROTOPLUGIN_UTF8_VALIDATION"
+)
+
+// WithEnvDefaultsDisabled returns a new RunOption that says to not read default options from the environment.
+//
+// By default, Main and Run read default options from the environment variables LenientValidationEnvKey,
+// DeterministicMarshalEnvKey, EagerValidationEnvKey, UTF8ValidationEnvKey, and DiagnosticsFormatEnvKey.
+// This allows operators to change the behavior of the plugin framework, for example in CI, without modifying
+// and re-releasing every plugin. These environment variables can only enable behavior - options given to
+// Main or Run always take precedence, and an environment variable set to a false value has no effect. If an
+// environment variable is set to an invalid value, Main and Run return an error.
+//
+// Environment variables are not read by Invoke or ExecuteHandler, as these do not operate on an Env.
+//
+// This option can be passed to Main or Run.
+//
+// The default is to read default options from the environment.
+func WithEnvDefaultsDisabled() RunOption {
+	return optsFunc(func(opts *opts) {
+		opts.envDefaultsDisabled = true
+	})
+}
+
+// *** PRIVATE ***
+
+// applyEnvDefaults applies the default options set in the environment to opts.
+//
+// Options already set on opts take precedence.
+func applyEnvDefaults(env Env, opts *opts) error {
+	if opts.envDefaultsDisabled {
+		return nil
+	}
+	switch value, _ := lookupEnv(env.Environ, DiagnosticsFormatEnvKey); value {
+	case "":
+	case DiagnosticsFormatJSON:
+		opts.jsonDiagnostics = true
+	default:
+		return fmt.Errorf("invalid value %q for environment variable %s: must be %q", value, DiagnosticsFormatEnvKey, DiagnosticsFormatJSON)
+	}
+	lenientValidation, err := lookupEnvBool(env.Environ, LenientValidationEnvKey)
+	if err != nil {
+		return err
+	}
+	if lenientValidation && opts.lenientValidateErrorFunc == nil {
+		pluginEnv := PluginEnv{
+			ProgramName: env.ProgramName,
+			Stderr:      env.Stderr,
+			pluginName:  opts.pluginName,
+			// Set above if enabled via the environment.
+			jsonDiagnostics: opts.jsonDiagnostics,
+		}
+		opts.lenientValidateErrorFunc = func(err error) {
+			pluginEnv.Warnf("%v", err)
+		}
+	}
+	deterministicMarshal, err := lookupEnvBool(env.Environ, DeterministicMarshalEnvKey)
+	if err != nil {
+		return err
+	}
+	if deterministicMarshal {
+		opts.deterministicMarshal = true
+	}
+	// Defaults are prepended so that explicitly-given ResponseWriterOptions take precedence.
+	var defaultResponseWriterOptions []ResponseWriterOption
+	eagerValidation, err := lookupEnvBool(env.Environ, EagerValidationEnvKey)
+	if err != nil {
+		return err
+	}
+	if eagerValidation {
+		defaultResponseWriterOptions = append(defaultResponseWriterOptions, ResponseWriterWithEagerValidation())
+	}
+	utf8Validation, err := lookupEnvBool(env.Environ, UTF8ValidationEnvKey)
+	if err != nil {
+		return err
+	}
+	if utf8Validation {
+		defaultResponseWriterOptions = append(defaultResponseWriterOptions, ResponseWriterWithUTF8Validation())
+	}
+	if len(defaultResponseWriterOptions) > 0 {
+		opts.responseWriterOptions = append(defaultResponseWriterOptions, opts.responseWriterOptions...)
+	}
+	return nil
+}
+
+// lookupEnvBool looks up the boolean value of the environment variable with the given key within environ.
+//
+// An unset or empty environment variable is false.
+func lookupEnvBool(environ []string, key string) (bool, error) {
+	value, _ := lookupEnv(environ, key)
+	if value == "" {
+		return false, nil
+	}
+	boolValue, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid value %q for environment variable %s: must be a boolean", value, key)
+	}
+	return boolValue, nil
+}
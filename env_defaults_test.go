@@ -0,0 +1,98 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestEnvDefaults(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	fileDescriptorProtos, err := compile(ctx, map[string][]byte{
+		"a.proto": []byte(`syntax = "proto3"; package foo; message A {}`),
+	})
+	require.NoError(t, err)
+	codeGeneratorRequestData, err := proto.Marshal(
+		&pluginpb.CodeGeneratorRequest{
+			FileToGenerate: []string{"a.proto"},
+			ProtoFile:      fileDescriptorProtos,
+		},
+	)
+	require.NoError(t, err)
+
+	run := func(environ []string, runOptions ...RunOption) (string, error) {
+		stderr := bytes.NewBuffer(nil)
+		err := Run(
+			ctx,
+			Env{
+				Environ: environ,
+				Stdin:   bytes.NewReader(codeGeneratorRequestData),
+				Stdout:  io.Discard,
+				Stderr:  stderr,
+			},
+			HandlerFunc(func(_ context.Context, _ PluginEnv, responseWriter ResponseWriter, _ Request) error {
+				responseWriter.AddFile("./a.txt", "a")
+				return nil
+			}),
+			runOptions...,
+		)
+		return stderr.String(), err
+	}
+
+	_, err = run(nil)
+	responseValidationError := &ResponseValidationError{}
+	require.ErrorAs(t, err, &responseValidationError)
+
+	stderr, err := run([]string{LenientValidationEnvKey + "=1"})
+	require.NoError(t, err)
+	require.Contains(t, stderr, "warning: ")
+
+	_, err = run([]string{LenientValidationEnvKey + "=false"})
+	require.ErrorAs(t, err, &responseValidationError)
+
+	_, err = run([]string{LenientValidationEnvKey + "=1"}, WithEnvDefaultsDisabled())
+	require.ErrorAs(t, err, &responseValidationError)
+
+	// Explicit options take precedence.
+	var lenientErrs []error
+	stderr, err = run(
+		[]string{LenientValidationEnvKey + "=true"},
+		WithLenientValidation(func(err error) { lenientErrs = append(lenientErrs, err) }),
+	)
+	require.NoError(t, err)
+	require.Empty(t, stderr)
+	require.Len(t, lenientErrs, 1)
+
+	_, err = run([]string{LenientValidationEnvKey + "=yes"})
+	require.ErrorContains(t, err, LenientValidationEnvKey)
+	_, err = run([]string{UTF8ValidationEnvKey + "=yes"})
+	require.ErrorContains(t, err, UTF8ValidationEnvKey)
+
+	stderr, err = run([]string{LenientValidationEnvKey + "=1", DiagnosticsFormatEnvKey + "=" + DiagnosticsFormatJSON})
+	require.NoError(t, err)
+	require.Contains(t, stderr, `"severity":"warning"`)
+	_, err = run([]string{DiagnosticsFormatEnvKey + "=yaml"})
+	require.ErrorContains(t, err, DiagnosticsFormatEnvKey)
+}
@@ -0,0 +1,215 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPluginEnvLookupEnv(t *testing.T) {
+	t.Parallel()
+
+	pluginEnv := PluginEnv{
+		Environ: []string{"FOO=foo", "EMPTY=", "INVALID", "FOO=bar", "BAZ=a=b"},
+	}
+	value, ok := pluginEnv.LookupEnv("FOO")
+	require.True(t, ok)
+	require.Equal(t, "bar", value)
+	value, ok = pluginEnv.LookupEnv("EMPTY")
+	require.True(t, ok)
+	require.Empty(t, value)
+	value, ok = pluginEnv.LookupEnv("BAZ")
+	require.True(t, ok)
+	require.Equal(t, "a=b", value)
+	_, ok = pluginEnv.LookupEnv("INVALID")
+	require.False(t, ok)
+	_, ok = pluginEnv.LookupEnv("MISSING")
+	require.False(t, ok)
+
+	require.Equal(
+		t,
+		map[string]string{
+			"FOO":   "bar",
+			"EMPTY": "",
+			"BAZ":   "a=b",
+		},
+		pluginEnv.EnvMap(),
+	)
+	require.Empty(t, PluginEnv{}.EnvMap())
+}
+
+func TestPluginEnvLogf(t *testing.T) {
+	t.Parallel()
+
+	stderr := bytes.NewBuffer(nil)
+	pluginEnv := PluginEnv{Stderr: stderr}
+	pluginEnv.Logf("hello %s", "world")
+	pluginEnv.Warnf("careful\n")
+	pluginEnv.Errorf("bad %d", 1)
+	require.Equal(t, "hello world\nwarning: careful\nerror: bad 1\n", stderr.String())
+
+	stderr.Reset()
+	pluginEnv = PluginEnv{Stderr: stderr, pluginName: "protoc-gen-foo"}
+	pluginEnv.Logf("hello")
+	pluginEnv.Warnf("careful")
+	require.Equal(t, "protoc-gen-foo: hello\nprotoc-gen-foo: warning: careful\n", stderr.String())
+
+	stderr.Reset()
+	pluginEnv = PluginEnv{Stderr: stderr, pluginName: "protoc-gen-foo", colorize: true}
+	pluginEnv.Logf("hello")
+	pluginEnv.Errorf("bad")
+	require.Equal(t, "protoc-gen-foo: hello\nprotoc-gen-foo: \x1b[31merror:\x1b[0m bad\n", stderr.String())
+
+	stderr.Reset()
+	pluginEnv = PluginEnv{Stderr: stderr, ProgramName: "protoc-gen-bar"}
+	pluginEnv.Warnf("careful")
+	require.Equal(t, "protoc-gen-bar: warning: careful\n", stderr.String())
+
+	// A nil Stderr is a no-op.
+	PluginEnv{}.Logf("hello")
+}
+
+func TestPluginEnvName(t *testing.T) {
+	t.Parallel()
+
+	require.Empty(t, PluginEnv{}.Name())
+	require.Equal(t, "protoc-gen-bar", PluginEnv{ProgramName: "protoc-gen-bar"}.Name())
+	// WithPluginName takes precedence over the program name.
+	require.Equal(t, "protoc-gen-foo", PluginEnv{ProgramName: "protoc-gen-bar", pluginName: "protoc-gen-foo"}.Name())
+
+	require.Empty(t, programNameFromArg(""))
+	require.Equal(t, "protoc-gen-foo", programNameFromArg("protoc-gen-foo"))
+	require.Equal(t, "protoc-gen-foo", programNameFromArg(filepath.Join("usr", "bin", "protoc-gen-foo")))
+	require.Equal(t, "protoc-gen-foo", programNameFromArg("protoc-gen-foo.exe"))
+}
+
+func TestPluginEnvCacheDir(t *testing.T) {
+	t.Parallel()
+
+	_, err := PluginEnv{}.CacheDir()
+	require.Error(t, err)
+
+	baseDirPath := t.TempDir()
+	pluginEnv := PluginEnv{
+		Environ:    []string{CacheDirEnvKey + "=" + baseDirPath},
+		pluginName: "protoc-gen-foo",
+	}
+	dirPath, err := pluginEnv.CacheDir()
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(baseDirPath, "protoc-gen-foo"), dirPath)
+	fileInfo, err := os.Stat(dirPath)
+	require.NoError(t, err)
+	require.True(t, fileInfo.IsDir())
+
+	// ProgramName is used if WithPluginName was not specified.
+	dirPath, err = PluginEnv{
+		ProgramName: "protoc-gen-bar",
+		Environ:     []string{CacheDirEnvKey + "=" + baseDirPath},
+	}.CacheDir()
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(baseDirPath, "protoc-gen-bar"), dirPath)
+
+	for _, invalidName := range []string{".", "..", "foo/bar", "../foo"} {
+		_, err = PluginEnv{
+			Environ:    []string{CacheDirEnvKey + "=" + baseDirPath},
+			pluginName: invalidName,
+		}.CacheDir()
+		require.Error(t, err, invalidName)
+	}
+
+	testCases := []struct {
+		goos                string
+		environ             []string
+		expectedBaseDirPath string
+	}{
+		{
+			goos:                "linux",
+			environ:             []string{"HOME=/home/foo", "XDG_CACHE_HOME=/xdg"},
+			expectedBaseDirPath: filepath.Join("/xdg", "protoplugin"),
+		},
+		{
+			goos:                "linux",
+			environ:             []string{"HOME=/home/foo"},
+			expectedBaseDirPath: filepath.Join("/home/foo", ".cache", "protoplugin"),
+		},
+		{
+			goos:                "darwin",
+			environ:             []string{"HOME=/Users/foo", "XDG_CACHE_HOME=/xdg"},
+			expectedBaseDirPath: filepath.Join("/Users/foo", "Library", "Caches", "protoplugin"),
+		},
+		{
+			goos:                "windows",
+			environ:             []string{"LocalAppData=/appdata"},
+			expectedBaseDirPath: filepath.Join("/appdata", "protoplugin"),
+		},
+		{
+			goos:                "windows",
+			environ:             []string{"LocalAppData=/appdata", CacheDirEnvKey + "=/override"},
+			expectedBaseDirPath: "/override",
+		},
+	}
+	for _, testCase := range testCases {
+		baseDirPath, err := PluginEnv{Environ: testCase.environ}.cacheBaseDirPath(testCase.goos)
+		require.NoError(t, err)
+		require.Equal(t, testCase.expectedBaseDirPath, baseDirPath)
+	}
+	_, err = PluginEnv{}.cacheBaseDirPath("linux")
+	require.Error(t, err)
+}
+
+func TestPluginEnvReport(t *testing.T) {
+	t.Parallel()
+
+	stderr := bytes.NewBuffer(nil)
+	pluginEnv := PluginEnv{Stderr: stderr, pluginName: "protoc-gen-foo"}
+	pluginEnv.Report(
+		Diagnostic{
+			Severity: DiagnosticSeverityWarning,
+			Code:     "CODE",
+			Message:  "bad",
+			File:     "a.proto",
+			Path:     []int32{4, 0},
+		},
+	)
+	pluginEnv.Report(Diagnostic{Message: "hello"})
+	require.Equal(t, "protoc-gen-foo: warning: a.proto: bad (CODE)\nprotoc-gen-foo: hello\n", stderr.String())
+
+	stderr.Reset()
+	pluginEnv = PluginEnv{Stderr: stderr, pluginName: "protoc-gen-foo", colorize: true, jsonDiagnostics: true}
+	pluginEnv.Report(
+		Diagnostic{
+			Severity: DiagnosticSeverityWarning,
+			Code:     "CODE",
+			Message:  "bad",
+			File:     "a.proto",
+			Path:     []int32{4, 0},
+		},
+	)
+	pluginEnv.Logf("hello\n")
+	pluginEnv.Errorf("bad %d", 1)
+	require.Equal(
+		t,
+		`{"plugin":"protoc-gen-foo","severity":"warning","code":"CODE","message":"bad","file":"a.proto","path":[4,0]}
+{"plugin":"protoc-gen-foo","severity":"info","message":"hello"}
+{"plugin":"protoc-gen-foo","severity":"error","message":"bad 1"}
+`,
+		stderr.String(),
+	)
+}
@@ -15,27 +15,224 @@
 package protoplugin
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 )
 
-// unknownArgumentsError is the error returned if Main or Run are given arguments that are unknown.
+// UnknownArgumentsError is the error returned if Main or Run are given arguments that are unknown.
 //
-// The only known argument is --version if WithVersion is specified. If any other argumnt is
+// The only known argument is --version if WithVersion is specified. If any other argument is
 // specified to the plugin, or WithVersion is not specified, this error is returned.
-type unknownArgumentsError struct {
-	args []string
+type UnknownArgumentsError struct {
+	// Args are the arguments that were given to the plugin.
+	Args []string
 }
 
 func newUnknownArgumentsError(args []string) error {
-	return &unknownArgumentsError{args: args}
+	return &UnknownArgumentsError{Args: args}
 }
 
-func (a *unknownArgumentsError) Error() string {
-	if len(a.args) == 1 {
-		return "unknown argument: " + a.args[0]
+// Error implements error.
+func (u *UnknownArgumentsError) Error() string {
+	if len(u.Args) == 1 {
+		return "unknown argument: " + u.Args[0]
 	}
-	return "unknown arguments: " + strings.Join(a.args, " ")
+	return "unknown arguments: " + strings.Join(u.Args, " ")
+}
+
+// RequestValidationError is the error returned if a CodeGeneratorRequest is invalid.
+//
+// This is returned from NewRequest, and therefore from Run and Main if the plugin is given
+// an invalid CodeGeneratorRequest.
+type RequestValidationError struct {
+	// Err is the underlying validation error.
+	Err error
+}
+
+func newRequestValidationError(err error) *RequestValidationError {
+	return &RequestValidationError{Err: err}
+}
+
+// Error implements error.
+func (r *RequestValidationError) Error() string {
+	return r.Err.Error()
+}
+
+// Unwrap returns the underlying validation error.
+func (r *RequestValidationError) Unwrap() error {
+	return r.Err
+}
+
+// RequestTooLargeError is the error returned if a serialized CodeGeneratorRequest exceeds the limit set
+// by WithMaxRequestBytes.
+type RequestTooLargeError struct {
+	// MaxRequestBytes is the limit that was exceeded.
+	MaxRequestBytes int64
+}
+
+func newRequestTooLargeError(maxRequestBytes int64) *RequestTooLargeError {
+	return &RequestTooLargeError{MaxRequestBytes: maxRequestBytes}
+}
+
+// Error implements error.
+func (r *RequestTooLargeError) Error() string {
+	return fmt.Sprintf("CodeGeneratorRequest exceeds the maximum size of %d bytes", r.MaxRequestBytes)
+}
+
+// RequestMutationError is the error returned if the CodeGeneratorRequest was modified while the Handler was
+// invoked, and WithFrozenRequest was specified.
+type RequestMutationError struct {
+	// ModifiedFileNames are the names of the FileDescriptorProtos within the proto_file and
+	// source_file_descriptors fields that were modified.
+	//
+	// This may be empty if only other fields of the CodeGeneratorRequest were modified, or if
+	// FileDescriptorProtos were added or removed.
+	ModifiedFileNames []string
+}
+
+func newRequestMutationError(modifiedFileNames []string) *RequestMutationError {
+	return &RequestMutationError{ModifiedFileNames: modifiedFileNames}
+}
+
+// Error implements error.
+func (r *RequestMutationError) Error() string {
+	message := "CodeGeneratorRequest was modified by the Handler, but CodeGeneratorRequests must not be modified"
+	if len(r.ModifiedFileNames) > 0 {
+		message += " (modified files: " + strings.Join(r.ModifiedFileNames, ", ") + ")"
+	}
+	return message
+}
+
+// SourceRetentionOptionsUnavailableError is the error returned if source-retention options were requested,
+// but the CodeGeneratorRequest did not have source_file_descriptors populated.
+//
+// This is returned from Request.WithSourceRetentionOptions, and is the warning produced by
+// Request.WithSourceRetentionOptionsIfAvailable. This is almost always because the protobuf compiler
+// is too old.
+type SourceRetentionOptionsUnavailableError struct {
+	// CompilerVersion is the version of the compiler that invoked the plugin.
+	//
+	// This is nil if the compiler_version field was not present on the CodeGeneratorRequest.
+	CompilerVersion *CompilerVersion
+}
+
+func newSourceRetentionOptionsUnavailableError(compilerVersion *CompilerVersion) *SourceRetentionOptionsUnavailableError {
+	return &SourceRetentionOptionsUnavailableError{CompilerVersion: compilerVersion}
+}
+
+// Error implements error.
+func (s *SourceRetentionOptionsUnavailableError) Error() string {
+	message := "source_file_descriptors not set on CodeGeneratorRequest but source-retention options requested"
+	if s.CompilerVersion != nil {
+		message += " (compiler version " + s.CompilerVersion.String() + ")"
+	}
+	return message + " - you likely need to upgrade your protobuf compiler"
+}
+
+// CompilerRequirementsError is the error returned if the compiler that invoked the plugin does not satisfy
+// the CompilerRequirements of the plugin.
+//
+// This is returned from CheckCompilerRequirements, and is added to the CodeGeneratorResponse if
+// WithCompilerRequirements is specified.
+type CompilerRequirementsError struct {
+	// CompilerVersion is the version of the compiler that invoked the plugin.
+	//
+	// This is nil if the compiler_version field was not present on the CodeGeneratorRequest.
+	CompilerVersion *CompilerVersion
+	// UnsatisfiedRequirements are the CompilerRequirements that were not satisfied, in the order given.
+	//
+	// Always non-empty.
+	UnsatisfiedRequirements []CompilerRequirement
+
+	generatorName string
+}
+
+func newCompilerRequirementsError(
+	compilerVersion *CompilerVersion,
+	generatorName string,
+	unsatisfiedRequirements []CompilerRequirement,
+) *CompilerRequirementsError {
+	return &CompilerRequirementsError{
+		CompilerVersion:         compilerVersion,
+		UnsatisfiedRequirements: unsatisfiedRequirements,
+		generatorName:           generatorName,
+	}
+}
+
+// Error implements error.
+func (c *CompilerRequirementsError) Error() string {
+	var invokedMessage string
+	switch {
+	case c.CompilerVersion == nil:
+		invokedMessage = "the compiler did not provide its version"
+	case c.generatorName != "":
+		invokedMessage = "was invoked with " + c.generatorName + " " + c.CompilerVersion.String()
+	default:
+		invokedMessage = "was invoked with compiler version " + c.CompilerVersion.String()
+	}
+	if len(c.UnsatisfiedRequirements) == 1 {
+		return "this plugin requires a " + compilerRequirementMessage(c.UnsatisfiedRequirements[0]) + ", but " + invokedMessage
+	}
+	var builder strings.Builder
+	_, _ = builder.WriteString("this plugin requires a newer compiler, but ")
+	_, _ = builder.WriteString(invokedMessage)
+	_, _ = builder.WriteString(":")
+	for _, requirement := range c.UnsatisfiedRequirements {
+		_, _ = builder.WriteString("\n  - ")
+		_, _ = builder.WriteString(compilerRequirementMessage(requirement))
+	}
+	return builder.String()
+}
+
+// ResponseValidationError is the error returned if a CodeGeneratorResponse constructed by a
+// ResponseWriter is invalid.
+//
+// This is returned from ResponseWriter.ToCodeGeneratorResponse, and therefore from Run and Main
+// if the Handler writes an invalid response.
+type ResponseValidationError struct {
+	// FileName is the name of the CodeGeneratorResponse.File that was invalid.
+	//
+	// This is only set if the error was specific to a single file with a non-empty name,
+	// for example if a file name was duplicated or not normalized.
+	FileName string
+	// Err is the underlying validation error.
+	Err error
+}
+
+func newResponseValidationError(err error) *ResponseValidationError {
+	responseValidationError := &ResponseValidationError{Err: err}
+	unnormalizedError := &unnormalizedCodeGeneratorResponseFileNameError{}
+	duplicateError := &duplicateCodeGeneratorResponseFileNameError{}
+	limitError := &responseLimitError{}
+	invalidUTF8Error := &invalidUTF8ContentError{}
+	fileNamePortabilityError := &fileNamePortabilityError{}
+	insertionPointTargetError := &insertionPointTargetError{}
+	switch {
+	case errors.As(err, &unnormalizedError):
+		responseValidationError.FileName = unnormalizedError.name
+	case errors.As(err, &duplicateError):
+		responseValidationError.FileName = duplicateError.name
+	case errors.As(err, &limitError):
+		responseValidationError.FileName = limitError.name
+	case errors.As(err, &invalidUTF8Error):
+		responseValidationError.FileName = invalidUTF8Error.name
+	case errors.As(err, &fileNamePortabilityError):
+		responseValidationError.FileName = fileNamePortabilityError.name
+	case errors.As(err, &insertionPointTargetError):
+		responseValidationError.FileName = insertionPointTargetError.name
+	}
+	return responseValidationError
+}
+
+// Error implements error.
+func (r *ResponseValidationError) Error() string {
+	return r.Err.Error()
+}
+
+// Unwrap returns the underlying validation error.
+func (r *ResponseValidationError) Unwrap() error {
+	return r.Err
 }
 
 // unnormalizedCodeGeneratorResponseFileNameError is the error returned if a
@@ -99,3 +296,165 @@ func (d *duplicateCodeGeneratorResponseFileNameError) Error() string {
 	}
 	return fmt.Sprintf("duplicate generated file name %q.%s", d.name, warningMessage)
 }
+
+// responseLimitError is the error returned if a file added to a ResponseWriter exceeds the
+// limits set by ResponseWriterWithLimits.
+type responseLimitError struct {
+	name    string
+	message string
+}
+
+func newResponseLimitError(name string, message string) *responseLimitError {
+	return &responseLimitError{
+		name:    name,
+		message: message,
+	}
+}
+
+func (r *responseLimitError) Error() string {
+	return fmt.Sprintf("generated file %q: %s", r.name, r.message)
+}
+
+// invalidUTF8ContentError is the error returned if a CodeGeneratorResponse.File has content
+// that is not valid UTF-8 and UTF-8 validation is enabled.
+//
+// This may be printed as a warning instead of returned as an error, as this is recoverable.
+type invalidUTF8ContentError struct {
+	name      string
+	isWarning bool
+}
+
+func newInvalidUTF8ContentError(name string, isWarning bool) *invalidUTF8ContentError {
+	return &invalidUTF8ContentError{
+		name:      name,
+		isWarning: isWarning,
+	}
+}
+
+func (i *invalidUTF8ContentError) Error() string {
+	var warningMessage string
+	if i.isWarning {
+		warningMessage = ` Generation will continue with invalid sequences replaced by the Unicode replacement character.`
+	}
+	return fmt.Sprintf("generated file %q has content that is not valid UTF-8.%s", i.name, warningMessage)
+}
+
+// fileNamePortabilityError is the error returned if the name of a CodeGeneratorResponse.File
+// cannot be portably written to all common filesystems and file name portability checks are enabled.
+//
+// This may be printed as a warning instead of returned as an error.
+type fileNamePortabilityError struct {
+	name      string
+	message   string
+	isWarning bool
+}
+
+func newFileNamePortabilityError(name string, message string, isWarning bool) *fileNamePortabilityError {
+	return &fileNamePortabilityError{
+		name:      name,
+		message:   message,
+		isWarning: isWarning,
+	}
+}
+
+func (f *fileNamePortabilityError) Error() string {
+	var warningMessage string
+	if f.isWarning {
+		warningMessage = ` Generation will continue without error here, but the file may fail to be written or checked out on some platforms.`
+	}
+	return fmt.Sprintf("generated file %q: %s.%s", f.name, f.message, warningMessage)
+}
+
+// insertionPointTargetError is the error returned if a CodeGeneratorResponse.File with an insertion point
+// targets a file that was not generated earlier in the response and insertion point target checks are enabled.
+//
+// This may be printed as a warning instead of returned as an error.
+type insertionPointTargetError struct {
+	name           string
+	insertionPoint string
+	isWarning      bool
+}
+
+func newInsertionPointTargetError(name string, insertionPoint string, isWarning bool) *insertionPointTargetError {
+	return &insertionPointTargetError{
+		name:           name,
+		insertionPoint: insertionPoint,
+		isWarning:      isWarning,
+	}
+}
+
+func (i *insertionPointTargetError) Error() string {
+	var warningMessage string
+	if i.isWarning {
+		warningMessage = ` Generation will continue without error here, but the compiler will likely fail to apply the insertion point.`
+	}
+	return fmt.Sprintf(
+		"insertion point %q targets file %q, which was not generated earlier in the response.%s",
+		i.insertionPoint,
+		i.name,
+		warningMessage,
+	)
+}
+
+// eagerValidationError is the error recorded if a CodeGeneratorResponse.File was invalid when it
+// was added to a ResponseWriter and eager validation is enabled.
+type eagerValidationError struct {
+	err error
+	// The file and line of the call that added the file, or empty if this could not be determined.
+	callSite string
+}
+
+func newEagerValidationError(err error, callSite string) *eagerValidationError {
+	return &eagerValidationError{
+		err:      err,
+		callSite: callSite,
+	}
+}
+
+func (e *eagerValidationError) Error() string {
+	if e.callSite == "" {
+		return e.err.Error()
+	}
+	return fmt.Sprintf("%s (added at %s)", e.err.Error(), e.callSite)
+}
+
+func (e *eagerValidationError) Unwrap() error {
+	return e.err
+}
+
+// sealedWriteError is the error recorded if a ResponseWriter was written to after it was sealed.
+type sealedWriteError struct {
+	methodName string
+	stack      []byte
+}
+
+func newSealedWriteError(methodName string, stack []byte) *sealedWriteError {
+	return &sealedWriteError{
+		methodName: methodName,
+		stack:      stack,
+	}
+}
+
+func (s *sealedWriteError) Error() string {
+	return fmt.Sprintf(
+		"ResponseWriter.%s called after the ResponseWriter was sealed, likely from a goroutine that outlived the Handler:\n%s",
+		s.methodName,
+		s.stack,
+	)
+}
+
+// compilerRequirementMessage returns a message of the form "compiler version >= 27.0 for feature".
+func compilerRequirementMessage(requirement CompilerRequirement) string {
+	message := "compiler version >= " + (&CompilerVersion{
+		Major: requirement.Minimum.Major,
+		Minor: requirement.Minimum.Minor,
+		Patch: requirement.Minimum.Patch,
+	}).String()
+	switch {
+	case requirement.Feature != "":
+		message += " for " + requirement.Feature
+	case requirement.reason != "":
+		message += " " + requirement.reason
+	}
+	return message
+}
@@ -0,0 +1,59 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestRequestValidationError(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewRequest(
+		&pluginpb.CodeGeneratorRequest{
+			FileToGenerate: []string{"a.proto"},
+		},
+	)
+	var requestValidationError *RequestValidationError
+	require.ErrorAs(t, err, &requestValidationError)
+	require.Equal(t, err.Error(), requestValidationError.Err.Error())
+}
+
+func TestResponseValidationError(t *testing.T) {
+	t.Parallel()
+
+	responseWriter := NewResponseWriter()
+	responseWriter.AddFile("a.txt", "")
+	responseWriter.AddFile("a.txt", "")
+	_, err := responseWriter.ToCodeGeneratorResponse()
+	var responseValidationError *ResponseValidationError
+	require.ErrorAs(t, err, &responseValidationError)
+	require.Equal(t, "a.txt", responseValidationError.FileName)
+
+	responseWriter = NewResponseWriter()
+	responseWriter.AddFile("./b.txt", "")
+	_, err = responseWriter.PeekCodeGeneratorResponse()
+	require.ErrorAs(t, err, &responseValidationError)
+	require.Equal(t, "./b.txt", responseValidationError.FileName)
+
+	responseWriter = NewResponseWriter()
+	responseWriter.SetFeatureSupportsEditions(2, 1)
+	_, err = responseWriter.ToCodeGeneratorResponse()
+	require.ErrorAs(t, err, &responseValidationError)
+	require.Empty(t, responseValidationError.FileName)
+}
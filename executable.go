@@ -0,0 +1,158 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// ExecutableFilesManifestFileName is the name of the manifest file that lists the generated files that
+// should be executable.
+//
+// CodeGeneratorResponses have no concept of file permissions. As a convention, if any files are marked
+// executable with ResponseWriter.MarkExecutable, a file with this name is added to the CodeGeneratorResponse
+// with the names of these files, one per line, sorted.
+//
+// Consumers that understand this convention, such as WriteResponseFiles, should make the listed files
+// executable, and should not write the manifest itself. Consumers that do not understand this convention,
+// such as protoc and buf, write the manifest as a regular file, and write all files without the executable bit.
+const ExecutableFilesManifestFileName = ".protoplugin-executable-files"
+
+// ExecutableFileNames returns the names of the files that should be executable per the manifest within
+// the CodeGeneratorResponse.
+//
+// See ExecutableFilesManifestFileName for more details. If the CodeGeneratorResponse does not contain
+// a manifest, nil is returned.
+func ExecutableFileNames(codeGeneratorResponse *pluginpb.CodeGeneratorResponse) []string {
+	var executableFileNames []string
+	for _, file := range codeGeneratorResponse.GetFile() {
+		if file.GetName() != ExecutableFilesManifestFileName || file.GetInsertionPoint() != "" {
+			continue
+		}
+		for _, line := range strings.Split(file.GetContent(), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				executableFileNames = append(executableFileNames, line)
+			}
+		}
+	}
+	return executableFileNames
+}
+
+// WriteResponseFiles writes the files in the CodeGeneratorResponse to the directory, for plugins that
+// are run standalone instead of by a protobuf compiler.
+//
+// Files listed in the manifest named ExecutableFilesManifestFileName are written with mode 0755, and all
+// other files are written with mode 0644. The manifest itself is not written. Parent directories are created
+// as needed with mode 0755.
+//
+// Files with an empty name are appended to the previous file, per the documentation of
+// CodeGeneratorResponse.File.name. Insertion points are not supported, and an error is returned if any
+// file has an insertion point. An error is also returned if the CodeGeneratorResponse has an error set.
+//
+// The permissions of existing files are updated to match the above.
+func WriteResponseFiles(dirPath string, codeGeneratorResponse *pluginpb.CodeGeneratorResponse) error {
+	if errorMessage := codeGeneratorResponse.GetError(); errorMessage != "" {
+		return errors.New(errorMessage)
+	}
+	executableFileNames := make(map[string]struct{})
+	for _, executableFileName := range ExecutableFileNames(codeGeneratorResponse) {
+		executableFileNames[executableFileName] = struct{}{}
+	}
+	var names []string
+	nameToContent := make(map[string]*strings.Builder)
+	for _, file := range codeGeneratorResponse.GetFile() {
+		if file.GetInsertionPoint() != "" {
+			return fmt.Errorf("file %q has insertion point %q, which is not supported", file.GetName(), file.GetInsertionPoint())
+		}
+		name := file.GetName()
+		if name == "" {
+			if len(names) == 0 {
+				return errors.New("first file in CodeGeneratorResponse has an empty name")
+			}
+			name = names[len(names)-1]
+		}
+		if name == ExecutableFilesManifestFileName {
+			continue
+		}
+		content, ok := nameToContent[name]
+		if !ok {
+			content = &strings.Builder{}
+			nameToContent[name] = content
+			names = append(names, name)
+		}
+		_, _ = content.WriteString(file.GetContent())
+	}
+	for _, name := range names {
+		var perm os.FileMode = 0o644
+		if _, ok := executableFileNames[name]; ok {
+			perm = 0o755
+		}
+		filePath := filepath.Join(dirPath, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filePath, []byte(nameToContent[name].String()), perm); err != nil {
+			return err
+		}
+		// os.WriteFile does not change the permissions of existing files, and is subject to the umask.
+		if err := os.Chmod(filePath, perm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// *** PRIVATE ***
+
+// newExecutableFilesManifestFile returns the manifest file for the names of the files that were marked executable.
+//
+// An error is returned if any of the names were not added to the CodeGeneratorResponse without an insertion
+// point, or if the CodeGeneratorResponse already contains a file with the name of the manifest.
+//
+// Must be called after validateAndNormalizeCodeGeneratorResponse, as names are expected to be normalized.
+func newExecutableFilesManifestFile(
+	codeGeneratorResponse *pluginpb.CodeGeneratorResponse,
+	executableFileNames map[string]struct{},
+) (*pluginpb.CodeGeneratorResponse_File, error) {
+	fileNames := make(map[string]struct{}, len(codeGeneratorResponse.GetFile()))
+	for _, file := range codeGeneratorResponse.GetFile() {
+		if file.GetName() == ExecutableFilesManifestFileName {
+			return nil, fmt.Errorf("file %q is reserved for the executable files manifest", ExecutableFilesManifestFileName)
+		}
+		if file.GetInsertionPoint() == "" {
+			fileNames[file.GetName()] = struct{}{}
+		}
+	}
+	sortedExecutableFileNames := make([]string, 0, len(executableFileNames))
+	for executableFileName := range executableFileNames {
+		if _, ok := fileNames[executableFileName]; !ok {
+			return nil, fmt.Errorf("file %q was marked executable but was not added", executableFileName)
+		}
+		sortedExecutableFileNames = append(sortedExecutableFileNames, executableFileName)
+	}
+	sort.Strings(sortedExecutableFileNames)
+	return &pluginpb.CodeGeneratorResponse_File{
+		Name:    proto.String(ExecutableFilesManifestFileName),
+		Content: proto.String(strings.Join(sortedExecutableFileNames, "\n") + "\n"),
+	}, nil
+}
@@ -0,0 +1,116 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestResponseWriterMarkExecutable(t *testing.T) {
+	t.Parallel()
+
+	responseWriter := NewResponseWriter()
+	responseWriter.MarkExecutable("b.sh")
+	responseWriter.AddFile("a.txt", "a")
+	responseWriter.AddFile("b.sh", "b")
+	responseWriter.AddFile("c.sh", "c")
+	responseWriter.MarkExecutable("c.sh")
+	codeGeneratorResponse, err := responseWriter.ToCodeGeneratorResponse()
+	require.NoError(t, err)
+	require.Len(t, codeGeneratorResponse.GetFile(), 4)
+	require.Equal(t, ExecutableFilesManifestFileName, codeGeneratorResponse.GetFile()[3].GetName())
+	require.Equal(t, "b.sh\nc.sh\n", codeGeneratorResponse.GetFile()[3].GetContent())
+	require.Equal(t, []string{"b.sh", "c.sh"}, ExecutableFileNames(codeGeneratorResponse))
+
+	responseWriter = NewResponseWriter()
+	responseWriter.AddFile("a.txt", "a")
+	codeGeneratorResponse, err = responseWriter.ToCodeGeneratorResponse()
+	require.NoError(t, err)
+	require.Len(t, codeGeneratorResponse.GetFile(), 1)
+	require.Nil(t, ExecutableFileNames(codeGeneratorResponse))
+
+	responseWriter = NewResponseWriter()
+	responseWriter.AddFile("a.txt", "a")
+	responseWriter.MarkExecutable("b.sh")
+	_, err = responseWriter.ToCodeGeneratorResponse()
+	responseValidationError := &ResponseValidationError{}
+	require.ErrorAs(t, err, &responseValidationError)
+
+	responseWriter = NewResponseWriter()
+	responseWriter.AddFile("a.sh", "a")
+	responseWriter.AddFile(ExecutableFilesManifestFileName, "a.sh\n")
+	responseWriter.MarkExecutable("a.sh")
+	_, err = responseWriter.ToCodeGeneratorResponse()
+	require.ErrorAs(t, err, &responseValidationError)
+
+	responseWriter = NewResponseWriter()
+	outputFS := NewOutputFS(responseWriter)
+	require.NoError(t, outputFS.WriteFile("a.txt", []byte("a"), 0o644))
+	require.NoError(t, outputFS.WriteFile("b.sh", []byte("b"), 0o755))
+	codeGeneratorResponse, err = responseWriter.ToCodeGeneratorResponse()
+	require.NoError(t, err)
+	require.Equal(t, []string{"b.sh"}, ExecutableFileNames(codeGeneratorResponse))
+}
+
+func TestWriteResponseFiles(t *testing.T) {
+	t.Parallel()
+
+	dirPath := t.TempDir()
+	codeGeneratorResponse := &pluginpb.CodeGeneratorResponse{
+		File: []*pluginpb.CodeGeneratorResponse_File{
+			{Name: proto.String("a/a.txt"), Content: proto.String("a1")},
+			{Content: proto.String("a2")},
+			{Name: proto.String("b/b.sh"), Content: proto.String("b")},
+			{Name: proto.String(ExecutableFilesManifestFileName), Content: proto.String("b/b.sh\n")},
+		},
+	}
+	require.NoError(t, WriteResponseFiles(dirPath, codeGeneratorResponse))
+	data, err := os.ReadFile(filepath.Join(dirPath, "a", "a.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "a1a2", string(data))
+	data, err = os.ReadFile(filepath.Join(dirPath, "b", "b.sh"))
+	require.NoError(t, err)
+	require.Equal(t, "b", string(data))
+	_, err = os.Stat(filepath.Join(dirPath, ExecutableFilesManifestFileName))
+	require.ErrorIs(t, err, os.ErrNotExist)
+	if runtime.GOOS != "windows" {
+		fileInfo, err := os.Stat(filepath.Join(dirPath, "a", "a.txt"))
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(0o644), fileInfo.Mode().Perm())
+		fileInfo, err = os.Stat(filepath.Join(dirPath, "b", "b.sh"))
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(0o755), fileInfo.Mode().Perm())
+	}
+
+	require.Error(
+		t,
+		WriteResponseFiles(
+			dirPath,
+			&pluginpb.CodeGeneratorResponse{
+				File: []*pluginpb.CodeGeneratorResponse_File{
+					{Name: proto.String("a/a.txt"), InsertionPoint: proto.String("foo"), Content: proto.String("a")},
+				},
+			},
+		),
+	)
+	require.Error(t, WriteResponseFiles(dirPath, &pluginpb.CodeGeneratorResponse{Error: proto.String("foo")}))
+}
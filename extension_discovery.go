@@ -0,0 +1,126 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"errors"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// WithExtensionTypeDiscovery returns a new RunOption that says to resolve extensions using the extensions
+// defined within the proto_file field of the CodeGeneratorRequest itself.
+//
+// By default, custom options defined within the files being compiled are not known to the plugin, and end
+// up as unknown fields on the options messages unless the plugin wires up WithExtensionTypeResolver with
+// the extensions compiled into the plugin. With this option, after the CodeGeneratorRequest is read, an
+// extension type is constructed with dynamicpb for every extension defined within proto_file, and the
+// CodeGeneratorRequest is re-parsed so that these extensions are populated on the options messages. The
+// extensions can then be accessed via ProtoReflect on the options messages, for example with
+// protoreflect.Message.Range, or with protoreflect.Message.Get and the ExtensionDescriptor found via
+// Request.FindDescriptorByName.
+//
+// Extensions resolvable by the resolver from WithExtensionTypeResolver or WithUnmarshalOptions, or by
+// protoregistry.GlobalTypes if neither was specified, take precedence over the discovered extensions.
+//
+// This requires an additional marshal and unmarshal of the CodeGeneratorRequest if any extensions are defined,
+// so this should only be used if custom options are needed.
+//
+// This option can be passed to Main or Run.
+//
+// The default is to not discover extensions from the CodeGeneratorRequest.
+func WithExtensionTypeDiscovery() RunOption {
+	return optsFunc(func(opts *opts) {
+		opts.extensionTypeDiscovery = true
+	})
+}
+
+// *** PRIVATE ***
+
+// discoverExtensionTypes returns a new CodeGeneratorRequest re-parsed with the extensions defined within
+// the CodeGeneratorRequest.
+//
+// If no extensions are defined, the CodeGeneratorRequest is returned as-is.
+func discoverExtensionTypes(
+	codeGeneratorRequest *pluginpb.CodeGeneratorRequest,
+	opts *opts,
+) (*pluginpb.CodeGeneratorRequest, error) {
+	symbolTable, err := newRequest(codeGeneratorRequest).getSymbolTable()
+	if err != nil {
+		return nil, fmt.Errorf("could not discover extension types: %w", err)
+	}
+	types := &protoregistry.Types{}
+	for _, symbol := range symbolTable.symbols {
+		extensionDescriptor, ok := symbol.(protoreflect.ExtensionDescriptor)
+		if !ok {
+			continue
+		}
+		if err := types.RegisterExtension(dynamicpb.NewExtensionType(extensionDescriptor)); err != nil {
+			return nil, fmt.Errorf("could not discover extension types: %w", err)
+		}
+	}
+	if types.NumExtensions() == 0 {
+		return codeGeneratorRequest, nil
+	}
+	data, err := proto.Marshal(codeGeneratorRequest)
+	if err != nil {
+		return nil, err
+	}
+	unmarshalOptions := opts.unmarshalOptions
+	primaryResolver := unmarshalOptions.Resolver
+	if primaryResolver == nil {
+		primaryResolver = opts.extensionTypeResolver
+	}
+	if primaryResolver == nil {
+		primaryResolver = protoregistry.GlobalTypes
+	}
+	unmarshalOptions.Resolver = extensionTypeResolvers{primaryResolver, types}
+	discoveredCodeGeneratorRequest := &pluginpb.CodeGeneratorRequest{}
+	if err := unmarshalOptions.Unmarshal(data, discoveredCodeGeneratorRequest); err != nil {
+		return nil, err
+	}
+	return discoveredCodeGeneratorRequest, nil
+}
+
+// extensionTypeResolvers is a protoregistry.ExtensionTypeResolver that tries each resolver in order.
+type extensionTypeResolvers []protoregistry.ExtensionTypeResolver
+
+func (e extensionTypeResolvers) FindExtensionByName(field protoreflect.FullName) (protoreflect.ExtensionType, error) {
+	for _, resolver := range e {
+		extensionType, err := resolver.FindExtensionByName(field)
+		if !errors.Is(err, protoregistry.NotFound) {
+			return extensionType, err
+		}
+	}
+	return nil, protoregistry.NotFound
+}
+
+func (e extensionTypeResolvers) FindExtensionByNumber(
+	message protoreflect.FullName,
+	field protoreflect.FieldNumber,
+) (protoreflect.ExtensionType, error) {
+	for _, resolver := range e {
+		extensionType, err := resolver.FindExtensionByNumber(message, field)
+		if !errors.Is(err, protoregistry.NotFound) {
+			return extensionType, err
+		}
+	}
+	return nil, protoregistry.NotFound
+}
@@ -0,0 +1,98 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestWithExtensionTypeDiscoveryOption(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	fileDescriptorProtos, err := compile(ctx, map[string][]byte{
+		"google/protobuf/descriptor.proto": []byte(`
+			syntax = "proto2";
+			package google.protobuf;
+			message FieldOptions { extensions 1000 to max; }
+		`),
+		"a.proto": []byte(`
+			syntax = "proto3";
+			package foo;
+			import "google/protobuf/descriptor.proto";
+			extend google.protobuf.FieldOptions { string my_extension = 1000; }
+			message A { int32 field = 1 [(my_extension) = "foo"]; }
+		`),
+	})
+	require.NoError(t, err)
+	codeGeneratorRequestData, err := proto.Marshal(
+		&pluginpb.CodeGeneratorRequest{
+			FileToGenerate: []string{"a.proto"},
+			ProtoFile:      fileDescriptorProtos,
+		},
+	)
+	require.NoError(t, err)
+	// Parse with an empty resolver so that the custom option is an unknown field.
+	codeGeneratorRequest := &pluginpb.CodeGeneratorRequest{}
+	require.NoError(
+		t,
+		proto.UnmarshalOptions{Resolver: &protoregistry.Types{}}.Unmarshal(
+			codeGeneratorRequestData,
+			codeGeneratorRequest,
+		),
+	)
+
+	var unknown []byte
+	var extensionValue string
+	handler := HandlerFunc(
+		func(_ context.Context, _ PluginEnv, _ ResponseWriter, request Request) error {
+			descriptor, err := request.FindDescriptorByName("foo.A.field")
+			if err != nil {
+				return err
+			}
+			options, ok := descriptor.Options().(*descriptorpb.FieldOptions)
+			require.True(t, ok)
+			unknown = options.ProtoReflect().GetUnknown()
+			extensionValue = ""
+			options.ProtoReflect().Range(
+				func(fieldDescriptor protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+					if fieldDescriptor.FullName() == "foo.my_extension" {
+						extensionValue = value.String()
+					}
+					return true
+				},
+			)
+			return nil
+		},
+	)
+
+	_, err = Invoke(ctx, handler, codeGeneratorRequest)
+	require.NoError(t, err)
+	require.NotEmpty(t, unknown)
+	require.Empty(t, extensionValue)
+
+	_, err = Invoke(ctx, handler, codeGeneratorRequest, WithExtensionTypeDiscovery())
+	require.NoError(t, err)
+	require.Empty(t, unknown)
+	require.Equal(t, "foo", extensionValue)
+}
@@ -0,0 +1,148 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// FileDescriptorSetFileOption is an option for AddFileDescriptorSetFile.
+type FileDescriptorSetFileOption func(*fileDescriptorSetFileOptions)
+
+// FileDescriptorSetFileWithImports returns a new FileDescriptorSetFileOption that says to include all files
+// within the CodeGeneratorRequest, that is the files to generate and all of their transitive imports.
+//
+// This results in a self-contained FileDescriptorSet, which is required by most consumers that build
+// descriptors at runtime, such as reflection servers. This is equivalent to --include_imports for protoc.
+//
+// The default is to only include the files to generate.
+func FileDescriptorSetFileWithImports() FileDescriptorSetFileOption {
+	return func(fileDescriptorSetFileOptions *fileDescriptorSetFileOptions) {
+		fileDescriptorSetFileOptions.imports = true
+	}
+}
+
+// FileDescriptorSetFileWithSourceCodeInfo returns a new FileDescriptorSetFileOption that says to retain
+// SourceCodeInfo, that is comments and source locations, on the files.
+//
+// This is equivalent to --include_source_info for protoc.
+//
+// The default is to strip SourceCodeInfo from the files.
+func FileDescriptorSetFileWithSourceCodeInfo() FileDescriptorSetFileOption {
+	return func(fileDescriptorSetFileOptions *fileDescriptorSetFileOptions) {
+		fileDescriptorSetFileOptions.sourceCodeInfo = true
+	}
+}
+
+// FileDescriptorSetFileWithSourceRetentionOptions returns a new FileDescriptorSetFileOption that says to
+// include source-retention options on the files.
+//
+// See Request.WithSourceRetentionOptions for more details. An error is returned from AddFileDescriptorSetFile
+// if the CodeGeneratorRequest does not have source_file_descriptors populated.
+//
+// The default is to not include source-retention options.
+func FileDescriptorSetFileWithSourceRetentionOptions() FileDescriptorSetFileOption {
+	return func(fileDescriptorSetFileOptions *fileDescriptorSetFileOptions) {
+		fileDescriptorSetFileOptions.sourceRetentionOptions = true
+	}
+}
+
+// AddFileDescriptorSetFile adds a file with the given name to the ResponseWriter that contains the
+// binary-serialized FileDescriptorSet of the files within the Request.
+//
+// This is useful for plugins that distribute schemas, or that generate servers that need descriptors
+// at runtime, for example for reflection. The file name typically has the extension ".binpb".
+//
+// By default, the FileDescriptorSet only contains the files to generate, without SourceCodeInfo and without
+// source-retention options, which matches the defaults of --descriptor_set_out for protoc. The files are
+// ordered such that every file appears after its imports, if FileDescriptorSetFileWithImports is specified.
+// The FileDescriptorSet is deterministically serialized.
+//
+// The file is added with AddBinaryFile.
+func AddFileDescriptorSetFile(
+	responseWriter ResponseWriter,
+	request Request,
+	name string,
+	options ...FileDescriptorSetFileOption,
+) error {
+	fileDescriptorSetFileOptions := newFileDescriptorSetFileOptions()
+	for _, option := range options {
+		option(fileDescriptorSetFileOptions)
+	}
+	if fileDescriptorSetFileOptions.sourceRetentionOptions {
+		var err error
+		request, err = request.WithSourceRetentionOptions()
+		if err != nil {
+			return err
+		}
+	}
+	var fileDescriptorProtos []*descriptorpb.FileDescriptorProto
+	if fileDescriptorSetFileOptions.imports {
+		fileDescriptorProtos = request.AllFileDescriptorProtos()
+	} else {
+		fileDescriptorProtos = request.FileDescriptorProtosToGenerate()
+	}
+	if !fileDescriptorSetFileOptions.sourceCodeInfo {
+		for i, fileDescriptorProto := range fileDescriptorProtos {
+			fileDescriptorProtos[i] = withoutSourceCodeInfo(fileDescriptorProto)
+		}
+	}
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(
+		&descriptorpb.FileDescriptorSet{
+			File: fileDescriptorProtos,
+		},
+	)
+	if err != nil {
+		return err
+	}
+	responseWriter.AddBinaryFile(name, data)
+	return nil
+}
+
+// *** PRIVATE ***
+
+type fileDescriptorSetFileOptions struct {
+	imports                bool
+	sourceCodeInfo         bool
+	sourceRetentionOptions bool
+}
+
+func newFileDescriptorSetFileOptions() *fileDescriptorSetFileOptions {
+	return &fileDescriptorSetFileOptions{}
+}
+
+// withoutSourceCodeInfo returns a shallow copy of the FileDescriptorProto without SourceCodeInfo.
+//
+// If the FileDescriptorProto has no SourceCodeInfo, it is returned as-is.
+func withoutSourceCodeInfo(fileDescriptorProto *descriptorpb.FileDescriptorProto) *descriptorpb.FileDescriptorProto {
+	if fileDescriptorProto.SourceCodeInfo == nil {
+		return fileDescriptorProto
+	}
+	newFileDescriptorProto := &descriptorpb.FileDescriptorProto{}
+	newFileDescriptorProtoRef := newFileDescriptorProto.ProtoReflect()
+	fileDescriptorProtoRef := fileDescriptorProto.ProtoReflect()
+	fileDescriptorProtoRef.Range(
+		func(fieldDescriptor protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+			if fieldDescriptor.Name() != "source_code_info" {
+				newFileDescriptorProtoRef.Set(fieldDescriptor, value)
+			}
+			return true
+		},
+	)
+	newFileDescriptorProtoRef.SetUnknown(fileDescriptorProtoRef.GetUnknown())
+	return newFileDescriptorProto
+}
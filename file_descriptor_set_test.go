@@ -0,0 +1,124 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestAddFileDescriptorSetFile(t *testing.T) {
+	t.Parallel()
+
+	sourceCodeInfo := &descriptorpb.SourceCodeInfo{
+		Location: []*descriptorpb.SourceCodeInfo_Location{
+			{
+				Path: []int32{},
+				Span: []int32{0, 0, 1},
+			},
+		},
+	}
+	codeGeneratorRequest := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{"a.proto"},
+		ProtoFile: []*descriptorpb.FileDescriptorProto{
+			{
+				Name:           proto.String("b.proto"),
+				Syntax:         proto.String("proto3"),
+				SourceCodeInfo: sourceCodeInfo,
+			},
+			{
+				Name:           proto.String("a.proto"),
+				Syntax:         proto.String("proto3"),
+				Dependency:     []string{"b.proto"},
+				SourceCodeInfo: sourceCodeInfo,
+			},
+		},
+	}
+	testCases := []struct {
+		name                   string
+		options                []FileDescriptorSetFileOption
+		expectedFileNames      []string
+		expectedSourceCodeInfo bool
+	}{
+		{
+			name:              "default",
+			expectedFileNames: []string{"a.proto"},
+		},
+		{
+			name:              "imports",
+			options:           []FileDescriptorSetFileOption{FileDescriptorSetFileWithImports()},
+			expectedFileNames: []string{"b.proto", "a.proto"},
+		},
+		{
+			name: "imports_and_source_code_info",
+			options: []FileDescriptorSetFileOption{
+				FileDescriptorSetFileWithImports(),
+				FileDescriptorSetFileWithSourceCodeInfo(),
+			},
+			expectedFileNames:      []string{"b.proto", "a.proto"},
+			expectedSourceCodeInfo: true,
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			codeGeneratorResponse, err := Invoke(
+				context.Background(),
+				HandlerFunc(
+					func(_ context.Context, _ PluginEnv, responseWriter ResponseWriter, request Request) error {
+						return AddFileDescriptorSetFile(responseWriter, request, "descriptors.binpb", testCase.options...)
+					},
+				),
+				proto.Clone(codeGeneratorRequest).(*pluginpb.CodeGeneratorRequest),
+			)
+			require.NoError(t, err)
+			require.Len(t, codeGeneratorResponse.GetFile(), 1)
+			require.Equal(t, "descriptors.binpb", codeGeneratorResponse.GetFile()[0].GetName())
+			fileDescriptorSet := &descriptorpb.FileDescriptorSet{}
+			require.NoError(t, proto.Unmarshal([]byte(codeGeneratorResponse.GetFile()[0].GetContent()), fileDescriptorSet))
+			fileNames := make([]string, len(fileDescriptorSet.GetFile()))
+			for i, fileDescriptorProto := range fileDescriptorSet.GetFile() {
+				fileNames[i] = fileDescriptorProto.GetName()
+				require.Equal(t, testCase.expectedSourceCodeInfo, fileDescriptorProto.GetSourceCodeInfo() != nil)
+			}
+			require.Equal(t, testCase.expectedFileNames, fileNames)
+		})
+	}
+
+	// Source-retention options require source_file_descriptors.
+	_, err := Invoke(
+		context.Background(),
+		HandlerFunc(
+			func(_ context.Context, _ PluginEnv, responseWriter ResponseWriter, request Request) error {
+				return AddFileDescriptorSetFile(
+					responseWriter,
+					request,
+					"descriptors.binpb",
+					FileDescriptorSetFileWithSourceRetentionOptions(),
+				)
+			},
+		),
+		codeGeneratorRequest,
+	)
+	require.Error(t, err)
+	// The source CodeGeneratorRequest is not modified.
+	require.NotNil(t, codeGeneratorRequest.GetProtoFile()[0].GetSourceCodeInfo())
+}
@@ -0,0 +1,239 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+const (
+	// FileFilterIncludeParameterKey is the parameter key for include patterns.
+	//
+	// See WithFileFilterParameters for more details.
+	FileFilterIncludeParameterKey = "include"
+	// FileFilterExcludeParameterKey is the parameter key for exclude patterns.
+	//
+	// See WithFileFilterParameters for more details.
+	FileFilterExcludeParameterKey = "exclude"
+)
+
+// FileFilter is a filter for the files to generate, as specified by the include and exclude parameters.
+//
+// See WithFileFilterParameters for more details.
+type FileFilter struct {
+	// Include are the glob patterns for files to include.
+	//
+	// If empty, all files are included unless excluded.
+	Include []string
+	// Exclude are the glob patterns for files to exclude.
+	//
+	// Exclude patterns take precedence over Include patterns.
+	Exclude []string
+}
+
+// Match returns true if the path matches the FileFilter.
+//
+// A path matches if it matches any Include pattern, or there are no Include patterns, and it does not
+// match any Exclude pattern.
+//
+// Patterns are matched against the path with the same syntax as path.Match, with the addition that a
+// path element of "**" matches zero or more path elements. For example, "foo/**/*.proto" matches
+// "foo/a.proto" and "foo/bar/baz/a.proto".
+func (f *FileFilter) Match(path string) bool {
+	if len(f.Include) > 0 && !matchGlobs(f.Include, path) {
+		return false
+	}
+	return !matchGlobs(f.Exclude, path)
+}
+
+// WithFileFilterParameters returns a new RunOption that handles the include and exclude parameters before
+// the Handler is invoked.
+//
+// The parameter is parsed as a comma-separated list of "key" or "key=value" elements, which is the convention
+// for protoc plugins. Elements with the key "include" or "exclude" specify glob patterns for the files to
+// generate, as matched by FileFilter.Match, and may be specified multiple times. For example, with the parameter
+// "paths=source_relative,include=foo/**,exclude=foo/internal/**", the Handler is invoked with only the files to
+// generate within the directory foo, but not foo/internal, and with a parameter of "paths=source_relative".
+//
+// The include and exclude elements are removed from the parameter given to the Handler, and the files to
+// generate that do not match are removed from the Request, including from the CodeGeneratorRequest returned
+// by Request.CodeGeneratorRequest. All other files are retained, as they may be imported by the files to
+// generate. If no files to generate match, the Handler is invoked with no files to generate. The FileFilter
+// that was applied is available via Request.FileFilter.
+//
+// If a pattern is invalid, an error is added to the response and the Handler is not invoked.
+//
+// This gives all plugins consistent, documented include and exclude behavior. This option can be passed to
+// Main or Run.
+//
+// The default is to not handle the include and exclude parameters, and to pass the parameter to the Handler as-is.
+func WithFileFilterParameters() RunOption {
+	return optsFunc(func(opts *opts) {
+		opts.fileFilterParameters = true
+	})
+}
+
+// *** PRIVATE ***
+
+// applyFileFilterParameters parses the include and exclude parameters of the request, and returns a new request
+// with the files to generate filtered and the include and exclude parameters removed.
+//
+// If the parameter contains no include or exclude elements, the request is returned as-is.
+func applyFileFilterParameters(request *request) (*request, error) {
+	fileFilter, parameter, err := parseFileFilterParameter(request.Parameter())
+	if err != nil || fileFilter == nil {
+		return request, err
+	}
+	filteredRequest := request.withFilteredFilesToGenerate(parameter, fileFilter.Match)
+	filteredRequest.fileFilter = fileFilter
+	return filteredRequest, nil
+}
+
+// withFilteredFilesToGenerate returns a new request with only the files to generate that match keep,
+// and with the given parameter.
+//
+// All other fields of the CodeGeneratorRequest, including unknown fields, are retained. In particular, all
+// files are retained in proto_file, as they may be imported by the files to generate.
+func (r *request) withFilteredFilesToGenerate(parameter string, keep func(string) bool) *request {
+	codeGeneratorRequest := r.codeGeneratorRequest
+	filteredCodeGeneratorRequest := &pluginpb.CodeGeneratorRequest{}
+	filteredCodeGeneratorRequestRef := filteredCodeGeneratorRequest.ProtoReflect()
+	codeGeneratorRequestRef := codeGeneratorRequest.ProtoReflect()
+	codeGeneratorRequestRef.Range(
+		func(fieldDescriptor protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+			switch fieldDescriptor.Name() {
+			case "file_to_generate", "parameter", "source_file_descriptors":
+			default:
+				filteredCodeGeneratorRequestRef.Set(fieldDescriptor, value)
+			}
+			return true
+		},
+	)
+	filteredCodeGeneratorRequestRef.SetUnknown(codeGeneratorRequestRef.GetUnknown())
+	if parameter != "" {
+		filteredCodeGeneratorRequest.Parameter = &parameter
+	}
+	for _, fileToGenerate := range codeGeneratorRequest.GetFileToGenerate() {
+		if keep(fileToGenerate) {
+			filteredCodeGeneratorRequest.FileToGenerate = append(filteredCodeGeneratorRequest.FileToGenerate, fileToGenerate)
+		}
+	}
+	// source_file_descriptors must only contain the files to generate.
+	for _, sourceFileDescriptor := range codeGeneratorRequest.GetSourceFileDescriptors() {
+		if keep(sourceFileDescriptor.GetName()) {
+			filteredCodeGeneratorRequest.SourceFileDescriptors = append(
+				filteredCodeGeneratorRequest.SourceFileDescriptors,
+				sourceFileDescriptor,
+			)
+		}
+	}
+	filteredRequest := newRequest(filteredCodeGeneratorRequest)
+	// If all files to generate were filtered out, source_file_descriptors is empty, however
+	// source-retention options are still available for the (empty) set of files to generate.
+	filteredRequest.sourceFileDescriptorsPresent = r.sourceFileDescriptorsPresent
+	filteredRequest.fileToGenerateOrder = r.fileToGenerateOrder
+	filteredRequest.fileFilter = r.fileFilter
+	filteredRequest.sourceRetentionOptionsUnavailableWarning = r.sourceRetentionOptionsUnavailableWarning
+	filteredRequest.descriptorCache = r.descriptorCache
+	return filteredRequest
+}
+
+// parseFileFilterParameter parses the include and exclude elements of the parameter, returning the FileFilter
+// and the parameter with these elements removed.
+//
+// If there are no include or exclude elements, nil is returned for the FileFilter.
+func parseFileFilterParameter(parameter string) (*FileFilter, string, error) {
+	if parameter == "" {
+		return nil, "", nil
+	}
+	var fileFilter *FileFilter
+	var remainingElements []string
+	for _, element := range strings.Split(parameter, ",") {
+		key, value, _ := strings.Cut(element, "=")
+		key = strings.TrimSpace(key)
+		if key != FileFilterIncludeParameterKey && key != FileFilterExcludeParameterKey {
+			remainingElements = append(remainingElements, element)
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if err := validateGlob(value); err != nil {
+			return nil, "", fmt.Errorf("invalid %s parameter %q: %w", key, value, err)
+		}
+		if fileFilter == nil {
+			fileFilter = &FileFilter{}
+		}
+		if key == FileFilterIncludeParameterKey {
+			fileFilter.Include = append(fileFilter.Include, value)
+		} else {
+			fileFilter.Exclude = append(fileFilter.Exclude, value)
+		}
+	}
+	if fileFilter == nil {
+		return nil, parameter, nil
+	}
+	return fileFilter, strings.Join(remainingElements, ","), nil
+}
+
+func validateGlob(pattern string) error {
+	if pattern == "" {
+		return fmt.Errorf("pattern was empty")
+	}
+	for _, element := range strings.Split(pattern, "/") {
+		if element == "**" {
+			continue
+		}
+		if _, err := path.Match(element, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func matchGlobs(patterns []string, path string) bool {
+	for _, pattern := range patterns {
+		if matchGlobElements(strings.Split(pattern, "/"), strings.Split(path, "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchGlobElements(patternElements []string, pathElements []string) bool {
+	for len(patternElements) > 0 {
+		if patternElements[0] == "**" {
+			for i := 0; i <= len(pathElements); i++ {
+				if matchGlobElements(patternElements[1:], pathElements[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(pathElements) == 0 {
+			return false
+		}
+		// We have already validated the patterns.
+		if matched, _ := path.Match(patternElements[0], pathElements[0]); !matched {
+			return false
+		}
+		patternElements = patternElements[1:]
+		pathElements = pathElements[1:]
+	}
+	return len(pathElements) == 0
+}
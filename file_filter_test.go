@@ -0,0 +1,196 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestFileFilterMatch(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		include  []string
+		exclude  []string
+		path     string
+		expected bool
+	}{
+		{path: "a.proto", expected: true},
+		{include: []string{"*.proto"}, path: "a.proto", expected: true},
+		{include: []string{"*.proto"}, path: "foo/a.proto", expected: false},
+		{include: []string{"foo/**"}, path: "foo/a.proto", expected: true},
+		{include: []string{"foo/**"}, path: "foo/bar/a.proto", expected: true},
+		{include: []string{"foo/**"}, path: "bar/a.proto", expected: false},
+		{include: []string{"foo/**/a.proto"}, path: "foo/a.proto", expected: true},
+		{include: []string{"foo/**/a.proto"}, path: "foo/bar/baz/a.proto", expected: true},
+		{include: []string{"foo/**/a.proto"}, path: "foo/bar/b.proto", expected: false},
+		{include: []string{"**/a.proto"}, path: "a.proto", expected: true},
+		{include: []string{"bar/**", "foo/**"}, path: "foo/a.proto", expected: true},
+		{exclude: []string{"foo/internal/**"}, path: "foo/a.proto", expected: true},
+		{exclude: []string{"foo/internal/**"}, path: "foo/internal/a.proto", expected: false},
+		{include: []string{"foo/**"}, exclude: []string{"foo/internal/**"}, path: "foo/internal/a.proto", expected: false},
+		{include: []string{"foo/?.proto"}, path: "foo/a.proto", expected: true},
+		{include: []string{"foo/[b-z].proto"}, path: "foo/a.proto", expected: false},
+	}
+	for _, testCase := range testCases {
+		fileFilter := &FileFilter{Include: testCase.include, Exclude: testCase.exclude}
+		require.Equal(t, testCase.expected, fileFilter.Match(testCase.path), "%+v", testCase)
+	}
+}
+
+func TestParseFileFilterParameter(t *testing.T) {
+	t.Parallel()
+
+	fileFilter, parameter, err := parseFileFilterParameter("")
+	require.NoError(t, err)
+	require.Nil(t, fileFilter)
+	require.Empty(t, parameter)
+
+	fileFilter, parameter, err = parseFileFilterParameter("paths=source_relative,foo")
+	require.NoError(t, err)
+	require.Nil(t, fileFilter)
+	require.Equal(t, "paths=source_relative,foo", parameter)
+
+	fileFilter, parameter, err = parseFileFilterParameter("paths=source_relative,include=foo/**,exclude=foo/internal/**,include=bar/*.proto,foo")
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		&FileFilter{
+			Include: []string{"foo/**", "bar/*.proto"},
+			Exclude: []string{"foo/internal/**"},
+		},
+		fileFilter,
+	)
+	require.Equal(t, "paths=source_relative,foo", parameter)
+
+	fileFilter, parameter, err = parseFileFilterParameter("include=foo/**")
+	require.NoError(t, err)
+	require.Equal(t, &FileFilter{Include: []string{"foo/**"}}, fileFilter)
+	require.Empty(t, parameter)
+
+	_, _, err = parseFileFilterParameter("include=")
+	require.Error(t, err)
+	_, _, err = parseFileFilterParameter("exclude=foo/[")
+	require.Error(t, err)
+}
+
+func TestWithFileFilterParametersOption(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	codeGeneratorRequest := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{"foo/a.proto", "foo/internal/b.proto", "bar/c.proto"},
+		Parameter:      proto.String("paths=source_relative,include=foo/**,exclude=foo/internal/**"),
+		ProtoFile: []*descriptorpb.FileDescriptorProto{
+			{Name: proto.String("foo/a.proto"), Syntax: proto.String("proto3")},
+			{Name: proto.String("foo/internal/b.proto"), Syntax: proto.String("proto3")},
+			{Name: proto.String("bar/c.proto"), Syntax: proto.String("proto3")},
+		},
+	}
+	var fileNames []string
+	var parameter string
+	var fileFilter *FileFilter
+	handler := HandlerFunc(
+		func(_ context.Context, _ PluginEnv, _ ResponseWriter, request Request) error {
+			fileNames = nil
+			for _, fileDescriptorProto := range request.FileDescriptorProtosToGenerate() {
+				fileNames = append(fileNames, fileDescriptorProto.GetName())
+			}
+			parameter = request.Parameter()
+			fileFilter = request.FileFilter()
+			return nil
+		},
+	)
+
+	_, err := Invoke(ctx, handler, codeGeneratorRequest, WithFileFilterParameters())
+	require.NoError(t, err)
+	require.Equal(t, []string{"foo/a.proto"}, fileNames)
+	require.Equal(t, "paths=source_relative", parameter)
+	require.Equal(t, &FileFilter{Include: []string{"foo/**"}, Exclude: []string{"foo/internal/**"}}, fileFilter)
+
+	// Without the option, the parameter is passed through as-is.
+	_, err = Invoke(ctx, handler, codeGeneratorRequest)
+	require.NoError(t, err)
+	require.Equal(t, []string{"foo/a.proto", "foo/internal/b.proto", "bar/c.proto"}, fileNames)
+	require.Equal(t, codeGeneratorRequest.GetParameter(), parameter)
+	require.Nil(t, fileFilter)
+
+	// If no files match, the Handler is invoked with no files to generate.
+	codeGeneratorRequest.Parameter = proto.String("include=baz/**")
+	_, err = Invoke(ctx, handler, codeGeneratorRequest, WithFileFilterParameters())
+	require.NoError(t, err)
+	require.Empty(t, fileNames)
+	require.Empty(t, parameter)
+
+	// Invalid patterns result in an error on the response.
+	codeGeneratorRequest.Parameter = proto.String("include=foo/[")
+	codeGeneratorResponse, err := Invoke(ctx, handler, codeGeneratorRequest, WithFileFilterParameters())
+	require.NoError(t, err)
+	require.Contains(t, codeGeneratorResponse.GetError(), "invalid include parameter")
+}
+
+func TestWithFileFilterParametersOptionNoFilesToGenerate(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	unknown := protowire.AppendTag(nil, 1000, protowire.VarintType)
+	unknown = protowire.AppendVarint(unknown, 1)
+	codeGeneratorRequest := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{"a/a.proto"},
+		Parameter:      proto.String("exclude=a/**"),
+		ProtoFile: []*descriptorpb.FileDescriptorProto{
+			{Name: proto.String("a/a.proto"), Syntax: proto.String("proto3")},
+		},
+		SourceFileDescriptors: []*descriptorpb.FileDescriptorProto{
+			{Name: proto.String("a/a.proto"), Syntax: proto.String("proto3")},
+		},
+		CompilerVersion: &pluginpb.Version{Major: proto.Int32(27)},
+	}
+	codeGeneratorRequest.ProtoReflect().SetUnknown(unknown)
+	var sourceRetentionOptionsErr error
+	var filteredCodeGeneratorRequest *pluginpb.CodeGeneratorRequest
+	handler := HandlerFunc(
+		func(_ context.Context, _ PluginEnv, _ ResponseWriter, request Request) error {
+			_, sourceRetentionOptionsErr = request.WithSourceRetentionOptions()
+			filteredCodeGeneratorRequest = request.CodeGeneratorRequest()
+			return nil
+		},
+	)
+
+	_, err := Invoke(ctx, handler, codeGeneratorRequest, WithFileFilterParameters())
+	require.NoError(t, err)
+	require.NoError(t, sourceRetentionOptionsErr)
+	require.Empty(t, filteredCodeGeneratorRequest.GetFileToGenerate())
+	require.Empty(t, filteredCodeGeneratorRequest.GetSourceFileDescriptors())
+	require.Len(t, filteredCodeGeneratorRequest.GetProtoFile(), 1)
+	// All other fields, including unknown fields, are retained.
+	require.Equal(t, int32(27), filteredCodeGeneratorRequest.GetCompilerVersion().GetMajor())
+	require.Equal(t, unknown, []byte(filteredCodeGeneratorRequest.ProtoReflect().GetUnknown()))
+
+	// Without source_file_descriptors, source-retention options are still unavailable.
+	codeGeneratorRequest.SourceFileDescriptors = nil
+	codeGeneratorRequest.CompilerVersion = nil
+	_, err = Invoke(ctx, handler, codeGeneratorRequest, WithFileFilterParameters())
+	require.NoError(t, err)
+	sourceRetentionOptionsUnavailableError := &SourceRetentionOptionsUnavailableError{}
+	require.ErrorAs(t, sourceRetentionOptionsErr, &sourceRetentionOptionsUnavailableError)
+}
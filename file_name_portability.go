@@ -0,0 +1,125 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// *** PRIVATE ***
+
+// fileNamePortabilityChecker checks that the names of CodeGeneratorResponse.Files can be written
+// to all common filesystems.
+type fileNamePortabilityChecker struct {
+	// Zero or less means that the length of names is not checked.
+	maxFileNameLength int
+	// Non-nil if issues should be warnings instead of errors.
+	warningFunc func(error)
+}
+
+// check checks the names of all files in the response.
+//
+// Must be called after validateAndNormalizeCodeGeneratorResponse, as names are expected to be normalized.
+func (c *fileNamePortabilityChecker) check(response *pluginpb.CodeGeneratorResponse) error {
+	// Case-folded file or directory path to the first file or directory path seen with that case-folded value.
+	foldedPathToPath := make(map[string]string)
+	for _, file := range response.GetFile() {
+		if file.GetInsertionPoint() != "" {
+			// Files with insertion points refer to other files, and do not result in new files.
+			continue
+		}
+		name := file.GetName()
+		if c.maxFileNameLength > 0 && len(name) > c.maxFileNameLength {
+			if err := c.handle(
+				name,
+				fmt.Sprintf("name length of %d exceeds limit of %d", len(name), c.maxFileNameLength),
+			); err != nil {
+				return err
+			}
+		}
+		if message := checkPathElementPortability(name); message != "" {
+			if err := c.handle(name, message); err != nil {
+				return err
+			}
+		}
+		// We check every directory that the file is contained within, as well as the file itself. Both
+		// "Foo/a.txt" and "foo/b.txt" result in the same directory on case-insensitive filesystems.
+		for i := 0; i <= len(name); i++ {
+			if i != len(name) && name[i] != '/' {
+				continue
+			}
+			path := name[:i]
+			foldedPath := strings.ToLower(path)
+			existingPath, ok := foldedPathToPath[foldedPath]
+			if !ok {
+				foldedPathToPath[foldedPath] = path
+				continue
+			}
+			if existingPath != path {
+				if err := c.handle(
+					name,
+					fmt.Sprintf("path %q collides with %q on case-insensitive filesystems", path, existingPath),
+				); err != nil {
+					return err
+				}
+				// Only report the first collision for each file.
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// maxPathElementLength is the maximum length in bytes of a single file or directory name on common filesystems,
+// such as ext4, APFS, and NTFS.
+const maxPathElementLength = 255
+
+// windowsReservedNames are the names of devices on Windows, which cannot be used as file or directory names
+// regardless of case or extension.
+var windowsReservedNames = map[string]struct{}{
+	"con": {}, "prn": {}, "aux": {}, "nul": {},
+	"com1": {}, "com2": {}, "com3": {}, "com4": {}, "com5": {}, "com6": {}, "com7": {}, "com8": {}, "com9": {},
+	"lpt1": {}, "lpt2": {}, "lpt3": {}, "lpt4": {}, "lpt5": {}, "lpt6": {}, "lpt7": {}, "lpt8": {}, "lpt9": {},
+}
+
+// checkPathElementPortability checks each file and directory name within the path, returning a message
+// describing the first issue, or empty if there are no issues.
+func checkPathElementPortability(name string) string {
+	for _, element := range strings.Split(name, "/") {
+		if len(element) > maxPathElementLength {
+			return fmt.Sprintf("path element %q has length of %d which exceeds limit of %d", element, len(element), maxPathElementLength)
+		}
+		// Windows treats "aux.txt" and "aux.tar.gz" the same as "aux".
+		base, _, _ := strings.Cut(element, ".")
+		if _, ok := windowsReservedNames[strings.ToLower(strings.TrimRight(base, " "))]; ok {
+			return fmt.Sprintf("path element %q is a reserved name on Windows", element)
+		}
+		if strings.HasSuffix(element, ".") || strings.HasSuffix(element, " ") {
+			return fmt.Sprintf("path element %q ends with a dot or space, which is removed on Windows", element)
+		}
+	}
+	return ""
+}
+
+func (c *fileNamePortabilityChecker) handle(name string, message string) error {
+	if c.warningFunc == nil {
+		return newFileNamePortabilityError(name, message, false)
+	}
+	c.warningFunc(newFileNamePortabilityError(name, message, true))
+	return nil
+}
@@ -0,0 +1,149 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"crypto/sha256"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// WithFrozenRequest returns a new RunOption that says to fail if the CodeGeneratorRequest is modified
+// while the Handler is invoked.
+//
+// Handlers must not modify the CodeGeneratorRequest or the FileDescriptorProtos returned from a Request,
+// however nothing prevents them from doing so, for example via the Unsafe methods on Request or via
+// Request.CodeGeneratorRequest. Such modifications can result in corruption that is very hard to diagnose,
+// especially when Handlers are invoked in-process or multiple Handlers share a CodeGeneratorRequest.
+//
+// With this option, a fingerprint of the CodeGeneratorRequest is computed before any request interceptors
+// and the Handler are invoked, and is compared to a fingerprint computed after the Handler returns. If the
+// fingerprints differ, a *RequestMutationError is returned, which results in the plugin exiting with
+// a non-zero exit code. Modifications made after the Handler returns, for example by goroutines that
+// outlive the Handler, are not detected.
+//
+// Computing the fingerprints requires the CodeGeneratorRequest to be serialized twice, so this option is
+// primarily meant for tests and for debugging.
+//
+// This option can be passed to Main or Run.
+//
+// The default is to not check for modifications of the CodeGeneratorRequest.
+func WithFrozenRequest() RunOption {
+	return optsFunc(func(opts *opts) {
+		opts.frozenRequest = true
+	})
+}
+
+// *** PRIVATE ***
+
+// requestFingerprint is a fingerprint of a CodeGeneratorRequest.
+//
+// The FileDescriptorProtos are fingerprinted individually, so that modified files can be reported.
+type requestFingerprint struct {
+	// The fingerprint of the CodeGeneratorRequest without proto_file and source_file_descriptors.
+	other                 [sha256.Size]byte
+	protoFiles            [][sha256.Size]byte
+	sourceFileDescriptors [][sha256.Size]byte
+}
+
+func newRequestFingerprint(codeGeneratorRequest *pluginpb.CodeGeneratorRequest) (*requestFingerprint, error) {
+	other, err := fingerprintMessage(
+		&pluginpb.CodeGeneratorRequest{
+			FileToGenerate:  codeGeneratorRequest.GetFileToGenerate(),
+			Parameter:       codeGeneratorRequest.Parameter,
+			CompilerVersion: codeGeneratorRequest.GetCompilerVersion(),
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	protoFiles, err := fingerprintFileDescriptorProtos(codeGeneratorRequest.GetProtoFile())
+	if err != nil {
+		return nil, err
+	}
+	sourceFileDescriptors, err := fingerprintFileDescriptorProtos(codeGeneratorRequest.GetSourceFileDescriptors())
+	if err != nil {
+		return nil, err
+	}
+	return &requestFingerprint{
+		other:                 other,
+		protoFiles:            protoFiles,
+		sourceFileDescriptors: sourceFileDescriptors,
+	}, nil
+}
+
+// check returns a *RequestMutationError if the CodeGeneratorRequest no longer matches the fingerprint.
+func (r *requestFingerprint) check(codeGeneratorRequest *pluginpb.CodeGeneratorRequest) error {
+	current, err := newRequestFingerprint(codeGeneratorRequest)
+	if err != nil {
+		return err
+	}
+	var modifiedFileNames []string
+	modifiedFileNames = appendModifiedFileNames(
+		modifiedFileNames,
+		r.protoFiles,
+		current.protoFiles,
+		codeGeneratorRequest.GetProtoFile(),
+	)
+	modifiedFileNames = appendModifiedFileNames(
+		modifiedFileNames,
+		r.sourceFileDescriptors,
+		current.sourceFileDescriptors,
+		codeGeneratorRequest.GetSourceFileDescriptors(),
+	)
+	if len(modifiedFileNames) == 0 &&
+		r.other == current.other &&
+		len(r.protoFiles) == len(current.protoFiles) &&
+		len(r.sourceFileDescriptors) == len(current.sourceFileDescriptors) {
+		return nil
+	}
+	return newRequestMutationError(modifiedFileNames)
+}
+
+func appendModifiedFileNames(
+	modifiedFileNames []string,
+	previous [][sha256.Size]byte,
+	current [][sha256.Size]byte,
+	fileDescriptorProtos []*descriptorpb.FileDescriptorProto,
+) []string {
+	for i := 0; i < len(previous) && i < len(current); i++ {
+		if previous[i] != current[i] {
+			modifiedFileNames = append(modifiedFileNames, fileDescriptorProtos[i].GetName())
+		}
+	}
+	return modifiedFileNames
+}
+
+func fingerprintFileDescriptorProtos(fileDescriptorProtos []*descriptorpb.FileDescriptorProto) ([][sha256.Size]byte, error) {
+	fingerprints := make([][sha256.Size]byte, len(fileDescriptorProtos))
+	for i, fileDescriptorProto := range fileDescriptorProtos {
+		fingerprint, err := fingerprintMessage(fileDescriptorProto)
+		if err != nil {
+			return nil, err
+		}
+		fingerprints[i] = fingerprint
+	}
+	return fingerprints, nil
+}
+
+func fingerprintMessage(message proto.Message) ([sha256.Size]byte, error) {
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(message)
+	if err != nil {
+		return [sha256.Size]byte{}, err
+	}
+	return sha256.Sum256(data), nil
+}
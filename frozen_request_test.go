@@ -0,0 +1,79 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestWithFrozenRequestOption(t *testing.T) {
+	t.Parallel()
+
+	newCodeGeneratorRequest := func() *pluginpb.CodeGeneratorRequest {
+		return &pluginpb.CodeGeneratorRequest{
+			FileToGenerate: []string{"a.proto", "b.proto"},
+			ProtoFile: []*descriptorpb.FileDescriptorProto{
+				{
+					Name:   proto.String("a.proto"),
+					Syntax: proto.String("proto3"),
+				},
+				{
+					Name:   proto.String("b.proto"),
+					Syntax: proto.String("proto3"),
+				},
+			},
+		}
+	}
+	invoke := func(handler HandlerFunc, runOptions ...RunOption) error {
+		_, err := Invoke(context.Background(), handler, newCodeGeneratorRequest(), runOptions...)
+		return err
+	}
+	modifyFile := HandlerFunc(
+		func(_ context.Context, _ PluginEnv, _ ResponseWriter, request Request) error {
+			request.AllFileDescriptorProtosUnsafe()[1].Package = proto.String("foo")
+			return nil
+		},
+	)
+	modifyParameter := HandlerFunc(
+		func(_ context.Context, _ PluginEnv, _ ResponseWriter, request Request) error {
+			request.CodeGeneratorRequest().Parameter = proto.String("foo")
+			return nil
+		},
+	)
+	readOnly := HandlerFunc(
+		func(_ context.Context, _ PluginEnv, responseWriter ResponseWriter, request Request) error {
+			for _, fileDescriptorProto := range request.FileDescriptorProtosToGenerate() {
+				responseWriter.AddFile(fileDescriptorProto.GetName()+".txt", "")
+			}
+			return nil
+		},
+	)
+
+	require.NoError(t, invoke(modifyFile))
+	require.NoError(t, invoke(readOnly, WithFrozenRequest()))
+	err := invoke(modifyFile, WithFrozenRequest())
+	requestMutationError := &RequestMutationError{}
+	require.ErrorAs(t, err, &requestMutationError)
+	require.Equal(t, []string{"b.proto"}, requestMutationError.ModifiedFileNames)
+	err = invoke(modifyParameter, WithFrozenRequest())
+	require.ErrorAs(t, err, &requestMutationError)
+	require.Empty(t, requestMutationError.ModifiedFileNames)
+}
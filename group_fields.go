@@ -0,0 +1,284 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// GroupFieldDiagnosticCode is the code of the Diagnostics reported for group fields with GroupFieldPolicyWarn.
+const GroupFieldDiagnosticCode = "GROUP_FIELD"
+
+// GroupFieldPolicy says how group fields within the files to generate are handled.
+//
+// Group fields are proto2 fields of type TYPE_GROUP, and message fields that use the DELIMITED
+// message_encoding feature in Editions. Both are encoded on the wire with the deprecated group
+// encoding, which many plugins do not support.
+//
+// See WithGroupFieldPolicy for more details.
+type GroupFieldPolicy int
+
+const (
+	// GroupFieldPolicyAllow says to pass group fields to the Handler unmodified.
+	GroupFieldPolicyAllow GroupFieldPolicy = iota
+	// GroupFieldPolicyWarn says to report a warning Diagnostic with the code GroupFieldDiagnosticCode for every
+	// group field within the files to generate, and then invoke the Handler.
+	GroupFieldPolicyWarn
+	// GroupFieldPolicyError says to add an error to the response if any file to generate contains a group field,
+	// without invoking the Handler.
+	GroupFieldPolicyError
+	// GroupFieldPolicyConvert says to present all group fields to the Handler as regular message fields.
+	//
+	// Fields of type TYPE_GROUP are changed to TYPE_MESSAGE, and message fields that use the DELIMITED
+	// message_encoding feature have the feature set to LENGTH_PREFIXED. This is done for all files in the
+	// CodeGeneratorRequest, so that the view of all files is consistent.
+	//
+	// Note that this changes the wire format of the converted fields as seen by the Handler, so generated
+	// code that serializes messages will not be wire-compatible with other implementations for these fields.
+	// This is meant for Handlers that only need the message structure, such as documentation generators.
+	GroupFieldPolicyConvert
+)
+
+// WithGroupFieldPolicy returns a new RunOption that says to handle group fields according to the GroupFieldPolicy.
+//
+// This allows plugins that cannot support group fields to produce a clear warning or error, or to process
+// group fields as regular message fields, instead of crashing or silently generating incorrect code.
+//
+// This option can be passed to Main or Run.
+//
+// The default is GroupFieldPolicyAllow.
+func WithGroupFieldPolicy(groupFieldPolicy GroupFieldPolicy) RunOption {
+	return optsFunc(func(opts *opts) {
+		opts.groupFieldPolicy = groupFieldPolicy
+	})
+}
+
+// *** PRIVATE ***
+
+const (
+	fileMessageTypeTag   = 4
+	fileExtensionTag     = 7
+	messageFieldTag      = 2
+	messageNestedTypeTag = 3
+	messageExtensionTag  = 6
+)
+
+// groupField is a group field within a file.
+type groupField struct {
+	fileDescriptorProto  *descriptorpb.FileDescriptorProto
+	fieldDescriptorProto *descriptorpb.FieldDescriptorProto
+	// The fully-qualified name of the field, without a leading '.'.
+	fullName string
+	// The path of the field within the file, as used by SourceCodeInfo.Location.path.
+	path []int32
+}
+
+// checkGroupFields applies GroupFieldPolicyWarn and GroupFieldPolicyError to the files to generate.
+//
+// An error is returned if the GroupFieldPolicy is GroupFieldPolicyError and any file to generate
+// contains a group field.
+func checkGroupFields(pluginEnv PluginEnv, request Request, groupFieldPolicy GroupFieldPolicy) error {
+	if groupFieldPolicy != GroupFieldPolicyWarn && groupFieldPolicy != GroupFieldPolicyError {
+		return nil
+	}
+	for _, fileDescriptorProto := range request.FileDescriptorProtosToGenerateUnsafe() {
+		for _, groupField := range findGroupFields(fileDescriptorProto) {
+			message := fmt.Sprintf("field %s is a group field, which is not supported by this plugin", groupField.fullName)
+			if groupFieldPolicy == GroupFieldPolicyError {
+				return fmt.Errorf("%s: %s", fileDescriptorProto.GetName(), message)
+			}
+			pluginEnv.Report(
+				Diagnostic{
+					Severity: DiagnosticSeverityWarning,
+					Code:     GroupFieldDiagnosticCode,
+					Message:  message,
+					File:     fileDescriptorProto.GetName(),
+					Path:     groupField.path,
+				},
+			)
+		}
+	}
+	return nil
+}
+
+// convertGroupFields returns a CodeGeneratorRequest with all group fields converted to regular message fields.
+//
+// The CodeGeneratorRequest is not modified. If there are no group fields, the CodeGeneratorRequest is returned.
+// Otherwise, a shallow copy is returned, with converted copies of the files that contain group fields.
+func convertGroupFields(codeGeneratorRequest *pluginpb.CodeGeneratorRequest) *pluginpb.CodeGeneratorRequest {
+	protoFile, protoFileConverted := convertGroupFieldsInFiles(codeGeneratorRequest.GetProtoFile())
+	sourceFileDescriptors, sourceFileDescriptorsConverted := convertGroupFieldsInFiles(codeGeneratorRequest.GetSourceFileDescriptors())
+	if !protoFileConverted && !sourceFileDescriptorsConverted {
+		return codeGeneratorRequest
+	}
+	return &pluginpb.CodeGeneratorRequest{
+		FileToGenerate:        codeGeneratorRequest.GetFileToGenerate(),
+		Parameter:             codeGeneratorRequest.Parameter,
+		ProtoFile:             protoFile,
+		SourceFileDescriptors: sourceFileDescriptors,
+		CompilerVersion:       codeGeneratorRequest.GetCompilerVersion(),
+	}
+}
+
+// convertGroupFieldsInFiles returns the files with group fields converted, and whether any file was converted.
+func convertGroupFieldsInFiles(fileDescriptorProtos []*descriptorpb.FileDescriptorProto) ([]*descriptorpb.FileDescriptorProto, bool) {
+	var converted []*descriptorpb.FileDescriptorProto
+	for i, fileDescriptorProto := range fileDescriptorProtos {
+		if len(findGroupFields(fileDescriptorProto)) == 0 {
+			continue
+		}
+		if converted == nil {
+			converted = slicesClone(fileDescriptorProtos)
+		}
+		// Clone so that the original file is not modified, and find the group fields within the clone.
+		clone, _ := proto.Clone(fileDescriptorProto).(*descriptorpb.FileDescriptorProto)
+		for _, groupField := range findGroupFields(clone) {
+			convertGroupField(groupField)
+		}
+		converted[i] = clone
+	}
+	if converted == nil {
+		return fileDescriptorProtos, false
+	}
+	return converted, true
+}
+
+func convertGroupField(groupField *groupField) {
+	fieldDescriptorProto := groupField.fieldDescriptorProto
+	fieldDescriptorProto.Type = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+	if !isEditionsFileDescriptorProto(groupField.fileDescriptorProto) {
+		return
+	}
+	// The message_encoding may be inherited, so it must be set explicitly on the field.
+	if fieldDescriptorProto.Options == nil {
+		fieldDescriptorProto.Options = &descriptorpb.FieldOptions{}
+	}
+	if fieldDescriptorProto.Options.Features == nil {
+		fieldDescriptorProto.Options.Features = &descriptorpb.FeatureSet{}
+	}
+	fieldDescriptorProto.Options.Features.MessageEncoding = descriptorpb.FeatureSet_LENGTH_PREFIXED.Enum()
+}
+
+// findGroupFields returns all group fields within the file, including within nested messages and extensions.
+func findGroupFields(fileDescriptorProto *descriptorpb.FileDescriptorProto) []*groupField {
+	finder := &groupFieldFinder{
+		fileDescriptorProto: fileDescriptorProto,
+		editions:            isEditionsFileDescriptorProto(fileDescriptorProto),
+	}
+	fileMessageEncoding := fileDescriptorProto.GetOptions().GetFeatures().GetMessageEncoding()
+	finder.findInFields(fileDescriptorProto.GetExtension(), fileDescriptorProto.GetPackage(), []int32{fileExtensionTag}, fileMessageEncoding, nil)
+	for i, descriptorProto := range fileDescriptorProto.GetMessageType() {
+		finder.findInMessage(descriptorProto, fileDescriptorProto.GetPackage(), []int32{fileMessageTypeTag, int32(i)}, fileMessageEncoding) // #nosec:G115
+	}
+	return finder.groupFields
+}
+
+type groupFieldFinder struct {
+	fileDescriptorProto *descriptorpb.FileDescriptorProto
+	editions            bool
+	groupFields         []*groupField
+}
+
+func (g *groupFieldFinder) findInMessage(
+	descriptorProto *descriptorpb.DescriptorProto,
+	scope string,
+	path []int32,
+	messageEncoding descriptorpb.FeatureSet_MessageEncoding,
+) {
+	if messageMessageEncoding := descriptorProto.GetOptions().GetFeatures().GetMessageEncoding(); messageMessageEncoding != descriptorpb.FeatureSet_MESSAGE_ENCODING_UNKNOWN {
+		messageEncoding = messageMessageEncoding
+	}
+	name := joinFullName(scope, descriptorProto.GetName())
+	// Map fields are always length-prefixed, regardless of the message_encoding feature.
+	mapEntryTypeNames := make(map[string]struct{})
+	for _, nestedDescriptorProto := range descriptorProto.GetNestedType() {
+		if nestedDescriptorProto.GetOptions().GetMapEntry() {
+			mapEntryTypeNames["."+joinFullName(name, nestedDescriptorProto.GetName())] = struct{}{}
+		}
+	}
+	g.findInFields(descriptorProto.GetField(), name, appendPath(path, messageFieldTag), messageEncoding, mapEntryTypeNames)
+	g.findInFields(descriptorProto.GetExtension(), name, appendPath(path, messageExtensionTag), messageEncoding, nil)
+	for i, nestedDescriptorProto := range descriptorProto.GetNestedType() {
+		if nestedDescriptorProto.GetOptions().GetMapEntry() {
+			continue
+		}
+		g.findInMessage(nestedDescriptorProto, name, appendPath(path, messageNestedTypeTag, int32(i)), messageEncoding) // #nosec:G115
+	}
+}
+
+func (g *groupFieldFinder) findInFields(
+	fieldDescriptorProtos []*descriptorpb.FieldDescriptorProto,
+	scope string,
+	path []int32,
+	messageEncoding descriptorpb.FeatureSet_MessageEncoding,
+	mapEntryTypeNames map[string]struct{},
+) {
+	for i, fieldDescriptorProto := range fieldDescriptorProtos {
+		if _, ok := mapEntryTypeNames[fieldDescriptorProto.GetTypeName()]; ok {
+			continue
+		}
+		if !g.isGroupField(fieldDescriptorProto, messageEncoding) {
+			continue
+		}
+		g.groupFields = append(
+			g.groupFields,
+			&groupField{
+				fileDescriptorProto:  g.fileDescriptorProto,
+				fieldDescriptorProto: fieldDescriptorProto,
+				fullName:             joinFullName(scope, fieldDescriptorProto.GetName()),
+				path:                 appendPath(path, int32(i)), // #nosec:G115
+			},
+		)
+	}
+}
+
+func (g *groupFieldFinder) isGroupField(
+	fieldDescriptorProto *descriptorpb.FieldDescriptorProto,
+	messageEncoding descriptorpb.FeatureSet_MessageEncoding,
+) bool {
+	switch fieldDescriptorProto.GetType() {
+	case descriptorpb.FieldDescriptorProto_TYPE_GROUP:
+		return true
+	case descriptorpb.FieldDescriptorProto_TYPE_MESSAGE:
+		if !g.editions {
+			return false
+		}
+		if fieldMessageEncoding := fieldDescriptorProto.GetOptions().GetFeatures().GetMessageEncoding(); fieldMessageEncoding != descriptorpb.FeatureSet_MESSAGE_ENCODING_UNKNOWN {
+			messageEncoding = fieldMessageEncoding
+		}
+		return messageEncoding == descriptorpb.FeatureSet_DELIMITED
+	default:
+		return false
+	}
+}
+
+func joinFullName(scope string, name string) string {
+	if scope == "" {
+		return name
+	}
+	return strings.Join([]string{scope, name}, ".")
+}
+
+// appendPath returns a new path with the elements appended, without modifying path.
+func appendPath(path []int32, elements ...int32) []int32 {
+	newPath := make([]int32, 0, len(path)+len(elements))
+	newPath = append(newPath, path...)
+	return append(newPath, elements...)
+}
@@ -0,0 +1,125 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestWithGroupFieldPolicyOption(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	fileDescriptorProtos, err := compile(
+		ctx,
+		map[string][]byte{
+			"a.proto": []byte(`syntax = "proto2";
+package foo;
+message A {
+  optional group G = 1 {
+    optional string s = 2;
+  }
+  map<string, A> m = 3;
+}
+`),
+			"b.proto": []byte(`edition = "2023";
+package bar;
+option features.message_encoding = DELIMITED;
+message B {
+  B delimited = 1;
+  B length_prefixed = 2 [features.message_encoding = LENGTH_PREFIXED];
+  map<string, B> m = 3;
+}
+`),
+		},
+	)
+	require.NoError(t, err)
+	newCodeGeneratorRequest := func() *pluginpb.CodeGeneratorRequest {
+		return &pluginpb.CodeGeneratorRequest{
+			FileToGenerate: []string{"a.proto", "b.proto"},
+			ProtoFile:      fileDescriptorProtos,
+		}
+	}
+	editionsHandler := &testCapabilityDeclarer{
+		capabilities: Capabilities{
+			MinimumEdition: descriptorpb.Edition_EDITION_2023,
+			MaximumEdition: descriptorpb.Edition_EDITION_2023,
+		},
+	}
+
+	codeGeneratorRequestData, err := proto.Marshal(newCodeGeneratorRequest())
+	require.NoError(t, err)
+	stderr := bytes.NewBuffer(nil)
+	err = Run(
+		ctx,
+		Env{
+			Stdin:  bytes.NewReader(codeGeneratorRequestData),
+			Stdout: io.Discard,
+			Stderr: stderr,
+		},
+		editionsHandler,
+		WithGroupFieldPolicy(GroupFieldPolicyWarn),
+	)
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		"warning: a.proto: field foo.A.g is a group field, which is not supported by this plugin (GROUP_FIELD)\n"+
+			"warning: b.proto: field bar.B.delimited is a group field, which is not supported by this plugin (GROUP_FIELD)\n",
+		stderr.String(),
+	)
+
+	codeGeneratorResponse, err := Invoke(ctx, editionsHandler, newCodeGeneratorRequest(), WithGroupFieldPolicy(GroupFieldPolicyError))
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		"a.proto: field foo.A.g is a group field, which is not supported by this plugin",
+		codeGeneratorResponse.GetError(),
+	)
+
+	codeGeneratorRequest := newCodeGeneratorRequest()
+	_, err = Invoke(
+		ctx,
+		HandlerFunc(func(_ context.Context, _ PluginEnv, responseWriter ResponseWriter, request Request) error {
+			responseWriter.SetFeatureSupportsEditions(descriptorpb.Edition_EDITION_2023, descriptorpb.Edition_EDITION_2023)
+			fileDescriptors, err := request.FileDescriptorsToGenerate()
+			if err != nil {
+				return err
+			}
+			g := fileDescriptors[0].Messages().ByName("A").Fields().ByName("g")
+			require.Equal(t, protoreflect.MessageKind, g.Kind())
+			delimited := fileDescriptors[1].Messages().ByName("B").Fields().ByName("delimited")
+			require.Equal(t, protoreflect.MessageKind, delimited.Kind())
+			return nil
+		}),
+		codeGeneratorRequest,
+		WithGroupFieldPolicy(GroupFieldPolicyConvert),
+	)
+	require.NoError(t, err)
+	// The original CodeGeneratorRequest is not modified.
+	require.Equal(
+		t,
+		descriptorpb.FieldDescriptorProto_TYPE_GROUP,
+		codeGeneratorRequest.GetProtoFile()[0].GetMessageType()[0].GetField()[0].GetType(),
+	)
+}
@@ -0,0 +1,180 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// HandlerMux is a Handler that routes to one of several registered Handlers based on the value of
+// a reserved parameter key.
+//
+// This enables one plugin binary to expose multiple generation modes. For example, a HandlerMux with the
+// parameter key "gen" and Handlers registered for "base", "grpc", and "mock" routes a request with the
+// parameter "gen=grpc,paths=source_relative" to the Handler registered for "grpc", which is invoked with
+// the parameter "paths=source_relative".
+//
+// The parameter is parsed as a comma-separated list of "key" or "key=value" elements, which is the
+// convention for protoc plugins. The element with the parameter key is removed from the parameter
+// given to the selected Handler. If the parameter key is not present, the default Handler specified
+// by HandlerMuxWithDefault is used. If the parameter key is not present and there is no default, the
+// parameter key is specified more than once, or the value does not match any registered Handler, an
+// error listing the available values is added to the response via AddError.
+//
+// If the selected Handler implements CapabilityDeclarer, its Capabilities are applied before it is invoked,
+// in the same manner as for the Handler given to Main or Run.
+type HandlerMux struct {
+	parameterKey  string
+	nameToHandler map[string]Handler
+	defaultName   string
+	// Sorted.
+	names []string
+}
+
+// HandlerMuxOption is an option for a new HandlerMux.
+type HandlerMuxOption func(*HandlerMux)
+
+// HandlerMuxWithDefault returns a new HandlerMuxOption that says to use the Handler registered for the given
+// name if the parameter key is not present.
+//
+// The name must be registered with the HandlerMux.
+//
+// The default is to add an error to the response if the parameter key is not present.
+func HandlerMuxWithDefault(name string) HandlerMuxOption {
+	return func(handlerMux *HandlerMux) {
+		handlerMux.defaultName = name
+	}
+}
+
+// NewHandlerMux returns a new HandlerMux that routes on the given parameter key to the Handlers
+// registered by name in nameToHandler.
+//
+// An error is returned if the parameter key is empty or contains "," or "=", if no Handlers are registered,
+// if any name is empty or contains ",", if any Handler is nil, or if the default name is not registered.
+func NewHandlerMux(
+	parameterKey string,
+	nameToHandler map[string]Handler,
+	options ...HandlerMuxOption,
+) (*HandlerMux, error) {
+	if parameterKey == "" {
+		return nil, errors.New("parameter key must not be empty")
+	}
+	if strings.ContainsAny(parameterKey, ",=") {
+		return nil, fmt.Errorf("parameter key %q must not contain ',' or '='", parameterKey)
+	}
+	if len(nameToHandler) == 0 {
+		return nil, errors.New("at least one handler must be registered")
+	}
+	handlerMux := &HandlerMux{
+		parameterKey:  parameterKey,
+		nameToHandler: make(map[string]Handler, len(nameToHandler)),
+		names:         make([]string, 0, len(nameToHandler)),
+	}
+	for name, handler := range nameToHandler {
+		if name == "" || strings.Contains(name, ",") {
+			return nil, fmt.Errorf("invalid handler name %q", name)
+		}
+		if handler == nil {
+			return nil, fmt.Errorf("handler for name %q was nil", name)
+		}
+		handlerMux.nameToHandler[name] = handler
+		handlerMux.names = append(handlerMux.names, name)
+	}
+	sort.Strings(handlerMux.names)
+	for _, option := range options {
+		option(handlerMux)
+	}
+	if handlerMux.defaultName != "" {
+		if _, ok := handlerMux.nameToHandler[handlerMux.defaultName]; !ok {
+			return nil, fmt.Errorf("default name %q is not registered", handlerMux.defaultName)
+		}
+	}
+	return handlerMux, nil
+}
+
+// Names returns the sorted names of the registered Handlers.
+func (h *HandlerMux) Names() []string {
+	return slicesClone(h.names)
+}
+
+// Handle implements Handler.
+func (h *HandlerMux) Handle(
+	ctx context.Context,
+	pluginEnv PluginEnv,
+	responseWriter ResponseWriter,
+	request Request,
+) error {
+	name, parameter, err := h.parseParameter(request.Parameter())
+	if err != nil {
+		responseWriter.AddError(err.Error())
+		return nil
+	}
+	handler := h.nameToHandler[name]
+	subRequest, err := NewSubRequest(request, request.CodeGeneratorRequest().GetFileToGenerate(), parameter)
+	if err != nil {
+		return err
+	}
+	if err := applyCapabilities(handler, responseWriter, subRequest); err != nil {
+		responseWriter.AddError(err.Error())
+		return nil
+	}
+	return handler.Handle(ctx, pluginEnv, responseWriter, subRequest)
+}
+
+// *** PRIVATE ***
+
+// parseParameter returns the name of the selected Handler, and the parameter with the parameter key removed.
+func (h *HandlerMux) parseParameter(parameter string) (string, string, error) {
+	var name string
+	var found bool
+	var remainingElements []string
+	if parameter != "" {
+		for _, element := range strings.Split(parameter, ",") {
+			key, value, _ := strings.Cut(element, "=")
+			if strings.TrimSpace(key) != h.parameterKey {
+				remainingElements = append(remainingElements, element)
+				continue
+			}
+			if found {
+				return "", "", fmt.Errorf("parameter %q was specified more than once", h.parameterKey)
+			}
+			name = strings.TrimSpace(value)
+			found = true
+		}
+	}
+	if !found {
+		if h.defaultName == "" {
+			return "", "", fmt.Errorf(
+				"parameter %q must be specified, available values are: %s",
+				h.parameterKey,
+				strings.Join(h.names, ", "),
+			)
+		}
+		return h.defaultName, parameter, nil
+	}
+	if _, ok := h.nameToHandler[name]; !ok {
+		return "", "", fmt.Errorf(
+			"unknown value %q for parameter %q, available values are: %s",
+			name,
+			h.parameterKey,
+			strings.Join(h.names, ", "),
+		)
+	}
+	return name, strings.Join(remainingElements, ","), nil
+}
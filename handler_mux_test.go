@@ -0,0 +1,127 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestHandlerMux(t *testing.T) {
+	t.Parallel()
+
+	newHandler := func(name string) Handler {
+		return HandlerFunc(
+			func(_ context.Context, _ PluginEnv, responseWriter ResponseWriter, request Request) error {
+				responseWriter.AddFile(name+".txt", request.Parameter())
+				return nil
+			},
+		)
+	}
+	nameToHandler := map[string]Handler{
+		"base": newHandler("base"),
+		"grpc": newHandler("grpc"),
+		"mock": newHandler("mock"),
+	}
+	handlerMux, err := NewHandlerMux("gen", nameToHandler, HandlerMuxWithDefault("base"))
+	require.NoError(t, err)
+	require.Equal(t, []string{"base", "grpc", "mock"}, handlerMux.Names())
+	noDefaultHandlerMux, err := NewHandlerMux("gen", nameToHandler)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		handlerMux      *HandlerMux
+		parameter       string
+		expectedFile    string
+		expectedContent string
+		expectedError   string
+	}{
+		{
+			handlerMux:      handlerMux,
+			parameter:       "gen=grpc,paths=source_relative",
+			expectedFile:    "grpc.txt",
+			expectedContent: "paths=source_relative",
+		},
+		{
+			handlerMux:      handlerMux,
+			parameter:       "paths=source_relative, gen = mock",
+			expectedFile:    "mock.txt",
+			expectedContent: "paths=source_relative",
+		},
+		{
+			handlerMux:      handlerMux,
+			parameter:       "paths=source_relative",
+			expectedFile:    "base.txt",
+			expectedContent: "paths=source_relative",
+		},
+		{
+			handlerMux:   handlerMux,
+			expectedFile: "base.txt",
+		},
+		{
+			handlerMux:    handlerMux,
+			parameter:     "gen=foo",
+			expectedError: `unknown value "foo" for parameter "gen", available values are: base, grpc, mock`,
+		},
+		{
+			handlerMux:    handlerMux,
+			parameter:     "gen=grpc,gen=mock",
+			expectedError: `parameter "gen" was specified more than once`,
+		},
+		{
+			handlerMux:    noDefaultHandlerMux,
+			parameter:     "paths=source_relative",
+			expectedError: `parameter "gen" must be specified, available values are: base, grpc, mock`,
+		},
+	}
+	for _, testCase := range testCases {
+		codeGeneratorRequest := &pluginpb.CodeGeneratorRequest{
+			FileToGenerate: []string{"a.proto"},
+			ProtoFile: []*descriptorpb.FileDescriptorProto{
+				{
+					Name:   proto.String("a.proto"),
+					Syntax: proto.String("proto3"),
+				},
+			},
+		}
+		if testCase.parameter != "" {
+			codeGeneratorRequest.Parameter = proto.String(testCase.parameter)
+		}
+		codeGeneratorResponse, err := Invoke(context.Background(), testCase.handlerMux, codeGeneratorRequest)
+		require.NoError(t, err)
+		require.Equal(t, testCase.expectedError, codeGeneratorResponse.GetError(), testCase.parameter)
+		if testCase.expectedFile != "" {
+			require.Len(t, codeGeneratorResponse.GetFile(), 1)
+			require.Equal(t, testCase.expectedFile, codeGeneratorResponse.GetFile()[0].GetName())
+			require.Equal(t, testCase.expectedContent, codeGeneratorResponse.GetFile()[0].GetContent())
+		} else {
+			require.Empty(t, codeGeneratorResponse.GetFile())
+		}
+	}
+
+	_, err = NewHandlerMux("", nameToHandler)
+	require.Error(t, err)
+	_, err = NewHandlerMux("gen", nil)
+	require.Error(t, err)
+	_, err = NewHandlerMux("gen", map[string]Handler{"base": nil})
+	require.Error(t, err)
+	_, err = NewHandlerMux("gen", nameToHandler, HandlerMuxWithDefault("foo"))
+	require.Error(t, err)
+}
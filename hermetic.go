@@ -0,0 +1,150 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// SourceDateEpochEnvKey is the environment variable that, if set to a number of seconds since the Unix epoch,
+// fixes the time returned by PluginEnv.Now.
+//
+// See https://reproducible-builds.org/specs/source-date-epoch.
+const SourceDateEpochEnvKey = "SOURCE_DATE_EPOCH"
+
+// Now returns the current time as seen by the plugin.
+//
+// Handlers that stamp times into generated files should use this instead of time.Now, so that outputs
+// can be made reproducible for build caching.
+//
+// If WithNowFunc was specified, the result of the function is returned. Otherwise, if SourceDateEpochEnvKey
+// is set to a valid number of seconds since the Unix epoch, that time is returned in UTC. Otherwise,
+// time.Now is returned.
+func (p PluginEnv) Now() time.Time {
+	if p.nowFunc != nil {
+		return p.nowFunc()
+	}
+	if value, _ := p.LookupEnv(SourceDateEpochEnvKey); value != "" {
+		if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return time.Unix(seconds, 0).UTC()
+		}
+	}
+	return time.Now()
+}
+
+// Rand returns a source of pseudo-random numbers for the plugin.
+//
+// Handlers that generate identifiers should use this instead of the global functions in math/rand, so
+// that outputs can be made reproducible for build caching.
+//
+// By default, the source is seeded deterministically from a digest of the CodeGeneratorRequest, so the
+// same CodeGeneratorRequest results in the same sequence of numbers. The seed can be overridden with
+// WithRandSeed. If the PluginEnv was not provided by this package, the seed is 0.
+//
+// All *rand.Rand values returned from the same PluginEnv share a single source, and are safe for
+// concurrent use by multiple goroutines. The sequence of numbers is only deterministic if the order
+// of calls is deterministic. This source is not suitable for security-sensitive work.
+func (p PluginEnv) Rand() *rand.Rand {
+	if p.randSource == nil {
+		return rand.New(rand.NewSource(0)) // #nosec:G404
+	}
+	return rand.New(p.randSource) // #nosec:G404
+}
+
+// WithNowFunc returns a new RunOption that overrides the time returned by PluginEnv.Now.
+//
+// This is useful for tests, and for plugins that need a fixed time without relying on SourceDateEpochEnvKey.
+//
+// This option can be passed to Main or Run.
+func WithNowFunc(nowFunc func() time.Time) RunOption {
+	return optsFunc(func(opts *opts) {
+		opts.nowFunc = nowFunc
+	})
+}
+
+// WithRandSeed returns a new RunOption that seeds the source returned by PluginEnv.Rand with the given
+// seed, instead of a digest of the CodeGeneratorRequest.
+//
+// This option can be passed to Main or Run.
+func WithRandSeed(seed int64) RunOption {
+	return optsFunc(func(opts *opts) {
+		opts.randSeed = &seed
+	})
+}
+
+// *** PRIVATE ***
+
+// lockedRandSource is a rand.Source64 that is safe for concurrent use, and that is seeded lazily.
+type lockedRandSource struct {
+	getSource func() rand.Source64
+	lock      sync.Mutex
+}
+
+func newLockedRandSource(getSeed func() int64) *lockedRandSource {
+	return &lockedRandSource{
+		getSource: onceValue(func() rand.Source64 {
+			// rand.NewSource always returns a rand.Source64.
+			source, _ := rand.NewSource(getSeed()).(rand.Source64) // #nosec:G404
+			return source
+		}),
+	}
+}
+
+func (s *lockedRandSource) Int63() int64 {
+	source := s.getSource()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return source.Int63()
+}
+
+func (s *lockedRandSource) Uint64() uint64 {
+	source := s.getSource()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return source.Uint64()
+}
+
+func (s *lockedRandSource) Seed(seed int64) {
+	source := s.getSource()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	source.Seed(seed)
+}
+
+// newRequestRandSource returns the source for PluginEnv.Rand for the CodeGeneratorRequest.
+//
+// If randSeed is nil, the seed is computed from a digest of the CodeGeneratorRequest the first time
+// the source is used, so that Handlers that do not use PluginEnv.Rand do not pay for the digest.
+func newRequestRandSource(codeGeneratorRequest *pluginpb.CodeGeneratorRequest, randSeed *int64) *lockedRandSource {
+	if randSeed != nil {
+		seed := *randSeed
+		return newLockedRandSource(func() int64 { return seed })
+	}
+	return newLockedRandSource(func() int64 {
+		// Marshaling a valid CodeGeneratorRequest does not fail, and the seed is not required to
+		// be unique, so an error results in the digest of the empty data.
+		data, _ := proto.MarshalOptions{Deterministic: true}.Marshal(codeGeneratorRequest)
+		digest := sha256.Sum256(data)
+		return int64(binary.BigEndian.Uint64(digest[:8])) // #nosec:G115
+	})
+}
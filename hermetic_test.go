@@ -0,0 +1,99 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestPluginEnvNow(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(
+		t,
+		time.Unix(1700000000, 0).UTC(),
+		PluginEnv{Environ: []string{SourceDateEpochEnvKey + "=1700000000"}}.Now(),
+	)
+	before := time.Now()
+	now := PluginEnv{Environ: []string{SourceDateEpochEnvKey + "=invalid"}}.Now()
+	require.False(t, now.Before(before))
+
+	fixed := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	var handlerNow time.Time
+	_, err := Invoke(
+		context.Background(),
+		HandlerFunc(func(_ context.Context, pluginEnv PluginEnv, _ ResponseWriter, _ Request) error {
+			handlerNow = pluginEnv.Now()
+			return nil
+		}),
+		testNewRandCodeGeneratorRequest("a.proto"),
+		WithNowFunc(func() time.Time { return fixed }),
+	)
+	require.NoError(t, err)
+	require.Equal(t, fixed, handlerNow)
+}
+
+func TestPluginEnvRand(t *testing.T) {
+	t.Parallel()
+
+	invoke := func(codeGeneratorRequest *pluginpb.CodeGeneratorRequest, runOptions ...RunOption) []int64 {
+		var values []int64
+		_, err := Invoke(
+			context.Background(),
+			HandlerFunc(func(_ context.Context, pluginEnv PluginEnv, _ ResponseWriter, _ Request) error {
+				values = append(values, pluginEnv.Rand().Int63(), pluginEnv.Rand().Int63())
+				return nil
+			}),
+			codeGeneratorRequest,
+			runOptions...,
+		)
+		require.NoError(t, err)
+		return values
+	}
+
+	values := invoke(testNewRandCodeGeneratorRequest("a.proto"))
+	// The source is shared between calls to Rand.
+	require.NotEqual(t, values[0], values[1])
+	require.Equal(t, values, invoke(testNewRandCodeGeneratorRequest("a.proto")))
+	require.NotEqual(t, values, invoke(testNewRandCodeGeneratorRequest("b.proto")))
+
+	seeded := rand.New(rand.NewSource(42))
+	require.Equal(
+		t,
+		[]int64{seeded.Int63(), seeded.Int63()},
+		invoke(testNewRandCodeGeneratorRequest("a.proto"), WithRandSeed(42)),
+	)
+	require.Equal(t, rand.New(rand.NewSource(0)).Int63(), PluginEnv{}.Rand().Int63())
+}
+
+func testNewRandCodeGeneratorRequest(fileName string) *pluginpb.CodeGeneratorRequest {
+	return &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{fileName},
+		ProtoFile: []*descriptorpb.FileDescriptorProto{
+			{
+				Name:   proto.String(fileName),
+				Syntax: proto.String("proto3"),
+			},
+		},
+	}
+}
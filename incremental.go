@@ -0,0 +1,260 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// Incremental supports incremental generation, where expensive per-file work is skipped for files to
+// generate that have not changed since a previous invocation of the plugin.
+//
+// Each file to generate is fingerprinted by hashing its FileDescriptorProto, the FileDescriptorProtos of
+// all of its transitive dependencies, the parameter, and the version given to NewIncremental. The files
+// generated for a file to generate are stored in PluginEnv.CacheDir keyed by this fingerprint.
+//
+// A typical Handler looks like:
+//
+//	incremental, err := protoplugin.NewIncremental(pluginEnv, request, version)
+//	if err != nil {
+//		return err
+//	}
+//	changedFileDescriptorProtos, err := incremental.ChangedFileDescriptorProtosToGenerate(responseWriter)
+//	if err != nil {
+//		return err
+//	}
+//	for _, fileDescriptorProto := range changedFileDescriptorProtos {
+//		incremental.AddFile(responseWriter, fileDescriptorProto.GetName(), name, generate(fileDescriptorProto))
+//	}
+//	return incremental.Close()
+//
+// An Incremental is safe for concurrent use.
+type Incremental struct {
+	dirPath                  string
+	fileNameToFingerprint    map[string]string
+	fileDescriptorProtos     []*descriptorpb.FileDescriptorProto
+	lock                     sync.Mutex
+	fileNameToGeneratedFiles map[string][]*pluginpb.CodeGeneratorResponse_File
+}
+
+// NewIncremental returns a new Incremental for the Request.
+//
+// The version should change whenever the output of the plugin could change for the same input, for
+// example the version of the plugin. The plugin name must be set with WithPluginName, see
+// PluginEnv.CacheDir.
+func NewIncremental(pluginEnv PluginEnv, request Request, version string) (*Incremental, error) {
+	cacheDirPath, err := pluginEnv.CacheDir()
+	if err != nil {
+		return nil, err
+	}
+	dirPath := filepath.Join(cacheDirPath, incrementalDirName)
+	if err := os.MkdirAll(dirPath, 0o755); err != nil {
+		return nil, err
+	}
+	fileNameToFileDescriptorProto := make(map[string]*descriptorpb.FileDescriptorProto)
+	for _, fileDescriptorProto := range request.AllFileDescriptorProtosUnsafe() {
+		fileNameToFileDescriptorProto[fileDescriptorProto.GetName()] = fileDescriptorProto
+	}
+	fileDescriptorProtos := request.FileDescriptorProtosToGenerateUnsafe()
+	fileNameToFingerprint := make(map[string]string, len(fileDescriptorProtos))
+	for _, fileDescriptorProto := range fileDescriptorProtos {
+		fingerprint, err := incrementalFingerprint(
+			fileDescriptorProto,
+			fileNameToFileDescriptorProto,
+			request.Parameter(),
+			version,
+		)
+		if err != nil {
+			return nil, err
+		}
+		fileNameToFingerprint[fileDescriptorProto.GetName()] = fingerprint
+	}
+	return &Incremental{
+		dirPath:                  dirPath,
+		fileNameToFingerprint:    fileNameToFingerprint,
+		fileDescriptorProtos:     fileDescriptorProtos,
+		fileNameToGeneratedFiles: make(map[string][]*pluginpb.CodeGeneratorResponse_File),
+	}, nil
+}
+
+// ChangedFileDescriptorProtosToGenerate returns the FileDescriptorProtos for the files to generate that
+// have changed since a previous invocation, in the order of FileDescriptorProtosToGenerate.
+//
+// For all files to generate that have not changed, the files that were generated for them by the previous
+// invocation are added to the ResponseWriter.
+func (i *Incremental) ChangedFileDescriptorProtosToGenerate(responseWriter ResponseWriter) ([]*descriptorpb.FileDescriptorProto, error) {
+	var changedFileDescriptorProtos []*descriptorpb.FileDescriptorProto
+	for _, fileDescriptorProto := range i.fileDescriptorProtos {
+		codeGeneratorResponse, err := i.readEntry(i.fileNameToFingerprint[fileDescriptorProto.GetName()])
+		if err != nil {
+			return nil, err
+		}
+		if codeGeneratorResponse == nil {
+			changedFileDescriptorProtos = append(changedFileDescriptorProtos, fileDescriptorProto)
+			continue
+		}
+		responseWriter.AddCodeGeneratorResponseFiles(codeGeneratorResponse.GetFile()...)
+	}
+	return changedFileDescriptorProtos, nil
+}
+
+// AddFile adds the file with the given name and content to the ResponseWriter, and records it as generated
+// for the given file to generate.
+//
+// The recorded files are stored when Close is called.
+func (i *Incremental) AddFile(responseWriter ResponseWriter, fileToGenerate string, name string, content string) {
+	responseWriter.AddFile(name, content)
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	i.fileNameToGeneratedFiles[fileToGenerate] = append(
+		i.fileNameToGeneratedFiles[fileToGenerate],
+		&pluginpb.CodeGeneratorResponse_File{
+			Name:    proto.String(name),
+			Content: proto.String(content),
+		},
+	)
+}
+
+// Close stores the files recorded with AddFile, so that they can be re-used by subsequent invocations.
+//
+// Close should only be called if generation succeeded.
+func (i *Incremental) Close() error {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	var errs []error
+	for fileToGenerate, generatedFiles := range i.fileNameToGeneratedFiles {
+		fingerprint, ok := i.fileNameToFingerprint[fileToGenerate]
+		if !ok {
+			errs = append(errs, errors.New(fileToGenerate+" was given to Incremental.AddFile but is not a file to generate"))
+			continue
+		}
+		if err := i.writeEntry(fingerprint, &pluginpb.CodeGeneratorResponse{File: generatedFiles}); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// *** PRIVATE ***
+
+const (
+	incrementalDirName         = "incremental"
+	incrementalFingerprintSalt = "protoplugin-incremental-v1"
+)
+
+// readEntry reads the entry for the fingerprint, returning nil if there is no valid entry.
+func (i *Incremental) readEntry(fingerprint string) (*pluginpb.CodeGeneratorResponse, error) {
+	entryFilePath := i.entryFilePath(fingerprint)
+	data, err := os.ReadFile(entryFilePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	codeGeneratorResponse := &pluginpb.CodeGeneratorResponse{}
+	if err := proto.Unmarshal(data, codeGeneratorResponse); err != nil {
+		// A corrupt entry is treated as a cache miss.
+		return nil, os.Remove(entryFilePath)
+	}
+	return codeGeneratorResponse, nil
+}
+
+// writeEntry atomically writes the entry for the fingerprint.
+func (i *Incremental) writeEntry(fingerprint string, codeGeneratorResponse *pluginpb.CodeGeneratorResponse) (retErr error) {
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(codeGeneratorResponse)
+	if err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(i.dirPath, fingerprint+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if retErr != nil {
+			_ = os.Remove(file.Name())
+		}
+	}()
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), i.entryFilePath(fingerprint))
+}
+
+func (i *Incremental) entryFilePath(fingerprint string) string {
+	return filepath.Join(i.dirPath, fingerprint+".binpb")
+}
+
+// incrementalFingerprint computes the fingerprint of the file to generate.
+func incrementalFingerprint(
+	fileDescriptorProto *descriptorpb.FileDescriptorProto,
+	fileNameToFileDescriptorProto map[string]*descriptorpb.FileDescriptorProto,
+	parameter string,
+	version string,
+) (string, error) {
+	hash := sha256.New()
+	marshalOptions := proto.MarshalOptions{Deterministic: true}
+	writeString := func(value string) {
+		// Length-prefix all values so that values cannot run into each other.
+		_, _ = hash.Write(protowire.AppendString(nil, value))
+	}
+	writeString(incrementalFingerprintSalt)
+	writeString(version)
+	writeString(parameter)
+	seen := make(map[string]struct{})
+	var writeFile func(*descriptorpb.FileDescriptorProto) error
+	writeFile = func(fileDescriptorProto *descriptorpb.FileDescriptorProto) error {
+		if _, ok := seen[fileDescriptorProto.GetName()]; ok {
+			return nil
+		}
+		seen[fileDescriptorProto.GetName()] = struct{}{}
+		data, err := marshalOptions.Marshal(fileDescriptorProto)
+		if err != nil {
+			return err
+		}
+		writeString(string(data))
+		for _, dependency := range fileDescriptorProto.GetDependency() {
+			dependencyFileDescriptorProto, ok := fileNameToFileDescriptorProto[dependency]
+			if !ok {
+				// Validation of the CodeGeneratorRequest ensures that this does not happen, however
+				// NewRequestWithoutValidation may have been used.
+				writeString("missing:" + dependency)
+				continue
+			}
+			if err := writeFile(dependencyFileDescriptorProto); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := writeFile(fileDescriptorProto); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
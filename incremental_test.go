@@ -0,0 +1,126 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestIncremental(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	cacheDirPath := t.TempDir()
+	newCodeGeneratorRequest := func(aData string) *pluginpb.CodeGeneratorRequest {
+		fileDescriptorProtos, err := compile(
+			ctx,
+			map[string][]byte{
+				"a.proto": []byte(aData),
+				"b.proto": []byte(`syntax = "proto3"; package foo; message B {}`),
+				"c.proto": []byte(`syntax = "proto3"; package foo; import "a.proto"; message C { A a = 1; }`),
+			},
+		)
+		require.NoError(t, err)
+		return &pluginpb.CodeGeneratorRequest{
+			FileToGenerate: []string{"a.proto", "b.proto", "c.proto"},
+			ProtoFile:      fileDescriptorProtos,
+		}
+	}
+	invoke := func(codeGeneratorRequest *pluginpb.CodeGeneratorRequest, version string) ([]string, map[string]string) {
+		var generated []string
+		handler := HandlerFunc(
+			func(_ context.Context, pluginEnv PluginEnv, responseWriter ResponseWriter, request Request) error {
+				incremental, err := NewIncremental(pluginEnv, request, version)
+				if err != nil {
+					return err
+				}
+				changedFileDescriptorProtos, err := incremental.ChangedFileDescriptorProtosToGenerate(responseWriter)
+				if err != nil {
+					return err
+				}
+				for _, fileDescriptorProto := range changedFileDescriptorProtos {
+					generated = append(generated, fileDescriptorProto.GetName())
+					incremental.AddFile(
+						responseWriter,
+						fileDescriptorProto.GetName(),
+						fileDescriptorProto.GetName()+".txt",
+						fileDescriptorProto.GetMessageType()[0].GetName()+"\n",
+					)
+				}
+				return incremental.Close()
+			},
+		)
+		codeGeneratorResponse, err := Invoke(
+			ctx,
+			HandlerFunc(
+				func(ctx context.Context, pluginEnv PluginEnv, responseWriter ResponseWriter, request Request) error {
+					pluginEnv.Environ = []string{CacheDirEnvKey + "=" + cacheDirPath}
+					return handler.Handle(ctx, pluginEnv, responseWriter, request)
+				},
+			),
+			codeGeneratorRequest,
+			WithPluginName("protoc-gen-test"),
+		)
+		require.NoError(t, err)
+		require.Empty(t, codeGeneratorResponse.GetError())
+		files := make(map[string]string)
+		for _, file := range codeGeneratorResponse.GetFile() {
+			files[file.GetName()] = file.GetContent()
+		}
+		return generated, files
+	}
+	expectedFiles := map[string]string{
+		"a.proto.txt": "A\n",
+		"b.proto.txt": "B\n",
+		"c.proto.txt": "C\n",
+	}
+
+	generated, files := invoke(newCodeGeneratorRequest(`syntax = "proto3"; package foo; message A {}`), "v1")
+	require.Equal(t, []string{"a.proto", "b.proto", "c.proto"}, generated)
+	require.Equal(t, expectedFiles, files)
+
+	// Nothing changed.
+	generated, files = invoke(newCodeGeneratorRequest(`syntax = "proto3"; package foo; message A {}`), "v1")
+	require.Empty(t, generated)
+	require.Equal(t, expectedFiles, files)
+
+	// a.proto changed, and c.proto depends on a.proto.
+	generated, files = invoke(newCodeGeneratorRequest(`syntax = "proto3"; package foo; message A { string s = 1; }`), "v1")
+	require.Equal(t, []string{"a.proto", "c.proto"}, generated)
+	require.Equal(t, expectedFiles, files)
+
+	// The version changed.
+	generated, files = invoke(newCodeGeneratorRequest(`syntax = "proto3"; package foo; message A { string s = 1; }`), "v2")
+	require.Equal(t, []string{"a.proto", "b.proto", "c.proto"}, generated)
+	require.Equal(t, expectedFiles, files)
+}
+
+func TestIncrementalRequiresPluginName(t *testing.T) {
+	t.Parallel()
+
+	request := testNewRequest(
+		t,
+		[]string{"a.proto"},
+		map[string][]byte{
+			"a.proto": []byte(`syntax = "proto3"; package foo; message A {}`),
+		},
+	)
+	_, err := NewIncremental(PluginEnv{}, request, "v1")
+	require.Error(t, err)
+}
@@ -0,0 +1,66 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// *** PRIVATE ***
+
+// insertionPointTargetChecker checks that the targets of CodeGeneratorResponse.Files with insertion points
+// are generated earlier in the response, or are declared as external.
+type insertionPointTargetChecker struct {
+	externalTargetNames map[string]struct{}
+	// Non-nil if issues should be warnings instead of errors.
+	warningFunc func(error)
+}
+
+func newInsertionPointTargetChecker(externalTargetNames []string, warningFunc func(error)) *insertionPointTargetChecker {
+	externalTargetNameMap := make(map[string]struct{}, len(externalTargetNames))
+	for _, externalTargetName := range externalTargetNames {
+		externalTargetNameMap[externalTargetName] = struct{}{}
+	}
+	return &insertionPointTargetChecker{
+		externalTargetNames: externalTargetNameMap,
+		warningFunc:         warningFunc,
+	}
+}
+
+// check checks the targets of all files with insertion points in the response.
+//
+// Must be called after validateAndNormalizeCodeGeneratorResponse, as names are expected to be normalized.
+func (c *insertionPointTargetChecker) check(response *pluginpb.CodeGeneratorResponse) error {
+	fileNames := make(map[string]struct{}, len(response.GetFile()))
+	for _, file := range response.GetFile() {
+		name := file.GetName()
+		insertionPoint := file.GetInsertionPoint()
+		if insertionPoint == "" {
+			fileNames[name] = struct{}{}
+			continue
+		}
+		if _, ok := fileNames[name]; ok {
+			continue
+		}
+		if _, ok := c.externalTargetNames[name]; ok {
+			continue
+		}
+		if c.warningFunc == nil {
+			return newInsertionPointTargetError(name, insertionPoint, false)
+		}
+		c.warningFunc(newInsertionPointTargetError(name, insertionPoint, true))
+	}
+	return nil
+}
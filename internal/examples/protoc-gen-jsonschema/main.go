@@ -0,0 +1,66 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main implements a plugin that outputs a JSON Schema document for every
+// top-level message in each file, using the protopluginutil/jsonschema package.
+//
+// Example: if a/b.proto in package foo had top-level messages C, D, the files
+// "foo.C.schema.json" and "foo.D.schema.json" would be outputted.
+//
+// This shows how a plugin can support Editions via the protoreflect API.
+package main
+
+import (
+	"context"
+
+	"github.com/bufbuild/protoplugin"
+	"github.com/bufbuild/protoplugin/protopluginutil/jsonschema"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const version = "0.0.1"
+
+func main() {
+	protoplugin.Main(protoplugin.HandlerFunc(handle), protoplugin.WithVersion(version))
+}
+
+func handle(
+	_ context.Context,
+	_ protoplugin.PluginEnv,
+	responseWriter protoplugin.ResponseWriter,
+	request protoplugin.Request,
+) error {
+	responseWriter.SetFeatureProto3Optional()
+	// JSON Schema generation resolves presence via the protoreflect API, so all
+	// editions supported by the protoreflect API are supported.
+	responseWriter.SetFeatureSupportsEditions(descriptorpb.Edition_EDITION_PROTO2, descriptorpb.Edition_EDITION_2023)
+
+	fileDescriptors, err := request.FileDescriptorsToGenerate()
+	if err != nil {
+		return err
+	}
+	for _, fileDescriptor := range fileDescriptors {
+		messages := fileDescriptor.Messages()
+		for i := 0; i < messages.Len(); i++ {
+			message := messages.Get(i)
+			data, err := jsonschema.NewSchema(message).Marshal()
+			if err != nil {
+				return err
+			}
+			responseWriter.AddFile(string(message.FullName())+".schema.json", string(data))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,183 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main implements a plugin that outputs Markdown API documentation for each
+// file, including the comments attached to messages, fields, enums, services, and methods.
+//
+// Example: if a/b.proto had a message C, the file "a/b.md" would be outputted, containing
+// a section for C with a table of its fields.
+//
+// This shows how to use SourceCodeInfo via the protoreflect API and protopluginutil.DescriptorComments.
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/bufbuild/protoplugin"
+	"github.com/bufbuild/protoplugin/protopluginutil"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const version = "0.0.1"
+
+func main() {
+	protoplugin.Main(protoplugin.HandlerFunc(handle), protoplugin.WithVersion(version))
+}
+
+func handle(
+	_ context.Context,
+	_ protoplugin.PluginEnv,
+	responseWriter protoplugin.ResponseWriter,
+	request protoplugin.Request,
+) error {
+	responseWriter.SetFeatureProto3Optional()
+	responseWriter.SetFeatureSupportsEditions(descriptorpb.Edition_EDITION_PROTO2, descriptorpb.Edition_EDITION_2023)
+
+	fileDescriptors, err := request.FileDescriptorsToGenerate()
+	if err != nil {
+		return err
+	}
+	for _, fileDescriptor := range fileDescriptors {
+		responseWriter.AddFile(
+			strings.TrimSuffix(fileDescriptor.Path(), ".proto")+".md",
+			renderFile(fileDescriptor),
+		)
+	}
+
+	return nil
+}
+
+func renderFile(fileDescriptor protoreflect.FileDescriptor) string {
+	builder := &strings.Builder{}
+	fmt.Fprintf(builder, "# %s\n", fileDescriptor.Path())
+	if packageName := fileDescriptor.Package(); packageName != "" {
+		fmt.Fprintf(builder, "\nPackage: `%s`\n", packageName)
+	}
+	renderMessages(builder, fileDescriptor.Messages())
+	renderEnums(builder, fileDescriptor.Enums())
+	services := fileDescriptor.Services()
+	for i := 0; i < services.Len(); i++ {
+		renderService(builder, services.Get(i))
+	}
+	return builder.String()
+}
+
+func renderMessages(builder *strings.Builder, messages protoreflect.MessageDescriptors) {
+	for i := 0; i < messages.Len(); i++ {
+		message := messages.Get(i)
+		if message.IsMapEntry() {
+			continue
+		}
+		renderHeader(builder, message)
+		fields := message.Fields()
+		if fields.Len() > 0 {
+			builder.WriteString("\n| Field | Type | Description |\n| --- | --- | --- |\n")
+			for j := 0; j < fields.Len(); j++ {
+				field := fields.Get(j)
+				fmt.Fprintf(
+					builder,
+					"| `%s` | `%s` | %s |\n",
+					field.Name(),
+					fieldTypeName(field),
+					toTableCell(protopluginutil.DescriptorComments(field)),
+				)
+			}
+		}
+		renderMessages(builder, message.Messages())
+		renderEnums(builder, message.Enums())
+	}
+}
+
+func renderEnums(builder *strings.Builder, enums protoreflect.EnumDescriptors) {
+	for i := 0; i < enums.Len(); i++ {
+		enum := enums.Get(i)
+		renderHeader(builder, enum)
+		values := enum.Values()
+		builder.WriteString("\n| Value | Number | Description |\n| --- | --- | --- |\n")
+		for j := 0; j < values.Len(); j++ {
+			value := values.Get(j)
+			fmt.Fprintf(
+				builder,
+				"| `%s` | %d | %s |\n",
+				value.Name(),
+				value.Number(),
+				toTableCell(protopluginutil.DescriptorComments(value)),
+			)
+		}
+	}
+}
+
+func renderService(builder *strings.Builder, service protoreflect.ServiceDescriptor) {
+	renderHeader(builder, service)
+	methods := service.Methods()
+	for i := 0; i < methods.Len(); i++ {
+		method := methods.Get(i)
+		fmt.Fprintf(
+			builder,
+			"\n### %s\n\n`%s(%s) returns (%s)`\n",
+			method.Name(),
+			method.Name(),
+			streamingPrefix(method.IsStreamingClient())+string(method.Input().FullName()),
+			streamingPrefix(method.IsStreamingServer())+string(method.Output().FullName()),
+		)
+		if comments := protopluginutil.DescriptorComments(method); comments != "" {
+			fmt.Fprintf(builder, "\n%s\n", comments)
+		}
+	}
+}
+
+func renderHeader(builder *strings.Builder, descriptor protoreflect.Descriptor) {
+	fmt.Fprintf(builder, "\n## %s\n", descriptor.FullName())
+	if comments := protopluginutil.DescriptorComments(descriptor); comments != "" {
+		fmt.Fprintf(builder, "\n%s\n", comments)
+	}
+}
+
+func fieldTypeName(field protoreflect.FieldDescriptor) string {
+	switch {
+	case field.IsMap():
+		return fmt.Sprintf("map<%s, %s>", singularFieldTypeName(field.MapKey()), singularFieldTypeName(field.MapValue()))
+	case field.IsList():
+		return "repeated " + singularFieldTypeName(field)
+	default:
+		return singularFieldTypeName(field)
+	}
+}
+
+func singularFieldTypeName(field protoreflect.FieldDescriptor) string {
+	switch field.Kind() {
+	case protoreflect.EnumKind:
+		return string(field.Enum().FullName())
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return string(field.Message().FullName())
+	default:
+		return field.Kind().String()
+	}
+}
+
+func streamingPrefix(isStreaming bool) string {
+	if isStreaming {
+		return "stream "
+	}
+	return ""
+}
+
+// toTableCell converts multi-line comments into a form that can be embedded within a Markdown table cell.
+func toTableCell(comments string) string {
+	comments = strings.ReplaceAll(comments, "|", `\|`)
+	return strings.ReplaceAll(comments, "\n", "<br>")
+}
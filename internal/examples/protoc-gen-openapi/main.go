@@ -0,0 +1,323 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main implements a plugin that outputs a single OpenAPI v3.1 document for all services
+// in all files to generate.
+//
+// Example: if a/b.proto had a service foo.C with a unary method D, the file "openapi.json" would
+// be outputted, containing the operation "foo.C.D".
+//
+// Each unary method becomes an operation. If a method has the google.api.http option, the HTTP method,
+// path, and body of the rule are used. Otherwise, the operation is a POST to "/<service>/<method>" with
+// the input message as the body, as used by Connect and gRPC-Web. Streaming methods cannot be described
+// by OpenAPI, and are skipped. Message schemas are produced with the protopluginutil/jsonschema package.
+//
+// This shows how a plugin can produce aggregated output across files, how custom options such as
+// google.api.http can be read without compiling the option definitions into the plugin with
+// protoplugin.WithExtensionTypeDiscovery, and how to emit JSON documents.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/bufbuild/protoplugin"
+	"github.com/bufbuild/protoplugin/protopluginutil"
+	"github.com/bufbuild/protoplugin/protopluginutil/jsonschema"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const (
+	version = "0.0.1"
+
+	openAPIVersion  = "3.1.0"
+	outputFileName  = "openapi.json"
+	httpRuleName    = "google.api.http"
+	schemaRefPrefix = "#/components/schemas/"
+)
+
+// httpRuleMethodFieldNames are the fields of google.api.HttpRule that specify the HTTP method and path.
+var httpRuleMethodFieldNames = []protoreflect.Name{"get", "put", "post", "delete", "patch"}
+
+// pathParameterRegexp matches path parameters within a google.api.http path template, for example
+// "{name}" or "{name=shelves/*}".
+var pathParameterRegexp = regexp.MustCompile(`\{([^}=]+)(=[^}]*)?\}`)
+
+func main() {
+	protoplugin.Main(
+		protoplugin.HandlerFunc(handle),
+		protoplugin.WithVersion(version),
+		// This results in google.api.http being populated on the MethodOptions, as long as
+		// google/api/annotations.proto is imported by the files to generate.
+		protoplugin.WithExtensionTypeDiscovery(),
+	)
+}
+
+func handle(
+	_ context.Context,
+	_ protoplugin.PluginEnv,
+	responseWriter protoplugin.ResponseWriter,
+	request protoplugin.Request,
+) error {
+	responseWriter.SetFeatureProto3Optional()
+	responseWriter.SetFeatureSupportsEditions(descriptorpb.Edition_EDITION_PROTO2, descriptorpb.Edition_EDITION_2023)
+
+	fileDescriptors, err := request.FileDescriptorsToGenerate()
+	if err != nil {
+		return err
+	}
+	builder := newDocumentBuilder()
+	for _, fileDescriptor := range fileDescriptors {
+		if err := protopluginutil.WalkFileDescriptor(
+			fileDescriptor,
+			protopluginutil.DescriptorVisitor{
+				// There is nothing to document within messages and enums themselves, only the
+				// messages that are referenced by methods are documented.
+				EnterMessage: func(protoreflect.MessageDescriptor) error {
+					return protopluginutil.ErrSkipChildren
+				},
+				Method: builder.addMethod,
+			},
+		); err != nil {
+			return err
+		}
+	}
+	if len(builder.paths) == 0 {
+		// Nothing to document.
+		return nil
+	}
+	data, err := json.MarshalIndent(builder.document(), "", "  ")
+	if err != nil {
+		return err
+	}
+	responseWriter.AddFile(outputFileName, string(data)+"\n")
+	return nil
+}
+
+type documentBuilder struct {
+	// Path to HTTP method to operation.
+	paths map[string]map[string]any
+	// Message full name to schema.
+	schemas map[string]any
+	// The full names of all services with at least one operation.
+	tags map[string]string
+}
+
+func newDocumentBuilder() *documentBuilder {
+	return &documentBuilder{
+		paths:   make(map[string]map[string]any),
+		schemas: make(map[string]any),
+		tags:    make(map[string]string),
+	}
+}
+
+func (b *documentBuilder) document() map[string]any {
+	tagNames := make([]string, 0, len(b.tags))
+	for tagName := range b.tags {
+		tagNames = append(tagNames, tagName)
+	}
+	sort.Strings(tagNames)
+	tags := make([]any, 0, len(tagNames))
+	for _, tagName := range tagNames {
+		tags = append(tags, withDescription(map[string]any{"name": tagName}, b.tags[tagName]))
+	}
+	return map[string]any{
+		"openapi": openAPIVersion,
+		"info": map[string]any{
+			"title":   strings.Join(tagNames, ", "),
+			"version": version,
+		},
+		"tags":  tags,
+		"paths": b.paths,
+		"components": map[string]any{
+			"schemas": b.schemas,
+		},
+	}
+}
+
+func (b *documentBuilder) addMethod(method protoreflect.MethodDescriptor) error {
+	if method.IsStreamingClient() || method.IsStreamingServer() {
+		return nil
+	}
+	service, ok := method.Parent().(protoreflect.ServiceDescriptor)
+	if !ok {
+		return fmt.Errorf("parent of method %q is not a service", method.FullName())
+	}
+	httpMethod, path, body, err := httpRule(method)
+	if err != nil {
+		return err
+	}
+	operation := withDescription(
+		map[string]any{
+			"operationId": string(method.FullName()),
+			"tags":        []string{string(service.FullName())},
+			"responses": map[string]any{
+				"200": map[string]any{
+					"description": "OK",
+					"content": map[string]any{
+						"application/json": map[string]any{
+							"schema": b.schemaRef(method.Output()),
+						},
+					},
+				},
+			},
+		},
+		protopluginutil.DescriptorComments(method),
+	)
+	var parameters []any
+	for _, match := range pathParameterRegexp.FindAllStringSubmatch(path, -1) {
+		parameters = append(
+			parameters,
+			map[string]any{
+				"name":     match[1],
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]any{"type": "string"},
+			},
+		)
+	}
+	if len(parameters) > 0 {
+		operation["parameters"] = parameters
+	}
+	if requestBodySchema, err := b.requestBodySchema(method.Input(), body); err != nil {
+		return err
+	} else if requestBodySchema != nil {
+		operation["requestBody"] = map[string]any{
+			"required": true,
+			"content": map[string]any{
+				"application/json": map[string]any{
+					"schema": requestBodySchema,
+				},
+			},
+		}
+	}
+	// OpenAPI paths use "{name}" for parameters, while google.api.http allows "{name=pattern}".
+	path = pathParameterRegexp.ReplaceAllString(path, "{$1}")
+	operations, ok := b.paths[path]
+	if !ok {
+		operations = make(map[string]any)
+		b.paths[path] = operations
+	}
+	if _, ok := operations[httpMethod]; ok {
+		return fmt.Errorf("method %q: duplicate operation %s %s", method.FullName(), strings.ToUpper(httpMethod), path)
+	}
+	operations[httpMethod] = operation
+	b.tags[string(service.FullName())] = protopluginutil.DescriptorComments(service)
+	return nil
+}
+
+// requestBodySchema returns the schema of the request body, or nil if there is no request body.
+//
+// The body is the body field of the google.api.http rule: "*" for the entire input message, the name of
+// a field of the input message, or empty for no request body.
+func (b *documentBuilder) requestBodySchema(input protoreflect.MessageDescriptor, body string) (map[string]any, error) {
+	switch body {
+	case "":
+		return nil, nil
+	case "*":
+		return b.schemaRef(input), nil
+	default:
+		field := input.Fields().ByName(protoreflect.Name(body))
+		if field == nil {
+			return nil, fmt.Errorf("body field %q not found on message %q", body, input.FullName())
+		}
+		if field.Message() == nil || field.IsList() || field.IsMap() {
+			// Keep the example simple: non-message body fields are described by the entire input message.
+			return b.schemaRef(input), nil
+		}
+		return b.schemaRef(field.Message()), nil
+	}
+}
+
+// schemaRef adds the schemas for the message and all messages it references, and returns a reference to
+// the schema for the message.
+func (b *documentBuilder) schemaRef(message protoreflect.MessageDescriptor) map[string]any {
+	schema := jsonschema.NewSchema(message)
+	if defs, ok := schema["$defs"].(map[string]any); ok {
+		for name, def := range defs {
+			b.schemas[name] = rewriteRefs(def)
+		}
+	}
+	return map[string]any{"$ref": schemaRefPrefix + string(message.FullName())}
+}
+
+// rewriteRefs rewrites all JSON Schema "$defs" references to OpenAPI component references.
+func rewriteRefs(value any) any {
+	switch value := value.(type) {
+	case map[string]any:
+		for key, child := range value {
+			if ref, ok := child.(string); key == "$ref" && ok {
+				value[key] = schemaRefPrefix + strings.TrimPrefix(ref, "#/$defs/")
+				continue
+			}
+			value[key] = rewriteRefs(child)
+		}
+		return value
+	case []any:
+		for i, child := range value {
+			value[i] = rewriteRefs(child)
+		}
+		return value
+	default:
+		return value
+	}
+}
+
+// httpRule returns the lowercase HTTP method, path, and body for the method.
+//
+// The google.api.http option is used if present. The option is read via the protoreflect API by name,
+// as it is populated with a dynamic message by protoplugin.WithExtensionTypeDiscovery.
+func httpRule(method protoreflect.MethodDescriptor) (string, string, string, error) {
+	var rule protoreflect.Message
+	method.Options().ProtoReflect().Range(
+		func(field protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+			if field.IsExtension() && field.FullName() == httpRuleName && field.Message() != nil {
+				rule = value.Message()
+				return false
+			}
+			return true
+		},
+	)
+	if rule == nil {
+		return "post", "/" + string(method.Parent().FullName()) + "/" + string(method.Name()), "*", nil
+	}
+	fields := rule.Descriptor().Fields()
+	body := rule.Get(fields.ByName("body")).String()
+	for _, methodFieldName := range httpRuleMethodFieldNames {
+		if field := fields.ByName(methodFieldName); field != nil && rule.Has(field) {
+			return string(methodFieldName), rule.Get(field).String(), body, nil
+		}
+	}
+	if field := fields.ByName("custom"); field != nil && rule.Has(field) {
+		custom := rule.Get(field).Message()
+		customFields := custom.Descriptor().Fields()
+		return strings.ToLower(custom.Get(customFields.ByName("kind")).String()),
+			custom.Get(customFields.ByName("path")).String(),
+			body,
+			nil
+	}
+	return "", "", "", fmt.Errorf("method %q: %s option does not specify a pattern", method.FullName(), httpRuleName)
+}
+
+func withDescription(value map[string]any, description string) map[string]any {
+	if description != "" {
+		value["description"] = description
+	}
+	return value
+}
@@ -21,40 +21,23 @@
 package main
 
 import (
-	"context"
 	"errors"
 
 	"github.com/bufbuild/protoplugin"
 	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/types/pluginpb"
 )
 
 const version = "0.0.1"
 
 func main() {
-	protoplugin.Main(protoplugin.HandlerFunc(handle), protoplugin.WithVersion(version))
+	protoplugin.Main(
+		protoplugin.NewProtogenHandler(handle, protogen.Options{}),
+		protoplugin.WithVersion(version),
+	)
 }
 
-func handle(
-	_ context.Context,
-	_ protoplugin.PluginEnv,
-	responseWriter protoplugin.ResponseWriter,
-	request protoplugin.Request,
-) error {
-	plugin, err := protogen.Options{}.New(request.CodeGeneratorRequest())
-	if err != nil {
-		return err
-	}
-	if err := handleProtogenPlugin(plugin); err != nil {
-		plugin.Error(err)
-	}
-	response := plugin.Response()
-	responseWriter.AddCodeGeneratorResponseFiles(response.GetFile()...)
-	responseWriter.AddError(response.GetError())
-	responseWriter.SetFeatureProto3Optional()
-	return nil
-}
-
-func handleProtogenPlugin(plugin *protogen.Plugin) error {
-	_ = plugin
+func handle(plugin *protogen.Plugin) error {
+	plugin.SupportedFeatures = uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL)
 	return errors.New("TODO")
 }
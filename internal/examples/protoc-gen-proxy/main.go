@@ -0,0 +1,116 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main implements a plugin that proxies to another plugin binary.
+//
+// The path to the plugin binary to proxy to is given by the PROTOC_GEN_PROXY_PLUGIN
+// environment variable. The CodeGeneratorRequest is passed to the plugin as-is, and the
+// files, error, and features from its CodeGeneratorResponse are added to the response.
+//
+// Plugins in the wild frequently produce CodeGeneratorResponses that are not properly formed, for
+// example with duplicate file names or unnormalized file names. This plugin uses lenient validation
+// to print warnings for these issues instead of failing, which is what lenient validation was
+// built for.
+//
+// Example: PROTOC_GEN_PROXY_PLUGIN=protoc-gen-go protoc --proxy_out=. a.proto
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/bufbuild/protoplugin"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+const (
+	version = "0.0.1"
+
+	pluginEnvKey = "PROTOC_GEN_PROXY_PLUGIN"
+)
+
+func main() {
+	protoplugin.Main(
+		protoplugin.HandlerFunc(handle),
+		protoplugin.WithVersion(version),
+		protoplugin.WithLenientValidation(handleLenientValidationError),
+	)
+}
+
+func handle(
+	ctx context.Context,
+	pluginEnv protoplugin.PluginEnv,
+	responseWriter protoplugin.ResponseWriter,
+	request protoplugin.Request,
+) error {
+	pluginPath, ok := pluginEnv.LookupEnv(pluginEnvKey)
+	if !ok || pluginPath == "" {
+		return fmt.Errorf("%s must be set to the path of the plugin to proxy to", pluginEnvKey)
+	}
+	codeGeneratorResponse, err := execPlugin(ctx, pluginEnv, pluginPath, request.CodeGeneratorRequest())
+	if err != nil {
+		return err
+	}
+	if codeGeneratorResponse.Error != nil {
+		responseWriter.AddError(codeGeneratorResponse.GetError())
+	}
+	responseWriter.SetSupportedFeatures(codeGeneratorResponse.GetSupportedFeatures())
+	if codeGeneratorResponse.GetSupportedFeatures()&uint64(pluginpb.CodeGeneratorResponse_FEATURE_SUPPORTS_EDITIONS) != 0 {
+		responseWriter.SetFeatureSupportsEditions(
+			descriptorpb.Edition(codeGeneratorResponse.GetMinimumEdition()),
+			descriptorpb.Edition(codeGeneratorResponse.GetMaximumEdition()),
+		)
+	}
+	// Files with insertion points, duplicate files, and unnormalized file names are all passed
+	// through, and are validated leniently when the CodeGeneratorResponse is created.
+	responseWriter.AddCodeGeneratorResponseFiles(codeGeneratorResponse.GetFile()...)
+	return nil
+}
+
+func execPlugin(
+	ctx context.Context,
+	pluginEnv protoplugin.PluginEnv,
+	pluginPath string,
+	codeGeneratorRequest *pluginpb.CodeGeneratorRequest,
+) (*pluginpb.CodeGeneratorResponse, error) {
+	data, err := proto.Marshal(codeGeneratorRequest)
+	if err != nil {
+		return nil, err
+	}
+	stdout := bytes.NewBuffer(nil)
+	cmd := exec.CommandContext(ctx, pluginPath)
+	cmd.Env = pluginEnv.Environ
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stdout = stdout
+	cmd.Stderr = pluginEnv.Stderr
+	if err := cmd.Run(); err != nil {
+		exitError := &exec.ExitError{}
+		if errors.As(err, &exitError) {
+			// Return the *exec.ExitError as-is so that Main exits with the same exit code as the plugin.
+			return nil, err
+		}
+		return nil, fmt.Errorf("could not run %s: %w", pluginPath, err)
+	}
+	return protoplugin.ReadCodeGeneratorResponse(stdout)
+}
+
+func handleLenientValidationError(err error) {
+	_, _ = fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+}
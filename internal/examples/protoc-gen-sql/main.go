@@ -0,0 +1,143 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main implements a plugin that outputs PostgreSQL CREATE TABLE statements for every
+// top-level message in each file, using the protopluginutil/schema package.
+//
+// Example: if a/b.proto in package foo had top-level messages C, D, the file "a/b.sql"
+// would be outputted, containing the tables "foo_c" and "foo_d".
+//
+// Each field is mapped to a column. Scalar fields, enums, and the well-known types with a natural
+// column type such as google.protobuf.Timestamp are mapped to native PostgreSQL types. Fields that
+// are nullable per the schema package are NULL, and all others are NOT NULL. Nested messages, repeated
+// fields, and map fields are stored as JSONB.
+//
+// This shows how DDL-style generators can be structured: the schema package decides how Protobuf
+// types map to columns, and the generator only decides how each schema.Kind maps to its target system.
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/bufbuild/protoplugin"
+	"github.com/bufbuild/protoplugin/protopluginutil/schema"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const version = "0.0.1"
+
+func main() {
+	protoplugin.Main(protoplugin.HandlerFunc(handle), protoplugin.WithVersion(version))
+}
+
+func handle(
+	_ context.Context,
+	_ protoplugin.PluginEnv,
+	responseWriter protoplugin.ResponseWriter,
+	request protoplugin.Request,
+) error {
+	responseWriter.SetFeatureProto3Optional()
+	// The schema package resolves presence via the protoreflect API, so all
+	// editions supported by the protoreflect API are supported.
+	responseWriter.SetFeatureSupportsEditions(descriptorpb.Edition_EDITION_PROTO2, descriptorpb.Edition_EDITION_2023)
+
+	fileDescriptors, err := request.FileDescriptorsToGenerate()
+	if err != nil {
+		return err
+	}
+	for _, fileDescriptor := range fileDescriptors {
+		messages := fileDescriptor.Messages()
+		if messages.Len() == 0 {
+			continue
+		}
+		var builder strings.Builder
+		_, _ = builder.WriteString("-- Code generated by protoc-gen-sql. DO NOT EDIT.\n")
+		_, _ = builder.WriteString("-- source: " + fileDescriptor.Path() + "\n")
+		for i := 0; i < messages.Len(); i++ {
+			_, _ = builder.WriteString("\n")
+			writeCreateTable(&builder, schema.NewRecord(messages.Get(i)))
+		}
+		responseWriter.AddFile(strings.TrimSuffix(fileDescriptor.Path(), path.Ext(fileDescriptor.Path()))+".sql", builder.String())
+	}
+	return nil
+}
+
+func writeCreateTable(builder *strings.Builder, record *schema.Record) {
+	writeComment(builder, "", record.Comment)
+	_, _ = fmt.Fprintf(builder, "CREATE TABLE %s (\n", quoteIdentifier(tableName(record.Descriptor)))
+	for i, field := range record.Fields {
+		writeComment(builder, "  ", field.Comment)
+		nullability := "NOT NULL"
+		if field.Nullable {
+			nullability = "NULL"
+		}
+		_, _ = fmt.Fprintf(builder, "  %s %s %s", quoteIdentifier(field.Name), columnType(field.Type), nullability)
+		if i < len(record.Fields)-1 {
+			_, _ = builder.WriteString(",")
+		}
+		_, _ = builder.WriteString("\n")
+	}
+	_, _ = builder.WriteString(");\n")
+}
+
+func writeComment(builder *strings.Builder, indent string, comment string) {
+	if comment == "" {
+		return
+	}
+	for _, line := range strings.Split(comment, "\n") {
+		_, _ = builder.WriteString(strings.TrimRight(indent+"-- "+line, " ") + "\n")
+	}
+}
+
+func columnType(fieldType *schema.Type) string {
+	switch fieldType.Kind {
+	case schema.KindBool:
+		return "BOOLEAN"
+	case schema.KindInt32:
+		return "INTEGER"
+	case schema.KindInt64, schema.KindUint32:
+		return "BIGINT"
+	case schema.KindUint64:
+		return "NUMERIC(20)"
+	case schema.KindFloat:
+		return "REAL"
+	case schema.KindDouble:
+		return "DOUBLE PRECISION"
+	case schema.KindString, schema.KindEnum:
+		// Enums are stored by value name, so that the values remain meaningful if the enum is renumbered.
+		return "TEXT"
+	case schema.KindBytes:
+		return "BYTEA"
+	case schema.KindTimestamp:
+		return "TIMESTAMPTZ"
+	case schema.KindDuration:
+		return "INTERVAL"
+	default:
+		// KindJSON, KindRecord, KindList, and KindMap.
+		return "JSONB"
+	}
+}
+
+// tableName returns the table name for the message, for example "foo_bar_baz" for foo.bar.Baz.
+func tableName(message protoreflect.MessageDescriptor) string {
+	return strings.ToLower(strings.ReplaceAll(string(message.FullName()), ".", "_"))
+}
+
+func quoteIdentifier(identifier string) string {
+	return `"` + strings.ReplaceAll(identifier, `"`, `""`) + `"`
+}
@@ -0,0 +1,249 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package main implements a plugin that outputs a single JSON file summarizing the files to generate.
+//
+// Example: if a/b.proto and c/d.proto were given, the file "stats.json" would be outputted, containing
+// the number of files, messages, fields by type, enums, services, and methods, the syntaxes and editions
+// used, and the custom options seen, along with per-file counts. The name of the file can be changed
+// with the "out" parameter, for example "--statsjson_opt=out=a/stats.json".
+//
+// This shows how to write plugins that produce a single aggregate output for the entire request
+// rather than one output per file, and can also be used as a debugging aid to inspect what a
+// compiler sends to plugins. Custom options are resolved with WithExtensionTypeDiscovery.
+package main
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/bufbuild/protoplugin"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const (
+	version = "0.0.1"
+
+	defaultOutputFileName = "stats.json"
+)
+
+func main() {
+	protoplugin.Main(
+		protoplugin.HandlerFunc(handle),
+		protoplugin.WithVersion(version),
+		protoplugin.WithExtensionTypeDiscovery(),
+		protoplugin.WithUnusedParameterWarnings(),
+	)
+}
+
+type stats struct {
+	Totals        counts         `json:"totals"`
+	FieldsByType  map[string]int `json:"fieldsByType"`
+	Syntaxes      map[string]int `json:"syntaxes"`
+	Editions      []string       `json:"editions"`
+	CustomOptions map[string]int `json:"customOptions"`
+	Files         []*fileStats   `json:"files"`
+}
+
+type fileStats struct {
+	Name    string `json:"name"`
+	Package string `json:"package,omitempty"`
+	Syntax  string `json:"syntax"`
+	Edition string `json:"edition,omitempty"`
+	counts
+}
+
+type counts struct {
+	Files      int `json:"files,omitempty"`
+	Messages   int `json:"messages"`
+	Fields     int `json:"fields"`
+	Enums      int `json:"enums"`
+	EnumValues int `json:"enumValues"`
+	Services   int `json:"services"`
+	Methods    int `json:"methods"`
+	Extensions int `json:"extensions"`
+}
+
+func handle(
+	_ context.Context,
+	_ protoplugin.PluginEnv,
+	responseWriter protoplugin.ResponseWriter,
+	request protoplugin.Request,
+) error {
+	responseWriter.SetFeatureProto3Optional()
+	responseWriter.SetFeatureSupportsEditions(descriptorpb.Edition_EDITION_PROTO2, descriptorpb.Edition_EDITION_2023)
+
+	parameters, err := request.Parameters()
+	if err != nil {
+		return err
+	}
+	outputFileName := defaultOutputFileName
+	if value, ok := parameters.Get("out"); ok {
+		outputFileName = value
+	}
+	files, err := request.FilesToGenerate()
+	if err != nil {
+		return err
+	}
+
+	stats := &stats{
+		FieldsByType:  make(map[string]int),
+		Syntaxes:      make(map[string]int),
+		Editions:      []string{},
+		CustomOptions: make(map[string]int),
+		Files:         make([]*fileStats, 0, len(files)),
+	}
+	for _, edition := range request.Editions() {
+		stats.Editions = append(stats.Editions, edition.String())
+	}
+	for _, file := range files {
+		fileStats := &fileStats{
+			Name:    file.FileDescriptor.Path(),
+			Package: string(file.FileDescriptor.Package()),
+			Syntax:  file.FileDescriptor.Syntax().String(),
+		}
+		if file.FileDescriptor.Syntax() == protoreflect.Editions {
+			fileStats.Edition = file.FileDescriptorProto.GetEdition().String()
+		}
+		collector := &collector{
+			stats:  stats,
+			counts: &fileStats.counts,
+		}
+		collector.collectFile(file.FileDescriptor)
+		stats.Syntaxes[fileStats.Syntax]++
+		stats.Files = append(stats.Files, fileStats)
+		stats.Totals.add(fileStats.counts)
+		stats.Totals.Files++
+	}
+
+	data, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return err
+	}
+	responseWriter.AddFile(outputFileName, string(data)+"\n")
+	return nil
+}
+
+func (c *counts) add(other counts) {
+	c.Messages += other.Messages
+	c.Fields += other.Fields
+	c.Enums += other.Enums
+	c.EnumValues += other.EnumValues
+	c.Services += other.Services
+	c.Methods += other.Methods
+	c.Extensions += other.Extensions
+}
+
+// collector collects the stats for a single file.
+type collector struct {
+	stats  *stats
+	counts *counts
+}
+
+func (c *collector) collectFile(fileDescriptor protoreflect.FileDescriptor) {
+	c.collectOptions(fileDescriptor)
+	c.collectMessages(fileDescriptor.Messages())
+	c.collectEnums(fileDescriptor.Enums())
+	c.collectExtensions(fileDescriptor.Extensions())
+	services := fileDescriptor.Services()
+	for i := 0; i < services.Len(); i++ {
+		service := services.Get(i)
+		c.counts.Services++
+		c.collectOptions(service)
+		methods := service.Methods()
+		for j := 0; j < methods.Len(); j++ {
+			c.counts.Methods++
+			c.collectOptions(methods.Get(j))
+		}
+	}
+}
+
+func (c *collector) collectMessages(messages protoreflect.MessageDescriptors) {
+	for i := 0; i < messages.Len(); i++ {
+		message := messages.Get(i)
+		// Map entries are synthesized by the compiler, and are counted as map fields instead.
+		if message.IsMapEntry() {
+			continue
+		}
+		c.counts.Messages++
+		c.collectOptions(message)
+		fields := message.Fields()
+		for j := 0; j < fields.Len(); j++ {
+			c.collectField(fields.Get(j))
+		}
+		oneofs := message.Oneofs()
+		for j := 0; j < oneofs.Len(); j++ {
+			c.collectOptions(oneofs.Get(j))
+		}
+		c.collectMessages(message.Messages())
+		c.collectEnums(message.Enums())
+		c.collectExtensions(message.Extensions())
+	}
+}
+
+func (c *collector) collectEnums(enums protoreflect.EnumDescriptors) {
+	for i := 0; i < enums.Len(); i++ {
+		enum := enums.Get(i)
+		c.counts.Enums++
+		c.collectOptions(enum)
+		values := enum.Values()
+		for j := 0; j < values.Len(); j++ {
+			c.counts.EnumValues++
+			c.collectOptions(values.Get(j))
+		}
+	}
+}
+
+func (c *collector) collectExtensions(extensions protoreflect.ExtensionDescriptors) {
+	for i := 0; i < extensions.Len(); i++ {
+		c.counts.Extensions++
+		c.collectField(extensions.Get(i))
+	}
+}
+
+func (c *collector) collectField(field protoreflect.FieldDescriptor) {
+	// Extensions are counted separately, and are not included in the fields by type.
+	if !field.IsExtension() {
+		c.counts.Fields++
+		c.stats.FieldsByType[fieldTypeName(field)]++
+	}
+	c.collectOptions(field)
+}
+
+// collectOptions records the custom options set on the descriptor.
+//
+// Custom options are extensions of the options messages. Extensions that could not be resolved
+// remain as unknown fields, and are not recorded.
+func (c *collector) collectOptions(descriptor protoreflect.Descriptor) {
+	options := descriptor.Options()
+	if options == nil {
+		return
+	}
+	options.ProtoReflect().Range(
+		func(field protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+			if field.IsExtension() {
+				c.stats.CustomOptions[string(field.FullName())]++
+			}
+			return true
+		},
+	)
+}
+
+func fieldTypeName(field protoreflect.FieldDescriptor) string {
+	if field.IsMap() {
+		return "map"
+	}
+	return field.Kind().String()
+}
@@ -0,0 +1,90 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package protocompiletest provides helpers for compiling .proto files in tests.
+package protocompiletest
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/fs"
+	"testing"
+
+	"github.com/bufbuild/protocompile"
+	"github.com/bufbuild/protocompile/protoutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// CompileFile compiles the single .proto file at the given path with the given content, with source
+// code info, failing the test on error.
+//
+// The file may only import well-known types.
+func CompileFile(t testing.TB, path string, data []byte) protoreflect.FileDescriptor {
+	t.Helper()
+	compiledFiles := compile(t, map[string][]byte{path: data}, path)
+	require.Len(t, compiledFiles, 1)
+	return compiledFiles[0]
+}
+
+// CompileFiles compiles all of the .proto files in the map from path to content, with source code
+// info, failing the test on error.
+//
+// The files may import each other and well-known types.
+func CompileFiles(t testing.TB, pathToData map[string][]byte) *protoregistry.Files {
+	t.Helper()
+	paths := make([]string, 0, len(pathToData))
+	for path := range pathToData {
+		paths = append(paths, path)
+	}
+	compiledFiles := compile(t, pathToData, paths...)
+	fileDescriptorProtos := make([]*descriptorpb.FileDescriptorProto, len(compiledFiles))
+	for i, compiledFile := range compiledFiles {
+		fileDescriptorProtos[i] = protoutil.ProtoFromFileDescriptor(compiledFile)
+	}
+	files, err := protodesc.NewFiles(&descriptorpb.FileDescriptorSet{File: fileDescriptorProtos})
+	require.NoError(t, err)
+	return files
+}
+
+// *** PRIVATE ***
+
+func compile(t testing.TB, pathToData map[string][]byte, paths ...string) []protoreflect.FileDescriptor {
+	t.Helper()
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(
+			&protocompile.SourceResolver{
+				Accessor: func(path string) (io.ReadCloser, error) {
+					data, ok := pathToData[path]
+					if !ok {
+						return nil, &fs.PathError{Op: "read", Path: path, Err: fs.ErrNotExist}
+					}
+					return io.NopCloser(bytes.NewReader(data)), nil
+				},
+			},
+		),
+		SourceInfoMode: protocompile.SourceInfoStandard,
+	}
+	compiledFiles, err := compiler.Compile(context.Background(), paths...)
+	require.NoError(t, err)
+	fileDescriptors := make([]protoreflect.FileDescriptor, len(compiledFiles))
+	for i, compiledFile := range compiledFiles {
+		fileDescriptors[i] = compiledFile
+	}
+	return fileDescriptors
+}
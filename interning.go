@@ -0,0 +1,132 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// WithDescriptorInterning returns a new RunOption that says to share equal sub-messages and strings between
+// the proto_file and source_file_descriptors fields of the CodeGeneratorRequest.
+//
+// The FileDescriptorProtos in source_file_descriptors are near-identical to the corresponding FileDescriptorProtos
+// in proto_file, differing only in source-retention options. When unmarshaled, each copy has its own memory.
+// With this option, every sub-message and string within source_file_descriptors that is equal to its counterpart
+// in proto_file is replaced with the counterpart, which roughly halves the resident memory of the descriptors
+// for large requests. This is most useful for plugins that access both the FileDescriptorProtos with and without
+// source-retention options.
+//
+// After interning, the FileDescriptorProtos in proto_file and source_file_descriptors share memory. Neither should
+// be modified by the Handler, as a modification to one may be visible in the other. If Invoke or ExecuteHandler
+// is used, the CodeGeneratorRequest given is modified in place.
+//
+// This option can be passed to Main or Run.
+//
+// The default is to not intern descriptors.
+func WithDescriptorInterning() RunOption {
+	return optsFunc(func(opts *opts) {
+		opts.descriptorInterning = true
+	})
+}
+
+// *** PRIVATE ***
+
+// internSourceFileDescriptors replaces sub-messages and strings within the source_file_descriptors of
+// the CodeGeneratorRequest with the equal sub-messages and strings within proto_file.
+//
+// Files are matched by name. Files in source_file_descriptors without a match in proto_file are left unchanged.
+func internSourceFileDescriptors(codeGeneratorRequest *pluginpb.CodeGeneratorRequest) {
+	sourceFileDescriptors := codeGeneratorRequest.GetSourceFileDescriptors()
+	if len(sourceFileDescriptors) == 0 {
+		return
+	}
+	nameToProtoFile := make(map[string]*descriptorpb.FileDescriptorProto, len(codeGeneratorRequest.GetProtoFile()))
+	for _, protoFile := range codeGeneratorRequest.GetProtoFile() {
+		nameToProtoFile[protoFile.GetName()] = protoFile
+	}
+	for i, sourceFileDescriptor := range sourceFileDescriptors {
+		protoFile, ok := nameToProtoFile[sourceFileDescriptor.GetName()]
+		if !ok {
+			continue
+		}
+		if proto.Equal(protoFile, sourceFileDescriptor) {
+			sourceFileDescriptors[i] = protoFile
+			continue
+		}
+		internMessage(protoFile.ProtoReflect(), sourceFileDescriptor.ProtoReflect())
+	}
+}
+
+// internMessage replaces the fields of source with the equal fields of target.
+//
+// Sub-messages that are equal are shared entirely. Sub-messages that are not equal are interned recursively.
+// Repeated fields are only interned if the lists have the same length, as elements are matched by index.
+func internMessage(target protoreflect.Message, source protoreflect.Message) {
+	source.Range(
+		func(fieldDescriptor protoreflect.FieldDescriptor, sourceValue protoreflect.Value) bool {
+			if fieldDescriptor.IsMap() || !target.Has(fieldDescriptor) {
+				return true
+			}
+			targetValue := target.Get(fieldDescriptor)
+			if fieldDescriptor.IsList() {
+				sourceList := sourceValue.List()
+				targetList := targetValue.List()
+				if sourceList.Len() != targetList.Len() {
+					return true
+				}
+				for i := 0; i < sourceList.Len(); i++ {
+					if internedValue, ok := internValue(fieldDescriptor, targetList.Get(i), sourceList.Get(i)); ok {
+						sourceList.Set(i, internedValue)
+					}
+				}
+				return true
+			}
+			if internedValue, ok := internValue(fieldDescriptor, targetValue, sourceValue); ok {
+				source.Set(fieldDescriptor, internedValue)
+			}
+			return true
+		},
+	)
+}
+
+// internValue returns the target value and true if the source value should be replaced with it.
+//
+// Message values that are not equal are interned recursively, and false is returned.
+func internValue(
+	fieldDescriptor protoreflect.FieldDescriptor,
+	targetValue protoreflect.Value,
+	sourceValue protoreflect.Value,
+) (protoreflect.Value, bool) {
+	switch fieldDescriptor.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		targetMessage := targetValue.Message()
+		sourceMessage := sourceValue.Message()
+		if proto.Equal(targetMessage.Interface(), sourceMessage.Interface()) {
+			return targetValue, true
+		}
+		internMessage(targetMessage, sourceMessage)
+		return protoreflect.Value{}, false
+	case protoreflect.StringKind:
+		if targetValue.String() == sourceValue.String() {
+			return targetValue, true
+		}
+		return protoreflect.Value{}, false
+	default:
+		return protoreflect.Value{}, false
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestInternSourceFileDescriptors(t *testing.T) {
+	t.Parallel()
+
+	codeGeneratorRequest := newTestInterningCodeGeneratorRequest()
+	expected := proto.Clone(codeGeneratorRequest)
+	internSourceFileDescriptors(codeGeneratorRequest)
+	require.Empty(t, cmp.Diff(expected, codeGeneratorRequest, protocmp.Transform()))
+
+	protoFiles := codeGeneratorRequest.GetProtoFile()
+	sourceFileDescriptors := codeGeneratorRequest.GetSourceFileDescriptors()
+	// a.proto differs in the options of one field, so only the equal sub-messages are shared.
+	require.NotSame(t, protoFiles[0], sourceFileDescriptors[0])
+	require.NotSame(t, protoFiles[0].GetMessageType()[0], sourceFileDescriptors[0].GetMessageType()[0])
+	require.Same(t, protoFiles[0].GetMessageType()[0].GetField()[0], sourceFileDescriptors[0].GetMessageType()[0].GetField()[0])
+	require.NotSame(t, protoFiles[0].GetMessageType()[0].GetField()[1], sourceFileDescriptors[0].GetMessageType()[0].GetField()[1])
+	require.Same(t, protoFiles[0].GetMessageType()[1], sourceFileDescriptors[0].GetMessageType()[1])
+	// b.proto is equal, so the entire file is shared.
+	require.Same(t, protoFiles[1], sourceFileDescriptors[1])
+}
+
+func TestWithDescriptorInterning(t *testing.T) {
+	t.Parallel()
+
+	codeGeneratorRequest := newTestInterningCodeGeneratorRequest()
+	expected := proto.Clone(codeGeneratorRequest).(*pluginpb.CodeGeneratorRequest)
+	handler := HandlerFunc(
+		func(_ context.Context, _ PluginEnv, _ ResponseWriter, request Request) error {
+			require.Empty(
+				t,
+				cmp.Diff(
+					expected.GetProtoFile(),
+					request.FileDescriptorProtosToGenerate(),
+					protocmp.Transform(),
+				),
+			)
+			request, err := request.WithSourceRetentionOptions()
+			require.NoError(t, err)
+			require.Empty(
+				t,
+				cmp.Diff(
+					expected.GetSourceFileDescriptors(),
+					request.FileDescriptorProtosToGenerate(),
+					protocmp.Transform(),
+				),
+			)
+			return nil
+		},
+	)
+	_, err := Invoke(context.Background(), handler, codeGeneratorRequest, WithDescriptorInterning())
+	require.NoError(t, err)
+	require.Same(t, codeGeneratorRequest.GetProtoFile()[1], codeGeneratorRequest.GetSourceFileDescriptors()[1])
+}
+
+func newTestInterningCodeGeneratorRequest() *pluginpb.CodeGeneratorRequest {
+	newFileDescriptorProtos := func(sourceRetention bool) []*descriptorpb.FileDescriptorProto {
+		var fieldOptions *descriptorpb.FieldOptions
+		if sourceRetention {
+			fieldOptions = &descriptorpb.FieldOptions{
+				Deprecated: proto.Bool(true),
+			}
+		}
+		return []*descriptorpb.FileDescriptorProto{
+			{
+				Name:    proto.String("a.proto"),
+				Package: proto.String("a"),
+				Syntax:  proto.String("proto3"),
+				MessageType: []*descriptorpb.DescriptorProto{
+					{
+						Name: proto.String("Foo"),
+						Field: []*descriptorpb.FieldDescriptorProto{
+							{
+								Name:     proto.String("one"),
+								JsonName: proto.String("one"),
+								Number:   proto.Int32(1),
+								Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+								Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+							},
+							{
+								Name:     proto.String("two"),
+								JsonName: proto.String("two"),
+								Number:   proto.Int32(2),
+								Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+								Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+								Options:  fieldOptions,
+							},
+						},
+					},
+					{
+						Name: proto.String("Bar"),
+					},
+				},
+			},
+			{
+				Name:    proto.String("b.proto"),
+				Package: proto.String("b"),
+				Syntax:  proto.String("proto3"),
+			},
+		}
+	}
+	return &pluginpb.CodeGeneratorRequest{
+		FileToGenerate:        []string{"a.proto", "b.proto"},
+		ProtoFile:             newFileDescriptorProtos(false),
+		SourceFileDescriptors: newFileDescriptorProtos(true),
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// MarshalRequest marshals the CodeGeneratorRequest with the same wire behavior that consumers of plugins,
+// such as protoc and buf, use.
+//
+// MarshalRequest, UnmarshalRequest, MarshalResponse, and UnmarshalResponse encapsulate the exact wire behavior
+// that Run uses, and are intended for alternate transports such as sockets, worker pools, or WASM hosts, so that
+// these transports stay byte-compatible with the stdio-based protocol.
+//
+// Options that affect marshaling, such as WithDeterministicMarshal, are applied. All other options have no effect.
+func MarshalRequest(codeGeneratorRequest *pluginpb.CodeGeneratorRequest, options ...RunOption) ([]byte, error) {
+	opts := newOpts()
+	for _, option := range options {
+		option.applyRunOption(opts)
+	}
+	return proto.MarshalOptions{Deterministic: opts.deterministicMarshal}.Marshal(codeGeneratorRequest)
+}
+
+// UnmarshalRequest unmarshals a CodeGeneratorRequest in the same manner as Run.
+//
+// Options that affect reading and unmarshaling, such as WithMaxRequestBytes, WithUnmarshalOptions, and
+// WithExtensionTypeResolver, are applied. All other options have no effect. See MarshalRequest for more details.
+func UnmarshalRequest(data []byte, options ...RunOption) (*pluginpb.CodeGeneratorRequest, error) {
+	opts := newOpts()
+	for _, option := range options {
+		option.applyRunOption(opts)
+	}
+	if opts.maxRequestBytes > 0 && int64(len(data)) > opts.maxRequestBytes {
+		return nil, newRequestTooLargeError(opts.maxRequestBytes)
+	}
+	return unmarshalRequest(data, opts)
+}
+
+// MarshalResponse marshals the CodeGeneratorResponse in the same manner as Run.
+//
+// Options that affect marshaling, such as WithDeterministicMarshal, are applied. WithResponseCompression
+// has no effect, as whether to compress depends on the environment of the consumer. All other options have
+// no effect. See MarshalRequest for more details.
+func MarshalResponse(codeGeneratorResponse *pluginpb.CodeGeneratorResponse, options ...RunOption) ([]byte, error) {
+	opts := newOpts()
+	for _, option := range options {
+		option.applyRunOption(opts)
+	}
+	return marshalResponse(codeGeneratorResponse, opts)
+}
+
+// UnmarshalResponse unmarshals a CodeGeneratorResponse written by Run.
+//
+// The data may be compressed or uncompressed. If the data was compressed by a plugin that was given
+// WithResponseCompression, it is transparently decompressed. See MarshalRequest for more details.
+func UnmarshalResponse(data []byte) (*pluginpb.CodeGeneratorResponse, error) {
+	if bytes.HasPrefix(data, gzipMagic) {
+		gzipReader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		data, err = io.ReadAll(gzipReader)
+		if err != nil {
+			return nil, err
+		}
+		if err := gzipReader.Close(); err != nil {
+			return nil, err
+		}
+	}
+	codeGeneratorResponse := &pluginpb.CodeGeneratorResponse{}
+	if err := proto.Unmarshal(data, codeGeneratorResponse); err != nil {
+		return nil, err
+	}
+	return codeGeneratorResponse, nil
+}
+
+// *** PRIVATE ***
+
+// unmarshalRequest unmarshals a CodeGeneratorRequest.
+func unmarshalRequest(data []byte, opts *opts) (*pluginpb.CodeGeneratorRequest, error) {
+	codeGeneratorRequest := &pluginpb.CodeGeneratorRequest{}
+	unmarshalOptions := opts.unmarshalOptions
+	if unmarshalOptions.Resolver == nil {
+		unmarshalOptions.Resolver = opts.extensionTypeResolver
+	}
+	if err := unmarshalOptions.Unmarshal(data, codeGeneratorRequest); err != nil {
+		return nil, err
+	}
+	return codeGeneratorRequest, nil
+}
+
+// marshalResponse marshals the CodeGeneratorResponse.
+func marshalResponse(codeGeneratorResponse *pluginpb.CodeGeneratorResponse, opts *opts) ([]byte, error) {
+	return proto.MarshalOptions{Deterministic: opts.deterministicMarshal}.Marshal(codeGeneratorResponse)
+}
@@ -0,0 +1,92 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestMarshalRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	fileDescriptorProtos, err := compile(ctx, map[string][]byte{
+		"a.proto": []byte(`syntax = "proto3"; package foo; message A {}`),
+	})
+	require.NoError(t, err)
+	codeGeneratorRequest := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{"a.proto"},
+		ProtoFile:      fileDescriptorProtos,
+	}
+	handler := HandlerFunc(func(_ context.Context, _ PluginEnv, responseWriter ResponseWriter, _ Request) error {
+		responseWriter.AddFile("a.txt", "a")
+		return nil
+	})
+	runOptions := []RunOption{WithDeterministicMarshal()}
+
+	requestData, err := MarshalRequest(codeGeneratorRequest, runOptions...)
+	require.NoError(t, err)
+	unmarshaledCodeGeneratorRequest, err := UnmarshalRequest(requestData, runOptions...)
+	require.NoError(t, err)
+	require.Empty(t, cmp.Diff(codeGeneratorRequest, unmarshaledCodeGeneratorRequest, protocmp.Transform()))
+
+	stdout := bytes.NewBuffer(nil)
+	require.NoError(
+		t,
+		Run(
+			ctx,
+			Env{
+				Stdin:  bytes.NewReader(requestData),
+				Stdout: stdout,
+				Stderr: io.Discard,
+			},
+			handler,
+			runOptions...,
+		),
+	)
+	codeGeneratorResponse, err := Invoke(ctx, handler, unmarshaledCodeGeneratorRequest, runOptions...)
+	require.NoError(t, err)
+	responseData, err := MarshalResponse(codeGeneratorResponse, runOptions...)
+	require.NoError(t, err)
+	require.Equal(t, stdout.Bytes(), responseData)
+
+	unmarshaledCodeGeneratorResponse, err := UnmarshalResponse(responseData)
+	require.NoError(t, err)
+	require.True(t, proto.Equal(codeGeneratorResponse, unmarshaledCodeGeneratorResponse))
+
+	compressedResponseData, err := maybeCompressResponseData(
+		responseData,
+		[]string{ResponseCompressionEnvKey + "=" + ResponseCompressionGzip},
+		true,
+	)
+	require.NoError(t, err)
+	require.NotEqual(t, responseData, compressedResponseData)
+	unmarshaledCodeGeneratorResponse, err = UnmarshalResponse(compressedResponseData)
+	require.NoError(t, err)
+	require.True(t, proto.Equal(codeGeneratorResponse, unmarshaledCodeGeneratorResponse))
+
+	_, err = UnmarshalRequest([]byte{0xff})
+	require.Error(t, err)
+}
@@ -0,0 +1,363 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+const (
+	// MetricFilesGenerated is the name of the counter of files in the CodeGeneratorResponse.
+	MetricFilesGenerated = "protoplugin_files_generated_total"
+	// MetricBytesGenerated is the name of the counter of bytes of file content in the CodeGeneratorResponse.
+	MetricBytesGenerated = "protoplugin_generated_bytes_total"
+	// MetricHandlerDurationSeconds is the name of the histogram of the duration of Handler invocations in seconds.
+	MetricHandlerDurationSeconds = "protoplugin_handler_duration_seconds"
+)
+
+// Metrics records counters and histograms for a single CodeGeneratorRequest.
+//
+// Metrics are obtained with PluginEnv.Metrics, and are exported by the MetricsExporters given with
+// WithMetricsExporter after the Handler returns. In addition to the metrics recorded by the Handler,
+// the MetricFilesGenerated, MetricBytesGenerated, and MetricHandlerDurationSeconds metrics are recorded.
+//
+// All methods are safe for concurrent use by multiple goroutines, and are no-ops on a nil *Metrics.
+type Metrics struct {
+	lock       sync.Mutex
+	counters   map[string]float64
+	histograms map[string]*MetricsHistogram
+}
+
+// Add adds the delta to the counter with the name.
+//
+// Names should consist of ASCII letters, digits, and underscores, and should be prefixed with the
+// name of the plugin, for example "protoc_gen_foo_messages_total".
+func (m *Metrics) Add(name string, delta float64) {
+	if m == nil {
+		return
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.counters[name] += delta
+}
+
+// Observe records the value in the histogram with the name.
+//
+// See Add for the conventions for names.
+func (m *Metrics) Observe(name string, value float64) {
+	if m == nil {
+		return
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	histogram, ok := m.histograms[name]
+	if !ok {
+		histogram = &MetricsHistogram{
+			Min: math.Inf(1),
+			Max: math.Inf(-1),
+		}
+		m.histograms[name] = histogram
+	}
+	histogram.Count++
+	histogram.Sum += value
+	if value < histogram.Min {
+		histogram.Min = value
+	}
+	if value > histogram.Max {
+		histogram.Max = value
+	}
+}
+
+// Snapshot returns a copy of the current values of the metrics.
+//
+// Returns nil if the *Metrics is nil.
+func (m *Metrics) Snapshot() *MetricsSnapshot {
+	if m == nil {
+		return nil
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	snapshot := &MetricsSnapshot{
+		Counters:   make(map[string]float64, len(m.counters)),
+		Histograms: make(map[string]MetricsHistogram, len(m.histograms)),
+	}
+	for name, value := range m.counters {
+		snapshot.Counters[name] = value
+	}
+	for name, histogram := range m.histograms {
+		snapshot.Histograms[name] = *histogram
+	}
+	return snapshot
+}
+
+// MetricsSnapshot is a copy of the values of Metrics.
+type MetricsSnapshot struct {
+	// Counters is a map from counter name to value.
+	Counters map[string]float64 `json:"counters"`
+	// Histograms is a map from histogram name to summary.
+	Histograms map[string]MetricsHistogram `json:"histograms"`
+}
+
+// MetricsHistogram summarizes the values observed for a histogram.
+type MetricsHistogram struct {
+	// Count is the number of values observed.
+	Count uint64 `json:"count"`
+	// Sum is the sum of the values observed.
+	Sum float64 `json:"sum"`
+	// Min is the smallest value observed.
+	Min float64 `json:"min"`
+	// Max is the largest value observed.
+	Max float64 `json:"max"`
+}
+
+// MetricsExporter exports Metrics.
+//
+// See WithMetricsExporter for more details.
+type MetricsExporter interface {
+	// Export exports the MetricsSnapshot for a single CodeGeneratorRequest.
+	//
+	// Export is called once per CodeGeneratorRequest, and may therefore be called multiple times within a single
+	// process, for example in batch mode.
+	Export(ctx context.Context, pluginEnv PluginEnv, snapshot *MetricsSnapshot) error
+}
+
+// MetricsExporterFunc is a function that implements MetricsExporter.
+type MetricsExporterFunc func(context.Context, PluginEnv, *MetricsSnapshot) error
+
+// Export implements MetricsExporter.
+func (m MetricsExporterFunc) Export(ctx context.Context, pluginEnv PluginEnv, snapshot *MetricsSnapshot) error {
+	return m(ctx, pluginEnv, snapshot)
+}
+
+// NewPrometheusTextfileMetricsExporter returns a new MetricsExporter that writes the metrics to the file
+// in the Prometheus text exposition format, for consumption by the textfile collector of the Prometheus
+// node exporter.
+//
+// The file is written atomically, and is overwritten on every export. Metric names are sanitized to be
+// valid Prometheus metric names. If PluginEnv.Name is non-empty, every metric has a "plugin" label with
+// the name. Histograms are written as summaries without quantiles.
+func NewPrometheusTextfileMetricsExporter(filePath string) MetricsExporter {
+	return MetricsExporterFunc(
+		func(_ context.Context, pluginEnv PluginEnv, snapshot *MetricsSnapshot) error {
+			return writeFileAtomic(filePath, marshalPrometheusText(pluginEnv.Name(), snapshot))
+		},
+	)
+}
+
+// NewJSONMetricsExporter returns a new MetricsExporter that writes the metrics as a single line of
+// JSON to PluginEnv.Stderr.
+//
+// The JSON object has a "plugin" field with PluginEnv.Name, and "counters" and "histograms" fields with
+// the values of the MetricsSnapshot. Errors writing to stderr are ignored.
+func NewJSONMetricsExporter() MetricsExporter {
+	return MetricsExporterFunc(
+		func(_ context.Context, pluginEnv PluginEnv, snapshot *MetricsSnapshot) error {
+			if pluginEnv.Stderr == nil {
+				return nil
+			}
+			data, err := json.Marshal(
+				&jsonMetrics{
+					Plugin:          pluginEnv.Name(),
+					MetricsSnapshot: snapshot,
+				},
+			)
+			if err != nil {
+				return err
+			}
+			_, _ = pluginEnv.Stderr.Write(append(data, '\n'))
+			return nil
+		},
+	)
+}
+
+// WithMetricsExporter returns a new RunOption that says to export the Metrics of each CodeGeneratorRequest
+// with the MetricsExporter.
+//
+// Metrics are exported after the CodeGeneratorResponse is produced, including if the CodeGeneratorResponse
+// contains an error. If the Handler returns an error, Metrics are not exported. If the MetricsExporter returns
+// an error, the error is returned from Main, Run, Invoke, or ExecuteHandler.
+//
+// This option can be specified multiple times, in which case the MetricsExporters are called in the order given.
+//
+// This option can be passed to Main or Run.
+//
+// The default is to not export Metrics.
+func WithMetricsExporter(exporter MetricsExporter) RunOption {
+	return optsFunc(func(opts *opts) {
+		opts.metricsExporters = append(opts.metricsExporters, exporter)
+	})
+}
+
+// Metrics returns the Metrics for the current CodeGeneratorRequest.
+//
+// If the PluginEnv was not provided by this package, nil is returned, on which all methods are no-ops.
+func (p PluginEnv) Metrics() *Metrics {
+	return p.metrics
+}
+
+// *** PRIVATE ***
+
+type jsonMetrics struct {
+	Plugin string `json:"plugin,omitempty"`
+	*MetricsSnapshot
+}
+
+func newMetrics() *Metrics {
+	return &Metrics{
+		counters:   make(map[string]float64),
+		histograms: make(map[string]*MetricsHistogram),
+	}
+}
+
+// handleWithMetrics invokes the Handler, recording MetricHandlerDurationSeconds.
+func handleWithMetrics(
+	ctx context.Context,
+	handler Handler,
+	pluginEnv PluginEnv,
+	responseWriter ResponseWriter,
+	request Request,
+) error {
+	start := time.Now()
+	err := handler.Handle(ctx, pluginEnv, responseWriter, request)
+	pluginEnv.metrics.Observe(MetricHandlerDurationSeconds, time.Since(start).Seconds())
+	return err
+}
+
+// exportMetrics records the metrics for the CodeGeneratorResponse, and exports the Metrics with each MetricsExporter.
+func exportMetrics(
+	ctx context.Context,
+	pluginEnv PluginEnv,
+	codeGeneratorResponse *pluginpb.CodeGeneratorResponse,
+	exporters []MetricsExporter,
+) error {
+	var generatedBytes int
+	for _, file := range codeGeneratorResponse.GetFile() {
+		generatedBytes += len(file.GetContent())
+	}
+	pluginEnv.metrics.Add(MetricFilesGenerated, float64(len(codeGeneratorResponse.GetFile())))
+	pluginEnv.metrics.Add(MetricBytesGenerated, float64(generatedBytes))
+	snapshot := pluginEnv.metrics.Snapshot()
+	for _, exporter := range exporters {
+		if err := exporter.Export(ctx, pluginEnv, snapshot); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// marshalPrometheusText marshals the MetricsSnapshot in the Prometheus text exposition format.
+//
+// Metrics are sorted by name for deterministic output.
+func marshalPrometheusText(pluginName string, snapshot *MetricsSnapshot) []byte {
+	var labels string
+	if pluginName != "" {
+		labels = `{plugin="` + escapePrometheusLabelValue(pluginName) + `"}`
+	}
+	buffer := bytes.NewBuffer(nil)
+	for _, name := range sortedKeys(snapshot.Counters) {
+		prometheusName := sanitizePrometheusName(name)
+		_, _ = buffer.WriteString("# TYPE " + prometheusName + " counter\n")
+		_, _ = buffer.WriteString(prometheusName + labels + " " + formatPrometheusValue(snapshot.Counters[name]) + "\n")
+	}
+	for _, name := range sortedKeys(snapshot.Histograms) {
+		prometheusName := sanitizePrometheusName(name)
+		histogram := snapshot.Histograms[name]
+		_, _ = buffer.WriteString("# TYPE " + prometheusName + " summary\n")
+		_, _ = buffer.WriteString(prometheusName + "_sum" + labels + " " + formatPrometheusValue(histogram.Sum) + "\n")
+		_, _ = buffer.WriteString(prometheusName + "_count" + labels + " " + strconv.FormatUint(histogram.Count, 10) + "\n")
+	}
+	return buffer.Bytes()
+}
+
+// sanitizePrometheusName replaces all characters that are not valid in a Prometheus metric name with
+// underscores.
+func sanitizePrometheusName(name string) string {
+	var builder strings.Builder
+	for i, r := range name {
+		switch {
+		case r == '_' || r == ':' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z'):
+			_, _ = builder.WriteRune(r)
+		case r >= '0' && r <= '9' && i > 0:
+			_, _ = builder.WriteRune(r)
+		default:
+			_ = builder.WriteByte('_')
+		}
+	}
+	return builder.String()
+}
+
+func escapePrometheusLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+func formatPrometheusValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// writeFileAtomic writes the data to a temporary file in the same directory, and then renames it to the file path.
+func writeFileAtomic(filePath string, data []byte) (retErr error) {
+	file, err := os.CreateTemp(filepath.Dir(filePath), filepath.Base(filePath)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if retErr != nil {
+			_ = os.Remove(file.Name())
+		}
+	}()
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	// os.CreateTemp creates files that are only readable by the owner, but the file is
+	// typically read by a separate process, such as the Prometheus node exporter.
+	if err := os.Chmod(file.Name(), 0644); err != nil { // #nosec:G302
+		return err
+	}
+	return os.Rename(file.Name(), filePath)
+}
@@ -0,0 +1,129 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestWithMetricsExporterOption(t *testing.T) {
+	t.Parallel()
+
+	codeGeneratorRequestData, err := proto.Marshal(
+		&pluginpb.CodeGeneratorRequest{
+			FileToGenerate: []string{"a.proto"},
+			ProtoFile: []*descriptorpb.FileDescriptorProto{
+				{
+					Name:   proto.String("a.proto"),
+					Syntax: proto.String("proto3"),
+				},
+			},
+		},
+	)
+	require.NoError(t, err)
+	textfilePath := filepath.Join(t.TempDir(), "protoc-gen-foo.prom")
+	var snapshot *MetricsSnapshot
+	stderr := bytes.NewBuffer(nil)
+	err = Run(
+		context.Background(),
+		Env{
+			Stdin:  bytes.NewReader(codeGeneratorRequestData),
+			Stdout: io.Discard,
+			Stderr: stderr,
+		},
+		HandlerFunc(func(_ context.Context, pluginEnv PluginEnv, responseWriter ResponseWriter, _ Request) error {
+			pluginEnv.Metrics().Add("protoc_gen_foo_messages_total", 2)
+			pluginEnv.Metrics().Add("protoc_gen_foo_messages_total", 1)
+			pluginEnv.Metrics().Observe("protoc-gen-foo.size", 3)
+			pluginEnv.Metrics().Observe("protoc-gen-foo.size", 5)
+			responseWriter.AddFile("a.txt", "hello")
+			responseWriter.AddFile("b.txt", "world!")
+			return nil
+		}),
+		WithPluginName("protoc-gen-foo"),
+		WithMetricsExporter(
+			MetricsExporterFunc(func(_ context.Context, _ PluginEnv, metricsSnapshot *MetricsSnapshot) error {
+				snapshot = metricsSnapshot
+				return nil
+			}),
+		),
+		WithMetricsExporter(NewPrometheusTextfileMetricsExporter(textfilePath)),
+		WithMetricsExporter(NewJSONMetricsExporter()),
+	)
+	require.NoError(t, err)
+
+	require.NotNil(t, snapshot)
+	require.Equal(
+		t,
+		map[string]float64{
+			"protoc_gen_foo_messages_total": 3,
+			MetricFilesGenerated:            2,
+			MetricBytesGenerated:            11,
+		},
+		snapshot.Counters,
+	)
+	require.Equal(
+		t,
+		MetricsHistogram{Count: 2, Sum: 8, Min: 3, Max: 5},
+		snapshot.Histograms["protoc-gen-foo.size"],
+	)
+	require.Equal(t, uint64(1), snapshot.Histograms[MetricHandlerDurationSeconds].Count)
+
+	data, err := os.ReadFile(textfilePath)
+	require.NoError(t, err)
+	require.Contains(
+		t,
+		string(data),
+		`# TYPE protoc_gen_foo_messages_total counter
+protoc_gen_foo_messages_total{plugin="protoc-gen-foo"} 3
+# TYPE protoplugin_files_generated_total counter
+protoplugin_files_generated_total{plugin="protoc-gen-foo"} 2
+`,
+	)
+	require.Contains(
+		t,
+		string(data),
+		`# TYPE protoc_gen_foo_size summary
+protoc_gen_foo_size_sum{plugin="protoc-gen-foo"} 8
+protoc_gen_foo_size_count{plugin="protoc-gen-foo"} 2
+`,
+	)
+
+	jsonMetrics := &jsonMetrics{}
+	require.NoError(t, json.Unmarshal(stderr.Bytes(), jsonMetrics))
+	require.Equal(t, "protoc-gen-foo", jsonMetrics.Plugin)
+	require.Equal(t, snapshot.Counters, jsonMetrics.Counters)
+}
+
+func TestMetricsNil(t *testing.T) {
+	t.Parallel()
+
+	metrics := PluginEnv{}.Metrics()
+	require.Nil(t, metrics)
+	metrics.Add("foo", 1)
+	metrics.Observe("bar", 1)
+	require.Nil(t, metrics.Snapshot())
+}
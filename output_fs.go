@@ -0,0 +1,127 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"sync"
+)
+
+// OutputFS is a write-only filesystem view over a ResponseWriter.
+//
+// OutputFS allows code generators that were written against a filesystem API to be adapted
+// to protoplugin without rewriting their emit layer. Every file written to an OutputFS
+// is added to the underlying ResponseWriter via AddFile.
+//
+// OutputFS contains a private method to ensure that it is not constructed outside this package, to
+// enable us to modify the OutputFS interface in the future without breaking compatibility.
+type OutputFS interface {
+	// Create creates a new file with the given name.
+	//
+	// The content written to the returned io.WriteCloser is added to the ResponseWriter
+	// when Close is called. If Close is never called, the file is never added.
+	//
+	// An error of type *fs.PathError will be returned if the name is an invalid path.
+	// Paths are considered valid if they are non-empty, relative, use '/' as the path separator,
+	// do not jump context, and are equal to filepath.ToSlash(filepath.Clean(name)).
+	Create(name string) (io.WriteCloser, error)
+	// MkdirAll is a no-op.
+	//
+	// Directories are implicit in CodeGeneratorResponses. This exists so that code written
+	// against a filesystem API that creates parent directories before writing files works as-is.
+	MkdirAll(path string, perm fs.FileMode) error
+	// WriteFile writes the data to the file with the given name.
+	//
+	// CodeGeneratorResponses have no concept of file permissions. If perm has any executable bits set,
+	// the file is marked executable via ResponseWriter.MarkExecutable, and all other bits are ignored.
+	//
+	// An error of type *fs.PathError will be returned if the name is an invalid path.
+	WriteFile(name string, data []byte, perm fs.FileMode) error
+
+	isOutputFS()
+}
+
+// NewOutputFS returns a new OutputFS that writes to the given ResponseWriter.
+func NewOutputFS(responseWriter ResponseWriter) OutputFS {
+	return &outputFS{
+		responseWriter: responseWriter,
+	}
+}
+
+// *** PRIVATE ***
+
+type outputFS struct {
+	responseWriter ResponseWriter
+}
+
+func (o *outputFS) Create(name string) (io.WriteCloser, error) {
+	if err := validateAndCheckPathIsNormalized("name", name); err != nil {
+		return nil, &fs.PathError{Op: "create", Path: name, Err: err}
+	}
+	return &outputFile{
+		responseWriter: o.responseWriter,
+		name:           name,
+	}, nil
+}
+
+func (*outputFS) MkdirAll(string, fs.FileMode) error {
+	return nil
+}
+
+func (o *outputFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	if err := validateAndCheckPathIsNormalized("name", name); err != nil {
+		return &fs.PathError{Op: "write", Path: name, Err: err}
+	}
+	o.responseWriter.AddFile(name, string(data))
+	if perm&0o111 != 0 {
+		o.responseWriter.MarkExecutable(name)
+	}
+	return nil
+}
+
+func (*outputFS) isOutputFS() {}
+
+type outputFile struct {
+	responseWriter ResponseWriter
+	name           string
+	buffer         bytes.Buffer
+	closed         bool
+
+	lock sync.Mutex
+}
+
+func (o *outputFile) Write(p []byte) (int, error) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	if o.closed {
+		return 0, &fs.PathError{Op: "write", Path: o.name, Err: fs.ErrClosed}
+	}
+	return o.buffer.Write(p)
+}
+
+func (o *outputFile) Close() error {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	if o.closed {
+		return &fs.PathError{Op: "close", Path: o.name, Err: fs.ErrClosed}
+	}
+	o.closed = true
+	o.responseWriter.AddFile(o.name, o.buffer.String())
+	return nil
+}
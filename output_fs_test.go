@@ -0,0 +1,60 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"io"
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutputFS(t *testing.T) {
+	t.Parallel()
+
+	responseWriter := NewResponseWriter()
+	outputFS := NewOutputFS(responseWriter)
+
+	require.NoError(t, outputFS.MkdirAll("a/b", 0755))
+	require.NoError(t, outputFS.WriteFile("a/b/c.txt", []byte("c"), 0644))
+	writeCloser, err := outputFS.Create("a/d.txt")
+	require.NoError(t, err)
+	_, err = io.WriteString(writeCloser, "d1")
+	require.NoError(t, err)
+	_, err = io.WriteString(writeCloser, "d2")
+	require.NoError(t, err)
+	require.NoError(t, writeCloser.Close())
+	_, err = io.WriteString(writeCloser, "d3")
+	require.ErrorIs(t, err, fs.ErrClosed)
+	require.ErrorIs(t, writeCloser.Close(), fs.ErrClosed)
+
+	// Files that are never closed are never added.
+	_, err = outputFS.Create("e.txt")
+	require.NoError(t, err)
+
+	var pathError *fs.PathError
+	_, err = outputFS.Create("../f.txt")
+	require.ErrorAs(t, err, &pathError)
+	require.ErrorAs(t, outputFS.WriteFile("./g.txt", nil, 0644), &pathError)
+
+	codeGeneratorResponse, err := responseWriter.ToCodeGeneratorResponse()
+	require.NoError(t, err)
+	require.Len(t, codeGeneratorResponse.GetFile(), 2)
+	require.Equal(t, "a/b/c.txt", codeGeneratorResponse.GetFile()[0].GetName())
+	require.Equal(t, "c", codeGeneratorResponse.GetFile()[0].GetContent())
+	require.Equal(t, "a/d.txt", codeGeneratorResponse.GetFile()[1].GetName())
+	require.Equal(t, "d1d2", codeGeneratorResponse.GetFile()[1].GetContent())
+}
@@ -0,0 +1,69 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// WithOutputPrefix returns a new RunOption that says to prefix the names of all files in the
+// CodeGeneratorResponse with the given directory.
+//
+// This allows tools that compose multiple Handlers into a single CodeGeneratorResponse to namespace the
+// outputs of each Handler, without modifying the Handlers. For example, with the prefix "gen/foo", a file
+// "a/b.txt" added by the Handler is named "gen/foo/a/b.txt" in the CodeGeneratorResponse.
+//
+// The prefix is a directory, and may optionally end with "/". It must be a normalized relative path that
+// uses '/' as the path separator and does not jump context, otherwise an error is returned before the
+// Handler is invoked. Files that are added with insertion points are also prefixed, so insertion points
+// can only target files within the same prefix.
+//
+// The prefix is applied to the CodeGeneratorResponse after it is produced by the ResponseWriter, and before
+// any functions given with WithResponseTransform. The Handler and the ResponseWriter are unaware of the prefix.
+// The names of files within the DryRunReport given with WithDryRun are also prefixed.
+//
+// This option can be passed to Main or Run.
+//
+// The default is to not prefix file names.
+func WithOutputPrefix(prefix string) RunOption {
+	return optsFunc(func(opts *opts) {
+		opts.outputPrefix = prefix
+	})
+}
+
+// *** PRIVATE ***
+
+// normalizeOutputPrefix validates the prefix given to WithOutputPrefix, and returns it with a trailing "/".
+func normalizeOutputPrefix(prefix string) (string, error) {
+	dirPath := strings.TrimSuffix(prefix, "/")
+	if dirPath == "." || dirPath == ".." {
+		return "", fmt.Errorf("output prefix %q should not be %q", prefix, dirPath)
+	}
+	if err := validateAndCheckPathIsNormalized("output prefix", dirPath); err != nil {
+		return "", err
+	}
+	return dirPath + "/", nil
+}
+
+// applyOutputPrefix prefixes the names of all files in the CodeGeneratorResponse with the normalized prefix.
+func applyOutputPrefix(codeGeneratorResponse *pluginpb.CodeGeneratorResponse, normalizedPrefix string) {
+	for _, file := range codeGeneratorResponse.GetFile() {
+		file.Name = proto.String(normalizedPrefix + file.GetName())
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestWithOutputPrefixOption(t *testing.T) {
+	t.Parallel()
+
+	invoke := func(prefix string, runOptions ...RunOption) (*pluginpb.CodeGeneratorResponse, error) {
+		return Invoke(
+			context.Background(),
+			HandlerFunc(func(_ context.Context, _ PluginEnv, responseWriter ResponseWriter, _ Request) error {
+				responseWriter.AddFile("a.txt", "a")
+				responseWriter.AddFile("b/c.txt", "c")
+				return nil
+			}),
+			&pluginpb.CodeGeneratorRequest{
+				FileToGenerate: []string{"a.proto"},
+				ProtoFile: []*descriptorpb.FileDescriptorProto{
+					{
+						Name:   proto.String("a.proto"),
+						Syntax: proto.String("proto3"),
+					},
+				},
+			},
+			append(runOptions, WithOutputPrefix(prefix))...,
+		)
+	}
+	fileNames := func(codeGeneratorResponse *pluginpb.CodeGeneratorResponse) []string {
+		var fileNames []string
+		for _, file := range codeGeneratorResponse.GetFile() {
+			fileNames = append(fileNames, file.GetName())
+		}
+		return fileNames
+	}
+
+	codeGeneratorResponse, err := invoke("gen/foo")
+	require.NoError(t, err)
+	require.Equal(t, []string{"gen/foo/a.txt", "gen/foo/b/c.txt"}, fileNames(codeGeneratorResponse))
+	codeGeneratorResponse, err = invoke("gen/foo/")
+	require.NoError(t, err)
+	require.Equal(t, []string{"gen/foo/a.txt", "gen/foo/b/c.txt"}, fileNames(codeGeneratorResponse))
+
+	var dryRunReport *DryRunReport
+	_, err = invoke(
+		"gen",
+		WithDryRun(func(report *DryRunReport) error {
+			dryRunReport = report
+			return nil
+		}),
+	)
+	require.NoError(t, err)
+	require.Equal(t, "gen/a.txt", dryRunReport.Files[0].Name)
+
+	for _, invalidPrefix := range []string{"/gen", "../gen", "gen/../..", "gen//foo", "./gen", ".", ".."} {
+		_, err = invoke(invalidPrefix)
+		require.Error(t, err, invalidPrefix)
+	}
+}
@@ -0,0 +1,242 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Parameters are the parsed parameter of a CodeGeneratorRequest.
+//
+// The parameter is parsed as a comma-separated list of "key" or "key=value" elements, which is the convention
+// for protoc plugins. Whitespace around keys and values is trimmed, and empty elements are ignored.
+//
+// Parameters tracks which keys have been read, so that parameters that the plugin does not support, for
+// example because of a typo such as "path=source_relative" instead of "paths=source_relative", can be
+// reported. See WithUnusedParameterWarnings for more details.
+//
+// Parameters is safe for concurrent use by multiple goroutines.
+type Parameters struct {
+	// The keys in the order they first appear.
+	keys        []string
+	keyToValues map[string][]string
+
+	lock sync.Mutex
+	// The keys that were read, including keys that were not present.
+	readKeys map[string]struct{}
+}
+
+// ParseParameters parses the parameter of a CodeGeneratorRequest.
+//
+// An error is returned if any element has an empty key.
+func ParseParameters(parameter string) (*Parameters, error) {
+	parameters := &Parameters{
+		keyToValues: make(map[string][]string),
+		readKeys:    make(map[string]struct{}),
+	}
+	if parameter == "" {
+		return parameters, nil
+	}
+	for _, element := range strings.Split(parameter, ",") {
+		if strings.TrimSpace(element) == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(element, "=")
+		key = strings.TrimSpace(key)
+		if key == "" {
+			return nil, errors.New("invalid parameter " + strconv.Quote(element) + ": key was empty")
+		}
+		if _, ok := parameters.keyToValues[key]; !ok {
+			parameters.keys = append(parameters.keys, key)
+		}
+		parameters.keyToValues[key] = append(parameters.keyToValues[key], strings.TrimSpace(value))
+	}
+	return parameters, nil
+}
+
+// Keys returns the distinct keys in the order they first appear within the parameter.
+//
+// This does not mark the keys as read.
+func (p *Parameters) Keys() []string {
+	return slicesClone(p.keys)
+}
+
+// Get returns the value of the last element with the key, and whether the key was present.
+//
+// The value is empty if the element did not have a value, for example for "key" as opposed to "key=value".
+func (p *Parameters) Get(key string) (string, bool) {
+	p.markRead(key)
+	values, ok := p.keyToValues[key]
+	if !ok {
+		return "", false
+	}
+	return values[len(values)-1], true
+}
+
+// GetAll returns the values of all elements with the key, in the order they appear within the parameter.
+//
+// This is useful for keys that may be specified multiple times.
+func (p *Parameters) GetAll(key string) []string {
+	p.markRead(key)
+	return slicesClone(p.keyToValues[key])
+}
+
+// GetBool returns the value of the last element with the key as a bool.
+//
+// If the key was not present, false is returned. If the element did not have a value, for example "key" as
+// opposed to "key=true", true is returned. Otherwise, the value is parsed with strconv.ParseBool, and an
+// error is returned if the value is invalid.
+func (p *Parameters) GetBool(key string) (bool, error) {
+	value, ok := p.Get(key)
+	if !ok {
+		return false, nil
+	}
+	if value == "" {
+		return true, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, errors.New("invalid value " + strconv.Quote(value) + " for parameter " + strconv.Quote(key) + ": must be a bool")
+	}
+	return b, nil
+}
+
+// Unused returns the keys that were present but were never read via Get, GetAll, or GetBool, in the order
+// they first appear within the parameter.
+func (p *Parameters) Unused() []string {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	var unused []string
+	for _, key := range p.keys {
+		if _, ok := p.readKeys[key]; !ok {
+			unused = append(unused, key)
+		}
+	}
+	return unused
+}
+
+// WithUnusedParameterWarnings returns a new RunOption that says to produce a warning for every parameter
+// key that the Handler did not read.
+//
+// Keys are tracked via the Parameters returned from Request.Parameters. If the Handler never reads any key
+// via Request.Parameters, no warnings are produced, as the Handler may parse the parameter itself. If a key
+// is similar to a key that the Handler read, the warning suggests the key that was read. For example, with
+// the parameter "path=source_relative", a Handler that read "paths" results in the warning
+// `unused parameter "path", did you mean "paths"?`.
+//
+// Warnings are written to stderr via PluginEnv.Warnf after the Handler returns.
+//
+// This option can be passed to Main or Run.
+//
+// The default is to not produce warnings for unused parameters.
+func WithUnusedParameterWarnings() RunOption {
+	return optsFunc(func(opts *opts) {
+		opts.unusedParameterWarnings = true
+	})
+}
+
+// *** PRIVATE ***
+
+// warnUnusedParameters produces a warning for every unused key of the Parameters of the Request.
+//
+// If the parameter could not be parsed, no warnings are produced, as the Handler was given the error.
+func warnUnusedParameters(pluginEnv PluginEnv, request Request) {
+	parameters, err := request.Parameters()
+	if err != nil {
+		return
+	}
+	for _, warning := range parameters.unusedWarnings() {
+		pluginEnv.Warnf("%s", warning)
+	}
+}
+
+func (p *Parameters) markRead(key string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.readKeys[key] = struct{}{}
+}
+
+// unusedWarnings returns the warnings for the unused keys.
+//
+// If no keys were read, no warnings are returned.
+func (p *Parameters) unusedWarnings() []string {
+	p.lock.Lock()
+	readKeys := make([]string, 0, len(p.readKeys))
+	for readKey := range p.readKeys {
+		readKeys = append(readKeys, readKey)
+	}
+	p.lock.Unlock()
+	if len(readKeys) == 0 {
+		return nil
+	}
+	var warnings []string
+	for _, key := range p.Unused() {
+		warning := "unused parameter " + strconv.Quote(key)
+		if suggestion := closestKey(key, readKeys); suggestion != "" {
+			warning += ", did you mean " + strconv.Quote(suggestion) + "?"
+		}
+		warnings = append(warnings, warning)
+	}
+	return warnings
+}
+
+// closestKey returns the candidate with the smallest edit distance to the key, if the edit distance is
+// at most 2. If there are multiple such candidates, the lexicographically smallest is returned.
+func closestKey(key string, candidates []string) string {
+	var closest string
+	closestDistance := 3
+	for _, candidate := range candidates {
+		distance := editDistance(key, candidate)
+		if distance < closestDistance || (distance == closestDistance && candidate < closest) {
+			closest = candidate
+			closestDistance = distance
+		}
+	}
+	return closest
+}
+
+// editDistance returns the Levenshtein distance between the strings.
+func editDistance(a string, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			substitutionCost := 1
+			if a[i-1] == b[j-1] {
+				substitutionCost = 0
+			}
+			current[j] = minInt(previous[j]+1, current[j-1]+1, previous[j-1]+substitutionCost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+func minInt(first int, others ...int) int {
+	result := first
+	for _, other := range others {
+		if other < result {
+			result = other
+		}
+	}
+	return result
+}
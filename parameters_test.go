@@ -0,0 +1,121 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestParseParameters(t *testing.T) {
+	t.Parallel()
+
+	parameters, err := ParseParameters(" paths = source_relative ,foo,,M=a.proto=b,M=c.proto=d,foo=false")
+	require.NoError(t, err)
+	require.Equal(t, []string{"paths", "foo", "M"}, parameters.Keys())
+	require.Equal(t, []string{"paths", "foo", "M"}, parameters.Unused())
+	value, ok := parameters.Get("paths")
+	require.True(t, ok)
+	require.Equal(t, "source_relative", value)
+	require.Equal(t, []string{"a.proto=b", "c.proto=d"}, parameters.GetAll("M"))
+	b, err := parameters.GetBool("foo")
+	require.NoError(t, err)
+	require.False(t, b)
+	require.Empty(t, parameters.Unused())
+	_, ok = parameters.Get("bar")
+	require.False(t, ok)
+
+	parameters, err = ParseParameters("foo,bar=yes")
+	require.NoError(t, err)
+	b, err = parameters.GetBool("foo")
+	require.NoError(t, err)
+	require.True(t, b)
+	_, err = parameters.GetBool("bar")
+	require.Error(t, err)
+	b, err = parameters.GetBool("baz")
+	require.NoError(t, err)
+	require.False(t, b)
+
+	parameters, err = ParseParameters("")
+	require.NoError(t, err)
+	require.Empty(t, parameters.Keys())
+
+	_, err = ParseParameters("foo,=bar")
+	require.Error(t, err)
+}
+
+func TestWithUnusedParameterWarningsOption(t *testing.T) {
+	t.Parallel()
+
+	run := func(parameter string, handler HandlerFunc, runOptions ...RunOption) string {
+		codeGeneratorRequestData, err := proto.Marshal(
+			&pluginpb.CodeGeneratorRequest{
+				FileToGenerate: []string{"a.proto"},
+				Parameter:      proto.String(parameter),
+				ProtoFile: []*descriptorpb.FileDescriptorProto{
+					{
+						Name:   proto.String("a.proto"),
+						Syntax: proto.String("proto3"),
+					},
+				},
+			},
+		)
+		require.NoError(t, err)
+		stderr := bytes.NewBuffer(nil)
+		err = Run(
+			context.Background(),
+			Env{
+				Stdin:  bytes.NewReader(codeGeneratorRequestData),
+				Stdout: io.Discard,
+				Stderr: stderr,
+			},
+			handler,
+			runOptions...,
+		)
+		require.NoError(t, err)
+		return stderr.String()
+	}
+	readPaths := HandlerFunc(
+		func(_ context.Context, _ PluginEnv, _ ResponseWriter, request Request) error {
+			parameters, err := request.Parameters()
+			if err != nil {
+				return err
+			}
+			_, _ = parameters.Get("paths")
+			return nil
+		},
+	)
+	readNothing := HandlerFunc(
+		func(context.Context, PluginEnv, ResponseWriter, Request) error {
+			return nil
+		},
+	)
+
+	require.Empty(t, run("path=source_relative", readPaths))
+	require.Equal(
+		t,
+		"warning: unused parameter \"path\", did you mean \"paths\"?\nwarning: unused parameter \"other\"\n",
+		run("path=source_relative,other", readPaths, WithUnusedParameterWarnings()),
+	)
+	require.Empty(t, run("paths=source_relative", readPaths, WithUnusedParameterWarnings()))
+	require.Empty(t, run("path=source_relative", readNothing, WithUnusedParameterWarnings()))
+}
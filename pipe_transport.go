@@ -0,0 +1,105 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	// StdinPipeEnvKey is the environment variable that a consumer of a plugin sets on Windows to have the
+	// plugin read the CodeGeneratorRequest from a named pipe or inherited handle instead of stdin.
+	//
+	// The value is either the path of a named pipe or file, such as `\\.\pipe\protoc-gen-foo-request`,
+	// or "handle:" followed by the decimal value of a handle inherited from the parent process, such as
+	// "handle:312".
+	//
+	// This is useful for shells and environments on Windows that do not preserve binary data on stdin and
+	// stdout, for example by translating line endings. The variable is ignored on other platforms.
+	StdinPipeEnvKey = "PROTOPLUGIN_STDIN_PIPE"
+	// StdoutPipeEnvKey is the environment variable that a consumer of a plugin sets on Windows to have the
+	// plugin write the CodeGeneratorResponse to a named pipe or inherited handle instead of stdout.
+	//
+	// The value has the same format as the value of StdinPipeEnvKey. The variable is ignored on other platforms.
+	StdoutPipeEnvKey = "PROTOPLUGIN_STDOUT_PIPE"
+
+	pipeHandlePrefix = "handle:"
+)
+
+// *** PRIVATE ***
+
+// openPipeTransport returns a copy of the Env with Stdin and Stdout replaced by the pipes specified by
+// StdinPipeEnvKey and StdoutPipeEnvKey, if the platform supports pipe transport and the variables are set.
+//
+// The returned function closes any opened pipes, and must always be called.
+func openPipeTransport(env Env) (Env, func() error, error) {
+	if !pipeTransportSupported {
+		return env, func() error { return nil }, nil
+	}
+	return openPipes(env)
+}
+
+// openPipes does the work of openPipeTransport regardless of the platform.
+func openPipes(env Env) (Env, func() error, error) {
+	var files []*os.File
+	closeFiles := func() error {
+		var errs []error
+		for _, file := range files {
+			errs = append(errs, file.Close())
+		}
+		return errors.Join(errs...)
+	}
+	if value, _ := lookupEnv(env.Environ, StdinPipeEnvKey); value != "" {
+		file, err := openPipe(StdinPipeEnvKey, value, os.O_RDONLY)
+		if err != nil {
+			return env, nil, err
+		}
+		files = append(files, file)
+		env.Stdin = file
+	}
+	if value, _ := lookupEnv(env.Environ, StdoutPipeEnvKey); value != "" {
+		file, err := openPipe(StdoutPipeEnvKey, value, os.O_WRONLY)
+		if err != nil {
+			return env, nil, errors.Join(err, closeFiles())
+		}
+		files = append(files, file)
+		env.Stdout = file
+	}
+	return env, closeFiles, nil
+}
+
+// openPipe opens the named pipe, file, or inherited handle specified by the value of the environment variable.
+func openPipe(key string, value string, flag int) (*os.File, error) {
+	if handleString, ok := strings.CutPrefix(value, pipeHandlePrefix); ok {
+		handle, err := strconv.ParseUint(handleString, 10, strconv.IntSize)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q for environment variable %s: invalid handle: %w", value, key, err)
+		}
+		file := os.NewFile(uintptr(handle), value) // #nosec:G115
+		if file == nil {
+			return nil, fmt.Errorf("invalid value %q for environment variable %s: invalid handle", value, key)
+		}
+		return file, nil
+	}
+	file, err := os.OpenFile(value, flag, 0)
+	if err != nil {
+		return nil, fmt.Errorf("could not open %s for environment variable %s: %w", value, key, err)
+	}
+	return file, nil
+}
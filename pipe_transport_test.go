@@ -0,0 +1,72 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenPipes(t *testing.T) {
+	t.Parallel()
+
+	tmpDirPath := t.TempDir()
+	stdinFilePath := filepath.Join(tmpDirPath, "stdin")
+	require.NoError(t, os.WriteFile(stdinFilePath, []byte("request"), 0600))
+	stdoutFilePath := filepath.Join(tmpDirPath, "stdout")
+	require.NoError(t, os.WriteFile(stdoutFilePath, nil, 0600))
+	// Use a raw handle rather than an *os.File, as ownership of the handle is transferred by openPipes.
+	stdoutHandle, err := syscall.Open(stdoutFilePath, syscall.O_WRONLY, 0)
+	require.NoError(t, err)
+
+	stdin := bytes.NewReader(nil)
+	stdout := bytes.NewBuffer(nil)
+	env := Env{Stdin: stdin, Stdout: stdout}
+	pipeEnv, closePipes, err := openPipes(env)
+	require.NoError(t, err)
+	require.Same(t, stdin, pipeEnv.Stdin)
+	require.Same(t, stdout, pipeEnv.Stdout)
+	require.NoError(t, closePipes())
+
+	env.Environ = []string{
+		StdinPipeEnvKey + "=" + stdinFilePath,
+		StdoutPipeEnvKey + "=" + pipeHandlePrefix + strconv.FormatUint(uint64(stdoutHandle), 10),
+	}
+	pipeEnv, closePipes, err = openPipes(env)
+	require.NoError(t, err)
+	data, err := io.ReadAll(pipeEnv.Stdin)
+	require.NoError(t, err)
+	require.Equal(t, "request", string(data))
+	_, err = pipeEnv.Stdout.Write([]byte("response"))
+	require.NoError(t, err)
+	require.NoError(t, closePipes())
+	data, err = os.ReadFile(stdoutFilePath)
+	require.NoError(t, err)
+	require.Equal(t, "response", string(data))
+
+	env.Environ = []string{StdinPipeEnvKey + "=" + filepath.Join(tmpDirPath, "missing")}
+	_, _, err = openPipes(env)
+	require.Error(t, err)
+	env.Environ = []string{StdoutPipeEnvKey + "=" + pipeHandlePrefix + "foo"}
+	_, _, err = openPipes(env)
+	require.Error(t, err)
+}
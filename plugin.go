@@ -0,0 +1,141 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// Plugin is a Handler along with its options.
+//
+// A Plugin is configured once with New, and can then be executed many times via Main, Run, or Invoke.
+// This is useful for embedders such as servers and workers that execute the same plugin for many
+// requests. The functions Main, Run, and Invoke are equivalent to creating a Plugin with New and calling
+// the corresponding method.
+//
+// A Plugin is safe for concurrent use if the Handler and all given options are safe for concurrent use.
+type Plugin struct {
+	handler Handler
+	opts    *opts
+}
+
+// New returns a new Plugin for the Handler.
+//
+// All RunOptions are also MainOptions, so both can be given. MainOptions that are not RunOptions, such as
+// WithExitCodeFunc, only have an effect on Main.
+func New(handler Handler, options ...MainOption) *Plugin {
+	opts := newOpts()
+	for _, option := range options {
+		option.applyMainOption(opts)
+	}
+	return newPlugin(handler, opts)
+}
+
+// Main runs the Plugin with the os-based environment, and exits the process.
+//
+// See the Main function for more details.
+func (p *Plugin) Main() {
+	ctx, cancel := withCancelInterruptSignal(context.Background())
+	exitCode := runMain(ctx, osEnv, p.handler, p.opts)
+	cancel()
+	if exitCode != 0 {
+		os.Exit(exitCode)
+	}
+}
+
+// Run runs the Plugin for the given environment.
+//
+// See the Run function for more details.
+func (p *Plugin) Run(ctx context.Context, env Env) error {
+	return run(ctx, env, p.handler, p.opts)
+}
+
+// Invoke invokes the Plugin in-process for the given CodeGeneratorRequest, returning the resulting
+// CodeGeneratorResponse.
+//
+// See the Invoke function for more details.
+func (p *Plugin) Invoke(
+	ctx context.Context,
+	codeGeneratorRequest *pluginpb.CodeGeneratorRequest,
+) (*pluginpb.CodeGeneratorResponse, error) {
+	return invoke(
+		ctx,
+		PluginEnv{
+			Stderr: io.Discard,
+		},
+		p.handler,
+		codeGeneratorRequest,
+		p.opts,
+	)
+}
+
+// *** PRIVATE ***
+
+// runMain runs the Handler for Main, writing any error to the stderr of the environment, and returns
+// the exit code.
+//
+// The environment defaults are applied here rather than in run, so that the error is written in the
+// diagnostics format specified by the environment.
+func runMain(ctx context.Context, env Env, handler Handler, opts *opts) int {
+	runOpts, err := withEnvDefaults(env, opts)
+	if err == nil {
+		opts = runOpts
+		err = runWithEnvDefaults(ctx, env, handler, opts)
+	}
+	if err == nil {
+		return 0
+	}
+	exitError := &exec.ExitError{}
+	// Swallow error message for exec.ExitErrors - it was printed via os.Stderr redirection.
+	if !errors.As(err, &exitError) {
+		if errString := err.Error(); errString != "" {
+			if opts.jsonDiagnostics {
+				writeJSONDiagnostic(
+					env.Stderr,
+					PluginEnv{ProgramName: env.ProgramName, pluginName: opts.pluginName}.Name(),
+					Diagnostic{
+						Severity: DiagnosticSeverityError,
+						Message:  errString,
+					},
+				)
+			} else {
+				_, _ = fmt.Fprintln(env.Stderr, errString)
+			}
+		}
+	}
+	return getExitCode(err, opts.exitCodeFunc)
+}
+
+func newPlugin(handler Handler, opts *opts) *Plugin {
+	return &Plugin{
+		handler: handler,
+		opts:    opts,
+	}
+}
+
+func newPluginForRunOptions(handler Handler, options []RunOption) *Plugin {
+	opts := newOpts()
+	for _, option := range options {
+		option.applyRunOption(opts)
+	}
+	return newPlugin(handler, opts)
+}
@@ -0,0 +1,121 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestPlugin(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	fileDescriptorProtos, err := compile(ctx, map[string][]byte{
+		"a.proto": []byte(`syntax = "proto3"; package foo; message A {}`),
+	})
+	require.NoError(t, err)
+	codeGeneratorRequest := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{"a.proto"},
+		ProtoFile:      fileDescriptorProtos,
+	}
+	codeGeneratorRequestData, err := proto.Marshal(codeGeneratorRequest)
+	require.NoError(t, err)
+
+	var invocations int
+	plugin := New(
+		HandlerFunc(func(_ context.Context, _ PluginEnv, responseWriter ResponseWriter, _ Request) error {
+			invocations++
+			responseWriter.AddFile("./a.txt", "a")
+			return nil
+		}),
+		WithOutputPrefix("gen"),
+	)
+	run := func(environ []string) error {
+		return plugin.Run(
+			ctx,
+			Env{
+				Environ: environ,
+				Stdin:   bytes.NewReader(codeGeneratorRequestData),
+				Stdout:  io.Discard,
+				Stderr:  io.Discard,
+			},
+		)
+	}
+
+	// The unnormalized file name is an error unless lenient validation is enabled via the environment.
+	_, err = plugin.Invoke(ctx, codeGeneratorRequest)
+	responseValidationError := &ResponseValidationError{}
+	require.ErrorAs(t, err, &responseValidationError)
+	require.NoError(t, run([]string{LenientValidationEnvKey + "=1"}))
+	// Environment defaults from a previous run do not carry over.
+	require.ErrorAs(t, run(nil), &responseValidationError)
+	require.Equal(t, 3, invocations)
+
+	plugin = New(
+		HandlerFunc(func(_ context.Context, _ PluginEnv, responseWriter ResponseWriter, _ Request) error {
+			responseWriter.AddFile("a.txt", "a")
+			return nil
+		}),
+		WithOutputPrefix("gen"),
+	)
+	for i := 0; i < 2; i++ {
+		codeGeneratorResponse, err := plugin.Invoke(ctx, codeGeneratorRequest)
+		require.NoError(t, err)
+		require.Len(t, codeGeneratorResponse.GetFile(), 1)
+		require.Equal(t, "gen/a.txt", codeGeneratorResponse.GetFile()[0].GetName())
+	}
+}
+
+func TestPluginMainJSONDiagnosticsFromEnv(t *testing.T) {
+	t.Parallel()
+
+	handler := HandlerFunc(func(context.Context, PluginEnv, ResponseWriter, Request) error { return nil })
+	runMainWithEnviron := func(environ []string) (int, string) {
+		stderr := bytes.NewBuffer(nil)
+		exitCode := runMain(
+			context.Background(),
+			Env{
+				ProgramName: "protoc-gen-test",
+				Environ:     environ,
+				Stdin:       bytes.NewReader(nil),
+				Stdout:      io.Discard,
+				Stderr:      stderr,
+			},
+			handler,
+			New(handler).opts,
+		)
+		return exitCode, stderr.String()
+	}
+
+	// The empty CodeGeneratorRequest is invalid.
+	exitCode, stderr := runMainWithEnviron(nil)
+	require.Equal(t, 1, exitCode)
+	require.Equal(t, "CodeGeneratorRequest: proto_file: empty\n", stderr)
+	exitCode, stderr = runMainWithEnviron([]string{DiagnosticsFormatEnvKey + "=" + DiagnosticsFormatJSON})
+	require.Equal(t, 1, exitCode)
+	require.JSONEq(
+		t,
+		`{"plugin":"protoc-gen-test","severity":"error","message":"CodeGeneratorRequest: proto_file: empty"}`,
+		stderr,
+	)
+}
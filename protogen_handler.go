@@ -0,0 +1,70 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"context"
+
+	"google.golang.org/protobuf/compiler/protogen"
+)
+
+// NewProtogenHandler returns a new Handler that invokes the function with a *protogen.Plugin.
+//
+// This performs the scaffolding needed to use protogen within protoplugin: a *protogen.Plugin is created
+// from the CodeGeneratorRequest with the given protogen.Options, the function is invoked, and the files,
+// error, supported features, and supported editions of the resulting CodeGeneratorResponse are copied to the
+// ResponseWriter. This gives Go code generators written with protogen the validation and tooling of protoplugin
+// with a one-line adapter:
+//
+//	func main() {
+//	  protoplugin.Main(protoplugin.NewProtogenHandler(generate, protogen.Options{}))
+//	}
+//
+// As with protogen.Options.Run, an error returned from the function is added to the CodeGeneratorResponse
+// via plugin.Error, and does not result in the Handler returning an error. An error creating the
+// *protogen.Plugin, for example due to an invalid parameter, is returned from the Handler.
+//
+// Features and editions are copied from the plugin.SupportedFeatures, plugin.SupportedEditionsMinimum, and
+// plugin.SupportedEditionsMaximum fields, so the function must set these for the CodeGeneratorResponse to
+// declare them.
+func NewProtogenHandler(f func(*protogen.Plugin) error, options protogen.Options) Handler {
+	return HandlerFunc(
+		func(
+			_ context.Context,
+			_ PluginEnv,
+			responseWriter ResponseWriter,
+			request Request,
+		) error {
+			plugin, err := options.New(request.CodeGeneratorRequest())
+			if err != nil {
+				return err
+			}
+			if err := f(plugin); err != nil {
+				plugin.Error(err)
+			}
+			response := plugin.Response()
+			responseWriter.AddCodeGeneratorResponseFiles(response.GetFile()...)
+			responseWriter.AddError(response.GetError())
+			if supportedFeatures := response.GetSupportedFeatures(); supportedFeatures != 0 {
+				responseWriter.SetSupportedFeatures(supportedFeatures)
+			}
+			if response.MinimumEdition != nil && response.MaximumEdition != nil {
+				responseWriter.SetMinimumEdition(response.GetMinimumEdition())
+				responseWriter.SetMaximumEdition(response.GetMaximumEdition())
+			}
+			return nil
+		},
+	)
+}
@@ -0,0 +1,107 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestNewProtogenHandler(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	fileDescriptorProtos, err := compile(ctx, map[string][]byte{
+		"a.proto": []byte(`syntax = "proto3"; package foo; option go_package = "example.com/foo"; message A {}`),
+	})
+	require.NoError(t, err)
+	codeGeneratorRequest := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{"a.proto"},
+		Parameter:      proto.String("paths=source_relative"),
+		ProtoFile:      fileDescriptorProtos,
+	}
+
+	codeGeneratorResponse, err := Invoke(
+		ctx,
+		NewProtogenHandler(
+			func(plugin *protogen.Plugin) error {
+				plugin.SupportedFeatures = uint64(
+					pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL |
+						pluginpb.CodeGeneratorResponse_FEATURE_SUPPORTS_EDITIONS,
+				)
+				plugin.SupportedEditionsMinimum = descriptorpb.Edition_EDITION_PROTO2
+				plugin.SupportedEditionsMaximum = descriptorpb.Edition_EDITION_2023
+				for _, file := range plugin.Files {
+					if !file.Generate {
+						continue
+					}
+					generatedFile := plugin.NewGeneratedFile(file.GeneratedFilenamePrefix+".txt", file.GoImportPath)
+					for _, message := range file.Messages {
+						generatedFile.P(message.GoIdent.GoName)
+					}
+				}
+				return nil
+			},
+			protogen.Options{},
+		),
+		codeGeneratorRequest,
+	)
+	require.NoError(t, err)
+	require.Empty(t, codeGeneratorResponse.GetError())
+	require.Len(t, codeGeneratorResponse.GetFile(), 1)
+	require.Equal(t, "a.txt", codeGeneratorResponse.GetFile()[0].GetName())
+	require.Equal(t, "A\n", codeGeneratorResponse.GetFile()[0].GetContent())
+	require.Equal(
+		t,
+		uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL|pluginpb.CodeGeneratorResponse_FEATURE_SUPPORTS_EDITIONS),
+		codeGeneratorResponse.GetSupportedFeatures(),
+	)
+	require.Equal(t, int32(descriptorpb.Edition_EDITION_PROTO2), codeGeneratorResponse.GetMinimumEdition())
+	require.Equal(t, int32(descriptorpb.Edition_EDITION_2023), codeGeneratorResponse.GetMaximumEdition())
+
+	codeGeneratorResponse, err = Invoke(
+		ctx,
+		NewProtogenHandler(
+			func(*protogen.Plugin) error {
+				return errors.New("foo")
+			},
+			protogen.Options{},
+		),
+		codeGeneratorRequest,
+	)
+	require.NoError(t, err)
+	require.Equal(t, "foo", codeGeneratorResponse.GetError())
+
+	codeGeneratorRequest.Parameter = proto.String("paths=invalid")
+	_, err = Invoke(
+		ctx,
+		NewProtogenHandler(
+			func(*protogen.Plugin) error {
+				return nil
+			},
+			protogen.Options{},
+		),
+		codeGeneratorRequest,
+	)
+	require.Error(t, err)
+}
@@ -16,6 +16,7 @@
 package protoplugin
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -23,6 +24,7 @@ import (
 	"os"
 	"os/exec"
 	"os/signal"
+	"time"
 
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoregistry"
@@ -32,11 +34,12 @@ import (
 var (
 	// osEnv is the os-based Env used in Main.
 	osEnv = Env{
-		Args:    os.Args[1:],
-		Environ: os.Environ(),
-		Stdin:   os.Stdin,
-		Stdout:  os.Stdout,
-		Stderr:  os.Stderr,
+		ProgramName: programNameFromArg(os.Args[0]),
+		Args:        os.Args[1:],
+		Environ:     os.Environ(),
+		Stdin:       os.Stdin,
+		Stdout:      os.Stdout,
+		Stderr:      os.Stderr,
 	}
 	interruptSignals = append([]os.Signal{os.Interrupt}, extraInterruptSignals...)
 )
@@ -50,25 +53,7 @@ var (
 //	  protoplugin.Main(newHandler())
 //	}
 func Main(handler Handler, options ...MainOption) {
-	opts := newOpts()
-	for _, option := range options {
-		option.applyMainOption(opts)
-	}
-	ctx, cancel := withCancelInterruptSignal(context.Background())
-	if err := run(ctx, osEnv, handler, opts); err != nil {
-		exitError := &exec.ExitError{}
-		if errors.As(err, &exitError) {
-			cancel()
-			// Swallow error message - it was printed via os.Stderr redirection.
-			os.Exit(exitError.ExitCode())
-		}
-		if errString := err.Error(); errString != "" {
-			_, _ = fmt.Fprintln(os.Stderr, errString)
-		}
-		cancel()
-		os.Exit(1)
-	}
-	cancel()
+	New(handler, options...).Main()
 }
 
 // Run runs the plugin using the Handler for the given environment.
@@ -82,11 +67,81 @@ func Run(
 	handler Handler,
 	options ...RunOption,
 ) error {
+	return newPluginForRunOptions(handler, options).Run(ctx, env)
+}
+
+// Invoke invokes the Handler in-process for the given CodeGeneratorRequest, returning the resulting
+// CodeGeneratorResponse.
+//
+// This is intended for compilers and other tools that want to execute Handlers without going through
+// stdin and stdout. The CodeGeneratorRequest is validated and the CodeGeneratorResponse is validated and
+// normalized in the same manner as Run. Errors returned from the Handler are returned as-is.
+//
+// The Handler is given a PluginEnv with no environment variables, and with stderr discarded.
+//
+// All RunOptions can be given, however options that only affect the stdio-based protocol, such as WithVersion
+// and WithResponseCompression, have no effect.
+func Invoke(
+	ctx context.Context,
+	handler Handler,
+	codeGeneratorRequest *pluginpb.CodeGeneratorRequest,
+	options ...RunOption,
+) (*pluginpb.CodeGeneratorResponse, error) {
+	return newPluginForRunOptions(handler, options).Invoke(ctx, codeGeneratorRequest)
+}
+
+// ReadRequest reads a serialized CodeGeneratorRequest from the reader.
+//
+// ReadRequest, ExecuteHandler, and WriteResponse are the phases of Run, and are intended for embedders
+// that want to insert custom steps between phases, such as metrics, transforms, or caching, without
+// reimplementing the stdio handling of Run. Calling the three phases in order with the same RunOptions
+// is equivalent to calling Run, except that arguments are not handled.
+//
+// Options that affect reading and unmarshaling, such as WithMaxRequestBytes, WithUnmarshalOptions, and
+// WithExtensionTypeResolver, are applied. All other options have no effect.
+func ReadRequest(reader io.Reader, options ...RunOption) (*pluginpb.CodeGeneratorRequest, error) {
 	opts := newOpts()
 	for _, option := range options {
 		option.applyRunOption(opts)
 	}
-	return run(ctx, env, handler, opts)
+	return readRequest(reader, opts)
+}
+
+// ExecuteHandler invokes the Handler for the CodeGeneratorRequest with the given PluginEnv, returning the
+// resulting CodeGeneratorResponse.
+//
+// This is the same as Invoke, except that the Handler is given the PluginEnv. See ReadRequest for more details
+// on the phases of Run.
+func ExecuteHandler(
+	ctx context.Context,
+	pluginEnv PluginEnv,
+	handler Handler,
+	codeGeneratorRequest *pluginpb.CodeGeneratorRequest,
+	options ...RunOption,
+) (*pluginpb.CodeGeneratorResponse, error) {
+	opts := newOpts()
+	for _, option := range options {
+		option.applyRunOption(opts)
+	}
+	return invoke(ctx, pluginEnv, handler, codeGeneratorRequest, opts)
+}
+
+// WriteResponse writes the serialized CodeGeneratorResponse to the writer.
+//
+// Options that affect marshaling, such as WithDeterministicMarshal, are applied. WithResponseCompression
+// has no effect, as whether to compress depends on the environment of the consumer. All other options have
+// no effect. See ReadRequest for more details on the phases of Run.
+func WriteResponse(writer io.Writer, codeGeneratorResponse *pluginpb.CodeGeneratorResponse, options ...RunOption) error {
+	opts := newOpts()
+	for _, option := range options {
+		option.applyRunOption(opts)
+	}
+	data, err := marshalResponse(codeGeneratorResponse, opts)
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(data)
+	return err
 }
 
 // MainOption is an option for Main.
@@ -146,6 +201,170 @@ func WithLenientValidation(lenientValidateErrorFunc func(error)) RunOption {
 	})
 }
 
+// WithAdditionalSupportedFeatureBits returns a new RunOption that says to accept the given bits within
+// supported_features, in addition to the CodeGeneratorResponse.Features known to this package.
+//
+// See ResponseWriterWithAdditionalSupportedFeatureBits for more details.
+//
+// This option can be passed to Main or Run.
+//
+// The default is to reject all bits that do not correspond to a known CodeGeneratorResponse.Feature.
+func WithAdditionalSupportedFeatureBits(bits uint64) RunOption {
+	return optsFunc(func(opts *opts) {
+		opts.additionalSupportedFeatureBits |= bits
+		opts.responseWriterOptions = append(
+			opts.responseWriterOptions,
+			ResponseWriterWithAdditionalSupportedFeatureBits(bits),
+		)
+	})
+}
+
+// WithIdenticalDuplicateDeduplication returns a new RunOption that says to silently deduplicate files without
+// insertion points that have the same name and identical content.
+//
+// See ResponseWriterWithIdenticalDuplicateDeduplication for more details.
+//
+// This option can be passed to Main or Run.
+//
+// The default is to treat all files with duplicate names as duplicates.
+func WithIdenticalDuplicateDeduplication() RunOption {
+	return optsFunc(func(opts *opts) {
+		opts.responseWriterOptions = append(
+			opts.responseWriterOptions,
+			ResponseWriterWithIdenticalDuplicateDeduplication(),
+		)
+	})
+}
+
+// WithPathRewriter returns a new RunOption that rewrites the names of all files when they are added
+// to the ResponseWriter.
+//
+// See ResponseWriterWithPathRewriter for more details.
+//
+// This option can be passed to Main or Run.
+//
+// The default is to not rewrite names.
+func WithPathRewriter(pathRewriter func(name string) (string, error)) RunOption {
+	return optsFunc(func(opts *opts) {
+		opts.responseWriterOptions = append(
+			opts.responseWriterOptions,
+			ResponseWriterWithPathRewriter(pathRewriter),
+		)
+	})
+}
+
+// WithUTF8Validation returns a new RunOption that validates that the content of all generated files is valid UTF-8.
+//
+// See ResponseWriterWithUTF8Validation for more details.
+//
+// This option can be passed to Main or Run.
+//
+// The default is to not validate content.
+func WithUTF8Validation() RunOption {
+	return optsFunc(func(opts *opts) {
+		opts.responseWriterOptions = append(opts.responseWriterOptions, ResponseWriterWithUTF8Validation())
+	})
+}
+
+// WithLineEndings returns a new RunOption that normalizes the line endings of the content of all generated files.
+//
+// See ResponseWriterWithLineEndings for more details.
+//
+// This option can be passed to Main or Run.
+//
+// The default is to preserve line endings.
+func WithLineEndings(defaultLineEnding LineEnding, extensionToLineEnding map[string]LineEnding) RunOption {
+	return optsFunc(func(opts *opts) {
+		opts.responseWriterOptions = append(
+			opts.responseWriterOptions,
+			ResponseWriterWithLineEndings(defaultLineEnding, extensionToLineEnding),
+		)
+	})
+}
+
+// WithResponseLimits returns a new RunOption that enforces limits on the files added to the response.
+//
+// See ResponseWriterWithLimits for more details on the limits.
+//
+// This option can be passed to Main or Run.
+//
+// The default is to not enforce any limits.
+func WithResponseLimits(maxFiles int, maxTotalBytes int64, maxFileBytes int64) RunOption {
+	return optsFunc(func(opts *opts) {
+		opts.responseWriterOptions = append(
+			opts.responseWriterOptions,
+			ResponseWriterWithLimits(maxFiles, maxTotalBytes, maxFileBytes),
+		)
+	})
+}
+
+// WithFileToGenerateOrder returns a new RunOption that says to return the FileDescriptorProtos from
+// Request.FileDescriptorProtosToGenerate in the order of the file_to_generate field on the CodeGeneratorRequest,
+// regardless of the order of proto_file and source_file_descriptors.
+//
+// Request.FileDescriptorsToGenerate always returns FileDescriptors in the order of file_to_generate. With this
+// option, all accessors for the files to generate iterate in the same order, which generators that emit
+// aggregated outputs can rely on for reproducible output. The CodeGeneratorRequest is validated to have exactly
+// one FileDescriptorProto for each value of file_to_generate, unless WithSkipRequestValidation is specified.
+//
+// This option can be passed to Main or Run.
+//
+// The default is to return FileDescriptorProtos in the order of proto_file, or source_file_descriptors if
+// Request.WithSourceRetentionOptions is used.
+func WithFileToGenerateOrder() RunOption {
+	return optsFunc(func(opts *opts) {
+		opts.fileToGenerateOrder = true
+	})
+}
+
+// WithEagerValidation returns a new RunOption that says to validate generated files as they are added to the
+// ResponseWriter, instead of only after the Handler returns.
+//
+// See ResponseWriterWithEagerValidation for more details.
+//
+// This option can be passed to Main or Run.
+//
+// The default is to validate generated files only after the Handler returns.
+func WithEagerValidation() RunOption {
+	return optsFunc(func(opts *opts) {
+		opts.responseWriterOptions = append(opts.responseWriterOptions, ResponseWriterWithEagerValidation())
+	})
+}
+
+// WithFileNamePortabilityCheck returns a new RunOption that checks that the names of all generated files can be
+// written to all common filesystems.
+//
+// See ResponseWriterWithFileNamePortabilityCheck for more details.
+//
+// This option can be passed to Main or Run.
+//
+// The default is to not check file names for portability.
+func WithFileNamePortabilityCheck(maxFileNameLength int, warningFunc func(error)) RunOption {
+	return optsFunc(func(opts *opts) {
+		opts.responseWriterOptions = append(
+			opts.responseWriterOptions,
+			ResponseWriterWithFileNamePortabilityCheck(maxFileNameLength, warningFunc),
+		)
+	})
+}
+
+// WithInsertionPointTargetCheck returns a new RunOption that checks that every file with an insertion point
+// targets a file that was generated earlier in the response, or is declared in externalTargetNames.
+//
+// See ResponseWriterWithInsertionPointTargetCheck for more details.
+//
+// This option can be passed to Main or Run.
+//
+// The default is to not check the targets of insertion points.
+func WithInsertionPointTargetCheck(externalTargetNames []string, warningFunc func(error)) RunOption {
+	return optsFunc(func(opts *opts) {
+		opts.responseWriterOptions = append(
+			opts.responseWriterOptions,
+			ResponseWriterWithInsertionPointTargetCheck(externalTargetNames, warningFunc),
+		)
+	})
+}
+
 // WithExtensionTypeResolver returns a new RunOption that overrides the default extension resolver when
 // unmarshaling Protobuf messages.
 func WithExtensionTypeResolver(extensionTypeResolver protoregistry.ExtensionTypeResolver) RunOption {
@@ -154,6 +373,289 @@ func WithExtensionTypeResolver(extensionTypeResolver protoregistry.ExtensionType
 	})
 }
 
+// WithUnmarshalOptions returns a new RunOption that overrides the options used when unmarshaling
+// the CodeGeneratorRequest.
+//
+// This can be used to tune unmarshaling for very large CodeGeneratorRequests, for example by setting
+// DiscardUnknown to avoid retaining unknown fields, or by setting RecursionLimit.
+//
+// If the Resolver field is not set, the resolver from WithExtensionTypeResolver is used, if given.
+//
+// This option can be passed to Main or Run.
+//
+// The default is to use the zero value of proto.UnmarshalOptions.
+func WithUnmarshalOptions(unmarshalOptions proto.UnmarshalOptions) RunOption {
+	return optsFunc(func(opts *opts) {
+		opts.unmarshalOptions = unmarshalOptions
+	})
+}
+
+// WithRequestInterceptor returns a new RunOption that will result in the given function being called
+// with the validated Request before the Handler is invoked.
+//
+// This is useful for enforcing schema conventions at generation time, for example requiring that
+// specific custom options are present on all files to generate.
+//
+// If the function returns an error, the Handler will not be invoked, and the error message will be
+// added to the CodeGeneratorResponse via AddError. That is, the plugin will not exit with a non-zero
+// exit code, as a rejection is considered an issue with the input .proto files, not with the plugin.
+//
+// This option can be passed multiple times. Interceptors are called in the order they were given,
+// and the first interceptor that returns an error stops the invocation of any subsequent interceptors.
+//
+// This option can be passed to Main or Run.
+func WithRequestInterceptor(requestInterceptor func(context.Context, Request) error) RunOption {
+	return optsFunc(func(opts *opts) {
+		opts.requestInterceptors = append(opts.requestInterceptors, requestInterceptor)
+	})
+}
+
+// WithRequestTransform returns a new RunOption that will result in the given function being called
+// to transform the CodeGeneratorRequest before the Request is created.
+//
+// This is useful for wrappers that need to uniformly rewrite CodeGeneratorRequests, for example to
+// rewrite packages, strip files, or inject parameters.
+//
+// The function is given the validated CodeGeneratorRequest, and may either modify it in place and return it,
+// or return a new CodeGeneratorRequest. If Invoke or ExecuteHandler is used, the CodeGeneratorRequest given
+// to the function is the CodeGeneratorRequest given by the caller. The returned CodeGeneratorRequest is
+// validated again, unless WithSkipRequestValidation is specified.
+//
+// If the function returns an error, the Handler will not be invoked, and the error is returned. If the function
+// returns a nil CodeGeneratorRequest, an error is returned.
+//
+// This option can be passed multiple times. Transforms are called in the order they were given, each with
+// the CodeGeneratorRequest returned by the previous transform. In batch mode, the transforms are called for
+// each CodeGeneratorRequest.
+//
+// This option can be passed to Main or Run.
+func WithRequestTransform(
+	requestTransform func(*pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorRequest, error),
+) RunOption {
+	return optsFunc(func(opts *opts) {
+		opts.requestTransforms = append(opts.requestTransforms, requestTransform)
+	})
+}
+
+// WithResponseTransform returns a new RunOption that will result in the given function being called
+// to transform the CodeGeneratorResponse after it is built from the ResponseWriter, and before it is marshaled.
+//
+// This is useful for proxies and wrappers that need to uniformly rewrite CodeGeneratorResponses without
+// modifying the Handler, for example to rename output paths, inject license headers, or filter files.
+//
+// The function is given the validated CodeGeneratorResponse, and may either modify it in place and return it,
+// or return a new CodeGeneratorResponse. The returned CodeGeneratorResponse is validated again. Note that
+// the options that only apply to the ResponseWriter, such as WithLineEndings or WithFileNamePortabilityCheck,
+// are not applied to the transformed CodeGeneratorResponse.
+//
+// If the function returns an error, the error is returned. If the function returns a nil
+// CodeGeneratorResponse, an error is returned.
+//
+// This option can be passed multiple times. Transforms are called in the order they were given, each with
+// the CodeGeneratorResponse returned by the previous transform. In batch mode, the transforms are called for
+// each CodeGeneratorResponse.
+//
+// This option can be passed to Main or Run.
+func WithResponseTransform(
+	responseTransform func(*pluginpb.CodeGeneratorResponse) (*pluginpb.CodeGeneratorResponse, error),
+) RunOption {
+	return optsFunc(func(opts *opts) {
+		opts.responseTransforms = append(opts.responseTransforms, responseTransform)
+	})
+}
+
+// WithMinimumCompilerVersion returns a new RunOption that will result in the plugin failing with an error
+// added to the CodeGeneratorResponse if the compiler that invoked the plugin is older than the given version.
+//
+// The reason is included in the error message, and should describe why the version is required, for
+// example "for source-retention options". The reason may be empty. The Suffix of the minimum version is
+// not considered, see CompilerVersion.AtLeast.
+//
+// The resulting error message is of the form "this plugin requires a compiler version >= 27.0 for
+// source-retention options, but was invoked with protoc 26.1". If the compiler_version field was not
+// present on the CodeGeneratorRequest, the check fails as well, as the compiler is assumed to be too old
+// to provide it.
+//
+// This is a single CompilerRequirement, and the error is a *CompilerRequirementsError. To declare minimum
+// compiler versions for several features at once, use WithCompilerRequirements.
+//
+// This is implemented as a request interceptor, see WithRequestInterceptor for more details.
+//
+// This option can be passed to Main or Run.
+func WithMinimumCompilerVersion(minimum CompilerVersion, reason string) RunOption {
+	return WithRequestInterceptor(
+		func(_ context.Context, request Request) error {
+			return CheckCompilerRequirements(request, CompilerRequirement{Minimum: minimum, reason: reason})
+		},
+	)
+}
+
+// WithEnvAllowlist returns a new RunOption that will result in the Handler only observing the given
+// environment variables via PluginEnv.
+//
+// All other environment variables are removed from PluginEnv.Environ before the Handler is invoked.
+// This is useful for sandboxed execution, where plugins should not depend on the environment they
+// happen to be invoked within. The environment variables that control the plugin framework itself,
+// such as ResponseCompressionEnvKey, are still respected.
+//
+// This option can be passed multiple times, in which case the allowlists are combined.
+//
+// This option can be passed to Main or Run.
+//
+// The default is to pass all environment variables to the Handler.
+func WithEnvAllowlist(keys ...string) RunOption {
+	return optsFunc(func(opts *opts) {
+		if opts.envAllowlist == nil {
+			opts.envAllowlist = make(map[string]struct{}, len(keys))
+		}
+		for _, key := range keys {
+			opts.envAllowlist[key] = struct{}{}
+		}
+	})
+}
+
+// WithPluginName returns a new RunOption that sets the name of the plugin.
+//
+// The plugin name is used as a prefix for messages written with PluginEnv.Logf, PluginEnv.Warnf, and
+// PluginEnv.Errorf. For example, with a plugin name of "protoc-gen-foo", PluginEnv.Warnf("bad") writes
+// "protoc-gen-foo: warning: bad" to stderr. The plugin name is also returned from PluginEnv.Name, and
+// is used to scope the directory returned by PluginEnv.CacheDir.
+//
+// This option can be passed to Main or Run.
+//
+// The default is to use the program name from Env.ProgramName for PluginEnv.Name and as the prefix for
+// messages, and for PluginEnv.CacheDir to return an error.
+func WithPluginName(pluginName string) RunOption {
+	return optsFunc(func(opts *opts) {
+		opts.pluginName = pluginName
+	})
+}
+
+// WithColorizedLogs returns a new RunOption that will result in the labels of messages written with
+// PluginEnv.Warnf and PluginEnv.Errorf being colorized with ANSI escape codes if stderr is a terminal.
+//
+// Colorization is never applied if the NO_COLOR environment variable is set to a non-empty value,
+// see https://no-color.org.
+//
+// This option can be passed to Main or Run.
+//
+// The default is to never colorize messages.
+func WithColorizedLogs() RunOption {
+	return optsFunc(func(opts *opts) {
+		opts.colorizedLogs = true
+	})
+}
+
+// WithResponseCompression returns a new RunOption that will result in the serialized CodeGeneratorResponse
+// being gzip-compressed if the consumer of the plugin indicates that it supports compression.
+//
+// Consumers indicate support by setting the environment variable PROTOPLUGIN_RESPONSE_COMPRESSION=gzip
+// (see ResponseCompressionEnvKey) when invoking the plugin. If this environment variable is not set, the
+// CodeGeneratorResponse is written uncompressed, as protoc and buf do not support compressed responses.
+// Consumers can use ReadCodeGeneratorResponse to read both compressed and uncompressed responses.
+//
+// This is useful for plugins that emit large amounts of generated code in remote-execution environments.
+//
+// This option can be passed to Main or Run.
+//
+// The default is to never compress CodeGeneratorResponses.
+func WithResponseCompression() RunOption {
+	return optsFunc(func(opts *opts) {
+		opts.responseCompression = true
+	})
+}
+
+// WithDescriptorVersionSkewValidation returns a new RunOption that will result in the CodeGeneratorRequest
+// being validated against version skew of google/protobuf/descriptor.proto.
+//
+// If google/protobuf/descriptor.proto is contained within the proto_file field of the CodeGeneratorRequest,
+// this validates that it defines all fields and enum values used by the FileDescriptorProtos in the
+// CodeGeneratorRequest. For example, if a file uses editions features, but the descriptor.proto given to
+// the compiler was older than editions, the features would be unknown to anything interpreting options
+// using the descriptor.proto in the CodeGeneratorRequest. This results in an actionable error instead of
+// unknown-field behavior downstream.
+//
+// If validation fails, a *RequestValidationError is returned.
+//
+// This option can be passed to Main or Run.
+//
+// The default is to not perform this validation.
+func WithDescriptorVersionSkewValidation() RunOption {
+	return optsFunc(func(opts *opts) {
+		opts.descriptorVersionSkewValidation = true
+	})
+}
+
+// WithDeterministicMarshal returns a new RunOption that will result in the CodeGeneratorResponse being
+// marshaled deterministically, as with proto.MarshalOptions{Deterministic: true}.
+//
+// This is useful for wrapping build systems that use the serialized CodeGeneratorResponse as a cache key,
+// as byte-identical responses are produced for identical inputs by the same binary.
+//
+// This option can be passed to Main or Run.
+//
+// The default is to marshal with the default proto.MarshalOptions.
+func WithDeterministicMarshal() RunOption {
+	return optsFunc(func(opts *opts) {
+		opts.deterministicMarshal = true
+	})
+}
+
+// WithMaxRequestBytes returns a new RunOption that bounds the size of serialized CodeGeneratorRequests
+// read from stdin to maxRequestBytes.
+//
+// If a CodeGeneratorRequest exceeds the limit, the plugin fails with a *RequestTooLargeError instead of
+// attempting to buffer and unmarshal it. The input is read incrementally, so reading stops as soon as the limit is
+// exceeded. If stdin is a regular file, such as when stdin is redirected from a file, the request is rejected
+// before any of it is read. In batch mode, the limit applies to each CodeGeneratorRequest. This protects CI
+// runners and other shared environments from malformed or adversarial CodeGeneratorRequests.
+//
+// A value of zero or less means that no limit is enforced.
+//
+// This option can be passed to Main or Run, and is also applied by ReadRequest.
+//
+// The default is to not limit the size of CodeGeneratorRequests.
+func WithMaxRequestBytes(maxRequestBytes int64) RunOption {
+	return optsFunc(func(opts *opts) {
+		opts.maxRequestBytes = maxRequestBytes
+	})
+}
+
+// WithSkipRequestValidation returns a new RunOption that will result in the CodeGeneratorRequest
+// not being validated before it is given to the Handler.
+//
+// This should only be used by hosts that have already validated the CodeGeneratorRequest, for example
+// when invoking plugins in-process within trusted pipelines. See NewRequestWithoutValidation for more details.
+//
+// This option can be passed to Main or Run.
+//
+// The default is to validate all CodeGeneratorRequests.
+func WithSkipRequestValidation() RunOption {
+	return optsFunc(func(opts *opts) {
+		opts.skipRequestValidation = true
+	})
+}
+
+// WithExitCodeFunc returns a new MainOption that will result in the given function being called to
+// determine the exit code when the plugin fails with an error.
+//
+// This allows plugin authors to map specific errors to distinct exit codes that build systems can react to,
+// for example to distinguish between Handler errors and I/O errors.
+//
+// If the function returns a value less than or equal to zero, an exit code of 1 will be used, as the
+// plugin must always exit with a non-zero exit code on error. The error message is printed to stderr
+// regardless of the exit code.
+//
+// This option can only be passed to Main, as Run returns errors to the caller directly.
+//
+// The default is to exit with the exit code of the error if the error is an *exec.ExitError, and to exit
+// with an exit code of 1 otherwise.
+func WithExitCodeFunc(exitCodeFunc func(err error) int) MainOption {
+	return mainOptsFunc(func(opts *opts) {
+		opts.exitCodeFunc = exitCodeFunc
+	})
+}
+
 /// *** PRIVATE ***
 
 func run(
@@ -161,6 +663,20 @@ func run(
 	env Env,
 	handler Handler,
 	opts *opts,
+) error {
+	opts, err := withEnvDefaults(env, opts)
+	if err != nil {
+		return err
+	}
+	return runWithEnvDefaults(ctx, env, handler, opts)
+}
+
+// runWithEnvDefaults is run for opts that have had the environment defaults applied via withEnvDefaults.
+func runWithEnvDefaults(
+	ctx context.Context,
+	env Env,
+	handler Handler,
+	opts *opts,
 ) error {
 	switch len(env.Args) {
 	case 0:
@@ -174,36 +690,61 @@ func run(
 		return newUnknownArgumentsError(env.Args)
 	}
 
-	input, err := io.ReadAll(env.Stdin)
+	env, closePipeTransport, err := openPipeTransport(env)
 	if err != nil {
 		return err
 	}
-	codeGeneratorRequest := &pluginpb.CodeGeneratorRequest{}
-	unmarshalOptions := proto.UnmarshalOptions{Resolver: opts.extensionTypeResolver}
-	if err := unmarshalOptions.Unmarshal(input, codeGeneratorRequest); err != nil {
-		return err
+	if err := runRequest(ctx, env, handler, opts); err != nil {
+		return errors.Join(err, closePipeTransport())
+	}
+	return closePipeTransport()
+}
+
+// withEnvDefaults returns a copy of opts with the environment defaults applied.
+//
+// opts is not modified, as opts may be shared by a Plugin that is run many times.
+func withEnvDefaults(env Env, opts *opts) (*opts, error) {
+	runOpts := *opts
+	if err := applyEnvDefaults(env, &runOpts); err != nil {
+		return nil, err
+	}
+	return &runOpts, nil
+}
+
+// runRequest reads the CodeGeneratorRequest from stdin, invokes the Handler, and writes the
+// CodeGeneratorResponse to stdout.
+func runRequest(
+	ctx context.Context,
+	env Env,
+	handler Handler,
+	opts *opts,
+) error {
+	if isBatchMode(env.Environ, opts.batchMode) {
+		return runBatch(ctx, env, handler, opts)
 	}
-	request, err := NewRequest(codeGeneratorRequest)
+	codeGeneratorRequest, err := readRequest(env.Stdin, opts)
 	if err != nil {
 		return err
 	}
-	responseWriter := NewResponseWriter(ResponseWriterWithLenientValidation(opts.lenientValidateErrorFunc))
-	if err := handler.Handle(
+	codeGeneratorResponse, err := invoke(
 		ctx,
 		PluginEnv{
-			Environ: env.Environ,
-			Stderr:  env.Stderr,
+			ProgramName: env.ProgramName,
+			Environ:     env.Environ,
+			Stderr:      env.Stderr,
 		},
-		responseWriter,
-		request,
-	); err != nil {
+		handler,
+		codeGeneratorRequest,
+		opts,
+	)
+	if err != nil {
 		return err
 	}
-	codeGeneratorResponse, err := responseWriter.ToCodeGeneratorResponse()
+	data, err := marshalResponse(codeGeneratorResponse, opts)
 	if err != nil {
 		return err
 	}
-	data, err := proto.Marshal(codeGeneratorResponse)
+	data, err = maybeCompressResponseData(data, env.Environ, opts.responseCompression)
 	if err != nil {
 		return err
 	}
@@ -211,6 +752,295 @@ func run(
 	return err
 }
 
+// readRequest reads and unmarshals a CodeGeneratorRequest from the reader.
+func readRequest(reader io.Reader, opts *opts) (*pluginpb.CodeGeneratorRequest, error) {
+	input, err := readInput(reader, opts.maxRequestBytes)
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalRequest(input, opts)
+}
+
+// invoke invokes the Handler for the CodeGeneratorRequest, returning the resulting CodeGeneratorResponse.
+func invoke(
+	ctx context.Context,
+	pluginEnv PluginEnv,
+	handler Handler,
+	codeGeneratorRequest *pluginpb.CodeGeneratorRequest,
+	opts *opts,
+) (*pluginpb.CodeGeneratorResponse, error) {
+	pluginEnv.pluginName = opts.pluginName
+	pluginEnv.jsonDiagnostics = opts.jsonDiagnostics
+	if opts.colorizedLogs && isTerminal(pluginEnv.Stderr) {
+		if noColor, _ := lookupEnv(pluginEnv.Environ, "NO_COLOR"); noColor == "" {
+			pluginEnv.colorize = true
+		}
+	}
+	if opts.envAllowlist != nil {
+		pluginEnv.Environ = filterEnviron(pluginEnv.Environ, opts.envAllowlist)
+	}
+	var outputPrefix string
+	if opts.outputPrefix != "" {
+		var err error
+		outputPrefix, err = normalizeOutputPrefix(opts.outputPrefix)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !opts.skipRequestValidation {
+		if err := validateCodeGeneratorRequest(codeGeneratorRequest); err != nil {
+			return nil, newRequestValidationError(err)
+		}
+	}
+	if len(opts.requestTransforms) > 0 {
+		var err error
+		codeGeneratorRequest, err = transformRequest(codeGeneratorRequest, opts.requestTransforms)
+		if err != nil {
+			return nil, err
+		}
+		if !opts.skipRequestValidation {
+			if err := validateCodeGeneratorRequest(codeGeneratorRequest); err != nil {
+				return nil, newRequestValidationError(err)
+			}
+		}
+	}
+	if opts.extensionTypeDiscovery {
+		var err error
+		codeGeneratorRequest, err = discoverExtensionTypes(codeGeneratorRequest, opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if opts.descriptorInterning {
+		internSourceFileDescriptors(codeGeneratorRequest)
+	}
+	if opts.groupFieldPolicy == GroupFieldPolicyConvert {
+		codeGeneratorRequest = convertGroupFields(codeGeneratorRequest)
+	}
+	pluginEnv.nowFunc = opts.nowFunc
+	pluginEnv.randSource = newRequestRandSource(codeGeneratorRequest, opts.randSeed)
+	pluginEnv.metrics = newMetrics()
+	request := newRequest(codeGeneratorRequest)
+	request.fileToGenerateOrder = opts.fileToGenerateOrder
+	request.descriptorCache = opts.descriptorCache
+	request.sourceRetentionOptionsUnavailableWarning = newOnceWarning(
+		func(err error) { pluginEnv.Warnf("%v", err) },
+	)
+	var fileFilterErr error
+	if opts.fileFilterParameters {
+		request, fileFilterErr = applyFileFilterParameters(request)
+	}
+	if opts.skipWellKnownTypes {
+		request = applySkipWellKnownTypes(request)
+	}
+	if opts.descriptorVersionSkewValidation {
+		if err := validateDescriptorVersionSkew(codeGeneratorRequest); err != nil {
+			return nil, newRequestValidationError(err)
+		}
+	}
+	responseWriter := NewResponseWriter(
+		append(
+			[]ResponseWriterOption{
+				ResponseWriterWithLenientValidation(opts.lenientValidateErrorFunc),
+				ResponseWriterWithSealedWriteFunc(func(err error) { pluginEnv.Errorf("%v", err) }),
+			},
+			opts.responseWriterOptions...,
+		)...,
+	)
+	var dryRunResponseWriter *dryRunResponseWriter
+	if opts.dryRunReportFunc != nil {
+		dryRunResponseWriter = newDryRunResponseWriter(responseWriter)
+		responseWriter = dryRunResponseWriter
+	}
+	var requestFingerprint *requestFingerprint
+	if opts.frozenRequest {
+		var err error
+		requestFingerprint, err = newRequestFingerprint(codeGeneratorRequest)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if fileFilterErr != nil {
+		responseWriter.AddError(fileFilterErr.Error())
+	} else if err := applyCapabilities(handler, responseWriter, request); err != nil {
+		responseWriter.AddError(err.Error())
+	} else if err := checkGroupFields(pluginEnv, request, opts.groupFieldPolicy); err != nil {
+		responseWriter.AddError(err.Error())
+	} else if err := interceptRequest(ctx, request, opts.requestInterceptors); err != nil {
+		responseWriter.AddError(err.Error())
+	} else if err := enterSandboxes(ctx, pluginEnv, opts.sandboxes); err != nil {
+		responseWriter.seal()
+		return nil, err
+	} else if err := handleWithMetrics(ctx, handler, pluginEnv, responseWriter, request); err != nil {
+		responseWriter.seal()
+		return nil, err
+	}
+	// Any writes from goroutines that outlived the Handler are dropped from here on.
+	responseWriter.seal()
+	if requestFingerprint != nil {
+		if err := requestFingerprint.check(codeGeneratorRequest); err != nil {
+			return nil, err
+		}
+	}
+	if opts.unusedParameterWarnings {
+		warnUnusedParameters(pluginEnv, request)
+	}
+	if dryRunResponseWriter != nil {
+		dryRunReport := dryRunResponseWriter.report()
+		if outputPrefix != "" {
+			for i := range dryRunReport.Files {
+				dryRunReport.Files[i].Name = outputPrefix + dryRunReport.Files[i].Name
+			}
+		}
+		if err := opts.dryRunReportFunc(dryRunReport); err != nil {
+			return nil, err
+		}
+	}
+	codeGeneratorResponse, err := responseWriter.ToCodeGeneratorResponse()
+	if err != nil {
+		return nil, err
+	}
+	checkEmptyResponse(pluginEnv, codeGeneratorResponse, opts.emptyResponsePolicy)
+	if outputPrefix != "" {
+		applyOutputPrefix(codeGeneratorResponse, outputPrefix)
+	}
+	if len(opts.responseTransforms) > 0 {
+		codeGeneratorResponse, err = transformResponse(codeGeneratorResponse, opts.responseTransforms)
+		if err != nil {
+			return nil, err
+		}
+		if err := validateAndNormalizeCodeGeneratorResponse(
+			codeGeneratorResponse,
+			opts.lenientValidateErrorFunc,
+			false,
+			opts.additionalSupportedFeatureBits,
+		); err != nil {
+			return nil, newResponseValidationError(err)
+		}
+	}
+	if len(opts.metricsExporters) > 0 {
+		if err := exportMetrics(ctx, pluginEnv, codeGeneratorResponse, opts.metricsExporters); err != nil {
+			return nil, err
+		}
+	}
+	return codeGeneratorResponse, nil
+}
+
+// readInput reads all of the input from the reader.
+//
+// If the reader is a regular file, such as when stdin is redirected from a file, the buffer is
+// sized up front to avoid repeated growth and copying for large CodeGeneratorRequests.
+//
+// If maxBytes is greater than zero, a *RequestTooLargeError is returned as soon as the input is known
+// to exceed maxBytes. For regular files, this is before any input is read.
+func readInput(reader io.Reader, maxBytes int64) ([]byte, error) {
+	size := regularFileSize(reader)
+	if maxBytes > 0 {
+		if size > maxBytes {
+			return nil, newRequestTooLargeError(maxBytes)
+		}
+		// Read at most one byte more than the limit, so that we can detect that the limit was exceeded
+		// without reading the remainder of the input.
+		reader = io.LimitReader(reader, maxBytes+1)
+	}
+	var data []byte
+	if size > 0 {
+		buffer := bytes.NewBuffer(make([]byte, 0, size+bytes.MinRead))
+		if _, err := buffer.ReadFrom(reader); err != nil {
+			return nil, err
+		}
+		data = buffer.Bytes()
+	} else {
+		var err error
+		data, err = io.ReadAll(reader)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if maxBytes > 0 && int64(len(data)) > maxBytes {
+		return nil, newRequestTooLargeError(maxBytes)
+	}
+	return data, nil
+}
+
+// regularFileSize returns the size of the reader if it is a regular file, or -1 otherwise.
+func regularFileSize(reader io.Reader) int64 {
+	statReader, ok := reader.(interface{ Stat() (os.FileInfo, error) })
+	if !ok {
+		return -1
+	}
+	fileInfo, err := statReader.Stat()
+	if err != nil || !fileInfo.Mode().IsRegular() {
+		return -1
+	}
+	return fileInfo.Size()
+}
+
+// getExitCode returns the exit code Main should exit with for the error.
+func getExitCode(err error, exitCodeFunc func(error) int) int {
+	if exitCodeFunc != nil {
+		if exitCode := exitCodeFunc(err); exitCode > 0 {
+			return exitCode
+		}
+		return 1
+	}
+	exitError := &exec.ExitError{}
+	if errors.As(err, &exitError) {
+		return exitError.ExitCode()
+	}
+	return 1
+}
+
+// transformRequest calls each request transform in order, returning the transformed CodeGeneratorRequest.
+func transformRequest(
+	codeGeneratorRequest *pluginpb.CodeGeneratorRequest,
+	requestTransforms []func(*pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorRequest, error),
+) (*pluginpb.CodeGeneratorRequest, error) {
+	for _, requestTransform := range requestTransforms {
+		var err error
+		codeGeneratorRequest, err = requestTransform(codeGeneratorRequest)
+		if err != nil {
+			return nil, err
+		}
+		if codeGeneratorRequest == nil {
+			return nil, errors.New("request transform returned a nil CodeGeneratorRequest")
+		}
+	}
+	return codeGeneratorRequest, nil
+}
+
+// transformResponse calls each response transform in order, returning the transformed CodeGeneratorResponse.
+func transformResponse(
+	codeGeneratorResponse *pluginpb.CodeGeneratorResponse,
+	responseTransforms []func(*pluginpb.CodeGeneratorResponse) (*pluginpb.CodeGeneratorResponse, error),
+) (*pluginpb.CodeGeneratorResponse, error) {
+	for _, responseTransform := range responseTransforms {
+		var err error
+		codeGeneratorResponse, err = responseTransform(codeGeneratorResponse)
+		if err != nil {
+			return nil, err
+		}
+		if codeGeneratorResponse == nil {
+			return nil, errors.New("response transform returned a nil CodeGeneratorResponse")
+		}
+	}
+	return codeGeneratorResponse, nil
+}
+
+// interceptRequest calls each request interceptor in order, returning the first error.
+func interceptRequest(
+	ctx context.Context,
+	request Request,
+	requestInterceptors []func(context.Context, Request) error,
+) error {
+	for _, requestInterceptor := range requestInterceptors {
+		if err := requestInterceptor(ctx, request); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // withCancelInterruptSignal returns a context that is cancelled if interrupt signals are sent.
 func withCancelInterruptSignal(ctx context.Context) (context.Context, context.CancelFunc) {
 	interruptSignalC, closer := newInterruptSignalChannel()
@@ -236,9 +1066,43 @@ func newInterruptSignalChannel() (<-chan os.Signal, func()) {
 }
 
 type opts struct {
-	version                  string
-	lenientValidateErrorFunc func(error)
-	extensionTypeResolver    protoregistry.ExtensionTypeResolver
+	version                         string
+	lenientValidateErrorFunc        func(error)
+	extensionTypeResolver           protoregistry.ExtensionTypeResolver
+	unmarshalOptions                proto.UnmarshalOptions
+	extensionTypeDiscovery          bool
+	requestInterceptors             []func(context.Context, Request) error
+	requestTransforms               []func(*pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorRequest, error)
+	responseTransforms              []func(*pluginpb.CodeGeneratorResponse) (*pluginpb.CodeGeneratorResponse, error)
+	responseCompression             bool
+	batchMode                       bool
+	fileToGenerateOrder             bool
+	fileFilterParameters            bool
+	skipWellKnownTypes              bool
+	deterministicMarshal            bool
+	skipRequestValidation           bool
+	descriptorVersionSkewValidation bool
+	dryRunReportFunc                func(*DryRunReport) error
+	responseWriterOptions           []ResponseWriterOption
+	exitCodeFunc                    func(error) int
+	envAllowlist                    map[string]struct{}
+	pluginName                      string
+	colorizedLogs                   bool
+	envDefaultsDisabled             bool
+	jsonDiagnostics                 bool
+	maxRequestBytes                 int64
+	sandboxes                       []Sandbox
+	descriptorInterning             bool
+	frozenRequest                   bool
+	unusedParameterWarnings         bool
+	nowFunc                         func() time.Time
+	randSeed                        *int64
+	metricsExporters                []MetricsExporter
+	descriptorCache                 *DescriptorCache
+	additionalSupportedFeatureBits  uint64
+	groupFieldPolicy                GroupFieldPolicy
+	outputPrefix                    string
+	emptyResponsePolicy             EmptyResponsePolicy
 }
 
 func newOpts() *opts {
@@ -254,3 +1118,9 @@ func (f optsFunc) applyMainOption(opts *opts) {
 func (f optsFunc) applyRunOption(opts *opts) {
 	f(opts)
 }
+
+type mainOptsFunc func(*opts)
+
+func (f mainOptsFunc) applyMainOption(opts *opts) {
+	f(opts)
+}
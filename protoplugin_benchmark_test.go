@@ -0,0 +1,54 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin_test
+
+import (
+	"testing"
+
+	"github.com/bufbuild/protoplugin"
+	"github.com/bufbuild/protoplugin/protoplugintest"
+	"github.com/stretchr/testify/require"
+)
+
+func BenchmarkNewRequest(b *testing.B) {
+	codeGeneratorRequest := protoplugintest.NewSyntheticCodeGeneratorRequest(1000, 10, 10)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := protoplugin.NewRequest(codeGeneratorRequest)
+		require.NoError(b, err)
+	}
+}
+
+func BenchmarkFileDescriptorProtosToGenerate(b *testing.B) {
+	request, err := protoplugin.NewRequest(protoplugintest.NewSyntheticCodeGeneratorRequest(1000, 10, 10))
+	require.NoError(b, err)
+	request, err = request.WithSourceRetentionOptions()
+	require.NoError(b, err)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = request.FileDescriptorProtosToGenerate()
+		_ = request.AllFileDescriptorProtos()
+	}
+}
+
+func BenchmarkAllFiles(b *testing.B) {
+	request, err := protoplugin.NewRequest(protoplugintest.NewSyntheticCodeGeneratorRequest(100, 10, 10))
+	require.NoError(b, err)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := request.AllFiles()
+		require.NoError(b, err)
+	}
+}
@@ -17,8 +17,12 @@ package protoplugin
 import (
 	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"io"
 	"io/fs"
+	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 	"testing"
@@ -27,6 +31,7 @@ import (
 	"github.com/bufbuild/protocompile"
 	"github.com/bufbuild/protocompile/protoutil"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoregistry"
 	"google.golang.org/protobuf/types/descriptorpb"
@@ -92,7 +97,7 @@ func TestWithVersionOption(t *testing.T) {
 		return stdout.String(), err
 	}
 
-	var unknownArgumentsError *unknownArgumentsError
+	var unknownArgumentsError *UnknownArgumentsError
 	_, err := run([]string{"--unsupported"})
 	require.ErrorAs(t, err, &unknownArgumentsError)
 	_, err = run([]string{"--unsupported"}, WithVersion("0.0.1"))
@@ -201,6 +206,669 @@ func TestWithExtensionTypeResolverOption(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestWithRequestInterceptorOption(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	fileDescriptorProtos, err := compile(ctx, map[string][]byte{
+		"a.proto": []byte(`syntax = "proto3"; package foo; message A {}`),
+	})
+	require.NoError(t, err)
+	codeGeneratorRequestData, err := proto.Marshal(
+		&pluginpb.CodeGeneratorRequest{
+			FileToGenerate: []string{"a.proto"},
+			ProtoFile:      fileDescriptorProtos,
+		},
+	)
+	require.NoError(t, err)
+
+	run := func(runOptions ...RunOption) (*pluginpb.CodeGeneratorResponse, bool) {
+		var handled bool
+		stdout := bytes.NewBuffer(nil)
+		err := Run(
+			ctx,
+			Env{
+				Stdin:  bytes.NewReader(codeGeneratorRequestData),
+				Stdout: stdout,
+				Stderr: io.Discard,
+			},
+			HandlerFunc(func(_ context.Context, _ PluginEnv, _ ResponseWriter, _ Request) error {
+				handled = true
+				return nil
+			}),
+			runOptions...,
+		)
+		require.NoError(t, err)
+		codeGeneratorResponse := &pluginpb.CodeGeneratorResponse{}
+		require.NoError(t, proto.Unmarshal(stdout.Bytes(), codeGeneratorResponse))
+		return codeGeneratorResponse, handled
+	}
+
+	var intercepted []string
+	accept := func(name string) RunOption {
+		return WithRequestInterceptor(func(_ context.Context, request Request) error {
+			require.Len(t, request.FileDescriptorProtosToGenerate(), 1)
+			intercepted = append(intercepted, name)
+			return nil
+		})
+	}
+	reject := func(name string) RunOption {
+		return WithRequestInterceptor(func(_ context.Context, _ Request) error {
+			intercepted = append(intercepted, name)
+			return errors.New(name + " rejected")
+		})
+	}
+
+	codeGeneratorResponse, handled := run(accept("first"), accept("second"))
+	require.True(t, handled)
+	require.Nil(t, codeGeneratorResponse.Error)
+	require.Equal(t, []string{"first", "second"}, intercepted)
+
+	intercepted = nil
+	codeGeneratorResponse, handled = run(accept("first"), reject("second"), accept("third"))
+	require.False(t, handled)
+	require.Equal(t, "second rejected", codeGeneratorResponse.GetError())
+	require.Equal(t, []string{"first", "second"}, intercepted)
+}
+
+func TestWithRequestTransformOption(t *testing.T) {
+	t.Parallel()
+
+	newCodeGeneratorRequest := func() *pluginpb.CodeGeneratorRequest {
+		return &pluginpb.CodeGeneratorRequest{
+			FileToGenerate: []string{"a.proto", "b.proto"},
+			ProtoFile: []*descriptorpb.FileDescriptorProto{
+				{
+					Name:   proto.String("a.proto"),
+					Syntax: proto.String("proto3"),
+				},
+				{
+					Name:   proto.String("b.proto"),
+					Syntax: proto.String("proto3"),
+				},
+			},
+		}
+	}
+	invoke := func(runOptions ...RunOption) ([]string, string, error) {
+		var filesToGenerate []string
+		var parameter string
+		_, err := Invoke(
+			context.Background(),
+			HandlerFunc(func(_ context.Context, _ PluginEnv, _ ResponseWriter, request Request) error {
+				for _, fileDescriptorProto := range request.FileDescriptorProtosToGenerate() {
+					filesToGenerate = append(filesToGenerate, fileDescriptorProto.GetName())
+				}
+				parameter = request.Parameter()
+				return nil
+			}),
+			newCodeGeneratorRequest(),
+			runOptions...,
+		)
+		return filesToGenerate, parameter, err
+	}
+
+	stripB := WithRequestTransform(
+		func(codeGeneratorRequest *pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorRequest, error) {
+			codeGeneratorRequest.FileToGenerate = []string{"a.proto"}
+			return codeGeneratorRequest, nil
+		},
+	)
+	injectParameter := WithRequestTransform(
+		func(codeGeneratorRequest *pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorRequest, error) {
+			require.Equal(t, []string{"a.proto"}, codeGeneratorRequest.GetFileToGenerate())
+			newCodeGeneratorRequest := proto.Clone(codeGeneratorRequest).(*pluginpb.CodeGeneratorRequest)
+			newCodeGeneratorRequest.Parameter = proto.String("foo=bar")
+			return newCodeGeneratorRequest, nil
+		},
+	)
+	filesToGenerate, parameter, err := invoke(stripB, injectParameter)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a.proto"}, filesToGenerate)
+	require.Equal(t, "foo=bar", parameter)
+
+	transformErr := errors.New("transform")
+	_, _, err = invoke(
+		WithRequestTransform(
+			func(*pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorRequest, error) {
+				return nil, transformErr
+			},
+		),
+	)
+	require.ErrorIs(t, err, transformErr)
+
+	_, _, err = invoke(
+		WithRequestTransform(
+			func(*pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorRequest, error) {
+				return nil, nil
+			},
+		),
+	)
+	require.Error(t, err)
+
+	// The transformed CodeGeneratorRequest is validated.
+	_, _, err = invoke(
+		WithRequestTransform(
+			func(codeGeneratorRequest *pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorRequest, error) {
+				codeGeneratorRequest.FileToGenerate = []string{"c.proto"}
+				return codeGeneratorRequest, nil
+			},
+		),
+	)
+	var requestValidationError *RequestValidationError
+	require.ErrorAs(t, err, &requestValidationError)
+}
+
+func TestWithResponseTransformOption(t *testing.T) {
+	t.Parallel()
+
+	codeGeneratorRequest := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{"a.proto"},
+		ProtoFile: []*descriptorpb.FileDescriptorProto{
+			{
+				Name:   proto.String("a.proto"),
+				Syntax: proto.String("proto3"),
+			},
+		},
+	}
+	handler := HandlerFunc(func(_ context.Context, _ PluginEnv, responseWriter ResponseWriter, _ Request) error {
+		responseWriter.AddFile("a.txt", "a\n")
+		responseWriter.AddFile("b.txt", "b\n")
+		return nil
+	})
+
+	renameFiles := WithResponseTransform(
+		func(codeGeneratorResponse *pluginpb.CodeGeneratorResponse) (*pluginpb.CodeGeneratorResponse, error) {
+			for _, file := range codeGeneratorResponse.GetFile() {
+				file.Name = proto.String("gen/" + file.GetName())
+			}
+			return codeGeneratorResponse, nil
+		},
+	)
+	addHeader := WithResponseTransform(
+		func(codeGeneratorResponse *pluginpb.CodeGeneratorResponse) (*pluginpb.CodeGeneratorResponse, error) {
+			for _, file := range codeGeneratorResponse.GetFile() {
+				require.True(t, strings.HasPrefix(file.GetName(), "gen/"))
+				file.Content = proto.String("// Header.\n" + file.GetContent())
+			}
+			return codeGeneratorResponse, nil
+		},
+	)
+	codeGeneratorResponse, err := Invoke(context.Background(), handler, codeGeneratorRequest, renameFiles, addHeader)
+	require.NoError(t, err)
+	require.Len(t, codeGeneratorResponse.GetFile(), 2)
+	require.Equal(t, "gen/a.txt", codeGeneratorResponse.GetFile()[0].GetName())
+	require.Equal(t, "// Header.\na\n", codeGeneratorResponse.GetFile()[0].GetContent())
+	require.Equal(t, "gen/b.txt", codeGeneratorResponse.GetFile()[1].GetName())
+
+	transformErr := errors.New("transform")
+	_, err = Invoke(
+		context.Background(),
+		handler,
+		codeGeneratorRequest,
+		WithResponseTransform(
+			func(*pluginpb.CodeGeneratorResponse) (*pluginpb.CodeGeneratorResponse, error) {
+				return nil, transformErr
+			},
+		),
+	)
+	require.ErrorIs(t, err, transformErr)
+
+	_, err = Invoke(
+		context.Background(),
+		handler,
+		codeGeneratorRequest,
+		WithResponseTransform(
+			func(*pluginpb.CodeGeneratorResponse) (*pluginpb.CodeGeneratorResponse, error) {
+				return nil, nil
+			},
+		),
+	)
+	require.Error(t, err)
+
+	// The transformed CodeGeneratorResponse is validated.
+	_, err = Invoke(
+		context.Background(),
+		handler,
+		codeGeneratorRequest,
+		WithResponseTransform(
+			func(codeGeneratorResponse *pluginpb.CodeGeneratorResponse) (*pluginpb.CodeGeneratorResponse, error) {
+				codeGeneratorResponse.GetFile()[0].Name = proto.String("../a.txt")
+				return codeGeneratorResponse, nil
+			},
+		),
+	)
+	var responseValidationError *ResponseValidationError
+	require.ErrorAs(t, err, &responseValidationError)
+}
+
+func TestWithMinimumCompilerVersionOption(t *testing.T) {
+	t.Parallel()
+
+	invoke := func(version *pluginpb.Version) (*pluginpb.CodeGeneratorResponse, bool) {
+		var handled bool
+		codeGeneratorResponse, err := Invoke(
+			context.Background(),
+			HandlerFunc(func(_ context.Context, _ PluginEnv, _ ResponseWriter, _ Request) error {
+				handled = true
+				return nil
+			}),
+			&pluginpb.CodeGeneratorRequest{
+				FileToGenerate: []string{"a.proto"},
+				ProtoFile: []*descriptorpb.FileDescriptorProto{
+					{
+						Name:   proto.String("a.proto"),
+						Syntax: proto.String("proto3"),
+					},
+				},
+				CompilerVersion: version,
+			},
+			WithMinimumCompilerVersion(CompilerVersion{Major: 27}, "for source-retention options"),
+		)
+		require.NoError(t, err)
+		return codeGeneratorResponse, handled
+	}
+
+	codeGeneratorResponse, handled := invoke(&pluginpb.Version{Major: proto.Int32(27), Minor: proto.Int32(1)})
+	require.True(t, handled)
+	require.Nil(t, codeGeneratorResponse.Error)
+
+	codeGeneratorResponse, handled = invoke(&pluginpb.Version{Major: proto.Int32(26), Minor: proto.Int32(1)})
+	require.False(t, handled)
+	require.Equal(
+		t,
+		"this plugin requires a compiler version >= 27.0 for source-retention options, but was invoked with protoc 26.1",
+		codeGeneratorResponse.GetError(),
+	)
+
+	codeGeneratorResponse, handled = invoke(nil)
+	require.False(t, handled)
+	require.Equal(
+		t,
+		"this plugin requires a compiler version >= 27.0 for source-retention options, but the compiler did not provide its version",
+		codeGeneratorResponse.GetError(),
+	)
+}
+
+func TestWithEnvAllowlistOption(t *testing.T) {
+	t.Parallel()
+
+	codeGeneratorRequestData, err := proto.Marshal(
+		&pluginpb.CodeGeneratorRequest{
+			FileToGenerate: []string{"a.proto"},
+			ProtoFile: []*descriptorpb.FileDescriptorProto{
+				{
+					Name:   proto.String("a.proto"),
+					Syntax: proto.String("proto3"),
+				},
+			},
+		},
+	)
+	require.NoError(t, err)
+
+	run := func(runOptions ...RunOption) []string {
+		var environ []string
+		err := Run(
+			context.Background(),
+			Env{
+				Environ: []string{"FOO=foo", "BAR=bar", "BAZ=baz"},
+				Stdin:   bytes.NewReader(codeGeneratorRequestData),
+				Stdout:  io.Discard,
+				Stderr:  io.Discard,
+			},
+			HandlerFunc(func(_ context.Context, pluginEnv PluginEnv, _ ResponseWriter, _ Request) error {
+				environ = pluginEnv.Environ
+				return nil
+			}),
+			runOptions...,
+		)
+		require.NoError(t, err)
+		return environ
+	}
+
+	require.Equal(t, []string{"FOO=foo", "BAR=bar", "BAZ=baz"}, run())
+	require.Equal(t, []string{"FOO=foo", "BAZ=baz"}, run(WithEnvAllowlist("FOO"), WithEnvAllowlist("BAZ", "MISSING")))
+	require.Empty(t, run(WithEnvAllowlist()))
+}
+
+func TestWithPluginNameOption(t *testing.T) {
+	t.Parallel()
+
+	stderr := bytes.NewBuffer(nil)
+	_, err := Invoke(
+		context.Background(),
+		HandlerFunc(func(_ context.Context, pluginEnv PluginEnv, _ ResponseWriter, _ Request) error {
+			pluginEnv.Stderr = stderr
+			pluginEnv.Warnf("careful")
+			return nil
+		}),
+		&pluginpb.CodeGeneratorRequest{
+			FileToGenerate: []string{"a.proto"},
+			ProtoFile: []*descriptorpb.FileDescriptorProto{
+				{
+					Name:   proto.String("a.proto"),
+					Syntax: proto.String("proto3"),
+				},
+			},
+		},
+		WithPluginName("protoc-gen-foo"),
+		// stderr is not a terminal, so this has no effect.
+		WithColorizedLogs(),
+	)
+	require.NoError(t, err)
+	require.Equal(t, "protoc-gen-foo: warning: careful\n", stderr.String())
+}
+
+func TestWithSkipRequestValidationOption(t *testing.T) {
+	t.Parallel()
+
+	// The negative compiler version is caught by validation, but does not
+	// affect the operation of the Request.
+	codeGeneratorRequestData, err := proto.Marshal(
+		&pluginpb.CodeGeneratorRequest{
+			FileToGenerate: []string{"a.proto"},
+			ProtoFile: []*descriptorpb.FileDescriptorProto{
+				{
+					Name:   proto.String("a.proto"),
+					Syntax: proto.String("proto3"),
+				},
+			},
+			CompilerVersion: &pluginpb.Version{
+				Major: proto.Int32(-1),
+			},
+		},
+	)
+	require.NoError(t, err)
+
+	run := func(runOptions ...RunOption) (bool, error) {
+		var handled bool
+		err := Run(
+			context.Background(),
+			Env{
+				Stdin:  bytes.NewReader(codeGeneratorRequestData),
+				Stdout: io.Discard,
+				Stderr: io.Discard,
+			},
+			HandlerFunc(func(_ context.Context, _ PluginEnv, _ ResponseWriter, request Request) error {
+				handled = true
+				require.Len(t, request.FileDescriptorProtosToGenerate(), 1)
+				return nil
+			}),
+			runOptions...,
+		)
+		return handled, err
+	}
+
+	handled, err := run()
+	var requestValidationError *RequestValidationError
+	require.ErrorAs(t, err, &requestValidationError)
+	require.False(t, handled)
+	handled, err = run(WithSkipRequestValidation())
+	require.NoError(t, err)
+	require.True(t, handled)
+}
+
+func TestWithUnmarshalOptionsOption(t *testing.T) {
+	t.Parallel()
+
+	codeGeneratorRequestData, err := proto.Marshal(
+		&pluginpb.CodeGeneratorRequest{
+			FileToGenerate: []string{"a.proto"},
+			ProtoFile: []*descriptorpb.FileDescriptorProto{
+				{
+					Name:   proto.String("a.proto"),
+					Syntax: proto.String("proto3"),
+				},
+			},
+		},
+	)
+	require.NoError(t, err)
+	// Append an unknown field with field number 1000 and varint value 1.
+	codeGeneratorRequestData = protowire.AppendTag(codeGeneratorRequestData, 1000, protowire.VarintType)
+	codeGeneratorRequestData = protowire.AppendVarint(codeGeneratorRequestData, 1)
+
+	run := func(runOptions ...RunOption) int {
+		var unknownLen int
+		err := Run(
+			context.Background(),
+			Env{
+				Stdin:  bytes.NewReader(codeGeneratorRequestData),
+				Stdout: io.Discard,
+				Stderr: io.Discard,
+			},
+			HandlerFunc(func(_ context.Context, _ PluginEnv, _ ResponseWriter, request Request) error {
+				unknownLen = len(request.CodeGeneratorRequest().ProtoReflect().GetUnknown())
+				return nil
+			}),
+			runOptions...,
+		)
+		require.NoError(t, err)
+		return unknownLen
+	}
+
+	require.NotZero(t, run())
+	require.Zero(t, run(WithUnmarshalOptions(proto.UnmarshalOptions{DiscardUnknown: true})))
+}
+
+func TestWithDeterministicMarshalOption(t *testing.T) {
+	t.Parallel()
+
+	codeGeneratorRequestData, err := proto.Marshal(
+		&pluginpb.CodeGeneratorRequest{
+			FileToGenerate: []string{"a.proto"},
+			ProtoFile: []*descriptorpb.FileDescriptorProto{
+				{
+					Name:   proto.String("a.proto"),
+					Syntax: proto.String("proto3"),
+				},
+			},
+		},
+	)
+	require.NoError(t, err)
+
+	run := func() []byte {
+		stdout := bytes.NewBuffer(nil)
+		err := Run(
+			context.Background(),
+			Env{
+				Stdin:  bytes.NewReader(codeGeneratorRequestData),
+				Stdout: stdout,
+				Stderr: io.Discard,
+			},
+			HandlerFunc(func(_ context.Context, _ PluginEnv, responseWriter ResponseWriter, _ Request) error {
+				responseWriter.AddFile("a.txt", "a\n")
+				responseWriter.AddFile("b.txt", "b\n")
+				return nil
+			}),
+			WithDeterministicMarshal(),
+		)
+		require.NoError(t, err)
+		return stdout.Bytes()
+	}
+
+	data := run()
+	require.Equal(t, data, run())
+	codeGeneratorResponse := &pluginpb.CodeGeneratorResponse{}
+	require.NoError(t, proto.Unmarshal(data, codeGeneratorResponse))
+	expectedData, err := proto.MarshalOptions{Deterministic: true}.Marshal(codeGeneratorResponse)
+	require.NoError(t, err)
+	require.Equal(t, expectedData, data)
+}
+
+func TestRunPhases(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	codeGeneratorRequestData, err := proto.Marshal(
+		&pluginpb.CodeGeneratorRequest{
+			FileToGenerate: []string{"a.proto"},
+			Parameter:      proto.String("foo"),
+			ProtoFile: []*descriptorpb.FileDescriptorProto{
+				{
+					Name:   proto.String("a.proto"),
+					Syntax: proto.String("proto3"),
+				},
+			},
+		},
+	)
+	require.NoError(t, err)
+	handler := HandlerFunc(
+		func(_ context.Context, pluginEnv PluginEnv, responseWriter ResponseWriter, request Request) error {
+			value, _ := pluginEnv.LookupEnv("KEY")
+			pluginEnv.Logf("%s", value)
+			responseWriter.AddFile("a.txt", request.Parameter())
+			return nil
+		},
+	)
+	environ := []string{"KEY=value"}
+
+	runStdout := bytes.NewBuffer(nil)
+	runStderr := bytes.NewBuffer(nil)
+	err = Run(
+		ctx,
+		Env{
+			Environ: environ,
+			Stdin:   bytes.NewReader(codeGeneratorRequestData),
+			Stdout:  runStdout,
+			Stderr:  runStderr,
+		},
+		handler,
+		WithDeterministicMarshal(),
+	)
+	require.NoError(t, err)
+
+	codeGeneratorRequest, err := ReadRequest(bytes.NewReader(codeGeneratorRequestData))
+	require.NoError(t, err)
+	require.Equal(t, "foo", codeGeneratorRequest.GetParameter())
+	stderr := bytes.NewBuffer(nil)
+	codeGeneratorResponse, err := ExecuteHandler(
+		ctx,
+		PluginEnv{
+			Environ: environ,
+			Stderr:  stderr,
+		},
+		handler,
+		codeGeneratorRequest,
+	)
+	require.NoError(t, err)
+	stdout := bytes.NewBuffer(nil)
+	require.NoError(t, WriteResponse(stdout, codeGeneratorResponse, WithDeterministicMarshal()))
+
+	require.Equal(t, runStdout.Bytes(), stdout.Bytes())
+	require.Equal(t, runStderr.String(), stderr.String())
+	require.Equal(t, "value\n", stderr.String())
+
+	_, err = ReadRequest(bytes.NewReader([]byte{0xff}))
+	require.Error(t, err)
+}
+
+func TestReadInput(t *testing.T) {
+	t.Parallel()
+
+	data := bytes.Repeat([]byte("abcdefgh"), 1024)
+	filePath := filepath.Join(t.TempDir(), "input")
+	require.NoError(t, os.WriteFile(filePath, data, 0600))
+	file, err := os.Open(filePath)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = file.Close() })
+	input, err := readInput(file, 0)
+	require.NoError(t, err)
+	require.Equal(t, data, input)
+
+	input, err = readInput(bytes.NewReader(data), 0)
+	require.NoError(t, err)
+	require.Equal(t, data, input)
+
+	input, err = readInput(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	require.Equal(t, data, input)
+	requestTooLargeError := &RequestTooLargeError{}
+	_, err = readInput(bytes.NewReader(data), int64(len(data)-1))
+	require.ErrorAs(t, err, &requestTooLargeError)
+	require.Equal(t, int64(len(data)-1), requestTooLargeError.MaxRequestBytes)
+	file, err = os.Open(filePath)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = file.Close() })
+	_, err = readInput(file, int64(len(data)-1))
+	require.ErrorAs(t, err, &requestTooLargeError)
+	// The file was rejected based on its size, before any of it was read.
+	offset, err := file.Seek(0, io.SeekCurrent)
+	require.NoError(t, err)
+	require.Zero(t, offset)
+}
+
+func TestInvoke(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	fileDescriptorProtos, err := compile(ctx, map[string][]byte{
+		"a.proto": []byte(`syntax = "proto3"; package foo; message A {}`),
+	})
+	require.NoError(t, err)
+	handler := HandlerFunc(func(_ context.Context, _ PluginEnv, responseWriter ResponseWriter, request Request) error {
+		for _, fileDescriptorProto := range request.FileDescriptorProtosToGenerate() {
+			responseWriter.AddFile(fileDescriptorProto.GetName()+".txt", request.Parameter())
+		}
+		return nil
+	})
+
+	codeGeneratorResponse, err := Invoke(
+		ctx,
+		handler,
+		&pluginpb.CodeGeneratorRequest{
+			FileToGenerate: []string{"a.proto"},
+			Parameter:      proto.String("foo"),
+			ProtoFile:      fileDescriptorProtos,
+		},
+	)
+	require.NoError(t, err)
+	require.True(
+		t,
+		proto.Equal(
+			&pluginpb.CodeGeneratorResponse{
+				File: []*pluginpb.CodeGeneratorResponse_File{
+					{
+						Name:    proto.String("a.proto.txt"),
+						Content: proto.String("foo"),
+					},
+				},
+			},
+			codeGeneratorResponse,
+		),
+	)
+
+	_, err = Invoke(ctx, handler, &pluginpb.CodeGeneratorRequest{})
+	var requestValidationError *RequestValidationError
+	require.ErrorAs(t, err, &requestValidationError)
+
+	codeGeneratorResponse, err = Invoke(
+		ctx,
+		handler,
+		&pluginpb.CodeGeneratorRequest{
+			FileToGenerate: []string{"a.proto"},
+			ProtoFile:      fileDescriptorProtos,
+		},
+		WithRequestInterceptor(func(context.Context, Request) error { return errors.New("rejected") }),
+	)
+	require.NoError(t, err)
+	require.Equal(t, "rejected", codeGeneratorResponse.GetError())
+}
+
+func TestWithExitCodeFuncOption(t *testing.T) {
+	t.Parallel()
+
+	errFoo := errors.New("foo")
+	exitCodeFunc := func(err error) int {
+		if errors.Is(err, errFoo) {
+			return 2
+		}
+		return 0
+	}
+	require.Equal(t, 1, getExitCode(errFoo, nil))
+	require.Equal(t, 2, getExitCode(fmt.Errorf("wrapped: %w", errFoo), exitCodeFunc))
+	require.Equal(t, 1, getExitCode(errors.New("bar"), exitCodeFunc))
+}
+
 func testBasic(
 	t *testing.T,
 	fileToGenerate []string,
@@ -26,3 +26,6 @@ import (
 //
 // For unix-like platforms, this adds syscall.SIGTERM.
 var extraInterruptSignals = []os.Signal{syscall.SIGTERM}
+
+// pipeTransportSupported says whether StdinPipeEnvKey and StdoutPipeEnvKey are respected.
+const pipeTransportSupported = false
@@ -25,3 +25,6 @@ import (
 //
 // For unix-like platforms, this adds syscall.SIGTERM.
 var extraInterruptSignals = []os.Signal{}
+
+// pipeTransportSupported says whether StdinPipeEnvKey and StdoutPipeEnvKey are respected.
+const pipeTransportSupported = true
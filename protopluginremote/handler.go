@@ -0,0 +1,176 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protopluginremote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/bufbuild/protoplugin"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// NewHandler returns a new protoplugin.Handler that executes the plugin at the remote endpoint at the given URL.
+//
+// The CodeGeneratorRequest is sent to the URL as-is. The files, error, supported features, and editions of the
+// CodeGeneratorResponse from the remote endpoint are added to the ResponseWriter. If the remote endpoint returns
+// an error, a *Error is returned.
+func NewHandler(url string, options ...HandlerOption) protoplugin.Handler {
+	handler := &handler{
+		url:              url,
+		httpClient:       http.DefaultClient,
+		header:           make(http.Header),
+		maxResponseBytes: DefaultMaxMessageBytes,
+	}
+	for _, option := range options {
+		option(handler)
+	}
+	return handler
+}
+
+// HandlerOption is an option for a new Handler.
+type HandlerOption func(*handler)
+
+// HandlerWithHTTPClient returns a new HandlerOption that uses the given http.Client to make requests.
+//
+// The default is to use http.DefaultClient.
+func HandlerWithHTTPClient(httpClient *http.Client) HandlerOption {
+	return func(handler *handler) {
+		handler.httpClient = httpClient
+	}
+}
+
+// HandlerWithHeader returns a new HandlerOption that adds the given header to all requests, for example
+// an Authorization header.
+//
+// This option can be given multiple times.
+func HandlerWithHeader(key string, value string) HandlerOption {
+	return func(handler *handler) {
+		handler.header.Add(key, value)
+	}
+}
+
+// HandlerWithMaxResponseBytes returns a new HandlerOption that limits the size of response bodies read
+// from the remote endpoint. Larger responses result in an error.
+//
+// The default is DefaultMaxMessageBytes. A value of zero or less disables the limit.
+func HandlerWithMaxResponseBytes(maxResponseBytes int64) HandlerOption {
+	return func(handler *handler) {
+		handler.maxResponseBytes = maxResponseBytes
+	}
+}
+
+// *** PRIVATE ***
+
+type handler struct {
+	url              string
+	httpClient       *http.Client
+	header           http.Header
+	maxResponseBytes int64
+}
+
+func (h *handler) Handle(
+	ctx context.Context,
+	_ protoplugin.PluginEnv,
+	responseWriter protoplugin.ResponseWriter,
+	request protoplugin.Request,
+) error {
+	codeGeneratorResponse, err := h.call(ctx, request.CodeGeneratorRequest())
+	if err != nil {
+		return err
+	}
+	if codeGeneratorResponse.Error != nil {
+		responseWriter.AddError(codeGeneratorResponse.GetError())
+	}
+	responseWriter.SetSupportedFeatures(codeGeneratorResponse.GetSupportedFeatures())
+	if codeGeneratorResponse.GetSupportedFeatures()&uint64(pluginpb.CodeGeneratorResponse_FEATURE_SUPPORTS_EDITIONS) != 0 {
+		responseWriter.SetFeatureSupportsEditions(
+			descriptorpb.Edition(codeGeneratorResponse.GetMinimumEdition()),
+			descriptorpb.Edition(codeGeneratorResponse.GetMaximumEdition()),
+		)
+	}
+	responseWriter.AddCodeGeneratorResponseFiles(codeGeneratorResponse.GetFile()...)
+	return nil
+}
+
+func (h *handler) call(
+	ctx context.Context,
+	codeGeneratorRequest *pluginpb.CodeGeneratorRequest,
+) (*pluginpb.CodeGeneratorResponse, error) {
+	data, err := protoplugin.MarshalRequest(codeGeneratorRequest)
+	if err != nil {
+		return nil, err
+	}
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range h.header {
+		httpRequest.Header[key] = values
+	}
+	httpRequest.Header.Set("Content-Type", ContentType)
+	httpRequest.Header.Set(connectProtocolVersionHeader, connectProtocolVersion)
+	httpResponse, err := h.httpClient.Do(httpRequest)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = httpResponse.Body.Close()
+	}()
+	var reader io.Reader = httpResponse.Body
+	if h.maxResponseBytes > 0 {
+		// Read at most one byte more than the limit, so that we can detect that the limit was exceeded.
+		reader = io.LimitReader(reader, h.maxResponseBytes+1)
+	}
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	if h.maxResponseBytes > 0 && int64(len(body)) > h.maxResponseBytes {
+		return nil, fmt.Errorf("remote plugin: response exceeds the maximum size of %d bytes", h.maxResponseBytes)
+	}
+	if httpResponse.StatusCode != http.StatusOK {
+		return nil, newErrorFromResponse(httpResponse.StatusCode, body)
+	}
+	if contentType := httpResponse.Header.Get("Content-Type"); contentType != ContentType {
+		return nil, fmt.Errorf("remote plugin: unexpected Content-Type %q", contentType)
+	}
+	return protoplugin.UnmarshalResponse(body)
+}
+
+// newErrorFromResponse returns a new *Error from a non-200 response.
+//
+// If the body is a valid Connect error, its code and message are used. Otherwise, the code
+// is derived from the HTTP status code.
+func newErrorFromResponse(httpStatusCode int, body []byte) *Error {
+	wireError := &wireError{}
+	if err := json.Unmarshal(body, wireError); err != nil || wireError.Code == "" {
+		return &Error{
+			Code:           httpStatusCodeToCode(httpStatusCode),
+			Message:        http.StatusText(httpStatusCode),
+			HTTPStatusCode: httpStatusCode,
+		}
+	}
+	return &Error{
+		Code:           wireError.Code,
+		Message:        wireError.Message,
+		HTTPStatusCode: httpStatusCode,
+	}
+}
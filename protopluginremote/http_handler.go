@@ -0,0 +1,102 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protopluginremote
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/bufbuild/protoplugin"
+)
+
+// NewHTTPHandler returns a new http.Handler that serves the protoplugin.Handler as a remote endpoint.
+//
+// The Handler is invoked with protoplugin.Invoke, and the given RunOptions are passed to Invoke. If the
+// CodeGeneratorRequest is invalid, an error with CodeInvalidArgument is returned. If the Handler returns
+// an error, or the CodeGeneratorResponse is invalid, an error with CodeInternal is returned.
+//
+// Request bodies larger than DefaultMaxMessageBytes, or the limit set with protoplugin.WithMaxRequestBytes
+// if smaller, are rejected with CodeResourceExhausted.
+//
+// The returned http.Handler can be used by any path, and does not perform authentication.
+func NewHTTPHandler(handler protoplugin.Handler, options ...protoplugin.RunOption) http.Handler {
+	return &httpHandler{
+		handler: handler,
+		options: options,
+	}
+}
+
+// *** PRIVATE ***
+
+type httpHandler struct {
+	handler protoplugin.Handler
+	options []protoplugin.RunOption
+}
+
+func (h *httpHandler) ServeHTTP(responseWriter http.ResponseWriter, httpRequest *http.Request) {
+	if httpRequest.Method != http.MethodPost {
+		writeError(responseWriter, CodeUnimplemented, "method must be POST")
+		return
+	}
+	if contentType := httpRequest.Header.Get("Content-Type"); contentType != ContentType {
+		responseWriter.Header().Set("Accept-Post", ContentType)
+		responseWriter.WriteHeader(http.StatusUnsupportedMediaType)
+		return
+	}
+	codeGeneratorRequest, err := protoplugin.ReadRequest(
+		http.MaxBytesReader(responseWriter, httpRequest.Body, DefaultMaxMessageBytes),
+		h.options...,
+	)
+	if err != nil {
+		requestTooLargeError := &protoplugin.RequestTooLargeError{}
+		maxBytesError := &http.MaxBytesError{}
+		if errors.As(err, &requestTooLargeError) || errors.As(err, &maxBytesError) {
+			writeError(responseWriter, CodeResourceExhausted, err.Error())
+			return
+		}
+		writeError(responseWriter, CodeInvalidArgument, err.Error())
+		return
+	}
+	codeGeneratorResponse, err := protoplugin.Invoke(httpRequest.Context(), h.handler, codeGeneratorRequest, h.options...)
+	if err != nil {
+		requestValidationError := &protoplugin.RequestValidationError{}
+		if errors.As(err, &requestValidationError) {
+			writeError(responseWriter, CodeInvalidArgument, err.Error())
+			return
+		}
+		writeError(responseWriter, CodeInternal, err.Error())
+		return
+	}
+	data, err := protoplugin.MarshalResponse(codeGeneratorResponse, h.options...)
+	if err != nil {
+		writeError(responseWriter, CodeInternal, err.Error())
+		return
+	}
+	responseWriter.Header().Set("Content-Type", ContentType)
+	responseWriter.WriteHeader(http.StatusOK)
+	_, _ = responseWriter.Write(data)
+}
+
+func writeError(responseWriter http.ResponseWriter, code Code, message string) {
+	data, err := json.Marshal(&wireError{Code: code, Message: message})
+	if err != nil {
+		// This should never happen, as wireError only contains strings.
+		data = []byte(`{"code":"internal"}`)
+	}
+	responseWriter.Header().Set("Content-Type", errorContentType)
+	responseWriter.WriteHeader(codeToHTTPStatusCode(code))
+	_, _ = responseWriter.Write(data)
+}
@@ -0,0 +1,123 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package protopluginremote provides a client and server for executing plugins over HTTP.
+//
+// The protocol is the Connect protocol (see https://connectrpc.com/docs/protocol) for a unary
+// RPC using the binary Protobuf codec. The request message is a CodeGeneratorRequest, and the response
+// message is a CodeGeneratorResponse. This allows a plugin served with NewHTTPHandler to be used behind
+// the protoplugin.Handler interface, so that local and remote generators can be mixed uniformly.
+//
+// This is not the API of any existing remote plugin execution service, and can only be used with
+// endpoints that implement the protocol above.
+//
+// NewHandler returns a protoplugin.Handler that delegates to a remote endpoint, and NewHTTPHandler
+// returns an http.Handler that serves a protoplugin.Handler as a remote endpoint.
+package protopluginremote
+
+import (
+	"fmt"
+	"net/http"
+)
+
+const (
+	// DefaultMaxMessageBytes is the default maximum size of a serialized CodeGeneratorRequest read by
+	// NewHTTPHandler, and of a serialized CodeGeneratorResponse read by NewHandler.
+	DefaultMaxMessageBytes = 256 << 20
+	// ContentType is the Content-Type of requests and successful responses.
+	ContentType = "application/proto"
+
+	connectProtocolVersionHeader = "Connect-Protocol-Version"
+	connectProtocolVersion       = "1"
+	errorContentType             = "application/json"
+)
+
+// Code is a Connect error code, for example "invalid_argument".
+//
+// See https://connectrpc.com/docs/protocol#error-codes for all codes.
+type Code string
+
+const (
+	// CodeUnknown is the code used when a more specific code is not known.
+	CodeUnknown Code = "unknown"
+	// CodeInvalidArgument is the code used when the CodeGeneratorRequest is invalid.
+	CodeInvalidArgument Code = "invalid_argument"
+	// CodeInternal is the code used when the Handler returned an error.
+	CodeInternal Code = "internal"
+	// CodeUnimplemented is the code used when the HTTP method is not POST.
+	CodeUnimplemented Code = "unimplemented"
+	// CodeResourceExhausted is the code used when the CodeGeneratorRequest is too large.
+	CodeResourceExhausted Code = "resource_exhausted"
+)
+
+// Error is the error returned from a Handler created with NewHandler when the remote
+// endpoint returned an error.
+type Error struct {
+	// Code is the Connect error code.
+	Code Code
+	// Message is the error message from the remote endpoint.
+	Message string
+	// HTTPStatusCode is the HTTP status code of the response.
+	HTTPStatusCode int
+}
+
+// Error implements error.
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("remote plugin: %s", e.Code)
+	}
+	return fmt.Sprintf("remote plugin: %s: %s", e.Code, e.Message)
+}
+
+// *** PRIVATE ***
+
+// codeToHTTPStatusCode maps Connect error codes to HTTP status codes, per the Connect protocol.
+func codeToHTTPStatusCode(code Code) int {
+	switch code {
+	case CodeInvalidArgument:
+		return http.StatusBadRequest
+	case CodeUnimplemented:
+		return http.StatusNotImplemented
+	case CodeResourceExhausted:
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// httpStatusCodeToCode maps HTTP status codes to Connect error codes, per the Connect protocol.
+//
+// This is only used if the error response did not contain a code.
+func httpStatusCodeToCode(httpStatusCode int) Code {
+	switch httpStatusCode {
+	case http.StatusBadRequest:
+		return Code("internal")
+	case http.StatusUnauthorized:
+		return Code("unauthenticated")
+	case http.StatusForbidden:
+		return Code("permission_denied")
+	case http.StatusNotFound:
+		return CodeUnimplemented
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return Code("unavailable")
+	default:
+		return CodeUnknown
+	}
+}
+
+// wireError is the JSON representation of a Connect error.
+type wireError struct {
+	Code    Code   `json:"code"`
+	Message string `json:"message,omitempty"`
+}
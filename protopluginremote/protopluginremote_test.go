@@ -0,0 +1,184 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protopluginremote
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bufbuild/protoplugin"
+	"github.com/bufbuild/protoplugin/protoplugintest"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestRemote(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	var authorization string
+	server := httptest.NewServer(
+		http.HandlerFunc(func(responseWriter http.ResponseWriter, httpRequest *http.Request) {
+			authorization = httpRequest.Header.Get("Authorization")
+			NewHTTPHandler(
+				protoplugin.HandlerFunc(
+					func(
+						_ context.Context,
+						_ protoplugin.PluginEnv,
+						responseWriter protoplugin.ResponseWriter,
+						request protoplugin.Request,
+					) error {
+						if request.Parameter() == "fail" {
+							return errors.New("failed")
+						}
+						if request.Parameter() == "add_error" {
+							responseWriter.AddError("added error")
+						}
+						responseWriter.SetFeatureProto3Optional()
+						responseWriter.SetFeatureSupportsEditions(descriptorpb.Edition_EDITION_PROTO2, descriptorpb.Edition_EDITION_2023)
+						for _, fileDescriptorProto := range request.FileDescriptorProtosToGenerate() {
+							responseWriter.AddFile(fileDescriptorProto.GetName()+".txt", fileDescriptorProto.GetPackage())
+						}
+						return nil
+					},
+				),
+			).ServeHTTP(responseWriter, httpRequest)
+		}),
+	)
+	t.Cleanup(server.Close)
+	handler := NewHandler(
+		server.URL,
+		HandlerWithHTTPClient(server.Client()),
+		HandlerWithHeader("Authorization", "Bearer token"),
+	)
+
+	codeGeneratorRequest := protoplugintest.NewSyntheticCodeGeneratorRequest(2, 1, 1)
+	codeGeneratorResponse, err := protoplugin.Invoke(ctx, handler, codeGeneratorRequest)
+	require.NoError(t, err)
+	require.Equal(t, "Bearer token", authorization)
+	require.Len(t, codeGeneratorResponse.GetFile(), 2)
+	require.Equal(t, "synthetic/file0.proto.txt", codeGeneratorResponse.GetFile()[0].GetName())
+	require.Equal(t, "synthetic.file0", codeGeneratorResponse.GetFile()[0].GetContent())
+	require.Equal(
+		t,
+		uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL|pluginpb.CodeGeneratorResponse_FEATURE_SUPPORTS_EDITIONS),
+		codeGeneratorResponse.GetSupportedFeatures(),
+	)
+	require.Equal(t, int32(descriptorpb.Edition_EDITION_2023), codeGeneratorResponse.GetMaximumEdition())
+
+	codeGeneratorRequest.Parameter = proto.String("add_error")
+	codeGeneratorResponse, err = protoplugin.Invoke(ctx, handler, codeGeneratorRequest)
+	require.NoError(t, err)
+	require.Equal(t, "added error", codeGeneratorResponse.GetError())
+
+	codeGeneratorRequest.Parameter = proto.String("fail")
+	_, err = protoplugin.Invoke(ctx, handler, codeGeneratorRequest)
+	remoteError := &Error{}
+	require.ErrorAs(t, err, &remoteError)
+	require.Equal(t, CodeInternal, remoteError.Code)
+	require.Equal(t, "failed", remoteError.Message)
+	require.Equal(t, http.StatusInternalServerError, remoteError.HTTPStatusCode)
+
+	// The remote endpoint validates the CodeGeneratorRequest.
+	codeGeneratorRequest.FileToGenerate = []string{"missing.proto"}
+	codeGeneratorRequest.Parameter = nil
+	err = NewHandler(server.URL, HandlerWithHTTPClient(server.Client())).Handle(
+		ctx,
+		protoplugin.PluginEnv{},
+		protoplugin.NewResponseWriter(),
+		protoplugin.NewRequestWithoutValidation(codeGeneratorRequest),
+	)
+	require.ErrorAs(t, err, &remoteError)
+	require.Equal(t, CodeInvalidArgument, remoteError.Code)
+	require.Equal(t, http.StatusBadRequest, remoteError.HTTPStatusCode)
+}
+
+func TestRemoteNonConnectError(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(server.Close)
+	_, err := protoplugin.Invoke(
+		context.Background(),
+		NewHandler(server.URL, HandlerWithHTTPClient(server.Client())),
+		protoplugintest.NewSyntheticCodeGeneratorRequest(1, 1, 1),
+	)
+	remoteError := &Error{}
+	require.ErrorAs(t, err, &remoteError)
+	require.Equal(t, CodeUnimplemented, remoteError.Code)
+	require.Equal(t, http.StatusNotFound, remoteError.HTTPStatusCode)
+}
+
+func TestRemoteRequestTooLarge(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(
+		NewHTTPHandler(
+			protoplugin.HandlerFunc(
+				func(context.Context, protoplugin.PluginEnv, protoplugin.ResponseWriter, protoplugin.Request) error {
+					return nil
+				},
+			),
+			protoplugin.WithMaxRequestBytes(16),
+		),
+	)
+	t.Cleanup(server.Close)
+	_, err := protoplugin.Invoke(
+		context.Background(),
+		NewHandler(server.URL, HandlerWithHTTPClient(server.Client())),
+		protoplugintest.NewSyntheticCodeGeneratorRequest(1, 1, 1),
+	)
+	remoteError := &Error{}
+	require.ErrorAs(t, err, &remoteError)
+	require.Equal(t, CodeResourceExhausted, remoteError.Code)
+	require.Equal(t, http.StatusTooManyRequests, remoteError.HTTPStatusCode)
+}
+
+func TestRemoteResponseTooLarge(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(
+		NewHTTPHandler(
+			protoplugin.HandlerFunc(
+				func(
+					_ context.Context,
+					_ protoplugin.PluginEnv,
+					responseWriter protoplugin.ResponseWriter,
+					_ protoplugin.Request,
+				) error {
+					responseWriter.AddFile("foo.txt", strings.Repeat("a", 1024))
+					return nil
+				},
+			),
+		),
+	)
+	t.Cleanup(server.Close)
+	_, err := protoplugin.Invoke(
+		context.Background(),
+		NewHandler(
+			server.URL,
+			HandlerWithHTTPClient(server.Client()),
+			HandlerWithMaxResponseBytes(512),
+		),
+		protoplugintest.NewSyntheticCodeGeneratorRequest(1, 1, 1),
+	)
+	require.ErrorContains(t, err, "maximum size of 512 bytes")
+}
@@ -0,0 +1,527 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conformance
+
+import (
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// Case is a conformance test case.
+type Case struct {
+	// Name is the name of the Case.
+	//
+	// Names are unique, and are suitable for use as subtest names.
+	Name string
+	// CodeGeneratorRequest is the CodeGeneratorRequest for the Case.
+	//
+	// This is a new CodeGeneratorRequest on every call to Cases, and can be modified.
+	CodeGeneratorRequest *pluginpb.CodeGeneratorRequest
+}
+
+// Cases returns the conformance test Cases.
+//
+// The Cases are constructed to match the shape of the CodeGeneratorRequests that protoc and buf produce
+// at various versions, covering proto2, proto3, proto3 optional, editions, and source-retention options:
+//
+//   - protoc versions before 26.0 do not populate source_file_descriptors.
+//   - protoc 26.0 and later populate source_file_descriptors, and strip source-retention options from proto_file.
+//   - protoc 27.0 and later support edition 2023.
+//   - buf sets the suffix of compiler_version to "buf".
+//
+// All Cases are valid CodeGeneratorRequests, and all files can be built into a protoregistry.Files.
+func Cases() []*Case {
+	return []*Case{
+		{
+			Name: "protoc_3_21_proto2",
+			CodeGeneratorRequest: newCodeGeneratorRequest(
+				newVersion(3, 21, 12, ""),
+				[]*descriptorpb.FileDescriptorProto{newProto2FileDescriptorProto()},
+				nil,
+				false,
+			),
+		},
+		{
+			Name: "protoc_3_21_proto3",
+			CodeGeneratorRequest: newCodeGeneratorRequest(
+				newVersion(3, 21, 12, ""),
+				[]*descriptorpb.FileDescriptorProto{newProto3FileDescriptorProto()},
+				[]*descriptorpb.FileDescriptorProto{timestampFileDescriptorProto()},
+				false,
+			),
+		},
+		{
+			Name: "protoc_3_21_proto3_optional",
+			CodeGeneratorRequest: newCodeGeneratorRequest(
+				newVersion(3, 21, 12, ""),
+				[]*descriptorpb.FileDescriptorProto{newProto3OptionalFileDescriptorProto()},
+				nil,
+				false,
+			),
+		},
+		{
+			Name: "protoc_3_21_multiple_files",
+			CodeGeneratorRequest: newCodeGeneratorRequest(
+				newVersion(3, 21, 12, ""),
+				[]*descriptorpb.FileDescriptorProto{
+					newProto2FileDescriptorProto(),
+					newProto3FileDescriptorProto(),
+				},
+				[]*descriptorpb.FileDescriptorProto{timestampFileDescriptorProto()},
+				false,
+			),
+		},
+		{
+			Name: "protoc_26_source_retention",
+			CodeGeneratorRequest: newCodeGeneratorRequest(
+				newVersion(5, 26, 1, ""),
+				[]*descriptorpb.FileDescriptorProto{newSourceRetentionFileDescriptorProto()},
+				[]*descriptorpb.FileDescriptorProto{
+					descriptorFileDescriptorProto(),
+					newSourceRetentionOptionsFileDescriptorProto(),
+				},
+				true,
+			),
+		},
+		{
+			Name: "protoc_27_editions",
+			CodeGeneratorRequest: newCodeGeneratorRequest(
+				newVersion(5, 27, 0, ""),
+				[]*descriptorpb.FileDescriptorProto{newEditionsFileDescriptorProto()},
+				nil,
+				true,
+			),
+		},
+		{
+			Name: "buf_proto3_optional",
+			CodeGeneratorRequest: newCodeGeneratorRequest(
+				newVersion(5, 28, 2, "buf"),
+				[]*descriptorpb.FileDescriptorProto{
+					newProto3FileDescriptorProto(),
+					newProto3OptionalFileDescriptorProto(),
+				},
+				[]*descriptorpb.FileDescriptorProto{timestampFileDescriptorProto()},
+				true,
+			),
+		},
+		{
+			Name: "buf_editions",
+			CodeGeneratorRequest: newCodeGeneratorRequest(
+				newVersion(5, 28, 2, "buf"),
+				[]*descriptorpb.FileDescriptorProto{
+					newProto2FileDescriptorProto(),
+					newEditionsFileDescriptorProto(),
+				},
+				nil,
+				true,
+			),
+		},
+	}
+}
+
+// *** PRIVATE ***
+
+const (
+	// sourceRetentionOptionNumber is the field number of the source-retention option in
+	// newSourceRetentionOptionsFileDescriptorProto.
+	sourceRetentionOptionNumber = 50000
+)
+
+// newCodeGeneratorRequest returns a new CodeGeneratorRequest.
+//
+// filesToGenerate must be in topological order, and dependencies must contain all dependencies
+// of filesToGenerate that are not themselves in filesToGenerate, in topological order.
+//
+// If sourceFileDescriptors is true, source_file_descriptors is populated. For files with
+// source-retention options, source_file_descriptors contains the options, while proto_file does not.
+func newCodeGeneratorRequest(
+	version *pluginpb.Version,
+	filesToGenerate []*descriptorpb.FileDescriptorProto,
+	dependencies []*descriptorpb.FileDescriptorProto,
+	sourceFileDescriptors bool,
+) *pluginpb.CodeGeneratorRequest {
+	codeGeneratorRequest := &pluginpb.CodeGeneratorRequest{
+		CompilerVersion: version,
+		ProtoFile:       dependencies,
+	}
+	for _, fileToGenerate := range filesToGenerate {
+		codeGeneratorRequest.FileToGenerate = append(codeGeneratorRequest.GetFileToGenerate(), fileToGenerate.GetName())
+		if sourceFileDescriptors {
+			codeGeneratorRequest.SourceFileDescriptors = append(codeGeneratorRequest.GetSourceFileDescriptors(), fileToGenerate)
+		}
+		codeGeneratorRequest.ProtoFile = append(codeGeneratorRequest.GetProtoFile(), withoutSourceRetentionOptions(fileToGenerate))
+	}
+	return codeGeneratorRequest
+}
+
+func newVersion(major int32, minor int32, patch int32, suffix string) *pluginpb.Version {
+	version := &pluginpb.Version{
+		Major: proto.Int32(major),
+		Minor: proto.Int32(minor),
+		Patch: proto.Int32(patch),
+	}
+	if suffix != "" {
+		version.Suffix = proto.String(suffix)
+	}
+	return version
+}
+
+func newProto2FileDescriptorProto() *descriptorpb.FileDescriptorProto {
+	return &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("conformance/proto2.proto"),
+		Package: proto.String("conformance.proto2"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("Proto2"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					newField("required_string", 1, descriptorpb.FieldDescriptorProto_LABEL_REQUIRED, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					withDefaultValue(
+						newField("optional_int32", 2, descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL, descriptorpb.FieldDescriptorProto_TYPE_INT32, ""),
+						"5",
+					),
+					newField("repeated_nested", 3, descriptorpb.FieldDescriptorProto_LABEL_REPEATED, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".conformance.proto2.Proto2.Nested"),
+					newField("optional_enum", 4, descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL, descriptorpb.FieldDescriptorProto_TYPE_ENUM, ".conformance.proto2.Enum"),
+				},
+				NestedType: []*descriptorpb.DescriptorProto{
+					{
+						Name: proto.String("Nested"),
+						Field: []*descriptorpb.FieldDescriptorProto{
+							newField("optional_bool", 1, descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL, descriptorpb.FieldDescriptorProto_TYPE_BOOL, ""),
+						},
+					},
+				},
+				ExtensionRange: []*descriptorpb.DescriptorProto_ExtensionRange{
+					{
+						Start: proto.Int32(100),
+						End:   proto.Int32(201),
+					},
+				},
+			},
+		},
+		EnumType: []*descriptorpb.EnumDescriptorProto{
+			newEnum("Enum", "ENUM_ZERO", "ENUM_ONE"),
+		},
+		Extension: []*descriptorpb.FieldDescriptorProto{
+			withExtendee(
+				newField("optional_extension", 100, descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+				".conformance.proto2.Proto2",
+			),
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{
+			{
+				Name: proto.String("Service"),
+				Method: []*descriptorpb.MethodDescriptorProto{
+					{
+						Name:       proto.String("Method"),
+						InputType:  proto.String(".conformance.proto2.Proto2"),
+						OutputType: proto.String(".conformance.proto2.Proto2"),
+					},
+				},
+			},
+		},
+		SourceCodeInfo: newSourceCodeInfo(" Proto2 is a proto2 message.\n"),
+	}
+}
+
+func newProto3FileDescriptorProto() *descriptorpb.FileDescriptorProto {
+	return &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("conformance/proto3.proto"),
+		Package:    proto.String("conformance.proto3"),
+		Dependency: []string{timestampFileDescriptorProto().GetName()},
+		Syntax:     proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("Proto3"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					newField("string_field", 1, descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					newField("map_field", 2, descriptorpb.FieldDescriptorProto_LABEL_REPEATED, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".conformance.proto3.Proto3.MapFieldEntry"),
+					newField("timestamp_field", 3, descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".google.protobuf.Timestamp"),
+					withOneofIndex(
+						newField("oneof_string", 4, descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+						0,
+						false,
+					),
+					withOneofIndex(
+						newField("oneof_int32", 5, descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL, descriptorpb.FieldDescriptorProto_TYPE_INT32, ""),
+						0,
+						false,
+					),
+					newField("packed_int64", 6, descriptorpb.FieldDescriptorProto_LABEL_REPEATED, descriptorpb.FieldDescriptorProto_TYPE_INT64, ""),
+				},
+				NestedType: []*descriptorpb.DescriptorProto{
+					{
+						Name: proto.String("MapFieldEntry"),
+						Field: []*descriptorpb.FieldDescriptorProto{
+							newField("key", 1, descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+							newField("value", 2, descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL, descriptorpb.FieldDescriptorProto_TYPE_INT64, ""),
+						},
+						Options: &descriptorpb.MessageOptions{
+							MapEntry: proto.Bool(true),
+						},
+					},
+				},
+				OneofDecl: []*descriptorpb.OneofDescriptorProto{
+					{
+						Name: proto.String("oneof_field"),
+					},
+				},
+			},
+		},
+		SourceCodeInfo: newSourceCodeInfo(" Proto3 is a proto3 message.\n"),
+	}
+}
+
+func newProto3OptionalFileDescriptorProto() *descriptorpb.FileDescriptorProto {
+	return &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("conformance/proto3_optional.proto"),
+		Package: proto.String("conformance.proto3optional"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("Proto3Optional"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					withOneofIndex(
+						newField("optional_string", 1, descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+						0,
+						true,
+					),
+					newField("implicit_string", 2, descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+				},
+				OneofDecl: []*descriptorpb.OneofDescriptorProto{
+					{
+						// Synthetic oneof for the proto3 optional field.
+						Name: proto.String("_optional_string"),
+					},
+				},
+			},
+		},
+		SourceCodeInfo: newSourceCodeInfo(" Proto3Optional has a proto3 optional field.\n"),
+	}
+}
+
+func newEditionsFileDescriptorProto() *descriptorpb.FileDescriptorProto {
+	return &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("conformance/editions.proto"),
+		Package: proto.String("conformance.editions"),
+		Syntax:  proto.String("editions"),
+		Edition: descriptorpb.Edition_EDITION_2023.Enum(),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("Editions"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					newField("explicit_string", 1, descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					withFieldOptions(
+						newField("implicit_int32", 2, descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL, descriptorpb.FieldDescriptorProto_TYPE_INT32, ""),
+						&descriptorpb.FieldOptions{
+							Features: &descriptorpb.FeatureSet{
+								FieldPresence: descriptorpb.FeatureSet_IMPLICIT.Enum(),
+							},
+						},
+					),
+					newField("repeated_int32", 3, descriptorpb.FieldDescriptorProto_LABEL_REPEATED, descriptorpb.FieldDescriptorProto_TYPE_INT32, ""),
+					newField("open_enum", 4, descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL, descriptorpb.FieldDescriptorProto_TYPE_ENUM, ".conformance.editions.OpenEnum"),
+				},
+			},
+		},
+		EnumType: []*descriptorpb.EnumDescriptorProto{
+			newEnum("OpenEnum", "OPEN_ENUM_UNSPECIFIED", "OPEN_ENUM_ONE"),
+		},
+		SourceCodeInfo: newSourceCodeInfo(" Editions is an edition 2023 message.\n"),
+	}
+}
+
+func newSourceRetentionOptionsFileDescriptorProto() *descriptorpb.FileDescriptorProto {
+	return &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("conformance/options.proto"),
+		Package:    proto.String("conformance.options"),
+		Dependency: []string{descriptorFileDescriptorProto().GetName()},
+		Extension: []*descriptorpb.FieldDescriptorProto{
+			withFieldOptions(
+				withExtendee(
+					newField("source_option", sourceRetentionOptionNumber, descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					".google.protobuf.FileOptions",
+				),
+				&descriptorpb.FieldOptions{
+					Retention: descriptorpb.FieldOptions_RETENTION_SOURCE.Enum(),
+				},
+			),
+		},
+	}
+}
+
+func newSourceRetentionFileDescriptorProto() *descriptorpb.FileDescriptorProto {
+	fileOptions := &descriptorpb.FileOptions{}
+	// The custom option is encoded as an unknown field, as it would be if the
+	// options were unmarshaled without a resolver for the extension.
+	fileOptions.ProtoReflect().SetUnknown(
+		protowire.AppendString(
+			protowire.AppendTag(nil, sourceRetentionOptionNumber, protowire.BytesType),
+			"source",
+		),
+	)
+	return &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("conformance/source_retention.proto"),
+		Package:    proto.String("conformance.sourceretention"),
+		Dependency: []string{newSourceRetentionOptionsFileDescriptorProto().GetName()},
+		Syntax:     proto.String("proto3"),
+		Options:    fileOptions,
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("SourceRetention"),
+			},
+		},
+		SourceCodeInfo: newSourceCodeInfo(" SourceRetention is in a file with source-retention options.\n"),
+	}
+}
+
+// withoutSourceRetentionOptions returns the FileDescriptorProto as it would appear in proto_file.
+//
+// The only source-retention option used in the Cases is the file option from newSourceRetentionOptionsFileDescriptorProto.
+func withoutSourceRetentionOptions(fileDescriptorProto *descriptorpb.FileDescriptorProto) *descriptorpb.FileDescriptorProto {
+	if fileDescriptorProto.GetOptions() == nil {
+		return fileDescriptorProto
+	}
+	clone, _ := proto.Clone(fileDescriptorProto).(*descriptorpb.FileDescriptorProto)
+	var unknown []byte
+	remaining := clone.GetOptions().ProtoReflect().GetUnknown()
+	for len(remaining) > 0 {
+		number, wireType, n := protowire.ConsumeTag(remaining)
+		if n < 0 {
+			break
+		}
+		m := protowire.ConsumeFieldValue(number, wireType, remaining[n:])
+		if m < 0 {
+			break
+		}
+		if number != sourceRetentionOptionNumber {
+			unknown = append(unknown, remaining[:n+m]...)
+		}
+		remaining = remaining[n+m:]
+	}
+	clone.GetOptions().ProtoReflect().SetUnknown(unknown)
+	if proto.Size(clone.GetOptions()) == 0 {
+		clone.Options = nil
+	}
+	return clone
+}
+
+func newField(
+	name string,
+	number int32,
+	label descriptorpb.FieldDescriptorProto_Label,
+	fieldType descriptorpb.FieldDescriptorProto_Type,
+	typeName string,
+) *descriptorpb.FieldDescriptorProto {
+	field := &descriptorpb.FieldDescriptorProto{
+		Name:     proto.String(name),
+		Number:   proto.Int32(number),
+		Label:    label.Enum(),
+		Type:     fieldType.Enum(),
+		JsonName: proto.String(jsonName(name)),
+	}
+	if typeName != "" {
+		field.TypeName = proto.String(typeName)
+	}
+	return field
+}
+
+func withDefaultValue(field *descriptorpb.FieldDescriptorProto, defaultValue string) *descriptorpb.FieldDescriptorProto {
+	field.DefaultValue = proto.String(defaultValue)
+	return field
+}
+
+func withExtendee(field *descriptorpb.FieldDescriptorProto, extendee string) *descriptorpb.FieldDescriptorProto {
+	field.Extendee = proto.String(extendee)
+	return field
+}
+
+func withOneofIndex(field *descriptorpb.FieldDescriptorProto, oneofIndex int32, proto3Optional bool) *descriptorpb.FieldDescriptorProto {
+	field.OneofIndex = proto.Int32(oneofIndex)
+	if proto3Optional {
+		field.Proto3Optional = proto.Bool(true)
+	}
+	return field
+}
+
+func withFieldOptions(field *descriptorpb.FieldDescriptorProto, fieldOptions *descriptorpb.FieldOptions) *descriptorpb.FieldDescriptorProto {
+	field.Options = fieldOptions
+	return field
+}
+
+func newEnum(name string, valueNames ...string) *descriptorpb.EnumDescriptorProto {
+	enum := &descriptorpb.EnumDescriptorProto{
+		Name: proto.String(name),
+	}
+	for i, valueName := range valueNames {
+		enum.Value = append(
+			enum.GetValue(),
+			&descriptorpb.EnumValueDescriptorProto{
+				Name:   proto.String(valueName),
+				Number: proto.Int32(int32(i)), // #nosec:G115 should never overflow
+			},
+		)
+	}
+	return enum
+}
+
+// newSourceCodeInfo returns a new SourceCodeInfo with a location for the file, and a location
+// with the given leading comment for the first message.
+func newSourceCodeInfo(firstMessageLeadingComment string) *descriptorpb.SourceCodeInfo {
+	return &descriptorpb.SourceCodeInfo{
+		Location: []*descriptorpb.SourceCodeInfo_Location{
+			{
+				Path: []int32{},
+				Span: []int32{0, 0, 10, 1},
+			},
+			{
+				Path:            []int32{4, 0},
+				Span:            []int32{2, 0, 10, 1},
+				LeadingComments: proto.String(firstMessageLeadingComment),
+			},
+		},
+	}
+}
+
+// jsonName returns the JSON name for the field name, as computed by protoc.
+func jsonName(name string) string {
+	jsonName := make([]byte, 0, len(name))
+	var upperNext bool
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case c == '_':
+			upperNext = true
+		case upperNext && 'a' <= c && c <= 'z':
+			jsonName = append(jsonName, c-'a'+'A')
+			upperNext = false
+		default:
+			jsonName = append(jsonName, c)
+			upperNext = false
+		}
+	}
+	return string(jsonName)
+}
+
+func timestampFileDescriptorProto() *descriptorpb.FileDescriptorProto {
+	return protodesc.ToFileDescriptorProto(timestamppb.File_google_protobuf_timestamp_proto)
+}
+
+func descriptorFileDescriptorProto() *descriptorpb.FileDescriptorProto {
+	return protodesc.ToFileDescriptorProto(descriptorpb.File_google_protobuf_descriptor_proto)
+}
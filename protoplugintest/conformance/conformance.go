@@ -0,0 +1,172 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package conformance provides a conformance test suite for plugins built with protoplugin.
+//
+// The suite runs a Handler against a corpus of CodeGeneratorRequests that match the shape of the
+// requests produced by multiple protoc and buf versions, and checks that the Handler produces
+// spec-conformant CodeGeneratorResponses. This helps plugin authors claim compatibility across
+// compiler versions.
+//
+// The typical usage is within a test:
+//
+//	func TestConformance(t *testing.T) {
+//		t.Parallel()
+//		conformance.Run(t, protoplugin.HandlerFunc(handle))
+//	}
+package conformance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/bufbuild/protoplugin"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// UnsupportedError is the error returned from Check if the Handler does not declare support for a
+// feature that the Case requires, such as proto3 optional or editions.
+//
+// Compilers fail with an error in this situation, which is expected behavior for plugins that
+// do not support the feature. Run skips Cases that result in an UnsupportedError.
+type UnsupportedError struct {
+	// Feature is the name of the unsupported feature.
+	Feature string
+}
+
+// Error implements error.
+func (u *UnsupportedError) Error() string {
+	return "handler does not declare support for " + u.Feature
+}
+
+// Run runs the Handler against all Cases as subtests of t.
+//
+// Cases that result in an UnsupportedError are skipped. All other errors result in the subtest failing.
+//
+// The RunOptions are passed to protoplugin.Invoke for every Case.
+func Run(t *testing.T, handler protoplugin.Handler, options ...protoplugin.RunOption) {
+	for _, testCase := range Cases() {
+		testCase := testCase
+		t.Run(testCase.Name, func(t *testing.T) {
+			t.Parallel()
+			err := Check(context.Background(), handler, testCase, options...)
+			unsupportedError := &UnsupportedError{}
+			switch {
+			case err == nil:
+			case errors.As(err, &unsupportedError):
+				t.Skip(err.Error())
+			default:
+				t.Error(err)
+			}
+		})
+	}
+}
+
+// Check runs the Handler against the Case, and returns an error if the Handler did not
+// produce a conformant CodeGeneratorResponse.
+//
+// The following are checked:
+//
+//   - The Handler does not return an error, and does not add an error to the CodeGeneratorResponse.
+//   - The CodeGeneratorResponse is valid per the CodeGeneratorResponse spec, as validated by protoplugin.
+//   - If any file to generate uses proto3 optional, the Handler declares support for proto3 optional.
+//   - If any file to generate uses editions, the Handler declares support for editions, and the edition is
+//     within the declared minimum and maximum editions.
+//   - The Handler is deterministic, that is invoking the Handler twice produces the same CodeGeneratorResponse.
+//
+// If the Handler does not declare support for a feature that the Case requires, an *UnsupportedError is returned.
+//
+// The RunOptions are passed to protoplugin.Invoke.
+func Check(
+	ctx context.Context,
+	handler protoplugin.Handler,
+	testCase *Case,
+	options ...protoplugin.RunOption,
+) error {
+	codeGeneratorResponse, err := protoplugin.Invoke(ctx, handler, testCase.CodeGeneratorRequest, options...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", testCase.Name, err)
+	}
+	if err := checkSupportedFeatures(testCase.CodeGeneratorRequest, codeGeneratorResponse); err != nil {
+		return err
+	}
+	if codeGeneratorResponse.Error != nil {
+		return fmt.Errorf("%s: handler added error to CodeGeneratorResponse: %s", testCase.Name, codeGeneratorResponse.GetError())
+	}
+	secondCodeGeneratorResponse, err := protoplugin.Invoke(ctx, handler, testCase.CodeGeneratorRequest, options...)
+	if err != nil {
+		return fmt.Errorf("%s: second invocation: %w", testCase.Name, err)
+	}
+	if !proto.Equal(codeGeneratorResponse, secondCodeGeneratorResponse) {
+		return fmt.Errorf("%s: handler is not deterministic, invoking twice produced different CodeGeneratorResponses", testCase.Name)
+	}
+	return nil
+}
+
+// *** PRIVATE ***
+
+// checkSupportedFeatures checks that the features that the CodeGeneratorRequest requires are declared as
+// supported on the CodeGeneratorResponse, mirroring the checks that protoc performs.
+func checkSupportedFeatures(
+	codeGeneratorRequest *pluginpb.CodeGeneratorRequest,
+	codeGeneratorResponse *pluginpb.CodeGeneratorResponse,
+) error {
+	supportedFeatures := codeGeneratorResponse.GetSupportedFeatures()
+	filesToGenerate := make(map[string]struct{}, len(codeGeneratorRequest.GetFileToGenerate()))
+	for _, fileToGenerate := range codeGeneratorRequest.GetFileToGenerate() {
+		filesToGenerate[fileToGenerate] = struct{}{}
+	}
+	for _, fileDescriptorProto := range codeGeneratorRequest.GetProtoFile() {
+		if _, ok := filesToGenerate[fileDescriptorProto.GetName()]; !ok {
+			continue
+		}
+		if supportedFeatures&uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL) == 0 &&
+			hasProto3Optional(fileDescriptorProto.GetMessageType()) {
+			return &UnsupportedError{Feature: "proto3 optional"}
+		}
+		if fileDescriptorProto.GetSyntax() != "editions" {
+			continue
+		}
+		edition := fileDescriptorProto.GetEdition()
+		if supportedFeatures&uint64(pluginpb.CodeGeneratorResponse_FEATURE_SUPPORTS_EDITIONS) == 0 ||
+			int32(edition) < codeGeneratorResponse.GetMinimumEdition() ||
+			int32(edition) > codeGeneratorResponse.GetMaximumEdition() {
+			return &UnsupportedError{Feature: "edition " + editionString(edition)}
+		}
+	}
+	return nil
+}
+
+func hasProto3Optional(descriptorProtos []*descriptorpb.DescriptorProto) bool {
+	for _, descriptorProto := range descriptorProtos {
+		for _, field := range descriptorProto.GetField() {
+			if field.GetProto3Optional() {
+				return true
+			}
+		}
+		if hasProto3Optional(descriptorProto.GetNestedType()) {
+			return true
+		}
+	}
+	return false
+}
+
+func editionString(edition descriptorpb.Edition) string {
+	return strings.TrimPrefix(edition.String(), "EDITION_")
+}
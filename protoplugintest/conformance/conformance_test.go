@@ -0,0 +1,124 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conformance
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/bufbuild/protoplugin"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestCases(t *testing.T) {
+	t.Parallel()
+
+	names := make(map[string]struct{})
+	for _, testCase := range Cases() {
+		_, ok := names[testCase.Name]
+		require.False(t, ok, "duplicate name %q", testCase.Name)
+		names[testCase.Name] = struct{}{}
+
+		request, err := protoplugin.NewRequest(testCase.CodeGeneratorRequest)
+		require.NoError(t, err, testCase.Name)
+		_, err = request.FileDescriptorsToGenerate()
+		require.NoError(t, err, testCase.Name)
+		if len(testCase.CodeGeneratorRequest.GetSourceFileDescriptors()) > 0 {
+			request, err = request.WithSourceRetentionOptions()
+			require.NoError(t, err, testCase.Name)
+			_, err = protodesc.NewFiles(&descriptorpb.FileDescriptorSet{File: request.AllFileDescriptorProtos()})
+			require.NoError(t, err, testCase.Name)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	Run(
+		t,
+		protoplugin.HandlerFunc(
+			func(_ context.Context, _ protoplugin.PluginEnv, responseWriter protoplugin.ResponseWriter, request protoplugin.Request) error {
+				responseWriter.SetFeatureProto3Optional()
+				responseWriter.SetFeatureSupportsEditions(descriptorpb.Edition_EDITION_PROTO2, descriptorpb.Edition_EDITION_2023)
+				fileDescriptors, err := request.FileDescriptorsToGenerate()
+				if err != nil {
+					return err
+				}
+				for _, fileDescriptor := range fileDescriptors {
+					responseWriter.AddFile(fileDescriptor.Path()+".txt", string(fileDescriptor.Package())+"\n")
+				}
+				return nil
+			},
+		),
+	)
+}
+
+func TestCheck(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	casesByName := make(map[string]*Case)
+	for _, testCase := range Cases() {
+		casesByName[testCase.Name] = testCase
+	}
+
+	noFeaturesHandler := protoplugin.HandlerFunc(
+		func(context.Context, protoplugin.PluginEnv, protoplugin.ResponseWriter, protoplugin.Request) error {
+			return nil
+		},
+	)
+	require.NoError(t, Check(ctx, noFeaturesHandler, casesByName["protoc_3_21_proto2"]))
+	unsupportedError := &UnsupportedError{}
+	err := Check(ctx, noFeaturesHandler, casesByName["protoc_3_21_proto3_optional"])
+	require.ErrorAs(t, err, &unsupportedError)
+	require.Equal(t, "proto3 optional", unsupportedError.Feature)
+	err = Check(ctx, noFeaturesHandler, casesByName["protoc_27_editions"])
+	require.ErrorAs(t, err, &unsupportedError)
+	require.Equal(t, "edition 2023", unsupportedError.Feature)
+
+	var counter atomic.Int64
+	nonDeterministicHandler := protoplugin.HandlerFunc(
+		func(_ context.Context, _ protoplugin.PluginEnv, responseWriter protoplugin.ResponseWriter, _ protoplugin.Request) error {
+			responseWriter.AddFile("a.txt", strconv.FormatInt(counter.Add(1), 10))
+			return nil
+		},
+	)
+	err = Check(ctx, nonDeterministicHandler, casesByName["protoc_3_21_proto2"])
+	require.ErrorContains(t, err, "not deterministic")
+
+	invalidHandler := protoplugin.HandlerFunc(
+		func(_ context.Context, _ protoplugin.PluginEnv, responseWriter protoplugin.ResponseWriter, _ protoplugin.Request) error {
+			responseWriter.AddFile("../a.txt", "")
+			return nil
+		},
+	)
+	err = Check(ctx, invalidHandler, casesByName["protoc_3_21_proto2"])
+	responseValidationError := &protoplugin.ResponseValidationError{}
+	require.ErrorAs(t, err, &responseValidationError)
+
+	errorHandler := protoplugin.HandlerFunc(
+		func(_ context.Context, _ protoplugin.PluginEnv, responseWriter protoplugin.ResponseWriter, _ protoplugin.Request) error {
+			responseWriter.AddError("bad")
+			return nil
+		},
+	)
+	err = Check(ctx, errorHandler, casesByName["protoc_3_21_proto2"])
+	require.ErrorContains(t, err, "handler added error to CodeGeneratorResponse: bad")
+}
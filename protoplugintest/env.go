@@ -0,0 +1,176 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugintest
+
+import (
+	"bytes"
+	"io"
+	"sync"
+
+	"github.com/bufbuild/protoplugin"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// Env is a test environment for plugins.
+//
+// Env provides a protoplugin.Env and protoplugin.PluginEnv with in-memory stdio, and allows reading
+// what was written to stdout and stderr after the plugin has run, for example:
+//
+//	env := protoplugintest.NewEnv(protoplugintest.EnvWithCodeGeneratorRequest(codeGeneratorRequest))
+//	if err := protoplugin.Run(ctx, env.Env(), handler); err != nil {
+//	  t.Fatal(err)
+//	}
+//	codeGeneratorResponse, err := env.CodeGeneratorResponse()
+//
+// Env is safe for concurrent use, so that Handlers that write to stderr from multiple goroutines
+// can be tested.
+type Env struct {
+	programName string
+	args        []string
+	environ     []string
+	stdin       io.Reader
+	stdout      *lockedBuffer
+	stderr      *lockedBuffer
+}
+
+// NewEnv returns a new Env.
+//
+// By default, there is no program name, no args, no environment variables, and stdin is empty.
+func NewEnv(options ...EnvOption) *Env {
+	env := &Env{
+		stdin:  bytes.NewReader(nil),
+		stdout: &lockedBuffer{},
+		stderr: &lockedBuffer{},
+	}
+	for _, option := range options {
+		option(env)
+	}
+	return env
+}
+
+// EnvOption is an option for a new Env.
+type EnvOption func(*Env)
+
+// EnvWithProgramName returns a new EnvOption that sets the program name, for example "protoc-gen-foo".
+func EnvWithProgramName(programName string) EnvOption {
+	return func(env *Env) {
+		env.programName = programName
+	}
+}
+
+// EnvWithArgs returns a new EnvOption that sets the program arguments, not including the program name.
+func EnvWithArgs(args ...string) EnvOption {
+	return func(env *Env) {
+		env.args = args
+	}
+}
+
+// EnvWithEnviron returns a new EnvOption that sets the environment variables, in the form "key=value".
+func EnvWithEnviron(environ ...string) EnvOption {
+	return func(env *Env) {
+		env.environ = environ
+	}
+}
+
+// EnvWithStdin returns a new EnvOption that sets stdin.
+func EnvWithStdin(stdin io.Reader) EnvOption {
+	return func(env *Env) {
+		env.stdin = stdin
+	}
+}
+
+// EnvWithCodeGeneratorRequest returns a new EnvOption that sets stdin to the marshaled CodeGeneratorRequest.
+//
+// If the CodeGeneratorRequest cannot be marshaled, reading from stdin will return the marshal error.
+func EnvWithCodeGeneratorRequest(codeGeneratorRequest *pluginpb.CodeGeneratorRequest) EnvOption {
+	return func(env *Env) {
+		data, err := proto.Marshal(codeGeneratorRequest)
+		if err != nil {
+			env.stdin = errReader{err: err}
+			return
+		}
+		env.stdin = bytes.NewReader(data)
+	}
+}
+
+// Env returns a new protoplugin.Env that uses the Env's args, environment variables, and stdio.
+func (e *Env) Env() protoplugin.Env {
+	return protoplugin.Env{
+		ProgramName: e.programName,
+		Args:        e.args,
+		Environ:     e.environ,
+		Stdin:       e.stdin,
+		Stdout:      e.stdout,
+		Stderr:      e.stderr,
+	}
+}
+
+// PluginEnv returns a new protoplugin.PluginEnv that uses the Env's program name, environment variables, and stderr.
+//
+// This is useful for invoking Handlers directly.
+func (e *Env) PluginEnv() protoplugin.PluginEnv {
+	return protoplugin.PluginEnv{
+		ProgramName: e.programName,
+		Environ:     e.environ,
+		Stderr:      e.stderr,
+	}
+}
+
+// Stdout returns a copy of what has been written to stdout.
+func (e *Env) Stdout() []byte {
+	return e.stdout.Bytes()
+}
+
+// Stderr returns what has been written to stderr.
+func (e *Env) Stderr() string {
+	return string(e.stderr.Bytes())
+}
+
+// CodeGeneratorResponse unmarshals what has been written to stdout as a CodeGeneratorResponse.
+func (e *Env) CodeGeneratorResponse() (*pluginpb.CodeGeneratorResponse, error) {
+	codeGeneratorResponse := &pluginpb.CodeGeneratorResponse{}
+	if err := proto.Unmarshal(e.stdout.Bytes(), codeGeneratorResponse); err != nil {
+		return nil, err
+	}
+	return codeGeneratorResponse, nil
+}
+
+// *** PRIVATE ***
+
+type lockedBuffer struct {
+	buffer bytes.Buffer
+	lock   sync.Mutex
+}
+
+func (l *lockedBuffer) Write(p []byte) (int, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.buffer.Write(p)
+}
+
+func (l *lockedBuffer) Bytes() []byte {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return bytes.Clone(l.buffer.Bytes())
+}
+
+type errReader struct {
+	err error
+}
+
+func (e errReader) Read([]byte) (int, error) {
+	return 0, e.err
+}
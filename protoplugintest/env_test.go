@@ -0,0 +1,75 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugintest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bufbuild/protoplugin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnv(t *testing.T) {
+	t.Parallel()
+
+	env := NewEnv(
+		EnvWithCodeGeneratorRequest(NewSyntheticCodeGeneratorRequest(2, 1, 1)),
+		EnvWithEnviron("FOO=bar"),
+		EnvWithProgramName("protoc-gen-test"),
+	)
+	err := protoplugin.Run(
+		context.Background(),
+		env.Env(),
+		protoplugin.HandlerFunc(
+			func(
+				_ context.Context,
+				pluginEnv protoplugin.PluginEnv,
+				responseWriter protoplugin.ResponseWriter,
+				request protoplugin.Request,
+			) error {
+				value, _ := pluginEnv.LookupEnv("FOO")
+				pluginEnv.Logf("FOO=%s", value)
+				for _, fileDescriptorProto := range request.FileDescriptorProtosToGenerate() {
+					responseWriter.AddFile(fileDescriptorProto.GetName()+".txt", "")
+				}
+				return nil
+			},
+		),
+	)
+	require.NoError(t, err)
+	require.Equal(t, "protoc-gen-test: FOO=bar\n", env.Stderr())
+	codeGeneratorResponse, err := env.CodeGeneratorResponse()
+	require.NoError(t, err)
+	require.Len(t, codeGeneratorResponse.GetFile(), 2)
+
+	env = NewEnv(EnvWithArgs("--version"))
+	err = protoplugin.Run(
+		context.Background(),
+		env.Env(),
+		protoplugin.HandlerFunc(
+			func(context.Context, protoplugin.PluginEnv, protoplugin.ResponseWriter, protoplugin.Request) error {
+				return nil
+			},
+		),
+		protoplugin.WithVersion("1.0.0"),
+	)
+	require.NoError(t, err)
+	require.Equal(t, "1.0.0\n", string(env.Stdout()))
+
+	env = NewEnv()
+	env.PluginEnv().Logf("foo")
+	require.Equal(t, "foo\n", env.Stderr())
+}
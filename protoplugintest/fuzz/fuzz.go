@@ -0,0 +1,127 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fuzz provides fuzz targets for the validation and source-retention option stripping of protoplugin.
+//
+// The fuzz targets are seeded with small and synthetic CodeGeneratorRequests, and are intended to be run
+// within the CI of downstream users, for example to check the version of protoplugin they depend on
+// against their own corpus. The typical usage is within a test file:
+//
+//	func FuzzValidateCodeGeneratorRequest(f *testing.F) {
+//		fuzz.ValidateCodeGeneratorRequest(f)
+//	}
+//
+// Additional seeds can be added with f.Add before calling the fuzz target. Each seed is a serialized
+// CodeGeneratorRequest for ValidateCodeGeneratorRequest, and a serialized FileDescriptorProto for
+// StripSourceRetentionOptions.
+package fuzz
+
+import (
+	"testing"
+
+	"github.com/bufbuild/protoplugin"
+	"github.com/bufbuild/protoplugin/protoplugintest"
+	"github.com/bufbuild/protoplugin/protopluginutil"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// ValidateCodeGeneratorRequest fuzzes the validation of CodeGeneratorRequests by protoplugin.NewRequest.
+//
+// The fuzz target checks that CodeGeneratorRequests that pass validation never cause the accessors of
+// the resulting Request to panic.
+func ValidateCodeGeneratorRequest(f *testing.F) {
+	for _, codeGeneratorRequest := range seedCodeGeneratorRequests() {
+		data, err := proto.Marshal(codeGeneratorRequest)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+	f.Fuzz(func(_ *testing.T, data []byte) {
+		codeGeneratorRequest := &pluginpb.CodeGeneratorRequest{}
+		if err := proto.Unmarshal(data, codeGeneratorRequest); err != nil {
+			return
+		}
+		request, err := protoplugin.NewRequest(codeGeneratorRequest)
+		if err != nil {
+			return
+		}
+		// Validated requests must never cause accessors to panic.
+		_ = request.FileDescriptorProtosToGenerate()
+		_ = request.AllFileDescriptorProtos()
+		_, _ = request.FileDescriptorsToGenerate()
+		_ = request.CompilerVersion().String()
+		if sourceRetentionRequest, err := request.WithSourceRetentionOptions(); err == nil {
+			_ = sourceRetentionRequest.FileDescriptorProtosToGenerate()
+			_ = sourceRetentionRequest.AllFileDescriptorProtos()
+		}
+	})
+}
+
+// StripSourceRetentionOptions fuzzes protopluginutil.StripSourceRetentionOptions.
+//
+// The fuzz target checks that stripping never panics, and that the stripped FileDescriptorProto
+// can be serialized.
+func StripSourceRetentionOptions(f *testing.F) {
+	for _, codeGeneratorRequest := range seedCodeGeneratorRequests() {
+		for _, fileDescriptorProto := range codeGeneratorRequest.GetProtoFile() {
+			data, err := proto.Marshal(fileDescriptorProto)
+			if err != nil {
+				f.Fatal(err)
+			}
+			f.Add(data)
+		}
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fileDescriptorProto := &descriptorpb.FileDescriptorProto{}
+		if err := proto.Unmarshal(data, fileDescriptorProto); err != nil {
+			return
+		}
+		strippedFileDescriptorProto, err := protopluginutil.StripSourceRetentionOptions(fileDescriptorProto)
+		if err != nil {
+			return
+		}
+		if _, err := proto.Marshal(strippedFileDescriptorProto); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+// *** PRIVATE ***
+
+func seedCodeGeneratorRequests() []*pluginpb.CodeGeneratorRequest {
+	return []*pluginpb.CodeGeneratorRequest{
+		{},
+		{
+			FileToGenerate: []string{"a.proto"},
+			ProtoFile: []*descriptorpb.FileDescriptorProto{
+				{Name: proto.String("a.proto")},
+			},
+		},
+		{
+			FileToGenerate: []string{"a.proto"},
+			ProtoFile: []*descriptorpb.FileDescriptorProto{
+				{Name: proto.String("b.proto")},
+				{Name: proto.String("a.proto"), Dependency: []string{"b.proto"}},
+			},
+			SourceFileDescriptors: []*descriptorpb.FileDescriptorProto{
+				{Name: proto.String("a.proto"), Dependency: []string{"b.proto"}},
+			},
+			CompilerVersion: &pluginpb.Version{Major: proto.Int32(27)},
+		},
+		protoplugintest.NewSyntheticCodeGeneratorRequest(3, 2, 2),
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package protoplugintest provides utilities for testing plugins built with protoplugin,
+// as well as for testing protoplugin itself.
+package protoplugintest
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// NewSyntheticCodeGeneratorRequest returns a new CodeGeneratorRequest containing synthetic files.
+//
+// This is intended for benchmarking plugins and protoplugin itself against large requests.
+//
+// The request will contain numFiles files named "synthetic/fileN.proto", each of which contains
+// numMessagesPerFile messages with numFieldsPerMessage fields each. Every file after the first depends
+// on the file before it, and the last field of every message references a message in the previous file,
+// so that the files form a linear dependency chain. Every message has a SourceCodeInfo location with
+// a leading comment.
+//
+// All files are included in file_to_generate, and source_file_descriptors is populated with
+// the same FileDescriptorProtos as proto_file.
+//
+// The returned CodeGeneratorRequest is valid, and can be used to construct Requests with NewRequest.
+func NewSyntheticCodeGeneratorRequest(
+	numFiles int,
+	numMessagesPerFile int,
+	numFieldsPerMessage int,
+) *pluginpb.CodeGeneratorRequest {
+	codeGeneratorRequest := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate:        make([]string, numFiles),
+		ProtoFile:             make([]*descriptorpb.FileDescriptorProto, numFiles),
+		SourceFileDescriptors: make([]*descriptorpb.FileDescriptorProto, numFiles),
+	}
+	for i := 0; i < numFiles; i++ {
+		fileDescriptorProto := newSyntheticFileDescriptorProto(i, numMessagesPerFile, numFieldsPerMessage)
+		codeGeneratorRequest.FileToGenerate[i] = fileDescriptorProto.GetName()
+		codeGeneratorRequest.ProtoFile[i] = fileDescriptorProto
+		codeGeneratorRequest.SourceFileDescriptors[i] = fileDescriptorProto
+	}
+	return codeGeneratorRequest
+}
+
+// *** PRIVATE ***
+
+func newSyntheticFileDescriptorProto(
+	fileIndex int,
+	numMessagesPerFile int,
+	numFieldsPerMessage int,
+) *descriptorpb.FileDescriptorProto {
+	fileDescriptorProto := &descriptorpb.FileDescriptorProto{
+		Name:           proto.String(syntheticFileName(fileIndex)),
+		Package:        proto.String(syntheticPackageName(fileIndex)),
+		Syntax:         proto.String("proto3"),
+		MessageType:    make([]*descriptorpb.DescriptorProto, numMessagesPerFile),
+		SourceCodeInfo: &descriptorpb.SourceCodeInfo{},
+	}
+	if fileIndex > 0 {
+		fileDescriptorProto.Dependency = []string{syntheticFileName(fileIndex - 1)}
+	}
+	for i := 0; i < numMessagesPerFile; i++ {
+		descriptorProto := &descriptorpb.DescriptorProto{
+			Name:  proto.String(syntheticMessageName(i)),
+			Field: make([]*descriptorpb.FieldDescriptorProto, numFieldsPerMessage),
+		}
+		for j := 0; j < numFieldsPerMessage; j++ {
+			fieldDescriptorProto := &descriptorpb.FieldDescriptorProto{
+				Name:     proto.String(fmt.Sprintf("field%d", j)),
+				JsonName: proto.String(fmt.Sprintf("field%d", j)),
+				Number:   proto.Int32(int32(j + 1)), // #nosec:G115 should never overflow
+				Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+				Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+			}
+			if fileIndex > 0 && j == numFieldsPerMessage-1 {
+				fieldDescriptorProto.Type = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+				fieldDescriptorProto.TypeName = proto.String(
+					"." + syntheticPackageName(fileIndex-1) + "." + syntheticMessageName(i),
+				)
+			}
+			descriptorProto.Field[j] = fieldDescriptorProto
+		}
+		fileDescriptorProto.MessageType[i] = descriptorProto
+		fileDescriptorProto.SourceCodeInfo.Location = append(
+			fileDescriptorProto.SourceCodeInfo.Location,
+			&descriptorpb.SourceCodeInfo_Location{
+				Path:            []int32{4, int32(i)},    // #nosec:G115 should never overflow
+				Span:            []int32{int32(i), 0, 1}, // #nosec:G115 should never overflow
+				LeadingComments: proto.String(" " + syntheticMessageName(i) + " is a synthetic message.\n"),
+			},
+		)
+	}
+	return fileDescriptorProto
+}
+
+func syntheticFileName(fileIndex int) string {
+	return fmt.Sprintf("synthetic/file%d.proto", fileIndex)
+}
+
+func syntheticPackageName(fileIndex int) string {
+	return fmt.Sprintf("synthetic.file%d", fileIndex)
+}
+
+func syntheticMessageName(messageIndex int) string {
+	return fmt.Sprintf("Message%d", messageIndex)
+}
@@ -0,0 +1,251 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protopluginutil
+
+import (
+	"math"
+	"sort"
+	"strconv"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// CanonicalizeFileDescriptorProto returns a deterministic, normalized copy of the FileDescriptorProto.
+//
+// Compilers serialize semantically-equivalent descriptors slightly differently. The returned FileDescriptorProto
+// is suitable for hashing and equality comparison across compilers. The following normalizations are applied:
+//
+//   - The syntax field is cleared for proto2 files, and the edition field is cleared for proto2 and proto3 files.
+//   - Options messages that have no fields set are cleared.
+//   - Unrecognized options, that is custom options that were retained as unknown fields, are sorted by field number.
+//   - Field default values are normalized, for example "0x10" becomes "16" and "1E10" becomes "1e+10".
+//   - Field JSON names are cleared if they are equal to the JSON name computed from the field name.
+//
+// SourceCodeInfo is not modified. Use StripSourceCodeInfo to remove it before comparison if source locations
+// should not be considered.
+//
+// The input FileDescriptorProto is not modified.
+func CanonicalizeFileDescriptorProto(file *descriptorpb.FileDescriptorProto) (*descriptorpb.FileDescriptorProto, error) {
+	file, ok := proto.Clone(file).(*descriptorpb.FileDescriptorProto)
+	if !ok || file == nil {
+		return file, nil
+	}
+	switch file.GetSyntax() {
+	case "", "proto2":
+		file.Syntax = nil
+		if file.GetEdition() == descriptorpb.Edition_EDITION_PROTO2 {
+			file.Edition = nil
+		}
+	case "proto3":
+		if file.GetEdition() == descriptorpb.Edition_EDITION_PROTO3 {
+			file.Edition = nil
+		}
+	}
+	file.Options = canonicalizeOptions(file.GetOptions())
+	if err := Walk(
+		file,
+		Visitor{
+			EnterMessage: func(message *descriptorpb.DescriptorProto, _ protoreflect.SourcePath) error {
+				message.Options = canonicalizeOptions(message.GetOptions())
+				for _, extensionRange := range message.GetExtensionRange() {
+					extensionRange.Options = canonicalizeOptions(extensionRange.GetOptions())
+				}
+				return nil
+			},
+			Field: func(field *descriptorpb.FieldDescriptorProto, _ protoreflect.SourcePath) error {
+				canonicalizeField(field)
+				return nil
+			},
+			Oneof: func(oneof *descriptorpb.OneofDescriptorProto, _ protoreflect.SourcePath) error {
+				oneof.Options = canonicalizeOptions(oneof.GetOptions())
+				return nil
+			},
+			Enum: func(enum *descriptorpb.EnumDescriptorProto, _ protoreflect.SourcePath) error {
+				enum.Options = canonicalizeOptions(enum.GetOptions())
+				return nil
+			},
+			EnumValue: func(enumValue *descriptorpb.EnumValueDescriptorProto, _ protoreflect.SourcePath) error {
+				enumValue.Options = canonicalizeOptions(enumValue.GetOptions())
+				return nil
+			},
+			Service: func(service *descriptorpb.ServiceDescriptorProto, _ protoreflect.SourcePath) error {
+				service.Options = canonicalizeOptions(service.GetOptions())
+				return nil
+			},
+			Method: func(method *descriptorpb.MethodDescriptorProto, _ protoreflect.SourcePath) error {
+				method.Options = canonicalizeOptions(method.GetOptions())
+				return nil
+			},
+			Extension: func(extension *descriptorpb.FieldDescriptorProto, _ protoreflect.SourcePath) error {
+				canonicalizeField(extension)
+				return nil
+			},
+		},
+	); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// *** PRIVATE ***
+
+func canonicalizeField(field *descriptorpb.FieldDescriptorProto) {
+	field.Options = canonicalizeOptions(field.GetOptions())
+	if field.JsonName != nil && field.GetJsonName() == defaultJSONName(field.GetName()) {
+		field.JsonName = nil
+	}
+	if field.DefaultValue != nil {
+		field.DefaultValue = proto.String(canonicalizeDefaultValue(field.GetType(), field.GetDefaultValue()))
+	}
+}
+
+// canonicalizeOptions clears the options if no fields are set, and otherwise sorts the unknown fields.
+func canonicalizeOptions[M proto.Message](options M) M {
+	message := options.ProtoReflect()
+	if !message.IsValid() {
+		return options
+	}
+	if proto.Size(options) == 0 {
+		var zero M
+		return zero
+	}
+	if unknown := message.GetUnknown(); len(unknown) > 0 {
+		message.SetUnknown(sortUnknownFields(unknown))
+	}
+	return options
+}
+
+// sortUnknownFields stably sorts the unknown fields by field number.
+//
+// If the unknown fields cannot be parsed, they are returned unchanged.
+func sortUnknownFields(unknown protoreflect.RawFields) protoreflect.RawFields {
+	type unknownField struct {
+		number protowire.Number
+		data   []byte
+	}
+	var unknownFields []unknownField
+	for remaining := unknown; len(remaining) > 0; {
+		number, _, length := protowire.ConsumeField(remaining)
+		if length < 0 {
+			return unknown
+		}
+		unknownFields = append(unknownFields, unknownField{number: number, data: remaining[:length]})
+		remaining = remaining[length:]
+	}
+	sort.SliceStable(
+		unknownFields,
+		func(i int, j int) bool {
+			return unknownFields[i].number < unknownFields[j].number
+		},
+	)
+	sorted := make(protoreflect.RawFields, 0, len(unknown))
+	for _, unknownField := range unknownFields {
+		sorted = append(sorted, unknownField.data...)
+	}
+	return sorted
+}
+
+// canonicalizeDefaultValue returns the canonical form of the default value for a field of the given type.
+//
+// If the default value cannot be parsed, it is returned unchanged.
+func canonicalizeDefaultValue(fieldType descriptorpb.FieldDescriptorProto_Type, defaultValue string) string {
+	switch fieldType {
+	case descriptorpb.FieldDescriptorProto_TYPE_DOUBLE:
+		return canonicalizeFloatDefaultValue(defaultValue, 64)
+	case descriptorpb.FieldDescriptorProto_TYPE_FLOAT:
+		return canonicalizeFloatDefaultValue(defaultValue, 32)
+	case descriptorpb.FieldDescriptorProto_TYPE_INT32,
+		descriptorpb.FieldDescriptorProto_TYPE_SINT32,
+		descriptorpb.FieldDescriptorProto_TYPE_SFIXED32:
+		return canonicalizeIntDefaultValue(defaultValue, 32)
+	case descriptorpb.FieldDescriptorProto_TYPE_INT64,
+		descriptorpb.FieldDescriptorProto_TYPE_SINT64,
+		descriptorpb.FieldDescriptorProto_TYPE_SFIXED64:
+		return canonicalizeIntDefaultValue(defaultValue, 64)
+	case descriptorpb.FieldDescriptorProto_TYPE_UINT32,
+		descriptorpb.FieldDescriptorProto_TYPE_FIXED32:
+		return canonicalizeUintDefaultValue(defaultValue, 32)
+	case descriptorpb.FieldDescriptorProto_TYPE_UINT64,
+		descriptorpb.FieldDescriptorProto_TYPE_FIXED64:
+		return canonicalizeUintDefaultValue(defaultValue, 64)
+	case descriptorpb.FieldDescriptorProto_TYPE_BOOL:
+		value, err := strconv.ParseBool(defaultValue)
+		if err != nil {
+			return defaultValue
+		}
+		return strconv.FormatBool(value)
+	default:
+		return defaultValue
+	}
+}
+
+func canonicalizeFloatDefaultValue(defaultValue string, bitSize int) string {
+	value, err := strconv.ParseFloat(defaultValue, bitSize)
+	if err != nil {
+		return defaultValue
+	}
+	// These match the values that protoc uses.
+	switch {
+	case math.IsInf(value, 1):
+		return "inf"
+	case math.IsInf(value, -1):
+		return "-inf"
+	case math.IsNaN(value):
+		return "nan"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, bitSize)
+	}
+}
+
+func canonicalizeIntDefaultValue(defaultValue string, bitSize int) string {
+	value, err := strconv.ParseInt(defaultValue, 0, bitSize)
+	if err != nil {
+		return defaultValue
+	}
+	return strconv.FormatInt(value, 10)
+}
+
+func canonicalizeUintDefaultValue(defaultValue string, bitSize int) string {
+	value, err := strconv.ParseUint(defaultValue, 0, bitSize)
+	if err != nil {
+		return defaultValue
+	}
+	return strconv.FormatUint(value, 10)
+}
+
+// defaultJSONName returns the JSON name that protoc computes for a field name.
+//
+// Underscores are removed, and the letter following an underscore is uppercased.
+func defaultJSONName(name string) string {
+	jsonName := make([]byte, 0, len(name))
+	var upperNext bool
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case c == '_':
+			upperNext = true
+		case upperNext && 'a' <= c && c <= 'z':
+			jsonName = append(jsonName, c-'a'+'A')
+			upperNext = false
+		default:
+			jsonName = append(jsonName, c)
+			upperNext = false
+		}
+	}
+	return string(jsonName)
+}
@@ -0,0 +1,144 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protopluginutil
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestCanonicalizeFileDescriptorProto(t *testing.T) {
+	t.Parallel()
+
+	newFieldOptions := func(numbers ...protowire.Number) *descriptorpb.FieldOptions {
+		fieldOptions := &descriptorpb.FieldOptions{}
+		var unknown []byte
+		for _, number := range numbers {
+			unknown = protowire.AppendTag(unknown, number, protowire.VarintType)
+			unknown = protowire.AppendVarint(unknown, uint64(number))
+		}
+		fieldOptions.ProtoReflect().SetUnknown(unknown)
+		return fieldOptions
+	}
+	newFile := func(
+		syntax *string,
+		messageOptions *descriptorpb.MessageOptions,
+		fieldOptions *descriptorpb.FieldOptions,
+		jsonName *string,
+		int32DefaultValue string,
+		doubleDefaultValue string,
+	) *descriptorpb.FileDescriptorProto {
+		return &descriptorpb.FileDescriptorProto{
+			Name:   proto.String("a.proto"),
+			Syntax: syntax,
+			MessageType: []*descriptorpb.DescriptorProto{
+				{
+					Name:    proto.String("Foo"),
+					Options: messageOptions,
+					Field: []*descriptorpb.FieldDescriptorProto{
+						{
+							Name:         proto.String("foo_bar"),
+							JsonName:     jsonName,
+							Number:       proto.Int32(1),
+							Label:        descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+							Type:         descriptorpb.FieldDescriptorProto_TYPE_INT32.Enum(),
+							DefaultValue: proto.String(int32DefaultValue),
+							Options:      fieldOptions,
+						},
+						{
+							Name:         proto.String("baz"),
+							JsonName:     proto.String("customBaz"),
+							Number:       proto.Int32(2),
+							Label:        descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+							Type:         descriptorpb.FieldDescriptorProto_TYPE_DOUBLE.Enum(),
+							DefaultValue: proto.String(doubleDefaultValue),
+						},
+					},
+				},
+			},
+		}
+	}
+
+	first := newFile(
+		proto.String("proto2"),
+		&descriptorpb.MessageOptions{},
+		newFieldOptions(50001, 50000),
+		proto.String("fooBar"),
+		"0x10",
+		"1E10",
+	)
+	firstClone := proto.Clone(first)
+	second := newFile(
+		nil,
+		nil,
+		newFieldOptions(50000, 50001),
+		nil,
+		"16",
+		"10000000000",
+	)
+	canonicalFirst, err := CanonicalizeFileDescriptorProto(first)
+	require.NoError(t, err)
+	canonicalSecond, err := CanonicalizeFileDescriptorProto(second)
+	require.NoError(t, err)
+	require.Empty(t, cmp.Diff(canonicalFirst, canonicalSecond, protocmp.Transform()))
+	firstData, err := proto.MarshalOptions{Deterministic: true}.Marshal(canonicalFirst)
+	require.NoError(t, err)
+	secondData, err := proto.MarshalOptions{Deterministic: true}.Marshal(canonicalSecond)
+	require.NoError(t, err)
+	require.Equal(t, firstData, secondData)
+	// The input is not modified.
+	require.Empty(t, cmp.Diff(firstClone, first, protocmp.Transform()))
+
+	message := canonicalFirst.GetMessageType()[0]
+	require.Nil(t, canonicalFirst.Syntax)
+	require.Nil(t, message.GetOptions())
+	require.Nil(t, message.GetField()[0].JsonName)
+	require.Equal(t, "16", message.GetField()[0].GetDefaultValue())
+	require.Equal(t, "customBaz", message.GetField()[1].GetJsonName())
+	require.Equal(t, "1e+10", message.GetField()[1].GetDefaultValue())
+}
+
+func TestCanonicalizeDefaultValue(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		fieldType    descriptorpb.FieldDescriptorProto_Type
+		defaultValue string
+		expected     string
+	}{
+		{descriptorpb.FieldDescriptorProto_TYPE_DOUBLE, "Infinity", "inf"},
+		{descriptorpb.FieldDescriptorProto_TYPE_DOUBLE, "-inf", "-inf"},
+		{descriptorpb.FieldDescriptorProto_TYPE_DOUBLE, "NaN", "nan"},
+		{descriptorpb.FieldDescriptorProto_TYPE_FLOAT, "1.50", "1.5"},
+		{descriptorpb.FieldDescriptorProto_TYPE_INT64, "-0x10", "-16"},
+		{descriptorpb.FieldDescriptorProto_TYPE_UINT32, "0X1F", "31"},
+		{descriptorpb.FieldDescriptorProto_TYPE_BOOL, "true", "true"},
+		{descriptorpb.FieldDescriptorProto_TYPE_STRING, "0x10", "0x10"},
+		{descriptorpb.FieldDescriptorProto_TYPE_INT32, "not a number", "not a number"},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.fieldType.String()+"/"+testCase.defaultValue, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, testCase.expected, canonicalizeDefaultValue(testCase.fieldType, testCase.defaultValue))
+		})
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protopluginutil
+
+import (
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// CommentDirective is a machine-readable directive within a comment.
+//
+// Directives are lines of the form "namespace:name" or "namespace:name=value", for example
+// "protoc-gen-foo:ignore" or "protoc-gen-foo:name=Bar". See ParseCommentDirectives for more details.
+type CommentDirective struct {
+	// Name is the name of the directive, for example "ignore" for "protoc-gen-foo:ignore".
+	Name string
+	// Value is the value of the directive, for example "Bar" for "protoc-gen-foo:name=Bar".
+	//
+	// Empty if the directive did not have a value.
+	Value string
+}
+
+// CommentDirectives are the CommentDirectives within a comment, in the order they appear.
+type CommentDirectives []CommentDirective
+
+// Has returns true if a CommentDirective with the name is present.
+func (c CommentDirectives) Has(name string) bool {
+	_, ok := c.Get(name)
+	return ok
+}
+
+// Get returns the value of the last CommentDirective with the name, and whether such a CommentDirective is present.
+func (c CommentDirectives) Get(name string) (string, bool) {
+	for i := len(c) - 1; i >= 0; i-- {
+		if c[i].Name == name {
+			return c[i].Value, true
+		}
+	}
+	return "", false
+}
+
+// ParseCommentDirectives parses the CommentDirectives with the namespace from the comment.
+//
+// A directive is a line of the comment that, after leading whitespace is removed, begins with the namespace
+// followed by ":", and then a name, optionally followed by "=" and a value. Names consist of ASCII letters,
+// digits, '_', '-', and '.'. Values are the remainder of the line with surrounding whitespace removed, and
+// whitespace before the "=" is ignored. Lines that do not match are ignored, so directives can be freely mixed
+// with prose. For example, with the namespace "protoc-gen-foo", the comment:
+//
+//	Foo does things.
+//
+//	protoc-gen-foo:ignore
+//	protoc-gen-foo:name=Bar
+//
+// results in the directives {Name: "ignore"} and {Name: "name", Value: "Bar"}.
+//
+// Returns nil if the namespace is empty or there are no directives.
+func ParseCommentDirectives(namespace string, comment string) CommentDirectives {
+	if namespace == "" {
+		return nil
+	}
+	var commentDirectives CommentDirectives
+	for _, line := range strings.Split(comment, "\n") {
+		if commentDirective, ok := parseCommentDirective(namespace, line); ok {
+			commentDirectives = append(commentDirectives, commentDirective)
+		}
+	}
+	return commentDirectives
+}
+
+// DescriptorCommentDirectives returns the CommentDirectives with the namespace from the leading comments
+// of the descriptor.
+//
+// Only leading comments are considered, as trailing comments are commonly used for prose about the
+// element that follows. If the file was not compiled with SourceCodeInfo, or the descriptor has no leading
+// comments, this returns nil.
+func DescriptorCommentDirectives(namespace string, descriptor protoreflect.Descriptor) CommentDirectives {
+	parentFile := descriptor.ParentFile()
+	if parentFile == nil {
+		return nil
+	}
+	return ParseCommentDirectives(namespace, parentFile.SourceLocations().ByDescriptor(descriptor).LeadingComments)
+}
+
+// StripCommentDirectives returns the comment with all lines that are CommentDirectives with the namespace
+// removed.
+//
+// This is useful to avoid emitting directives in documentation within generated code, for example
+// StripCommentDirectives("protoc-gen-foo", DescriptorComments(descriptor)). Leading and trailing empty
+// lines that result from removing directives are also removed.
+func StripCommentDirectives(namespace string, comment string) string {
+	if namespace == "" {
+		return comment
+	}
+	lines := strings.Split(comment, "\n")
+	keptLines := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if _, ok := parseCommentDirective(namespace, line); !ok {
+			keptLines = append(keptLines, line)
+		}
+	}
+	if len(keptLines) == len(lines) {
+		return comment
+	}
+	for len(keptLines) > 0 && strings.TrimSpace(keptLines[0]) == "" {
+		keptLines = keptLines[1:]
+	}
+	for len(keptLines) > 0 && strings.TrimSpace(keptLines[len(keptLines)-1]) == "" {
+		keptLines = keptLines[:len(keptLines)-1]
+	}
+	return strings.Join(keptLines, "\n")
+}
+
+// *** PRIVATE ***
+
+func parseCommentDirective(namespace string, line string) (CommentDirective, bool) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(line), namespace+":")
+	if !ok {
+		return CommentDirective{}, false
+	}
+	name, value, _ := strings.Cut(rest, "=")
+	name = strings.TrimRight(name, " \t")
+	if name == "" || strings.IndexFunc(name, isNotCommentDirectiveNameRune) >= 0 {
+		return CommentDirective{}, false
+	}
+	return CommentDirective{
+		Name:  name,
+		Value: strings.TrimSpace(value),
+	}, true
+}
+
+func isNotCommentDirectiveNameRune(r rune) bool {
+	return !((r >= 'a' && r <= 'z') ||
+		(r >= 'A' && r <= 'Z') ||
+		(r >= '0' && r <= '9') ||
+		r == '_' || r == '-' || r == '.')
+}
@@ -0,0 +1,91 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protopluginutil
+
+import (
+	"testing"
+
+	"github.com/bufbuild/protoplugin/internal/protocompiletest"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestParseCommentDirectives(t *testing.T) {
+	t.Parallel()
+
+	comment := ` Foo does things.
+
+ protoc-gen-foo:ignore
+ protoc-gen-foo:name = Bar Baz
+ protoc-gen-foo:not a directive
+ protoc-gen-bar:ignore
+ see protoc-gen-foo:ignore
+ protoc-gen-foo:name=Qux
+`
+	commentDirectives := ParseCommentDirectives("protoc-gen-foo", comment)
+	require.Equal(
+		t,
+		CommentDirectives{
+			{Name: "ignore"},
+			{Name: "name", Value: "Bar Baz"},
+			{Name: "name", Value: "Qux"},
+		},
+		commentDirectives,
+	)
+	require.True(t, commentDirectives.Has("ignore"))
+	require.False(t, commentDirectives.Has("other"))
+	value, ok := commentDirectives.Get("name")
+	require.True(t, ok)
+	require.Equal(t, "Qux", value)
+	require.Nil(t, ParseCommentDirectives("", comment))
+
+	require.Equal(
+		t,
+		" Foo does things.\n\n protoc-gen-foo:not a directive\n protoc-gen-bar:ignore\n see protoc-gen-foo:ignore",
+		StripCommentDirectives("protoc-gen-foo", comment),
+	)
+	require.Equal(t, "no directives\n", StripCommentDirectives("protoc-gen-foo", "no directives\n"))
+}
+
+func TestDescriptorCommentDirectives(t *testing.T) {
+	t.Parallel()
+
+	files := protocompiletest.CompileFiles(
+		t,
+		map[string][]byte{
+			"a.proto": []byte(`syntax = "proto3";
+package foo;
+
+// A is a message.
+// protoc-gen-foo:ignore
+message A {
+  string b = 1; // protoc-gen-foo:ignore
+  // protoc-gen-foo:name=C
+  string c = 2;
+}
+`),
+		},
+	)
+	directives := func(name protoreflect.FullName) CommentDirectives {
+		descriptor, err := files.FindDescriptorByName(name)
+		require.NoError(t, err)
+		return DescriptorCommentDirectives("protoc-gen-foo", descriptor)
+	}
+
+	require.Equal(t, CommentDirectives{{Name: "ignore"}}, directives("foo.A"))
+	// Trailing comments are not considered.
+	require.Nil(t, directives("foo.A.b"))
+	require.Equal(t, CommentDirectives{{Name: "name", Value: "C"}}, directives("foo.A.c"))
+}
@@ -0,0 +1,218 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protopluginutil
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// DescriptorComments returns the joined comments for the descriptor.
+//
+// This is a convenience function for JoinComments(descriptor.ParentFile().SourceLocations().ByDescriptor(descriptor)).
+// If the file was not compiled with SourceCodeInfo, or the descriptor has no comments, this returns the empty string.
+func DescriptorComments(descriptor protoreflect.Descriptor) string {
+	parentFile := descriptor.ParentFile()
+	if parentFile == nil {
+		return ""
+	}
+	return JoinComments(parentFile.SourceLocations().ByDescriptor(descriptor))
+}
+
+// JoinComments joins the leading and trailing comments of the SourceLocation into a single string.
+//
+// Leading detached comments are not included, as they are by definition not attached to the element.
+//
+// Comments are normalized: the single leading space that conventionally follows the comment marker is removed
+// from each line, trailing whitespace is removed from each line, and leading and trailing empty lines are
+// removed. The leading and trailing comments are separated by an empty line if both are present.
+func JoinComments(sourceLocation protoreflect.SourceLocation) string {
+	var parts []string
+	for _, comment := range []string{sourceLocation.LeadingComments, sourceLocation.TrailingComments} {
+		if normalized := normalizeComment(comment); normalized != "" {
+			parts = append(parts, normalized)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// SanitizeCommentOption is an option for SanitizeComment.
+type SanitizeCommentOption func(*sanitizeCommentOptions)
+
+// SanitizeCommentWithLinkRewriter returns a new SanitizeCommentOption that rewrites all URLs within
+// the comment with the given function.
+//
+// URLs are sequences of the form "scheme://..." up to the next whitespace or bracket, not including
+// trailing punctuation. This is useful to normalize links that are only valid within a monorepo, such as
+// links to internal code review or code search tools, before the comments are published in generated code.
+//
+// The default is to not rewrite URLs.
+func SanitizeCommentWithLinkRewriter(rewriteLink func(link string) string) SanitizeCommentOption {
+	return func(sanitizeCommentOptions *sanitizeCommentOptions) {
+		sanitizeCommentOptions.rewriteLink = rewriteLink
+	}
+}
+
+// SanitizeCommentWithLineWidth returns a new SanitizeCommentOption that wraps lines longer than the given
+// width in bytes.
+//
+// Lines are wrapped at spaces, and words longer than the width, such as URLs, are never split. Lines that
+// begin with whitespace, such as indented code blocks, are never wrapped. The width does not include any
+// comment markers that the generator adds, so generators should subtract the length of their comment markers.
+//
+// The default is to not wrap lines.
+func SanitizeCommentWithLineWidth(width int) SanitizeCommentOption {
+	return func(sanitizeCommentOptions *sanitizeCommentOptions) {
+		sanitizeCommentOptions.lineWidth = width
+	}
+}
+
+// SanitizeCommentWithEscapedDirectives returns a new SanitizeCommentOption that prefixes lines that begin
+// with any of the given directive prefixes with escape.
+//
+// This prevents comment lines from being interpreted as directives by the target language. For example,
+// a Go generator that emits each comment line as "//" followed by the line can use
+// SanitizeCommentWithEscapedDirectives(" ", "go:", "line ") so that a comment line of "go:generate" is
+// emitted as "// go:generate", which is not a directive.
+//
+// The escape is prepended to the line as-is, so it must be chosen such that the result is not a directive
+// in the target language.
+//
+// The default is to not escape any directives.
+func SanitizeCommentWithEscapedDirectives(escape string, directivePrefixes ...string) SanitizeCommentOption {
+	return func(sanitizeCommentOptions *sanitizeCommentOptions) {
+		sanitizeCommentOptions.directiveEscape = escape
+		sanitizeCommentOptions.directivePrefixes = directivePrefixes
+	}
+}
+
+// SanitizeComment sanitizes a comment, such as one returned from DescriptorComments, for safe embedding
+// within comments of generated code.
+//
+// Proto comments are written by humans for humans, and frequently contain sequences that break compilation
+// of generated code when forwarded naively. The following is always performed:
+//
+//   - Carriage returns are removed, so that line endings are "\n".
+//   - "*/" is replaced with "* /", and "/*" is replaced with "/ *", so that the comment cannot terminate,
+//     or be interpreted as opening, a block comment in C-style languages.
+//   - Control characters other than newlines and tabs are removed.
+//
+// Additional sanitization, such as rewriting links, wrapping lines, and escaping directives, can be
+// enabled with SanitizeCommentOptions. Links are rewritten first, then lines are wrapped, then directives
+// are escaped on each resulting line. Escaped lines may therefore exceed the line width by the length of
+// the escape.
+//
+// The returned comment does not include any comment markers - generators are responsible for adding
+// these to each line.
+func SanitizeComment(comment string, options ...SanitizeCommentOption) string {
+	sanitizeCommentOptions := newSanitizeCommentOptions()
+	for _, option := range options {
+		option(sanitizeCommentOptions)
+	}
+	comment = strings.Map(removeControlCharacter, comment)
+	if sanitizeCommentOptions.rewriteLink != nil {
+		comment = linkRegexp.ReplaceAllStringFunc(comment, sanitizeCommentOptions.rewriteLink)
+	}
+	// Replace iteratively, as replacing may result in new sequences, for example "/*/" becomes "/ */".
+	for strings.Contains(comment, "*/") || strings.Contains(comment, "/*") {
+		comment = strings.ReplaceAll(comment, "*/", "* /")
+		comment = strings.ReplaceAll(comment, "/*", "/ *")
+	}
+	lines := strings.Split(comment, "\n")
+	sanitizedLines := make([]string, 0, len(lines))
+	for _, line := range lines {
+		// Escape after wrapping, as wrapping may result in new lines that begin with a directive prefix.
+		for _, wrappedLine := range wrapLine(line, sanitizeCommentOptions.lineWidth) {
+			sanitizedLines = append(sanitizedLines, sanitizeCommentOptions.escapeDirective(wrappedLine))
+		}
+	}
+	return strings.Join(sanitizedLines, "\n")
+}
+
+// *** PRIVATE ***
+
+// linkRegexp matches URLs, not including trailing punctuation.
+var linkRegexp = regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^\s<>"'()\[\]{}]*[^\s<>"'()\[\]{}.,;:!?]`)
+
+type sanitizeCommentOptions struct {
+	rewriteLink       func(string) string
+	lineWidth         int
+	directiveEscape   string
+	directivePrefixes []string
+}
+
+func newSanitizeCommentOptions() *sanitizeCommentOptions {
+	return &sanitizeCommentOptions{}
+}
+
+func (s *sanitizeCommentOptions) escapeDirective(line string) string {
+	for _, directivePrefix := range s.directivePrefixes {
+		if directivePrefix != "" && strings.HasPrefix(line, directivePrefix) {
+			return s.directiveEscape + line
+		}
+	}
+	return line
+}
+
+// removeControlCharacter is a strings.Map function that removes control characters other than newlines and tabs.
+func removeControlCharacter(r rune) rune {
+	if r != '\n' && r != '\t' && unicode.IsControl(r) {
+		return -1
+	}
+	return r
+}
+
+// wrapLine wraps the line at spaces such that each resulting line is at most width bytes, unless a single
+// word is longer than width.
+//
+// Lines that begin with whitespace are not wrapped. If width is zero or less, the line is not wrapped.
+func wrapLine(line string, width int) []string {
+	if width <= 0 || len(line) <= width || strings.TrimLeft(line, " \t") != line {
+		return []string{line}
+	}
+	var lines []string
+	var builder strings.Builder
+	for _, word := range strings.Fields(line) {
+		if builder.Len() > 0 && builder.Len()+1+len(word) > width {
+			lines = append(lines, builder.String())
+			builder.Reset()
+		}
+		if builder.Len() > 0 {
+			_, _ = builder.WriteString(" ")
+		}
+		_, _ = builder.WriteString(word)
+	}
+	if builder.Len() > 0 {
+		lines = append(lines, builder.String())
+	}
+	return lines
+}
+
+func normalizeComment(comment string) string {
+	lines := strings.Split(comment, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(strings.TrimPrefix(line, " "), " \t\r")
+	}
+	for len(lines) > 0 && lines[0] == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
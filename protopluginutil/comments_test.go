@@ -0,0 +1,133 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protopluginutil
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bufbuild/protoplugin/internal/protocompiletest"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestDescriptorComments(t *testing.T) {
+	t.Parallel()
+
+	files := protocompiletest.CompileFiles(
+		t,
+		map[string][]byte{
+			"a.proto": []byte(`syntax = "proto3";
+package foo;
+
+// Detached.
+
+// A is a message.
+//
+//   Indented.
+message A {
+  string b = 1; // b is a field.
+  // c is a field.
+  string c = 2; // c trailing.
+  string d = 3;
+}
+
+/*
+ * Block comment.
+ */
+enum E { E_ZERO = 0; }
+`),
+		},
+	)
+	comments := func(name protoreflect.FullName) string {
+		descriptor, err := files.FindDescriptorByName(name)
+		require.NoError(t, err)
+		return DescriptorComments(descriptor)
+	}
+
+	require.Equal(t, "A is a message.\n\n  Indented.", comments("foo.A"))
+	require.Equal(t, "b is a field.", comments("foo.A.b"))
+	require.Equal(t, "c is a field.\n\nc trailing.", comments("foo.A.c"))
+	require.Equal(t, "", comments("foo.A.d"))
+	require.Equal(t, "Block comment.", comments("foo.E"))
+}
+
+func TestSanitizeComment(t *testing.T) {
+	t.Parallel()
+
+	for _, testCase := range []struct {
+		name     string
+		comment  string
+		options  []SanitizeCommentOption
+		expected string
+	}{
+		{
+			name:     "unchanged",
+			comment:  "A is a message.\n\n  Indented.",
+			expected: "A is a message.\n\n  Indented.",
+		},
+		{
+			name:     "block_comments",
+			comment:  "Matches a/*/b and ends with */",
+			expected: "Matches a/ * /b and ends with * /",
+		},
+		{
+			name:     "control_characters",
+			comment:  "a\r\nb\x00c\td",
+			expected: "a\nbc\td",
+		},
+		{
+			name:    "link_rewriter",
+			comment: "See https://internal.example.com/foo, and (http://internal.example.com/bar).",
+			options: []SanitizeCommentOption{
+				SanitizeCommentWithLinkRewriter(func(link string) string {
+					return strings.Replace(link, "internal.example.com", "example.com", 1)
+				}),
+			},
+			expected: "See https://example.com/foo, and (http://example.com/bar).",
+		},
+		{
+			name:    "escaped_directives",
+			comment: "go:generate foo\nline 1\nnot go:generate",
+			options: []SanitizeCommentOption{
+				SanitizeCommentWithEscapedDirectives(" ", "go:", "line "),
+			},
+			expected: " go:generate foo\n line 1\nnot go:generate",
+		},
+		{
+			name:    "escaped_directives_after_wrapping",
+			comment: "run this: go:generate foo",
+			options: []SanitizeCommentOption{
+				SanitizeCommentWithLineWidth(10),
+				SanitizeCommentWithEscapedDirectives(" ", "go:"),
+			},
+			expected: "run this:\n go:generate\nfoo",
+		},
+		{
+			name:    "line_width",
+			comment: "The quick brown fox jumps over https://example.com/a/very/long/link\n    indented code that is not wrapped\n\nshort",
+			options: []SanitizeCommentOption{
+				SanitizeCommentWithLineWidth(16),
+			},
+			expected: "The quick brown\nfox jumps over\nhttps://example.com/a/very/long/link\n    indented code that is not wrapped\n\nshort",
+		},
+	} {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, testCase.expected, SanitizeComment(testCase.comment, testCase.options...))
+		})
+	}
+}
@@ -0,0 +1,360 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protopluginutil
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// ConvertEditionsToProto3Like converts a FileDescriptorProto that uses Editions into an equivalent
+// FileDescriptorProto that uses proto3 or proto2 syntax.
+//
+// This allows plugins that have not yet implemented Editions support to opt into an automatic downgrade
+// path. Conversion to proto3 is attempted first, and if the file uses features that cannot be represented
+// in proto3, conversion to proto2 is attempted. If the file cannot be represented in either syntax, an error
+// describing the unsupported features is returned.
+//
+// The conversion performs the following:
+//
+//   - Singular scalar fields with explicit presence become proto3 optional fields in proto3.
+//   - Fields with the field_presence feature resolved to LEGACY_REQUIRED become required fields in proto2.
+//   - Message fields with the message_encoding feature resolved to DELIMITED become group fields in proto2,
+//     if the field satisfies the restrictions placed on groups.
+//   - The repeated_field_encoding feature is converted to the packed option where it differs from the default
+//     of the target syntax.
+//   - All features are removed from options, and the edition is cleared.
+//
+// Features that cannot be represented include, among others: implicit presence in proto2, closed enums and
+// LEGACY_BEST_EFFORT JSON handling in proto3, and UTF-8 validation settings that differ from the semantics of
+// the target syntax. Only the given file is inspected, so references to enums in other files are not checked
+// for compatibility with the target syntax.
+//
+// If the file does not use Editions, a copy of the file is returned unmodified. The input FileDescriptorProto
+// is never modified.
+func ConvertEditionsToProto3Like(
+	fileDescriptorProto *descriptorpb.FileDescriptorProto,
+) (*descriptorpb.FileDescriptorProto, error) {
+	if fileDescriptorProto.GetSyntax() != "editions" {
+		return cloneFileDescriptorProto(fileDescriptorProto)
+	}
+	defaults, err := FeatureSetDefaultsForEdition(fileDescriptorProto.GetEdition())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fileDescriptorProto.GetName(), err)
+	}
+	var errs []error
+	for _, syntax := range []string{"proto3", "proto2"} {
+		converted, err := cloneFileDescriptorProto(fileDescriptorProto)
+		if err != nil {
+			return nil, err
+		}
+		converter := &editionsConverter{syntax: syntax}
+		if err := converter.convertFile(converted, defaults); err != nil {
+			errs = append(errs, fmt.Errorf("cannot convert to %s: %w", syntax, err))
+			continue
+		}
+		return converted, nil
+	}
+	return nil, fmt.Errorf("%s: %w", fileDescriptorProto.GetName(), errors.Join(errs...))
+}
+
+// *** PRIVATE ***
+
+type editionsConverter struct {
+	// Either "proto2" or "proto3".
+	syntax string
+}
+
+func (c *editionsConverter) isProto3() bool {
+	return c.syntax == "proto3"
+}
+
+func (c *editionsConverter) convertFile(file *descriptorpb.FileDescriptorProto, defaults *descriptorpb.FeatureSet) error {
+	features := mergeFeatures(defaults, file.GetOptions().GetFeatures())
+	scopeName := file.GetPackage()
+	scopeMessageNames := messageNames(file.GetMessageType())
+	for _, message := range file.GetMessageType() {
+		if err := c.convertMessage(message, features, scopeName); err != nil {
+			return err
+		}
+	}
+	for _, enum := range file.GetEnumType() {
+		if err := c.convertEnum(enum, features, scopeName); err != nil {
+			return err
+		}
+	}
+	for _, extension := range file.GetExtension() {
+		fieldFeatures := mergeFeatures(features, extension.GetOptions().GetFeatures())
+		if err := c.convertField(extension, fieldFeatures, scopeName, scopeMessageNames, true, false); err != nil {
+			return err
+		}
+	}
+	for _, service := range file.GetService() {
+		clearFeatures(service.GetOptions())
+		for _, method := range service.GetMethod() {
+			clearFeatures(method.GetOptions())
+		}
+	}
+	clearFeatures(file.GetOptions())
+	file.Syntax = proto.String(c.syntax)
+	file.Edition = nil
+	return nil
+}
+
+func (c *editionsConverter) convertMessage(
+	message *descriptorpb.DescriptorProto,
+	parentFeatures *descriptorpb.FeatureSet,
+	parentScopeName string,
+) error {
+	scopeName := joinName(parentScopeName, message.GetName())
+	features := mergeFeatures(parentFeatures, message.GetOptions().GetFeatures())
+	if c.isProto3() {
+		if features.GetJsonFormat() == descriptorpb.FeatureSet_LEGACY_BEST_EFFORT {
+			return fmt.Errorf("message %q: LEGACY_BEST_EFFORT json_format is not supported", scopeName)
+		}
+		if len(message.GetExtensionRange()) > 0 {
+			return fmt.Errorf("message %q: extension ranges are not supported", scopeName)
+		}
+	}
+	isMapEntry := message.GetOptions().GetMapEntry()
+	scopeMessageNames := messageNames(message.GetNestedType())
+	oneofFeatures := make([]*descriptorpb.FeatureSet, len(message.GetOneofDecl()))
+	oneofNames := make(map[string]struct{}, len(message.GetOneofDecl()))
+	for i, oneof := range message.GetOneofDecl() {
+		oneofFeatures[i] = mergeFeatures(features, oneof.GetOptions().GetFeatures())
+		oneofNames[oneof.GetName()] = struct{}{}
+		clearFeatures(oneof.GetOptions())
+	}
+	for _, field := range message.GetField() {
+		fieldParentFeatures := features
+		if field.OneofIndex != nil {
+			oneofIndex := int(field.GetOneofIndex())
+			if oneofIndex < 0 || oneofIndex >= len(oneofFeatures) {
+				return fmt.Errorf("field %q: invalid oneof_index %d", joinName(scopeName, field.GetName()), oneofIndex)
+			}
+			fieldParentFeatures = oneofFeatures[oneofIndex]
+		}
+		fieldFeatures := mergeFeatures(fieldParentFeatures, field.GetOptions().GetFeatures())
+		if err := c.convertField(field, fieldFeatures, scopeName, scopeMessageNames, false, isMapEntry); err != nil {
+			return err
+		}
+		if field.GetProto3Optional() {
+			// Synthetic oneofs must come after all real oneofs, which is guaranteed by appending.
+			oneofName := "_" + field.GetName()
+			for {
+				if _, ok := oneofNames[oneofName]; !ok {
+					break
+				}
+				oneofName = "X" + oneofName
+			}
+			oneofNames[oneofName] = struct{}{}
+			field.OneofIndex = proto.Int32(int32(len(message.GetOneofDecl()))) // #nosec:G115 should never overflow
+			message.OneofDecl = append(message.GetOneofDecl(), &descriptorpb.OneofDescriptorProto{Name: proto.String(oneofName)})
+		}
+	}
+	for _, extension := range message.GetExtension() {
+		fieldFeatures := mergeFeatures(features, extension.GetOptions().GetFeatures())
+		if err := c.convertField(extension, fieldFeatures, scopeName, scopeMessageNames, true, false); err != nil {
+			return err
+		}
+	}
+	for _, nestedMessage := range message.GetNestedType() {
+		if err := c.convertMessage(nestedMessage, features, scopeName); err != nil {
+			return err
+		}
+	}
+	for _, enum := range message.GetEnumType() {
+		if err := c.convertEnum(enum, features, scopeName); err != nil {
+			return err
+		}
+	}
+	clearFeatures(message.GetOptions())
+	return nil
+}
+
+func (c *editionsConverter) convertField(
+	field *descriptorpb.FieldDescriptorProto,
+	features *descriptorpb.FeatureSet,
+	scopeName string,
+	// The names of the messages defined in the same scope as the field, used to validate groups.
+	scopeMessageNames map[string]struct{},
+	isExtension bool,
+	isMapEntry bool,
+) error {
+	name := joinName(scopeName, field.GetName())
+	if c.isProto3() && field.DefaultValue != nil {
+		return fmt.Errorf("field %q: default values are not supported", name)
+	}
+	switch field.GetLabel() {
+	case descriptorpb.FieldDescriptorProto_LABEL_REPEATED:
+		if isPackableType(field.GetType()) {
+			repeatedFieldEncoding := features.GetRepeatedFieldEncoding()
+			switch {
+			case c.isProto3() && repeatedFieldEncoding == descriptorpb.FeatureSet_EXPANDED:
+				fieldOptions(field).Packed = proto.Bool(false)
+			case !c.isProto3() && repeatedFieldEncoding == descriptorpb.FeatureSet_PACKED:
+				fieldOptions(field).Packed = proto.Bool(true)
+			}
+		}
+	case descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL:
+		if isMapEntry {
+			break
+		}
+		switch features.GetFieldPresence() {
+		case descriptorpb.FeatureSet_LEGACY_REQUIRED:
+			if c.isProto3() {
+				return fmt.Errorf("field %q: required fields are not supported", name)
+			}
+			field.Label = descriptorpb.FieldDescriptorProto_LABEL_REQUIRED.Enum()
+		case descriptorpb.FeatureSet_IMPLICIT:
+			if !c.isProto3() && !isMessageType(field.GetType()) && field.OneofIndex == nil && !isExtension {
+				return fmt.Errorf("field %q: implicit presence is not supported", name)
+			}
+		case descriptorpb.FeatureSet_EXPLICIT:
+			if c.isProto3() && !isMessageType(field.GetType()) && field.OneofIndex == nil && !isExtension {
+				field.Proto3Optional = proto.Bool(true)
+			}
+		}
+	}
+	if field.GetType() == descriptorpb.FieldDescriptorProto_TYPE_MESSAGE &&
+		features.GetMessageEncoding() == descriptorpb.FeatureSet_DELIMITED &&
+		!isMapEntry {
+		if c.isProto3() {
+			return fmt.Errorf("field %q: delimited message encoding is not supported", name)
+		}
+		// Groups must be named after their message type in lowercase, and the message type must be
+		// defined in the same scope as the field.
+		messageName := strings.TrimPrefix(field.GetTypeName(), "."+joinName(scopeName, ""))
+		if _, ok := scopeMessageNames[messageName]; !ok || strings.ToLower(messageName) != field.GetName() {
+			return fmt.Errorf("field %q: delimited message encoding can only be represented as a group", name)
+		}
+		field.Type = descriptorpb.FieldDescriptorProto_TYPE_GROUP.Enum()
+	}
+	if field.GetType() == descriptorpb.FieldDescriptorProto_TYPE_STRING {
+		utf8Validation := features.GetUtf8Validation()
+		if c.isProto3() && utf8Validation != descriptorpb.FeatureSet_VERIFY {
+			return fmt.Errorf("field %q: disabling UTF-8 validation is not supported", name)
+		}
+		if !c.isProto3() && utf8Validation != descriptorpb.FeatureSet_NONE {
+			return fmt.Errorf("field %q: UTF-8 validation is not supported", name)
+		}
+	}
+	clearFeatures(field.GetOptions())
+	return nil
+}
+
+func (c *editionsConverter) convertEnum(
+	enum *descriptorpb.EnumDescriptorProto,
+	parentFeatures *descriptorpb.FeatureSet,
+	parentScopeName string,
+) error {
+	name := joinName(parentScopeName, enum.GetName())
+	features := mergeFeatures(parentFeatures, enum.GetOptions().GetFeatures())
+	switch features.GetEnumType() {
+	case descriptorpb.FeatureSet_CLOSED:
+		if c.isProto3() {
+			return fmt.Errorf("enum %q: closed enums are not supported", name)
+		}
+	case descriptorpb.FeatureSet_OPEN:
+		if !c.isProto3() {
+			return fmt.Errorf("enum %q: open enums are not supported", name)
+		}
+	}
+	if c.isProto3() {
+		if features.GetJsonFormat() == descriptorpb.FeatureSet_LEGACY_BEST_EFFORT {
+			return fmt.Errorf("enum %q: LEGACY_BEST_EFFORT json_format is not supported", name)
+		}
+		if values := enum.GetValue(); len(values) > 0 && values[0].GetNumber() != 0 {
+			return fmt.Errorf("enum %q: first value must be zero", name)
+		}
+	}
+	for _, value := range enum.GetValue() {
+		clearFeatures(value.GetOptions())
+	}
+	clearFeatures(enum.GetOptions())
+	return nil
+}
+
+// mergeFeatures returns a new FeatureSet with the features of child overriding the features of parent.
+func mergeFeatures(parent *descriptorpb.FeatureSet, child *descriptorpb.FeatureSet) *descriptorpb.FeatureSet {
+	if child == nil {
+		return parent
+	}
+	merged, _ := proto.Clone(parent).(*descriptorpb.FeatureSet)
+	proto.Merge(merged, child)
+	return merged
+}
+
+// clearFeatures clears the features field on the options message, if present.
+func clearFeatures(options proto.Message) {
+	message := options.ProtoReflect()
+	if !message.IsValid() {
+		return
+	}
+	if featuresField := message.Descriptor().Fields().ByName("features"); featuresField != nil {
+		message.Clear(featuresField)
+	}
+}
+
+func fieldOptions(field *descriptorpb.FieldDescriptorProto) *descriptorpb.FieldOptions {
+	if field.Options == nil {
+		field.Options = &descriptorpb.FieldOptions{}
+	}
+	return field.Options
+}
+
+func messageNames(messages []*descriptorpb.DescriptorProto) map[string]struct{} {
+	names := make(map[string]struct{}, len(messages))
+	for _, message := range messages {
+		names[message.GetName()] = struct{}{}
+	}
+	return names
+}
+
+func joinName(scopeName string, name string) string {
+	if scopeName == "" {
+		return name
+	}
+	return scopeName + "." + name
+}
+
+func isMessageType(fieldType descriptorpb.FieldDescriptorProto_Type) bool {
+	return fieldType == descriptorpb.FieldDescriptorProto_TYPE_MESSAGE ||
+		fieldType == descriptorpb.FieldDescriptorProto_TYPE_GROUP
+}
+
+func isPackableType(fieldType descriptorpb.FieldDescriptorProto_Type) bool {
+	switch fieldType {
+	case descriptorpb.FieldDescriptorProto_TYPE_STRING,
+		descriptorpb.FieldDescriptorProto_TYPE_BYTES,
+		descriptorpb.FieldDescriptorProto_TYPE_MESSAGE,
+		descriptorpb.FieldDescriptorProto_TYPE_GROUP:
+		return false
+	default:
+		return true
+	}
+}
+
+func cloneFileDescriptorProto(fileDescriptorProto *descriptorpb.FileDescriptorProto) (*descriptorpb.FileDescriptorProto, error) {
+	clone, ok := proto.Clone(fileDescriptorProto).(*descriptorpb.FileDescriptorProto)
+	if !ok {
+		return nil, fmt.Errorf("could not clone %T", fileDescriptorProto)
+	}
+	return clone, nil
+}
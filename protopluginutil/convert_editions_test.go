@@ -0,0 +1,137 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protopluginutil
+
+import (
+	"testing"
+
+	"github.com/bufbuild/protoplugin/internal/protocompiletest"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestConvertEditionsToProto3Like(t *testing.T) {
+	t.Parallel()
+
+	files := protocompiletest.CompileFiles(
+		t,
+		map[string][]byte{
+			"proto3_like.proto": []byte(`
+				edition = "2023";
+				package proto3_like;
+				message A {
+					int32 explicit_field = 1;
+					int32 implicit_field = 2 [features.field_presence = IMPLICIT];
+					repeated int32 packed_field = 3;
+					repeated int32 expanded_field = 4 [features.repeated_field_encoding = EXPANDED];
+					A message_field = 5;
+					map<string, A> map_field = 6;
+					oneof o { string oneof_field = 7; }
+				}
+				enum E { E_ZERO = 0; }
+			`),
+			"proto2_like.proto": []byte(`
+				edition = "2023";
+				package proto2_like;
+				option features.enum_type = CLOSED;
+				option features.utf8_validation = NONE;
+				message A {
+					int32 explicit_field = 1 [default = 5];
+					int32 required_field = 2 [features.field_presence = LEGACY_REQUIRED];
+					repeated int32 packed_field = 3;
+					message Group { string a = 1; }
+					Group group = 4 [features.message_encoding = DELIMITED];
+					string string_field = 5;
+				}
+				enum E { E_ONE = 1; }
+			`),
+			"unconvertible.proto": []byte(`
+				edition = "2023";
+				package unconvertible;
+				message A {
+					int32 implicit_field = 1 [features.field_presence = IMPLICIT];
+				}
+				enum E { option features.enum_type = CLOSED; E_ZERO = 0; }
+			`),
+		},
+	)
+	convert := func(path string) (*descriptorpb.FileDescriptorProto, protoreflect.FileDescriptor, error) {
+		fileDescriptor, err := files.FindFileByPath(path)
+		require.NoError(t, err)
+		fileDescriptorProto := protodesc.ToFileDescriptorProto(fileDescriptor)
+		converted, err := ConvertEditionsToProto3Like(fileDescriptorProto)
+		// The input should never be modified.
+		require.Equal(t, "editions", fileDescriptorProto.GetSyntax())
+		if err != nil {
+			return nil, nil, err
+		}
+		// The converted file should be valid.
+		convertedFileDescriptor, err := protodesc.NewFile(converted, files)
+		require.NoError(t, err)
+		return converted, convertedFileDescriptor, nil
+	}
+
+	converted, fileDescriptor, err := convert("proto3_like.proto")
+	require.NoError(t, err)
+	require.Equal(t, "proto3", converted.GetSyntax())
+	require.Zero(t, converted.GetEdition())
+	message := fileDescriptor.Messages().ByName("A")
+	require.True(t, message.Fields().ByName("explicit_field").HasOptionalKeyword())
+	require.False(t, message.Fields().ByName("implicit_field").HasPresence())
+	require.True(t, message.Fields().ByName("packed_field").IsPacked())
+	require.False(t, message.Fields().ByName("expanded_field").IsPacked())
+	require.True(t, message.Fields().ByName("map_field").IsMap())
+	require.Equal(t, "o", string(message.Fields().ByName("oneof_field").ContainingOneof().Name()))
+	require.Equal(t, "_explicit_field", string(message.Fields().ByName("explicit_field").ContainingOneof().Name()))
+	requireSameFieldPresence(t, files, "proto3_like.A", message)
+
+	converted, fileDescriptor, err = convert("proto2_like.proto")
+	require.NoError(t, err)
+	require.Equal(t, "proto2", converted.GetSyntax())
+	message = fileDescriptor.Messages().ByName("A")
+	require.Equal(t, protoreflect.Required, message.Fields().ByName("required_field").Cardinality())
+	require.True(t, message.Fields().ByName("packed_field").IsPacked())
+	require.Equal(t, protoreflect.GroupKind, message.Fields().ByName("group").Kind())
+	require.Equal(t, int32(5), int32(message.Fields().ByName("explicit_field").Default().Int()))
+	require.True(t, fileDescriptor.Enums().ByName("E").IsClosed())
+	requireSameFieldPresence(t, files, "proto2_like.A", message)
+
+	_, _, err = convert("unconvertible.proto")
+	require.ErrorContains(t, err, `cannot convert to proto3: enum "unconvertible.E": closed enums are not supported`)
+	require.ErrorContains(t, err, `cannot convert to proto2: field "unconvertible.A.implicit_field": implicit presence is not supported`)
+}
+
+func requireSameFieldPresence(
+	t *testing.T,
+	files interface {
+		FindDescriptorByName(protoreflect.FullName) (protoreflect.Descriptor, error)
+	},
+	name protoreflect.FullName,
+	converted protoreflect.MessageDescriptor,
+) {
+	descriptor, err := files.FindDescriptorByName(name)
+	require.NoError(t, err)
+	original, ok := descriptor.(protoreflect.MessageDescriptor)
+	require.True(t, ok)
+	for i := 0; i < original.Fields().Len(); i++ {
+		originalField := original.Fields().Get(i)
+		convertedField := converted.Fields().ByName(originalField.Name())
+		require.NotNil(t, convertedField)
+		require.Equal(t, originalField.HasPresence(), convertedField.HasPresence(), originalField.FullName())
+		require.Equal(t, originalField.Kind(), convertedField.Kind(), originalField.FullName())
+	}
+}
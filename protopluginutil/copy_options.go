@@ -0,0 +1,296 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protopluginutil
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// CopyOptionsOption is an option for CopyOptions.
+type CopyOptionsOption func(*copyOptionsOptions)
+
+// CopyOptionsWithSourceRetentionOptions returns a new CopyOptionsOption that says to copy options with
+// source retention.
+//
+// This is useful for plugins that emit .proto source files, where source-retention options are meaningful.
+//
+// The default is to not copy options with source retention, as these are not meant to be available at
+// runtime, and generated descriptors are usually embedded into generated code.
+func CopyOptionsWithSourceRetentionOptions() CopyOptionsOption {
+	return func(copyOptionsOptions *copyOptionsOptions) {
+		copyOptionsOptions.sourceRetentionOptions = true
+	}
+}
+
+// CopyOptionsWithIllegalOptionsDropped returns a new CopyOptionsOption that says to silently drop options
+// that are not legal at the destination, instead of returning an error.
+//
+// The default is to return an error if any option is not legal at the destination.
+func CopyOptionsWithIllegalOptionsDropped() CopyOptionsOption {
+	return func(copyOptionsOptions *copyOptionsOptions) {
+		copyOptionsOptions.dropIllegalOptions = true
+	}
+}
+
+// CopyOptions copies the options set on the source options message to the destination options message.
+//
+// This is useful for plugins that emit new descriptors derived from existing descriptors, for example
+// gateway protos, and need to carry over options from the existing descriptors. The source and destination
+// must be options messages from descriptor.proto, such as a *descriptorpb.MethodOptions, but do not need to
+// be of the same type. For example, a plugin that generates a message for every field can copy the options
+// of a *descriptorpb.FieldOptions to a *descriptorpb.MessageOptions.
+//
+// An option is legal at the destination if:
+//
+//   - The destination has a field with the same name, kind, and type, for standard options such as
+//     deprecated and features. Custom options must extend the type of the destination.
+//   - The targets of the option, if any, include the element type of the destination. For example,
+//     FieldOptions are used for fields, so the destination element type of a *descriptorpb.FieldOptions
+//     is TARGET_TYPE_FIELD. This is also validated for the fields of message-typed options, such as
+//     the fields of the FeatureSet within features.
+//
+// If an option is not legal at the destination, an error is returned, unless CopyOptionsWithIllegalOptionsDropped
+// is specified. Options with source retention are not copied unless CopyOptionsWithSourceRetentionOptions is
+// specified. The uninterpreted_option field is never copied. Unknown fields, such as custom options that were
+// not resolved, are only copied if the source and destination are of the same type.
+//
+// Options that are set on both the source and destination are overwritten with the value from the source.
+// Copied values are deep copies. If an error is returned, the destination may have been partially modified.
+func CopyOptions(destination proto.Message, source proto.Message, options ...CopyOptionsOption) error {
+	copyOptionsOptions := newCopyOptionsOptions()
+	for _, option := range options {
+		option(copyOptionsOptions)
+	}
+	destinationMessage := destination.ProtoReflect()
+	sourceMessage := source.ProtoReflect()
+	targetType, ok := optionsMessageTargetType[destinationMessage.Descriptor().FullName()]
+	if !ok {
+		return fmt.Errorf("destination %s is not an options message", destinationMessage.Descriptor().FullName())
+	}
+	if _, ok := optionsMessageTargetType[sourceMessage.Descriptor().FullName()]; !ok {
+		return fmt.Errorf("source %s is not an options message", sourceMessage.Descriptor().FullName())
+	}
+	copier := &optionsCopier{
+		targetType:         targetType,
+		copyOptionsOptions: copyOptionsOptions,
+	}
+	var err error
+	sourceMessage.Range(func(sourceField protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		if !sourceField.IsExtension() && sourceField.Name() == "uninterpreted_option" {
+			return true
+		}
+		var keep bool
+		keep, err = copier.keep(sourceField)
+		if err != nil || !keep {
+			return err == nil
+		}
+		destinationField := findDestinationOptionsField(destinationMessage.Descriptor(), sourceField)
+		if destinationField == nil {
+			err = copier.illegal(sourceField, fmt.Errorf("not an option of %s", destinationMessage.Descriptor().FullName()))
+			return err == nil
+		}
+		var copiedValue protoreflect.Value
+		copiedValue, err = copier.copyValue(destinationMessage, destinationField, value)
+		if err != nil {
+			return false
+		}
+		destinationMessage.Set(destinationField, copiedValue)
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	if destinationMessage.Descriptor().FullName() == sourceMessage.Descriptor().FullName() {
+		if unknown := sourceMessage.GetUnknown(); len(unknown) > 0 {
+			destinationMessage.SetUnknown(append(destinationMessage.GetUnknown(), unknown...))
+		}
+	}
+	return nil
+}
+
+// *** PRIVATE ***
+
+// optionsMessageTargetType is a map from the full name of each options message to the target type of
+// the elements that use the options message.
+var optionsMessageTargetType = map[protoreflect.FullName]descriptorpb.FieldOptions_OptionTargetType{
+	"google.protobuf.FileOptions":           descriptorpb.FieldOptions_TARGET_TYPE_FILE,
+	"google.protobuf.ExtensionRangeOptions": descriptorpb.FieldOptions_TARGET_TYPE_EXTENSION_RANGE,
+	"google.protobuf.MessageOptions":        descriptorpb.FieldOptions_TARGET_TYPE_MESSAGE,
+	"google.protobuf.FieldOptions":          descriptorpb.FieldOptions_TARGET_TYPE_FIELD,
+	"google.protobuf.OneofOptions":          descriptorpb.FieldOptions_TARGET_TYPE_ONEOF,
+	"google.protobuf.EnumOptions":           descriptorpb.FieldOptions_TARGET_TYPE_ENUM,
+	"google.protobuf.EnumValueOptions":      descriptorpb.FieldOptions_TARGET_TYPE_ENUM_ENTRY,
+	"google.protobuf.ServiceOptions":        descriptorpb.FieldOptions_TARGET_TYPE_SERVICE,
+	"google.protobuf.MethodOptions":         descriptorpb.FieldOptions_TARGET_TYPE_METHOD,
+}
+
+type copyOptionsOptions struct {
+	sourceRetentionOptions bool
+	dropIllegalOptions     bool
+}
+
+func newCopyOptionsOptions() *copyOptionsOptions {
+	return &copyOptionsOptions{}
+}
+
+type optionsCopier struct {
+	targetType descriptorpb.FieldOptions_OptionTargetType
+	*copyOptionsOptions
+}
+
+// keep returns true if the field should be copied.
+//
+// An error is returned if the field is not legal at the destination and illegal options are not dropped.
+func (o *optionsCopier) keep(field protoreflect.FieldDescriptor) (bool, error) {
+	fieldOptions, ok := field.Options().(*descriptorpb.FieldOptions)
+	if !ok {
+		return false, fmt.Errorf("field options is unexpected type: got %T, want %T", field.Options(), fieldOptions)
+	}
+	if !o.sourceRetentionOptions && fieldOptions.GetRetention() == descriptorpb.FieldOptions_RETENTION_SOURCE {
+		return false, nil
+	}
+	if targets := fieldOptions.GetTargets(); len(targets) > 0 {
+		for _, target := range targets {
+			if target == o.targetType {
+				return true, nil
+			}
+		}
+		return false, o.illegal(field, fmt.Errorf("targets do not include %v", o.targetType))
+	}
+	return true, nil
+}
+
+// illegal returns the error for an option that is not legal at the destination, or nil if illegal
+// options are dropped.
+func (o *optionsCopier) illegal(field protoreflect.FieldDescriptor, err error) error {
+	if o.dropIllegalOptions {
+		return nil
+	}
+	return fmt.Errorf("option %s is not legal for %v: %w", field.FullName(), o.targetType, err)
+}
+
+// copyValue returns a deep copy of the value of the field for the parent message.
+//
+// Message values are copied field by field, dropping fields that should not be kept.
+func (o *optionsCopier) copyValue(
+	parent protoreflect.Message,
+	field protoreflect.FieldDescriptor,
+	value protoreflect.Value,
+) (protoreflect.Value, error) {
+	switch {
+	case field.IsList():
+		sourceList := value.List()
+		list := parent.NewField(field).List()
+		for i := 0; i < sourceList.Len(); i++ {
+			element := sourceList.Get(i)
+			if field.Message() != nil {
+				message, err := o.copyMessage(list.NewElement().Message(), element.Message())
+				if err != nil {
+					return protoreflect.Value{}, err
+				}
+				element = protoreflect.ValueOfMessage(message)
+			} else if field.Kind() == protoreflect.BytesKind {
+				element = protoreflect.ValueOfBytes(append([]byte(nil), element.Bytes()...))
+			}
+			list.Append(element)
+		}
+		return protoreflect.ValueOfList(list), nil
+	case field.IsMap():
+		sourceMap := value.Map()
+		m := parent.NewField(field).Map()
+		sourceMap.Range(func(key protoreflect.MapKey, element protoreflect.Value) bool {
+			if field.MapValue().Message() != nil {
+				element = protoreflect.ValueOfMessage(proto.Clone(element.Message().Interface()).ProtoReflect())
+			} else if field.MapValue().Kind() == protoreflect.BytesKind {
+				element = protoreflect.ValueOfBytes(append([]byte(nil), element.Bytes()...))
+			}
+			m.Set(key, element)
+			return true
+		})
+		return protoreflect.ValueOfMap(m), nil
+	case field.Message() != nil:
+		message, err := o.copyMessage(parent.NewField(field).Message(), value.Message())
+		if err != nil {
+			return protoreflect.Value{}, err
+		}
+		return protoreflect.ValueOfMessage(message), nil
+	case field.Kind() == protoreflect.BytesKind:
+		return protoreflect.ValueOfBytes(append([]byte(nil), value.Bytes()...)), nil
+	default:
+		return value, nil
+	}
+}
+
+// copyMessage copies the fields of the source message that should be kept to the destination message,
+// which must be of the same type and empty.
+func (o *optionsCopier) copyMessage(destination protoreflect.Message, source protoreflect.Message) (protoreflect.Message, error) {
+	var err error
+	source.Range(func(field protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		var keep bool
+		keep, err = o.keep(field)
+		if err != nil || !keep {
+			return err == nil
+		}
+		var copiedValue protoreflect.Value
+		copiedValue, err = o.copyValue(destination, field, value)
+		if err != nil {
+			return false
+		}
+		destination.Set(field, copiedValue)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if unknown := source.GetUnknown(); len(unknown) > 0 {
+		destination.SetUnknown(append(protoreflect.RawFields(nil), unknown...))
+	}
+	return destination, nil
+}
+
+// findDestinationOptionsField returns the field of the destination options message that corresponds to
+// the field of the source options message, or nil if there is no such field.
+func findDestinationOptionsField(
+	destination protoreflect.MessageDescriptor,
+	sourceField protoreflect.FieldDescriptor,
+) protoreflect.FieldDescriptor {
+	if sourceField.IsExtension() {
+		if sourceField.ContainingMessage().FullName() != destination.FullName() {
+			return nil
+		}
+		return sourceField
+	}
+	if sourceField.ContainingMessage().FullName() == destination.FullName() {
+		return destination.Fields().ByNumber(sourceField.Number())
+	}
+	destinationField := destination.Fields().ByName(sourceField.Name())
+	if destinationField == nil ||
+		destinationField.Kind() != sourceField.Kind() ||
+		destinationField.Cardinality() != sourceField.Cardinality() ||
+		destinationField.IsMap() != sourceField.IsMap() {
+		return nil
+	}
+	if sourceMessage := sourceField.Message(); sourceMessage != nil && sourceMessage.FullName() != destinationField.Message().FullName() {
+		return nil
+	}
+	if sourceEnum := sourceField.Enum(); sourceEnum != nil && sourceEnum.FullName() != destinationField.Enum().FullName() {
+		return nil
+	}
+	return destinationField
+}
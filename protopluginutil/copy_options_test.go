@@ -0,0 +1,133 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protopluginutil
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func TestCopyOptions(t *testing.T) {
+	t.Parallel()
+
+	source := &descriptorpb.FieldOptions{
+		Deprecated: proto.Bool(true),
+		Features: &descriptorpb.FeatureSet{
+			FieldPresence: descriptorpb.FeatureSet_EXPLICIT.Enum(),
+			JsonFormat:    descriptorpb.FeatureSet_LEGACY_BEST_EFFORT.Enum(),
+		},
+	}
+	// field_presence cannot be set on messages.
+	err := CopyOptions(&descriptorpb.MessageOptions{}, source)
+	require.ErrorContains(t, err, "google.protobuf.FeatureSet.field_presence")
+	destination := &descriptorpb.MessageOptions{
+		NoStandardDescriptorAccessor: proto.Bool(true),
+	}
+	require.NoError(t, CopyOptions(destination, source, CopyOptionsWithIllegalOptionsDropped()))
+	require.Empty(
+		t,
+		cmp.Diff(
+			&descriptorpb.MessageOptions{
+				NoStandardDescriptorAccessor: proto.Bool(true),
+				Deprecated:                   proto.Bool(true),
+				Features: &descriptorpb.FeatureSet{
+					JsonFormat: descriptorpb.FeatureSet_LEGACY_BEST_EFFORT.Enum(),
+				},
+			},
+			destination,
+			protocmp.Transform(),
+		),
+	)
+	// The copy is a deep copy.
+	require.NotSame(t, source.GetFeatures(), destination.GetFeatures())
+
+	// ctype is not an option of MessageOptions.
+	err = CopyOptions(&descriptorpb.MessageOptions{}, &descriptorpb.FieldOptions{Ctype: descriptorpb.FieldOptions_CORD.Enum()})
+	require.ErrorContains(t, err, "not an option of google.protobuf.MessageOptions")
+
+	err = CopyOptions(&descriptorpb.MessageOptions{}, &descriptorpb.DescriptorProto{})
+	require.Error(t, err)
+}
+
+func TestCopyOptionsRetention(t *testing.T) {
+	t.Parallel()
+
+	fileDescriptor, err := protodesc.NewFile(
+		&descriptorpb.FileDescriptorProto{
+			Name:       proto.String("a.proto"),
+			Package:    proto.String("foo"),
+			Syntax:     proto.String("proto2"),
+			Dependency: []string{"google/protobuf/descriptor.proto"},
+			Extension: []*descriptorpb.FieldDescriptorProto{
+				{
+					Name:     proto.String("runtime"),
+					Number:   proto.Int32(50000),
+					Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+					Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+					Extendee: proto.String(".google.protobuf.MessageOptions"),
+				},
+				{
+					Name:     proto.String("source"),
+					Number:   proto.Int32(50001),
+					Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+					Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+					Extendee: proto.String(".google.protobuf.MessageOptions"),
+					Options: &descriptorpb.FieldOptions{
+						Retention: descriptorpb.FieldOptions_RETENTION_SOURCE.Enum(),
+					},
+				},
+			},
+		},
+		protoregistry.GlobalFiles,
+	)
+	require.NoError(t, err)
+	runtimeExtension := dynamicpb.NewExtensionType(fileDescriptor.Extensions().ByName("runtime"))
+	sourceExtension := dynamicpb.NewExtensionType(fileDescriptor.Extensions().ByName("source"))
+	source := &descriptorpb.MessageOptions{}
+	proto.SetExtension(source, runtimeExtension, protoreflect.ValueOfString("a").Interface())
+	proto.SetExtension(source, sourceExtension, protoreflect.ValueOfString("b").Interface())
+	source.ProtoReflect().SetUnknown(protowireUnknownField)
+
+	destination := &descriptorpb.MessageOptions{}
+	require.NoError(t, CopyOptions(destination, source))
+	require.True(t, proto.HasExtension(destination, runtimeExtension))
+	require.False(t, proto.HasExtension(destination, sourceExtension))
+	require.Equal(t, protowireUnknownField, destination.ProtoReflect().GetUnknown())
+
+	destination = &descriptorpb.MessageOptions{}
+	require.NoError(t, CopyOptions(destination, source, CopyOptionsWithSourceRetentionOptions()))
+	require.True(t, proto.HasExtension(destination, sourceExtension))
+
+	// Custom options of MessageOptions cannot be copied to EnumOptions.
+	err = CopyOptions(&descriptorpb.EnumOptions{}, source)
+	require.ErrorContains(t, err, "foo.runtime")
+	enumOptions := &descriptorpb.EnumOptions{}
+	require.NoError(t, CopyOptions(enumOptions, source, CopyOptionsWithIllegalOptionsDropped()))
+	// Unknown fields are only copied between options of the same type.
+	require.Empty(t, cmp.Diff(&descriptorpb.EnumOptions{}, enumOptions, protocmp.Transform()))
+}
+
+// protowireUnknownField is field 60000 with varint value 1.
+var protowireUnknownField = protoreflect.RawFields(protowire.AppendVarint(protowire.AppendTag(nil, 60000, protowire.VarintType), 1))
@@ -0,0 +1,316 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protopluginutil
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// ElementKind is the kind of an element of a FileDescriptorProto.
+type ElementKind int
+
+const (
+	// ElementKindFile is a file.
+	ElementKindFile ElementKind = iota + 1
+	// ElementKindMessage is a message.
+	ElementKindMessage
+	// ElementKindField is a field of a message.
+	ElementKindField
+	// ElementKindOneof is a oneof of a message.
+	ElementKindOneof
+	// ElementKindEnum is an enum.
+	ElementKindEnum
+	// ElementKindEnumValue is a value of an enum.
+	ElementKindEnumValue
+	// ElementKindService is a service.
+	ElementKindService
+	// ElementKindMethod is a method of a service.
+	ElementKindMethod
+	// ElementKindExtension is an extension.
+	ElementKindExtension
+)
+
+// String implements fmt.Stringer.
+func (e ElementKind) String() string {
+	switch e {
+	case ElementKindFile:
+		return "file"
+	case ElementKindMessage:
+		return "message"
+	case ElementKindField:
+		return "field"
+	case ElementKindOneof:
+		return "oneof"
+	case ElementKindEnum:
+		return "enum"
+	case ElementKindEnumValue:
+		return "enum value"
+	case ElementKindService:
+		return "service"
+	case ElementKindMethod:
+		return "method"
+	case ElementKindExtension:
+		return "extension"
+	default:
+		return fmt.Sprintf("ElementKind(%d)", int(e))
+	}
+}
+
+// ChangeKind is the kind of a Change.
+type ChangeKind int
+
+const (
+	// ChangeKindAdded says that the element was added.
+	ChangeKindAdded ChangeKind = iota + 1
+	// ChangeKindRemoved says that the element was removed.
+	ChangeKindRemoved
+	// ChangeKindNumberChanged says that the number of the field, extension, or enum value changed.
+	ChangeKindNumberChanged
+	// ChangeKindTypeChanged says that the type of the field or extension changed, including its
+	// label, or that the input type, output type, or streaming of the method changed.
+	ChangeKindTypeChanged
+	// ChangeKindOptionsChanged says that the options of the element changed.
+	ChangeKindOptionsChanged
+)
+
+// String implements fmt.Stringer.
+func (c ChangeKind) String() string {
+	switch c {
+	case ChangeKindAdded:
+		return "added"
+	case ChangeKindRemoved:
+		return "removed"
+	case ChangeKindNumberChanged:
+		return "number changed"
+	case ChangeKindTypeChanged:
+		return "type changed"
+	case ChangeKindOptionsChanged:
+		return "options changed"
+	default:
+		return fmt.Sprintf("ChangeKind(%d)", int(c))
+	}
+}
+
+// Change is a single change between two sets of FileDescriptorProtos.
+type Change struct {
+	// Kind is the kind of change.
+	Kind ChangeKind
+	// ElementKind is the kind of the element that changed.
+	ElementKind ElementKind
+	// Name is the name of the element that changed.
+	//
+	// For files, this is the path of the file. For all other elements, this is the fully-qualified
+	// name of the element, without a leading dot. As in Protobuf, enum values are scoped to the
+	// parent of their enum.
+	Name string
+	// OldNumber is the number of the field, extension, or enum value before the change.
+	//
+	// This is only set for ChangeKindNumberChanged.
+	OldNumber int32
+	// NewNumber is the number of the field, extension, or enum value after the change.
+	//
+	// This is only set for ChangeKindNumberChanged.
+	NewNumber int32
+}
+
+// String implements fmt.Stringer.
+func (c Change) String() string {
+	if c.Kind == ChangeKindNumberChanged {
+		return fmt.Sprintf("%s %s: %s from %d to %d", c.ElementKind, c.Name, c.Kind, c.OldNumber, c.NewNumber)
+	}
+	return fmt.Sprintf("%s %s: %s", c.ElementKind, c.Name, c.Kind)
+}
+
+// DiffFileDescriptorProtos returns the Changes between the old and new FileDescriptorProtos.
+//
+// This is useful for plugins that generate migration code or compatibility shims, which need to
+// compare the files in a CodeGeneratorRequest against a baseline set of FileDescriptorProtos.
+//
+// Elements are matched by their names, not by the files they are declared within, so moving an element
+// between files only results in the files themselves changing. Renaming an element results in the element
+// being removed and added.
+//
+// The returned Changes are sorted by Name, and then by ElementKind and Kind. If there are no changes,
+// an empty slice is returned.
+//
+// SourceCodeInfo is not considered.
+func DiffFileDescriptorProtos(oldFiles []*descriptorpb.FileDescriptorProto, newFiles []*descriptorpb.FileDescriptorProto) []Change {
+	oldElements := newDiffElements(oldFiles)
+	newElements := newDiffElements(newFiles)
+	var changes []Change
+	for key, oldElement := range oldElements {
+		newElement, ok := newElements[key]
+		if !ok {
+			changes = append(changes, Change{Kind: ChangeKindRemoved, ElementKind: key.elementKind, Name: key.name})
+			continue
+		}
+		if oldElement.number != newElement.number {
+			changes = append(
+				changes,
+				Change{
+					Kind:        ChangeKindNumberChanged,
+					ElementKind: key.elementKind,
+					Name:        key.name,
+					OldNumber:   oldElement.number,
+					NewNumber:   newElement.number,
+				},
+			)
+		}
+		if oldElement.typeSignature != newElement.typeSignature {
+			changes = append(changes, Change{Kind: ChangeKindTypeChanged, ElementKind: key.elementKind, Name: key.name})
+		}
+		if !proto.Equal(oldElement.options, newElement.options) {
+			changes = append(changes, Change{Kind: ChangeKindOptionsChanged, ElementKind: key.elementKind, Name: key.name})
+		}
+	}
+	for key := range newElements {
+		if _, ok := oldElements[key]; !ok {
+			changes = append(changes, Change{Kind: ChangeKindAdded, ElementKind: key.elementKind, Name: key.name})
+		}
+	}
+	sort.Slice(
+		changes,
+		func(i int, j int) bool {
+			if changes[i].Name != changes[j].Name {
+				return changes[i].Name < changes[j].Name
+			}
+			if changes[i].ElementKind != changes[j].ElementKind {
+				return changes[i].ElementKind < changes[j].ElementKind
+			}
+			return changes[i].Kind < changes[j].Kind
+		},
+	)
+	if changes == nil {
+		return []Change{}
+	}
+	return changes
+}
+
+// *** PRIVATE ***
+
+type diffElementKey struct {
+	elementKind ElementKind
+	name        string
+}
+
+type diffElement struct {
+	number        int32
+	typeSignature string
+	options       proto.Message
+}
+
+func newDiffElements(files []*descriptorpb.FileDescriptorProto) map[diffElementKey]*diffElement {
+	elements := make(map[diffElementKey]*diffElement)
+	for _, file := range files {
+		elements[diffElementKey{elementKind: ElementKindFile, name: file.GetName()}] = &diffElement{
+			options: file.GetOptions(),
+		}
+		scope := file.GetPackage()
+		addDiffElementsForMessages(elements, scope, file.GetMessageType())
+		addDiffElementsForEnums(elements, scope, file.GetEnumType())
+		addDiffElementsForExtensions(elements, scope, file.GetExtension())
+		for _, service := range file.GetService() {
+			serviceName := joinName(scope, service.GetName())
+			elements[diffElementKey{elementKind: ElementKindService, name: serviceName}] = &diffElement{
+				options: service.GetOptions(),
+			}
+			for _, method := range service.GetMethod() {
+				elements[diffElementKey{elementKind: ElementKindMethod, name: joinName(serviceName, method.GetName())}] = &diffElement{
+					typeSignature: fmt.Sprintf(
+						"%t %s %t %s",
+						method.GetClientStreaming(),
+						strings.TrimPrefix(method.GetInputType(), "."),
+						method.GetServerStreaming(),
+						strings.TrimPrefix(method.GetOutputType(), "."),
+					),
+					options: method.GetOptions(),
+				}
+			}
+		}
+	}
+	return elements
+}
+
+func addDiffElementsForMessages(
+	elements map[diffElementKey]*diffElement,
+	scope string,
+	messages []*descriptorpb.DescriptorProto,
+) {
+	for _, message := range messages {
+		messageName := joinName(scope, message.GetName())
+		elements[diffElementKey{elementKind: ElementKindMessage, name: messageName}] = &diffElement{
+			options: message.GetOptions(),
+		}
+		for _, field := range message.GetField() {
+			elements[diffElementKey{elementKind: ElementKindField, name: joinName(messageName, field.GetName())}] = newFieldDiffElement(field)
+		}
+		for _, oneof := range message.GetOneofDecl() {
+			elements[diffElementKey{elementKind: ElementKindOneof, name: joinName(messageName, oneof.GetName())}] = &diffElement{
+				options: oneof.GetOptions(),
+			}
+		}
+		addDiffElementsForMessages(elements, messageName, message.GetNestedType())
+		addDiffElementsForEnums(elements, messageName, message.GetEnumType())
+		addDiffElementsForExtensions(elements, messageName, message.GetExtension())
+	}
+}
+
+func addDiffElementsForEnums(
+	elements map[diffElementKey]*diffElement,
+	scope string,
+	enums []*descriptorpb.EnumDescriptorProto,
+) {
+	for _, enum := range enums {
+		elements[diffElementKey{elementKind: ElementKindEnum, name: joinName(scope, enum.GetName())}] = &diffElement{
+			options: enum.GetOptions(),
+		}
+		for _, enumValue := range enum.GetValue() {
+			elements[diffElementKey{elementKind: ElementKindEnumValue, name: joinName(scope, enumValue.GetName())}] = &diffElement{
+				number:  enumValue.GetNumber(),
+				options: enumValue.GetOptions(),
+			}
+		}
+	}
+}
+
+func addDiffElementsForExtensions(
+	elements map[diffElementKey]*diffElement,
+	scope string,
+	extensions []*descriptorpb.FieldDescriptorProto,
+) {
+	for _, extension := range extensions {
+		elements[diffElementKey{elementKind: ElementKindExtension, name: joinName(scope, extension.GetName())}] = newFieldDiffElement(extension)
+	}
+}
+
+func newFieldDiffElement(field *descriptorpb.FieldDescriptorProto) *diffElement {
+	return &diffElement{
+		number: field.GetNumber(),
+		typeSignature: fmt.Sprintf(
+			"%s %s %s %s %t",
+			field.GetLabel(),
+			field.GetType(),
+			strings.TrimPrefix(field.GetTypeName(), "."),
+			strings.TrimPrefix(field.GetExtendee(), "."),
+			field.GetProto3Optional(),
+		),
+		options: field.GetOptions(),
+	}
+}
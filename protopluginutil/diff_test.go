@@ -0,0 +1,110 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protopluginutil
+
+import (
+	"testing"
+
+	"github.com/bufbuild/protoplugin/internal/protocompiletest"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestDiffFileDescriptorProtos(t *testing.T) {
+	t.Parallel()
+
+	oldFiles := testCompileFileDescriptorProtos(
+		t,
+		map[string][]byte{
+			"a.proto": []byte(`syntax = "proto3";
+package foo;
+message A {
+  string b = 1;
+  int32 c = 2;
+  string d = 3 [deprecated = true];
+  message Removed {}
+}
+enum E {
+  E_ZERO = 0;
+  E_ONE = 1;
+}
+service S {
+  rpc M(A) returns (A);
+}
+`),
+		},
+	)
+	newFiles := testCompileFileDescriptorProtos(
+		t,
+		map[string][]byte{
+			"a.proto": []byte(`syntax = "proto3";
+package foo;
+message A {
+  string b = 4;
+  int64 c = 2;
+  string d = 3;
+  string e = 5;
+}
+enum E {
+  E_ZERO = 0;
+  E_ONE = 2;
+}
+service S {
+  rpc M(A) returns (stream A);
+}
+`),
+			"b.proto": []byte(`syntax = "proto3"; package bar; message B {}`),
+		},
+	)
+
+	require.Equal(
+		t,
+		[]Change{
+			{Kind: ChangeKindAdded, ElementKind: ElementKindFile, Name: "b.proto"},
+			{Kind: ChangeKindAdded, ElementKind: ElementKindMessage, Name: "bar.B"},
+			{Kind: ChangeKindRemoved, ElementKind: ElementKindMessage, Name: "foo.A.Removed"},
+			{Kind: ChangeKindNumberChanged, ElementKind: ElementKindField, Name: "foo.A.b", OldNumber: 1, NewNumber: 4},
+			{Kind: ChangeKindTypeChanged, ElementKind: ElementKindField, Name: "foo.A.c"},
+			{Kind: ChangeKindOptionsChanged, ElementKind: ElementKindField, Name: "foo.A.d"},
+			{Kind: ChangeKindAdded, ElementKind: ElementKindField, Name: "foo.A.e"},
+			{Kind: ChangeKindNumberChanged, ElementKind: ElementKindEnumValue, Name: "foo.E_ONE", OldNumber: 1, NewNumber: 2},
+			{Kind: ChangeKindTypeChanged, ElementKind: ElementKindMethod, Name: "foo.S.M"},
+		},
+		DiffFileDescriptorProtos(oldFiles, newFiles),
+	)
+	require.Empty(t, DiffFileDescriptorProtos(oldFiles, oldFiles))
+	require.Equal(
+		t,
+		"field foo.A.b: number changed from 1 to 4",
+		Change{Kind: ChangeKindNumberChanged, ElementKind: ElementKindField, Name: "foo.A.b", OldNumber: 1, NewNumber: 4}.String(),
+	)
+	require.Equal(
+		t,
+		"enum value foo.E_ZERO: removed",
+		Change{Kind: ChangeKindRemoved, ElementKind: ElementKindEnumValue, Name: "foo.E_ZERO"}.String(),
+	)
+}
+
+func testCompileFileDescriptorProtos(t *testing.T, pathToData map[string][]byte) []*descriptorpb.FileDescriptorProto {
+	files := protocompiletest.CompileFiles(t, pathToData)
+	var fileDescriptorProtos []*descriptorpb.FileDescriptorProto
+	for path := range pathToData {
+		fileDescriptor, err := files.FindFileByPath(path)
+		require.NoError(t, err)
+		fileDescriptorProtos = append(fileDescriptorProtos, protodesc.ToFileDescriptorProto(fileDescriptor))
+	}
+	return fileDescriptorProtos
+}
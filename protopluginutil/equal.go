@@ -0,0 +1,98 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protopluginutil
+
+import (
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// EqualFileDescriptorProtosOption is an option for EqualFileDescriptorProtosIgnoringInfo.
+type EqualFileDescriptorProtosOption func(*equalFileDescriptorProtosOptions)
+
+// EqualFileDescriptorProtosWithSemanticEquality returns a new EqualFileDescriptorProtosOption that says to
+// compare the FileDescriptorProtos after canonicalizing them with CanonicalizeFileDescriptorProto.
+//
+// This results in FileDescriptorProtos that differ only in how compilers serialize them, for example in the
+// order of unrecognized options or the form of default values, being considered equal.
+//
+// The default is to compare the FileDescriptorProtos as-is, other than SourceCodeInfo.
+func EqualFileDescriptorProtosWithSemanticEquality() EqualFileDescriptorProtosOption {
+	return func(equalFileDescriptorProtosOptions *equalFileDescriptorProtosOptions) {
+		equalFileDescriptorProtosOptions.semanticEquality = true
+	}
+}
+
+// EqualFileDescriptorProtosIgnoringInfo returns true if the FileDescriptorProtos are equal, ignoring SourceCodeInfo.
+//
+// This is useful for proxies and caches that need to decide if two FileDescriptorProtos describe the same schema,
+// without being affected by differences in comments or source spans.
+//
+// Neither FileDescriptorProto is modified.
+func EqualFileDescriptorProtosIgnoringInfo(
+	a *descriptorpb.FileDescriptorProto,
+	b *descriptorpb.FileDescriptorProto,
+	options ...EqualFileDescriptorProtosOption,
+) bool {
+	equalFileDescriptorProtosOptions := newEqualFileDescriptorProtosOptions()
+	for _, option := range options {
+		option(equalFileDescriptorProtosOptions)
+	}
+	if a == nil || b == nil {
+		return a == b
+	}
+	if equalFileDescriptorProtosOptions.semanticEquality {
+		var err error
+		if a, err = CanonicalizeFileDescriptorProto(a); err != nil {
+			return false
+		}
+		if b, err = CanonicalizeFileDescriptorProto(b); err != nil {
+			return false
+		}
+	}
+	return proto.Equal(withoutSourceCodeInfo(a), withoutSourceCodeInfo(b))
+}
+
+// *** PRIVATE ***
+
+type equalFileDescriptorProtosOptions struct {
+	semanticEquality bool
+}
+
+func newEqualFileDescriptorProtosOptions() *equalFileDescriptorProtosOptions {
+	return &equalFileDescriptorProtosOptions{}
+}
+
+// withoutSourceCodeInfo returns a shallow copy of the FileDescriptorProto without SourceCodeInfo.
+//
+// If the FileDescriptorProto has no SourceCodeInfo, it is returned as-is.
+func withoutSourceCodeInfo(file *descriptorpb.FileDescriptorProto) *descriptorpb.FileDescriptorProto {
+	if file.SourceCodeInfo == nil {
+		return file
+	}
+	fileWithoutSourceCodeInfo := &descriptorpb.FileDescriptorProto{}
+	fileWithoutSourceCodeInfoRef := fileWithoutSourceCodeInfo.ProtoReflect()
+	file.ProtoReflect().Range(
+		func(fieldDescriptor protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+			if fieldDescriptor.Number() != fileSourceCodeInfoTag {
+				fileWithoutSourceCodeInfoRef.Set(fieldDescriptor, value)
+			}
+			return true
+		},
+	)
+	fileWithoutSourceCodeInfoRef.SetUnknown(file.ProtoReflect().GetUnknown())
+	return fileWithoutSourceCodeInfo
+}
@@ -0,0 +1,82 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protopluginutil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestEqualFileDescriptorProtosIgnoringInfo(t *testing.T) {
+	t.Parallel()
+
+	compile := func(data string) *descriptorpb.FileDescriptorProto {
+		fileDescriptorProtos := testCompileFileDescriptorProtos(t, map[string][]byte{"a.proto": []byte(data)})
+		require.Len(t, fileDescriptorProtos, 1)
+		return fileDescriptorProtos[0]
+	}
+	a := compile(`syntax = "proto3";
+package foo;
+// A is a message.
+message A {
+  string b = 1;
+}
+`)
+	b := compile(`syntax = "proto3";
+
+package foo;
+
+// A is a message with a different comment.
+message A {
+  string b  =  1; // And a trailing comment.
+}
+`)
+	c := compile(`syntax = "proto3";
+package foo;
+message A {
+  int32 b = 1;
+}
+`)
+	require.NotNil(t, a.GetSourceCodeInfo())
+	require.False(t, proto.Equal(a, b))
+	require.True(t, EqualFileDescriptorProtosIgnoringInfo(a, b))
+	require.False(t, EqualFileDescriptorProtosIgnoringInfo(a, c))
+	require.True(t, EqualFileDescriptorProtosIgnoringInfo(nil, nil))
+	require.False(t, EqualFileDescriptorProtosIgnoringInfo(a, nil))
+	// The inputs are not modified.
+	require.NotNil(t, a.GetSourceCodeInfo())
+	require.NotNil(t, b.GetSourceCodeInfo())
+
+	proto2WithSyntax := &descriptorpb.FileDescriptorProto{
+		Name:   proto.String("a.proto"),
+		Syntax: proto.String("proto2"),
+	}
+	proto2WithoutSyntax := &descriptorpb.FileDescriptorProto{
+		Name: proto.String("a.proto"),
+	}
+	require.False(t, EqualFileDescriptorProtosIgnoringInfo(proto2WithSyntax, proto2WithoutSyntax))
+	require.True(
+		t,
+		EqualFileDescriptorProtosIgnoringInfo(
+			proto2WithSyntax,
+			proto2WithoutSyntax,
+			EqualFileDescriptorProtosWithSemanticEquality(),
+		),
+	)
+	require.False(t, EqualFileDescriptorProtosIgnoringInfo(a, c, EqualFileDescriptorProtosWithSemanticEquality()))
+}
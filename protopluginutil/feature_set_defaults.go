@@ -0,0 +1,105 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protopluginutil
+
+import (
+	_ "embed"
+	"fmt"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+var (
+	// editionsDefaultsData is the serialized FeatureSetDefaults for all editions supported by
+	// google.golang.org/protobuf, including the Go-specific features.
+	//
+	// This is copied from google.golang.org/protobuf/internal/editiondefaults/editions_defaults.binpb,
+	// and should be updated when google.golang.org/protobuf is upgraded to a version that supports
+	// new editions.
+	//
+	//go:embed editions_defaults.binpb
+	editionsDefaultsData []byte
+
+	featureSetDefaults     *descriptorpb.FeatureSetDefaults
+	featureSetDefaultsErr  error
+	featureSetDefaultsOnce sync.Once
+)
+
+// FeatureSetDefaults returns the FeatureSetDefaults for all editions supported by this package.
+//
+// This is the same data that protoc and buf compute from descriptor.proto and the language-specific
+// feature files, and is useful for plugins that need to resolve features when the compiler did not
+// supply resolved features. The returned FeatureSetDefaults is a copy, and can be freely modified.
+func FeatureSetDefaults() (*descriptorpb.FeatureSetDefaults, error) {
+	featureSetDefaults, err := getFeatureSetDefaults()
+	if err != nil {
+		return nil, err
+	}
+	clone, ok := proto.Clone(featureSetDefaults).(*descriptorpb.FeatureSetDefaults)
+	if !ok {
+		return nil, fmt.Errorf("could not clone %T", featureSetDefaults)
+	}
+	return clone, nil
+}
+
+// FeatureSetDefaultsForEdition returns the default FeatureSet for the given edition.
+//
+// The returned FeatureSet has all features set to their default values for the edition, that is both
+// the overridable and fixed features are merged into a single FeatureSet. The returned FeatureSet is a
+// copy, and can be freely modified.
+//
+// An error is returned if the edition is not within the range of editions supported by this package.
+func FeatureSetDefaultsForEdition(edition descriptorpb.Edition) (*descriptorpb.FeatureSet, error) {
+	featureSetDefaults, err := getFeatureSetDefaults()
+	if err != nil {
+		return nil, err
+	}
+	if edition < featureSetDefaults.GetMinimumEdition() || edition > featureSetDefaults.GetMaximumEdition() {
+		return nil, fmt.Errorf(
+			"edition %v is not within the supported range of editions %v to %v",
+			edition,
+			featureSetDefaults.GetMinimumEdition(),
+			featureSetDefaults.GetMaximumEdition(),
+		)
+	}
+	// The defaults are sorted by edition, and the applicable defaults are those of
+	// the latest edition that is less than or equal to the given edition.
+	var editionDefault *descriptorpb.FeatureSetDefaults_FeatureSetEditionDefault
+	for _, candidate := range featureSetDefaults.GetDefaults() {
+		if candidate.GetEdition() > edition {
+			break
+		}
+		editionDefault = candidate
+	}
+	if editionDefault == nil {
+		return nil, fmt.Errorf("no FeatureSet defaults for edition %v", edition)
+	}
+	featureSet := &descriptorpb.FeatureSet{}
+	proto.Merge(featureSet, editionDefault.GetOverridableFeatures())
+	proto.Merge(featureSet, editionDefault.GetFixedFeatures())
+	return featureSet, nil
+}
+
+// *** PRIVATE ***
+
+func getFeatureSetDefaults() (*descriptorpb.FeatureSetDefaults, error) {
+	featureSetDefaultsOnce.Do(func() {
+		featureSetDefaults = &descriptorpb.FeatureSetDefaults{}
+		featureSetDefaultsErr = proto.Unmarshal(editionsDefaultsData, featureSetDefaults)
+	})
+	return featureSetDefaults, featureSetDefaultsErr
+}
@@ -0,0 +1,59 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protopluginutil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestFeatureSetDefaultsForEdition(t *testing.T) {
+	t.Parallel()
+
+	featureSetDefaults, err := FeatureSetDefaults()
+	require.NoError(t, err)
+	require.Equal(t, descriptorpb.Edition_EDITION_PROTO2, featureSetDefaults.GetMinimumEdition())
+	require.GreaterOrEqual(t, featureSetDefaults.GetMaximumEdition(), descriptorpb.Edition_EDITION_2023)
+
+	featureSet, err := FeatureSetDefaultsForEdition(descriptorpb.Edition_EDITION_PROTO2)
+	require.NoError(t, err)
+	require.Equal(t, descriptorpb.FeatureSet_EXPLICIT, featureSet.GetFieldPresence())
+	require.Equal(t, descriptorpb.FeatureSet_CLOSED, featureSet.GetEnumType())
+	require.Equal(t, descriptorpb.FeatureSet_EXPANDED, featureSet.GetRepeatedFieldEncoding())
+
+	featureSet, err = FeatureSetDefaultsForEdition(descriptorpb.Edition_EDITION_PROTO3)
+	require.NoError(t, err)
+	require.Equal(t, descriptorpb.FeatureSet_IMPLICIT, featureSet.GetFieldPresence())
+	require.Equal(t, descriptorpb.FeatureSet_OPEN, featureSet.GetEnumType())
+	require.Equal(t, descriptorpb.FeatureSet_PACKED, featureSet.GetRepeatedFieldEncoding())
+
+	featureSet, err = FeatureSetDefaultsForEdition(descriptorpb.Edition_EDITION_2023)
+	require.NoError(t, err)
+	require.Equal(t, descriptorpb.FeatureSet_EXPLICIT, featureSet.GetFieldPresence())
+	require.Equal(t, descriptorpb.FeatureSet_OPEN, featureSet.GetEnumType())
+	require.Equal(t, descriptorpb.FeatureSet_LENGTH_PREFIXED, featureSet.GetMessageEncoding())
+	// Modifying the returned FeatureSet should not affect future calls.
+	featureSet.FieldPresence = descriptorpb.FeatureSet_IMPLICIT.Enum()
+	featureSet, err = FeatureSetDefaultsForEdition(descriptorpb.Edition_EDITION_2023)
+	require.NoError(t, err)
+	require.Equal(t, descriptorpb.FeatureSet_EXPLICIT, featureSet.GetFieldPresence())
+
+	_, err = FeatureSetDefaultsForEdition(descriptorpb.Edition_EDITION_LEGACY)
+	require.Error(t, err)
+	_, err = FeatureSetDefaultsForEdition(descriptorpb.Edition_EDITION_MAX)
+	require.Error(t, err)
+}
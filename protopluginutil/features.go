@@ -0,0 +1,88 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protopluginutil
+
+import (
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// FieldUsesDelimitedEncoding returns true if the field is encoded using the delimited (group) wire format.
+//
+// This is true for proto2 group fields, and for message fields in editions files that have the
+// message_encoding feature resolved to DELIMITED, including extensions.
+func FieldUsesDelimitedEncoding(field protoreflect.FieldDescriptor) bool {
+	switch field.Kind() {
+	case protoreflect.GroupKind:
+		return true
+	case protoreflect.MessageKind:
+		if field.ParentFile().Syntax() != protoreflect.Editions || field.IsMap() {
+			return false
+		}
+		// Extensions are not converted to GroupKind by protodesc, so we resolve the feature ourselves.
+		return resolveFeatureSetValue(
+			field,
+			func(featureSet *descriptorpb.FeatureSet) bool { return featureSet.MessageEncoding != nil },
+			func(featureSet *descriptorpb.FeatureSet) bool {
+				return featureSet.GetMessageEncoding() == descriptorpb.FeatureSet_DELIMITED
+			},
+		)
+	default:
+		return false
+	}
+}
+
+// FieldHasExplicitPresence returns true if the field tracks presence, that is if it
+// distinguishes between a field being unset and a field being set to its default value.
+//
+// This is true for proto2 optional fields, proto3 optional fields, fields within oneofs, message fields,
+// extensions, and fields in editions files that have the field_presence feature resolved to
+// EXPLICIT or LEGACY_REQUIRED. Repeated fields never have explicit presence.
+func FieldHasExplicitPresence(field protoreflect.FieldDescriptor) bool {
+	return field.HasPresence()
+}
+
+// EnumIsOpen returns true if the enum uses open semantics, that is if unknown values are
+// stored in the field rather than in the unknown fields of the message.
+//
+// This is true for proto3 enums, and for enums in editions files that have the enum_type feature
+// resolved to OPEN. Proto2 enums are always closed.
+func EnumIsOpen(enum protoreflect.EnumDescriptor) bool {
+	return !enum.IsClosed()
+}
+
+// *** PRIVATE ***
+
+// resolveFeatureSetValue walks from the descriptor to its parent file, returning the result of getValue for
+// the first FeatureSet that has the feature set per isSet. If no FeatureSet has the feature set,
+// getValue is called with an empty FeatureSet.
+func resolveFeatureSetValue(
+	descriptor protoreflect.Descriptor,
+	isSet func(*descriptorpb.FeatureSet) bool,
+	getValue func(*descriptorpb.FeatureSet) bool,
+) bool {
+	for ; descriptor != nil; descriptor = descriptor.Parent() {
+		options, ok := descriptor.Options().(interface {
+			GetFeatures() *descriptorpb.FeatureSet
+		})
+		if !ok {
+			continue
+		}
+		if featureSet := options.GetFeatures(); featureSet != nil && isSet(featureSet) {
+			return getValue(featureSet)
+		}
+	}
+	return getValue(&descriptorpb.FeatureSet{})
+}
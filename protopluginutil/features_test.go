@@ -0,0 +1,124 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protopluginutil
+
+import (
+	"testing"
+
+	"github.com/bufbuild/protoplugin/internal/protocompiletest"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestFeatures(t *testing.T) {
+	t.Parallel()
+
+	files := protocompiletest.CompileFiles(
+		t,
+		map[string][]byte{
+			"proto2.proto": []byte(`
+				syntax = "proto2";
+				package proto2;
+				message A {
+					optional int32 optional_field = 1;
+					repeated int32 repeated_field = 2;
+					optional group Group = 3 { optional int32 a = 1; }
+					optional A message_field = 4;
+					extensions 100 to 200;
+				}
+				enum E { E_ZERO = 0; }
+			`),
+			"proto3.proto": []byte(`
+				syntax = "proto3";
+				package proto3;
+				message A {
+					int32 implicit_field = 1;
+					optional int32 optional_field = 2;
+					A message_field = 3;
+				}
+				enum E { E_ZERO = 0; }
+			`),
+			"editions.proto": []byte(`
+				edition = "2023";
+				package editions;
+				import "proto2.proto";
+				message A {
+					int32 explicit_field = 1;
+					int32 implicit_field = 2 [features.field_presence = IMPLICIT];
+					A length_prefixed_field = 3;
+					A delimited_field = 4 [features.message_encoding = DELIMITED];
+				}
+				extend proto2.A {
+					A length_prefixed_ext = 101;
+					A delimited_ext = 102 [features.message_encoding = DELIMITED];
+				}
+				enum Open { OPEN_ZERO = 0; }
+				enum Closed { option features.enum_type = CLOSED; CLOSED_ZERO = 0; }
+			`),
+			"editions_delimited.proto": []byte(`
+				edition = "2023";
+				package editions_delimited;
+				import "editions.proto";
+				import "proto2.proto";
+				option features.message_encoding = DELIMITED;
+				message B {
+					editions.A delimited_field = 1;
+					editions.A length_prefixed_field = 2 [features.message_encoding = LENGTH_PREFIXED];
+					extend proto2.A { editions.A delimited_ext = 100; }
+				}
+			`),
+		},
+	)
+
+	field := func(name protoreflect.FullName) protoreflect.FieldDescriptor {
+		descriptor, err := files.FindDescriptorByName(name)
+		require.NoError(t, err)
+		fieldDescriptor, ok := descriptor.(protoreflect.FieldDescriptor)
+		require.True(t, ok)
+		return fieldDescriptor
+	}
+	enum := func(name protoreflect.FullName) protoreflect.EnumDescriptor {
+		descriptor, err := files.FindDescriptorByName(name)
+		require.NoError(t, err)
+		enumDescriptor, ok := descriptor.(protoreflect.EnumDescriptor)
+		require.True(t, ok)
+		return enumDescriptor
+	}
+
+	require.False(t, FieldUsesDelimitedEncoding(field("proto2.A.optional_field")))
+	require.True(t, FieldUsesDelimitedEncoding(field("proto2.A.group")))
+	require.False(t, FieldUsesDelimitedEncoding(field("proto2.A.message_field")))
+	require.False(t, FieldUsesDelimitedEncoding(field("proto3.A.message_field")))
+	require.False(t, FieldUsesDelimitedEncoding(field("editions.A.length_prefixed_field")))
+	require.True(t, FieldUsesDelimitedEncoding(field("editions.A.delimited_field")))
+	require.True(t, FieldUsesDelimitedEncoding(field("editions_delimited.B.delimited_field")))
+	require.False(t, FieldUsesDelimitedEncoding(field("editions_delimited.B.length_prefixed_field")))
+	require.True(t, FieldUsesDelimitedEncoding(field("editions_delimited.B.delimited_ext")))
+	require.False(t, FieldUsesDelimitedEncoding(field("editions.length_prefixed_ext")))
+	require.True(t, FieldUsesDelimitedEncoding(field("editions.delimited_ext")))
+
+	require.True(t, FieldHasExplicitPresence(field("proto2.A.optional_field")))
+	require.False(t, FieldHasExplicitPresence(field("proto2.A.repeated_field")))
+	require.False(t, FieldHasExplicitPresence(field("proto3.A.implicit_field")))
+	require.True(t, FieldHasExplicitPresence(field("proto3.A.optional_field")))
+	require.True(t, FieldHasExplicitPresence(field("proto3.A.message_field")))
+	require.True(t, FieldHasExplicitPresence(field("editions.A.explicit_field")))
+	require.False(t, FieldHasExplicitPresence(field("editions.A.implicit_field")))
+
+	require.False(t, EnumIsOpen(enum("proto2.E")))
+	require.True(t, EnumIsOpen(enum("proto3.E")))
+	require.True(t, EnumIsOpen(enum("editions.Open")))
+	require.False(t, EnumIsOpen(enum("editions.Closed")))
+}
@@ -0,0 +1,133 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protopluginutil
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// ApplyInsertionPoints resolves all files with insertion points into the content of their target files,
+// returning the resulting files, none of which have insertion points.
+//
+// This uses the same algorithm as protoc: the content of an insertion point file is inserted immediately before
+// the line containing the marker "@@protoc_insertion_point(NAME)" in the target file, with each inserted line
+// prefixed by the whitespace that the marker line is indented with. If the marker is within an inline comment
+// of the form "/* @@protoc_insertion_point(NAME) */", the content is inserted immediately before the comment,
+// without indentation. A newline is appended to the content if it does not end with one.
+//
+// Files are processed in order. The target of an insertion point must be a file without an insertion point that
+// appears earlier in the given files, otherwise an error is returned. Files with an empty name are appended to
+// the previous file, per the documentation of CodeGeneratorResponse.File.name. An error is also returned if the
+// marker is not found in the target file, or if a file without an insertion point appears more than once.
+//
+// The returned files are in the order that their names first appear. Files whose content was modified by an
+// insertion point do not have GeneratedCodeInfo, as the offsets would no longer be valid. The given files are
+// not modified.
+//
+// This enables standalone plugins and proxies to materialize the final outputs of a CodeGeneratorResponse.
+func ApplyInsertionPoints(files []*pluginpb.CodeGeneratorResponse_File) ([]*pluginpb.CodeGeneratorResponse_File, error) {
+	files, err := mergeContinuationFiles(files)
+	if err != nil {
+		return nil, err
+	}
+	resultFiles := make([]*pluginpb.CodeGeneratorResponse_File, 0, len(files))
+	nameToResultFile := make(map[string]*pluginpb.CodeGeneratorResponse_File, len(files))
+	for _, file := range files {
+		name := file.GetName()
+		insertionPoint := file.GetInsertionPoint()
+		if insertionPoint == "" {
+			if _, ok := nameToResultFile[name]; ok {
+				return nil, fmt.Errorf("duplicate file %q", name)
+			}
+			resultFile := proto.Clone(file).(*pluginpb.CodeGeneratorResponse_File)
+			resultFiles = append(resultFiles, resultFile)
+			nameToResultFile[name] = resultFile
+			continue
+		}
+		resultFile, ok := nameToResultFile[name]
+		if !ok {
+			return nil, fmt.Errorf("insertion point %q targets file %q, which was not previously generated", insertionPoint, name)
+		}
+		content, err := applyInsertionPoint(resultFile.GetContent(), insertionPoint, file.GetContent())
+		if err != nil {
+			return nil, fmt.Errorf("file %q: %w", name, err)
+		}
+		resultFile.Content = proto.String(content)
+		resultFile.GeneratedCodeInfo = nil
+	}
+	return resultFiles, nil
+}
+
+// *** PRIVATE ***
+
+// mergeContinuationFiles appends the content of files with empty names to the previous file.
+//
+// The given files are not modified.
+func mergeContinuationFiles(files []*pluginpb.CodeGeneratorResponse_File) ([]*pluginpb.CodeGeneratorResponse_File, error) {
+	mergedFiles := make([]*pluginpb.CodeGeneratorResponse_File, 0, len(files))
+	for _, file := range files {
+		if file.GetName() != "" {
+			mergedFiles = append(mergedFiles, file)
+			continue
+		}
+		if len(mergedFiles) == 0 {
+			return nil, errors.New("first file has an empty name")
+		}
+		previousFile := proto.Clone(mergedFiles[len(mergedFiles)-1]).(*pluginpb.CodeGeneratorResponse_File)
+		previousFile.Content = proto.String(previousFile.GetContent() + file.GetContent())
+		mergedFiles[len(mergedFiles)-1] = previousFile
+	}
+	return mergedFiles, nil
+}
+
+// applyInsertionPoint inserts the data into the content at the insertion point, using protoc's algorithm.
+func applyInsertionPoint(content string, insertionPoint string, data string) (string, error) {
+	marker := "@@protoc_insertion_point(" + insertionPoint + ")"
+	markerIndex := strings.Index(content, marker)
+	if markerIndex < 0 {
+		return "", fmt.Errorf("insertion point %q not found", insertionPoint)
+	}
+	var insertIndex int
+	if markerIndex > 3 && content[markerIndex-3:markerIndex-1] == "/*" {
+		// Inline insertion point of the form "/* @@protoc_insertion_point(NAME) */".
+		insertIndex = markerIndex - 3
+	} else {
+		// Insert at the beginning of the line, pushing the insertion point down.
+		insertIndex = strings.LastIndexByte(content[:markerIndex], '\n') + 1
+	}
+	if data != "" && !strings.HasSuffix(data, "\n") {
+		data += "\n"
+	}
+	indent := content[insertIndex:]
+	indent = indent[:len(indent)-len(strings.TrimLeft(indent, " \t"))]
+	if indent != "" {
+		lines := strings.SplitAfter(data, "\n")
+		var builder strings.Builder
+		for _, line := range lines {
+			if line == "" {
+				continue
+			}
+			_, _ = builder.WriteString(indent)
+			_, _ = builder.WriteString(line)
+		}
+		data = builder.String()
+	}
+	return content[:insertIndex] + data + content[insertIndex:], nil
+}
@@ -0,0 +1,126 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protopluginutil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestApplyInsertionPoints(t *testing.T) {
+	t.Parallel()
+
+	files := []*pluginpb.CodeGeneratorResponse_File{
+		{
+			Name:              proto.String("a.txt"),
+			Content:           proto.String("class A {\n  // @@protoc_insertion_point(class_scope)\n}\nint x = f(/* @@protoc_insertion_point(arg) */);\n"),
+			GeneratedCodeInfo: &descriptorpb.GeneratedCodeInfo{},
+		},
+		{
+			Name:              proto.String("b.txt"),
+			Content:           proto.String("b\n"),
+			GeneratedCodeInfo: &descriptorpb.GeneratedCodeInfo{},
+		},
+		{
+			Name:           proto.String("a.txt"),
+			InsertionPoint: proto.String("class_scope"),
+			Content:        proto.String("int foo;\nint bar;"),
+		},
+		{
+			Content: proto.String("\nint baz;\n"),
+		},
+		{
+			Name:           proto.String("a.txt"),
+			InsertionPoint: proto.String("arg"),
+			Content:        proto.String("1, "),
+		},
+		{
+			Name:           proto.String("a.txt"),
+			InsertionPoint: proto.String("class_scope"),
+			Content:        proto.String("int qux;\n"),
+		},
+	}
+	originalFiles := make([]*pluginpb.CodeGeneratorResponse_File, len(files))
+	for i, file := range files {
+		originalFiles[i] = proto.Clone(file).(*pluginpb.CodeGeneratorResponse_File)
+	}
+	resultFiles, err := ApplyInsertionPoints(files)
+	require.NoError(t, err)
+	require.Len(t, resultFiles, 2)
+	require.Equal(t, "a.txt", resultFiles[0].GetName())
+	require.Equal(
+		t,
+		`class A {
+  int foo;
+  int bar;
+  int baz;
+  int qux;
+  // @@protoc_insertion_point(class_scope)
+}
+int x = f(1, 
+/* @@protoc_insertion_point(arg) */);
+`,
+		resultFiles[0].GetContent(),
+	)
+	require.Nil(t, resultFiles[0].GetGeneratedCodeInfo())
+	require.Equal(t, "b.txt", resultFiles[1].GetName())
+	require.Equal(t, "b\n", resultFiles[1].GetContent())
+	require.NotNil(t, resultFiles[1].GetGeneratedCodeInfo())
+	for i, file := range files {
+		require.True(t, proto.Equal(originalFiles[i], file))
+	}
+
+	_, err = ApplyInsertionPoints(
+		[]*pluginpb.CodeGeneratorResponse_File{
+			{
+				Name:           proto.String("a.txt"),
+				InsertionPoint: proto.String("foo"),
+				Content:        proto.String("a"),
+			},
+		},
+	)
+	require.Error(t, err)
+	_, err = ApplyInsertionPoints(
+		[]*pluginpb.CodeGeneratorResponse_File{
+			{
+				Name:    proto.String("a.txt"),
+				Content: proto.String("a"),
+			},
+			{
+				Name:           proto.String("a.txt"),
+				InsertionPoint: proto.String("foo"),
+				Content:        proto.String("a"),
+			},
+		},
+	)
+	require.Error(t, err)
+	_, err = ApplyInsertionPoints(
+		[]*pluginpb.CodeGeneratorResponse_File{
+			{
+				Name:    proto.String("a.txt"),
+				Content: proto.String("a"),
+			},
+			{
+				Name:    proto.String("a.txt"),
+				Content: proto.String("a"),
+			},
+		},
+	)
+	require.Error(t, err)
+}
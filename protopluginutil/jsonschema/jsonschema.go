@@ -0,0 +1,288 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package jsonschema converts Protobuf message descriptors into JSON Schema documents.
+//
+// The produced schemas describe the ProtoJSON representation of messages, as specified at
+// https://protobuf.dev/programming-guides/json. Well-known types are mapped to their special
+// ProtoJSON representations, and leading comments are propagated as descriptions.
+package jsonschema
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/bufbuild/protoplugin/protopluginutil"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Draft is the JSON Schema draft that produced schemas conform to.
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is a JSON Schema document.
+//
+// Schemas are represented as generic maps, so that they can be further modified by callers
+// before being marshaled. Marshaling a Schema with encoding/json is deterministic, as
+// encoding/json sorts map keys.
+type Schema map[string]any
+
+// NewSchema returns a new JSON Schema document for the ProtoJSON representation of the message.
+//
+// The root of the document references the definition of the message. The definitions of the message and
+// of all messages it transitively references are contained within "$defs", keyed by full name.
+//
+// Fields use their JSON names. Fields that are required (proto2 required fields, or fields with the
+// field_presence feature resolved to LEGACY_REQUIRED) are listed as required. Fields with implicit presence
+// have a "default" of their zero value, as omitting them is equivalent to setting them to their zero value.
+func NewSchema(messageDescriptor protoreflect.MessageDescriptor) Schema {
+	builder := &schemaBuilder{
+		defs: make(map[string]any),
+	}
+	builder.addMessageDefinition(messageDescriptor)
+	return Schema{
+		"$schema": Draft,
+		"$id":     string(messageDescriptor.FullName()) + ".schema.json",
+		"$ref":    definitionRef(messageDescriptor),
+		"$defs":   builder.defs,
+	}
+}
+
+// Marshal returns the indented JSON representation of the Schema.
+func (s Schema) Marshal() ([]byte, error) {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// *** PRIVATE ***
+
+type schemaBuilder struct {
+	defs map[string]any
+}
+
+func (b *schemaBuilder) addMessageDefinition(messageDescriptor protoreflect.MessageDescriptor) {
+	name := string(messageDescriptor.FullName())
+	if _, ok := b.defs[name]; ok {
+		return
+	}
+	if wellKnownTypeSchema, ok := wellKnownTypeSchemas[name]; ok {
+		b.defs[name] = withDescription(wellKnownTypeSchema(), messageDescriptor)
+		return
+	}
+	properties := make(map[string]any)
+	schema := withDescription(
+		map[string]any{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		},
+		messageDescriptor,
+	)
+	// Add before recursing so that recursive messages terminate.
+	b.defs[name] = schema
+	var required []string
+	fields := messageDescriptor.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fieldDescriptor := fields.Get(i)
+		properties[fieldDescriptor.JSONName()] = b.fieldSchema(fieldDescriptor)
+		if fieldDescriptor.Cardinality() == protoreflect.Required {
+			required = append(required, fieldDescriptor.JSONName())
+		}
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+}
+
+func (b *schemaBuilder) fieldSchema(fieldDescriptor protoreflect.FieldDescriptor) map[string]any {
+	var schema map[string]any
+	switch {
+	case fieldDescriptor.IsMap():
+		schema = map[string]any{
+			"type":                 "object",
+			"propertyNames":        mapKeySchema(fieldDescriptor.MapKey()),
+			"additionalProperties": b.singularFieldSchema(fieldDescriptor.MapValue()),
+		}
+	case fieldDescriptor.IsList():
+		schema = map[string]any{
+			"type":  "array",
+			"items": b.singularFieldSchema(fieldDescriptor),
+		}
+	default:
+		schema = b.singularFieldSchema(fieldDescriptor)
+		if !protopluginutil.FieldHasExplicitPresence(fieldDescriptor) {
+			schema["default"] = zeroValue(fieldDescriptor)
+		}
+	}
+	return withDescription(schema, fieldDescriptor)
+}
+
+func (b *schemaBuilder) singularFieldSchema(fieldDescriptor protoreflect.FieldDescriptor) map[string]any {
+	switch fieldDescriptor.Kind() {
+	case protoreflect.BoolKind:
+		return map[string]any{"type": "boolean"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return map[string]any{"type": "integer", "format": "uint32", "minimum": 0}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		// ProtoJSON encodes 64-bit integers as strings, but accepts numbers as well.
+		return map[string]any{"type": []string{"integer", "string"}, "format": "int64"}
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return map[string]any{"type": []string{"integer", "string"}, "format": "uint64"}
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		// ProtoJSON encodes special values as the strings "NaN", "Infinity", and "-Infinity".
+		return map[string]any{
+			"anyOf": []any{
+				map[string]any{"type": "number"},
+				map[string]any{"type": "string", "enum": []string{"NaN", "Infinity", "-Infinity"}},
+			},
+		}
+	case protoreflect.StringKind:
+		return map[string]any{"type": "string"}
+	case protoreflect.BytesKind:
+		return map[string]any{"type": "string", "contentEncoding": "base64"}
+	case protoreflect.EnumKind:
+		return enumSchema(fieldDescriptor.Enum())
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		b.addMessageDefinition(fieldDescriptor.Message())
+		return map[string]any{"$ref": definitionRef(fieldDescriptor.Message())}
+	default:
+		return map[string]any{}
+	}
+}
+
+func enumSchema(enumDescriptor protoreflect.EnumDescriptor) map[string]any {
+	if enumDescriptor.FullName() == "google.protobuf.NullValue" {
+		return map[string]any{"type": "null"}
+	}
+	values := enumDescriptor.Values()
+	enum := make([]any, 0, 2*values.Len())
+	for i := 0; i < values.Len(); i++ {
+		enum = append(enum, string(values.Get(i).Name()))
+	}
+	// ProtoJSON accepts the numeric values of enums as well as the names.
+	for i := 0; i < values.Len(); i++ {
+		enum = append(enum, int32(values.Get(i).Number()))
+	}
+	return map[string]any{
+		"title": string(enumDescriptor.Name()),
+		"enum":  enum,
+	}
+}
+
+func mapKeySchema(fieldDescriptor protoreflect.FieldDescriptor) map[string]any {
+	switch fieldDescriptor.Kind() {
+	case protoreflect.BoolKind:
+		return map[string]any{"enum": []string{"true", "false"}}
+	case protoreflect.StringKind:
+		return map[string]any{"type": "string"}
+	default:
+		return map[string]any{"type": "string", "pattern": "^-?[0-9]+$"}
+	}
+}
+
+func zeroValue(fieldDescriptor protoreflect.FieldDescriptor) any {
+	switch fieldDescriptor.Kind() {
+	case protoreflect.BoolKind:
+		return false
+	case protoreflect.StringKind, protoreflect.BytesKind:
+		return ""
+	case protoreflect.EnumKind:
+		if values := fieldDescriptor.Enum().Values(); values.Len() > 0 {
+			return string(values.Get(0).Name())
+		}
+		return 0
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return "0"
+	default:
+		return 0
+	}
+}
+
+func definitionRef(messageDescriptor protoreflect.MessageDescriptor) string {
+	return "#/$defs/" + string(messageDescriptor.FullName())
+}
+
+// withDescription adds the leading comments of the descriptor as the description of the schema, if present.
+func withDescription(schema map[string]any, descriptor protoreflect.Descriptor) map[string]any {
+	sourceLocation := descriptor.ParentFile().SourceLocations().ByDescriptor(descriptor)
+	if description := strings.TrimSpace(sourceLocation.LeadingComments); description != "" {
+		schema["description"] = description
+	}
+	return schema
+}
+
+// wellKnownTypeSchemas contains the schemas for the well-known types that have
+// special ProtoJSON representations.
+var wellKnownTypeSchemas = map[string]func() map[string]any{
+	"google.protobuf.Any": func() map[string]any {
+		return map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"@type": map[string]any{"type": "string"}},
+			"required":   []string{"@type"},
+		}
+	},
+	"google.protobuf.Duration": func() map[string]any {
+		return map[string]any{"type": "string", "pattern": `^-?[0-9]+(\.[0-9]{0,9})?s$`}
+	},
+	"google.protobuf.Empty": func() map[string]any {
+		return map[string]any{"type": "object", "additionalProperties": false}
+	},
+	"google.protobuf.FieldMask": func() map[string]any {
+		return map[string]any{"type": "string"}
+	},
+	"google.protobuf.ListValue": func() map[string]any {
+		return map[string]any{"type": "array"}
+	},
+	"google.protobuf.Struct": func() map[string]any {
+		return map[string]any{"type": "object"}
+	},
+	"google.protobuf.Timestamp": func() map[string]any {
+		return map[string]any{"type": "string", "format": "date-time"}
+	},
+	"google.protobuf.Value": func() map[string]any {
+		return map[string]any{}
+	},
+	"google.protobuf.BoolValue": func() map[string]any {
+		return map[string]any{"type": []string{"boolean", "null"}}
+	},
+	"google.protobuf.BytesValue": func() map[string]any {
+		return map[string]any{"type": []string{"string", "null"}, "contentEncoding": "base64"}
+	},
+	"google.protobuf.DoubleValue": func() map[string]any {
+		return map[string]any{"type": []string{"number", "string", "null"}}
+	},
+	"google.protobuf.FloatValue": func() map[string]any {
+		return map[string]any{"type": []string{"number", "string", "null"}}
+	},
+	"google.protobuf.Int32Value": func() map[string]any {
+		return map[string]any{"type": []string{"integer", "null"}, "format": "int32"}
+	},
+	"google.protobuf.Int64Value": func() map[string]any {
+		return map[string]any{"type": []string{"integer", "string", "null"}, "format": "int64"}
+	},
+	"google.protobuf.StringValue": func() map[string]any {
+		return map[string]any{"type": []string{"string", "null"}}
+	},
+	"google.protobuf.UInt32Value": func() map[string]any {
+		return map[string]any{"type": []string{"integer", "null"}, "format": "uint32", "minimum": 0}
+	},
+	"google.protobuf.UInt64Value": func() map[string]any {
+		return map[string]any{"type": []string{"integer", "string", "null"}, "format": "uint64"}
+	},
+}
@@ -0,0 +1,111 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonschema
+
+import (
+	"testing"
+
+	"github.com/bufbuild/protoplugin/internal/protocompiletest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSchema(t *testing.T) {
+	t.Parallel()
+
+	fileDescriptor := protocompiletest.CompileFile(
+		t,
+		"a.proto",
+		[]byte(`
+			edition = "2023";
+			package foo;
+			import "google/protobuf/timestamp.proto";
+			import "google/protobuf/wrappers.proto";
+			// A is a message.
+			message A {
+				// explicit_field has explicit presence.
+				int64 explicit_field = 1;
+				string implicit_field = 2 [features.field_presence = IMPLICIT];
+				int32 required_field = 3 [features.field_presence = LEGACY_REQUIRED];
+				repeated B repeated_field = 4;
+				map<string, E> map_field = 5;
+				google.protobuf.Timestamp timestamp_field = 6;
+				google.protobuf.StringValue wrapper_field = 7;
+				A recursive_field = 8;
+			}
+			message B {
+				bytes bytes_field = 1;
+			}
+			enum E {
+				E_UNSPECIFIED = 0;
+				E_ONE = 1;
+			}
+		`),
+	)
+	schema := NewSchema(fileDescriptor.Messages().ByName("A"))
+	data, err := schema.Marshal()
+	require.NoError(t, err)
+	require.JSONEq(
+		t,
+		`{
+			"$schema": "https://json-schema.org/draft/2020-12/schema",
+			"$id": "foo.A.schema.json",
+			"$ref": "#/$defs/foo.A",
+			"$defs": {
+				"foo.A": {
+					"description": "A is a message.",
+					"type": "object",
+					"additionalProperties": false,
+					"required": ["requiredField"],
+					"properties": {
+						"explicitField": {
+							"description": "explicit_field has explicit presence.",
+							"type": ["integer", "string"],
+							"format": "int64"
+						},
+						"implicitField": {"type": "string", "default": ""},
+						"requiredField": {"type": "integer", "format": "int32"},
+						"repeatedField": {"type": "array", "items": {"$ref": "#/$defs/foo.B"}},
+						"mapField": {
+							"type": "object",
+							"propertyNames": {"type": "string"},
+							"additionalProperties": {
+								"title": "E",
+								"enum": ["E_UNSPECIFIED", "E_ONE", 0, 1]
+							}
+						},
+						"timestampField": {"$ref": "#/$defs/google.protobuf.Timestamp"},
+						"wrapperField": {"$ref": "#/$defs/google.protobuf.StringValue"},
+						"recursiveField": {"$ref": "#/$defs/foo.A"}
+					}
+				},
+				"foo.B": {
+					"type": "object",
+					"additionalProperties": false,
+					"properties": {
+						"bytesField": {"type": "string", "contentEncoding": "base64"}
+					}
+				},
+				"google.protobuf.Timestamp": {
+					"type": "string",
+					"format": "date-time"
+				},
+				"google.protobuf.StringValue": {
+					"type": ["string", "null"]
+				}
+			}
+		}`,
+		string(data),
+	)
+}
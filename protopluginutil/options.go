@@ -0,0 +1,121 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protopluginutil
+
+import (
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// FormatOptionsOption is an option for FormatOptions.
+type FormatOptionsOption func(*formatOptionsOptions)
+
+// FormatOptionsWithJSON returns a new FormatOptionsOption that formats the custom options as JSON
+// instead of the text format.
+//
+// Extensions are formatted with their full names in brackets as keys, for example "[foo.my_option]",
+// which is the JSON mapping for extensions.
+//
+// The default is to format the custom options in the text format.
+func FormatOptionsWithJSON() FormatOptionsOption {
+	return func(formatOptionsOptions *formatOptionsOptions) {
+		formatOptionsOptions.json = true
+	}
+}
+
+// FormatOptions formats all custom options set on the options message, for example a *descriptorpb.FieldOptions.
+//
+// Custom options are the extensions set on the options message. Standard options such as deprecated are not
+// included. If the resolver is non-nil, unknown fields on the options message are first resolved with the
+// resolver, which allows custom options that were not known when the options message was parsed to be
+// formatted. A resolver containing the extensions defined within a CodeGeneratorRequest can be built with
+// dynamicpb.NewExtensionType, or can be avoided entirely by using protoplugin.WithExtensionTypeDiscovery.
+// Unknown fields that cannot be resolved are not included.
+//
+// The custom options are formatted in the multi-line text format, or as JSON with FormatOptionsWithJSON.
+// If no custom options are set, the empty string is returned.
+//
+// This is useful for debugging, and for plugins that embed option values into generated documentation.
+// Note that the output of the text and JSON formats is not guaranteed to be stable across versions of
+// the protobuf runtime.
+func FormatOptions(
+	options proto.Message,
+	resolver protoregistry.ExtensionTypeResolver,
+	opts ...FormatOptionsOption,
+) (string, error) {
+	formatOptionsOptions := newFormatOptionsOptions()
+	for _, opt := range opts {
+		opt(formatOptionsOptions)
+	}
+	message := options.ProtoReflect()
+	if resolver != nil && len(message.GetUnknown()) > 0 {
+		data, err := proto.Marshal(options)
+		if err != nil {
+			return "", err
+		}
+		resolvedMessage := message.New()
+		if err := (proto.UnmarshalOptions{Resolver: resolver}).Unmarshal(data, resolvedMessage.Interface()); err != nil {
+			return "", err
+		}
+		message = resolvedMessage
+	}
+	customOptions := message.New()
+	message.Range(
+		func(fieldDescriptor protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+			if fieldDescriptor.IsExtension() {
+				customOptions.Set(fieldDescriptor, value)
+			}
+			return true
+		},
+	)
+	if isEmptyMessage(customOptions) {
+		return "", nil
+	}
+	var data []byte
+	var err error
+	if formatOptionsOptions.json {
+		data, err = protojson.MarshalOptions{Multiline: true, Indent: "  "}.Marshal(customOptions.Interface())
+	} else {
+		data, err = prototext.MarshalOptions{Multiline: true, Indent: "  "}.Marshal(customOptions.Interface())
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// *** PRIVATE ***
+
+type formatOptionsOptions struct {
+	json bool
+}
+
+func newFormatOptionsOptions() *formatOptionsOptions {
+	return &formatOptionsOptions{}
+}
+
+func isEmptyMessage(message protoreflect.Message) bool {
+	empty := true
+	message.Range(
+		func(protoreflect.FieldDescriptor, protoreflect.Value) bool {
+			empty = false
+			return false
+		},
+	)
+	return empty
+}
@@ -0,0 +1,112 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protopluginutil
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/bufbuild/protoplugin/internal/protocompiletest"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func TestFormatOptions(t *testing.T) {
+	t.Parallel()
+
+	files := protocompiletest.CompileFiles(
+		t,
+		map[string][]byte{
+			// protocompiletest.CompileFiles only includes the given files, so provide a minimal descriptor.proto.
+			"google/protobuf/descriptor.proto": []byte(`
+				syntax = "proto2";
+				package google.protobuf;
+				message FieldOptions {
+					optional bool deprecated = 3;
+					extensions 1000 to max;
+				}
+			`),
+			"a.proto": []byte(`
+				syntax = "proto3";
+				package foo;
+				import "google/protobuf/descriptor.proto";
+				extend google.protobuf.FieldOptions {
+					string my_string = 50000;
+					int32 my_int = 50001;
+				}
+				message A {
+					int32 one = 1 [(my_string) = "hello", (my_int) = 5, deprecated = true];
+					int32 two = 2 [deprecated = true];
+				}
+			`),
+		},
+	)
+	resolver := &protoregistry.Types{}
+	for _, name := range []protoreflect.FullName{"foo.my_string", "foo.my_int"} {
+		descriptor, err := files.FindDescriptorByName(name)
+		require.NoError(t, err)
+		extensionDescriptor, ok := descriptor.(protoreflect.ExtensionDescriptor)
+		require.True(t, ok)
+		require.NoError(t, resolver.RegisterExtension(dynamicpb.NewExtensionType(extensionDescriptor)))
+	}
+	fieldOptions := func(name protoreflect.FullName) *descriptorpb.FieldOptions {
+		descriptor, err := files.FindDescriptorByName(name)
+		require.NoError(t, err)
+		// Re-parse with an empty resolver so that the custom options are unknown fields, as they
+		// would be when a plugin reads a CodeGeneratorRequest.
+		data, err := proto.Marshal(descriptor.Options())
+		require.NoError(t, err)
+		options := &descriptorpb.FieldOptions{}
+		require.NoError(t, proto.UnmarshalOptions{Resolver: &protoregistry.Types{}}.Unmarshal(data, options))
+		return options
+	}
+
+	formatted, err := FormatOptions(fieldOptions("foo.A.one"), resolver)
+	require.NoError(t, err)
+	// The text format output is intentionally unstable in whitespace, so compare fields.
+	require.Equal(
+		t,
+		[]string{`[foo.my_int]:`, `5`, `[foo.my_string]:`, `"hello"`},
+		strings.Fields(formatted),
+	)
+
+	formatted, err = FormatOptions(fieldOptions("foo.A.one"), resolver, FormatOptionsWithJSON())
+	require.NoError(t, err)
+	var jsonValue map[string]any
+	require.NoError(t, json.Unmarshal([]byte(formatted), &jsonValue))
+	require.Equal(
+		t,
+		map[string]any{
+			"[foo.my_string]": "hello",
+			"[foo.my_int]":    float64(5),
+		},
+		jsonValue,
+	)
+
+	// Without a resolver, the custom options are unknown fields and are not included.
+	formatted, err = FormatOptions(fieldOptions("foo.A.one"), nil)
+	require.NoError(t, err)
+	require.Empty(t, formatted)
+
+	// Standard options are not included.
+	formatted, err = FormatOptions(fieldOptions("foo.A.two"), resolver)
+	require.NoError(t, err)
+	require.Empty(t, formatted)
+}
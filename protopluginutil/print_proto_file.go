@@ -0,0 +1,679 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protopluginutil
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// PrintProtoFile renders the FileDescriptor as .proto source.
+//
+// This is useful for plugins that produce trimmed or rewritten .proto files, such as schema registries
+// or exports of a subset of an API. The FileDescriptor can be built from a modified FileDescriptorProto
+// with protodesc.NewFile.
+//
+// Rendering is best-effort: the result compiles to a FileDescriptorProto equivalent to the original
+// (other than SourceCodeInfo), but formatting is normalized and the declarations are printed in the order
+// of the descriptor, which may differ from the original source. Message and enum types are always referenced
+// by their fully-qualified names. Comments are included from SourceCodeInfo, if present.
+//
+// Custom options are only printed if they are recognized, that is, if they were resolved when the options
+// were unmarshaled, as opposed to being retained as unknown fields. Use protoplugin.WithExtensionTypeDiscovery
+// to resolve custom options defined within the CodeGeneratorRequest.
+func PrintProtoFile(fileDescriptor protoreflect.FileDescriptor) (string, error) {
+	printer := &protoFilePrinter{
+		fileDescriptor:  fileDescriptor,
+		sourceLocations: fileDescriptor.SourceLocations(),
+	}
+	if err := printer.printFile(); err != nil {
+		return "", err
+	}
+	return printer.builder.String(), nil
+}
+
+// *** PRIVATE ***
+
+const (
+	fileSyntaxTag  = 12
+	fileEditionTag = 14
+	filePackageTag = 2
+	fileImportsTag = 3
+
+	// maxFieldNumber is the maximum field number, which is printed as "max" within ranges.
+	maxFieldNumber = 536870911
+)
+
+type protoFilePrinter struct {
+	fileDescriptor  protoreflect.FileDescriptor
+	sourceLocations protoreflect.SourceLocations
+	builder         strings.Builder
+	indent          int
+	// Whether the last line written was empty or opened a block, in which case no blank line is needed
+	// before the next declaration.
+	noBlankLineNeeded bool
+}
+
+func (p *protoFilePrinter) printFile() error {
+	syntaxSourceLocation := p.sourceLocations.ByPath(protoreflect.SourcePath{fileSyntaxTag})
+	if p.fileDescriptor.Syntax() == protoreflect.Editions {
+		syntaxSourceLocation = p.sourceLocations.ByPath(protoreflect.SourcePath{fileEditionTag})
+	}
+	p.printLeadingComments(syntaxSourceLocation)
+	switch syntax := p.fileDescriptor.Syntax(); syntax {
+	case protoreflect.Proto2, protoreflect.Proto3:
+		p.printStatement(syntaxSourceLocation, fmt.Sprintf("syntax = %q", syntax.String()))
+	case protoreflect.Editions:
+		edition := protodesc.ToFileDescriptorProto(p.fileDescriptor).GetEdition()
+		p.printStatement(syntaxSourceLocation, fmt.Sprintf("edition = %q", strings.TrimPrefix(edition.String(), "EDITION_")))
+	default:
+		return fmt.Errorf("unknown syntax for file %q: %v", p.fileDescriptor.Path(), syntax)
+	}
+	if packageName := p.fileDescriptor.Package(); packageName != "" {
+		p.printBlankLine()
+		packageSourceLocation := p.sourceLocations.ByPath(protoreflect.SourcePath{filePackageTag})
+		p.printLeadingComments(packageSourceLocation)
+		p.printStatement(packageSourceLocation, "package "+string(packageName))
+	}
+	imports := p.fileDescriptor.Imports()
+	for i := 0; i < imports.Len(); i++ {
+		if i == 0 {
+			p.printBlankLine()
+		}
+		fileImport := imports.Get(i)
+		importSourceLocation := p.sourceLocations.ByPath(protoreflect.SourcePath{fileImportsTag, int32(i)}) // #nosec:G115 should never overflow
+		p.printLeadingComments(importSourceLocation)
+		modifier := ""
+		switch {
+		case fileImport.IsPublic:
+			modifier = "public "
+		case fileImport.IsWeak:
+			modifier = "weak "
+		}
+		p.printStatement(importSourceLocation, fmt.Sprintf("import %s%s", modifier, quoteProtoString(fileImport.Path())))
+	}
+	p.printOptionStatements(p.fileDescriptor.Options())
+	groupMessages := make(map[protoreflect.FullName]struct{})
+	p.addExtensionGroupMessages(groupMessages, p.fileDescriptor.Extensions())
+	p.printMessages(p.fileDescriptor.Messages(), groupMessages)
+	p.printEnums(p.fileDescriptor.Enums())
+	services := p.fileDescriptor.Services()
+	for i := 0; i < services.Len(); i++ {
+		p.printService(services.Get(i))
+	}
+	p.printExtensions(p.fileDescriptor.Extensions())
+	return nil
+}
+
+func (p *protoFilePrinter) printMessages(
+	messages protoreflect.MessageDescriptors,
+	// The messages that are printed as groups, and should therefore not be printed as messages.
+	groupMessages map[protoreflect.FullName]struct{},
+) {
+	for i := 0; i < messages.Len(); i++ {
+		message := messages.Get(i)
+		if message.IsMapEntry() {
+			continue
+		}
+		if _, ok := groupMessages[message.FullName()]; ok {
+			continue
+		}
+		p.printBlankLine()
+		p.printLeadingComments(p.sourceLocations.ByDescriptor(message))
+		p.printBlockStart(message, "message "+string(message.Name()))
+		p.printMessageBody(message)
+		p.printBlockEnd()
+	}
+}
+
+func (p *protoFilePrinter) printMessageBody(message protoreflect.MessageDescriptor) {
+	p.printOptionStatements(message.Options())
+	groupMessages := make(map[protoreflect.FullName]struct{})
+	printedOneofs := make(map[protoreflect.FullName]struct{})
+	fields := message.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		if p.isGroup(field) {
+			groupMessages[field.Message().FullName()] = struct{}{}
+		}
+		oneof := field.ContainingOneof()
+		if oneof == nil || oneof.IsSynthetic() {
+			p.printField(field, false)
+			continue
+		}
+		if _, ok := printedOneofs[oneof.FullName()]; ok {
+			continue
+		}
+		printedOneofs[oneof.FullName()] = struct{}{}
+		p.printOneof(oneof)
+	}
+	p.addExtensionGroupMessages(groupMessages, message.Extensions())
+	p.printMessages(message.Messages(), groupMessages)
+	p.printEnums(message.Enums())
+	p.printExtensions(message.Extensions())
+	extensionRanges := message.ExtensionRanges()
+	for i := 0; i < extensionRanges.Len(); i++ {
+		if i == 0 {
+			p.printBlankLine()
+		}
+		extensionRange := extensionRanges.Get(i)
+		p.printLine(
+			"extensions " +
+				formatRange(int64(extensionRange[0]), int64(extensionRange[1])-1, maxFieldNumber) +
+				formatCompactOptions(p.optionEntries(message.ExtensionRangeOptions(i))) +
+				";",
+		)
+	}
+	p.printReserved(message.ReservedRanges(), message.ReservedNames())
+}
+
+func (p *protoFilePrinter) printOneof(oneof protoreflect.OneofDescriptor) {
+	p.printBlankLine()
+	p.printLeadingComments(p.sourceLocations.ByDescriptor(oneof))
+	p.printBlockStart(oneof, "oneof "+string(oneof.Name()))
+	p.printOptionStatements(oneof.Options())
+	fields := oneof.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		p.printField(fields.Get(i), true)
+	}
+	p.printBlockEnd()
+}
+
+func (p *protoFilePrinter) printField(field protoreflect.FieldDescriptor, inOneof bool) {
+	p.printLeadingComments(p.sourceLocations.ByDescriptor(field))
+	var label string
+	switch {
+	case field.IsMap() || inOneof:
+	case field.IsList():
+		label = "repeated "
+	case p.fileDescriptor.Syntax() == protoreflect.Proto2:
+		if field.Cardinality() == protoreflect.Required {
+			label = "required "
+		} else {
+			label = "optional "
+		}
+	case field.HasOptionalKeyword():
+		label = "optional "
+	}
+	var compactOptions []string
+	if field.HasDefault() {
+		compactOptions = append(compactOptions, "default = "+formatDefaultValue(field))
+	}
+	if !field.IsExtension() && field.HasJSONName() && field.JSONName() != defaultJSONName(string(field.Name())) {
+		compactOptions = append(compactOptions, "json_name = "+quoteProtoString(field.JSONName()))
+	}
+	compactOptions = append(compactOptions, p.optionEntries(field.Options())...)
+	if p.isGroup(field) {
+		p.printBlockStart(
+			field,
+			fmt.Sprintf(
+				"%sgroup %s = %d%s",
+				label,
+				field.Message().Name(),
+				field.Number(),
+				formatCompactOptions(compactOptions),
+			),
+		)
+		p.printMessageBody(field.Message())
+		p.printBlockEnd()
+		return
+	}
+	p.printStatement(
+		p.sourceLocations.ByDescriptor(field),
+		fmt.Sprintf(
+			"%s%s %s = %d%s",
+			label,
+			fieldTypeName(field),
+			field.Name(),
+			field.Number(),
+			formatCompactOptions(compactOptions),
+		),
+	)
+}
+
+// addExtensionGroupMessages adds the messages of the extensions that are printed as groups to groupMessages.
+//
+// The message of a group extension is declared in the same scope as the extension, so it is printed
+// within the extend block instead of as a message.
+func (p *protoFilePrinter) addExtensionGroupMessages(
+	groupMessages map[protoreflect.FullName]struct{},
+	extensions protoreflect.ExtensionDescriptors,
+) {
+	for i := 0; i < extensions.Len(); i++ {
+		if extension := extensions.Get(i); p.isGroup(extension) {
+			groupMessages[extension.Message().FullName()] = struct{}{}
+		}
+	}
+}
+
+// isGroup returns true if the field should be printed with the proto2 group syntax.
+func (p *protoFilePrinter) isGroup(field protoreflect.FieldDescriptor) bool {
+	return p.fileDescriptor.Syntax() == protoreflect.Proto2 && field.Kind() == protoreflect.GroupKind
+}
+
+func (p *protoFilePrinter) printExtensions(extensions protoreflect.ExtensionDescriptors) {
+	var extendee protoreflect.FullName
+	for i := 0; i < extensions.Len(); i++ {
+		extension := extensions.Get(i)
+		if i == 0 || extension.ContainingMessage().FullName() != extendee {
+			if i != 0 {
+				p.printBlockEnd()
+			}
+			extendee = extension.ContainingMessage().FullName()
+			p.printBlankLine()
+			p.printLine("extend ." + string(extendee) + " {")
+			p.indent++
+		}
+		p.printField(extension, false)
+	}
+	if extensions.Len() > 0 {
+		p.printBlockEnd()
+	}
+}
+
+func (p *protoFilePrinter) printEnums(enums protoreflect.EnumDescriptors) {
+	for i := 0; i < enums.Len(); i++ {
+		enum := enums.Get(i)
+		p.printBlankLine()
+		p.printLeadingComments(p.sourceLocations.ByDescriptor(enum))
+		p.printBlockStart(enum, "enum "+string(enum.Name()))
+		p.printOptionStatements(enum.Options())
+		values := enum.Values()
+		for j := 0; j < values.Len(); j++ {
+			value := values.Get(j)
+			p.printLeadingComments(p.sourceLocations.ByDescriptor(value))
+			p.printStatement(
+				p.sourceLocations.ByDescriptor(value),
+				fmt.Sprintf(
+					"%s = %d%s",
+					value.Name(),
+					value.Number(),
+					formatCompactOptions(p.optionEntries(value.Options())),
+				),
+			)
+		}
+		reservedRanges := enum.ReservedRanges()
+		var formattedReservedRanges []string
+		for j := 0; j < reservedRanges.Len(); j++ {
+			reservedRange := reservedRanges.Get(j)
+			formattedReservedRanges = append(
+				formattedReservedRanges,
+				formatRange(int64(reservedRange[0]), int64(reservedRange[1]), math.MaxInt32),
+			)
+		}
+		p.printReservedStatements(formattedReservedRanges, enum.ReservedNames())
+		p.printBlockEnd()
+	}
+}
+
+func (p *protoFilePrinter) printService(service protoreflect.ServiceDescriptor) {
+	p.printBlankLine()
+	p.printLeadingComments(p.sourceLocations.ByDescriptor(service))
+	p.printBlockStart(service, "service "+string(service.Name()))
+	p.printOptionStatements(service.Options())
+	methods := service.Methods()
+	for i := 0; i < methods.Len(); i++ {
+		method := methods.Get(i)
+		p.printLeadingComments(p.sourceLocations.ByDescriptor(method))
+		var inputStream, outputStream string
+		if method.IsStreamingClient() {
+			inputStream = "stream "
+		}
+		if method.IsStreamingServer() {
+			outputStream = "stream "
+		}
+		signature := fmt.Sprintf(
+			"rpc %s(%s.%s) returns (%s.%s)",
+			method.Name(),
+			inputStream,
+			method.Input().FullName(),
+			outputStream,
+			method.Output().FullName(),
+		)
+		optionEntries := p.optionEntries(method.Options())
+		if len(optionEntries) == 0 {
+			p.printStatement(p.sourceLocations.ByDescriptor(method), signature)
+			continue
+		}
+		p.printBlockStart(method, signature)
+		for _, optionEntry := range optionEntries {
+			p.printLine("option " + optionEntry + ";")
+		}
+		p.printBlockEnd()
+	}
+	p.printBlockEnd()
+}
+
+func (p *protoFilePrinter) printReserved(reservedRanges protoreflect.FieldRanges, reservedNames protoreflect.Names) {
+	var formattedReservedRanges []string
+	for i := 0; i < reservedRanges.Len(); i++ {
+		reservedRange := reservedRanges.Get(i)
+		formattedReservedRanges = append(
+			formattedReservedRanges,
+			formatRange(int64(reservedRange[0]), int64(reservedRange[1])-1, maxFieldNumber),
+		)
+	}
+	p.printReservedStatements(formattedReservedRanges, reservedNames)
+}
+
+func (p *protoFilePrinter) printReservedStatements(formattedReservedRanges []string, reservedNames protoreflect.Names) {
+	if len(formattedReservedRanges) > 0 {
+		p.printBlankLine()
+		p.printLine("reserved " + strings.Join(formattedReservedRanges, ", ") + ";")
+	}
+	if reservedNames.Len() > 0 {
+		quotedReservedNames := make([]string, reservedNames.Len())
+		for i := 0; i < reservedNames.Len(); i++ {
+			quotedReservedNames[i] = quoteProtoString(string(reservedNames.Get(i)))
+		}
+		if len(formattedReservedRanges) == 0 {
+			p.printBlankLine()
+		}
+		p.printLine("reserved " + strings.Join(quotedReservedNames, ", ") + ";")
+	}
+}
+
+// printOptionStatements prints an option statement for every option set, preceded by a blank line.
+func (p *protoFilePrinter) printOptionStatements(options proto.Message) {
+	optionEntries := p.optionEntries(options)
+	if len(optionEntries) == 0 {
+		return
+	}
+	p.printBlankLine()
+	for _, optionEntry := range optionEntries {
+		p.printLine("option " + optionEntry + ";")
+	}
+	p.printBlankLine()
+}
+
+// optionEntries returns the "name = value" entries for every option set, ordered by field number.
+//
+// Repeated options result in an entry for every value.
+func (p *protoFilePrinter) optionEntries(options proto.Message) []string {
+	if options == nil {
+		return nil
+	}
+	message := options.ProtoReflect()
+	if !message.IsValid() {
+		return nil
+	}
+	var optionEntries []string
+	for _, field := range sortedFields(message) {
+		if !field.IsExtension() && field.Name() == "uninterpreted_option" {
+			continue
+		}
+		name := string(field.Name())
+		if field.IsExtension() {
+			name = "(" + string(field.FullName()) + ")"
+		}
+		value := message.Get(field)
+		if field.IsList() {
+			list := value.List()
+			for i := 0; i < list.Len(); i++ {
+				optionEntries = append(optionEntries, name+" = "+formatValue(field, list.Get(i)))
+			}
+			continue
+		}
+		optionEntries = append(optionEntries, name+" = "+formatValue(field, value))
+	}
+	return optionEntries
+}
+
+// printBlockStart prints the start of a block for the descriptor, including the trailing comments.
+func (p *protoFilePrinter) printBlockStart(descriptor protoreflect.Descriptor, declaration string) {
+	p.printLine(declaration + " {")
+	p.indent++
+	p.printCommentLines(p.sourceLocations.ByDescriptor(descriptor).TrailingComments)
+	p.noBlankLineNeeded = true
+}
+
+func (p *protoFilePrinter) printBlockEnd() {
+	p.indent--
+	p.printLine("}")
+}
+
+// printStatement prints the statement terminated by a semicolon, including the trailing comments.
+func (p *protoFilePrinter) printStatement(sourceLocation protoreflect.SourceLocation, statement string) {
+	trailingComments := strings.TrimSuffix(sourceLocation.TrailingComments, "\n")
+	if trailingComments != "" && !strings.Contains(trailingComments, "\n") {
+		p.printLine(statement + "; //" + trailingComments)
+		return
+	}
+	p.printLine(statement + ";")
+	p.printCommentLines(trailingComments)
+}
+
+func (p *protoFilePrinter) printLeadingComments(sourceLocation protoreflect.SourceLocation) {
+	for _, leadingDetachedComments := range sourceLocation.LeadingDetachedComments {
+		p.printCommentLines(leadingDetachedComments)
+		p.printBlankLine()
+	}
+	p.printCommentLines(sourceLocation.LeadingComments)
+}
+
+func (p *protoFilePrinter) printCommentLines(comments string) {
+	comments = strings.TrimSuffix(comments, "\n")
+	if comments == "" {
+		return
+	}
+	for _, line := range strings.Split(comments, "\n") {
+		p.printLine(strings.TrimRight("//"+line, " \t"))
+	}
+}
+
+// printBlankLine prints a blank line, unless one is not needed.
+func (p *protoFilePrinter) printBlankLine() {
+	if p.noBlankLineNeeded || p.builder.Len() == 0 {
+		return
+	}
+	_, _ = p.builder.WriteString("\n")
+	p.noBlankLineNeeded = true
+}
+
+func (p *protoFilePrinter) printLine(line string) {
+	_, _ = p.builder.WriteString(strings.Repeat("  ", p.indent))
+	_, _ = p.builder.WriteString(line)
+	_, _ = p.builder.WriteString("\n")
+	p.noBlankLineNeeded = false
+}
+
+func fieldTypeName(field protoreflect.FieldDescriptor) string {
+	switch {
+	case field.IsMap():
+		return "map<" + fieldTypeName(field.MapKey()) + ", " + fieldTypeName(field.MapValue()) + ">"
+	case field.Message() != nil:
+		return "." + string(field.Message().FullName())
+	case field.Enum() != nil:
+		return "." + string(field.Enum().FullName())
+	default:
+		return field.Kind().String()
+	}
+}
+
+func formatCompactOptions(compactOptions []string) string {
+	if len(compactOptions) == 0 {
+		return ""
+	}
+	return " [" + strings.Join(compactOptions, ", ") + "]"
+}
+
+// formatRange formats the inclusive range.
+func formatRange(start int64, end int64, maxValue int64) string {
+	switch {
+	case start == end:
+		return strconv.FormatInt(start, 10)
+	case end == maxValue:
+		return strconv.FormatInt(start, 10) + " to max"
+	default:
+		return strconv.FormatInt(start, 10) + " to " + strconv.FormatInt(end, 10)
+	}
+}
+
+func formatDefaultValue(field protoreflect.FieldDescriptor) string {
+	if field.Kind() == protoreflect.EnumKind {
+		return string(field.DefaultEnumValue().Name())
+	}
+	return formatValue(field, field.Default())
+}
+
+// formatValue formats a singular value of the field as a .proto literal.
+func formatValue(field protoreflect.FieldDescriptor, value protoreflect.Value) string {
+	switch field.Kind() {
+	case protoreflect.BoolKind:
+		return strconv.FormatBool(value.Bool())
+	case protoreflect.EnumKind:
+		if enumValue := field.Enum().Values().ByNumber(value.Enum()); enumValue != nil {
+			return string(enumValue.Name())
+		}
+		return strconv.FormatInt(int64(value.Enum()), 10)
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return strconv.FormatInt(value.Int(), 10)
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return strconv.FormatUint(value.Uint(), 10)
+	case protoreflect.FloatKind:
+		return formatFloat(value.Float(), 32)
+	case protoreflect.DoubleKind:
+		return formatFloat(value.Float(), 64)
+	case protoreflect.StringKind:
+		return quoteProtoString(value.String())
+	case protoreflect.BytesKind:
+		return quoteProtoString(string(value.Bytes()))
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return formatMessageLiteral(value.Message())
+	default:
+		return value.String()
+	}
+}
+
+func formatFloat(value float64, bitSize int) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "inf"
+	case math.IsInf(value, -1):
+		return "-inf"
+	case math.IsNaN(value):
+		return "nan"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, bitSize)
+	}
+}
+
+// formatMessageLiteral formats the message in the text format on a single line, for use as an option value.
+func formatMessageLiteral(message protoreflect.Message) string {
+	var entries []string
+	for _, field := range sortedFields(message) {
+		name := field.TextName()
+		if field.IsExtension() {
+			name = "[" + string(field.FullName()) + "]"
+		}
+		value := message.Get(field)
+		switch {
+		case field.IsMap():
+			mapValue := value.Map()
+			var mapKeys []protoreflect.MapKey
+			mapValue.Range(
+				func(mapKey protoreflect.MapKey, _ protoreflect.Value) bool {
+					mapKeys = append(mapKeys, mapKey)
+					return true
+				},
+			)
+			sort.Slice(
+				mapKeys,
+				func(i int, j int) bool {
+					return mapKeys[i].String() < mapKeys[j].String()
+				},
+			)
+			for _, mapKey := range mapKeys {
+				entries = append(
+					entries,
+					fmt.Sprintf(
+						"%s: { key: %s value: %s }",
+						name,
+						formatValue(field.MapKey(), mapKey.Value()),
+						formatValue(field.MapValue(), mapValue.Get(mapKey)),
+					),
+				)
+			}
+		case field.IsList():
+			list := value.List()
+			for i := 0; i < list.Len(); i++ {
+				entries = append(entries, name+": "+formatValue(field, list.Get(i)))
+			}
+		default:
+			entries = append(entries, name+": "+formatValue(field, value))
+		}
+	}
+	if len(entries) == 0 {
+		return "{}"
+	}
+	return "{ " + strings.Join(entries, " ") + " }"
+}
+
+// sortedFields returns the fields set on the message, ordered by field number.
+func sortedFields(message protoreflect.Message) []protoreflect.FieldDescriptor {
+	var fields []protoreflect.FieldDescriptor
+	message.Range(
+		func(field protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+			fields = append(fields, field)
+			return true
+		},
+	)
+	sort.Slice(
+		fields,
+		func(i int, j int) bool {
+			return fields[i].Number() < fields[j].Number()
+		},
+	)
+	return fields
+}
+
+// quoteProtoString quotes the string as a .proto string literal.
+//
+// Printable characters and valid UTF-8 are retained as-is, and all other bytes are escaped.
+func quoteProtoString(value string) string {
+	var builder strings.Builder
+	_ = builder.WriteByte('"')
+	for i := 0; i < len(value); {
+		r, size := utf8.DecodeRuneInString(value[i:])
+		switch {
+		case r == '"':
+			_, _ = builder.WriteString(`\"`)
+		case r == '\\':
+			_, _ = builder.WriteString(`\\`)
+		case r == '\n':
+			_, _ = builder.WriteString(`\n`)
+		case r == '\r':
+			_, _ = builder.WriteString(`\r`)
+		case r == '\t':
+			_, _ = builder.WriteString(`\t`)
+		case (r == utf8.RuneError && size == 1) || r < 0x20 || r == 0x7f:
+			_, _ = fmt.Fprintf(&builder, `\%03o`, value[i])
+		default:
+			_, _ = builder.WriteString(value[i : i+size])
+		}
+		i += size
+	}
+	_ = builder.WriteByte('"')
+	return builder.String()
+}
@@ -0,0 +1,246 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protopluginutil
+
+import (
+	"testing"
+
+	"github.com/bufbuild/protoplugin/internal/protocompiletest"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protodesc"
+)
+
+func TestPrintProtoFile(t *testing.T) {
+	t.Parallel()
+
+	files := protocompiletest.CompileFiles(
+		t,
+		map[string][]byte{
+			"a.proto": []byte(`// Copyright.
+
+syntax = "proto3";
+
+// Package comment.
+package foo;
+
+import "b.proto";
+
+option go_package = "example.com/foo";
+
+// A is a message.
+message A {
+  // B is a field.
+  string b = 1; // Trailing.
+  bar.B c = 2 [deprecated = true];
+  optional int32 d = 3 [json_name = "dee"];
+  oneof e {
+    string f = 4;
+    int64 g = 5;
+  }
+  map<string, A> h = 6;
+  repeated E i = 7;
+  reserved 10, 20 to 30;
+  reserved "j";
+}
+
+enum E {
+  E_UNSPECIFIED = 0;
+  E_ONE = 1;
+}
+
+service S {
+  rpc M(A) returns (stream A) {
+    option idempotency_level = NO_SIDE_EFFECTS;
+  }
+}
+`),
+			"b.proto": []byte(`syntax = "proto3";
+package bar;
+message B {}
+`),
+		},
+	)
+	fileDescriptor, err := files.FindFileByPath("a.proto")
+	require.NoError(t, err)
+	printed, err := PrintProtoFile(fileDescriptor)
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		`// Copyright.
+
+syntax = "proto3";
+
+// Package comment.
+package foo;
+
+import "b.proto";
+
+option go_package = "example.com/foo";
+
+// A is a message.
+message A {
+  // B is a field.
+  string b = 1; // Trailing.
+  .bar.B c = 2 [deprecated = true];
+  optional int32 d = 3 [json_name = "dee"];
+
+  oneof e {
+    string f = 4;
+    int64 g = 5;
+  }
+  map<string, .foo.A> h = 6;
+  repeated .foo.E i = 7;
+
+  reserved 10, 20 to 30;
+  reserved "j";
+}
+
+enum E {
+  E_UNSPECIFIED = 0;
+  E_ONE = 1;
+}
+
+service S {
+  rpc M(.foo.A) returns (stream .foo.A) {
+    option idempotency_level = NO_SIDE_EFFECTS;
+  }
+}
+`,
+		printed,
+	)
+}
+
+func TestPrintProtoFileRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name string
+		data string
+	}{
+		{
+			name: "proto2",
+			data: `syntax = "proto2";
+package foo.bar;
+option java_package = "com.foo";
+option optimize_for = CODE_SIZE;
+message A {
+  option deprecated = true;
+  required string b = 1 [default = "x\"y\n\001"];
+  optional double c = 2 [default = inf];
+  optional float d = 3 [default = -1.5e10];
+  optional int64 e = 4 [default = -0x10];
+  optional E f = 5 [default = E_ONE];
+  optional bytes g = 6 [default = "\377\000"];
+  repeated int32 h = 7 [packed = true];
+  optional group I = 8 {
+    optional string j = 1;
+  }
+  oneof k {
+    string l = 9;
+    A m = 10;
+  }
+  extensions 100 to 199, 1000 to max;
+  extend A {
+    optional string n = 100;
+  }
+  message Nested {
+    enum NestedEnum {
+      NESTED_ZERO = 0;
+      reserved 5 to 10, 20;
+      reserved "FOO";
+    }
+  }
+}
+enum E {
+  option allow_alias = true;
+  E_ZERO = 0;
+  E_ONE = 1;
+  E_ALIAS = 1 [deprecated = true];
+}
+extend A {
+  optional A o = 101;
+  repeated int32 p = 102;
+}
+service S {
+  option deprecated = true;
+  rpc M(stream A) returns (A);
+}
+`,
+		},
+		{
+			name: "proto2_extension_groups",
+			data: `syntax = "proto2";
+package p;
+message A {
+  extensions 100 to 199;
+  message B {
+    optional string b = 1;
+  }
+  extend A {
+    optional group NestedEG = 100 {
+      optional string a = 1;
+    }
+  }
+}
+extend A {
+  repeated group EG = 101 {
+    optional int32 c = 1;
+  }
+  optional string d = 102;
+}
+`,
+		},
+		{
+			name: "editions",
+			data: `edition = "2023";
+package foo;
+option features.field_presence = IMPLICIT;
+message A {
+  string b = 1 [features.field_presence = EXPLICIT];
+  A c = 2 [features.message_encoding = DELIMITED];
+  int32 d = 3 [features.field_presence = LEGACY_REQUIRED];
+  repeated int32 e = 4 [features.repeated_field_encoding = EXPANDED];
+}
+enum E {
+  option features.enum_type = CLOSED;
+  E_ZERO = 0;
+}
+`,
+		},
+	}
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			files := protocompiletest.CompileFiles(t, map[string][]byte{"a.proto": []byte(testCase.data)})
+			fileDescriptor, err := files.FindFileByPath("a.proto")
+			require.NoError(t, err)
+			printed, err := PrintProtoFile(fileDescriptor)
+			require.NoError(t, err)
+			printedFiles := protocompiletest.CompileFiles(t, map[string][]byte{"a.proto": []byte(printed)})
+			printedFileDescriptor, err := printedFiles.FindFileByPath("a.proto")
+			require.NoError(t, err)
+			require.True(
+				t,
+				EqualFileDescriptorProtosIgnoringInfo(
+					protodesc.ToFileDescriptorProto(fileDescriptor),
+					protodesc.ToFileDescriptorProto(printedFileDescriptor),
+					EqualFileDescriptorProtosWithSemanticEquality(),
+				),
+				printed,
+			)
+		})
+	}
+}
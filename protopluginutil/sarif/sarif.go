@@ -0,0 +1,346 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sarif accumulates findings of lint-style plugins and emits them as SARIF reports.
+//
+// SARIF is the Static Analysis Results Interchange Format, as specified at
+// https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html. SARIF reports can be uploaded
+// to GitHub code scanning, among other tools.
+package sarif
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/bufbuild/protoplugin"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	// Version is the version of SARIF that reports conform to.
+	Version = "2.1.0"
+	// SchemaURI is the URI of the JSON schema for the version of SARIF that reports conform to.
+	SchemaURI = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+// Level is the level of a Finding.
+type Level string
+
+const (
+	// LevelError is a serious problem.
+	LevelError Level = "error"
+	// LevelWarning is a problem that is not serious.
+	LevelWarning Level = "warning"
+	// LevelNote is a minor problem or an opportunity for improvement.
+	LevelNote Level = "note"
+)
+
+// Finding is a single result of analysis.
+type Finding struct {
+	// RuleID is the identifier of the rule that produced the Finding, for example "FIELD_LOWER_SNAKE_CASE".
+	//
+	// Required.
+	RuleID string
+	// Level is the level of the Finding.
+	//
+	// If empty, LevelWarning is used.
+	Level Level
+	// Message is the human-readable message.
+	//
+	// Required.
+	Message string
+	// Descriptor is the optional descriptor the Finding applies to.
+	//
+	// If set, the location of the Finding is the path of the file of the Descriptor, and the span of
+	// the Descriptor from the SourceCodeInfo of the file, if present.
+	Descriptor protoreflect.Descriptor
+}
+
+// Report accumulates Findings and produces a SARIF report.
+//
+// Reports are safe for concurrent use.
+type Report struct {
+	toolName string
+	options  *reportOptions
+
+	lock     sync.Mutex
+	findings []Finding
+}
+
+// ReportOption is an option for NewReport.
+type ReportOption func(*reportOptions)
+
+// ReportWithToolVersion returns a new ReportOption that sets the version of the tool that produced the report.
+//
+// The default is to not include a version.
+func ReportWithToolVersion(toolVersion string) ReportOption {
+	return func(reportOptions *reportOptions) {
+		reportOptions.toolVersion = toolVersion
+	}
+}
+
+// ReportWithInformationURI returns a new ReportOption that sets the URI of documentation for the tool that
+// produced the report.
+//
+// The default is to not include an information URI.
+func ReportWithInformationURI(informationURI string) ReportOption {
+	return func(reportOptions *reportOptions) {
+		reportOptions.informationURI = informationURI
+	}
+}
+
+// ReportWithRuleHelp returns a new ReportOption that sets the short description of the rule with the given ID.
+//
+// This may be specified multiple times for different rules. The default is to not include a description.
+func ReportWithRuleHelp(ruleID string, help string) ReportOption {
+	return func(reportOptions *reportOptions) {
+		reportOptions.ruleIDToHelp[ruleID] = help
+	}
+}
+
+// NewReport returns a new Report for the tool with the given name.
+//
+// The tool name is typically the plugin name, as returned by protoplugin.PluginEnv.Name.
+func NewReport(toolName string, options ...ReportOption) *Report {
+	reportOptions := newReportOptions()
+	for _, option := range options {
+		option(reportOptions)
+	}
+	return &Report{
+		toolName: toolName,
+		options:  reportOptions,
+	}
+}
+
+// Add adds the Finding to the Report.
+func (r *Report) Add(finding Finding) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.findings = append(r.findings, finding)
+}
+
+// Len returns the number of Findings added to the Report.
+func (r *Report) Len() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return len(r.findings)
+}
+
+// Marshal returns the indented JSON representation of the SARIF report.
+//
+// Results are in the order that Findings were added. Rules are sorted by ID.
+func (r *Report) Marshal() ([]byte, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	data, err := json.MarshalIndent(r.toLog(), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// AddFile adds the SARIF report as a file with the given name to the ResponseWriter.
+//
+// The file name typically has the extension ".sarif".
+func (r *Report) AddFile(responseWriter protoplugin.ResponseWriter, name string) error {
+	data, err := r.Marshal()
+	if err != nil {
+		return err
+	}
+	responseWriter.AddFile(name, string(data))
+	return nil
+}
+
+// Write writes the SARIF report to the writer, for example to protoplugin.PluginEnv.Stderr.
+func (r *Report) Write(writer io.Writer) error {
+	data, err := r.Marshal()
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(data)
+	return err
+}
+
+// *** PRIVATE ***
+
+type reportOptions struct {
+	toolVersion    string
+	informationURI string
+	ruleIDToHelp   map[string]string
+}
+
+func newReportOptions() *reportOptions {
+	return &reportOptions{
+		ruleIDToHelp: make(map[string]string),
+	}
+}
+
+type sarifLog struct {
+	Schema  string      `json:"$schema"`
+	Version string      `json:"version"`
+	Runs    []*sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    *sarifTool     `json:"tool"`
+	Results []*sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver *sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string       `json:"name"`
+	Version        string       `json:"version,omitempty"`
+	InformationURI string       `json:"informationUri,omitempty"`
+	Rules          []*sarifRule `json:"rules,omitempty"`
+}
+
+type sarifRule struct {
+	ID               string        `json:"id"`
+	ShortDescription *sarifMessage `json:"shortDescription,omitempty"`
+}
+
+type sarifResult struct {
+	RuleID    string           `json:"ruleId"`
+	RuleIndex int              `json:"ruleIndex"`
+	Level     Level            `json:"level"`
+	Message   *sarifMessage    `json:"message"`
+	Locations []*sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation *sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation *sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion           `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine"`
+	EndColumn   int `json:"endColumn"`
+}
+
+// toLog converts the Report to a SARIF log.
+//
+// Must be called while holding the lock.
+func (r *Report) toLog() *sarifLog {
+	ruleIDs := make(map[string]struct{}, len(r.options.ruleIDToHelp))
+	for ruleID := range r.options.ruleIDToHelp {
+		ruleIDs[ruleID] = struct{}{}
+	}
+	for _, finding := range r.findings {
+		ruleIDs[finding.RuleID] = struct{}{}
+	}
+	sortedRuleIDs := make([]string, 0, len(ruleIDs))
+	for ruleID := range ruleIDs {
+		sortedRuleIDs = append(sortedRuleIDs, ruleID)
+	}
+	sort.Strings(sortedRuleIDs)
+	ruleIDToIndex := make(map[string]int, len(sortedRuleIDs))
+	rules := make([]*sarifRule, len(sortedRuleIDs))
+	for i, ruleID := range sortedRuleIDs {
+		ruleIDToIndex[ruleID] = i
+		rules[i] = &sarifRule{ID: ruleID}
+		if help := r.options.ruleIDToHelp[ruleID]; help != "" {
+			rules[i].ShortDescription = &sarifMessage{Text: help}
+		}
+	}
+	// SARIF requires results to be present, even if empty.
+	results := make([]*sarifResult, 0, len(r.findings))
+	for _, finding := range r.findings {
+		level := finding.Level
+		if level == "" {
+			level = LevelWarning
+		}
+		result := &sarifResult{
+			RuleID:    finding.RuleID,
+			RuleIndex: ruleIDToIndex[finding.RuleID],
+			Level:     level,
+			Message:   &sarifMessage{Text: finding.Message},
+		}
+		if location := newLocation(finding.Descriptor); location != nil {
+			result.Locations = []*sarifLocation{location}
+		}
+		results = append(results, result)
+	}
+	return &sarifLog{
+		Schema:  SchemaURI,
+		Version: Version,
+		Runs: []*sarifRun{
+			{
+				Tool: &sarifTool{
+					Driver: &sarifDriver{
+						Name:           r.toolName,
+						Version:        r.options.toolVersion,
+						InformationURI: r.options.informationURI,
+						Rules:          rules,
+					},
+				},
+				Results: results,
+			},
+		},
+	}
+}
+
+// newLocation returns the location of the descriptor, or nil if the descriptor is nil.
+func newLocation(descriptor protoreflect.Descriptor) *sarifLocation {
+	if descriptor == nil {
+		return nil
+	}
+	fileDescriptor := descriptor.ParentFile()
+	if fileDescriptor == nil {
+		return nil
+	}
+	physicalLocation := &sarifPhysicalLocation{
+		ArtifactLocation: &sarifArtifactLocation{
+			URI: fileDescriptor.Path(),
+		},
+	}
+	// A file has no span of its own.
+	if descriptor != fileDescriptor {
+		sourceLocation := fileDescriptor.SourceLocations().ByDescriptor(descriptor)
+		// SourceCodeInfo lines and columns are zero-based, while SARIF lines and columns are one-based.
+		// Both use exclusive end columns.
+		if sourceLocation.Path != nil {
+			physicalLocation.Region = &sarifRegion{
+				StartLine:   sourceLocation.StartLine + 1,
+				StartColumn: sourceLocation.StartColumn + 1,
+				EndLine:     sourceLocation.EndLine + 1,
+				EndColumn:   sourceLocation.EndColumn + 1,
+			}
+		}
+	}
+	return &sarifLocation{
+		PhysicalLocation: physicalLocation,
+	}
+}
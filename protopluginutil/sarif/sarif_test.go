@@ -0,0 +1,145 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sarif
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/bufbuild/protoplugin"
+	"github.com/bufbuild/protoplugin/internal/protocompiletest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReport(t *testing.T) {
+	t.Parallel()
+
+	fileDescriptor := protocompiletest.CompileFile(
+		t,
+		"a/a.proto",
+		[]byte(`syntax = "proto3";
+package foo;
+message A {
+  string FooBar = 1;
+}
+`),
+	)
+	report := NewReport(
+		"protoc-gen-lint",
+		ReportWithToolVersion("1.0.0"),
+		ReportWithRuleHelp("FIELD_LOWER_SNAKE_CASE", "Field names must be lower_snake_case."),
+	)
+	report.Add(
+		Finding{
+			RuleID:     "FIELD_LOWER_SNAKE_CASE",
+			Level:      LevelError,
+			Message:    `Field name "FooBar" should be "foo_bar".`,
+			Descriptor: fileDescriptor.Messages().Get(0).Fields().Get(0),
+		},
+	)
+	report.Add(
+		Finding{
+			RuleID:     "PACKAGE_VERSION_SUFFIX",
+			Message:    `Package name "foo" should be suffixed with a version.`,
+			Descriptor: fileDescriptor,
+		},
+	)
+	report.Add(
+		Finding{
+			RuleID:  "PACKAGE_VERSION_SUFFIX",
+			Level:   LevelNote,
+			Message: "No location.",
+		},
+	)
+	require.Equal(t, 3, report.Len())
+	data, err := report.Marshal()
+	require.NoError(t, err)
+	require.JSONEq(
+		t,
+		`{
+  "$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+  "version": "2.1.0",
+  "runs": [
+    {
+      "tool": {
+        "driver": {
+          "name": "protoc-gen-lint",
+          "version": "1.0.0",
+          "rules": [
+            {
+              "id": "FIELD_LOWER_SNAKE_CASE",
+              "shortDescription": {"text": "Field names must be lower_snake_case."}
+            },
+            {
+              "id": "PACKAGE_VERSION_SUFFIX"
+            }
+          ]
+        }
+      },
+      "results": [
+        {
+          "ruleId": "FIELD_LOWER_SNAKE_CASE",
+          "ruleIndex": 0,
+          "level": "error",
+          "message": {"text": "Field name \"FooBar\" should be \"foo_bar\"."},
+          "locations": [
+            {
+              "physicalLocation": {
+                "artifactLocation": {"uri": "a/a.proto"},
+                "region": {"startLine": 4, "startColumn": 3, "endLine": 4, "endColumn": 21}
+              }
+            }
+          ]
+        },
+        {
+          "ruleId": "PACKAGE_VERSION_SUFFIX",
+          "ruleIndex": 1,
+          "level": "warning",
+          "message": {"text": "Package name \"foo\" should be suffixed with a version."},
+          "locations": [
+            {
+              "physicalLocation": {
+                "artifactLocation": {"uri": "a/a.proto"}
+              }
+            }
+          ]
+        },
+        {
+          "ruleId": "PACKAGE_VERSION_SUFFIX",
+          "ruleIndex": 1,
+          "level": "note",
+          "message": {"text": "No location."}
+        }
+      ]
+    }
+  ]
+}`,
+		string(data),
+	)
+
+	responseWriter := protoplugin.NewResponseWriter()
+	require.NoError(t, report.AddFile(responseWriter, "lint.sarif"))
+	codeGeneratorResponse, err := responseWriter.ToCodeGeneratorResponse()
+	require.NoError(t, err)
+	require.Len(t, codeGeneratorResponse.GetFile(), 1)
+	require.Equal(t, string(data), codeGeneratorResponse.GetFile()[0].GetContent())
+
+	// An empty report still has an empty results array, as required by SARIF.
+	data, err = NewReport("protoc-gen-lint").Marshal()
+	require.NoError(t, err)
+	var log map[string]any
+	require.NoError(t, json.Unmarshal(data, &log))
+	require.Equal(t, []any{}, log["runs"].([]any)[0].(map[string]any)["results"])
+}
@@ -0,0 +1,262 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package schema maps Protobuf message descriptors to an abstract, language-agnostic schema model.
+//
+// The model is intended for generators that produce schemas for other systems, such as SQL DDL,
+// BigQuery table schemas, or Avro schemas. These generators all need to make the same decisions
+// about how Protobuf types map to columns: which fields are nullable, how repeated and map fields
+// are represented, and how the well-known types such as google.protobuf.Timestamp and the wrapper
+// types are treated. This package makes these decisions once, so that generators only need to map
+// each Kind to a type in their target system.
+package schema
+
+import (
+	"github.com/bufbuild/protoplugin/protopluginutil"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Kind is the kind of a Type.
+type Kind int
+
+const (
+	// KindBool is a boolean.
+	KindBool Kind = iota + 1
+	// KindInt32 is a signed 32-bit integer.
+	KindInt32
+	// KindInt64 is a signed 64-bit integer.
+	KindInt64
+	// KindUint32 is an unsigned 32-bit integer.
+	KindUint32
+	// KindUint64 is an unsigned 64-bit integer.
+	KindUint64
+	// KindFloat is a 32-bit floating point number.
+	KindFloat
+	// KindDouble is a 64-bit floating point number.
+	KindDouble
+	// KindString is a UTF-8 string.
+	KindString
+	// KindBytes is a sequence of bytes.
+	KindBytes
+	// KindEnum is an enum. Type.Enum is set.
+	KindEnum
+	// KindTimestamp is a point in time, from google.protobuf.Timestamp.
+	KindTimestamp
+	// KindDuration is a span of time, from google.protobuf.Duration.
+	KindDuration
+	// KindJSON is an arbitrary JSON value, from google.protobuf.Struct, google.protobuf.Value,
+	// google.protobuf.ListValue, and google.protobuf.Any.
+	KindJSON
+	// KindRecord is a nested message. Type.Record is set.
+	KindRecord
+	// KindList is a list of elements. Type.Element is set.
+	KindList
+	// KindMap is a map from keys to values. Type.Key and Type.Element are set.
+	KindMap
+)
+
+// String implements fmt.Stringer.
+func (k Kind) String() string {
+	if name, ok := kindToString[k]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// Type is the type of a Field.
+type Type struct {
+	// Kind is the kind of the type.
+	Kind Kind
+	// Enum is the enum, if Kind is KindEnum.
+	Enum protoreflect.EnumDescriptor
+	// Record is the nested Record, if Kind is KindRecord.
+	Record *Record
+	// Key is the type of the keys, if Kind is KindMap.
+	//
+	// This is always a scalar kind.
+	Key *Type
+	// Element is the type of the elements if Kind is KindList, or the type of the values if Kind is KindMap.
+	Element *Type
+}
+
+// Field is a field of a Record.
+type Field struct {
+	// Name is the name of the field, as declared within the .proto file.
+	Name string
+	// Descriptor is the descriptor of the field.
+	Descriptor protoreflect.FieldDescriptor
+	// Type is the type of the field.
+	Type *Type
+	// Nullable says that the field can be absent.
+	//
+	// This is true for singular fields with explicit presence, including message fields, fields within
+	// oneofs, and proto3 optional fields, and for fields of the wrapper types such as google.protobuf.StringValue.
+	// Repeated and map fields are never nullable, as the absence of elements is represented as an empty list or map.
+	Nullable bool
+	// Comment is the comment attached to the field, if any.
+	Comment string
+}
+
+// Record is the schema of a message.
+type Record struct {
+	// Descriptor is the descriptor of the message.
+	Descriptor protoreflect.MessageDescriptor
+	// Fields are the fields of the message, in declaration order.
+	Fields []*Field
+	// Comment is the comment attached to the message, if any.
+	Comment string
+}
+
+// NewRecord returns a new Record for the message.
+//
+// Records for messages referenced by fields are created recursively. Each message results in a single
+// Record, so messages that reference themselves, directly or indirectly, result in cyclic Records.
+// Generators that flatten Records must detect cycles, for example by tracking the Records being visited.
+func NewRecord(message protoreflect.MessageDescriptor) *Record {
+	builder := &recordBuilder{
+		nameToRecord: make(map[protoreflect.FullName]*Record),
+	}
+	return builder.record(message)
+}
+
+// *** PRIVATE ***
+
+var kindToString = map[Kind]string{
+	KindBool:      "bool",
+	KindInt32:     "int32",
+	KindInt64:     "int64",
+	KindUint32:    "uint32",
+	KindUint64:    "uint64",
+	KindFloat:     "float",
+	KindDouble:    "double",
+	KindString:    "string",
+	KindBytes:     "bytes",
+	KindEnum:      "enum",
+	KindTimestamp: "timestamp",
+	KindDuration:  "duration",
+	KindJSON:      "json",
+	KindRecord:    "record",
+	KindList:      "list",
+	KindMap:       "map",
+}
+
+// wellKnownTypeNameToKind contains the well-known types that are mapped to a Kind instead of a Record.
+var wellKnownTypeNameToKind = map[protoreflect.FullName]Kind{
+	"google.protobuf.Any":       KindJSON,
+	"google.protobuf.Duration":  KindDuration,
+	"google.protobuf.FieldMask": KindString,
+	"google.protobuf.ListValue": KindJSON,
+	"google.protobuf.Struct":    KindJSON,
+	"google.protobuf.Timestamp": KindTimestamp,
+	"google.protobuf.Value":     KindJSON,
+}
+
+// wrapperTypeNameToKind contains the wrapper types, which are mapped to nullable scalars.
+var wrapperTypeNameToKind = map[protoreflect.FullName]Kind{
+	"google.protobuf.BoolValue":   KindBool,
+	"google.protobuf.BytesValue":  KindBytes,
+	"google.protobuf.DoubleValue": KindDouble,
+	"google.protobuf.FloatValue":  KindFloat,
+	"google.protobuf.Int32Value":  KindInt32,
+	"google.protobuf.Int64Value":  KindInt64,
+	"google.protobuf.StringValue": KindString,
+	"google.protobuf.UInt32Value": KindUint32,
+	"google.protobuf.UInt64Value": KindUint64,
+}
+
+type recordBuilder struct {
+	nameToRecord map[protoreflect.FullName]*Record
+}
+
+func (b *recordBuilder) record(message protoreflect.MessageDescriptor) *Record {
+	if record, ok := b.nameToRecord[message.FullName()]; ok {
+		return record
+	}
+	record := &Record{
+		Descriptor: message,
+		Comment:    protopluginutil.DescriptorComments(message),
+	}
+	// Add before recursing so that recursive messages terminate.
+	b.nameToRecord[message.FullName()] = record
+	fields := message.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		record.Fields = append(record.Fields, b.field(fields.Get(i)))
+	}
+	return record
+}
+
+func (b *recordBuilder) field(fieldDescriptor protoreflect.FieldDescriptor) *Field {
+	field := &Field{
+		Name:       string(fieldDescriptor.Name()),
+		Descriptor: fieldDescriptor,
+		Comment:    protopluginutil.DescriptorComments(fieldDescriptor),
+	}
+	switch {
+	case fieldDescriptor.IsMap():
+		keyType, _ := b.singularType(fieldDescriptor.MapKey())
+		valueType, _ := b.singularType(fieldDescriptor.MapValue())
+		field.Type = &Type{
+			Kind:    KindMap,
+			Key:     keyType,
+			Element: valueType,
+		}
+	case fieldDescriptor.IsList():
+		elementType, _ := b.singularType(fieldDescriptor)
+		field.Type = &Type{
+			Kind:    KindList,
+			Element: elementType,
+		}
+	default:
+		var isWrapper bool
+		field.Type, isWrapper = b.singularType(fieldDescriptor)
+		field.Nullable = isWrapper || protopluginutil.FieldHasExplicitPresence(fieldDescriptor)
+	}
+	return field
+}
+
+// singularType returns the Type of a single value of the field, and whether the field is of a wrapper type.
+func (b *recordBuilder) singularType(fieldDescriptor protoreflect.FieldDescriptor) (*Type, bool) {
+	switch fieldDescriptor.Kind() {
+	case protoreflect.BoolKind:
+		return &Type{Kind: KindBool}, false
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return &Type{Kind: KindInt32}, false
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return &Type{Kind: KindInt64}, false
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return &Type{Kind: KindUint32}, false
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return &Type{Kind: KindUint64}, false
+	case protoreflect.FloatKind:
+		return &Type{Kind: KindFloat}, false
+	case protoreflect.DoubleKind:
+		return &Type{Kind: KindDouble}, false
+	case protoreflect.StringKind:
+		return &Type{Kind: KindString}, false
+	case protoreflect.BytesKind:
+		return &Type{Kind: KindBytes}, false
+	case protoreflect.EnumKind:
+		return &Type{Kind: KindEnum, Enum: fieldDescriptor.Enum()}, false
+	default:
+		// MessageKind and GroupKind.
+		message := fieldDescriptor.Message()
+		if kind, ok := wrapperTypeNameToKind[message.FullName()]; ok {
+			return &Type{Kind: kind}, true
+		}
+		if kind, ok := wellKnownTypeNameToKind[message.FullName()]; ok {
+			return &Type{Kind: kind}, false
+		}
+		return &Type{Kind: KindRecord, Record: b.record(message)}, false
+	}
+}
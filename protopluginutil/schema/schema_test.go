@@ -0,0 +1,117 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import (
+	"testing"
+
+	"github.com/bufbuild/protoplugin/internal/protocompiletest"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func TestNewRecord(t *testing.T) {
+	t.Parallel()
+
+	fileDescriptor := protocompiletest.CompileFile(
+		t,
+		"a.proto",
+		[]byte(`
+			syntax = "proto3";
+			package foo;
+			import "google/protobuf/struct.proto";
+			import "google/protobuf/timestamp.proto";
+			import "google/protobuf/wrappers.proto";
+			// A is a message.
+			message A {
+				// id is the ID.
+				int64 id = 1;
+				optional string name = 2;
+				repeated uint32 numbers = 3;
+				map<string, B> bs = 4;
+				google.protobuf.Timestamp create_time = 5;
+				google.protobuf.StringValue nickname = 6;
+				google.protobuf.Struct metadata = 7;
+				E e = 8;
+				A parent = 9;
+				oneof value {
+					double double_value = 10;
+					bytes bytes_value = 11;
+				}
+			}
+			message B {
+				fixed64 count = 1;
+			}
+			enum E {
+				E_UNSPECIFIED = 0;
+			}
+		`),
+	)
+	record := NewRecord(fileDescriptor.Messages().ByName("A"))
+	require.Equal(t, "A is a message.", record.Comment)
+	require.Equal(
+		t,
+		[]testField{
+			{name: "id", kind: KindInt64},
+			{name: "name", kind: KindString, nullable: true},
+			{name: "numbers", kind: KindList, elementKind: KindUint32},
+			{name: "bs", kind: KindMap, keyKind: KindString, elementKind: KindRecord},
+			{name: "create_time", kind: KindTimestamp, nullable: true},
+			{name: "nickname", kind: KindString, nullable: true},
+			{name: "metadata", kind: KindJSON, nullable: true},
+			{name: "e", kind: KindEnum},
+			{name: "parent", kind: KindRecord, nullable: true},
+			{name: "double_value", kind: KindDouble, nullable: true},
+			{name: "bytes_value", kind: KindBytes, nullable: true},
+		},
+		testFields(record),
+	)
+	require.Equal(t, "id is the ID.", record.Fields[0].Comment)
+	require.Equal(t, protoreflect.FullName("foo.E"), record.Fields[7].Type.Enum.FullName())
+	// Recursive messages result in cyclic Records.
+	require.Same(t, record, record.Fields[8].Type.Record)
+	bRecord := record.Fields[3].Type.Element.Record
+	require.Equal(t, protoreflect.FullName("foo.B"), bRecord.Descriptor.FullName())
+	require.Equal(t, []testField{{name: "count", kind: KindUint64}}, testFields(bRecord))
+
+	require.Equal(t, "timestamp", KindTimestamp.String())
+	require.Equal(t, "unknown", Kind(0).String())
+}
+
+type testField struct {
+	name        string
+	kind        Kind
+	keyKind     Kind
+	elementKind Kind
+	nullable    bool
+}
+
+func testFields(record *Record) []testField {
+	testFields := make([]testField, len(record.Fields))
+	for i, field := range record.Fields {
+		testFields[i] = testField{
+			name:     field.Name,
+			kind:     field.Type.Kind,
+			nullable: field.Nullable,
+		}
+		if field.Type.Key != nil {
+			testFields[i].keyKind = field.Type.Key.Kind
+		}
+		if field.Type.Element != nil {
+			testFields[i].elementKind = field.Type.Element.Kind
+		}
+	}
+	return testFields
+}
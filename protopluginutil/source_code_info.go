@@ -0,0 +1,150 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protopluginutil
+
+import (
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// StripSourceCodeInfoOption is an option for StripSourceCodeInfo and StripSourceCodeInfoFromRequest.
+type StripSourceCodeInfoOption func(*stripSourceCodeInfoOptions)
+
+// StripSourceCodeInfoWithLeadingComments returns a new StripSourceCodeInfoOption that says to retain
+// the locations that have leading comments.
+//
+// Only the path, span, and leading comments of these locations are retained. All other locations,
+// and the trailing and leading detached comments of all locations, are removed.
+//
+// The default is to remove all locations.
+func StripSourceCodeInfoWithLeadingComments() StripSourceCodeInfoOption {
+	return func(stripSourceCodeInfoOptions *stripSourceCodeInfoOptions) {
+		stripSourceCodeInfoOptions.keepLeadingComments = true
+	}
+}
+
+// StripSourceCodeInfo returns a FileDescriptorProto that omits SourceCodeInfo.
+//
+// This reduces memory usage and marshaling costs for plugins that forward requests to
+// generators that never read comments or source locations.
+//
+// If the FileDescriptorProto has no SourceCodeInfo, the original FileDescriptorProto is returned.
+// Otherwise, a new FileDescriptorProto is returned with the SourceCodeInfo stripped.
+//
+// Even when a copy is returned, it is not a deep copy: it may share data with the
+// input FileDescriptorProto, and mutations to the returned FileDescriptorProto may impact
+// the input FileDescriptorProto.
+func StripSourceCodeInfo(
+	file *descriptorpb.FileDescriptorProto,
+	options ...StripSourceCodeInfoOption,
+) (*descriptorpb.FileDescriptorProto, error) {
+	stripSourceCodeInfoOptions := newStripSourceCodeInfoOptions()
+	for _, option := range options {
+		option(stripSourceCodeInfoOptions)
+	}
+	return stripSourceCodeInfo(file, stripSourceCodeInfoOptions)
+}
+
+// StripSourceCodeInfoFromRequest returns a CodeGeneratorRequest with SourceCodeInfo stripped from all
+// FileDescriptorProtos in the proto_file and source_file_descriptors fields.
+//
+// See StripSourceCodeInfo for more details.
+//
+// The input CodeGeneratorRequest is never modified, however the returned CodeGeneratorRequest
+// is not a deep copy, and may share data with the input CodeGeneratorRequest.
+func StripSourceCodeInfoFromRequest(
+	request *pluginpb.CodeGeneratorRequest,
+	options ...StripSourceCodeInfoOption,
+) (*pluginpb.CodeGeneratorRequest, error) {
+	stripSourceCodeInfoOptions := newStripSourceCodeInfoOptions()
+	for _, option := range options {
+		option(stripSourceCodeInfoOptions)
+	}
+	newRequest, err := shallowCopy(request)
+	if err != nil {
+		return nil, err
+	}
+	newRequest.ProtoFile, err = stripSourceCodeInfoFromAll(request.GetProtoFile(), stripSourceCodeInfoOptions)
+	if err != nil {
+		return nil, err
+	}
+	newRequest.SourceFileDescriptors, err = stripSourceCodeInfoFromAll(request.GetSourceFileDescriptors(), stripSourceCodeInfoOptions)
+	if err != nil {
+		return nil, err
+	}
+	return newRequest, nil
+}
+
+// *** PRIVATE ***
+
+type stripSourceCodeInfoOptions struct {
+	keepLeadingComments bool
+}
+
+func newStripSourceCodeInfoOptions() *stripSourceCodeInfoOptions {
+	return &stripSourceCodeInfoOptions{}
+}
+
+func stripSourceCodeInfoFromAll(
+	files []*descriptorpb.FileDescriptorProto,
+	stripSourceCodeInfoOptions *stripSourceCodeInfoOptions,
+) ([]*descriptorpb.FileDescriptorProto, error) {
+	if files == nil {
+		return nil, nil
+	}
+	newFiles := make([]*descriptorpb.FileDescriptorProto, len(files))
+	for i, file := range files {
+		newFile, err := stripSourceCodeInfo(file, stripSourceCodeInfoOptions)
+		if err != nil {
+			return nil, err
+		}
+		newFiles[i] = newFile
+	}
+	return newFiles, nil
+}
+
+func stripSourceCodeInfo(
+	file *descriptorpb.FileDescriptorProto,
+	stripSourceCodeInfoOptions *stripSourceCodeInfoOptions,
+) (*descriptorpb.FileDescriptorProto, error) {
+	if file.GetSourceCodeInfo() == nil {
+		return file, nil
+	}
+	newFile, err := shallowCopy(file)
+	if err != nil {
+		return nil, err
+	}
+	newFile.SourceCodeInfo = nil
+	if stripSourceCodeInfoOptions.keepLeadingComments {
+		var locations []*descriptorpb.SourceCodeInfo_Location
+		for _, location := range file.GetSourceCodeInfo().GetLocation() {
+			if location.LeadingComments == nil {
+				continue
+			}
+			locations = append(
+				locations,
+				&descriptorpb.SourceCodeInfo_Location{
+					Path:            location.GetPath(),
+					Span:            location.GetSpan(),
+					LeadingComments: location.LeadingComments,
+				},
+			)
+		}
+		if len(locations) > 0 {
+			newFile.SourceCodeInfo = &descriptorpb.SourceCodeInfo{Location: locations}
+		}
+	}
+	return newFile, nil
+}
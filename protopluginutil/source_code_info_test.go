@@ -0,0 +1,113 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protopluginutil
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestStripSourceCodeInfo(t *testing.T) {
+	t.Parallel()
+
+	file := testNewFileDescriptorProtoWithSourceCodeInfo()
+	original := proto.Clone(file)
+
+	stripped, err := StripSourceCodeInfo(file)
+	require.NoError(t, err)
+	require.Nil(t, stripped.GetSourceCodeInfo())
+	require.Equal(t, "a.proto", stripped.GetName())
+	require.Empty(t, cmp.Diff(original, file, protocmp.Transform()), "input must not be modified")
+
+	stripped, err = StripSourceCodeInfo(file, StripSourceCodeInfoWithLeadingComments())
+	require.NoError(t, err)
+	require.Empty(
+		t,
+		cmp.Diff(
+			&descriptorpb.SourceCodeInfo{
+				Location: []*descriptorpb.SourceCodeInfo_Location{
+					{
+						Path:            []int32{4, 0},
+						Span:            []int32{2, 0, 3, 1},
+						LeadingComments: proto.String(" A is a message.\n"),
+					},
+				},
+			},
+			stripped.GetSourceCodeInfo(),
+			protocmp.Transform(),
+		),
+	)
+	require.Empty(t, cmp.Diff(original, file, protocmp.Transform()), "input must not be modified")
+
+	noSourceCodeInfo := &descriptorpb.FileDescriptorProto{Name: proto.String("b.proto")}
+	stripped, err = StripSourceCodeInfo(noSourceCodeInfo)
+	require.NoError(t, err)
+	require.Same(t, noSourceCodeInfo, stripped)
+}
+
+func TestStripSourceCodeInfoFromRequest(t *testing.T) {
+	t.Parallel()
+
+	request := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate:        []string{"a.proto"},
+		Parameter:             proto.String("foo=bar"),
+		ProtoFile:             []*descriptorpb.FileDescriptorProto{testNewFileDescriptorProtoWithSourceCodeInfo()},
+		SourceFileDescriptors: []*descriptorpb.FileDescriptorProto{testNewFileDescriptorProtoWithSourceCodeInfo()},
+	}
+	original := proto.Clone(request)
+
+	stripped, err := StripSourceCodeInfoFromRequest(request)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a.proto"}, stripped.GetFileToGenerate())
+	require.Equal(t, "foo=bar", stripped.GetParameter())
+	require.Len(t, stripped.GetProtoFile(), 1)
+	require.Nil(t, stripped.GetProtoFile()[0].GetSourceCodeInfo())
+	require.Len(t, stripped.GetSourceFileDescriptors(), 1)
+	require.Nil(t, stripped.GetSourceFileDescriptors()[0].GetSourceCodeInfo())
+	require.Empty(t, cmp.Diff(original, request, protocmp.Transform()), "input must not be modified")
+}
+
+func testNewFileDescriptorProtoWithSourceCodeInfo() *descriptorpb.FileDescriptorProto {
+	return &descriptorpb.FileDescriptorProto{
+		Name:        proto.String("a.proto"),
+		Syntax:      proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{Name: proto.String("A")}},
+		SourceCodeInfo: &descriptorpb.SourceCodeInfo{
+			Location: []*descriptorpb.SourceCodeInfo_Location{
+				{
+					Path: []int32{},
+					Span: []int32{0, 0, 3, 1},
+				},
+				{
+					Path:                    []int32{4, 0},
+					Span:                    []int32{2, 0, 3, 1},
+					LeadingComments:         proto.String(" A is a message.\n"),
+					TrailingComments:        proto.String(" Trailing.\n"),
+					LeadingDetachedComments: []string{" Detached.\n"},
+				},
+				{
+					Path: []int32{4, 0, 1},
+					Span: []int32{2, 8, 9},
+				},
+			},
+		},
+	}
+}
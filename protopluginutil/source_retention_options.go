@@ -28,6 +28,7 @@ const (
 	fileServicesTag           = 6
 	fileExtensionsTag         = 7
 	fileOptionsTag            = 8
+	fileSourceCodeInfoTag     = 9
 	messageFieldsTag          = 2
 	messageNestedMessagesTag  = 3
 	messageEnumsTag           = 4
@@ -47,7 +48,7 @@ const (
 )
 
 // StripSourceRetentionOptions returns a FileDescriptorProto that omits any source-retention options.
-
+//
 // If the FileDescriptorProto has no source-retention options, the original FileDescriptorProto is returned.
 // If the FileDescriptorProto has source-retention options, a new FileDescriptorProto is returned with
 // the source-retention options stripped.
@@ -56,6 +57,55 @@ const (
 // input FileDescriptorProto, and mutations to the returned FileDescriptorProto may impact
 // the input FileDescriptorProto.
 func StripSourceRetentionOptions(file *descriptorpb.FileDescriptorProto) (*descriptorpb.FileDescriptorProto, error) {
+	return filterOptions(file, keepNonSourceRetentionOption)
+}
+
+// FilterOptions returns a FileDescriptorProto that omits any options for which keep returns false.
+//
+// The keep function is called for every option set on the file, and on every message, field, oneof,
+// extension range, enum, enum value, service, and method within the file. This includes both standard
+// options (such as java_package) and custom options, that is, extensions of the options messages.
+// Custom options are only passed to keep if they are recognized, that is, if they were resolved
+// when the options were unmarshaled, as opposed to being retained as unknown fields. Use
+// FieldDescriptor.IsExtension to distinguish custom options from standard options.
+//
+// This is useful for registries and proxies that need to remove organization-internal custom options,
+// for example by full name or by extension number range, before handing descriptors to third-party plugins.
+//
+// Source code locations for removed options are removed as well.
+//
+// If no options are removed, the original FileDescriptorProto is returned. Otherwise, a new
+// FileDescriptorProto is returned with the options removed.
+//
+// Even when a copy is returned, it is not a deep copy: it may share data with the
+// input FileDescriptorProto, and mutations to the returned FileDescriptorProto may impact
+// the input FileDescriptorProto.
+func FilterOptions(
+	file *descriptorpb.FileDescriptorProto,
+	keep func(protoreflect.FieldDescriptor) bool,
+) (*descriptorpb.FileDescriptorProto, error) {
+	return filterOptions(
+		file,
+		func(fieldDescriptor protoreflect.FieldDescriptor) (bool, error) {
+			return keep(fieldDescriptor), nil
+		},
+	)
+}
+
+// *** PRIVATE ***
+
+// keepOptionFunc says whether or not an option should be kept.
+type keepOptionFunc func(protoreflect.FieldDescriptor) (bool, error)
+
+func keepNonSourceRetentionOption(field protoreflect.FieldDescriptor) (bool, error) {
+	fieldOpts, ok := field.Options().(*descriptorpb.FieldOptions)
+	if !ok {
+		return false, fmt.Errorf("field options is unexpected type: got %T, want %T", field.Options(), fieldOpts)
+	}
+	return fieldOpts.GetRetention() != descriptorpb.FieldOptions_RETENTION_SOURCE, nil
+}
+
+func filterOptions(file *descriptorpb.FileDescriptorProto, keep keepOptionFunc) (*descriptorpb.FileDescriptorProto, error) {
 	var path sourcePath
 	var removedPaths *sourcePathTrie
 	if file.GetSourceCodeInfo() != nil && len(file.GetSourceCodeInfo().GetLocation()) > 0 {
@@ -64,7 +114,7 @@ func StripSourceRetentionOptions(file *descriptorpb.FileDescriptorProto) (*descr
 	}
 	var dirty bool
 	optionsPath := path.push(fileOptionsTag)
-	newOpts, err := stripSourceRetentionOptionsFromProtoMessage(file.GetOptions(), optionsPath, removedPaths)
+	newOpts, err := filterOptionsFromProtoMessage(file.GetOptions(), keep, optionsPath, removedPaths)
 	if err != nil {
 		return nil, err
 	}
@@ -72,7 +122,7 @@ func StripSourceRetentionOptions(file *descriptorpb.FileDescriptorProto) (*descr
 		dirty = true
 	}
 	msgsPath := path.push(fileMessagesTag)
-	newMsgs, changed, err := stripOptionsFromAll(file.GetMessageType(), stripSourceRetentionOptionsFromMessage, msgsPath, removedPaths)
+	newMsgs, changed, err := stripOptionsFromAll(file.GetMessageType(), filterOptionsFromMessage, keep, msgsPath, removedPaths)
 	if err != nil {
 		return nil, err
 	}
@@ -80,7 +130,7 @@ func StripSourceRetentionOptions(file *descriptorpb.FileDescriptorProto) (*descr
 		dirty = true
 	}
 	enumsPath := path.push(fileEnumsTag)
-	newEnums, changed, err := stripOptionsFromAll(file.GetEnumType(), stripSourceRetentionOptionsFromEnum, enumsPath, removedPaths)
+	newEnums, changed, err := stripOptionsFromAll(file.GetEnumType(), filterOptionsFromEnum, keep, enumsPath, removedPaths)
 	if err != nil {
 		return nil, err
 	}
@@ -88,7 +138,7 @@ func StripSourceRetentionOptions(file *descriptorpb.FileDescriptorProto) (*descr
 		dirty = true
 	}
 	extsPath := path.push(fileExtensionsTag)
-	newExts, changed, err := stripOptionsFromAll(file.GetExtension(), stripSourceRetentionOptionsFromField, extsPath, removedPaths)
+	newExts, changed, err := stripOptionsFromAll(file.GetExtension(), filterOptionsFromField, keep, extsPath, removedPaths)
 	if err != nil {
 		return nil, err
 	}
@@ -96,7 +146,7 @@ func StripSourceRetentionOptions(file *descriptorpb.FileDescriptorProto) (*descr
 		dirty = true
 	}
 	svcsPath := path.push(fileServicesTag)
-	newSvcs, changed, err := stripOptionsFromAll(file.GetService(), stripSourceRetentionOptionsFromService, svcsPath, removedPaths)
+	newSvcs, changed, err := stripOptionsFromAll(file.GetService(), filterOptionsFromService, keep, svcsPath, removedPaths)
 	if err != nil {
 		return nil, err
 	}
@@ -117,30 +167,34 @@ func StripSourceRetentionOptions(file *descriptorpb.FileDescriptorProto) (*descr
 	newFile.EnumType = newEnums
 	newFile.Extension = newExts
 	newFile.Service = newSvcs
-	newFile.SourceCodeInfo = stripSourcePathsForSourceRetentionOptions(newFile.GetSourceCodeInfo(), removedPaths)
+	newFile.SourceCodeInfo = stripRemovedSourcePaths(newFile.GetSourceCodeInfo(), removedPaths)
 	return newFile, nil
 }
 
-func stripSourceRetentionOptionsFromProtoMessage[M proto.Message](
+func filterOptionsFromProtoMessage[M proto.Message](
 	options M,
+	keep keepOptionFunc,
 	path sourcePath,
 	removedPaths *sourcePathTrie,
 ) (M, error) {
 	optionsRef := options.ProtoReflect()
 	// See if there are any options to strip.
-	var hasFieldToStrip bool
+	var fieldNumbersToStrip map[protoreflect.FieldNumber]struct{}
 	var numFieldsToKeep int
 	var err error
 	optionsRef.Range(func(field protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
-		fieldOpts, ok := field.Options().(*descriptorpb.FieldOptions)
-		if !ok {
-			err = fmt.Errorf("field options is unexpected type: got %T, want %T", field.Options(), fieldOpts)
+		var shouldKeep bool
+		shouldKeep, err = keep(field)
+		if err != nil {
 			return false
 		}
-		if fieldOpts.GetRetention() == descriptorpb.FieldOptions_RETENTION_SOURCE {
-			hasFieldToStrip = true
-		} else {
+		if shouldKeep {
 			numFieldsToKeep++
+		} else {
+			if fieldNumbersToStrip == nil {
+				fieldNumbersToStrip = make(map[protoreflect.FieldNumber]struct{})
+			}
+			fieldNumbersToStrip[field.Number()] = struct{}{}
 		}
 		return true
 	})
@@ -148,7 +202,7 @@ func stripSourceRetentionOptionsFromProtoMessage[M proto.Message](
 	if err != nil {
 		return zero, err
 	}
-	if !hasFieldToStrip {
+	if len(fieldNumbersToStrip) == 0 {
 		return options, nil
 	}
 
@@ -166,32 +220,25 @@ func stripSourceRetentionOptionsFromProtoMessage[M proto.Message](
 		return zero, fmt.Errorf("creating new message of same type resulted in unexpected type; got %T, want %T", newOptions.Interface(), zero)
 	}
 	optionsRef.Range(func(field protoreflect.FieldDescriptor, val protoreflect.Value) bool {
-		fieldOpts, ok := field.Options().(*descriptorpb.FieldOptions)
-		if !ok {
-			err = fmt.Errorf("field options is unexpected type: got %T, want %T", field.Options(), fieldOpts)
-			return false
-		}
-		if fieldOpts.GetRetention() != descriptorpb.FieldOptions_RETENTION_SOURCE {
-			newOptions.Set(field, val)
-		} else {
+		if _, ok := fieldNumbersToStrip[field.Number()]; ok {
 			removedPaths.addPath(path.push(int32(field.Number())))
+		} else {
+			newOptions.Set(field, val)
 		}
 		return true
 	})
-	if err != nil {
-		return zero, err
-	}
 	return ret, nil
 }
 
-func stripSourceRetentionOptionsFromMessage(
+func filterOptionsFromMessage(
 	msg *descriptorpb.DescriptorProto,
+	keep keepOptionFunc,
 	path sourcePath,
 	removedPaths *sourcePathTrie,
 ) (*descriptorpb.DescriptorProto, error) {
 	var dirty bool
 	optionsPath := path.push(messageOptionsTag)
-	newOpts, err := stripSourceRetentionOptionsFromProtoMessage(msg.GetOptions(), optionsPath, removedPaths)
+	newOpts, err := filterOptionsFromProtoMessage(msg.GetOptions(), keep, optionsPath, removedPaths)
 	if err != nil {
 		return nil, err
 	}
@@ -199,7 +246,7 @@ func stripSourceRetentionOptionsFromMessage(
 		dirty = true
 	}
 	fieldsPath := path.push(messageFieldsTag)
-	newFields, changed, err := stripOptionsFromAll(msg.GetField(), stripSourceRetentionOptionsFromField, fieldsPath, removedPaths)
+	newFields, changed, err := stripOptionsFromAll(msg.GetField(), filterOptionsFromField, keep, fieldsPath, removedPaths)
 	if err != nil {
 		return nil, err
 	}
@@ -207,7 +254,7 @@ func stripSourceRetentionOptionsFromMessage(
 		dirty = true
 	}
 	oneofsPath := path.push(messageOneofsTag)
-	newOneofs, changed, err := stripOptionsFromAll(msg.GetOneofDecl(), stripSourceRetentionOptionsFromOneof, oneofsPath, removedPaths)
+	newOneofs, changed, err := stripOptionsFromAll(msg.GetOneofDecl(), filterOptionsFromOneof, keep, oneofsPath, removedPaths)
 	if err != nil {
 		return nil, err
 	}
@@ -215,7 +262,7 @@ func stripSourceRetentionOptionsFromMessage(
 		dirty = true
 	}
 	extRangesPath := path.push(messageExtensionRangesTag)
-	newExtRanges, changed, err := stripOptionsFromAll(msg.GetExtensionRange(), stripSourceRetentionOptionsFromExtensionRange, extRangesPath, removedPaths)
+	newExtRanges, changed, err := stripOptionsFromAll(msg.GetExtensionRange(), filterOptionsFromExtensionRange, keep, extRangesPath, removedPaths)
 	if err != nil {
 		return nil, err
 	}
@@ -223,7 +270,7 @@ func stripSourceRetentionOptionsFromMessage(
 		dirty = true
 	}
 	msgsPath := path.push(messageNestedMessagesTag)
-	newMsgs, changed, err := stripOptionsFromAll(msg.GetNestedType(), stripSourceRetentionOptionsFromMessage, msgsPath, removedPaths)
+	newMsgs, changed, err := stripOptionsFromAll(msg.GetNestedType(), filterOptionsFromMessage, keep, msgsPath, removedPaths)
 	if err != nil {
 		return nil, err
 	}
@@ -231,7 +278,7 @@ func stripSourceRetentionOptionsFromMessage(
 		dirty = true
 	}
 	enumsPath := path.push(messageEnumsTag)
-	newEnums, changed, err := stripOptionsFromAll(msg.GetEnumType(), stripSourceRetentionOptionsFromEnum, enumsPath, removedPaths)
+	newEnums, changed, err := stripOptionsFromAll(msg.GetEnumType(), filterOptionsFromEnum, keep, enumsPath, removedPaths)
 	if err != nil {
 		return nil, err
 	}
@@ -239,7 +286,7 @@ func stripSourceRetentionOptionsFromMessage(
 		dirty = true
 	}
 	extsPath := path.push(messageExtensionsTag)
-	newExts, changed, err := stripOptionsFromAll(msg.GetExtension(), stripSourceRetentionOptionsFromField, extsPath, removedPaths)
+	newExts, changed, err := stripOptionsFromAll(msg.GetExtension(), filterOptionsFromField, keep, extsPath, removedPaths)
 	if err != nil {
 		return nil, err
 	}
@@ -265,13 +312,14 @@ func stripSourceRetentionOptionsFromMessage(
 	return newMsg, nil
 }
 
-func stripSourceRetentionOptionsFromField(
+func filterOptionsFromField(
 	field *descriptorpb.FieldDescriptorProto,
+	keep keepOptionFunc,
 	path sourcePath,
 	removedPaths *sourcePathTrie,
 ) (*descriptorpb.FieldDescriptorProto, error) {
 	optionsPath := path.push(fieldOptionsTag)
-	newOpts, err := stripSourceRetentionOptionsFromProtoMessage(field.GetOptions(), optionsPath, removedPaths)
+	newOpts, err := filterOptionsFromProtoMessage(field.GetOptions(), keep, optionsPath, removedPaths)
 	if err != nil {
 		return nil, err
 	}
@@ -286,13 +334,14 @@ func stripSourceRetentionOptionsFromField(
 	return newField, nil
 }
 
-func stripSourceRetentionOptionsFromOneof(
+func filterOptionsFromOneof(
 	oneof *descriptorpb.OneofDescriptorProto,
+	keep keepOptionFunc,
 	path sourcePath,
 	removedPaths *sourcePathTrie,
 ) (*descriptorpb.OneofDescriptorProto, error) {
 	optionsPath := path.push(oneofOptionsTag)
-	newOpts, err := stripSourceRetentionOptionsFromProtoMessage(oneof.GetOptions(), optionsPath, removedPaths)
+	newOpts, err := filterOptionsFromProtoMessage(oneof.GetOptions(), keep, optionsPath, removedPaths)
 	if err != nil {
 		return nil, err
 	}
@@ -307,13 +356,14 @@ func stripSourceRetentionOptionsFromOneof(
 	return newOneof, nil
 }
 
-func stripSourceRetentionOptionsFromExtensionRange(
+func filterOptionsFromExtensionRange(
 	extRange *descriptorpb.DescriptorProto_ExtensionRange,
+	keep keepOptionFunc,
 	path sourcePath,
 	removedPaths *sourcePathTrie,
 ) (*descriptorpb.DescriptorProto_ExtensionRange, error) {
 	optionsPath := path.push(extensionRangeOptionsTag)
-	newOpts, err := stripSourceRetentionOptionsFromProtoMessage(extRange.GetOptions(), optionsPath, removedPaths)
+	newOpts, err := filterOptionsFromProtoMessage(extRange.GetOptions(), keep, optionsPath, removedPaths)
 	if err != nil {
 		return nil, err
 	}
@@ -328,14 +378,15 @@ func stripSourceRetentionOptionsFromExtensionRange(
 	return newExtRange, nil
 }
 
-func stripSourceRetentionOptionsFromEnum(
+func filterOptionsFromEnum(
 	enum *descriptorpb.EnumDescriptorProto,
+	keep keepOptionFunc,
 	path sourcePath,
 	removedPaths *sourcePathTrie,
 ) (*descriptorpb.EnumDescriptorProto, error) {
 	var dirty bool
 	optionsPath := path.push(enumOptionsTag)
-	newOpts, err := stripSourceRetentionOptionsFromProtoMessage(enum.GetOptions(), optionsPath, removedPaths)
+	newOpts, err := filterOptionsFromProtoMessage(enum.GetOptions(), keep, optionsPath, removedPaths)
 	if err != nil {
 		return nil, err
 	}
@@ -343,7 +394,7 @@ func stripSourceRetentionOptionsFromEnum(
 		dirty = true
 	}
 	valsPath := path.push(enumValuesTag)
-	newVals, changed, err := stripOptionsFromAll(enum.GetValue(), stripSourceRetentionOptionsFromEnumValue, valsPath, removedPaths)
+	newVals, changed, err := stripOptionsFromAll(enum.GetValue(), filterOptionsFromEnumValue, keep, valsPath, removedPaths)
 	if err != nil {
 		return nil, err
 	}
@@ -364,13 +415,14 @@ func stripSourceRetentionOptionsFromEnum(
 	return newEnum, nil
 }
 
-func stripSourceRetentionOptionsFromEnumValue(
+func filterOptionsFromEnumValue(
 	enumVal *descriptorpb.EnumValueDescriptorProto,
+	keep keepOptionFunc,
 	path sourcePath,
 	removedPaths *sourcePathTrie,
 ) (*descriptorpb.EnumValueDescriptorProto, error) {
 	optionsPath := path.push(enumValOptionsTag)
-	newOpts, err := stripSourceRetentionOptionsFromProtoMessage(enumVal.GetOptions(), optionsPath, removedPaths)
+	newOpts, err := filterOptionsFromProtoMessage(enumVal.GetOptions(), keep, optionsPath, removedPaths)
 	if err != nil {
 		return nil, err
 	}
@@ -385,14 +437,15 @@ func stripSourceRetentionOptionsFromEnumValue(
 	return newEnumVal, nil
 }
 
-func stripSourceRetentionOptionsFromService(
+func filterOptionsFromService(
 	svc *descriptorpb.ServiceDescriptorProto,
+	keep keepOptionFunc,
 	path sourcePath,
 	removedPaths *sourcePathTrie,
 ) (*descriptorpb.ServiceDescriptorProto, error) {
 	var dirty bool
 	optionsPath := path.push(serviceOptionsTag)
-	newOpts, err := stripSourceRetentionOptionsFromProtoMessage(svc.GetOptions(), optionsPath, removedPaths)
+	newOpts, err := filterOptionsFromProtoMessage(svc.GetOptions(), keep, optionsPath, removedPaths)
 	if err != nil {
 		return nil, err
 	}
@@ -400,7 +453,7 @@ func stripSourceRetentionOptionsFromService(
 		dirty = true
 	}
 	methodsPath := path.push(serviceMethodsTag)
-	newMethods, changed, err := stripOptionsFromAll(svc.GetMethod(), stripSourceRetentionOptionsFromMethod, methodsPath, removedPaths)
+	newMethods, changed, err := stripOptionsFromAll(svc.GetMethod(), filterOptionsFromMethod, keep, methodsPath, removedPaths)
 	if err != nil {
 		return nil, err
 	}
@@ -421,13 +474,14 @@ func stripSourceRetentionOptionsFromService(
 	return newSvc, nil
 }
 
-func stripSourceRetentionOptionsFromMethod(
+func filterOptionsFromMethod(
 	method *descriptorpb.MethodDescriptorProto,
+	keep keepOptionFunc,
 	path sourcePath,
 	removedPaths *sourcePathTrie,
 ) (*descriptorpb.MethodDescriptorProto, error) {
 	optionsPath := path.push(methodOptionsTag)
-	newOpts, err := stripSourceRetentionOptionsFromProtoMessage(method.GetOptions(), optionsPath, removedPaths)
+	newOpts, err := filterOptionsFromProtoMessage(method.GetOptions(), keep, optionsPath, removedPaths)
 	if err != nil {
 		return nil, err
 	}
@@ -442,7 +496,7 @@ func stripSourceRetentionOptionsFromMethod(
 	return newMethod, nil
 }
 
-func stripSourcePathsForSourceRetentionOptions(
+func stripRemovedSourcePaths(
 	sourceInfo *descriptorpb.SourceCodeInfo,
 	removedPaths *sourcePathTrie,
 ) *descriptorpb.SourceCodeInfo {
@@ -478,7 +532,7 @@ func shallowCopy[M proto.Message](msg M) (M, error) {
 }
 
 // stripOptionsFromAll applies the given function to each element in the given
-// slice in order to remove source-retention options from it. It returns the new
+// slice in order to remove options that should not be kept from it. It returns the new
 // slice and a bool indicating whether anything was actually changed. If the
 // second value is false, then the returned slice is the same slice as the input
 // slice. Usually, T is a pointer type, in which case the given updateFunc should
@@ -487,14 +541,15 @@ func shallowCopy[M proto.Message](msg M) (M, error) {
 // value.
 func stripOptionsFromAll[T comparable](
 	slice []T,
-	updateFunc func(T, sourcePath, *sourcePathTrie) (T, error),
+	updateFunc func(T, keepOptionFunc, sourcePath, *sourcePathTrie) (T, error),
+	keep keepOptionFunc,
 	path sourcePath,
 	removedPaths *sourcePathTrie,
 ) ([]T, bool, error) {
 	var updated []T // initialized lazily, only when/if a copy is needed
 	for i, item := range slice {
 		index := int32(i) // #nosec:G115 should never overflow
-		newItem, err := updateFunc(item, path.push(index), removedPaths)
+		newItem, err := updateFunc(item, keep, path.push(index), removedPaths)
 		if err != nil {
 			return nil, false, err
 		}
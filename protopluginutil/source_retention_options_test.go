@@ -18,6 +18,7 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/bufbuild/protoplugin/protoplugintest"
 	"github.com/google/go-cmp/cmp"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/protobuf/proto"
@@ -585,7 +586,7 @@ func TestStripSourceRetentionOptionsFromProtoMessage(t *testing.T) {
 	listVal.Append(protoreflect.ValueOfInt32(-456))
 	options.Set(extSourceRetention.TypeDescriptor(), protoreflect.ValueOfList(listVal))
 
-	actualOptionsAfterStrip, err := stripSourceRetentionOptionsFromProtoMessage(optionsMsg, nil, nil)
+	actualOptionsAfterStrip, err := filterOptionsFromProtoMessage(optionsMsg, keepNonSourceRetentionOption, nil, nil)
 	require.NoError(t, err)
 
 	require.NotSame(t, actualOptionsAfterStrip, optionsMsg)
@@ -594,7 +595,7 @@ func TestStripSourceRetentionOptionsFromProtoMessage(t *testing.T) {
 	// If we do it again, there are no changes to made (since source-only options were
 	// already stripped). So we should get back unmodified value.
 	optionsMsg = actualOptionsAfterStrip
-	actualOptionsAfterStrip, err = stripSourceRetentionOptionsFromProtoMessage(optionsMsg, nil, nil)
+	actualOptionsAfterStrip, err = filterOptionsFromProtoMessage(optionsMsg, keepNonSourceRetentionOption, nil, nil)
 	require.NoError(t, err)
 
 	require.Same(t, actualOptionsAfterStrip, optionsMsg)
@@ -607,17 +608,133 @@ func TestStripSourceRetentionOptionsFromProtoMessage(t *testing.T) {
 	options = optionsMsg.ProtoReflect() // weird that we have to call this again (bug in protobuf-go?)
 	options.Set(extSourceRetention.TypeDescriptor(), protoreflect.ValueOfList(listVal))
 
-	actualOptionsAfterStrip, err = stripSourceRetentionOptionsFromProtoMessage(optionsMsg, nil, nil)
+	actualOptionsAfterStrip, err = filterOptionsFromProtoMessage(optionsMsg, keepNonSourceRetentionOption, nil, nil)
 	require.NoError(t, err)
 
 	require.Same(t, (*descriptorpb.FileOptions)(nil), actualOptionsAfterStrip)
 }
 
+func TestFilterOptions(t *testing.T) {
+	t.Parallel()
+	optsFileProto := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("options.proto"),
+		Package:    proto.String("acme.internal"),
+		Dependency: []string{"google/protobuf/descriptor.proto"},
+		Extension: []*descriptorpb.FieldDescriptorProto{
+			{
+				Extendee: proto.String(".google.protobuf.FileOptions"),
+				Name:     proto.String("internal_owner"),
+				Number:   proto.Int32(50000),
+				Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+				Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+			},
+			{
+				Extendee: proto.String(".google.protobuf.MessageOptions"),
+				Name:     proto.String("public_tag"),
+				Number:   proto.Int32(60000),
+				Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+				Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+			},
+			{
+				Extendee: proto.String(".google.protobuf.FieldOptions"),
+				Name:     proto.String("internal_only"),
+				Number:   proto.Int32(50001),
+				Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+				Type:     descriptorpb.FieldDescriptorProto_TYPE_BOOL.Enum(),
+			},
+		},
+	}
+	optsFile, err := protodesc.NewFile(optsFileProto, protoregistry.GlobalFiles)
+	require.NoError(t, err)
+	extInternalOwner := dynamicpb.NewExtensionType(optsFile.Extensions().ByName("internal_owner"))
+	extPublicTag := dynamicpb.NewExtensionType(optsFile.Extensions().ByName("public_tag"))
+	extInternalOnly := dynamicpb.NewExtensionType(optsFile.Extensions().ByName("internal_only"))
+
+	fileOptions := &descriptorpb.FileOptions{GoPackage: proto.String("acme/foo")}
+	proto.SetExtension(fileOptions, extInternalOwner, "team-a")
+	messageOptions := &descriptorpb.MessageOptions{}
+	proto.SetExtension(messageOptions, extPublicTag, "tag")
+	fieldOptions := &descriptorpb.FieldOptions{}
+	proto.SetExtension(fieldOptions, extInternalOnly, true)
+	file := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("foo.proto"),
+		Options: fileOptions,
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name:    proto.String("Foo"),
+				Options: messageOptions,
+				Field: []*descriptorpb.FieldDescriptorProto{
+					{
+						Name:    proto.String("bar"),
+						Number:  proto.Int32(1),
+						Label:   descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						Type:    descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+						Options: fieldOptions,
+					},
+				},
+			},
+		},
+		SourceCodeInfo: &descriptorpb.SourceCodeInfo{
+			Location: []*descriptorpb.SourceCodeInfo_Location{
+				{Path: []int32{}, Span: []int32{0, 0, 10, 0}},
+				{Path: []int32{fileOptionsTag, 50000}, Span: []int32{1, 0, 30}},
+				{Path: []int32{fileOptionsTag, 11}, Span: []int32{2, 0, 30}},
+				{Path: []int32{fileMessagesTag, 0, messageFieldsTag, 0, fieldOptionsTag}, Span: []int32{4, 20, 50}},
+				{Path: []int32{fileMessagesTag, 0, messageFieldsTag, 0, fieldOptionsTag, 50001}, Span: []int32{4, 21, 49}},
+			},
+		},
+	}
+	original := proto.Clone(file)
+
+	// Remove custom options in the organization-internal extension number range.
+	keep := func(fieldDescriptor protoreflect.FieldDescriptor) bool {
+		return !fieldDescriptor.IsExtension() || fieldDescriptor.Number() < 50000 || fieldDescriptor.Number() >= 60000
+	}
+	filteredFile, err := FilterOptions(file, keep)
+	require.NoError(t, err)
+	require.NotSame(t, file, filteredFile)
+	require.Empty(t, cmp.Diff(original, file, protocmp.Transform()), "input must not be modified")
+
+	require.Empty(t, cmp.Diff(&descriptorpb.FileOptions{GoPackage: proto.String("acme/foo")}, filteredFile.GetOptions(), protocmp.Transform()))
+	require.Same(t, messageOptions, filteredFile.GetMessageType()[0].GetOptions())
+	// All options of the field were removed, so the options are cleared.
+	require.Nil(t, filteredFile.GetMessageType()[0].GetField()[0].GetOptions())
+	require.Empty(
+		t,
+		cmp.Diff(
+			&descriptorpb.SourceCodeInfo{
+				Location: []*descriptorpb.SourceCodeInfo_Location{
+					{Path: []int32{}, Span: []int32{0, 0, 10, 0}},
+					{Path: []int32{fileOptionsTag, 11}, Span: []int32{2, 0, 30}},
+				},
+			},
+			filteredFile.GetSourceCodeInfo(),
+			protocmp.Transform(),
+		),
+	)
+
+	// Remove a standard option by full name.
+	filteredFile, err = FilterOptions(
+		file,
+		func(fieldDescriptor protoreflect.FieldDescriptor) bool {
+			return fieldDescriptor.FullName() != "google.protobuf.FileOptions.go_package"
+		},
+	)
+	require.NoError(t, err)
+	require.True(t, proto.HasExtension(filteredFile.GetOptions(), extInternalOwner))
+	require.Empty(t, filteredFile.GetOptions().GetGoPackage())
+
+	// Nothing to remove.
+	filteredFile, err = FilterOptions(file, func(protoreflect.FieldDescriptor) bool { return true })
+	require.NoError(t, err)
+	require.Same(t, file, filteredFile)
+}
+
 func TestStripOptionsFromAll(t *testing.T) {
 	t.Parallel()
 
 	errInvalid := errors.New("invalid value")
-	updateFunc := func(value *int32, _ sourcePath, _ *sourcePathTrie) (*int32, error) {
+	updateFunc := func(value *int32, _ keepOptionFunc, _ sourcePath, _ *sourcePathTrie) (*int32, error) {
 		if value == nil {
 			return proto.Int32(-1), nil
 		}
@@ -635,7 +752,7 @@ func TestStripOptionsFromAll(t *testing.T) {
 		proto.Int32(3), proto.Int32(4), proto.Int32(5),
 		proto.Int32(6), proto.Int32(7), proto.Int32(8),
 	}
-	newVals, changed, err := stripOptionsFromAll(vals, updateFunc, nil, nil)
+	newVals, changed, err := stripOptionsFromAll(vals, updateFunc, nil, nil, nil)
 	require.NoError(t, err)
 	require.True(t, changed)
 	expected := []*int32{
@@ -649,7 +766,7 @@ func TestStripOptionsFromAll(t *testing.T) {
 		nil, proto.Int32(1), proto.Int32(2),
 		proto.Int32(3), proto.Int32(4), proto.Int32(5),
 	}
-	newVals, changed, err = stripOptionsFromAll(vals, updateFunc, nil, nil)
+	newVals, changed, err = stripOptionsFromAll(vals, updateFunc, nil, nil, nil)
 	require.NoError(t, err)
 	require.True(t, changed)
 	expected = []*int32{
@@ -663,7 +780,7 @@ func TestStripOptionsFromAll(t *testing.T) {
 		proto.Int32(0), proto.Int32(1), proto.Int32(2),
 		proto.Int32(3), proto.Int32(4), proto.Int32(5),
 	}
-	newVals, changed, err = stripOptionsFromAll(vals, updateFunc, nil, nil)
+	newVals, changed, err = stripOptionsFromAll(vals, updateFunc, nil, nil, nil)
 	require.NoError(t, err)
 	require.False(t, changed)
 	require.Equal(t, vals, newVals)
@@ -673,10 +790,21 @@ func TestStripOptionsFromAll(t *testing.T) {
 		proto.Int32(0), proto.Int32(1), proto.Int32(2),
 		proto.Int32(3), proto.Int32(-101), proto.Int32(5),
 	}
-	_, _, err = stripOptionsFromAll(vals, updateFunc, nil, nil)
+	_, _, err = stripOptionsFromAll(vals, updateFunc, nil, nil, nil)
 	require.ErrorIs(t, err, errInvalid)
 }
 
+func BenchmarkStripSourceRetentionOptions(b *testing.B) {
+	fileDescriptorProtos := protoplugintest.NewSyntheticCodeGeneratorRequest(100, 100, 100).GetProtoFile()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, fileDescriptorProto := range fileDescriptorProtos {
+			_, err := StripSourceRetentionOptions(fileDescriptorProto)
+			require.NoError(b, err)
+		}
+	}
+}
+
 func testCombineAll[T any](slices ...[]T) []T {
 	result := slices[0]
 	for _, exts := range slices[1:] {
@@ -0,0 +1,181 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protopluginutil
+
+import (
+	"sort"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// TypeReferences are the types transitively referenced by a message, as returned by MessageTypeReferences.
+type TypeReferences struct {
+	// Messages are the referenced messages.
+	//
+	// The root message is always first. Synthetic map entry messages are not included, however the
+	// types of their values are.
+	Messages []protoreflect.MessageDescriptor
+	// Enums are the referenced enums.
+	Enums []protoreflect.EnumDescriptor
+	// Extensions are the extensions of the referenced messages.
+	//
+	// This is only populated if MessageTypeReferencesWithFiles is used.
+	Extensions []protoreflect.ExtensionDescriptor
+}
+
+// MessageTypeReferencesOption is an option for MessageTypeReferences.
+type MessageTypeReferencesOption func(*messageTypeReferencesOptions)
+
+// MessageTypeReferencesWithFiles returns a new MessageTypeReferencesOption that restricts the referenced types
+// to those within the given Files, and includes the extensions declared within the given Files.
+//
+// Types declared in files not within the given Files are not included, and the types they reference are not
+// traversed. For each referenced message, the extensions of the message declared within the given Files are
+// included, and the types of the extensions are traversed. The Files are typically the result of
+// Request.AllFiles.
+//
+// The default is to include all referenced types regardless of their files, and to not include extensions,
+// as extensions cannot be discovered from a message alone.
+func MessageTypeReferencesWithFiles(files *protoregistry.Files) MessageTypeReferencesOption {
+	return func(messageTypeReferencesOptions *messageTypeReferencesOptions) {
+		messageTypeReferencesOptions.files = files
+	}
+}
+
+// MessageTypeReferences returns the transitive closure of the types referenced by the message.
+//
+// Message and enum types of fields are traversed, including the value types of map fields, and the fields of
+// extensions if MessageTypeReferencesWithFiles is used. Each type is returned once, even if it is referenced
+// multiple times or referenced cyclically. Types are returned in the order they are discovered by a
+// breadth-first traversal from the message, with fields traversed in declaration order, and extensions of a
+// message traversed in order of field number after its fields, so the result is deterministic.
+//
+// This is useful for plugins that generate self-contained schemas per root message, such as Avro, JSON Schema,
+// or BigQuery schemas, which must include definitions for every referenced type. This does not depend on any
+// language-specific generated code.
+func MessageTypeReferences(
+	message protoreflect.MessageDescriptor,
+	options ...MessageTypeReferencesOption,
+) *TypeReferences {
+	messageTypeReferencesOptions := newMessageTypeReferencesOptions()
+	for _, option := range options {
+		option(messageTypeReferencesOptions)
+	}
+	builder := &typeReferencesBuilder{
+		files:          messageTypeReferencesOptions.files,
+		typeReferences: &TypeReferences{},
+		seen:           make(map[protoreflect.FullName]struct{}),
+	}
+	if builder.files != nil {
+		builder.extendeeToExtensions = extendeeToExtensions(builder.files)
+	}
+	builder.seen[message.FullName()] = struct{}{}
+	builder.typeReferences.Messages = append(builder.typeReferences.Messages, message)
+	builder.queue = append(builder.queue, message)
+	for len(builder.queue) > 0 {
+		messageDescriptor := builder.queue[0]
+		builder.queue = builder.queue[1:]
+		fields := messageDescriptor.Fields()
+		for i := 0; i < fields.Len(); i++ {
+			builder.addFieldTypes(fields.Get(i))
+		}
+		for _, extension := range builder.extendeeToExtensions[messageDescriptor.FullName()] {
+			if !builder.markSeen(extension) {
+				continue
+			}
+			builder.typeReferences.Extensions = append(builder.typeReferences.Extensions, extension)
+			builder.addFieldTypes(extension)
+		}
+	}
+	return builder.typeReferences
+}
+
+// *** PRIVATE ***
+
+type messageTypeReferencesOptions struct {
+	files *protoregistry.Files
+}
+
+func newMessageTypeReferencesOptions() *messageTypeReferencesOptions {
+	return &messageTypeReferencesOptions{}
+}
+
+type typeReferencesBuilder struct {
+	// Nil if types are not restricted.
+	files                *protoregistry.Files
+	extendeeToExtensions map[protoreflect.FullName][]protoreflect.ExtensionDescriptor
+	typeReferences       *TypeReferences
+	seen                 map[protoreflect.FullName]struct{}
+	queue                []protoreflect.MessageDescriptor
+}
+
+func (b *typeReferencesBuilder) addFieldTypes(field protoreflect.FieldDescriptor) {
+	if message := field.Message(); message != nil {
+		if message.IsMapEntry() {
+			// The key of a map can only be a scalar, so only the value can reference types.
+			b.addFieldTypes(field.MapValue())
+			return
+		}
+		if b.markSeen(message) {
+			b.typeReferences.Messages = append(b.typeReferences.Messages, message)
+			b.queue = append(b.queue, message)
+		}
+		return
+	}
+	if enum := field.Enum(); enum != nil && b.markSeen(enum) {
+		b.typeReferences.Enums = append(b.typeReferences.Enums, enum)
+	}
+}
+
+// markSeen marks the descriptor as seen, returning false if it was already seen or should not be included.
+func (b *typeReferencesBuilder) markSeen(descriptor protoreflect.Descriptor) bool {
+	if _, ok := b.seen[descriptor.FullName()]; ok {
+		return false
+	}
+	if b.files != nil {
+		if _, err := b.files.FindFileByPath(descriptor.ParentFile().Path()); err != nil {
+			return false
+		}
+	}
+	b.seen[descriptor.FullName()] = struct{}{}
+	return true
+}
+
+// extendeeToExtensions returns a map from message name to the extensions of the message within the Files,
+// sorted by field number.
+func extendeeToExtensions(files *protoregistry.Files) map[protoreflect.FullName][]protoreflect.ExtensionDescriptor {
+	extendeeToExtensions := make(map[protoreflect.FullName][]protoreflect.ExtensionDescriptor)
+	// WalkFiles only returns errors from the callbacks.
+	_ = WalkFiles(
+		files,
+		DescriptorVisitor{
+			Extension: func(extension protoreflect.ExtensionDescriptor) error {
+				extendee := extension.ContainingMessage().FullName()
+				extendeeToExtensions[extendee] = append(extendeeToExtensions[extendee], extension)
+				return nil
+			},
+		},
+	)
+	for _, extensions := range extendeeToExtensions {
+		sort.Slice(
+			extensions,
+			func(i int, j int) bool {
+				return extensions[i].Number() < extensions[j].Number()
+			},
+		)
+	}
+	return extendeeToExtensions
+}
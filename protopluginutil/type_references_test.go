@@ -0,0 +1,124 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protopluginutil
+
+import (
+	"testing"
+
+	"github.com/bufbuild/protoplugin/internal/protocompiletest"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+func TestMessageTypeReferences(t *testing.T) {
+	t.Parallel()
+
+	files := protocompiletest.CompileFiles(
+		t,
+		map[string][]byte{
+			"a.proto": []byte(`
+				syntax = "proto2";
+				package foo;
+				import "b.proto";
+				message Root {
+					optional bar.B b = 1;
+					map<string, Value> values = 2;
+					optional Kind kind = 3;
+					optional Root self = 4;
+					repeated Value more_values = 5;
+					extensions 100 to 200;
+				}
+				message Value {
+					optional Nested nested = 1;
+					message Nested {
+						optional Root root = 1;
+					}
+				}
+				enum Kind {
+					KIND_UNSPECIFIED = 0;
+				}
+				message Unreferenced {}
+			`),
+			"b.proto": []byte(`
+				syntax = "proto2";
+				package bar;
+				message B {
+					optional C c = 1;
+				}
+				message C {}
+			`),
+			"c.proto": []byte(`
+				syntax = "proto2";
+				package baz;
+				import "a.proto";
+				extend foo.Root {
+					optional Ext second = 101;
+					optional string first = 100;
+				}
+				message Ext {
+					optional ExtKind kind = 1;
+				}
+				enum ExtKind {
+					EXT_KIND_UNSPECIFIED = 0;
+				}
+			`),
+		},
+	)
+	descriptor, err := files.FindDescriptorByName("foo.Root")
+	require.NoError(t, err)
+	root, ok := descriptor.(protoreflect.MessageDescriptor)
+	require.True(t, ok)
+
+	typeReferences := MessageTypeReferences(root)
+	require.Equal(
+		t,
+		[]protoreflect.FullName{"foo.Root", "bar.B", "foo.Value", "bar.C", "foo.Value.Nested"},
+		testFullNames(typeReferences.Messages),
+	)
+	require.Equal(t, []protoreflect.FullName{"foo.Kind"}, testFullNames(typeReferences.Enums))
+	require.Empty(t, typeReferences.Extensions)
+
+	typeReferences = MessageTypeReferences(root, MessageTypeReferencesWithFiles(files))
+	require.Equal(
+		t,
+		[]protoreflect.FullName{"foo.Root", "bar.B", "foo.Value", "baz.Ext", "bar.C", "foo.Value.Nested"},
+		testFullNames(typeReferences.Messages),
+	)
+	require.Equal(t, []protoreflect.FullName{"foo.Kind", "baz.ExtKind"}, testFullNames(typeReferences.Enums))
+	require.Equal(t, []protoreflect.FullName{"baz.first", "baz.second"}, testFullNames(typeReferences.Extensions))
+
+	// Types in files outside of the Files are not included or traversed.
+	restrictedFiles := &protoregistry.Files{}
+	for _, path := range []string{"a.proto", "c.proto"} {
+		fileDescriptor, err := files.FindFileByPath(path)
+		require.NoError(t, err)
+		require.NoError(t, restrictedFiles.RegisterFile(fileDescriptor))
+	}
+	typeReferences = MessageTypeReferences(root, MessageTypeReferencesWithFiles(restrictedFiles))
+	require.Equal(
+		t,
+		[]protoreflect.FullName{"foo.Root", "foo.Value", "baz.Ext", "foo.Value.Nested"},
+		testFullNames(typeReferences.Messages),
+	)
+}
+
+func testFullNames[T protoreflect.Descriptor](descriptors []T) []protoreflect.FullName {
+	fullNames := make([]protoreflect.FullName, len(descriptors))
+	for i, descriptor := range descriptors {
+		fullNames[i] = descriptor.FullName()
+	}
+	return fullNames
+}
@@ -0,0 +1,192 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protopluginutil
+
+import (
+	"errors"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// ErrSkipChildren is used as a return value from the EnterMessage, Enum, and Service callbacks
+// of a Visitor, and additionally the File callback of a DescriptorVisitor, to indicate that the
+// children of the descriptor should not be visited. In these cases, it is not returned as an error.
+//
+// ErrSkipChildren is not meaningful when returned from any other callback, such as Field or Method.
+// In this case, it is treated like any other error: the walk is stopped and ErrSkipChildren is returned as-is.
+var ErrSkipChildren = errors.New("skip children")
+
+// Visitor contains the callbacks invoked by Walk.
+//
+// Each callback is optional. If a callback is nil, the corresponding descriptors are
+// still traversed, but no function is invoked for them.
+//
+// Each callback is given the descriptor and its source path within the FileDescriptorProto, that is
+// the path that would be used to look up the descriptor's location within SourceCodeInfo. The source
+// path is only valid for the duration of the callback, and must be copied if retained.
+//
+// If a callback returns an error, the walk is stopped and the error is returned from Walk,
+// with the exception of ErrSkipChildren returned from EnterMessage, Enum, or Service.
+type Visitor struct {
+	// EnterMessage is called for each message before its fields, oneofs, nested messages,
+	// nested enums, and nested extensions are visited.
+	EnterMessage func(*descriptorpb.DescriptorProto, protoreflect.SourcePath) error
+	// ExitMessage is called for each message after its children are visited.
+	//
+	// This is not called if EnterMessage returned an error, including ErrSkipChildren.
+	ExitMessage func(*descriptorpb.DescriptorProto, protoreflect.SourcePath) error
+	// Field is called for each field of a message.
+	//
+	// This is not called for extensions, see Extension.
+	Field func(*descriptorpb.FieldDescriptorProto, protoreflect.SourcePath) error
+	// Oneof is called for each oneof of a message.
+	Oneof func(*descriptorpb.OneofDescriptorProto, protoreflect.SourcePath) error
+	// Enum is called for each enum, before its values are visited.
+	Enum func(*descriptorpb.EnumDescriptorProto, protoreflect.SourcePath) error
+	// EnumValue is called for each value of an enum.
+	EnumValue func(*descriptorpb.EnumValueDescriptorProto, protoreflect.SourcePath) error
+	// Service is called for each service, before its methods are visited.
+	Service func(*descriptorpb.ServiceDescriptorProto, protoreflect.SourcePath) error
+	// Method is called for each method of a service.
+	Method func(*descriptorpb.MethodDescriptorProto, protoreflect.SourcePath) error
+	// Extension is called for each extension, whether declared at the top level of the file
+	// or nested within a message.
+	Extension func(*descriptorpb.FieldDescriptorProto, protoreflect.SourcePath) error
+}
+
+// Walk traverses the FileDescriptorProto, invoking the callbacks on the Visitor.
+//
+// Descriptors are visited in the order they are declared within the FileDescriptorProto. Top-level
+// messages are visited first, then top-level enums, services, and extensions. Within a message, fields
+// are visited first, then oneofs, nested messages, nested enums, and nested extensions.
+func Walk(file *descriptorpb.FileDescriptorProto, visitor Visitor) error {
+	walker := &walker{
+		visitor: visitor,
+		path:    make(protoreflect.SourcePath, 0, 16),
+	}
+	return walker.walkFile(file)
+}
+
+// *** PRIVATE ***
+
+type walker struct {
+	visitor Visitor
+	path    protoreflect.SourcePath
+}
+
+func (w *walker) walkFile(file *descriptorpb.FileDescriptorProto) error {
+	for i, message := range file.GetMessageType() {
+		if err := w.withPath(fileMessagesTag, i, func() error { return w.walkMessage(message) }); err != nil {
+			return err
+		}
+	}
+	for i, enum := range file.GetEnumType() {
+		if err := w.withPath(fileEnumsTag, i, func() error { return w.walkEnum(enum) }); err != nil {
+			return err
+		}
+	}
+	for i, service := range file.GetService() {
+		if err := w.withPath(fileServicesTag, i, func() error { return w.walkService(service) }); err != nil {
+			return err
+		}
+	}
+	for i, extension := range file.GetExtension() {
+		if err := w.withPath(fileExtensionsTag, i, func() error { return visit(w.visitor.Extension, extension, w.path) }); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *walker) walkMessage(message *descriptorpb.DescriptorProto) error {
+	if err := visit(w.visitor.EnterMessage, message, w.path); err != nil {
+		if errors.Is(err, ErrSkipChildren) {
+			return nil
+		}
+		return err
+	}
+	for i, field := range message.GetField() {
+		if err := w.withPath(messageFieldsTag, i, func() error { return visit(w.visitor.Field, field, w.path) }); err != nil {
+			return err
+		}
+	}
+	for i, oneof := range message.GetOneofDecl() {
+		if err := w.withPath(messageOneofsTag, i, func() error { return visit(w.visitor.Oneof, oneof, w.path) }); err != nil {
+			return err
+		}
+	}
+	for i, nestedMessage := range message.GetNestedType() {
+		if err := w.withPath(messageNestedMessagesTag, i, func() error { return w.walkMessage(nestedMessage) }); err != nil {
+			return err
+		}
+	}
+	for i, enum := range message.GetEnumType() {
+		if err := w.withPath(messageEnumsTag, i, func() error { return w.walkEnum(enum) }); err != nil {
+			return err
+		}
+	}
+	for i, extension := range message.GetExtension() {
+		if err := w.withPath(messageExtensionsTag, i, func() error { return visit(w.visitor.Extension, extension, w.path) }); err != nil {
+			return err
+		}
+	}
+	return visit(w.visitor.ExitMessage, message, w.path)
+}
+
+func (w *walker) walkEnum(enum *descriptorpb.EnumDescriptorProto) error {
+	if err := visit(w.visitor.Enum, enum, w.path); err != nil {
+		if errors.Is(err, ErrSkipChildren) {
+			return nil
+		}
+		return err
+	}
+	for i, enumValue := range enum.GetValue() {
+		if err := w.withPath(enumValuesTag, i, func() error { return visit(w.visitor.EnumValue, enumValue, w.path) }); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *walker) walkService(service *descriptorpb.ServiceDescriptorProto) error {
+	if err := visit(w.visitor.Service, service, w.path); err != nil {
+		if errors.Is(err, ErrSkipChildren) {
+			return nil
+		}
+		return err
+	}
+	for i, method := range service.GetMethod() {
+		if err := w.withPath(serviceMethodsTag, i, func() error { return visit(w.visitor.Method, method, w.path) }); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// withPath pushes the tag and index onto the current path, calls f, and then pops the tag and index.
+func (w *walker) withPath(tag int32, index int, f func() error) error {
+	w.path = append(w.path, tag, int32(index)) // #nosec:G115 should never overflow
+	err := f()
+	w.path = w.path[:len(w.path)-2]
+	return err
+}
+
+func visit[T any](f func(T, protoreflect.SourcePath) error, value T, path protoreflect.SourcePath) error {
+	if f == nil {
+		return nil
+	}
+	return f(value, path)
+}
@@ -0,0 +1,205 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protopluginutil
+
+import (
+	"errors"
+	"sort"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// DescriptorVisitor contains the callbacks invoked by WalkFiles and WalkFileDescriptor.
+//
+// This is the equivalent of Visitor for resolved descriptors. Each callback is optional. If a
+// callback is nil, the corresponding descriptors are still traversed, but no function is invoked for them.
+//
+// If a callback returns an error, the walk is stopped and the error is returned,
+// with the exception of ErrSkipChildren returned from File, EnterMessage, Enum, or Service.
+type DescriptorVisitor struct {
+	// File is called for each file before its children are visited.
+	//
+	// ErrSkipChildren may be returned to skip the contents of the file.
+	File func(protoreflect.FileDescriptor) error
+	// EnterMessage is called for each message before its fields, oneofs, nested messages,
+	// nested enums, and nested extensions are visited.
+	EnterMessage func(protoreflect.MessageDescriptor) error
+	// ExitMessage is called for each message after its children are visited.
+	//
+	// This is not called if EnterMessage returned an error, including ErrSkipChildren.
+	ExitMessage func(protoreflect.MessageDescriptor) error
+	// Field is called for each field of a message.
+	//
+	// This is not called for extensions, see Extension.
+	Field func(protoreflect.FieldDescriptor) error
+	// Oneof is called for each oneof of a message, including synthetic oneofs.
+	Oneof func(protoreflect.OneofDescriptor) error
+	// Enum is called for each enum, before its values are visited.
+	Enum func(protoreflect.EnumDescriptor) error
+	// EnumValue is called for each value of an enum.
+	EnumValue func(protoreflect.EnumValueDescriptor) error
+	// Service is called for each service, before its methods are visited.
+	Service func(protoreflect.ServiceDescriptor) error
+	// Method is called for each method of a service.
+	Method func(protoreflect.MethodDescriptor) error
+	// Extension is called for each extension, whether declared at the top level of the file
+	// or nested within a message.
+	Extension func(protoreflect.ExtensionDescriptor) error
+}
+
+// WalkFiles traverses all files within the Files, invoking the callbacks on the DescriptorVisitor.
+//
+// Files are visited in order of their paths, so that the traversal is deterministic. Within each file,
+// descriptors are visited in the same order as WalkFileDescriptor.
+func WalkFiles(files *protoregistry.Files, visitor DescriptorVisitor) error {
+	fileDescriptors := make([]protoreflect.FileDescriptor, 0, files.NumFiles())
+	files.RangeFiles(func(fileDescriptor protoreflect.FileDescriptor) bool {
+		fileDescriptors = append(fileDescriptors, fileDescriptor)
+		return true
+	})
+	sort.Slice(
+		fileDescriptors,
+		func(i int, j int) bool {
+			return fileDescriptors[i].Path() < fileDescriptors[j].Path()
+		},
+	)
+	for _, fileDescriptor := range fileDescriptors {
+		if err := WalkFileDescriptor(fileDescriptor, visitor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WalkFileDescriptor traverses the FileDescriptor, invoking the callbacks on the DescriptorVisitor.
+//
+// Descriptors are visited in the order they are declared within the file. Top-level
+// messages are visited first, then top-level enums, services, and extensions. Within a message, fields
+// are visited first, then oneofs, nested messages, nested enums, and nested extensions.
+func WalkFileDescriptor(fileDescriptor protoreflect.FileDescriptor, visitor DescriptorVisitor) error {
+	if err := visitDescriptor(visitor.File, fileDescriptor); err != nil {
+		if errors.Is(err, ErrSkipChildren) {
+			return nil
+		}
+		return err
+	}
+	if err := walkMessageDescriptors(fileDescriptor.Messages(), visitor); err != nil {
+		return err
+	}
+	if err := walkEnumDescriptors(fileDescriptor.Enums(), visitor); err != nil {
+		return err
+	}
+	services := fileDescriptor.Services()
+	for i := 0; i < services.Len(); i++ {
+		if err := walkServiceDescriptor(services.Get(i), visitor); err != nil {
+			return err
+		}
+	}
+	return walkExtensionDescriptors(fileDescriptor.Extensions(), visitor)
+}
+
+// *** PRIVATE ***
+
+func walkMessageDescriptors(messages protoreflect.MessageDescriptors, visitor DescriptorVisitor) error {
+	for i := 0; i < messages.Len(); i++ {
+		if err := walkMessageDescriptor(messages.Get(i), visitor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func walkMessageDescriptor(message protoreflect.MessageDescriptor, visitor DescriptorVisitor) error {
+	if err := visitDescriptor(visitor.EnterMessage, message); err != nil {
+		if errors.Is(err, ErrSkipChildren) {
+			return nil
+		}
+		return err
+	}
+	fields := message.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		if err := visitDescriptor(visitor.Field, fields.Get(i)); err != nil {
+			return err
+		}
+	}
+	oneofs := message.Oneofs()
+	for i := 0; i < oneofs.Len(); i++ {
+		if err := visitDescriptor(visitor.Oneof, oneofs.Get(i)); err != nil {
+			return err
+		}
+	}
+	if err := walkMessageDescriptors(message.Messages(), visitor); err != nil {
+		return err
+	}
+	if err := walkEnumDescriptors(message.Enums(), visitor); err != nil {
+		return err
+	}
+	if err := walkExtensionDescriptors(message.Extensions(), visitor); err != nil {
+		return err
+	}
+	return visitDescriptor(visitor.ExitMessage, message)
+}
+
+func walkEnumDescriptors(enums protoreflect.EnumDescriptors, visitor DescriptorVisitor) error {
+	for i := 0; i < enums.Len(); i++ {
+		enum := enums.Get(i)
+		if err := visitDescriptor(visitor.Enum, enum); err != nil {
+			if errors.Is(err, ErrSkipChildren) {
+				continue
+			}
+			return err
+		}
+		values := enum.Values()
+		for j := 0; j < values.Len(); j++ {
+			if err := visitDescriptor(visitor.EnumValue, values.Get(j)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func walkServiceDescriptor(service protoreflect.ServiceDescriptor, visitor DescriptorVisitor) error {
+	if err := visitDescriptor(visitor.Service, service); err != nil {
+		if errors.Is(err, ErrSkipChildren) {
+			return nil
+		}
+		return err
+	}
+	methods := service.Methods()
+	for i := 0; i < methods.Len(); i++ {
+		if err := visitDescriptor(visitor.Method, methods.Get(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func walkExtensionDescriptors(extensions protoreflect.ExtensionDescriptors, visitor DescriptorVisitor) error {
+	for i := 0; i < extensions.Len(); i++ {
+		if err := visitDescriptor(visitor.Extension, extensions.Get(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func visitDescriptor[T protoreflect.Descriptor](f func(T) error, descriptor T) error {
+	if f == nil {
+		return nil
+	}
+	return f(descriptor)
+}
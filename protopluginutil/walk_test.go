@@ -0,0 +1,292 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protopluginutil
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestWalk(t *testing.T) {
+	t.Parallel()
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name: proto.String("a.proto"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("A"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					{Name: proto.String("a1")},
+					{Name: proto.String("a2")},
+				},
+				OneofDecl: []*descriptorpb.OneofDescriptorProto{
+					{Name: proto.String("o")},
+				},
+				NestedType: []*descriptorpb.DescriptorProto{
+					{
+						Name: proto.String("B"),
+						Field: []*descriptorpb.FieldDescriptorProto{
+							{Name: proto.String("b1")},
+						},
+					},
+				},
+				EnumType: []*descriptorpb.EnumDescriptorProto{
+					{
+						Name: proto.String("C"),
+						Value: []*descriptorpb.EnumValueDescriptorProto{
+							{Name: proto.String("C_0")},
+						},
+					},
+				},
+				Extension: []*descriptorpb.FieldDescriptorProto{
+					{Name: proto.String("a_ext")},
+				},
+			},
+		},
+		EnumType: []*descriptorpb.EnumDescriptorProto{
+			{
+				Name: proto.String("D"),
+				Value: []*descriptorpb.EnumValueDescriptorProto{
+					{Name: proto.String("D_0")},
+					{Name: proto.String("D_1")},
+				},
+			},
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{
+			{
+				Name: proto.String("S"),
+				Method: []*descriptorpb.MethodDescriptorProto{
+					{Name: proto.String("M")},
+				},
+			},
+		},
+		Extension: []*descriptorpb.FieldDescriptorProto{
+			{Name: proto.String("ext")},
+		},
+	}
+
+	var visited []string
+	record := func(kind string, name string, path protoreflect.SourcePath) {
+		visited = append(visited, fmt.Sprintf("%s %s %v", kind, name, []int32(path)))
+	}
+	visitor := Visitor{
+		EnterMessage: func(message *descriptorpb.DescriptorProto, path protoreflect.SourcePath) error {
+			record("enter", message.GetName(), path)
+			return nil
+		},
+		ExitMessage: func(message *descriptorpb.DescriptorProto, path protoreflect.SourcePath) error {
+			record("exit", message.GetName(), path)
+			return nil
+		},
+		Field: func(field *descriptorpb.FieldDescriptorProto, path protoreflect.SourcePath) error {
+			record("field", field.GetName(), path)
+			return nil
+		},
+		Oneof: func(oneof *descriptorpb.OneofDescriptorProto, path protoreflect.SourcePath) error {
+			record("oneof", oneof.GetName(), path)
+			return nil
+		},
+		Enum: func(enum *descriptorpb.EnumDescriptorProto, path protoreflect.SourcePath) error {
+			record("enum", enum.GetName(), path)
+			return nil
+		},
+		EnumValue: func(enumValue *descriptorpb.EnumValueDescriptorProto, path protoreflect.SourcePath) error {
+			record("enumvalue", enumValue.GetName(), path)
+			return nil
+		},
+		Service: func(service *descriptorpb.ServiceDescriptorProto, path protoreflect.SourcePath) error {
+			record("service", service.GetName(), path)
+			return nil
+		},
+		Method: func(method *descriptorpb.MethodDescriptorProto, path protoreflect.SourcePath) error {
+			record("method", method.GetName(), path)
+			return nil
+		},
+		Extension: func(extension *descriptorpb.FieldDescriptorProto, path protoreflect.SourcePath) error {
+			record("extension", extension.GetName(), path)
+			return nil
+		},
+	}
+	require.NoError(t, Walk(file, visitor))
+	require.Equal(
+		t,
+		[]string{
+			"enter A [4 0]",
+			"field a1 [4 0 2 0]",
+			"field a2 [4 0 2 1]",
+			"oneof o [4 0 8 0]",
+			"enter B [4 0 3 0]",
+			"field b1 [4 0 3 0 2 0]",
+			"exit B [4 0 3 0]",
+			"enum C [4 0 4 0]",
+			"enumvalue C_0 [4 0 4 0 2 0]",
+			"extension a_ext [4 0 6 0]",
+			"exit A [4 0]",
+			"enum D [5 0]",
+			"enumvalue D_0 [5 0 2 0]",
+			"enumvalue D_1 [5 0 2 1]",
+			"service S [6 0]",
+			"method M [6 0 2 0]",
+			"extension ext [7 0]",
+		},
+		visited,
+	)
+
+	visited = nil
+	visitor.EnterMessage = func(message *descriptorpb.DescriptorProto, path protoreflect.SourcePath) error {
+		record("enter", message.GetName(), path)
+		return ErrSkipChildren
+	}
+	visitor.Enum = func(enum *descriptorpb.EnumDescriptorProto, path protoreflect.SourcePath) error {
+		record("enum", enum.GetName(), path)
+		return ErrSkipChildren
+	}
+	require.NoError(t, Walk(file, visitor))
+	require.Equal(
+		t,
+		[]string{
+			"enter A [4 0]",
+			"enum D [5 0]",
+			"service S [6 0]",
+			"method M [6 0 2 0]",
+			"extension ext [7 0]",
+		},
+		visited,
+	)
+
+	// ErrSkipChildren is returned as-is from callbacks for descriptors without children.
+	visitor.Method = func(*descriptorpb.MethodDescriptorProto, protoreflect.SourcePath) error {
+		return ErrSkipChildren
+	}
+	require.ErrorIs(t, Walk(file, visitor), ErrSkipChildren)
+
+	errStop := errors.New("stop")
+	visitor.Service = func(*descriptorpb.ServiceDescriptorProto, protoreflect.SourcePath) error {
+		return errStop
+	}
+	require.ErrorIs(t, Walk(file, visitor), errStop)
+
+	// An empty Visitor is valid.
+	require.NoError(t, Walk(file, Visitor{}))
+}
+
+func TestWalkFiles(t *testing.T) {
+	t.Parallel()
+
+	files, err := protodesc.NewFiles(
+		&descriptorpb.FileDescriptorSet{
+			File: []*descriptorpb.FileDescriptorProto{
+				{
+					Name:    proto.String("b.proto"),
+					Package: proto.String("b"),
+					Syntax:  proto.String("proto3"),
+					EnumType: []*descriptorpb.EnumDescriptorProto{
+						{
+							Name: proto.String("E"),
+							Value: []*descriptorpb.EnumValueDescriptorProto{
+								{Name: proto.String("E_0"), Number: proto.Int32(0)},
+							},
+						},
+					},
+				},
+				{
+					Name:       proto.String("a.proto"),
+					Package:    proto.String("a"),
+					Syntax:     proto.String("proto3"),
+					Dependency: []string{"b.proto"},
+					MessageType: []*descriptorpb.DescriptorProto{
+						{
+							Name: proto.String("A"),
+							Field: []*descriptorpb.FieldDescriptorProto{
+								{
+									Name:     proto.String("e"),
+									Number:   proto.Int32(1),
+									Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+									Type:     descriptorpb.FieldDescriptorProto_TYPE_ENUM.Enum(),
+									TypeName: proto.String(".b.E"),
+								},
+							},
+							NestedType: []*descriptorpb.DescriptorProto{
+								{Name: proto.String("B")},
+							},
+						},
+					},
+					Service: []*descriptorpb.ServiceDescriptorProto{
+						{
+							Name: proto.String("S"),
+							Method: []*descriptorpb.MethodDescriptorProto{
+								{
+									Name:       proto.String("M"),
+									InputType:  proto.String(".a.A"),
+									OutputType: proto.String(".a.A"),
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	)
+	require.NoError(t, err)
+
+	var visited []string
+	record := func(descriptor protoreflect.Descriptor) error {
+		visited = append(visited, string(descriptor.FullName()))
+		return nil
+	}
+	visitor := DescriptorVisitor{
+		File: func(fileDescriptor protoreflect.FileDescriptor) error {
+			visited = append(visited, fileDescriptor.Path())
+			return nil
+		},
+		EnterMessage: func(message protoreflect.MessageDescriptor) error { return record(message) },
+		Field:        func(field protoreflect.FieldDescriptor) error { return record(field) },
+		Enum:         func(enum protoreflect.EnumDescriptor) error { return record(enum) },
+		EnumValue:    func(enumValue protoreflect.EnumValueDescriptor) error { return record(enumValue) },
+		Service:      func(service protoreflect.ServiceDescriptor) error { return record(service) },
+		Method:       func(method protoreflect.MethodDescriptor) error { return record(method) },
+	}
+	require.NoError(t, WalkFiles(files, visitor))
+	require.Equal(
+		t,
+		[]string{
+			"a.proto",
+			"a.A",
+			"a.A.e",
+			"a.A.B",
+			"a.S",
+			"a.S.M",
+			"b.proto",
+			"b.E",
+			"b.E_0",
+		},
+		visited,
+	)
+
+	visited = nil
+	visitor.File = func(fileDescriptor protoreflect.FileDescriptor) error {
+		visited = append(visited, fileDescriptor.Path())
+		return ErrSkipChildren
+	}
+	require.NoError(t, WalkFiles(files, visitor))
+	require.Equal(t, []string{"a.proto", "b.proto"}, visited)
+}
@@ -15,7 +15,8 @@
 package protoplugin
 
 import (
-	"errors"
+	"sort"
+	"sync"
 
 	"google.golang.org/protobuf/reflect/protodesc"
 	"google.golang.org/protobuf/reflect/protoreflect"
@@ -28,12 +29,29 @@ import (
 //
 // Request contains a private method to ensure that it is not constructed outside this package, to
 // enable us to modify the Request interface in the future without breaking compatibility.
+//
+// Request is safe for concurrent use by multiple goroutines, for example by worker goroutines within
+// a Handler that generates files in parallel. Values that are cached on the Request, such as the
+// FileDescriptorProtos returned by the Unsafe accessors and the index used by FindDescriptorByName,
+// are computed at most once regardless of how many goroutines request them concurrently. The
+// *protoregistry.Files returned by AllFiles is newly constructed on every call and is not shared.
+//
+// Callers must not modify any values documented as not being copies, such as the results of
+// CodeGeneratorRequest and the Unsafe accessors, as these are shared between all goroutines.
 type Request interface {
 	// Parameter returns the value of the parameter field on the CodeGeneratorRequest.
 	Parameter() string
+	// Parameters returns the parsed parameter field on the CodeGeneratorRequest.
+	//
+	// The same *Parameters is returned on every call, and is shared with all Requests returned from
+	// WithSourceRetentionOptions, so that keys read on any of them are tracked together.
+	// See ParseParameters and WithUnusedParameterWarnings for more details.
+	Parameters() (*Parameters, error)
 	// FileDescriptorsToGenerate returns the FileDescriptors for the files specified by the
 	// file_to_generate field on the CodeGeneratorRequest.
 	//
+	// The FileDescriptors are returned in the order of file_to_generate.
+	//
 	// The caller can assume that all FileDescriptors have a valid path as the name field.
 	// Paths are considered valid if they are non-empty, relative, use '/' as the path separator, do not jump context,
 	// and have `.proto` as the file extension.
@@ -47,10 +65,16 @@ type Request interface {
 	// The caller can assume that all FileDescriptors have a valid path as the name field.
 	// Paths are considered valid if they are non-empty, relative, use '/' as the path separator, do not jump context,
 	// and have `.proto` as the file extension.
+	//
+	// If WithDescriptorCache is specified, FileDescriptors may be shared with other Requests.
 	AllFiles() (*protoregistry.Files, error)
 	// FileDescriptorProtosToGenerate returns the FileDescriptors for the files specified by the
 	// file_to_generate field.
 	//
+	// By default, the FileDescriptorProtos are returned in the order of proto_file, or source_file_descriptors
+	// if WithSourceRetentionOptions is specified. If the WithFileToGenerateOrder RunOption is specified, the
+	// FileDescriptorProtos are returned in the order of file_to_generate.
+	//
 	// The caller can assume that all FileDescriptorProtoss have a valid path as the name field.
 	// Paths are considered valid if they are non-empty, relative, use '/' as the path separator, do not jump context,
 	// and have `.proto` as the file extension.
@@ -65,12 +89,80 @@ type Request interface {
 	// Paths are considered valid if they are non-empty, relative, use '/' as the path separator, do not jump context,
 	// and have `.proto` as the file extension.
 	AllFileDescriptorProtos() []*descriptorpb.FileDescriptorProto
+	// FileDescriptorProtosToGenerateUnsafe returns the same FileDescriptorProtos as
+	// FileDescriptorProtosToGenerate, without copying.
+	//
+	// The returned slice is computed once and shared across calls - do not modify it! This is intended
+	// for handlers that would otherwise call FileDescriptorProtosToGenerate repeatedly, for example
+	// within per-file loops on large CodeGeneratorRequests.
+	FileDescriptorProtosToGenerateUnsafe() []*descriptorpb.FileDescriptorProto
+	// AllFileDescriptorProtosUnsafe returns the same FileDescriptorProtos as AllFileDescriptorProtos,
+	// without copying.
+	//
+	// The returned slice is computed once and shared across calls - do not modify it! This is intended
+	// for handlers that would otherwise call AllFileDescriptorProtos repeatedly, for example
+	// within per-file loops on large CodeGeneratorRequests.
+	AllFileDescriptorProtosUnsafe() []*descriptorpb.FileDescriptorProto
+	// FilesToGenerate returns the Files for the files specified by the file_to_generate field.
+	//
+	// The Files are in the same order as FileDescriptorProtosToGenerate. Each File pairs the
+	// FileDescriptorProto with its resolved FileDescriptor, so that callers do not need to
+	// iterate the two separately.
+	FilesToGenerate() ([]File, error)
+	// Files returns the Files for all files in the CodeGeneratorRequest.
+	//
+	// The Files are in the same order as AllFileDescriptorProtos. File.Generate is true for the
+	// files specified by the file_to_generate field.
+	Files() ([]File, error)
+	// FindDescriptorByName looks up a descriptor by its full name across all files in the CodeGeneratorRequest.
+	//
+	// This has the same semantics as protoregistry.Files.FindDescriptorByName on the result of AllFiles, however
+	// the underlying index is built once and shared across calls. If the descriptor is not found, an error
+	// wrapping protoregistry.NotFound is returned.
+	FindDescriptorByName(name protoreflect.FullName) (protoreflect.Descriptor, error)
+	// AllSymbols returns the descriptors for all messages, enums, services, and extensions across all
+	// files in the CodeGeneratorRequest, including nested messages, enums, and extensions.
+	//
+	// Files are iterated in the order of the proto_file field on the CodeGeneratorRequest, and symbols within
+	// each file are returned in the order they are declared, with top-level messages first, then top-level
+	// enums, services, and extensions. Nested symbols follow their parent message.
+	AllSymbols() ([]protoreflect.Descriptor, error)
+	// IndexOfFileToGenerate returns the index of the path within the file_to_generate field on the
+	// CodeGeneratorRequest, or -1 if the path is not a file to generate.
+	//
+	// This is useful for generators that emit aggregated outputs, and need to order per-file content
+	// by the order of file_to_generate for reproducibility.
+	IndexOfFileToGenerate(path string) int
+	// FileFilter returns the FileFilter that was applied to the files to generate by the include
+	// and exclude parameters.
+	//
+	// If WithFileFilterParameters was not specified, or the parameter did not contain include or
+	// exclude elements, nil is returned. See WithFileFilterParameters for more details.
+	FileFilter() *FileFilter
+	// Editions returns the distinct editions used by the files to generate, sorted from oldest to newest.
+	//
+	// Only files with syntax "editions" are considered, so proto2 and proto3 files do not contribute
+	// EDITION_PROTO2 or EDITION_PROTO3. If no file to generate uses Editions, nil is returned.
+	//
+	// The first and last elements are the minimum and maximum editions that the Handler needs to support.
+	// This is useful for Handlers that have separate code paths for Editions, or that want to produce
+	// targeted errors for specific editions beyond what Capabilities validates.
+	Editions() []descriptorpb.Edition
+	// RequiresEditionSupport returns true if any file to generate uses Editions.
+	//
+	// This is equivalent to len(Editions()) > 0.
+	RequiresEditionSupport() bool
 	// CompilerVersion returns the specified compiler_version on the CodeGeneratorRequest.
 	//
 	// If the compiler_version field was not present, nil is returned.
 	//
 	// The caller can assume that the major, minor, and patch values are non-negative.
 	CompilerVersion() *CompilerVersion
+	// GeneratorName returns the name of the compiler that invoked the plugin, if detectable.
+	//
+	// This returns "buf" if CompilerVersion().IsBuf() is true, "protoc" if CompilerVersion().IsProtoc()
+	// is true, and empty otherwise, including if the compiler_version field was not present.
+	GeneratorName() string
 	// CodeGeneratorRequest returns the raw underlying CodeGeneratorRequest.
 	//
 	// The returned CodeGeneratorRequest is a not copy - do not modify it! If you would
@@ -83,43 +175,119 @@ type Request interface {
 	// source-retention options are always included on files not in file_to_generate.
 	//
 	// An error will be returned if the underlying CodeGeneratorRequest did not have source_file_descriptors populated.
+	// This error will be a *SourceRetentionOptionsUnavailableError.
 	WithSourceRetentionOptions() (Request, error)
+	// WithSourceRetentionOptionsIfAvailable is the same as WithSourceRetentionOptions, except that if the underlying
+	// CodeGeneratorRequest did not have source_file_descriptors populated, the Request itself and false are returned.
+	//
+	// This allows plugins to transparently fall back to runtime-retention options when invoked by older compilers.
+	// When the Handler is invoked via Main, Run, Invoke, or ExecuteHandler, the first fallback for a given
+	// CodeGeneratorRequest produces a single warning with a *SourceRetentionOptionsUnavailableError, written to
+	// stderr via PluginEnv.Warnf.
+	WithSourceRetentionOptionsIfAvailable() (Request, bool)
 
+	rangeFileDescriptorProtosToGenerate(f func(*descriptorpb.FileDescriptorProto) bool)
+	rangeAllFileDescriptorProtos(f func(*descriptorpb.FileDescriptorProto) bool)
+	rangeFileDescriptorsToGenerate(f func(protoreflect.FileDescriptor, error) bool)
+	rangeAllFileDescriptors(f func(protoreflect.FileDescriptor, error) bool)
+	hasSourceRetentionOptions() bool
+	hasFileToGenerateOrder() bool
+	getSourceRetentionOptionsUnavailableWarning() *onceWarning
+	getDescriptorCache() *DescriptorCache
 	isRequest()
 }
 
+// File is a file within a CodeGeneratorRequest.
+type File struct {
+	// FileDescriptorProto is the FileDescriptorProto for the file.
+	//
+	// This is shared with the Request, and must not be modified.
+	FileDescriptorProto *descriptorpb.FileDescriptorProto
+	// FileDescriptor is the resolved FileDescriptor for the file.
+	FileDescriptor protoreflect.FileDescriptor
+	// Generate is true if the file is specified by the file_to_generate field.
+	Generate bool
+}
+
 // NewRequest returns a new Request for the CodeGeneratorRequest.
 //
-// The CodeGeneratorRequest will be validated as part of construction.
+// The CodeGeneratorRequest will be validated as part of construction. If the CodeGeneratorRequest
+// is invalid, a *RequestValidationError is returned.
 func NewRequest(codeGeneratorRequest *pluginpb.CodeGeneratorRequest) (Request, error) {
 	if err := validateCodeGeneratorRequest(codeGeneratorRequest); err != nil {
-		return nil, err
+		return nil, newRequestValidationError(err)
 	}
+	return newRequest(codeGeneratorRequest), nil
+}
+
+// NewRequestWithoutValidation returns a new Request for the CodeGeneratorRequest without validating
+// the CodeGeneratorRequest.
+//
+// This should only be used by hosts that have already validated the CodeGeneratorRequest, for example
+// compilers that invoke Handlers in-process with CodeGeneratorRequests they constructed themselves.
+// If the CodeGeneratorRequest is invalid, the behavior of the returned Request is undefined.
+//
+// Most users should use NewRequest.
+func NewRequestWithoutValidation(codeGeneratorRequest *pluginpb.CodeGeneratorRequest) Request {
+	return newRequest(codeGeneratorRequest)
+}
+
+// *** PRIVATE ***
+
+func newRequest(codeGeneratorRequest *pluginpb.CodeGeneratorRequest) *request {
 	request := &request{
-		codeGeneratorRequest: codeGeneratorRequest,
+		codeGeneratorRequest:         codeGeneratorRequest,
+		sourceFileDescriptorsPresent: len(codeGeneratorRequest.GetSourceFileDescriptors()) > 0,
 	}
 	request.getFilesToGenerateMap =
 		onceValue(request.getFilesToGenerateMapUncached)
 	request.getSourceFileDescriptorNameToFileDescriptorProtoMap =
 		onceValue(request.getSourceFileDescriptorNameToFileDescriptorProtoMapUncached)
-	return request, nil
+	request.getParameters =
+		onceValues(request.getParametersUncached)
+	request.initCachedValues()
+	return request
 }
 
-// *** PRIVATE ***
-
+// request is safe for concurrent use as all fields are either immutable after construction, or
+// are functions returned by onceValue or onceValues. Any new cached values must follow this pattern.
 type request struct {
 	codeGeneratorRequest *pluginpb.CodeGeneratorRequest
 
-	getFilesToGenerateMap                               func() map[string]struct{}
+	// The map is from file to generate to its index within file_to_generate.
+	getFilesToGenerateMap                               func() map[string]int
 	getSourceFileDescriptorNameToFileDescriptorProtoMap func() map[string]*descriptorpb.FileDescriptorProto
+	getParameters                                       func() (*Parameters, error)
+	// These depend on sourceRetentionOptions, so they cannot be shared between Requests with different values.
+	getFileDescriptorProtosToGenerate func() []*descriptorpb.FileDescriptorProto
+	getAllFileDescriptorProtos        func() []*descriptorpb.FileDescriptorProto
+	getSymbolTable                    func() (*symbolTable, error)
 
 	sourceRetentionOptions bool
+	// True if source_file_descriptors was populated on the CodeGeneratorRequest this Request was derived from.
+	//
+	// This is recorded separately, as filtering the files to generate may leave source_file_descriptors empty.
+	sourceFileDescriptorsPresent bool
+	// If true, FileDescriptorProtosToGenerate returns files in the order of file_to_generate.
+	fileToGenerateOrder bool
+	// The FileFilter applied by WithFileFilterParameters, if any.
+	fileFilter *FileFilter
+	// Shared between all Requests derived from the same CodeGeneratorRequest, so that the warning is produced once.
+	//
+	// Nil if no warning should be produced.
+	sourceRetentionOptionsUnavailableWarning *onceWarning
+	// The DescriptorCache set by WithDescriptorCache, if any.
+	descriptorCache *DescriptorCache
 }
 
 func (r *request) Parameter() string {
 	return r.codeGeneratorRequest.GetParameter()
 }
 
+func (r *request) Parameters() (*Parameters, error) {
+	return r.getParameters()
+}
+
 func (r *request) FileDescriptorsToGenerate() ([]protoreflect.FileDescriptor, error) {
 	files, err := r.AllFiles()
 	if err != nil {
@@ -137,47 +305,77 @@ func (r *request) FileDescriptorsToGenerate() ([]protoreflect.FileDescriptor, er
 }
 
 func (r *request) AllFiles() (*protoregistry.Files, error) {
-	return protodesc.NewFiles(&descriptorpb.FileDescriptorSet{File: r.AllFileDescriptorProtos()})
+	if r.descriptorCache != nil {
+		return r.descriptorCache.newFiles(r.getAllFileDescriptorProtos())
+	}
+	return protodesc.NewFiles(&descriptorpb.FileDescriptorSet{File: r.getAllFileDescriptorProtos()})
 }
 
 func (r *request) FileDescriptorProtosToGenerate() []*descriptorpb.FileDescriptorProto {
-	// If we want source-retention options, source_file_descriptors is all we need.
-	//
-	// We have validated that source_file_descriptors is populated via WithSourceRetentionOptions.
-	if r.sourceRetentionOptions {
-		return slicesClone(r.codeGeneratorRequest.GetSourceFileDescriptors())
-	}
-	// Otherwise, we need to get the values in proto_file that are in file_to_generate.
-	filesToGenerateMap := r.getFilesToGenerateMap()
-	fileDescriptorProtos := make([]*descriptorpb.FileDescriptorProto, 0, len(r.codeGeneratorRequest.GetFileToGenerate()))
-	for _, protoFile := range r.codeGeneratorRequest.GetProtoFile() {
-		if _, ok := filesToGenerateMap[protoFile.GetName()]; ok {
-			fileDescriptorProtos = append(fileDescriptorProtos, protoFile)
-		}
-	}
-	return fileDescriptorProtos
+	return slicesClone(r.getFileDescriptorProtosToGenerate())
 }
 
 func (r *request) AllFileDescriptorProtos() []*descriptorpb.FileDescriptorProto {
-	// If we do not want source-retention options, proto_file is all we need.
-	if !r.sourceRetentionOptions {
-		return slicesClone(r.codeGeneratorRequest.GetProtoFile())
+	return slicesClone(r.getAllFileDescriptorProtos())
+}
+
+func (r *request) FileDescriptorProtosToGenerateUnsafe() []*descriptorpb.FileDescriptorProto {
+	return r.getFileDescriptorProtosToGenerate()
+}
+
+func (r *request) AllFileDescriptorProtosUnsafe() []*descriptorpb.FileDescriptorProto {
+	return r.getAllFileDescriptorProtos()
+}
+
+func (r *request) FilesToGenerate() ([]File, error) {
+	return r.newFiles(r.getFileDescriptorProtosToGenerate())
+}
+
+func (r *request) Files() ([]File, error) {
+	return r.newFiles(r.getAllFileDescriptorProtos())
+}
+
+func (r *request) FindDescriptorByName(name protoreflect.FullName) (protoreflect.Descriptor, error) {
+	symbolTable, err := r.getSymbolTable()
+	if err != nil {
+		return nil, err
 	}
-	// Otherwise, we need to replace the values in proto_file that are in file_to_generate
-	// with the values from source_file_descriptors.
-	//
-	// We have validated that source_file_descriptors is populated via WithSourceRetentionOptions.
-	filesToGenerateMap := r.getFilesToGenerateMap()
-	sourceFileDescriptorNameToFileDescriptorProtoMap := r.getSourceFileDescriptorNameToFileDescriptorProtoMap()
-	fileDescriptorProtos := make([]*descriptorpb.FileDescriptorProto, len(r.codeGeneratorRequest.GetProtoFile()))
-	for i, protoFile := range r.codeGeneratorRequest.GetProtoFile() {
-		if _, ok := filesToGenerateMap[protoFile.GetName()]; ok {
-			// We assume we've done validation that source_file_descriptors contains file_to_generate.
-			protoFile = sourceFileDescriptorNameToFileDescriptorProtoMap[protoFile.GetName()]
-		}
-		fileDescriptorProtos[i] = protoFile
+	return symbolTable.files.FindDescriptorByName(name)
+}
+
+func (r *request) AllSymbols() ([]protoreflect.Descriptor, error) {
+	symbolTable, err := r.getSymbolTable()
+	if err != nil {
+		return nil, err
 	}
-	return fileDescriptorProtos
+	return slicesClone(symbolTable.symbols), nil
+}
+
+func (r *request) Editions() []descriptorpb.Edition {
+	var editions []descriptorpb.Edition
+	seen := make(map[descriptorpb.Edition]struct{})
+	r.rangeFileDescriptorProtosToGenerate(func(fileDescriptorProto *descriptorpb.FileDescriptorProto) bool {
+		if !isEditionsFileDescriptorProto(fileDescriptorProto) {
+			return true
+		}
+		edition := fileDescriptorProto.GetEdition()
+		if _, ok := seen[edition]; !ok {
+			seen[edition] = struct{}{}
+			editions = append(editions, edition)
+		}
+		return true
+	})
+	sort.Slice(editions, func(i int, j int) bool { return editions[i] < editions[j] })
+	return editions
+}
+
+func (r *request) RequiresEditionSupport() bool {
+	requiresEditionSupport := false
+	r.rangeFileDescriptorProtosToGenerate(func(fileDescriptorProto *descriptorpb.FileDescriptorProto) bool {
+		requiresEditionSupport = isEditionsFileDescriptorProto(fileDescriptorProto)
+		return !requiresEditionSupport
+	})
+	return requiresEditionSupport
 }
 
 func (r *request) CompilerVersion() *CompilerVersion {
@@ -193,6 +391,18 @@ func (r *request) CompilerVersion() *CompilerVersion {
 	return nil
 }
 
+func (r *request) GeneratorName() string {
+	compilerVersion := r.CompilerVersion()
+	switch {
+	case compilerVersion.IsBuf():
+		return "buf"
+	case compilerVersion.IsProtoc():
+		return "protoc"
+	default:
+		return ""
+	}
+}
+
 func (r *request) CodeGeneratorRequest() *pluginpb.CodeGeneratorRequest {
 	return r.codeGeneratorRequest
 }
@@ -201,29 +411,148 @@ func (r *request) WithSourceRetentionOptions() (Request, error) {
 	if err := r.validateSourceFileDescriptorsPresent(); err != nil {
 		return nil, err
 	}
-	return &request{
+	request := &request{
 		codeGeneratorRequest:                                r.codeGeneratorRequest,
 		getFilesToGenerateMap:                               r.getFilesToGenerateMap,
 		getSourceFileDescriptorNameToFileDescriptorProtoMap: r.getSourceFileDescriptorNameToFileDescriptorProtoMap,
+		getParameters:                                       r.getParameters,
 		sourceRetentionOptions:                              true,
-	}, nil
+		sourceFileDescriptorsPresent:                        r.sourceFileDescriptorsPresent,
+		fileToGenerateOrder:                                 r.fileToGenerateOrder,
+		fileFilter:                                          r.fileFilter,
+		sourceRetentionOptionsUnavailableWarning:            r.sourceRetentionOptionsUnavailableWarning,
+		descriptorCache:                                     r.descriptorCache,
+	}
+	request.initCachedValues()
+	return request, nil
+}
+
+func (r *request) WithSourceRetentionOptionsIfAvailable() (Request, bool) {
+	request, err := r.WithSourceRetentionOptions()
+	if err != nil {
+		r.sourceRetentionOptionsUnavailableWarning.warn(err)
+		return r, false
+	}
+	return request, true
+}
+
+func (r *request) IndexOfFileToGenerate(path string) int {
+	if i, ok := r.getFilesToGenerateMap()[path]; ok {
+		return i
+	}
+	return -1
+}
+
+func (r *request) FileFilter() *FileFilter {
+	return r.fileFilter
+}
+
+func (r *request) hasSourceRetentionOptions() bool {
+	return r.sourceRetentionOptions
+}
+
+func (r *request) hasFileToGenerateOrder() bool {
+	return r.fileToGenerateOrder
+}
+
+func (r *request) getSourceRetentionOptionsUnavailableWarning() *onceWarning {
+	return r.sourceRetentionOptionsUnavailableWarning
+}
+
+func (r *request) getDescriptorCache() *DescriptorCache {
+	return r.descriptorCache
 }
 
 func (r *request) validateSourceFileDescriptorsPresent() error {
-	if len(r.codeGeneratorRequest.GetSourceFileDescriptors()) == 0 &&
-		len(r.codeGeneratorRequest.GetProtoFile()) > 0 {
-		return errors.New("source_file_descriptors not set on CodeGeneratorRequest but source-retention options requested - you likely need to upgrade your protobuf compiler")
+	if !r.sourceFileDescriptorsPresent && len(r.codeGeneratorRequest.GetProtoFile()) > 0 {
+		return newSourceRetentionOptionsUnavailableError(r.CompilerVersion())
 	}
 	return nil
 }
 
-func (r *request) getFilesToGenerateMapUncached() map[string]struct{} {
+// initCachedValues initializes the cached values that depend on sourceRetentionOptions.
+func (r *request) initCachedValues() {
+	r.getFileDescriptorProtosToGenerate = onceValue(r.getFileDescriptorProtosToGenerateUncached)
+	r.getAllFileDescriptorProtos = onceValue(r.getAllFileDescriptorProtosUncached)
+	r.getSymbolTable = onceValues(r.getSymbolTableUncached)
+}
+
+func (r *request) getFileDescriptorProtosToGenerateUncached() []*descriptorpb.FileDescriptorProto {
+	if r.fileToGenerateOrder {
+		return r.getFileDescriptorProtosToGenerateInFileToGenerateOrder()
+	}
+	// If we want source-retention options, source_file_descriptors is all we need.
+	//
+	// We have validated that source_file_descriptors is populated via WithSourceRetentionOptions.
+	if r.sourceRetentionOptions {
+		return slicesClone(r.codeGeneratorRequest.GetSourceFileDescriptors())
+	}
+	// Otherwise, we need to get the values in proto_file that are in file_to_generate.
+	filesToGenerateMap := r.getFilesToGenerateMap()
+	fileDescriptorProtos := make([]*descriptorpb.FileDescriptorProto, 0, len(r.codeGeneratorRequest.GetFileToGenerate()))
+	for _, protoFile := range r.codeGeneratorRequest.GetProtoFile() {
+		if _, ok := filesToGenerateMap[protoFile.GetName()]; ok {
+			fileDescriptorProtos = append(fileDescriptorProtos, protoFile)
+		}
+	}
+	return fileDescriptorProtos
+}
+
+// getFileDescriptorProtosToGenerateInFileToGenerateOrder returns the FileDescriptorProtos to generate,
+// in the order of file_to_generate.
+//
+// This relies on validation that every file_to_generate has exactly one corresponding FileDescriptorProto. If this
+// does not hold, for example with WithSkipRequestValidation, files without a FileDescriptorProto are omitted.
+func (r *request) getFileDescriptorProtosToGenerateInFileToGenerateOrder() []*descriptorpb.FileDescriptorProto {
+	fileDescriptorProtos := r.codeGeneratorRequest.GetProtoFile()
+	if r.sourceRetentionOptions {
+		fileDescriptorProtos = r.codeGeneratorRequest.GetSourceFileDescriptors()
+	}
+	filesToGenerateMap := r.getFilesToGenerateMap()
+	orderedFileDescriptorProtos := make([]*descriptorpb.FileDescriptorProto, len(r.codeGeneratorRequest.GetFileToGenerate()))
+	for _, fileDescriptorProto := range fileDescriptorProtos {
+		if i, ok := filesToGenerateMap[fileDescriptorProto.GetName()]; ok {
+			orderedFileDescriptorProtos[i] = fileDescriptorProto
+		}
+	}
+	resultFileDescriptorProtos := orderedFileDescriptorProtos[:0]
+	for _, fileDescriptorProto := range orderedFileDescriptorProtos {
+		if fileDescriptorProto != nil {
+			resultFileDescriptorProtos = append(resultFileDescriptorProtos, fileDescriptorProto)
+		}
+	}
+	return resultFileDescriptorProtos
+}
+
+func (r *request) getAllFileDescriptorProtosUncached() []*descriptorpb.FileDescriptorProto {
+	// If we do not want source-retention options, proto_file is all we need.
+	if !r.sourceRetentionOptions {
+		return slicesClone(r.codeGeneratorRequest.GetProtoFile())
+	}
+	// Otherwise, we need to replace the values in proto_file that are in file_to_generate
+	// with the values from source_file_descriptors.
+	//
+	// We have validated that source_file_descriptors is populated via WithSourceRetentionOptions.
+	filesToGenerateMap := r.getFilesToGenerateMap()
+	sourceFileDescriptorNameToFileDescriptorProtoMap := r.getSourceFileDescriptorNameToFileDescriptorProtoMap()
+	fileDescriptorProtos := make([]*descriptorpb.FileDescriptorProto, len(r.codeGeneratorRequest.GetProtoFile()))
+	for i, protoFile := range r.codeGeneratorRequest.GetProtoFile() {
+		if _, ok := filesToGenerateMap[protoFile.GetName()]; ok {
+			// We assume we've done validation that source_file_descriptors contains file_to_generate.
+			protoFile = sourceFileDescriptorNameToFileDescriptorProtoMap[protoFile.GetName()]
+		}
+		fileDescriptorProtos[i] = protoFile
+	}
+	return fileDescriptorProtos
+}
+
+func (r *request) getFilesToGenerateMapUncached() map[string]int {
 	filesToGenerateMap := make(
-		map[string]struct{},
+		map[string]int,
 		len(r.codeGeneratorRequest.GetFileToGenerate()),
 	)
-	for _, fileToGenerate := range r.codeGeneratorRequest.GetFileToGenerate() {
-		filesToGenerateMap[fileToGenerate] = struct{}{}
+	for i, fileToGenerate := range r.codeGeneratorRequest.GetFileToGenerate() {
+		filesToGenerateMap[fileToGenerate] = i
 	}
 	return filesToGenerateMap
 }
@@ -239,4 +568,204 @@ func (r *request) getSourceFileDescriptorNameToFileDescriptorProtoMapUncached()
 	return sourceFileDescriptorNameToFileDescriptorProtoMap
 }
 
+// newFiles returns the Files for the FileDescriptorProtos, which must be from this Request.
+func (r *request) newFiles(fileDescriptorProtos []*descriptorpb.FileDescriptorProto) ([]File, error) {
+	symbolTable, err := r.getSymbolTable()
+	if err != nil {
+		return nil, err
+	}
+	files := make([]File, len(fileDescriptorProtos))
+	for i, fileDescriptorProto := range fileDescriptorProtos {
+		fileDescriptor, err := symbolTable.files.FindFileByPath(fileDescriptorProto.GetName())
+		if err != nil {
+			return nil, err
+		}
+		files[i] = File{
+			FileDescriptorProto: fileDescriptorProto,
+			FileDescriptor:      fileDescriptor,
+			Generate:            r.IndexOfFileToGenerate(fileDescriptorProto.GetName()) >= 0,
+		}
+	}
+	return files, nil
+}
+
+func isEditionsFileDescriptorProto(fileDescriptorProto *descriptorpb.FileDescriptorProto) bool {
+	return fileDescriptorProto.GetSyntax() == "editions"
+}
+
+func (r *request) getParametersUncached() (*Parameters, error) {
+	return ParseParameters(r.codeGeneratorRequest.GetParameter())
+}
+
+// rangeFileDescriptorProtosToGenerate calls f for each FileDescriptorProto that FileDescriptorProtosToGenerate
+// would return, in the same order, without materializing a slice. Iteration stops if f returns false.
+func (r *request) rangeFileDescriptorProtosToGenerate(f func(*descriptorpb.FileDescriptorProto) bool) {
+	if r.fileToGenerateOrder {
+		for _, fileDescriptorProto := range r.getFileDescriptorProtosToGenerate() {
+			if !f(fileDescriptorProto) {
+				return
+			}
+		}
+		return
+	}
+	if r.sourceRetentionOptions {
+		for _, sourceFileDescriptor := range r.codeGeneratorRequest.GetSourceFileDescriptors() {
+			if !f(sourceFileDescriptor) {
+				return
+			}
+		}
+		return
+	}
+	filesToGenerateMap := r.getFilesToGenerateMap()
+	for _, protoFile := range r.codeGeneratorRequest.GetProtoFile() {
+		if _, ok := filesToGenerateMap[protoFile.GetName()]; ok {
+			if !f(protoFile) {
+				return
+			}
+		}
+	}
+}
+
+// rangeAllFileDescriptorProtos calls f for each FileDescriptorProto that AllFileDescriptorProtos
+// would return, in the same order, without materializing a slice. Iteration stops if f returns false.
+func (r *request) rangeAllFileDescriptorProtos(f func(*descriptorpb.FileDescriptorProto) bool) {
+	var filesToGenerateMap map[string]int
+	var sourceFileDescriptorNameToFileDescriptorProtoMap map[string]*descriptorpb.FileDescriptorProto
+	if r.sourceRetentionOptions {
+		filesToGenerateMap = r.getFilesToGenerateMap()
+		sourceFileDescriptorNameToFileDescriptorProtoMap = r.getSourceFileDescriptorNameToFileDescriptorProtoMap()
+	}
+	for _, protoFile := range r.codeGeneratorRequest.GetProtoFile() {
+		if _, ok := filesToGenerateMap[protoFile.GetName()]; ok {
+			protoFile = sourceFileDescriptorNameToFileDescriptorProtoMap[protoFile.GetName()]
+		}
+		if !f(protoFile) {
+			return
+		}
+	}
+}
+
+// rangeFileDescriptorsToGenerate calls f for each FileDescriptor that FileDescriptorsToGenerate
+// would return, in the same order, using the cached symbol table.
+//
+// If the FileDescriptors cannot be built, f is called with the error and iteration stops.
+// Iteration also stops if f returns false.
+func (r *request) rangeFileDescriptorsToGenerate(f func(protoreflect.FileDescriptor, error) bool) {
+	symbolTable, err := r.getSymbolTable()
+	if err != nil {
+		f(nil, err)
+		return
+	}
+	for _, fileToGenerate := range r.codeGeneratorRequest.GetFileToGenerate() {
+		if !rangeFileDescriptor(symbolTable, fileToGenerate, f) {
+			return
+		}
+	}
+}
+
+// rangeAllFileDescriptors calls f for the FileDescriptor of each file in proto_file, in order,
+// using the cached symbol table.
+//
+// If the FileDescriptors cannot be built, f is called with the error and iteration stops.
+// Iteration also stops if f returns false.
+func (r *request) rangeAllFileDescriptors(f func(protoreflect.FileDescriptor, error) bool) {
+	symbolTable, err := r.getSymbolTable()
+	if err != nil {
+		f(nil, err)
+		return
+	}
+	for _, protoFile := range r.codeGeneratorRequest.GetProtoFile() {
+		if !rangeFileDescriptor(symbolTable, protoFile.GetName(), f) {
+			return
+		}
+	}
+}
+
+func (r *request) getSymbolTableUncached() (*symbolTable, error) {
+	files, err := r.AllFiles()
+	if err != nil {
+		return nil, err
+	}
+	symbolTable := &symbolTable{
+		files: files,
+	}
+	for _, fileDescriptorProto := range r.codeGeneratorRequest.GetProtoFile() {
+		fileDescriptor, err := files.FindFileByPath(fileDescriptorProto.GetName())
+		if err != nil {
+			return nil, err
+		}
+		symbolTable.addFileDescriptor(fileDescriptor)
+	}
+	return symbolTable, nil
+}
+
 func (*request) isRequest() {}
+
+// rangeFileDescriptor calls f with the FileDescriptor for the path, returning false if iteration should stop.
+func rangeFileDescriptor(
+	symbolTable *symbolTable,
+	path string,
+	f func(protoreflect.FileDescriptor, error) bool,
+) bool {
+	fileDescriptor, err := symbolTable.files.FindFileByPath(path)
+	if err != nil {
+		f(nil, err)
+		return false
+	}
+	return f(fileDescriptor, nil)
+}
+
+type symbolTable struct {
+	files   *protoregistry.Files
+	symbols []protoreflect.Descriptor
+}
+
+func (s *symbolTable) addFileDescriptor(fileDescriptor protoreflect.FileDescriptor) {
+	s.addMessageDescriptors(fileDescriptor.Messages())
+	s.addEnumDescriptors(fileDescriptor.Enums())
+	services := fileDescriptor.Services()
+	for i := 0; i < services.Len(); i++ {
+		s.symbols = append(s.symbols, services.Get(i))
+	}
+	s.addExtensionDescriptors(fileDescriptor.Extensions())
+}
+
+func (s *symbolTable) addMessageDescriptors(messageDescriptors protoreflect.MessageDescriptors) {
+	for i := 0; i < messageDescriptors.Len(); i++ {
+		messageDescriptor := messageDescriptors.Get(i)
+		s.symbols = append(s.symbols, messageDescriptor)
+		s.addMessageDescriptors(messageDescriptor.Messages())
+		s.addEnumDescriptors(messageDescriptor.Enums())
+		s.addExtensionDescriptors(messageDescriptor.Extensions())
+	}
+}
+
+func (s *symbolTable) addEnumDescriptors(enumDescriptors protoreflect.EnumDescriptors) {
+	for i := 0; i < enumDescriptors.Len(); i++ {
+		s.symbols = append(s.symbols, enumDescriptors.Get(i))
+	}
+}
+
+func (s *symbolTable) addExtensionDescriptors(extensionDescriptors protoreflect.ExtensionDescriptors) {
+	for i := 0; i < extensionDescriptors.Len(); i++ {
+		s.symbols = append(s.symbols, extensionDescriptors.Get(i))
+	}
+}
+
+// onceWarning produces a warning at most once.
+type onceWarning struct {
+	warningFunc func(error)
+	once        sync.Once
+}
+
+func newOnceWarning(warningFunc func(error)) *onceWarning {
+	return &onceWarning{warningFunc: warningFunc}
+}
+
+// warn calls the warning function with the error on the first call. If the onceWarning is nil, this is a no-op.
+func (o *onceWarning) warn(err error) {
+	if o == nil {
+		return
+	}
+	o.once.Do(func() { o.warningFunc(err) })
+}
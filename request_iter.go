@@ -0,0 +1,59 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.23
+
+package protoplugin
+
+import (
+	"iter"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Protoplugin needs to stay compatible with Go 1.21, so iterator-based accessors cannot be
+// added to the Request interface directly. Instead, these are provided as functions that are
+// only available when building with Go 1.23 or later.
+
+// FileDescriptorProtosToGenerateSeq returns an iterator over the same FileDescriptorProtos as
+// Request.FileDescriptorProtosToGenerate, in the same order, without materializing a slice.
+func FileDescriptorProtosToGenerateSeq(request Request) iter.Seq[*descriptorpb.FileDescriptorProto] {
+	return request.rangeFileDescriptorProtosToGenerate
+}
+
+// AllFileDescriptorProtosSeq returns an iterator over the same FileDescriptorProtos as
+// Request.AllFileDescriptorProtos, in the same order, without materializing a slice.
+func AllFileDescriptorProtosSeq(request Request) iter.Seq[*descriptorpb.FileDescriptorProto] {
+	return request.rangeAllFileDescriptorProtos
+}
+
+// FileDescriptorsToGenerateSeq returns an iterator over the same FileDescriptors as
+// Request.FileDescriptorsToGenerate, in the same order, without materializing a slice.
+//
+// If the FileDescriptors cannot be built, a single nil FileDescriptor and non-nil error are yielded.
+func FileDescriptorsToGenerateSeq(request Request) iter.Seq2[protoreflect.FileDescriptor, error] {
+	return request.rangeFileDescriptorsToGenerate
+}
+
+// AllFileDescriptorsSeq returns an iterator over the FileDescriptors for all files in the
+// CodeGeneratorRequest, in the order of the proto_file field.
+//
+// The FileDescriptors are the same as those contained in the Files returned from Request.AllFiles,
+// however they are built once and shared across calls.
+//
+// If the FileDescriptors cannot be built, a single nil FileDescriptor and non-nil error are yielded.
+func AllFileDescriptorsSeq(request Request) iter.Seq2[protoreflect.FileDescriptor, error] {
+	return request.rangeAllFileDescriptors
+}
@@ -0,0 +1,70 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.23
+
+package protoplugin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestSeqs(t *testing.T) {
+	t.Parallel()
+
+	request := testNewRequest(
+		t,
+		[]string{"c.proto", "a.proto"},
+		map[string][]byte{
+			"a.proto": []byte(`syntax = "proto3"; package foo; message A {}`),
+			"b.proto": []byte(`syntax = "proto3"; package foo; message B {}`),
+			"c.proto": []byte(`syntax = "proto3"; package foo; message C {}`),
+		},
+	)
+
+	var names []string
+	for fileDescriptorProto := range FileDescriptorProtosToGenerateSeq(request) {
+		names = append(names, fileDescriptorProto.GetName())
+	}
+	var expectedNames []string
+	for _, fileDescriptorProto := range request.FileDescriptorProtosToGenerate() {
+		expectedNames = append(expectedNames, fileDescriptorProto.GetName())
+	}
+	require.Equal(t, expectedNames, names)
+
+	names = nil
+	for fileDescriptorProto := range AllFileDescriptorProtosSeq(request) {
+		names = append(names, fileDescriptorProto.GetName())
+	}
+	require.Equal(t, []string{"a.proto", "b.proto", "c.proto"}, names)
+
+	names = nil
+	for fileDescriptor, err := range FileDescriptorsToGenerateSeq(request) {
+		require.NoError(t, err)
+		names = append(names, fileDescriptor.Path())
+	}
+	require.Equal(t, []string{"c.proto", "a.proto"}, names)
+
+	names = nil
+	for fileDescriptor, err := range AllFileDescriptorsSeq(request) {
+		require.NoError(t, err)
+		names = append(names, fileDescriptor.Path())
+		if len(names) == 2 {
+			break
+		}
+	}
+	require.Equal(t, []string{"a.proto", "b.proto"}, names)
+}
@@ -0,0 +1,454 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestRequestSymbols(t *testing.T) {
+	t.Parallel()
+
+	request := testNewRequest(
+		t,
+		[]string{"a.proto"},
+		map[string][]byte{
+			"a.proto": []byte(`
+				syntax = "proto2";
+				package foo;
+				import "b.proto";
+				message A {
+					message Nested { enum NestedEnum { NESTED_ENUM_ZERO = 0; } }
+					extend bar.B { optional string nested_ext = 2; }
+				}
+				enum E { E_ZERO = 0; }
+				service S { rpc M(A) returns (A); }
+				extend bar.B { optional string ext = 3; }
+			`),
+			"b.proto": []byte(`syntax = "proto2"; package bar; message B { extensions 1 to 10; }`),
+		},
+	)
+
+	symbols, err := request.AllSymbols()
+	require.NoError(t, err)
+	symbolNames := make([]string, len(symbols))
+	for i, symbol := range symbols {
+		symbolNames[i] = string(symbol.FullName())
+	}
+	require.Equal(
+		t,
+		[]string{
+			"foo.A",
+			"foo.A.Nested",
+			"foo.A.Nested.NestedEnum",
+			"foo.A.nested_ext",
+			"foo.E",
+			"foo.S",
+			"foo.ext",
+			"bar.B",
+		},
+		symbolNames,
+	)
+
+	descriptor, err := request.FindDescriptorByName("foo.A.Nested")
+	require.NoError(t, err)
+	_, ok := descriptor.(protoreflect.MessageDescriptor)
+	require.True(t, ok)
+	descriptor, err = request.FindDescriptorByName("foo.S.M")
+	require.NoError(t, err)
+	_, ok = descriptor.(protoreflect.MethodDescriptor)
+	require.True(t, ok)
+	_, err = request.FindDescriptorByName("foo.Missing")
+	require.ErrorIs(t, err, protoregistry.NotFound)
+}
+
+func TestRequestFileDescriptorProtosUnsafe(t *testing.T) {
+	t.Parallel()
+
+	request := testNewRequest(
+		t,
+		[]string{"a.proto"},
+		map[string][]byte{
+			"a.proto": []byte(`syntax = "proto3"; package foo; import "b.proto"; message A { bar.B b = 1; }`),
+			"b.proto": []byte(`syntax = "proto3"; package bar; message B {}`),
+		},
+	)
+
+	fileDescriptorProtosToGenerate := request.FileDescriptorProtosToGenerateUnsafe()
+	require.Equal(t, request.FileDescriptorProtosToGenerate(), fileDescriptorProtosToGenerate)
+	require.Len(t, fileDescriptorProtosToGenerate, 1)
+	require.Equal(t, "a.proto", fileDescriptorProtosToGenerate[0].GetName())
+	// The same slice is returned on every call.
+	require.Same(t, &fileDescriptorProtosToGenerate[0], &request.FileDescriptorProtosToGenerateUnsafe()[0])
+
+	allFileDescriptorProtos := request.AllFileDescriptorProtosUnsafe()
+	require.Equal(t, request.AllFileDescriptorProtos(), allFileDescriptorProtos)
+	require.Len(t, allFileDescriptorProtos, 2)
+	require.Same(t, &allFileDescriptorProtos[0], &request.AllFileDescriptorProtosUnsafe()[0])
+
+	// The copying accessors return new slices that can be modified without affecting the cached values.
+	copied := request.AllFileDescriptorProtos()
+	copied[0] = nil
+	require.NotNil(t, request.AllFileDescriptorProtosUnsafe()[0])
+}
+
+func TestRequestFileToGenerateOrder(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	fileDescriptorProtos, err := compile(
+		ctx,
+		map[string][]byte{
+			"a.proto": []byte(`syntax = "proto3"; package foo; import "b.proto"; message A { B b = 1; }`),
+			"b.proto": []byte(`syntax = "proto3"; package foo; message B {}`),
+		},
+	)
+	require.NoError(t, err)
+	// Order proto_file such that it differs from file_to_generate.
+	if fileDescriptorProtos[0].GetName() != "b.proto" {
+		fileDescriptorProtos[0], fileDescriptorProtos[1] = fileDescriptorProtos[1], fileDescriptorProtos[0]
+	}
+	codeGeneratorRequest := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate:        []string{"a.proto", "b.proto"},
+		ProtoFile:             fileDescriptorProtos,
+		SourceFileDescriptors: fileDescriptorProtos,
+	}
+
+	getNames := func(request Request) []string {
+		var names []string
+		for _, fileDescriptorProto := range request.FileDescriptorProtosToGenerate() {
+			names = append(names, fileDescriptorProto.GetName())
+		}
+		var fileDescriptorNames []string
+		fileDescriptors, err := request.FileDescriptorsToGenerate()
+		require.NoError(t, err)
+		for _, fileDescriptor := range fileDescriptors {
+			fileDescriptorNames = append(fileDescriptorNames, fileDescriptor.Path())
+		}
+		// FileDescriptorsToGenerate is always in file_to_generate order.
+		require.Equal(t, []string{"a.proto", "b.proto"}, fileDescriptorNames)
+		return names
+	}
+	invoke := func(options ...RunOption) {
+		_, err := Invoke(
+			ctx,
+			HandlerFunc(func(_ context.Context, _ PluginEnv, _ ResponseWriter, request Request) error {
+				expectedNames := []string{"b.proto", "a.proto"}
+				if request.hasFileToGenerateOrder() {
+					expectedNames = []string{"a.proto", "b.proto"}
+				}
+				require.Equal(t, expectedNames, getNames(request))
+				sourceRetentionRequest, err := request.WithSourceRetentionOptions()
+				require.NoError(t, err)
+				require.Equal(t, expectedNames, getNames(sourceRetentionRequest))
+				subRequest, err := NewSubRequest(request, []string{"a.proto", "b.proto"}, "")
+				require.NoError(t, err)
+				require.Equal(t, expectedNames, getNames(subRequest))
+				require.Equal(t, 0, request.IndexOfFileToGenerate("a.proto"))
+				require.Equal(t, 1, request.IndexOfFileToGenerate("b.proto"))
+				require.Equal(t, -1, request.IndexOfFileToGenerate("c.proto"))
+				return nil
+			}),
+			codeGeneratorRequest,
+			options...,
+		)
+		require.NoError(t, err)
+	}
+	invoke()
+	invoke(WithFileToGenerateOrder())
+}
+
+func TestRequestConcurrentAccess(t *testing.T) {
+	t.Parallel()
+
+	fileDescriptorProtos, err := compile(
+		context.Background(),
+		map[string][]byte{
+			"a.proto": []byte(`syntax = "proto3"; package foo; import "b.proto"; message A { bar.B b = 1; }`),
+			"b.proto": []byte(`syntax = "proto3"; package bar; message B { enum E { E_ZERO = 0; } }`),
+		},
+	)
+	require.NoError(t, err)
+	var sourceFileDescriptors []*descriptorpb.FileDescriptorProto
+	for _, fileDescriptorProto := range fileDescriptorProtos {
+		if fileDescriptorProto.GetName() == "a.proto" {
+			sourceFileDescriptors = append(sourceFileDescriptors, fileDescriptorProto)
+		}
+	}
+	request, err := NewRequest(
+		&pluginpb.CodeGeneratorRequest{
+			FileToGenerate:        []string{"a.proto"},
+			ProtoFile:             fileDescriptorProtos,
+			SourceFileDescriptors: sourceFileDescriptors,
+		},
+	)
+	require.NoError(t, err)
+
+	// This test is intended to be run with the race detector enabled. All goroutines share
+	// the same Requests, so that the lazily-computed cached values are raced on first use.
+	const numGoroutines = 16
+	var waitGroup sync.WaitGroup
+	errs := make([]error, numGoroutines)
+	for i := 0; i < numGoroutines; i++ {
+		i := i
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			errs[i] = testRequestAccessAll(request)
+		}()
+	}
+	waitGroup.Wait()
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+}
+
+func testNewRequest(
+	t *testing.T,
+	fileToGenerate []string,
+	pathToData map[string][]byte,
+) Request {
+	fileDescriptorProtos, err := compile(context.Background(), pathToData)
+	require.NoError(t, err)
+	request, err := NewRequest(
+		&pluginpb.CodeGeneratorRequest{
+			FileToGenerate: fileToGenerate,
+			ProtoFile:      fileDescriptorProtos,
+		},
+	)
+	require.NoError(t, err)
+	return request
+}
+
+// testRequestAccessAll calls every accessor on the Request, and on the Request returned by
+// WithSourceRetentionOptions, returning an error if any accessor fails or returns unexpected results.
+func testRequestAccessAll(request Request) error {
+	sourceRetentionRequest, err := request.WithSourceRetentionOptions()
+	if err != nil {
+		return err
+	}
+	for _, request := range []Request{request, sourceRetentionRequest} {
+		fileDescriptors, err := request.FileDescriptorsToGenerate()
+		if err != nil {
+			return err
+		}
+		if len(fileDescriptors) != 1 || fileDescriptors[0].Path() != "a.proto" {
+			return fmt.Errorf("unexpected FileDescriptorsToGenerate: %v", fileDescriptors)
+		}
+		files, err := request.AllFiles()
+		if err != nil {
+			return err
+		}
+		if numFiles := files.NumFiles(); numFiles != 2 {
+			return fmt.Errorf("expected 2 files from AllFiles but got %d", numFiles)
+		}
+		if numFiles := len(request.FileDescriptorProtosToGenerate()); numFiles != 1 {
+			return fmt.Errorf("expected 1 file from FileDescriptorProtosToGenerate but got %d", numFiles)
+		}
+		if numFiles := len(request.FileDescriptorProtosToGenerateUnsafe()); numFiles != 1 {
+			return fmt.Errorf("expected 1 file from FileDescriptorProtosToGenerateUnsafe but got %d", numFiles)
+		}
+		if numFiles := len(request.AllFileDescriptorProtos()); numFiles != 2 {
+			return fmt.Errorf("expected 2 files from AllFileDescriptorProtos but got %d", numFiles)
+		}
+		if numFiles := len(request.AllFileDescriptorProtosUnsafe()); numFiles != 2 {
+			return fmt.Errorf("expected 2 files from AllFileDescriptorProtosUnsafe but got %d", numFiles)
+		}
+		if _, err := request.FindDescriptorByName("bar.B.E"); err != nil {
+			return err
+		}
+		symbols, err := request.AllSymbols()
+		if err != nil {
+			return err
+		}
+		if len(symbols) != 3 {
+			return fmt.Errorf("expected 3 symbols but got %d", len(symbols))
+		}
+	}
+	return nil
+}
+
+func TestRequestWithSourceRetentionOptionsIfAvailable(t *testing.T) {
+	t.Parallel()
+
+	fileDescriptorProto := &descriptorpb.FileDescriptorProto{
+		Name:   proto.String("a.proto"),
+		Syntax: proto.String("proto3"),
+	}
+	stderr := bytes.NewBuffer(nil)
+	var available []bool
+	handler := HandlerFunc(
+		func(_ context.Context, _ PluginEnv, _ ResponseWriter, request Request) error {
+			for i := 0; i < 2; i++ {
+				sourceRetentionRequest, ok := request.WithSourceRetentionOptionsIfAvailable()
+				require.Equal(t, ok, sourceRetentionRequest.hasSourceRetentionOptions())
+				available = append(available, ok)
+			}
+			return nil
+		},
+	)
+
+	// Compilers that do not populate source_file_descriptors result in a single warning.
+	_, err := ExecuteHandler(
+		context.Background(),
+		PluginEnv{Stderr: stderr},
+		handler,
+		&pluginpb.CodeGeneratorRequest{
+			FileToGenerate:  []string{"a.proto"},
+			ProtoFile:       []*descriptorpb.FileDescriptorProto{fileDescriptorProto},
+			CompilerVersion: &pluginpb.Version{Major: proto.Int32(3), Minor: proto.Int32(20), Patch: proto.Int32(0)},
+		},
+	)
+	require.NoError(t, err)
+	require.Equal(t, []bool{false, false}, available)
+	require.Equal(
+		t,
+		"warning: source_file_descriptors not set on CodeGeneratorRequest but source-retention options requested (compiler version 3.20.0) - you likely need to upgrade your protobuf compiler\n",
+		stderr.String(),
+	)
+
+	available = nil
+	stderr.Reset()
+	_, err = ExecuteHandler(
+		context.Background(),
+		PluginEnv{Stderr: stderr},
+		handler,
+		&pluginpb.CodeGeneratorRequest{
+			FileToGenerate:        []string{"a.proto"},
+			ProtoFile:             []*descriptorpb.FileDescriptorProto{fileDescriptorProto},
+			SourceFileDescriptors: []*descriptorpb.FileDescriptorProto{fileDescriptorProto},
+		},
+	)
+	require.NoError(t, err)
+	require.Equal(t, []bool{true, true}, available)
+	require.Empty(t, stderr.String())
+
+	// The error from WithSourceRetentionOptions is structured.
+	request, err := NewRequest(
+		&pluginpb.CodeGeneratorRequest{
+			FileToGenerate: []string{"a.proto"},
+			ProtoFile:      []*descriptorpb.FileDescriptorProto{fileDescriptorProto},
+		},
+	)
+	require.NoError(t, err)
+	_, err = request.WithSourceRetentionOptions()
+	sourceRetentionOptionsUnavailableError := &SourceRetentionOptionsUnavailableError{}
+	require.ErrorAs(t, err, &sourceRetentionOptionsUnavailableError)
+	require.Nil(t, sourceRetentionOptionsUnavailableError.CompilerVersion)
+}
+
+func TestRequestEditions(t *testing.T) {
+	t.Parallel()
+
+	request := testNewRequest(
+		t,
+		[]string{"a.proto", "b.proto"},
+		map[string][]byte{
+			"a.proto": []byte(`syntax = "proto3"; package foo;`),
+			"b.proto": []byte(`syntax = "proto2"; package foo;`),
+		},
+	)
+	require.Nil(t, request.Editions())
+	require.False(t, request.RequiresEditionSupport())
+
+	request = testNewRequest(
+		t,
+		[]string{"a.proto", "b.proto", "c.proto"},
+		map[string][]byte{
+			"a.proto": []byte(`edition = "2023"; package foo;`),
+			"b.proto": []byte(`syntax = "proto3"; package foo;`),
+			"c.proto": []byte(`edition = "2023"; package foo; message C {}`),
+			"d.proto": []byte(`edition = "2023"; package bar;`),
+		},
+	)
+	require.Equal(t, []descriptorpb.Edition{descriptorpb.Edition_EDITION_2023}, request.Editions())
+	require.True(t, request.RequiresEditionSupport())
+
+	request, err := NewRequest(
+		&pluginpb.CodeGeneratorRequest{
+			FileToGenerate: []string{"a.proto", "b.proto", "c.proto"},
+			ProtoFile: []*descriptorpb.FileDescriptorProto{
+				{
+					Name:    proto.String("a.proto"),
+					Syntax:  proto.String("editions"),
+					Edition: descriptorpb.Edition_EDITION_2024.Enum(),
+				},
+				{
+					Name:    proto.String("b.proto"),
+					Syntax:  proto.String("editions"),
+					Edition: descriptorpb.Edition_EDITION_2023.Enum(),
+				},
+				{
+					Name:    proto.String("c.proto"),
+					Syntax:  proto.String("editions"),
+					Edition: descriptorpb.Edition_EDITION_2024.Enum(),
+				},
+			},
+		},
+	)
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		[]descriptorpb.Edition{descriptorpb.Edition_EDITION_2023, descriptorpb.Edition_EDITION_2024},
+		request.Editions(),
+	)
+}
+
+func TestRequestFiles(t *testing.T) {
+	t.Parallel()
+
+	request := testNewRequest(
+		t,
+		[]string{"b.proto", "a.proto"},
+		map[string][]byte{
+			"a.proto": []byte(`syntax = "proto3"; package foo; import "c.proto"; message A { bar.C c = 1; }`),
+			"b.proto": []byte(`syntax = "proto3"; package foo; message B {}`),
+			"c.proto": []byte(`syntax = "proto3"; package bar; message C {}`),
+		},
+	)
+
+	filesToGenerate, err := request.FilesToGenerate()
+	require.NoError(t, err)
+	fileDescriptorProtosToGenerate := request.FileDescriptorProtosToGenerateUnsafe()
+	require.Len(t, filesToGenerate, len(fileDescriptorProtosToGenerate))
+	for i, file := range filesToGenerate {
+		require.Same(t, fileDescriptorProtosToGenerate[i], file.FileDescriptorProto)
+		require.Equal(t, file.FileDescriptorProto.GetName(), file.FileDescriptor.Path())
+		require.True(t, file.Generate)
+	}
+
+	files, err := request.Files()
+	require.NoError(t, err)
+	allFileDescriptorProtos := request.AllFileDescriptorProtosUnsafe()
+	require.Len(t, files, len(allFileDescriptorProtos))
+	generated := make(map[string]bool)
+	for i, file := range files {
+		require.Same(t, allFileDescriptorProtos[i], file.FileDescriptorProto)
+		require.Equal(t, file.FileDescriptorProto.GetName(), file.FileDescriptor.Path())
+		generated[file.FileDescriptor.Path()] = file.Generate
+	}
+	require.Equal(t, map[string]bool{"a.proto": true, "b.proto": true, "c.proto": false}, generated)
+}
@@ -0,0 +1,137 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// ResponseFileBuilder builds a validated CodeGeneratorResponse.File.
+//
+// Create a ResponseFileBuilder with NewResponseFile, for example:
+//
+//	file, err := protoplugin.NewResponseFile("foo/bar.pb.go").
+//	  WithContent(content).
+//	  WithGeneratedCodeInfo(generatedCodeInfo).
+//	  Build()
+//	if err != nil {
+//	  return err
+//	}
+//	responseWriter.AddCodeGeneratorResponseFiles(file)
+//
+// This validates the file when it is built, instead of when ResponseWriter.ToCodeGeneratorResponse is called.
+type ResponseFileBuilder struct {
+	name              string
+	content           *string
+	insertionPoint    *string
+	generatedCodeInfo *descriptorpb.GeneratedCodeInfo
+}
+
+// NewResponseFile returns a new ResponseFileBuilder for a file with the given name.
+func NewResponseFile(name string) *ResponseFileBuilder {
+	return &ResponseFileBuilder{
+		name: name,
+	}
+}
+
+// WithContent sets the content of the file.
+func (b *ResponseFileBuilder) WithContent(content string) *ResponseFileBuilder {
+	b.content = proto.String(content)
+	return b
+}
+
+// WithInsertionPoint sets the insertion point of the file.
+//
+// See the documentation for CodeGeneratorResponse.File.insertion_point for more details.
+func (b *ResponseFileBuilder) WithInsertionPoint(insertionPoint string) *ResponseFileBuilder {
+	b.insertionPoint = proto.String(insertionPoint)
+	return b
+}
+
+// WithGeneratedCodeInfo sets the GeneratedCodeInfo of the file.
+//
+// The GeneratedCodeInfo is not copied.
+func (b *ResponseFileBuilder) WithGeneratedCodeInfo(generatedCodeInfo *descriptorpb.GeneratedCodeInfo) *ResponseFileBuilder {
+	b.generatedCodeInfo = generatedCodeInfo
+	return b
+}
+
+// Build validates and returns a new CodeGeneratorResponse.File.
+//
+// The following is validated:
+//
+//   - The name is non-empty, relative, uses '/' as the path separator, and is equal to
+//     filepath.ToSlash(filepath.Clean(name)).
+//   - If set, the insertion point is non-empty and does not contain ')'.
+//   - If set, the GeneratedCodeInfo annotations have valid begin and end offsets within the content.
+//
+// Build can be called multiple times, and each call returns a new CodeGeneratorResponse.File.
+func (b *ResponseFileBuilder) Build() (*pluginpb.CodeGeneratorResponse_File, error) {
+	if err := b.validate(); err != nil {
+		return nil, fmt.Errorf("CodeGeneratorResponse.File: %w", err)
+	}
+	file := &pluginpb.CodeGeneratorResponse_File{
+		Name:              proto.String(b.name),
+		GeneratedCodeInfo: b.generatedCodeInfo,
+	}
+	if b.content != nil {
+		file.Content = proto.String(*b.content)
+	}
+	if b.insertionPoint != nil {
+		file.InsertionPoint = proto.String(*b.insertionPoint)
+	}
+	return file, nil
+}
+
+// *** PRIVATE ***
+
+func (b *ResponseFileBuilder) validate() error {
+	if err := validateAndCheckPathIsNormalized("name", b.name); err != nil {
+		return err
+	}
+	if b.insertionPoint != nil {
+		insertionPoint := *b.insertionPoint
+		if insertionPoint == "" {
+			return errors.New("insertion_point: empty")
+		}
+		if strings.Contains(insertionPoint, ")") {
+			return fmt.Errorf("insertion_point: %q should not contain ')'", insertionPoint)
+		}
+	}
+	var contentLength int
+	if b.content != nil {
+		contentLength = len(*b.content)
+	}
+	for i, annotation := range b.generatedCodeInfo.GetAnnotation() {
+		begin := int(annotation.GetBegin())
+		end := int(annotation.GetEnd())
+		if begin < 0 || end < begin || end > contentLength {
+			return fmt.Errorf(
+				"generated_code_info.annotation[%d]: invalid offsets [%d, %d) for content of length %d",
+				i,
+				begin,
+				end,
+				contentLength,
+			)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestResponseFileBuilder(t *testing.T) {
+	t.Parallel()
+
+	generatedCodeInfo := &descriptorpb.GeneratedCodeInfo{
+		Annotation: []*descriptorpb.GeneratedCodeInfo_Annotation{
+			{
+				SourceFile: proto.String("a.proto"),
+				Begin:      proto.Int32(0),
+				End:        proto.Int32(3),
+			},
+		},
+	}
+	file, err := NewResponseFile("a/b.txt").
+		WithContent("foo").
+		WithInsertionPoint("point").
+		WithGeneratedCodeInfo(generatedCodeInfo).
+		Build()
+	require.NoError(t, err)
+	require.Empty(
+		t,
+		cmp.Diff(
+			&pluginpb.CodeGeneratorResponse_File{
+				Name:              proto.String("a/b.txt"),
+				InsertionPoint:    proto.String("point"),
+				Content:           proto.String("foo"),
+				GeneratedCodeInfo: generatedCodeInfo,
+			},
+			file,
+			protocmp.Transform(),
+		),
+	)
+
+	file, err = NewResponseFile("a.txt").Build()
+	require.NoError(t, err)
+	require.Nil(t, file.Content)
+	require.Nil(t, file.InsertionPoint)
+
+	for _, testCase := range []struct {
+		name          string
+		builder       *ResponseFileBuilder
+		expectedError string
+	}{
+		{
+			name:          "empty_name",
+			builder:       NewResponseFile(""),
+			expectedError: "CodeGeneratorResponse.File: name: path was empty",
+		},
+		{
+			name:          "unnormalized_name",
+			builder:       NewResponseFile("./a.txt"),
+			expectedError: `CodeGeneratorResponse.File: name: path "./a.txt" to be given as "a.txt"`,
+		},
+		{
+			name:          "empty_insertion_point",
+			builder:       NewResponseFile("a.txt").WithInsertionPoint(""),
+			expectedError: "CodeGeneratorResponse.File: insertion_point: empty",
+		},
+		{
+			name:          "invalid_insertion_point",
+			builder:       NewResponseFile("a.txt").WithInsertionPoint("foo)"),
+			expectedError: `CodeGeneratorResponse.File: insertion_point: "foo)" should not contain ')'`,
+		},
+		{
+			name:          "invalid_annotation",
+			builder:       NewResponseFile("a.txt").WithContent("fo").WithGeneratedCodeInfo(generatedCodeInfo),
+			expectedError: "CodeGeneratorResponse.File: generated_code_info.annotation[0]: invalid offsets [0, 3) for content of length 2",
+		},
+	} {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := testCase.builder.Build()
+			require.EqualError(t, err, testCase.expectedError)
+		})
+	}
+}
@@ -15,7 +15,10 @@
 package protoplugin
 
 import (
+	"encoding/base64"
 	"errors"
+	"fmt"
+	"runtime/debug"
 	"sync"
 
 	"google.golang.org/protobuf/proto"
@@ -23,6 +26,10 @@ import (
 	"google.golang.org/protobuf/types/pluginpb"
 )
 
+// BinaryFileBase64SidecarSuffix is the suffix added to the names of files added via AddBinaryFile
+// if ResponseWriterWithBase64BinaryFiles is used.
+const BinaryFileBase64SidecarSuffix = ".base64"
+
 // ResponseWriter is used by implementations of Handler to construct CodeGeneratorResponses.
 //
 // ResponseWriter contains a private method to ensure that it is not constructed outside this package, to
@@ -38,6 +45,17 @@ type ResponseWriter interface {
 	//
 	// If a file with the same name was already added, or the file name is not cleaned, a warning will be produced.
 	AddFile(name string, content string)
+	// AddBinaryFile adds the file with the given binary data to the response.
+	//
+	// CodeGeneratorResponse.File.content is a string field, however plugin.proto uses proto2 syntax, so the
+	// field is not validated to be UTF-8 by protoc, buf, or google.golang.org/protobuf, and arbitrary bytes survive
+	// serialization unmodified. protoc and buf write the bytes to disk as-is. Other consumers of
+	// CodeGeneratorResponses may not handle non-UTF-8 content - for these, use ResponseWriterWithBase64BinaryFiles.
+	//
+	// Binary files are exempt from UTF-8 validation and line ending normalization.
+	//
+	// Otherwise, this has the same semantics as AddFile.
+	AddBinaryFile(name string, data []byte)
 	// AddError adds the error message on the response.
 	//
 	// If there is an error with the actual input .proto files that results in your plugin's business logic not being able to be executed
@@ -47,6 +65,20 @@ type ResponseWriter interface {
 	// If there is an existing error message already added, the new error will be appended.
 	// Note that empty error messages will be ignored (ie it will be as if no error was set).
 	AddError(message string)
+	// AddErrorf adds the formatted error message on the response.
+	//
+	// This has the same semantics as AddError, with the message formatted via fmt.Sprintf.
+	AddErrorf(format string, args ...any)
+	// AddErrorWithLocation adds the error message on the response, prefixed with the given location.
+	//
+	// The error message will be formatted in the same manner as protoc formats errors, that is
+	// "file.proto:LINE:COLUMN: message". The span is a span from a SourceCodeInfo.Location, that is a zero-based
+	// [startLine, startColumn, endLine, endColumn] or [startLine, startColumn, endColumn]. The line and column will
+	// be outputted as one-based values, as is expected by editors and other tooling. If the span has less than two
+	// elements, the error message will be formatted as "file.proto: message".
+	//
+	// Otherwise, this has the same semantics as AddError.
+	AddErrorWithLocation(file string, span []int32, message string)
 	// SetFeatureProto3Optional sets the FEATURE_PROTO3_OPTIONAL feature on the response.
 	//
 	// This function should be preferred over SetSupportedFeatures. Use SetSupportedFeatures only if you need low-level access.
@@ -77,6 +109,23 @@ type ResponseWriter interface {
 	//
 	// If a file with the same name was already added, or the file name is not cleaned, a warning will be produced.
 	AddCodeGeneratorResponseFiles(files ...*pluginpb.CodeGeneratorResponse_File)
+	// FileCount returns the number of files that have been added to the response so far.
+	//
+	// Every CodeGeneratorResponse.File is counted, including files with insertion points. This allows
+	// Handlers to detect that they generated no files, for example because nothing matched a filter,
+	// and decide how to report this. See also WithEmptyResponsePolicy.
+	FileCount() int
+	// MarkExecutable marks the file with the given name as executable, for example for generated scripts.
+	//
+	// CodeGeneratorResponses have no concept of file permissions, so this is a convention: the names of all
+	// files marked executable are listed in a manifest file named ExecutableFilesManifestFileName, which is added
+	// to the response. See ExecutableFilesManifestFileName for the behavior of consumers. If no files are marked
+	// executable, no manifest is added.
+	//
+	// The file may be added before or after it is marked executable. If the file is not added without an insertion
+	// point by the time ToCodeGeneratorResponse is called, ToCodeGeneratorResponse will return a
+	// *ResponseValidationError.
+	MarkExecutable(name string)
 	// SetSupportedFeatures the given features on the response.
 	//
 	// You likely want to use the specific feature functions instead of this function.
@@ -110,10 +159,50 @@ type ResponseWriter interface {
 	// Most users of this library will not need to call this function. This function is only used if you are
 	// invoking Handlers outside of Main or Run.
 	//
-	// This function can be called exactly once. Future calls to this function will result in an error.
+	// If the CodeGeneratorResponse is invalid, a *ResponseValidationError is returned.
+	//
+	// This function can be called exactly once. Future calls to this function will result in an error,
+	// unless Reset is called.
+	//
+	// This seals the ResponseWriter. See Sealed for more details.
 	ToCodeGeneratorResponse() (*pluginpb.CodeGeneratorResponse, error)
+	// PeekCodeGeneratorResponse creates a CodeGeneratorResponse from the values currently written to the ResponseWriter,
+	// without finalizing the ResponseWriter.
+	//
+	// The returned CodeGeneratorResponse is a deep copy that has been validated and normalized in the same manner as
+	// ToCodeGeneratorResponse, and can be freely modified by the caller. Warnings that would be produced by lenient
+	// validation are not produced, as they will be produced when ToCodeGeneratorResponse is called.
+	//
+	// This function can be called any number of times, including after ToCodeGeneratorResponse has been called.
+	// This is intended for wrappers and tests that want to inspect a response before it is finalized.
+	PeekCodeGeneratorResponse() (*pluginpb.CodeGeneratorResponse, error)
+	// Reset resets the ResponseWriter to its initial state, as if it was just returned from NewResponseWriter.
+	//
+	// All files, errors, features, and editions are cleared, and ToCodeGeneratorResponse may be called again.
+	// The ResponseWriterOptions the ResponseWriter was created with are retained.
+	//
+	// This allows long-running processes that handle many requests to reuse ResponseWriters. Reset must not be
+	// called while a Handler is still using the ResponseWriter, and CodeGeneratorResponses previously returned from
+	// ToCodeGeneratorResponse are not affected by Reset.
+	//
+	// This unseals the ResponseWriter.
+	Reset()
+	// Sealed returns true if the ResponseWriter has been sealed.
+	//
+	// A ResponseWriter is sealed when ToCodeGeneratorResponse is called, and when invoked via Main, Run, Invoke, or
+	// ExecuteHandler, as soon as the Handler returns. All calls that write to a sealed ResponseWriter, such as AddFile,
+	// AddError, and SetSupportedFeatures, are dropped, as they would otherwise race with the creation of the
+	// CodeGeneratorResponse. These calls almost always come from goroutines started by a Handler that were not
+	// waited on before the Handler returned.
+	//
+	// The first such call is recorded as an error that includes the method called and the stack of the caller. If
+	// ToCodeGeneratorResponse has not yet been called, ToCodeGeneratorResponse will return this error. Otherwise, the
+	// error is given to the function specified by ResponseWriterWithSealedWriteFunc, if any. When invoked via Main
+	// or Run, the error is written to stderr by default.
+	Sealed() bool
 
 	isResponseWriter()
+	seal()
 }
 
 // NewResponseWriter returns a new ResponseWriter.
@@ -159,24 +248,293 @@ func ResponseWriterWithLenientValidation(lenientValidateErrorFunc func(error)) R
 	}
 }
 
+// ResponseWriterWithIdenticalDuplicateDeduplication returns a new ResponseWriterOption that says to silently
+// deduplicate files without insertion points that have the same name and identical content.
+//
+// This is useful for plugins that generate the same file multiple times, for example per-service
+// files keyed by shared types. Only the first occurrence of the file is kept.
+//
+// Files with the same name but different content are still handled as duplicates, that is they will
+// result in an error, or a warning if lenient validation is enabled.
+//
+// The default is to treat all files with duplicate names as duplicates.
+func ResponseWriterWithIdenticalDuplicateDeduplication() ResponseWriterOption {
+	return func(responseWriter *responseWriter) {
+		responseWriter.deduplicateIdenticalFiles = true
+	}
+}
+
+// ResponseWriterWithAdditionalSupportedFeatureBits returns a new ResponseWriterOption that says to accept the
+// given bits within supported_features, in addition to the CodeGeneratorResponse.Features known to this package.
+//
+// By default, any bit within supported_features that does not correspond to a CodeGeneratorResponse.Feature
+// known to this package results in a *ResponseValidationError. This protects against setting garbage bits,
+// but also means that plugins cannot declare a feature that was added to plugin.proto after the version
+// of google.golang.org/protobuf used by this package. This option allows such features to be declared
+// without waiting on a new release, for example:
+//
+//	// FEATURE_SUPPORTS_FOO = 4 was added to plugin.proto.
+//	ResponseWriterWithAdditionalSupportedFeatureBits(1 << 2)
+//
+// This option can be specified multiple times, in which case the bits are combined. Unknown bits that
+// are not specified are still rejected.
+func ResponseWriterWithAdditionalSupportedFeatureBits(bits uint64) ResponseWriterOption {
+	return func(responseWriter *responseWriter) {
+		responseWriter.additionalSupportedFeatureBits |= bits
+	}
+}
+
+// ResponseWriterWithPathRewriter returns a new ResponseWriterOption that rewrites the names of all files
+// when they are added to the ResponseWriter.
+//
+// The function is called with the name given to AddFile, AddBinaryFile, AddCodeGeneratorResponseFiles, or
+// MarkExecutable, and the returned name is used instead. This allows wrappers to implement output layouts,
+// such as source-relative or package-based directory trees, centrally for Handlers that were not written
+// with a configurable output layout. Names of files with insertion points are rewritten as well, so the
+// function should map a given name to the same result on every call. Files with no name, which continue
+// the previous file, are not rewritten.
+//
+// Rewritten names are subject to the same validation as all other names. If the function returns an error,
+// the file is dropped, and ToCodeGeneratorResponse returns a *ResponseValidationError wrapping the error.
+// Within AddCodeGeneratorResponseFiles, only the failing file and its continuations are dropped, and the
+// remaining files are still added.
+//
+// The function may be called concurrently if the ResponseWriter is used by multiple goroutines. The function
+// must not call methods on the ResponseWriter.
+//
+// The default is to not rewrite names.
+func ResponseWriterWithPathRewriter(pathRewriter func(name string) (string, error)) ResponseWriterOption {
+	return func(responseWriter *responseWriter) {
+		responseWriter.pathRewriter = pathRewriter
+	}
+}
+
+// ResponseWriterWithUTF8Validation returns a new ResponseWriterOption that validates that the content of all
+// files is valid UTF-8.
+//
+// The CodeGeneratorResponse.File content field is a string, and protoc and buf expect it to contain valid UTF-8.
+// If content is not valid UTF-8, ToCodeGeneratorResponse will return a *ResponseValidationError. If lenient validation
+// is enabled, a warning is produced instead and invalid byte sequences are replaced with the Unicode replacement
+// character.
+//
+// The default is to not validate content.
+func ResponseWriterWithUTF8Validation() ResponseWriterOption {
+	return func(responseWriter *responseWriter) {
+		responseWriter.validateUTF8 = true
+	}
+}
+
+// ResponseWriterWithLineEndings returns a new ResponseWriterOption that normalizes the line endings of the
+// content of all files.
+//
+// The line ending for a file is determined by the extension of its name, as given by path.Ext, for example
+// ".bat". If the extension is not present in extensionToLineEnding, defaultLineEnding is used.
+//
+// The default is to preserve line endings.
+func ResponseWriterWithLineEndings(defaultLineEnding LineEnding, extensionToLineEnding map[string]LineEnding) ResponseWriterOption {
+	return func(responseWriter *responseWriter) {
+		responseWriter.defaultLineEnding = defaultLineEnding
+		responseWriter.extensionToLineEnding = extensionToLineEnding
+	}
+}
+
+// ResponseWriterWithBase64BinaryFiles returns a new ResponseWriterOption that says to encode files added via
+// AddBinaryFile as base64 sidecar files.
+//
+// Instead of adding the file with the raw bytes, a file with the name suffixed with BinaryFileBase64SidecarSuffix
+// is added, containing the standard base64 encoding of the bytes. This is a convention for consumers that cannot
+// handle non-UTF-8 content within CodeGeneratorResponses, which are expected to decode the sidecar files and
+// write the decoded bytes to the name without the suffix.
+//
+// The default is to add binary files with their raw bytes.
+func ResponseWriterWithBase64BinaryFiles() ResponseWriterOption {
+	return func(responseWriter *responseWriter) {
+		responseWriter.base64BinaryFiles = true
+	}
+}
+
+// ResponseWriterWithLimits returns a new ResponseWriterOption that enforces limits on the files added to the
+// ResponseWriter.
+//
+// The limits are:
+//
+//   - maxFiles: The maximum number of CodeGeneratorResponse.Files, including files with insertion points.
+//   - maxTotalBytes: The maximum total size of the content of all files in bytes.
+//   - maxFileBytes: The maximum size of the content of any single file in bytes.
+//
+// A value of zero or less for any limit means that the limit is not enforced.
+//
+// The limits are checked as files are added. Once any limit is exceeded, the content of all subsequently added
+// files is discarded to bound memory usage, and ToCodeGeneratorResponse will return a *ResponseValidationError
+// identifying the first file that exceeded a limit.
+//
+// This is useful to protect CI systems from runaway generators.
+//
+// The default is to not enforce any limits.
+func ResponseWriterWithLimits(maxFiles int, maxTotalBytes int64, maxFileBytes int64) ResponseWriterOption {
+	return func(responseWriter *responseWriter) {
+		responseWriter.maxFiles = maxFiles
+		responseWriter.maxTotalBytes = maxTotalBytes
+		responseWriter.maxFileBytes = maxFileBytes
+	}
+}
+
+// ResponseWriterWithFileNamePortabilityCheck returns a new ResponseWriterOption that checks that the names of all
+// files can be written to all common filesystems.
+//
+// The following issues are checked:
+//
+//   - File names, or the directories that files are contained within, that differ only by case, for example
+//     "Foo.java" and "foo.java", or "Foo/a.txt" and "foo/b.txt". These collide on case-insensitive filesystems
+//     such as the defaults on macOS and Windows.
+//   - File or directory names that are reserved on Windows, such as "CON", "nul", or "aux.txt", regardless of
+//     case or extension.
+//   - File or directory names that end with a dot or space, which Windows removes.
+//   - File or directory names longer than 255 bytes, which is the limit on common filesystems.
+//   - File names longer than maxFileNameLength bytes. The name is relative to the output directory, so plugins
+//     should leave room for the output directory when choosing a limit. A value of zero or less means that the
+//     length of names is not checked.
+//
+// If warningFunc is nil, ToCodeGeneratorResponse will return a *ResponseValidationError on the first issue.
+// Otherwise, each issue is given to warningFunc and generation continues. Lenient validation has no effect on this
+// check.
+//
+// Files with insertion points are not checked, as they refer to files that already exist.
+//
+// The default is to not check file names for portability.
+func ResponseWriterWithFileNamePortabilityCheck(maxFileNameLength int, warningFunc func(error)) ResponseWriterOption {
+	return func(responseWriter *responseWriter) {
+		responseWriter.fileNamePortabilityChecker = &fileNamePortabilityChecker{
+			maxFileNameLength: maxFileNameLength,
+			warningFunc:       warningFunc,
+		}
+	}
+}
+
+// ResponseWriterWithInsertionPointTargetCheck returns a new ResponseWriterOption that checks that every file with
+// an insertion point targets a file that was generated earlier in the response.
+//
+// Plugins that use insertion points almost always intend to target files generated earlier in the same response.
+// Without this check, a missing or misnamed target only fails later within the compiler, with an error that
+// does not identify the plugin at fault. Files that are generated by other plugins, and therefore are not part
+// of the response, can be declared with externalTargetNames.
+//
+// If warningFunc is nil, ToCodeGeneratorResponse will return a *ResponseValidationError on the first issue.
+// Otherwise, each issue is given to warningFunc and generation continues. Lenient validation has no effect on this
+// check.
+//
+// The default is to not check the targets of insertion points.
+func ResponseWriterWithInsertionPointTargetCheck(externalTargetNames []string, warningFunc func(error)) ResponseWriterOption {
+	return func(responseWriter *responseWriter) {
+		responseWriter.insertionPointTargetChecker = newInsertionPointTargetChecker(externalTargetNames, warningFunc)
+	}
+}
+
+// ResponseWriterWithEagerValidation returns a new ResponseWriterOption that says to validate files as they are added,
+// instead of only when ToCodeGeneratorResponse is called.
+//
+// The following issues are detected when AddFile, AddBinaryFile, or AddCodeGeneratorResponseFiles is called:
+//
+//   - Nil CodeGeneratorResponse.Files.
+//   - Invalid file names, or file names that are not equal to filepath.ToSlash(filepath.Clean(name)), unless
+//     lenient validation is enabled.
+//   - Duplicate file names for files without insertion points, unless lenient validation is enabled, or the
+//     file is an identical duplicate and identical duplicate deduplication is enabled.
+//
+// The first issue is recorded along with the file and line of the call that added the offending file, and
+// ToCodeGeneratorResponse will return a *ResponseValidationError for this issue. Subsequently added files are
+// discarded. This makes it possible to identify which call within a Handler added an invalid file, which is
+// otherwise lost by the time ToCodeGeneratorResponse is called.
+//
+// The default is to validate files only when ToCodeGeneratorResponse is called.
+func ResponseWriterWithEagerValidation() ResponseWriterOption {
+	return func(responseWriter *responseWriter) {
+		responseWriter.eagerValidation = true
+	}
+}
+
+// ResponseWriterWithSealedWriteFunc returns a new ResponseWriterOption that says to call the given function with
+// the error produced by the first write to the ResponseWriter after ToCodeGeneratorResponse was called.
+//
+// See ResponseWriter.Sealed for more details. The function must not call any methods on the ResponseWriter.
+//
+// The default is to drop writes after ToCodeGeneratorResponse was called without reporting them.
+func ResponseWriterWithSealedWriteFunc(sealedWriteFunc func(error)) ResponseWriterOption {
+	return func(responseWriter *responseWriter) {
+		responseWriter.sealedWriteFunc = sealedWriteFunc
+	}
+}
+
 // *** PRIVATE ***
 
 type responseWriter struct {
 	codeGeneratorResponse *pluginpb.CodeGeneratorResponse
 	written               bool
 
-	lenientValidateErrorFunc func(error)
+	lenientValidateErrorFunc  func(error)
+	deduplicateIdenticalFiles bool
+	// Bits of supported_features that are valid in addition to the features known to this package.
+	additionalSupportedFeatureBits uint64
+	validateUTF8                   bool
+	base64BinaryFiles              bool
+	defaultLineEnding              LineEnding
+	extensionToLineEnding          map[string]LineEnding
+	// Nil if file names are not checked for portability.
+	fileNamePortabilityChecker *fileNamePortabilityChecker
+	// Nil if the targets of insertion points are not checked.
+	insertionPointTargetChecker *insertionPointTargetChecker
+
+	maxFiles      int
+	maxTotalBytes int64
+	maxFileBytes  int64
+	// The names of all files added via AddBinaryFile.
+	binaryFileNames map[string]struct{}
+	// The total bytes of all content added.
+	totalBytes int64
+	// Non-nil if a limit was exceeded.
+	limitErr error
+
+	eagerValidation bool
+	// Only populated if eagerValidation is true.
+	//
+	// The first file without an insertion point for each name.
+	eagerFileNameToFile map[string]*pluginpb.CodeGeneratorResponse_File
+	// Non-nil if eager validation failed.
+	eagerValidationErr error
+
+	// The names of all files marked executable.
+	executableFileNames map[string]struct{}
+
+	pathRewriter func(string) (string, error)
+	// Non-nil if the path rewriter returned an error.
+	pathRewriteErr error
+
+	sealed          bool
+	sealedWriteFunc func(error)
+	// Non-nil if there was a write after the ResponseWriter was sealed.
+	sealedWriteErr error
 
 	lock sync.RWMutex
 }
 
 func (r *responseWriter) AddFile(name string, content string) {
-	r.AddCodeGeneratorResponseFiles(
-		&pluginpb.CodeGeneratorResponse_File{
-			Name:    proto.String(name),
-			Content: proto.String(content),
-		},
-	)
+	name, ok := r.rewritePath(name)
+	if !ok {
+		return
+	}
+	r.addFile("AddFile", name, content, false)
+}
+
+func (r *responseWriter) AddBinaryFile(name string, data []byte) {
+	name, ok := r.rewritePath(name)
+	if !ok {
+		return
+	}
+	if r.base64BinaryFiles {
+		r.addFile("AddBinaryFile", name+BinaryFileBase64SidecarSuffix, base64.StdEncoding.EncodeToString(data), false)
+		return
+	}
+	r.addFile("AddBinaryFile", name, string(data), true)
 }
 
 func (r *responseWriter) AddError(message string) {
@@ -189,33 +547,101 @@ func (r *responseWriter) AddError(message string) {
 	if message == "" {
 		return
 	}
+	if r.checkSealed("AddError") {
+		return
+	}
 	if existingError := r.codeGeneratorResponse.GetError(); existingError != "" {
 		message = existingError + "; " + message
 	}
 	r.codeGeneratorResponse.Error = proto.String(message)
 }
 
+func (r *responseWriter) AddErrorf(format string, args ...any) {
+	r.AddError(fmt.Sprintf(format, args...))
+}
+
+func (r *responseWriter) AddErrorWithLocation(file string, span []int32, message string) {
+	if message == "" {
+		return
+	}
+	if len(span) < 2 {
+		r.AddError(file + ": " + message)
+		return
+	}
+	r.AddError(fmt.Sprintf("%s:%d:%d: %s", file, span[0]+1, span[1]+1, message))
+}
+
 func (r *responseWriter) SetFeatureProto3Optional() {
-	r.addSupportedFeatures(uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL))
+	r.addSupportedFeatures("SetFeatureProto3Optional", uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL))
 }
 
 func (r *responseWriter) SetFeatureSupportsEditions(minimumEdition descriptorpb.Edition, maximumEdition descriptorpb.Edition) {
-	r.addSupportedFeatures(uint64(pluginpb.CodeGeneratorResponse_FEATURE_SUPPORTS_EDITIONS))
+	r.addSupportedFeatures("SetFeatureSupportsEditions", uint64(pluginpb.CodeGeneratorResponse_FEATURE_SUPPORTS_EDITIONS))
 	r.SetMinimumEdition(int32(minimumEdition))
 	r.SetMaximumEdition(int32(maximumEdition))
 }
 
 func (r *responseWriter) AddCodeGeneratorResponseFiles(files ...*pluginpb.CodeGeneratorResponse_File) {
+	if r.pathRewriter != nil {
+		rewrittenFiles := make([]*pluginpb.CodeGeneratorResponse_File, 0, len(files))
+		// True if the previous named file was dropped, in which case its continuations are dropped as well.
+		var dropped bool
+		for _, file := range files {
+			// Nil files are left for validation, and files with no name continue the previous file.
+			if file.GetName() == "" {
+				if file == nil || !dropped {
+					rewrittenFiles = append(rewrittenFiles, file)
+				}
+				continue
+			}
+			name, ok := r.rewritePath(file.GetName())
+			dropped = !ok
+			if dropped {
+				continue
+			}
+			if name != file.GetName() {
+				// Do not modify the caller's file.
+				file, _ = proto.Clone(file).(*pluginpb.CodeGeneratorResponse_File)
+				file.Name = proto.String(name)
+			}
+			rewrittenFiles = append(rewrittenFiles, file)
+		}
+		files = rewrittenFiles
+	}
+	r.addCodeGeneratorResponseFiles("AddCodeGeneratorResponseFiles", false, files...)
+}
+
+func (r *responseWriter) FileCount() int {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return len(r.codeGeneratorResponse.GetFile())
+}
+
+func (r *responseWriter) MarkExecutable(name string) {
+	name, ok := r.rewritePath(name)
+	if !ok {
+		return
+	}
 	r.lock.Lock()
 	defer r.lock.Unlock()
 
-	r.codeGeneratorResponse.File = append(r.codeGeneratorResponse.GetFile(), files...)
+	if r.checkSealed("MarkExecutable") {
+		return
+	}
+	if r.executableFileNames == nil {
+		r.executableFileNames = make(map[string]struct{})
+	}
+	r.executableFileNames[name] = struct{}{}
 }
 
 func (r *responseWriter) SetSupportedFeatures(supportedFeatures uint64) {
 	r.lock.Lock()
 	defer r.lock.Unlock()
 
+	if r.checkSealed("SetSupportedFeatures") {
+		return
+	}
 	if supportedFeatures == 0 {
 		r.codeGeneratorResponse.SupportedFeatures = nil
 	} else {
@@ -227,6 +653,9 @@ func (r *responseWriter) SetMinimumEdition(minimumEdition int32) {
 	r.lock.Lock()
 	defer r.lock.Unlock()
 
+	if r.checkSealed("SetMinimumEdition") {
+		return
+	}
 	if minimumEdition == 0 {
 		r.codeGeneratorResponse.MinimumEdition = nil
 	} else {
@@ -238,6 +667,9 @@ func (r *responseWriter) SetMaximumEdition(maximumEdition int32) {
 	r.lock.Lock()
 	defer r.lock.Unlock()
 
+	if r.checkSealed("SetMaximumEdition") {
+		return
+	}
 	if maximumEdition == 0 {
 		r.codeGeneratorResponse.MaximumEdition = nil
 	} else {
@@ -246,29 +678,335 @@ func (r *responseWriter) SetMaximumEdition(maximumEdition int32) {
 }
 
 func (r *responseWriter) ToCodeGeneratorResponse() (*pluginpb.CodeGeneratorResponse, error) {
-	r.lock.RLock()
-	defer r.lock.RUnlock()
+	r.lock.Lock()
+	defer r.lock.Unlock()
 
 	if r.written {
 		// We do modifications of the CodeGeneratorResponse in validateAndNormalizeCodeGeneratorResponse, so if someone were
 		// to somehow reuse a ResponseWriter, they may get unexpected results in the future.
 		//
 		// This is an edge case - ResponseWriters are given to Handlers, so to reuse one would be very weird.
-		return nil, errors.New("ResponseWriter cannot be reused")
+		return nil, errors.New("ResponseWriter cannot be reused without calling Reset")
 	}
 	r.written = true
+	r.sealed = true
 
-	if err := validateAndNormalizeCodeGeneratorResponse(r.codeGeneratorResponse, r.lenientValidateErrorFunc); err != nil {
-		return nil, err
+	if r.sealedWriteErr != nil {
+		return nil, r.sealedWriteErr
+	}
+	if r.eagerValidationErr != nil {
+		return nil, newResponseValidationError(r.eagerValidationErr)
+	}
+	if r.limitErr != nil {
+		return nil, newResponseValidationError(r.limitErr)
+	}
+	if r.pathRewriteErr != nil {
+		return nil, newResponseValidationError(r.pathRewriteErr)
+	}
+	if err := validateAndNormalizeCodeGeneratorResponse(
+		r.codeGeneratorResponse,
+		r.lenientValidateErrorFunc,
+		r.deduplicateIdenticalFiles,
+		r.additionalSupportedFeatureBits,
+	); err != nil {
+		return nil, newResponseValidationError(err)
+	}
+	if err := r.newContentNormalizer(r.lenientValidateErrorFunc).normalize(r.codeGeneratorResponse); err != nil {
+		return nil, newResponseValidationError(err)
+	}
+	if err := r.addExecutableFilesManifestFile(r.codeGeneratorResponse); err != nil {
+		return nil, newResponseValidationError(err)
+	}
+	if r.fileNamePortabilityChecker != nil {
+		if err := r.fileNamePortabilityChecker.check(r.codeGeneratorResponse); err != nil {
+			return nil, newResponseValidationError(err)
+		}
+	}
+	if r.insertionPointTargetChecker != nil {
+		if err := r.insertionPointTargetChecker.check(r.codeGeneratorResponse); err != nil {
+			return nil, newResponseValidationError(err)
+		}
 	}
 	return r.codeGeneratorResponse, nil
 }
 
-func (r *responseWriter) addSupportedFeatures(supportedFeatures uint64) {
+func (r *responseWriter) PeekCodeGeneratorResponse() (*pluginpb.CodeGeneratorResponse, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	if r.sealedWriteErr != nil {
+		return nil, r.sealedWriteErr
+	}
+	if r.eagerValidationErr != nil {
+		return nil, newResponseValidationError(r.eagerValidationErr)
+	}
+	if r.limitErr != nil {
+		return nil, newResponseValidationError(r.limitErr)
+	}
+	if r.pathRewriteErr != nil {
+		return nil, newResponseValidationError(r.pathRewriteErr)
+	}
+	codeGeneratorResponse, ok := proto.Clone(r.codeGeneratorResponse).(*pluginpb.CodeGeneratorResponse)
+	if !ok {
+		return nil, errors.New("could not clone CodeGeneratorResponse")
+	}
+	var lenientValidateErrorFunc func(error)
+	if r.lenientValidateErrorFunc != nil {
+		// We still want to normalize the response, but we do not want to produce duplicate warnings.
+		lenientValidateErrorFunc = func(error) {}
+	}
+	if err := validateAndNormalizeCodeGeneratorResponse(
+		codeGeneratorResponse,
+		lenientValidateErrorFunc,
+		r.deduplicateIdenticalFiles,
+		r.additionalSupportedFeatureBits,
+	); err != nil {
+		return nil, newResponseValidationError(err)
+	}
+	if err := r.newContentNormalizer(lenientValidateErrorFunc).normalize(codeGeneratorResponse); err != nil {
+		return nil, newResponseValidationError(err)
+	}
+	if err := r.addExecutableFilesManifestFile(codeGeneratorResponse); err != nil {
+		return nil, newResponseValidationError(err)
+	}
+	if r.fileNamePortabilityChecker != nil {
+		fileNamePortabilityChecker := *r.fileNamePortabilityChecker
+		if fileNamePortabilityChecker.warningFunc != nil {
+			fileNamePortabilityChecker.warningFunc = func(error) {}
+		}
+		if err := fileNamePortabilityChecker.check(codeGeneratorResponse); err != nil {
+			return nil, newResponseValidationError(err)
+		}
+	}
+	if r.insertionPointTargetChecker != nil {
+		insertionPointTargetChecker := *r.insertionPointTargetChecker
+		if insertionPointTargetChecker.warningFunc != nil {
+			insertionPointTargetChecker.warningFunc = func(error) {}
+		}
+		if err := insertionPointTargetChecker.check(codeGeneratorResponse); err != nil {
+			return nil, newResponseValidationError(err)
+		}
+	}
+	return codeGeneratorResponse, nil
+}
+
+func (r *responseWriter) Reset() {
 	r.lock.Lock()
 	defer r.lock.Unlock()
 
+	// We do not clear the existing CodeGeneratorResponse, as it may have been returned from ToCodeGeneratorResponse.
+	r.codeGeneratorResponse = &pluginpb.CodeGeneratorResponse{}
+	r.written = false
+	r.binaryFileNames = nil
+	r.totalBytes = 0
+	r.limitErr = nil
+	r.pathRewriteErr = nil
+	r.eagerFileNameToFile = nil
+	r.eagerValidationErr = nil
+	r.executableFileNames = nil
+	r.sealed = false
+	r.sealedWriteErr = nil
+}
+
+func (r *responseWriter) Sealed() bool {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return r.sealed
+}
+
+func (r *responseWriter) newContentNormalizer(lenientValidateErrorFunc func(error)) *contentNormalizer {
+	return &contentNormalizer{
+		validateUTF8:             r.validateUTF8,
+		defaultLineEnding:        r.defaultLineEnding,
+		extensionToLineEnding:    r.extensionToLineEnding,
+		binaryFileNames:          r.binaryFileNames,
+		lenientValidateErrorFunc: lenientValidateErrorFunc,
+	}
+}
+
+// addFile adds the file without rewriting the name.
+func (r *responseWriter) addFile(methodName string, name string, content string, binary bool) {
+	r.addCodeGeneratorResponseFiles(
+		methodName,
+		binary,
+		&pluginpb.CodeGeneratorResponse_File{
+			Name:    proto.String(name),
+			Content: proto.String(content),
+		},
+	)
+}
+
+// rewritePath rewrites the name with the path rewriter, if any.
+//
+// If the path rewriter returns an error, the error is recorded and false is returned, in which case the
+// caller should drop the write. Must not be called while holding the lock, as the path rewriter may be
+// arbitrary code.
+func (r *responseWriter) rewritePath(name string) (string, bool) {
+	if r.pathRewriter == nil {
+		return name, true
+	}
+	rewrittenName, err := r.pathRewriter(name)
+	if err != nil {
+		r.lock.Lock()
+		defer r.lock.Unlock()
+		if r.pathRewriteErr == nil {
+			r.pathRewriteErr = fmt.Errorf("could not rewrite path %q: %w", name, err)
+		}
+		return "", false
+	}
+	return rewrittenName, true
+}
+
+// addCodeGeneratorResponseFiles adds the files without rewriting the names.
+//
+// If binary is true, the names of the files that are accepted are recorded as binary files.
+func (r *responseWriter) addCodeGeneratorResponseFiles(
+	methodName string,
+	binary bool,
+	files ...*pluginpb.CodeGeneratorResponse_File,
+) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.checkSealed(methodName) {
+		return
+	}
+	if r.limitErr != nil || r.eagerValidationErr != nil {
+		return
+	}
+	for _, file := range files {
+		if r.eagerValidation {
+			if err := r.validateFile(file); err != nil {
+				r.eagerValidationErr = newEagerValidationError(err, callSite())
+				return
+			}
+		}
+		if err := r.checkLimits(file); err != nil {
+			r.limitErr = err
+			return
+		}
+		r.codeGeneratorResponse.File = append(r.codeGeneratorResponse.GetFile(), file)
+		if binary {
+			if r.binaryFileNames == nil {
+				r.binaryFileNames = make(map[string]struct{})
+			}
+			r.binaryFileNames[file.GetName()] = struct{}{}
+		}
+	}
+}
+
+// addExecutableFilesManifestFile adds the manifest file to the response if any files were marked executable.
+//
+// Must be called while holding the lock.
+func (r *responseWriter) addExecutableFilesManifestFile(codeGeneratorResponse *pluginpb.CodeGeneratorResponse) error {
+	if len(r.executableFileNames) == 0 {
+		return nil
+	}
+	executableFilesManifestFile, err := newExecutableFilesManifestFile(codeGeneratorResponse, r.executableFileNames)
+	if err != nil {
+		return err
+	}
+	codeGeneratorResponse.File = append(codeGeneratorResponse.GetFile(), executableFilesManifestFile)
+	return nil
+}
+
+// checkSealed returns true if the ResponseWriter is sealed, recording and reporting the first write after sealing.
+//
+// Must be called while holding the lock.
+func (r *responseWriter) checkSealed(methodName string) bool {
+	if !r.sealed {
+		return false
+	}
+	if r.sealedWriteErr == nil {
+		r.sealedWriteErr = newSealedWriteError(methodName, debug.Stack())
+		if r.written && r.sealedWriteFunc != nil {
+			r.sealedWriteFunc(r.sealedWriteErr)
+		}
+	}
+	return true
+}
+
+// checkLimits checks if adding the file would exceed any limits.
+//
+// Must be called while holding the lock. If no limit is exceeded, the file is counted towards the limits.
+func (r *responseWriter) checkLimits(file *pluginpb.CodeGeneratorResponse_File) error {
+	name := file.GetName()
+	if r.maxFiles > 0 && len(r.codeGeneratorResponse.GetFile()) >= r.maxFiles {
+		return newResponseLimitError(name, fmt.Sprintf("number of files exceeds limit of %d", r.maxFiles))
+	}
+	fileBytes := int64(len(file.GetContent()))
+	if r.maxFileBytes > 0 && fileBytes > r.maxFileBytes {
+		return newResponseLimitError(name, fmt.Sprintf("size of %d bytes exceeds per-file limit of %d bytes", fileBytes, r.maxFileBytes))
+	}
+	if r.maxTotalBytes > 0 && r.totalBytes+fileBytes > r.maxTotalBytes {
+		return newResponseLimitError(name, fmt.Sprintf("total size of files exceeds limit of %d bytes", r.maxTotalBytes))
+	}
+	r.totalBytes += fileBytes
+	return nil
+}
+
+// validateFile validates the file as it is added.
+//
+// Must be called while holding the lock. If the file is valid, it is recorded for future duplicate detection.
+func (r *responseWriter) validateFile(file *pluginpb.CodeGeneratorResponse_File) error {
+	if file == nil {
+		return errors.New("file: nil")
+	}
+	name := file.GetName()
+	insertionPoint := file.GetInsertionPoint()
+	if name == "" {
+		// Files with empty names are appended to the previous file per the CodeGeneratorResponse spec.
+		if len(r.codeGeneratorResponse.GetFile()) == 0 {
+			return errors.New("file: first value had no name set")
+		}
+		if insertionPoint != "" {
+			return errors.New("file: empty name with non-empty insertion point")
+		}
+		return nil
+	}
+	normalizedName, err := validateAndNormalizePath("file", name)
+	if err != nil {
+		return err
+	}
+	if r.lenientValidateErrorFunc != nil {
+		// Unnormalized names and duplicates will be warnings, which are produced by ToCodeGeneratorResponse.
+		return nil
+	}
+	if name != normalizedName {
+		return fmt.Errorf("file: %w", newUnnormalizedCodeGeneratorResponseFileNameError(name, normalizedName, false))
+	}
+	if insertionPoint != "" {
+		return nil
+	}
+	if existingFile, ok := r.eagerFileNameToFile[name]; ok {
+		if r.deduplicateIdenticalFiles && proto.Equal(existingFile, file) {
+			return nil
+		}
+		return fmt.Errorf("file: %w", newDuplicateCodeGeneratorResponseFileNameError(name, false))
+	}
+	if r.eagerFileNameToFile == nil {
+		r.eagerFileNameToFile = make(map[string]*pluginpb.CodeGeneratorResponse_File)
+	}
+	r.eagerFileNameToFile[name] = file
+	return nil
+}
+
+func (r *responseWriter) addSupportedFeatures(methodName string, supportedFeatures uint64) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.checkSealed(methodName) {
+		return
+	}
 	r.codeGeneratorResponse.SupportedFeatures = proto.Uint64(r.codeGeneratorResponse.GetSupportedFeatures() | supportedFeatures)
 }
 
 func (*responseWriter) isResponseWriter() {}
+
+func (r *responseWriter) seal() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.sealed = true
+}
@@ -0,0 +1,631 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"context"
+	"errors"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestResponseWriterAddErrorHelpers(t *testing.T) {
+	t.Parallel()
+
+	responseWriter := NewResponseWriter()
+	responseWriter.AddErrorf("%s is %d", "foo", 1)
+	responseWriter.AddErrorWithLocation("a.proto", []int32{4, 2, 10}, "bad message")
+	responseWriter.AddErrorWithLocation("b.proto", nil, "bad file")
+	responseWriter.AddErrorWithLocation("c.proto", []int32{0, 0, 1}, "")
+	codeGeneratorResponse, err := responseWriter.ToCodeGeneratorResponse()
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		"foo is 1; a.proto:5:3: bad message; b.proto: bad file",
+		codeGeneratorResponse.GetError(),
+	)
+}
+
+func TestResponseWriterPeekCodeGeneratorResponse(t *testing.T) {
+	t.Parallel()
+
+	var warnings []error
+	responseWriter := NewResponseWriter(
+		ResponseWriterWithLenientValidation(
+			func(err error) {
+				warnings = append(warnings, err)
+			},
+		),
+	)
+	responseWriter.AddFile("a/../b.txt", "foo")
+	peekedCodeGeneratorResponse, err := responseWriter.PeekCodeGeneratorResponse()
+	require.NoError(t, err)
+	require.Empty(t, warnings)
+	require.Equal(t, "b.txt", peekedCodeGeneratorResponse.GetFile()[0].GetName())
+	// Modifying the peeked response should not affect the ResponseWriter.
+	peekedCodeGeneratorResponse.File = nil
+
+	responseWriter.AddFile("c.txt", "bar")
+	peekedCodeGeneratorResponse, err = responseWriter.PeekCodeGeneratorResponse()
+	require.NoError(t, err)
+	require.Len(t, peekedCodeGeneratorResponse.GetFile(), 2)
+
+	codeGeneratorResponse, err := responseWriter.ToCodeGeneratorResponse()
+	require.NoError(t, err)
+	require.Len(t, warnings, 1)
+	require.True(t, proto.Equal(peekedCodeGeneratorResponse, codeGeneratorResponse))
+
+	responseWriter = NewResponseWriter()
+	responseWriter.AddCodeGeneratorResponseFiles(&pluginpb.CodeGeneratorResponse_File{Name: proto.String("/a.txt")})
+	_, err = responseWriter.PeekCodeGeneratorResponse()
+	require.Error(t, err)
+}
+
+func TestResponseWriterReset(t *testing.T) {
+	t.Parallel()
+
+	responseWriter := NewResponseWriter()
+	responseWriter.AddFile("a.txt", "foo")
+	responseWriter.AddError("error")
+	responseWriter.SetFeatureProto3Optional()
+	codeGeneratorResponse, err := responseWriter.ToCodeGeneratorResponse()
+	require.NoError(t, err)
+	_, err = responseWriter.ToCodeGeneratorResponse()
+	require.Error(t, err)
+
+	responseWriter.Reset()
+	responseWriter.AddFile("b.txt", "bar")
+	resetCodeGeneratorResponse, err := responseWriter.ToCodeGeneratorResponse()
+	require.NoError(t, err)
+	require.True(
+		t,
+		proto.Equal(
+			&pluginpb.CodeGeneratorResponse{
+				File: []*pluginpb.CodeGeneratorResponse_File{
+					{
+						Name:    proto.String("b.txt"),
+						Content: proto.String("bar"),
+					},
+				},
+			},
+			resetCodeGeneratorResponse,
+		),
+	)
+	// The previously-returned CodeGeneratorResponse is not affected.
+	require.Equal(t, "a.txt", codeGeneratorResponse.GetFile()[0].GetName())
+	require.Equal(t, "error", codeGeneratorResponse.GetError())
+}
+
+func TestResponseWriterWithLimits(t *testing.T) {
+	t.Parallel()
+
+	for _, testCase := range []struct {
+		name             string
+		maxFiles         int
+		maxTotalBytes    int64
+		maxFileBytes     int64
+		expectedFileName string
+		expectedError    string
+	}{
+		{
+			name: "no_limits",
+		},
+		{
+			name:             "max_files",
+			maxFiles:         2,
+			expectedFileName: "c.txt",
+			expectedError:    `generated file "c.txt": number of files exceeds limit of 2`,
+		},
+		{
+			name:             "max_total_bytes",
+			maxTotalBytes:    5,
+			expectedFileName: "b.txt",
+			expectedError:    `generated file "b.txt": total size of files exceeds limit of 5 bytes`,
+		},
+		{
+			name:             "max_file_bytes",
+			maxFileBytes:     3,
+			expectedFileName: "c.txt",
+			expectedError:    `generated file "c.txt": size of 4 bytes exceeds per-file limit of 3 bytes`,
+		},
+		{
+			name:          "within_limits",
+			maxFiles:      3,
+			maxTotalBytes: 10,
+			maxFileBytes:  4,
+		},
+	} {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			responseWriter := NewResponseWriter(
+				ResponseWriterWithLimits(testCase.maxFiles, testCase.maxTotalBytes, testCase.maxFileBytes),
+			)
+			responseWriter.AddFile("a.txt", "foo")
+			responseWriter.AddFile("b.txt", "bar")
+			responseWriter.AddFile("c.txt", "bazz")
+			codeGeneratorResponse, err := responseWriter.ToCodeGeneratorResponse()
+			if testCase.expectedError == "" {
+				require.NoError(t, err)
+				require.Len(t, codeGeneratorResponse.GetFile(), 3)
+				return
+			}
+			var responseValidationError *ResponseValidationError
+			require.ErrorAs(t, err, &responseValidationError)
+			require.Equal(t, testCase.expectedFileName, responseValidationError.FileName)
+			require.EqualError(t, err, testCase.expectedError)
+		})
+	}
+}
+
+func TestResponseWriterBinaryFileNames(t *testing.T) {
+	t.Parallel()
+
+	responseWriter, ok := NewResponseWriter(ResponseWriterWithLimits(1, 0, 0)).(*responseWriter)
+	require.True(t, ok)
+	responseWriter.AddBinaryFile("a.bin", []byte("a"))
+	// Rejected files are not recorded as binary files.
+	responseWriter.AddBinaryFile("b.bin", []byte("b"))
+	require.Equal(t, map[string]struct{}{"a.bin": {}}, responseWriter.binaryFileNames)
+}
+
+func TestResponseWriterWithIdenticalDuplicateDeduplication(t *testing.T) {
+	t.Parallel()
+
+	responseWriter := NewResponseWriter(ResponseWriterWithIdenticalDuplicateDeduplication())
+	responseWriter.AddFile("a.txt", "foo")
+	responseWriter.AddFile("a.txt", "foo")
+	responseWriter.AddCodeGeneratorResponseFiles(
+		&pluginpb.CodeGeneratorResponse_File{
+			Name:           proto.String("a.txt"),
+			InsertionPoint: proto.String("point"),
+			Content:        proto.String("bar"),
+		},
+		&pluginpb.CodeGeneratorResponse_File{
+			Name:           proto.String("a.txt"),
+			InsertionPoint: proto.String("point"),
+			Content:        proto.String("bar"),
+		},
+	)
+	responseWriter.AddFile("a.txt", "foo")
+	codeGeneratorResponse, err := responseWriter.ToCodeGeneratorResponse()
+	require.NoError(t, err)
+	// Files with insertion points are never deduplicated.
+	require.Len(t, codeGeneratorResponse.GetFile(), 3)
+
+	responseWriter = NewResponseWriter(ResponseWriterWithIdenticalDuplicateDeduplication())
+	responseWriter.AddFile("a.txt", "foo")
+	responseWriter.AddFile("a.txt", "bar")
+	_, err = responseWriter.ToCodeGeneratorResponse()
+	var responseValidationError *ResponseValidationError
+	require.ErrorAs(t, err, &responseValidationError)
+	require.Equal(t, "a.txt", responseValidationError.FileName)
+
+	var warnings []error
+	responseWriter = NewResponseWriter(
+		ResponseWriterWithIdenticalDuplicateDeduplication(),
+		ResponseWriterWithLenientValidation(func(err error) { warnings = append(warnings, err) }),
+	)
+	responseWriter.AddFile("a.txt", "foo")
+	responseWriter.AddFile("./a.txt", "foo")
+	responseWriter.AddFile("a.txt", "bar")
+	codeGeneratorResponse, err = responseWriter.ToCodeGeneratorResponse()
+	require.NoError(t, err)
+	require.Len(t, codeGeneratorResponse.GetFile(), 1)
+	// One warning for the unnormalized name, one for the differing duplicate.
+	require.Len(t, warnings, 2)
+}
+
+func TestResponseWriterWithAdditionalSupportedFeatureBits(t *testing.T) {
+	t.Parallel()
+
+	const futureFeature = uint64(1 << 2)
+	responseWriter := NewResponseWriter()
+	responseWriter.SetSupportedFeatures(uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL) | futureFeature)
+	_, err := responseWriter.ToCodeGeneratorResponse()
+	var responseValidationError *ResponseValidationError
+	require.ErrorAs(t, err, &responseValidationError)
+
+	responseWriter = NewResponseWriter(ResponseWriterWithAdditionalSupportedFeatureBits(futureFeature))
+	responseWriter.SetSupportedFeatures(uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL) | futureFeature)
+	codeGeneratorResponse, err := responseWriter.ToCodeGeneratorResponse()
+	require.NoError(t, err)
+	require.Equal(t, uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL)|futureFeature, codeGeneratorResponse.GetSupportedFeatures())
+
+	// Bits that were not specified are still rejected.
+	responseWriter = NewResponseWriter(ResponseWriterWithAdditionalSupportedFeatureBits(futureFeature))
+	responseWriter.SetSupportedFeatures(futureFeature | 1<<3)
+	_, err = responseWriter.ToCodeGeneratorResponse()
+	require.ErrorAs(t, err, &responseValidationError)
+}
+
+func TestResponseWriterWithPathRewriter(t *testing.T) {
+	t.Parallel()
+
+	pathRewriter := func(name string) (string, error) {
+		if strings.HasPrefix(name, "bad/") {
+			return "", errors.New("bad path")
+		}
+		return path.Join("gen", path.Base(name)), nil
+	}
+	responseWriter := NewResponseWriter(ResponseWriterWithPathRewriter(pathRewriter))
+	responseWriter.AddFile("foo/v1/a.txt", "a")
+	responseWriter.AddBinaryFile("foo/v1/b.bin", []byte("b"))
+	responseWriter.MarkExecutable("foo/v1/b.bin")
+	file := &pluginpb.CodeGeneratorResponse_File{
+		Name:    proto.String("foo/v1/c.txt"),
+		Content: proto.String("c"),
+	}
+	responseWriter.AddCodeGeneratorResponseFiles(file)
+	// The caller's file is not modified.
+	require.Equal(t, "foo/v1/c.txt", file.GetName())
+	codeGeneratorResponse, err := responseWriter.ToCodeGeneratorResponse()
+	require.NoError(t, err)
+	fileNames := make([]string, 0, len(codeGeneratorResponse.GetFile()))
+	for _, file := range codeGeneratorResponse.GetFile() {
+		fileNames = append(fileNames, file.GetName())
+	}
+	// The executable manifest lists the rewritten name.
+	require.Equal(t, []string{"gen/a.txt", "gen/b.bin", "gen/c.txt", ExecutableFilesManifestFileName}, fileNames)
+	require.Equal(t, "gen/b.bin\n", codeGeneratorResponse.GetFile()[3].GetContent())
+
+	responseWriter = NewResponseWriter(ResponseWriterWithPathRewriter(pathRewriter))
+	responseWriter.AddFile("bad/a.txt", "a")
+	_, err = responseWriter.ToCodeGeneratorResponse()
+	var responseValidationError *ResponseValidationError
+	require.ErrorAs(t, err, &responseValidationError)
+	require.ErrorContains(t, err, "bad path")
+
+	// Only the failing file and its continuations are dropped from a batch.
+	responseWriter = NewResponseWriter(ResponseWriterWithPathRewriter(pathRewriter))
+	responseWriter.AddCodeGeneratorResponseFiles(
+		&pluginpb.CodeGeneratorResponse_File{
+			Name:    proto.String("bad/a.txt"),
+			Content: proto.String("a"),
+		},
+		&pluginpb.CodeGeneratorResponse_File{
+			Content: proto.String("a"),
+		},
+		&pluginpb.CodeGeneratorResponse_File{
+			Name:    proto.String("foo/v1/b.txt"),
+			Content: proto.String("b"),
+		},
+	)
+	require.Equal(t, 1, responseWriter.FileCount())
+	_, err = responseWriter.ToCodeGeneratorResponse()
+	require.ErrorContains(t, err, "bad path")
+
+	// Files with no name continue the previous file, and are not rewritten.
+	responseWriter = NewResponseWriter(
+		ResponseWriterWithPathRewriter(
+			func(name string) (string, error) {
+				return "gen/" + name, nil
+			},
+		),
+	)
+	responseWriter.AddCodeGeneratorResponseFiles(
+		&pluginpb.CodeGeneratorResponse_File{
+			Name:    proto.String("a.txt"),
+			Content: proto.String("a"),
+		},
+		&pluginpb.CodeGeneratorResponse_File{
+			Content: proto.String("b"),
+		},
+	)
+	codeGeneratorResponse, err = responseWriter.ToCodeGeneratorResponse()
+	require.NoError(t, err)
+	require.Len(t, codeGeneratorResponse.GetFile(), 1)
+	require.Equal(t, "gen/a.txt", codeGeneratorResponse.GetFile()[0].GetName())
+	require.Equal(t, "ab", codeGeneratorResponse.GetFile()[0].GetContent())
+
+	// Nil files are not rewritten, and result in an error as without a path rewriter.
+	responseWriter = NewResponseWriter(ResponseWriterWithPathRewriter(pathRewriter))
+	require.NotPanics(t, func() { responseWriter.AddCodeGeneratorResponseFiles(nil) })
+	_, err = responseWriter.ToCodeGeneratorResponse()
+	require.Error(t, err)
+
+	// Rewritten names are validated.
+	responseWriter = NewResponseWriter(
+		ResponseWriterWithPathRewriter(
+			func(name string) (string, error) {
+				return "../" + name, nil
+			},
+		),
+	)
+	responseWriter.AddFile("a.txt", "a")
+	_, err = responseWriter.ToCodeGeneratorResponse()
+	require.ErrorAs(t, err, &responseValidationError)
+}
+
+func TestResponseWriterWithFileNamePortabilityCheck(t *testing.T) {
+	t.Parallel()
+
+	for _, testCase := range []struct {
+		name              string
+		fileNames         []string
+		maxFileNameLength int
+		expectedFileName  string
+		expectedError     string
+	}{
+		{
+			name:      "no_collisions",
+			fileNames: []string{"a/a.txt", "a/b.txt", "b/a.txt"},
+		},
+		{
+			name:             "file_collision",
+			fileNames:        []string{"a/Foo.txt", "a/foo.txt"},
+			expectedFileName: "a/foo.txt",
+			expectedError:    `generated file "a/foo.txt": path "a/foo.txt" collides with "a/Foo.txt" on case-insensitive filesystems.`,
+		},
+		{
+			name:             "directory_collision",
+			fileNames:        []string{"Foo/a.txt", "foo/b.txt"},
+			expectedFileName: "foo/b.txt",
+			expectedError:    `generated file "foo/b.txt": path "foo" collides with "Foo" on case-insensitive filesystems.`,
+		},
+		{
+			name:             "windows_reserved_name",
+			fileNames:        []string{"a/Aux.txt"},
+			expectedFileName: "a/Aux.txt",
+			expectedError:    `generated file "a/Aux.txt": path element "Aux.txt" is a reserved name on Windows.`,
+		},
+		{
+			name:             "windows_reserved_directory_name",
+			fileNames:        []string{"nul/a.txt"},
+			expectedFileName: "nul/a.txt",
+			expectedError:    `generated file "nul/a.txt": path element "nul" is a reserved name on Windows.`,
+		},
+		{
+			name:      "windows_reserved_name_prefix",
+			fileNames: []string{"console.txt", "com10.txt", "auxiliary/a.txt"},
+		},
+		{
+			name:             "trailing_dot",
+			fileNames:        []string{"a./b.txt"},
+			expectedFileName: "a./b.txt",
+			expectedError:    `generated file "a./b.txt": path element "a." ends with a dot or space, which is removed on Windows.`,
+		},
+		{
+			name:             "trailing_space",
+			fileNames:        []string{"b.txt "},
+			expectedFileName: "b.txt ",
+			expectedError:    `generated file "b.txt ": path element "b.txt " ends with a dot or space, which is removed on Windows.`,
+		},
+		{
+			name:             "max_path_element_length",
+			fileNames:        []string{"a/" + strings.Repeat("b", 256)},
+			expectedFileName: "a/" + strings.Repeat("b", 256),
+			expectedError: `generated file "a/` + strings.Repeat("b", 256) + `": path element "` + strings.Repeat("b", 256) +
+				`" has length of 256 which exceeds limit of 255.`,
+		},
+		{
+			name:              "max_file_name_length",
+			fileNames:         []string{"a.txt", "abcdef.txt"},
+			maxFileNameLength: 5,
+			expectedFileName:  "abcdef.txt",
+			expectedError:     `generated file "abcdef.txt": name length of 10 exceeds limit of 5.`,
+		},
+	} {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			responseWriter := NewResponseWriter(
+				ResponseWriterWithFileNamePortabilityCheck(testCase.maxFileNameLength, nil),
+			)
+			for _, fileName := range testCase.fileNames {
+				responseWriter.AddFile(fileName, "")
+			}
+			_, err := responseWriter.PeekCodeGeneratorResponse()
+			if testCase.expectedError == "" {
+				require.NoError(t, err)
+			} else {
+				var responseValidationError *ResponseValidationError
+				require.ErrorAs(t, err, &responseValidationError)
+				require.Equal(t, testCase.expectedFileName, responseValidationError.FileName)
+				require.EqualError(t, err, testCase.expectedError)
+			}
+
+			var warnings []error
+			responseWriter = NewResponseWriter(
+				ResponseWriterWithFileNamePortabilityCheck(
+					testCase.maxFileNameLength,
+					func(err error) { warnings = append(warnings, err) },
+				),
+			)
+			for _, fileName := range testCase.fileNames {
+				responseWriter.AddFile(fileName, "")
+			}
+			codeGeneratorResponse, err := responseWriter.ToCodeGeneratorResponse()
+			require.NoError(t, err)
+			require.Len(t, codeGeneratorResponse.GetFile(), len(testCase.fileNames))
+			if testCase.expectedError == "" {
+				require.Empty(t, warnings)
+			} else {
+				require.Len(t, warnings, 1)
+			}
+		})
+	}
+}
+
+func TestResponseWriterWithInsertionPointTargetCheck(t *testing.T) {
+	t.Parallel()
+
+	newFile := func(name string, insertionPoint string) *pluginpb.CodeGeneratorResponse_File {
+		file := &pluginpb.CodeGeneratorResponse_File{
+			Name:    proto.String(name),
+			Content: proto.String(""),
+		}
+		if insertionPoint != "" {
+			file.InsertionPoint = proto.String(insertionPoint)
+		}
+		return file
+	}
+	files := []*pluginpb.CodeGeneratorResponse_File{
+		newFile("a.txt", ""),
+		newFile("a.txt", "foo"),
+		newFile("b.txt", "foo"),
+		newFile("c.txt", "foo"),
+	}
+
+	responseWriter := NewResponseWriter(ResponseWriterWithInsertionPointTargetCheck([]string{"c.txt"}, nil))
+	responseWriter.AddCodeGeneratorResponseFiles(files...)
+	_, err := responseWriter.PeekCodeGeneratorResponse()
+	var responseValidationError *ResponseValidationError
+	require.ErrorAs(t, err, &responseValidationError)
+	require.Equal(t, "b.txt", responseValidationError.FileName)
+	require.EqualError(t, err, `insertion point "foo" targets file "b.txt", which was not generated earlier in the response.`)
+
+	var warnings []error
+	responseWriter = NewResponseWriter(
+		ResponseWriterWithInsertionPointTargetCheck(
+			nil,
+			func(err error) { warnings = append(warnings, err) },
+		),
+	)
+	responseWriter.AddCodeGeneratorResponseFiles(files...)
+	codeGeneratorResponse, err := responseWriter.ToCodeGeneratorResponse()
+	require.NoError(t, err)
+	require.Len(t, codeGeneratorResponse.GetFile(), len(files))
+	require.Len(t, warnings, 2)
+
+	responseWriter = NewResponseWriter(ResponseWriterWithInsertionPointTargetCheck([]string{"b.txt", "c.txt"}, nil))
+	responseWriter.AddCodeGeneratorResponseFiles(files...)
+	_, err = responseWriter.ToCodeGeneratorResponse()
+	require.NoError(t, err)
+}
+
+func TestResponseWriterWithEagerValidation(t *testing.T) {
+	t.Parallel()
+
+	responseWriter := NewResponseWriter(ResponseWriterWithEagerValidation())
+	responseWriter.AddFile("a.txt", "foo")
+	responseWriter.AddFile("b.txt", "bar")
+	responseWriter.AddFile("a.txt", "baz")
+	// Discarded, as an issue was already detected.
+	responseWriter.AddFile("c.txt", "bat")
+	_, err := responseWriter.PeekCodeGeneratorResponse()
+	var responseValidationError *ResponseValidationError
+	require.ErrorAs(t, err, &responseValidationError)
+	require.Equal(t, "a.txt", responseValidationError.FileName)
+	require.Contains(t, err.Error(), `duplicate generated file name "a.txt"`)
+	require.Contains(t, err.Error(), "response_writer_test.go:")
+	_, err = responseWriter.ToCodeGeneratorResponse()
+	require.ErrorAs(t, err, &responseValidationError)
+
+	responseWriter.Reset()
+	responseWriter.AddCodeGeneratorResponseFiles(nil)
+	_, err = responseWriter.ToCodeGeneratorResponse()
+	require.ErrorContains(t, err, "file: nil (added at ")
+
+	responseWriter.Reset()
+	responseWriter.AddFile("../a.txt", "foo")
+	_, err = responseWriter.ToCodeGeneratorResponse()
+	require.ErrorContains(t, err, `file: path "../a.txt" should not jump context`)
+
+	responseWriter = NewResponseWriter(
+		ResponseWriterWithEagerValidation(),
+		ResponseWriterWithIdenticalDuplicateDeduplication(),
+	)
+	responseWriter.AddFile("a.txt", "foo")
+	responseWriter.AddFile("a.txt", "foo")
+	responseWriter.AddCodeGeneratorResponseFiles(
+		&pluginpb.CodeGeneratorResponse_File{
+			Name:           proto.String("a.txt"),
+			InsertionPoint: proto.String("point"),
+			Content:        proto.String("bar"),
+		},
+	)
+	codeGeneratorResponse, err := responseWriter.ToCodeGeneratorResponse()
+	require.NoError(t, err)
+	require.Len(t, codeGeneratorResponse.GetFile(), 2)
+
+	var warnings []error
+	responseWriter = NewResponseWriter(
+		ResponseWriterWithEagerValidation(),
+		ResponseWriterWithLenientValidation(func(err error) { warnings = append(warnings, err) }),
+	)
+	responseWriter.AddFile("a.txt", "foo")
+	responseWriter.AddFile("./a.txt", "bar")
+	codeGeneratorResponse, err = responseWriter.ToCodeGeneratorResponse()
+	require.NoError(t, err)
+	require.Len(t, codeGeneratorResponse.GetFile(), 1)
+	require.Len(t, warnings, 2)
+}
+
+func TestResponseWriterSealed(t *testing.T) {
+	t.Parallel()
+
+	var sealedWriteErrs []error
+	responseWriter := NewResponseWriter(
+		ResponseWriterWithSealedWriteFunc(func(err error) { sealedWriteErrs = append(sealedWriteErrs, err) }),
+	)
+	require.False(t, responseWriter.Sealed())
+	responseWriter.AddFile("a.txt", "foo")
+	codeGeneratorResponse, err := responseWriter.ToCodeGeneratorResponse()
+	require.NoError(t, err)
+	require.True(t, responseWriter.Sealed())
+
+	// Writes after ToCodeGeneratorResponse do not modify the returned CodeGeneratorResponse.
+	responseWriter.AddFile("b.txt", "bar")
+	responseWriter.AddError("error")
+	responseWriter.SetFeatureProto3Optional()
+	require.Len(t, codeGeneratorResponse.GetFile(), 1)
+	require.Empty(t, codeGeneratorResponse.GetError())
+	require.Zero(t, codeGeneratorResponse.GetSupportedFeatures())
+	// Only the first write is reported.
+	require.Len(t, sealedWriteErrs, 1)
+	require.Contains(t, sealedWriteErrs[0].Error(), "ResponseWriter.AddFile called after the ResponseWriter was sealed")
+	require.Contains(t, sealedWriteErrs[0].Error(), "TestResponseWriterSealed")
+	// PeekCodeGeneratorResponse reports the write as well.
+	_, err = responseWriter.PeekCodeGeneratorResponse()
+	require.ErrorContains(t, err, "ResponseWriter.AddFile called after the ResponseWriter was sealed")
+
+	responseWriter.Reset()
+	require.False(t, responseWriter.Sealed())
+	responseWriter.AddFile("b.txt", "bar")
+	_, err = responseWriter.ToCodeGeneratorResponse()
+	require.NoError(t, err)
+
+	// A write after the Handler returned, but before ToCodeGeneratorResponse, fails ToCodeGeneratorResponse.
+	var handlerResponseWriter ResponseWriter
+	_, err = Invoke(
+		context.Background(),
+		HandlerFunc(func(_ context.Context, _ PluginEnv, responseWriter ResponseWriter, _ Request) error {
+			handlerResponseWriter = responseWriter
+			return nil
+		}),
+		&pluginpb.CodeGeneratorRequest{
+			FileToGenerate: []string{"a.proto"},
+			ProtoFile: []*descriptorpb.FileDescriptorProto{
+				{
+					Name:   proto.String("a.proto"),
+					Syntax: proto.String("proto3"),
+				},
+			},
+		},
+		WithDryRun(func(*DryRunReport) error {
+			handlerResponseWriter.AddFile("a.txt", "foo")
+			return nil
+		}),
+	)
+	require.ErrorContains(t, err, "called after the ResponseWriter was sealed")
+	require.True(t, handlerResponseWriter.Sealed())
+}
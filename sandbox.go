@@ -0,0 +1,108 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"context"
+)
+
+// Sandbox restricts what a Handler can do.
+//
+// A Sandbox is entered after the CodeGeneratorRequest is read and validated, and before the Handler is invoked.
+// This gives registries and other hosts that execute third-party plugin code a standard integration point
+// for hardening, for example dropping privileges, setting resource limits, or installing seccomp filters.
+//
+// See WithSandbox for more details.
+type Sandbox interface {
+	// Enter restricts the current process before the Handler is invoked.
+	//
+	// If an error is returned, the Handler is not invoked, and the error is returned from Main, Run, Invoke,
+	// or ExecuteHandler.
+	//
+	// Enter is called once per CodeGeneratorRequest, and may therefore be called multiple times within a single
+	// process, for example in batch mode. Implementations must be safe to call multiple times.
+	Enter(ctx context.Context, pluginEnv PluginEnv) error
+}
+
+// SandboxFunc is a function that implements Sandbox.
+type SandboxFunc func(context.Context, PluginEnv) error
+
+// Enter implements Sandbox.
+func (s SandboxFunc) Enter(ctx context.Context, pluginEnv PluginEnv) error {
+	return s(ctx, pluginEnv)
+}
+
+// ResourceLimits are limits on the resources of the current process.
+//
+// A value of zero means that the corresponding resource is not limited.
+type ResourceLimits struct {
+	// CPUSeconds is the maximum amount of CPU time in seconds.
+	CPUSeconds uint64
+	// AddressSpaceBytes is the maximum size of the virtual memory of the process in bytes.
+	//
+	// The Go runtime reserves virtual memory well beyond what is actually used, so this should be
+	// set generously.
+	AddressSpaceBytes uint64
+	// FileSizeBytes is the maximum size of files that the process may create in bytes.
+	FileSizeBytes uint64
+	// OpenFiles is the maximum number of file descriptors that the process may have open.
+	OpenFiles uint64
+}
+
+// NewResourceLimitsSandbox returns a new Sandbox that applies the ResourceLimits to the current process.
+//
+// The soft and hard limits are both set to the given values, so the limits cannot be raised again by the
+// Handler. Hard limits cannot be raised by unprivileged processes, so if a current hard limit is already
+// lower than the given value, both limits are set to the current hard limit instead.
+//
+// This is only supported on Linux and macOS. On all other platforms, Enter returns an error, so that plugins
+// do not silently run without the requested limits.
+func NewResourceLimitsSandbox(resourceLimits ResourceLimits) Sandbox {
+	return SandboxFunc(
+		func(context.Context, PluginEnv) error {
+			return applyResourceLimits(resourceLimits)
+		},
+	)
+}
+
+// WithSandbox returns a new RunOption that says to enter the Sandbox before the Handler is invoked.
+//
+// This option can be specified multiple times, in which case the Sandboxes are entered in the order given.
+// If any Sandbox returns an error, the Handler is not invoked. Sandboxes are entered after the request
+// interceptors given with WithRequestInterceptor are called.
+//
+// Note that Sandboxes restrict the current process. If a Handler is invoked in-process, for example with
+// Invoke or ExecuteHandler, the restrictions apply to the calling program as well.
+//
+// This option can be passed to Main or Run.
+//
+// The default is to not enter a Sandbox.
+func WithSandbox(sandbox Sandbox) RunOption {
+	return optsFunc(func(opts *opts) {
+		opts.sandboxes = append(opts.sandboxes, sandbox)
+	})
+}
+
+// *** PRIVATE ***
+
+// enterSandboxes enters each Sandbox in order, returning the first error.
+func enterSandboxes(ctx context.Context, pluginEnv PluginEnv, sandboxes []Sandbox) error {
+	for _, sandbox := range sandboxes {
+		if err := sandbox.Enter(ctx, pluginEnv); err != nil {
+			return err
+		}
+	}
+	return nil
+}
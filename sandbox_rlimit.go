@@ -0,0 +1,52 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build darwin || linux
+
+package protoplugin
+
+import (
+	"syscall"
+)
+
+// applyResourceLimits applies the ResourceLimits to the current process.
+func applyResourceLimits(resourceLimits ResourceLimits) error {
+	for _, resourceLimit := range []struct {
+		resource int
+		value    uint64
+	}{
+		{resource: syscall.RLIMIT_CPU, value: resourceLimits.CPUSeconds},
+		{resource: syscall.RLIMIT_AS, value: resourceLimits.AddressSpaceBytes},
+		{resource: syscall.RLIMIT_FSIZE, value: resourceLimits.FileSizeBytes},
+		{resource: syscall.RLIMIT_NOFILE, value: resourceLimits.OpenFiles},
+	} {
+		if resourceLimit.value == 0 {
+			continue
+		}
+		rlimit := &syscall.Rlimit{}
+		if err := syscall.Getrlimit(resourceLimit.resource, rlimit); err != nil {
+			return err
+		}
+		// Never raise the hard limit, as this fails for unprivileged processes. The soft limit is set to the
+		// same value, so that the Handler cannot raise it again.
+		if rlimit.Max > resourceLimit.value {
+			rlimit.Max = resourceLimit.value
+		}
+		rlimit.Cur = rlimit.Max
+		if err := syscall.Setrlimit(resourceLimit.resource, rlimit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !darwin && !linux
+
+package protoplugin
+
+import (
+	"errors"
+	"runtime"
+)
+
+// applyResourceLimits returns an error, as resource limits are not supported on this platform.
+func applyResourceLimits(ResourceLimits) error {
+	return errors.New("resource limits are not supported on " + runtime.GOOS)
+}
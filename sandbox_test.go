@@ -0,0 +1,93 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"context"
+	"errors"
+	"math"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestWithSandboxOption(t *testing.T) {
+	t.Parallel()
+
+	codeGeneratorRequest := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{"a.proto"},
+		ProtoFile: []*descriptorpb.FileDescriptorProto{
+			{
+				Name:   proto.String("a.proto"),
+				Syntax: proto.String("proto3"),
+			},
+		},
+	}
+	var calls []string
+	handler := HandlerFunc(func(_ context.Context, _ PluginEnv, _ ResponseWriter, _ Request) error {
+		calls = append(calls, "handler")
+		return nil
+	})
+	newSandbox := func(name string, err error) Sandbox {
+		return SandboxFunc(func(context.Context, PluginEnv) error {
+			calls = append(calls, name)
+			return err
+		})
+	}
+
+	_, err := Invoke(
+		context.Background(),
+		handler,
+		codeGeneratorRequest,
+		WithSandbox(newSandbox("a", nil)),
+		WithSandbox(newSandbox("b", nil)),
+	)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b", "handler"}, calls)
+
+	calls = nil
+	sandboxErr := errors.New("sandbox")
+	_, err = Invoke(
+		context.Background(),
+		handler,
+		codeGeneratorRequest,
+		WithSandbox(newSandbox("a", sandboxErr)),
+		WithSandbox(newSandbox("b", nil)),
+	)
+	require.ErrorIs(t, err, sandboxErr)
+	require.Equal(t, []string{"a"}, calls)
+}
+
+func TestNewResourceLimitsSandbox(t *testing.T) {
+	t.Parallel()
+
+	// Limits are never raised, so a limit of the maximum value is a no-op that does not affect the test process.
+	err := NewResourceLimitsSandbox(
+		ResourceLimits{
+			OpenFiles: math.MaxUint64,
+		},
+	).Enter(context.Background(), PluginEnv{})
+	switch runtime.GOOS {
+	case "darwin", "linux":
+		require.NoError(t, err)
+	default:
+		require.Error(t, err)
+	}
+	require.NoError(t, NewResourceLimitsSandbox(ResourceLimits{}).Enter(context.Background(), PluginEnv{}))
+}
@@ -0,0 +1,117 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// NewSubRequest returns a new Request scoped to a subset of the files to generate of the given Request,
+// with the given parameter.
+//
+// This is intended for "driver" plugins that orchestrate several specialized Handlers over partitions
+// of the input. Use HandleSubRequest to invoke a Handler with the returned Request.
+//
+// All values of filesToGenerate must be within the files to generate of the given Request. All other
+// files in the given Request are retained, as they may be imported by the files to generate. If the given
+// Request had WithSourceRetentionOptions called, the returned Request will as well. The ordering of
+// FileDescriptorProtosToGenerate is also retained.
+//
+// The given Request is not modified.
+func NewSubRequest(request Request, filesToGenerate []string, parameter string) (Request, error) {
+	parentCodeGeneratorRequest := request.CodeGeneratorRequest()
+	parentFilesToGenerate := make(map[string]struct{}, len(parentCodeGeneratorRequest.GetFileToGenerate()))
+	for _, fileToGenerate := range parentCodeGeneratorRequest.GetFileToGenerate() {
+		parentFilesToGenerate[fileToGenerate] = struct{}{}
+	}
+	filesToGenerateMap := make(map[string]struct{}, len(filesToGenerate))
+	for _, fileToGenerate := range filesToGenerate {
+		if _, ok := parentFilesToGenerate[fileToGenerate]; !ok {
+			return nil, fmt.Errorf("file %q is not a file to generate of the parent request", fileToGenerate)
+		}
+		filesToGenerateMap[fileToGenerate] = struct{}{}
+	}
+	codeGeneratorRequest := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate:  slicesClone(filesToGenerate),
+		ProtoFile:       parentCodeGeneratorRequest.GetProtoFile(),
+		CompilerVersion: parentCodeGeneratorRequest.GetCompilerVersion(),
+	}
+	if parameter != "" {
+		codeGeneratorRequest.Parameter = &parameter
+	}
+	// source_file_descriptors must only contain the files to generate.
+	for _, sourceFileDescriptor := range parentCodeGeneratorRequest.GetSourceFileDescriptors() {
+		if _, ok := filesToGenerateMap[sourceFileDescriptor.GetName()]; ok {
+			codeGeneratorRequest.SourceFileDescriptors = append(
+				codeGeneratorRequest.SourceFileDescriptors,
+				sourceFileDescriptor,
+			)
+		}
+	}
+	if err := validateCodeGeneratorRequest(codeGeneratorRequest); err != nil {
+		return nil, newRequestValidationError(err)
+	}
+	subRequest := newRequest(codeGeneratorRequest)
+	subRequest.fileToGenerateOrder = request.hasFileToGenerateOrder()
+	subRequest.fileFilter = request.FileFilter()
+	subRequest.sourceRetentionOptionsUnavailableWarning = request.getSourceRetentionOptionsUnavailableWarning()
+	subRequest.descriptorCache = request.getDescriptorCache()
+	if request.hasSourceRetentionOptions() {
+		return subRequest.WithSourceRetentionOptions()
+	}
+	return subRequest, nil
+}
+
+// HandleSubRequest invokes the Handler with the Request, merging the output into the ResponseWriter.
+//
+// The Handler is given its own ResponseWriter, and the resulting CodeGeneratorResponse is validated
+// before being merged. All files are added to the given ResponseWriter via AddCodeGeneratorResponseFiles,
+// and any error on the response is added via AddError. Supported features and editions set by the Handler
+// are not merged - the calling Handler is responsible for declaring the features and editions it supports.
+// Files marked executable by the Handler are marked executable on the given ResponseWriter.
+//
+// Errors returned from the Handler, or from validating the response, are returned.
+func HandleSubRequest(
+	ctx context.Context,
+	pluginEnv PluginEnv,
+	responseWriter ResponseWriter,
+	handler Handler,
+	request Request,
+) error {
+	subResponseWriter := NewResponseWriter()
+	if err := handler.Handle(ctx, pluginEnv, subResponseWriter, request); err != nil {
+		return err
+	}
+	codeGeneratorResponse, err := subResponseWriter.ToCodeGeneratorResponse()
+	if err != nil {
+		return err
+	}
+	files := make([]*pluginpb.CodeGeneratorResponse_File, 0, len(codeGeneratorResponse.GetFile()))
+	for _, file := range codeGeneratorResponse.GetFile() {
+		// The manifest is regenerated by the given ResponseWriter.
+		if file.GetName() != ExecutableFilesManifestFileName {
+			files = append(files, file)
+		}
+	}
+	responseWriter.AddCodeGeneratorResponseFiles(files...)
+	for _, executableFileName := range ExecutableFileNames(codeGeneratorResponse) {
+		responseWriter.MarkExecutable(executableFileName)
+	}
+	responseWriter.AddError(codeGeneratorResponse.GetError())
+	return nil
+}
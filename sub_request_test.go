@@ -0,0 +1,95 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestSubRequest(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	fileDescriptorProtos, err := compile(ctx, map[string][]byte{
+		"a.proto": []byte(`syntax = "proto3"; package foo; message A {}`),
+		"b.proto": []byte(`syntax = "proto3"; package foo; import "a.proto"; message B { A a = 1; }`),
+		"c.proto": []byte(`syntax = "proto3"; package foo; message C {}`),
+	})
+	require.NoError(t, err)
+
+	subHandler := HandlerFunc(func(_ context.Context, _ PluginEnv, responseWriter ResponseWriter, request Request) error {
+		if request.Parameter() == "error" {
+			responseWriter.AddError("sub error")
+			return nil
+		}
+		fileDescriptors, err := request.FileDescriptorsToGenerate()
+		if err != nil {
+			return err
+		}
+		for _, fileDescriptor := range fileDescriptors {
+			responseWriter.AddFile(request.Parameter()+"/"+fileDescriptor.Path()+".txt", "")
+		}
+		return nil
+	})
+	codeGeneratorResponse, err := Invoke(
+		ctx,
+		HandlerFunc(func(ctx context.Context, pluginEnv PluginEnv, responseWriter ResponseWriter, request Request) error {
+			for _, subRequestArgs := range []struct {
+				filesToGenerate []string
+				parameter       string
+			}{
+				{filesToGenerate: []string{"b.proto"}, parameter: "one"},
+				{filesToGenerate: []string{"b.proto", "c.proto"}, parameter: "two"},
+				{filesToGenerate: []string{"c.proto"}, parameter: "error"},
+			} {
+				subRequest, err := NewSubRequest(request, subRequestArgs.filesToGenerate, subRequestArgs.parameter)
+				if err != nil {
+					return err
+				}
+				if err := HandleSubRequest(ctx, pluginEnv, responseWriter, subHandler, subRequest); err != nil {
+					return err
+				}
+			}
+			_, err := NewSubRequest(request, []string{"a.proto"}, "")
+			require.Error(t, err)
+			return nil
+		}),
+		&pluginpb.CodeGeneratorRequest{
+			FileToGenerate:        []string{"b.proto", "c.proto"},
+			ProtoFile:             fileDescriptorProtos,
+			SourceFileDescriptors: fileDescriptorProtos[1:],
+		},
+	)
+	require.NoError(t, err)
+	require.True(
+		t,
+		proto.Equal(
+			&pluginpb.CodeGeneratorResponse{
+				Error: proto.String("sub error"),
+				File: []*pluginpb.CodeGeneratorResponse_File{
+					{Name: proto.String("one/b.proto.txt"), Content: proto.String("")},
+					{Name: proto.String("two/b.proto.txt"), Content: proto.String("")},
+					{Name: proto.String("two/c.proto.txt"), Content: proto.String("")},
+				},
+			},
+			codeGeneratorResponse,
+		),
+	)
+}
@@ -153,6 +153,10 @@ func validateAndNormalizeCodeGeneratorResponse(
 	//
 	// If not set, no modifications will be performed.
 	lenientResponseValidateErrorFunc func(error),
+	// If true, files with the same name and identical values to a previous file are silently dropped.
+	deduplicateIdenticalFiles bool,
+	// Bits of supported_features that are valid in addition to allSupportedFeaturesMask.
+	additionalSupportedFeatureBits uint64,
 ) (retErr error) {
 	defer func() {
 		if retErr != nil {
@@ -168,7 +172,12 @@ func validateAndNormalizeCodeGeneratorResponse(
 	if len(response.File) != len(files) {
 		response.File = files
 	}
-	files, err = validateAndNormalizeCodeGeneratorResponseFilesWithPotentialDuplicates("file", response.File, lenientResponseValidateErrorFunc)
+	files, err = validateAndNormalizeCodeGeneratorResponseFilesWithPotentialDuplicates(
+		"file",
+		response.File,
+		lenientResponseValidateErrorFunc,
+		deduplicateIdenticalFiles,
+	)
 	if err != nil {
 		return err
 	}
@@ -177,7 +186,7 @@ func validateAndNormalizeCodeGeneratorResponse(
 		response.File = files
 	}
 
-	if response.GetSupportedFeatures()|allSupportedFeaturesMask != allSupportedFeaturesMask {
+	if supportedFeaturesMask := allSupportedFeaturesMask | additionalSupportedFeatureBits; response.GetSupportedFeatures()|supportedFeaturesMask != supportedFeaturesMask {
 		return fmt.Errorf("supported_features: unknown CodeGeneratorResponse.Features: %s", strconv.FormatUint(response.GetSupportedFeatures(), 2))
 	}
 	if response.GetSupportedFeatures()&uint64(pluginpb.CodeGeneratorResponse_FEATURE_SUPPORTS_EDITIONS) != 0 {
@@ -265,8 +274,14 @@ func validateAndNormalizeCodeGeneratorResponseFilesWithPotentialDuplicates(
 	files []*pluginpb.CodeGeneratorResponse_File,
 	// Non-nil if non-critical errors should be warnings instead of errors.
 	lenientResponseValidateErrorFunc func(error),
+	// If true, files with the same name and identical values to a previous file are silently dropped.
+	deduplicateIdenticalFiles bool,
 ) ([]*pluginpb.CodeGeneratorResponse_File, error) {
 	fileNames := make(map[string]struct{})
+	// Only populated if deduplicateIdenticalFiles is true.
+	//
+	// The first file without an insertion point for each name.
+	fileNameToFile := make(map[string]*pluginpb.CodeGeneratorResponse_File)
 	resultFiles := make([]*pluginpb.CodeGeneratorResponse_File, 0, len(files))
 	for _, file := range files {
 		name := file.GetName()
@@ -287,6 +302,10 @@ func validateAndNormalizeCodeGeneratorResponseFilesWithPotentialDuplicates(
 		}
 		// If insertionPoint is set, it is valid and correct to have a duplicate file.
 		if _, ok := fileNames[name]; ok && insertionPoint == "" {
+			if existingFile, ok := fileNameToFile[name]; ok && proto.Equal(existingFile, file) {
+				// Identical duplicate, silently drop.
+				continue
+			}
 			if lenientResponseValidateErrorFunc != nil {
 				lenientResponseValidateErrorFunc(newDuplicateCodeGeneratorResponseFileNameError(name, true))
 			} else {
@@ -296,6 +315,9 @@ func validateAndNormalizeCodeGeneratorResponseFilesWithPotentialDuplicates(
 			// Not a duplicate, add to result files.
 			resultFiles = append(resultFiles, file)
 			fileNames[name] = struct{}{}
+			if deduplicateIdenticalFiles && insertionPoint == "" {
+				fileNameToFile[name] = file
+			}
 		}
 	}
 	return resultFiles, nil
@@ -0,0 +1,81 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+// IsWellKnownTypeFile returns true if the path is the path of a file that defines well-known types,
+// or is otherwise distributed with the protobuf compiler within the google/protobuf directory, for
+// example "google/protobuf/timestamp.proto" or "google/protobuf/descriptor.proto".
+//
+// See WithSkipWellKnownTypes for more details.
+func IsWellKnownTypeFile(path string) bool {
+	_, ok := wellKnownTypeFilePaths[path]
+	return ok
+}
+
+// WithSkipWellKnownTypes returns a new RunOption that says to remove the files that define the well-known types
+// from the files to generate before the Handler is invoked.
+//
+// Nearly every plugin must avoid regenerating the well-known types, as they are provided by the runtime
+// libraries of each language. The well-known type files are determined by IsWellKnownTypeFile. The files are
+// retained as dependencies, that is they are still available via Request.AllFiles and Request.AllFileDescriptorProtos,
+// but are removed from the file_to_generate and source_file_descriptors fields, including on the CodeGeneratorRequest
+// returned by Request.CodeGeneratorRequest. If no other files are to be generated, the Handler is invoked with
+// no files to generate.
+//
+// This option can be passed to Main or Run.
+//
+// The default is to pass all files to generate to the Handler.
+func WithSkipWellKnownTypes() RunOption {
+	return optsFunc(func(opts *opts) {
+		opts.skipWellKnownTypes = true
+	})
+}
+
+// *** PRIVATE ***
+
+var wellKnownTypeFilePaths = map[string]struct{}{
+	"google/protobuf/any.proto":             {},
+	"google/protobuf/api.proto":             {},
+	"google/protobuf/compiler/plugin.proto": {},
+	"google/protobuf/cpp_features.proto":    {},
+	"google/protobuf/descriptor.proto":      {},
+	"google/protobuf/duration.proto":        {},
+	"google/protobuf/empty.proto":           {},
+	"google/protobuf/field_mask.proto":      {},
+	"google/protobuf/go_features.proto":     {},
+	"google/protobuf/java_features.proto":   {},
+	"google/protobuf/source_context.proto":  {},
+	"google/protobuf/struct.proto":          {},
+	"google/protobuf/timestamp.proto":       {},
+	"google/protobuf/type.proto":            {},
+	"google/protobuf/wrappers.proto":        {},
+}
+
+// applySkipWellKnownTypes returns a new request with the well-known type files removed from the files to generate.
+//
+// If there are no well-known type files to generate, the request is returned as-is.
+func applySkipWellKnownTypes(request *request) *request {
+	for _, fileToGenerate := range request.codeGeneratorRequest.GetFileToGenerate() {
+		if IsWellKnownTypeFile(fileToGenerate) {
+			return request.withFilteredFilesToGenerate(
+				request.Parameter(),
+				func(path string) bool {
+					return !IsWellKnownTypeFile(path)
+				},
+			)
+		}
+	}
+	return request
+}
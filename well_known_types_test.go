@@ -0,0 +1,102 @@
+// Copyright 2024-2025 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protoplugin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestWithSkipWellKnownTypesOption(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	codeGeneratorRequest := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{"google/protobuf/timestamp.proto", "a.proto"},
+		Parameter:      proto.String("foo"),
+		ProtoFile: []*descriptorpb.FileDescriptorProto{
+			{Name: proto.String("google/protobuf/timestamp.proto"), Syntax: proto.String("proto3")},
+			{
+				Name:       proto.String("a.proto"),
+				Syntax:     proto.String("proto3"),
+				Dependency: []string{"google/protobuf/timestamp.proto"},
+			},
+		},
+	}
+	var fileNames []string
+	var allFileNames []string
+	var parameter string
+	handler := HandlerFunc(
+		func(_ context.Context, _ PluginEnv, _ ResponseWriter, request Request) error {
+			fileNames = request.CodeGeneratorRequest().GetFileToGenerate()
+			allFileNames = nil
+			for _, fileDescriptorProto := range request.AllFileDescriptorProtos() {
+				allFileNames = append(allFileNames, fileDescriptorProto.GetName())
+			}
+			parameter = request.Parameter()
+			return nil
+		},
+	)
+
+	_, err := Invoke(ctx, handler, codeGeneratorRequest, WithSkipWellKnownTypes())
+	require.NoError(t, err)
+	require.Equal(t, []string{"a.proto"}, fileNames)
+	require.Equal(t, []string{"google/protobuf/timestamp.proto", "a.proto"}, allFileNames)
+	require.Equal(t, "foo", parameter)
+
+	_, err = Invoke(ctx, handler, codeGeneratorRequest)
+	require.NoError(t, err)
+	require.Equal(t, []string{"google/protobuf/timestamp.proto", "a.proto"}, fileNames)
+
+	require.True(t, IsWellKnownTypeFile("google/protobuf/descriptor.proto"))
+	require.True(t, IsWellKnownTypeFile("google/protobuf/compiler/plugin.proto"))
+	require.False(t, IsWellKnownTypeFile("google/protobuf/foo.proto"))
+	require.False(t, IsWellKnownTypeFile("timestamp.proto"))
+}
+
+func TestWithSkipWellKnownTypesOptionOnlyWellKnownTypes(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	codeGeneratorRequest := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{"google/protobuf/timestamp.proto"},
+		ProtoFile: []*descriptorpb.FileDescriptorProto{
+			{Name: proto.String("google/protobuf/timestamp.proto"), Syntax: proto.String("proto3")},
+		},
+		SourceFileDescriptors: []*descriptorpb.FileDescriptorProto{
+			{Name: proto.String("google/protobuf/timestamp.proto"), Syntax: proto.String("proto3")},
+		},
+		CompilerVersion: &pluginpb.Version{Major: proto.Int32(27)},
+	}
+	var fileNames []string
+	var sourceRetentionOptionsErr error
+	handler := HandlerFunc(
+		func(_ context.Context, _ PluginEnv, _ ResponseWriter, request Request) error {
+			fileNames = request.CodeGeneratorRequest().GetFileToGenerate()
+			_, sourceRetentionOptionsErr = request.WithSourceRetentionOptions()
+			return nil
+		},
+	)
+
+	_, err := Invoke(ctx, handler, codeGeneratorRequest, WithSkipWellKnownTypes())
+	require.NoError(t, err)
+	require.Empty(t, fileNames)
+	require.NoError(t, sourceRetentionOptionsErr)
+}